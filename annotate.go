@@ -0,0 +1,143 @@
+package harkit
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Keys of the annotations set by the built-in extractors.
+const (
+	AnnotationTraceID    = "trace_id"    // Trace ID of a W3C traceparent header, see [TraceParent].
+	AnnotationSpanID     = "span_id"     // Parent span ID of a W3C traceparent header.
+	AnnotationTraceFlags = "trace_flags" // Flags of a W3C traceparent header, e.g. "01" when sampled.
+	AnnotationRequestID  = "request_id"  // X-Request-Id header, see [RequestID].
+)
+
+// Annotate calls extract with each entry of log and merges the values it
+// returns into Entry.Annotations, the _annotations extension, e.g. to
+// correlate entries with backend traces. Calls can be chained to combine
+// extractors. A key already annotated with another value is overwritten,
+// and a warning names the values replaced. Empty values are ignored.
+func Annotate(log *harfile.Log, extract func(*harfile.Entry) map[string]string) []harfile.Warning {
+	var warnings []harfile.Warning
+	for i, e := range log.Entries {
+		if e == nil {
+			continue
+		}
+		values := extract(e)
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			v := values[key]
+			if v == "" {
+				continue
+			}
+			if old, ok := e.Annotations[key]; ok && old != v {
+				warnings = append(warnings, harfile.Warning{
+					Path:    fmt.Sprintf("log.entries[%d]._annotations.%s", i, key),
+					Message: fmt.Sprintf("%q replaced by %q", old, v),
+				})
+			}
+			if e.Annotations == nil {
+				e.Annotations = make(map[string]string)
+			}
+			e.Annotations[key] = v
+		}
+	}
+	return warnings
+}
+
+// traceparentPattern matches a W3C Trace Context traceparent header:
+// version, trace ID, parent span ID and flags in lowercase hex.
+var traceparentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})`)
+
+// TraceParent is an extractor for [Annotate] reading the W3C traceparent
+// request header into the AnnotationTraceID, AnnotationSpanID and
+// AnnotationTraceFlags annotations. Malformed headers, and the all-zero IDs
+// the specification declares invalid, are ignored.
+func TraceParent(e *harfile.Entry) map[string]string {
+	if e.Request == nil {
+		return nil
+	}
+	m := traceparentPattern.FindStringSubmatch(strings.TrimSpace(headerValue(e.Request.Headers, "traceparent")))
+	if m == nil || m[1] == "ff" || strings.Trim(m[2], "0") == "" || strings.Trim(m[3], "0") == "" {
+		return nil
+	}
+	return map[string]string{
+		AnnotationTraceID:    m[2],
+		AnnotationSpanID:     m[3],
+		AnnotationTraceFlags: m[4],
+	}
+}
+
+// RequestID is an extractor for [Annotate] reading the X-Request-Id header
+// of the response, or else of the request, into the AnnotationRequestID
+// annotation.
+func RequestID(e *harfile.Entry) map[string]string {
+	var id string
+	if e.Response != nil {
+		id = headerValue(e.Response.Headers, "X-Request-Id")
+	}
+	if id == "" && e.Request != nil {
+		id = headerValue(e.Request.Headers, "X-Request-Id")
+	}
+	if id = strings.TrimSpace(id); id == "" {
+		return nil
+	}
+	return map[string]string{AnnotationRequestID: id}
+}
+
+// HeaderAnnotations returns an extractor for [Annotate] copying the
+// headers named by names, matched case insensitively, into annotations
+// keyed by their lowercased name, e.g. "x-user-id". The request header is
+// taken first, else the response one.
+func HeaderAnnotations(names ...string) func(*harfile.Entry) map[string]string {
+	return func(e *harfile.Entry) map[string]string {
+		values := make(map[string]string)
+		for _, name := range names {
+			var v string
+			if e.Request != nil {
+				v = headerValue(e.Request.Headers, name)
+			}
+			if v == "" && e.Response != nil {
+				v = headerValue(e.Response.Headers, name)
+			}
+			if v != "" {
+				values[strings.ToLower(name)] = v
+			}
+		}
+		return values
+	}
+}
+
+// annotationFieldPrefix starts the name of the export fields of
+// annotations.
+const annotationFieldPrefix = "annotation."
+
+// AnnotationField returns the field exporting the annotation key with
+// [ExportCSV] and [ExportJSONL], named "annotation." followed by key. It is
+// missing for entries without that annotation.
+func AnnotationField(key string) Field {
+	return Field(annotationFieldPrefix + key)
+}
+
+// AnnotationFields returns the fields of every annotation key found in
+// log, sorted, e.g. to be appended to DefaultFields.
+func AnnotationFields(log *harfile.Log) []Field {
+	keys := make(map[string]bool)
+	for _, e := range log.Entries {
+		if e != nil {
+			for key := range e.Annotations {
+				keys[key] = true
+			}
+		}
+	}
+	var fields []Field
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		fields = append(fields, AnnotationField(key))
+	}
+	return fields
+}
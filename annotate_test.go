@@ -0,0 +1,159 @@
+package harkit
+
+import (
+	"bytes"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// annotatedEntry returns an entry with the given request and response
+// headers, each a name followed by its value.
+func annotatedEntry(reqHeaders, respHeaders []string) *harfile.Entry {
+	pairs := func(kv []string) []*harfile.NameValuePair {
+		var out []*harfile.NameValuePair
+		for i := 0; i+1 < len(kv); i += 2 {
+			out = append(out, &harfile.NameValuePair{Name: kv[i], Value: kv[i+1]})
+		}
+		return out
+	}
+	return &harfile.Entry{
+		Request:  &harfile.Request{Method: "GET", URL: "https://example.com/", Headers: pairs(reqHeaders)},
+		Response: &harfile.Response{Status: 200, Headers: pairs(respHeaders)},
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		annotatedEntry([]string{"traceparent", traceparent, "X-Request-Id", "req-1"}, nil),
+		nil,
+		annotatedEntry(nil, []string{"x-request-id", " resp-2 "}),
+		annotatedEntry(nil, nil),
+	}}
+	if w := Annotate(log, TraceParent); w != nil {
+		t.Errorf("warnings = %v", w)
+	}
+	if w := Annotate(log, RequestID); w != nil {
+		t.Errorf("warnings = %v", w)
+	}
+	want := map[string]string{
+		AnnotationTraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		AnnotationSpanID:     "00f067aa0ba902b7",
+		AnnotationTraceFlags: "01",
+		AnnotationRequestID:  "req-1",
+	}
+	if got := log.Entries[0].Annotations; !maps.Equal(got, want) {
+		t.Errorf("annotations = %v, want %v", got, want)
+	}
+	if got := log.Entries[2].Annotations; !maps.Equal(got, map[string]string{AnnotationRequestID: "resp-2"}) {
+		t.Errorf("annotations = %v", got)
+	}
+	if log.Entries[3].Annotations != nil {
+		t.Errorf("entry without headers annotated: %v", log.Entries[3].Annotations)
+	}
+}
+
+// TestAnnotateCollision checks that a value replacing another is warned
+// about, while the same value and empty values are not.
+func TestAnnotateCollision(t *testing.T) {
+	e := annotatedEntry(nil, nil)
+	e.Annotations = map[string]string{"team": "search", "region": "eu", "build": "42"}
+	log := &harfile.Log{Entries: []*harfile.Entry{nil, e}}
+	warnings := Annotate(log, func(*harfile.Entry) map[string]string {
+		return map[string]string{"team": "ads", "region": "eu", "build": "", "owner": "ada", "zone": "b"}
+	})
+	want := []harfile.Warning{{Path: "log.entries[1]._annotations.team", Message: `"search" replaced by "ads"`}}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %v, want %v", warnings, want)
+	}
+	if got := e.Annotations; !maps.Equal(got, map[string]string{"team": "ads", "region": "eu", "build": "42", "owner": "ada", "zone": "b"}) {
+		t.Errorf("annotations = %v", got)
+	}
+
+	// Warnings are in key order, whatever the order of the map.
+	warnings = Annotate(log, func(*harfile.Entry) map[string]string {
+		return map[string]string{"zone": "c", "owner": "bob", "team": "ads"}
+	})
+	var paths []string
+	for _, w := range warnings {
+		paths = append(paths, w.Path)
+	}
+	if want := []string{"log.entries[1]._annotations.owner", "log.entries[1]._annotations.zone"}; !slices.Equal(paths, want) {
+		t.Errorf("warnings at %q, want %q", paths, want)
+	}
+}
+
+func TestTraceParent(t *testing.T) {
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{" 01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", true}, // Later versions may append fields.
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6-00f067aa0ba902b7-01", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		got := TraceParent(annotatedEntry([]string{"Traceparent", tt.header}, nil))
+		if (got != nil) != tt.ok {
+			t.Errorf("TraceParent(%q) = %v", tt.header, got)
+		}
+	}
+	if got := TraceParent(&harfile.Entry{}); got != nil {
+		t.Errorf("entry without request = %v", got)
+	}
+}
+
+func TestHeaderAnnotations(t *testing.T) {
+	e := annotatedEntry([]string{"X-User-Id", "7", "X-Tenant", ""}, []string{"x-user-id", "8", "X-Tenant", "acme", "X-Region", "eu"})
+	got := HeaderAnnotations("X-User-Id", "x-tenant", "X-Absent")(e)
+	if want := map[string]string{"x-user-id": "7", "x-tenant": "acme"}; !maps.Equal(got, want) {
+		t.Errorf("annotations = %v, want %v", got, want)
+	}
+	if got := HeaderAnnotations("X-User-Id")(&harfile.Entry{}); len(got) != 0 {
+		t.Errorf("entry without request = %v", got)
+	}
+}
+
+func TestAnnotationFields(t *testing.T) {
+	a := annotatedEntry(nil, nil)
+	a.Annotations = map[string]string{"trace_id": "abc", "team": "ads"}
+	b := annotatedEntry(nil, nil)
+	b.Annotations = map[string]string{"team": "search"}
+	log := &harfile.Log{Entries: []*harfile.Entry{a, nil, b}}
+
+	fields := AnnotationFields(log)
+	if want := []Field{"annotation.team", "annotation.trace_id"}; !slices.Equal(fields, want) {
+		t.Fatalf("AnnotationFields = %q, want %q", fields, want)
+	}
+	if AnnotationFields(&harfile.Log{}) != nil {
+		t.Error("fields of an empty log")
+	}
+
+	var buf bytes.Buffer
+	if err := ExportCSV(&buf, log, append([]Field{FieldURL}, fields...)); err != nil {
+		t.Fatal(err)
+	}
+	want := "url,annotation.team,annotation.trace_id\n" +
+		"https://example.com/,ads,abc\n" +
+		"https://example.com/,search,\n"
+	if buf.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := ExportJSONL(&buf, log, JSONLOptions{Fields: []Field{AnnotationField("trace_id")}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"annotation.trace_id\":\"abc\"}\n{\"annotation.trace_id\":null}\n"; buf.String() != want {
+		t.Errorf("JSONL = %q, want %q", buf.String(), want)
+	}
+}
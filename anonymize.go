@@ -0,0 +1,320 @@
+package harkit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+var (
+	// DefaultEmailPattern matches the email addresses masked by [Anonymize].
+	DefaultEmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	// DefaultPhonePattern matches the phone numbers masked by [Anonymize]:
+	// nine digits or more, with an optional leading "+" and spaces, dots,
+	// dashes or parentheses in between.
+	DefaultPhonePattern = regexp.MustCompile(`\+?\(?\d(?:[ .()-]?\d){8,}`)
+)
+
+const (
+	emailMask = "[EMAIL]"
+	phoneMask = "[PHONE]"
+)
+
+// AnonymizeOptions configures [Anonymize].
+type AnonymizeOptions struct {
+	Key          []byte         // HMAC key of the cookie pseudonyms. Nil means a random key, so pseudonyms only match within one call.
+	EmailPattern *regexp.Regexp // Nil means DefaultEmailPattern.
+	PhonePattern *regexp.Regexp // Nil means DefaultPhonePattern.
+}
+
+// AnonymizeReport counts the values replaced by [Anonymize].
+type AnonymizeReport struct {
+	ServerIPs   int
+	Connections int
+	Cookies     int // Cookie values, in cookie lists and in Cookie and Set-Cookie headers.
+	Emails      int
+	Phones      int
+	UserAgents  int
+}
+
+// Total returns the number of anonymized values.
+func (r *AnonymizeReport) Total() int {
+	return r.ServerIPs + r.Connections + r.Cookies + r.Emails + r.Phones + r.UserAgents
+}
+
+// Anonymize removes personal data from har in place and reports what was
+// replaced:
+//   - ServerIPAddress and Connection are cleared;
+//   - cookie values are replaced by pseudonyms derived from opts.Key, so a
+//     given value maps to the same pseudonym across entries and session
+//     correlation is preserved;
+//   - email addresses and phone numbers are masked in query parameters,
+//     posted parameters and JSON bodies;
+//   - User-Agent headers are reduced to their first product token.
+//
+// Unlike [Redact], which removes secrets, Anonymize keeps captures usable
+// for analysis. Sizes are fixed as by Redact, and re-serialized JSON
+// bodies end up with sorted keys.
+func Anonymize(har *harfile.HAR, opts AnonymizeOptions) *AnonymizeReport {
+	a := &anonymizer{opts: opts, report: &AnonymizeReport{}}
+	if a.opts.Key == nil {
+		a.opts.Key = make([]byte, 32)
+		rand.Read(a.opts.Key)
+	}
+	if a.opts.EmailPattern == nil {
+		a.opts.EmailPattern = DefaultEmailPattern
+	}
+	if a.opts.PhonePattern == nil {
+		a.opts.PhonePattern = DefaultPhonePattern
+	}
+	if har == nil || har.Log == nil {
+		return a.report
+	}
+	for _, e := range har.Log.Entries {
+		if e == nil {
+			continue
+		}
+		if e.ServerIPAddress != "" {
+			e.ServerIPAddress = ""
+			a.report.ServerIPs++
+		}
+		if e.Connection != "" {
+			e.Connection = ""
+			a.report.Connections++
+		}
+		if e.Request != nil {
+			a.request(e.Request)
+		}
+		if e.Response != nil {
+			a.response(e.Response)
+		}
+	}
+	return a.report
+}
+
+type anonymizer struct {
+	opts   AnonymizeOptions
+	report *AnonymizeReport
+}
+
+// pseudonym returns the stable replacement of a cookie value.
+func (a *anonymizer) pseudonym(value string) string {
+	mac := hmac.New(sha256.New, a.opts.Key)
+	mac.Write([]byte(value))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func (a *anonymizer) request(req *harfile.Request) {
+	changed := false
+	for _, h := range req.Headers {
+		switch {
+		case h == nil:
+		case strings.EqualFold(h.Name, "Cookie"):
+			changed = a.cookieHeader(h) || changed
+		case strings.EqualFold(h.Name, "User-Agent"):
+			changed = a.userAgent(h) || changed
+		}
+	}
+	a.cookies(req.Cookies)
+
+	for _, q := range req.QueryString {
+		if q != nil {
+			q.Value = a.mask(q.Value)
+		}
+	}
+	if u, err := url.Parse(req.URL); err == nil && u.RawQuery != "" {
+		// Values listed in QueryString were counted already.
+		if raw := a.maskParams(u.RawQuery, len(req.QueryString) == 0); raw != u.RawQuery {
+			u.RawQuery = raw
+			req.URL = u.String()
+			changed = true
+		}
+	}
+	if changed {
+		req.HeadersSize = -1
+	}
+
+	pd := req.PostData
+	if pd == nil {
+		return
+	}
+	for _, p := range pd.Params {
+		if p != nil {
+			p.Value = a.mask(p.Value)
+		}
+	}
+	text := pd.Text
+	switch {
+	case isJSONMime(pd.MimeType):
+		text = a.maskJSON(text)
+	case strings.HasPrefix(strings.ToLower(pd.MimeType), "application/x-www-form-urlencoded"):
+		text = a.maskParams(text, len(pd.Params) == 0)
+	}
+	if text != pd.Text {
+		pd.Text = text
+		req.BodySize = int64(len(text))
+	}
+}
+
+func (a *anonymizer) response(resp *harfile.Response) {
+	changed := false
+	for _, h := range resp.Headers {
+		if h != nil && strings.EqualFold(h.Name, "Set-Cookie") {
+			name, rest, _ := strings.Cut(h.Value, ";")
+			name, value, ok := strings.Cut(name, "=")
+			if ok && value != "" {
+				h.Value = name + "=" + a.pseudonym(value)
+				if rest != "" {
+					h.Value += ";" + rest
+				}
+				a.report.Cookies++
+				changed = true
+			}
+		}
+	}
+	if changed {
+		resp.HeadersSize = -1
+	}
+	a.cookies(resp.Cookies)
+
+	rewriteContent(resp, func(mimeType, text string) string {
+		if !isJSONMime(mimeType) {
+			return text
+		}
+		return a.maskJSON(text)
+	})
+}
+
+func (a *anonymizer) cookies(cookies []*harfile.Cookie) {
+	for _, c := range cookies {
+		if c == nil || c.Value == "" {
+			continue
+		}
+		c.Value = a.pseudonym(c.Value)
+		a.report.Cookies++
+	}
+}
+
+// cookieHeader pseudonymizes the values of a Cookie header, keeping the
+// cookie names and order.
+func (a *anonymizer) cookieHeader(h *harfile.NameValuePair) bool {
+	parts := strings.Split(h.Value, ";")
+	changed := false
+	for i, part := range parts {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			continue
+		}
+		parts[i] = name + "=" + a.pseudonym(value)
+		a.report.Cookies++
+		changed = true
+	}
+	if changed {
+		h.Value = strings.Join(parts, ";")
+	}
+	return changed
+}
+
+// userAgent reduces a User-Agent header to its first product token, e.g.
+// "Mozilla/5.0".
+func (a *anonymizer) userAgent(h *harfile.NameValuePair) bool {
+	product, _, _ := strings.Cut(strings.TrimSpace(h.Value), " ")
+	if product == h.Value {
+		return false
+	}
+	h.Value = product
+	a.report.UserAgents++
+	return true
+}
+
+// mask replaces the email addresses and phone numbers of s.
+func (a *anonymizer) mask(s string) string {
+	s = a.opts.EmailPattern.ReplaceAllStringFunc(s, func(string) string {
+		a.report.Emails++
+		return emailMask
+	})
+	return a.opts.PhonePattern.ReplaceAllStringFunc(s, func(string) string {
+		a.report.Phones++
+		return phoneMask
+	})
+}
+
+// maskParams masks the values of a URL encoded parameter list, keeping
+// parameter order and the encoding of unchanged values. Replacements are
+// reported when count is set.
+func (a *anonymizer) maskParams(raw string, count bool) string {
+	if !count {
+		defer func(report AnonymizeReport) {
+			a.report.Emails, a.report.Phones = report.Emails, report.Phones
+		}(*a.report)
+	}
+	parts := strings.Split(raw, "&")
+	for i, part := range parts {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		decoded, err := url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		if masked := a.mask(decoded); masked != decoded {
+			parts[i] = key + "=" + url.QueryEscape(masked)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// maskJSON masks the string values of a JSON document. The document is
+// re-serialized when changed, so object keys end up sorted.
+func (a *anonymizer) maskJSON(text string) string {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return text
+	}
+	doc, changed := a.maskValue(doc)
+	if !changed {
+		return text
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return text
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func (a *anonymizer) maskValue(v any) (any, bool) {
+	changed := false
+	switch v := v.(type) {
+	case string:
+		masked := a.mask(v)
+		return masked, masked != v
+	case map[string]any:
+		for k, child := range v {
+			if masked, ok := a.maskValue(child); ok {
+				v[k] = masked
+				changed = true
+			}
+		}
+	case []any:
+		for i, child := range v {
+			if masked, ok := a.maskValue(child); ok {
+				v[i] = masked
+				changed = true
+			}
+		}
+	}
+	return v, changed
+}
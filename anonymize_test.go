@@ -0,0 +1,149 @@
+package harkit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// anonymizeHAR returns two entries of one session, with personal data in
+// every place Anonymize looks.
+func anonymizeHAR() *harfile.HAR {
+	login := &harfile.Entry{
+		ServerIPAddress: "203.0.113.7",
+		Connection:      "1234",
+		Request: &harfile.Request{
+			Method: "POST",
+			URL:    "https://example.com/login?email=ada%40example.com&lang=en",
+			Headers: []*harfile.NameValuePair{
+				{Name: "User-Agent", Value: "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0"},
+				{Name: "Cookie", Value: "sid=s1; theme=dark; empty="},
+			},
+			Cookies:     []*harfile.Cookie{{Name: "sid", Value: "s1"}, {Name: "theme", Value: "dark"}},
+			QueryString: []*harfile.NameValuePair{{Name: "email", Value: "ada@example.com"}, {Name: "lang", Value: "en"}},
+			PostData: &harfile.PostData{
+				MimeType: "application/x-www-form-urlencoded",
+				Params:   []*harfile.Param{{Name: "phone", Value: "+33 6 12 34 56 78"}},
+				Text:     "phone=%2B33+6+12+34+56+78&note=hi",
+			},
+			HeadersSize: 200,
+			BodySize:    33,
+		},
+		Response: &harfile.Response{
+			Status:  200,
+			Headers: []*harfile.NameValuePair{{Name: "Set-Cookie", Value: "sid=s2; Path=/; HttpOnly"}},
+			Cookies: []*harfile.Cookie{{Name: "sid", Value: "s2"}},
+			Content: &harfile.Content{
+				MimeType: "application/json",
+				Text:     `{"user":{"email":"ada@example.com","phone":"555.010.0199","id":7},"tags":["bob@example.org","x"]}`,
+			},
+			HeadersSize: 80,
+		},
+	}
+	next := &harfile.Entry{
+		Request: &harfile.Request{
+			Method:  "GET",
+			URL:     "https://example.com/home",
+			Headers: []*harfile.NameValuePair{{Name: "Cookie", Value: "sid=s2"}, {Name: "User-Agent", Value: "curl"}},
+			Cookies: []*harfile.Cookie{{Name: "sid", Value: "s2"}},
+		},
+		Response: &harfile.Response{Status: 200, Content: &harfile.Content{MimeType: "text/plain", Text: "mail ada@example.com"}},
+	}
+	return &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{login, nil, next}}}
+}
+
+func TestAnonymize(t *testing.T) {
+	h := anonymizeHAR()
+	key := []byte("test key")
+	report := Anonymize(h, AnonymizeOptions{Key: key})
+	want := AnonymizeReport{ServerIPs: 1, Connections: 1, Cookies: 8, Emails: 3, Phones: 2, UserAgents: 1}
+	if *report != want {
+		t.Errorf("report = %+v, want %+v", *report, want)
+	}
+	if report.Total() != 16 {
+		t.Errorf("Total = %d", report.Total())
+	}
+
+	login, next := h.Log.Entries[0], h.Log.Entries[2]
+	req, resp := login.Request, login.Response
+	if login.ServerIPAddress != "" || login.Connection != "" {
+		t.Error("connection details kept")
+	}
+	if ua := req.Headers[0].Value; ua != "Mozilla/5.0" || next.Request.Headers[1].Value != "curl" {
+		t.Errorf("user agents %q, %q", ua, next.Request.Headers[1].Value)
+	}
+
+	// A value maps to one pseudonym everywhere, so the session can still be
+	// followed from the Set-Cookie of the login to the next request.
+	s1, s2 := req.Cookies[0].Value, resp.Cookies[0].Value
+	if !strings.HasPrefix(s1, "anon-") || s1 == s2 || s1 == req.Cookies[1].Value {
+		t.Errorf("pseudonyms %q, %q, %q", s1, s2, req.Cookies[1].Value)
+	}
+	if got := req.Headers[1].Value; got != "sid="+s1+"; theme="+req.Cookies[1].Value+"; empty=" {
+		t.Errorf("Cookie header = %q", got)
+	}
+	if got := resp.Headers[0].Value; got != "sid="+s2+"; Path=/; HttpOnly" {
+		t.Errorf("Set-Cookie header = %q", got)
+	}
+	if next.Request.Cookies[0].Value != s2 || next.Request.Headers[0].Value != "sid="+s2 {
+		t.Errorf("next request cookies %q, header %q", next.Request.Cookies[0].Value, next.Request.Headers[0].Value)
+	}
+
+	if req.URL != "https://example.com/login?email=%5BEMAIL%5D&lang=en" || req.QueryString[0].Value != emailMask || req.HeadersSize != -1 {
+		t.Errorf("url %s, query %v, headersSize %d", req.URL, req.QueryString[0], req.HeadersSize)
+	}
+	if pd := req.PostData; pd.Params[0].Value != phoneMask || pd.Text != "phone=%5BPHONE%5D&note=hi" || req.BodySize != int64(len(pd.Text)) {
+		t.Errorf("postData %+v, bodySize %d", pd, req.BodySize)
+	}
+	var body any
+	if err := json.Unmarshal([]byte(resp.Content.Text), &body); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Content.Text; got != `{"tags":["[EMAIL]","x"],"user":{"email":"[EMAIL]","id":7,"phone":"[PHONE]"}}` {
+		t.Errorf("body = %s", got)
+	}
+	if resp.HeadersSize != -1 || next.Response.Content.Text != "mail ada@example.com" {
+		t.Errorf("headersSize %d, text body %q", resp.HeadersSize, next.Response.Content.Text)
+	}
+}
+
+func TestAnonymizeDeterministic(t *testing.T) {
+	run := func(key []byte) []byte {
+		h := anonymizeHAR()
+		Anonymize(h, AnonymizeOptions{Key: key})
+		data, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	a, b := run([]byte("k1")), run([]byte("k1"))
+	if string(a) != string(b) {
+		t.Error("the same key gave different results")
+	}
+	if string(a) == string(run([]byte("k2"))) {
+		t.Error("different keys gave the same pseudonyms")
+	}
+	// Random keys differ between calls.
+	if string(run(nil)) == string(run(nil)) {
+		t.Error("two random keys gave the same pseudonyms")
+	}
+
+	// Anonymizing again only replaces the pseudonyms.
+	h := anonymizeHAR()
+	Anonymize(h, AnonymizeOptions{Key: []byte("k1")})
+	if again := Anonymize(h, AnonymizeOptions{Key: []byte("k1")}); again.Total() != again.Cookies {
+		t.Errorf("second pass = %+v", *again)
+	}
+}
+
+func TestAnonymizeNil(t *testing.T) {
+	if r := Anonymize(nil, AnonymizeOptions{}); r.Total() != 0 {
+		t.Errorf("nil HAR = %+v", *r)
+	}
+	if r := Anonymize(&harfile.HAR{}, AnonymizeOptions{}); r.Total() != 0 {
+		t.Errorf("HAR without log = %+v", *r)
+	}
+}
@@ -0,0 +1,100 @@
+package harkit
+
+import (
+	"cmp"
+	"sync"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// DefaultAutosaveInterval is the period of autosaves when
+// RecorderOptions.AutosaveInterval is zero.
+const DefaultAutosaveInterval = 10 * time.Second
+
+// autosaver saves a Recorder periodically, on a goroutine of its own.
+type autosaver struct {
+	r    *Recorder
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mu    sync.Mutex // Serializes the saves.
+	saved uint64     // Generation of the log last saved.
+	dirty bool       // Whether nothing was saved yet.
+}
+
+func newAutosaver(r *Recorder, interval time.Duration) *autosaver {
+	a := &autosaver{r: r, stop: make(chan struct{}), done: make(chan struct{}), dirty: true}
+	go func() {
+		defer close(a.done)
+		clock := cmp.Or(r.opts.Clock, RealClock)
+		for {
+			timer := clock.NewTimer(interval)
+			select {
+			case <-a.stop:
+				timer.Stop()
+				return
+			case <-timer.C():
+				if err := a.save(false); err != nil && r.opts.OnAutosaveError != nil {
+					r.opts.OnAutosaveError(err)
+				}
+			}
+		}
+	}()
+	return a
+}
+
+// save writes a snapshot of the log, unless unchanged since the last save
+// and not forced.
+func (a *autosaver) save(force bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r := a.r
+	r.mu.Lock()
+	r.init()
+	gen := r.gen
+	if !force && !a.dirty && gen == a.saved {
+		r.mu.Unlock()
+		return nil
+	}
+	h := (&harfile.HAR{Log: r.log}).Clone()
+	r.mu.Unlock()
+
+	if err := writeFileAtomic(r.opts.AutosavePath, h); err != nil {
+		return err
+	}
+	a.saved, a.dirty = gen, false
+	return nil
+}
+
+// FlushNow saves the log to RecorderOptions.AutosavePath at once. It does
+// nothing for a Recorder without one.
+//
+// The log is copied under the lock, so entries appended during a save are
+// left for the next one, never lost nor saved twice, and written to a
+// temporary file renamed over the previous save: the file is a complete
+// HAR at all times, and a failed save leaves the previous one in place.
+// Autosaves skip a log unchanged since the last save; FlushNow always
+// writes.
+func (r *Recorder) FlushNow() error {
+	if r.autosave == nil {
+		return nil
+	}
+	return r.autosave.save(true)
+}
+
+// Close stops the autosaves of r and saves the log a last time, returning
+// the error of that save. Entries can still be appended, but are only saved
+// by FlushNow. Close does nothing for a Recorder without AutosavePath and is
+// safe to call more than once.
+func (r *Recorder) Close() error {
+	a := r.autosave
+	if a == nil {
+		return nil
+	}
+	a.once.Do(func() { close(a.stop) })
+	<-a.done
+	return a.save(false)
+}
@@ -0,0 +1,261 @@
+package harkit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// loadSave loads the autosave at path, failing the test unless it is a
+// complete HAR, and returns the URLs of its entries.
+func loadSave(t *testing.T, path string) []string {
+	t.Helper()
+	h, err := harfile.LoadFile(path)
+	if err != nil {
+		t.Fatalf("autosave not loadable: %v", err)
+	}
+	var urls []string
+	for _, e := range h.Log.Entries {
+		urls = append(urls, e.Request.URL)
+	}
+	return urls
+}
+
+func TestAutosavePeriodic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.har")
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewRecorder(RecorderOptions{
+		AutosavePath:     path,
+		AutosaveInterval: time.Second,
+		Clock:            clock,
+		OnAutosaveError:  func(err error) { t.Error(err) },
+	})
+	defer r.Close()
+
+	// advanceUntil moves the clock until the save holds n entries.
+	advanceUntil := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			clock.Advance(time.Second)
+			if _, err := os.Stat(path); err == nil && len(loadSave(t, path)) == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("no autosave of %d entries", n)
+	}
+	advanceUntil(0) // An empty log is saved once.
+	r.Append(harfile.NewEntry().Get("https://example.com/1").Build())
+	advanceUntil(1)
+	r.Append(harfile.NewEntry().Get("https://example.com/2").Build())
+	advanceUntil(2)
+
+	// An unchanged log is not saved again.
+	os.Remove(path)
+	for range 5 {
+		clock.Advance(time.Second)
+		time.Sleep(2 * time.Millisecond)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("unchanged log saved again: %v", err)
+	}
+	if err := r.FlushNow(); err != nil {
+		t.Fatal(err)
+	}
+	if urls := loadSave(t, path); len(urls) != 2 {
+		t.Errorf("FlushNow saved %v", urls)
+	}
+}
+
+// TestAutosaveConcurrentAppend saves repeatedly while entries are appended,
+// and checks that every save is a complete HAR holding a prefix of the
+// entries, and the final one all of them exactly once.
+func TestAutosaveConcurrentAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.har")
+	r := NewRecorder(RecorderOptions{AutosavePath: path, AutosaveInterval: time.Millisecond})
+	const writers, perWriter = 4, 100
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				r.Append(harfile.NewEntry().Get(fmt.Sprintf("https://example.com/%d/%d", w, i)).Build())
+			}
+		}()
+	}
+	done := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		last := 0
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := r.FlushNow(); err != nil {
+				t.Error(err)
+				return
+			}
+			urls := loadSave(t, path)
+			if len(urls) < last {
+				t.Errorf("save shrank from %d to %d entries", last, len(urls))
+			}
+			last = len(urls)
+		}
+	}()
+	wg.Wait()
+	close(done)
+	<-flushed
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	urls := loadSave(t, path)
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if seen[u] {
+			t.Errorf("%s saved twice", u)
+		}
+		seen[u] = true
+	}
+	if len(seen) != writers*perWriter {
+		t.Errorf("final save holds %d entries, want %d", len(seen), writers*perWriter)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("files left next to the save: %v", entries)
+	}
+}
+
+// failingStore holds bodies whose reads fail halfway while fail is set, so
+// that saving them breaks in the middle of the write.
+type failingStore struct {
+	fail *atomic.Bool
+}
+
+func (s failingStore) Put(text string) (harfile.BodyHandle, error) {
+	return failingBody{text: text, fail: s.fail}, nil
+}
+
+type failingBody struct {
+	text string
+	fail *atomic.Bool
+}
+
+func (b failingBody) Open() (io.ReadCloser, error) {
+	if !b.fail.Load() {
+		return io.NopCloser(strings.NewReader(b.text)), nil
+	}
+	return io.NopCloser(io.MultiReader(strings.NewReader(b.text[:len(b.text)/2]), iotest.ErrReader(errors.New("disk unplugged")))), nil
+}
+
+func TestAutosaveFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capture.har")
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	errs := make(chan error, 10)
+	r := NewRecorder(RecorderOptions{
+		AutosavePath:     path,
+		AutosaveInterval: time.Second,
+		Clock:            clock,
+		OnAutosaveError:  func(err error) { errs <- err },
+	})
+	defer r.Close()
+
+	r.Append(harfile.NewEntry().Get("https://example.com/ok").Build())
+	if err := r.FlushNow(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry whose body cannot be read makes the next saves fail after
+	// part of the log was written.
+	var fail atomic.Bool
+	fail.Store(true)
+	var doc strings.Builder
+	harfile.NewLog().Entries(harfile.NewEntry().Get("https://example.com/big").
+		RespondBody("text/plain", []byte(strings.Repeat("x", 1000))).Build()).HAR().Write(&doc)
+	big, err := harfile.LoadWithBodyStore(strings.NewReader(doc.String()), failingStore{&fail}, harfile.WithBodyThreshold(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Append(big.Log.Entries[0])
+	r.Append(harfile.NewEntry().Get("https://example.com/after").Build())
+
+	if err := r.FlushNow(); err == nil || !strings.Contains(err.Error(), "disk unplugged") {
+		t.Errorf("FlushNow error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	var periodic error
+	for periodic == nil && time.Now().Before(deadline) {
+		clock.Advance(time.Second)
+		select {
+		case periodic = <-errs:
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if periodic == nil {
+		t.Error("failed autosave not reported")
+	}
+
+	// The previous save is intact and no partial file is left behind.
+	if urls := loadSave(t, path); strings.Join(urls, " ") != "https://example.com/ok" {
+		t.Errorf("save after failures holds %v", urls)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("files left next to the save: %v", entries)
+	}
+
+	// Once the body reads again, nothing appended meanwhile was lost.
+	fail.Store(false)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "https://example.com/ok https://example.com/big https://example.com/after"
+	if urls := loadSave(t, path); strings.Join(urls, " ") != want {
+		t.Errorf("save after recovery holds %v", urls)
+	}
+}
+
+func TestAutosaveClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.har")
+	r := NewRecorder(RecorderOptions{AutosavePath: path, AutosaveInterval: time.Hour})
+	r.Append(harfile.NewEntry().Get("https://example.com/").Build())
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if urls := loadSave(t, path); len(urls) != 1 {
+		t.Errorf("Close saved %v", urls)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+
+	// Entries appended after Close are saved by FlushNow only.
+	r.Append(harfile.NewEntry().Get("https://example.com/late").Build())
+	if err := r.FlushNow(); err != nil {
+		t.Fatal(err)
+	}
+	if urls := loadSave(t, path); len(urls) != 2 {
+		t.Errorf("FlushNow after Close saved %v", urls)
+	}
+
+	plain := NewRecorder(RecorderOptions{})
+	if plain.FlushNow() != nil || plain.Close() != nil {
+		t.Error("error from a Recorder without autosave")
+	}
+}
@@ -0,0 +1,132 @@
+package harkit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// BodyTransformer rewrites the request and response bodies recorded by
+// [Transport] and [Middleware], see [WithBodyTransformer]. It is given the
+// MIME type and the decoded body, and returns what is recorded instead.
+type BodyTransformer interface {
+	Transform(mimeType string, body []byte) ([]byte, error)
+}
+
+// BodyTransformerFunc adapts a function to a [BodyTransformer].
+type BodyTransformerFunc func(mimeType string, body []byte) ([]byte, error)
+
+// Transform calls f(mimeType, body).
+func (f BodyTransformerFunc) Transform(mimeType string, body []byte) ([]byte, error) {
+	return f(mimeType, body)
+}
+
+// WithBodyTransformer transforms the captured copies of request and
+// response bodies before they are recorded, in the order given, e.g. to
+// drop fields that must never be stored. Bodies are transformed once
+// decompressed and cut by the body limits, so that a truncated JSON body
+// may fail to parse. The bytes sent and received by the application are
+// never changed.
+//
+// When a transformer fails, nothing is recorded of the body and its
+// comment gives the error; the request itself goes on. Content.Size and
+// the body sizes keep describing the bodies exchanged.
+func WithBodyTransformer(ts ...BodyTransformer) Option {
+	return func(o *options) {
+		o.transformers = append(o.transformers, ts...)
+	}
+}
+
+// DropJSONFields removes the values at paths from JSON bodies, in the
+// syntax of [harfile.PostData.JSONPath], "[*]" standing for every element
+// of an array, e.g. "password" or "$.cards[*].number". The body is written
+// back as by [harfile.CanonicalJSON]. Other bodies are kept, and so is an
+// empty body; a JSON body that does not parse fails.
+func DropJSONFields(paths ...string) BodyTransformer {
+	return BodyTransformerFunc(func(mimeType string, body []byte) ([]byte, error) {
+		if !isJSONMime(mimeType) || len(bytes.TrimSpace(body)) == 0 {
+			return body, nil
+		}
+		return harfile.CanonicalJSON(body, paths...)
+	})
+}
+
+// TruncateTo keeps the first n bytes of bodies.
+func TruncateTo(n int) BodyTransformer {
+	return BodyTransformerFunc(func(_ string, body []byte) ([]byte, error) {
+		return body[:min(len(body), max(n, 0))], nil
+	})
+}
+
+// HashBody replaces bodies with their SHA-256 digest, written
+// "sha256:" followed by the hex digest, so that bodies can be compared
+// without being stored. Empty bodies stay empty.
+func HashBody() BodyTransformer {
+	return BodyTransformerFunc(func(_ string, body []byte) ([]byte, error) {
+		if len(body) == 0 {
+			return body, nil
+		}
+		sum := sha256.Sum256(body)
+		return []byte("sha256:" + hex.EncodeToString(sum[:])), nil
+	})
+}
+
+// transformBodies applies the body transformers to the bodies of req and
+// resp, either of which may be nil.
+func (o *options) transformBodies(req *harfile.Request, resp *harfile.Response) {
+	if len(o.transformers) == 0 {
+		return
+	}
+	if req != nil && req.PostData != nil && (req.PostData.Text != "" || len(req.PostData.Params) > 0) {
+		pd := req.PostData
+		var body []byte
+		r, _, err := pd.BodyReader()
+		if err == nil {
+			body, err = io.ReadAll(r)
+		}
+		if err == nil {
+			body, err = o.transform(pd.MimeType, body)
+		}
+		var out *harfile.PostData
+		if err == nil {
+			out, err = harfile.PostDataFromBody(pd.MimeType, body)
+		}
+		if err != nil {
+			out = &harfile.PostData{MimeType: pd.MimeType, Params: []*harfile.Param{}}
+			out.Comment = fmt.Sprintf("body not recorded: %v", err)
+		} else if out.Comment == "" {
+			out.Comment = pd.Comment
+		}
+		out.Truncated = pd.Truncated
+		req.PostData = out
+	}
+	if resp != nil && resp.Content != nil && resp.Content.Text != "" {
+		c := resp.Content
+		size, compression, comment, truncated := c.Size, c.Compression, c.Comment, c.Truncated
+		body, err := c.DecodedBody()
+		if err == nil {
+			body, err = o.transform(c.MimeType, body)
+		}
+		if err != nil {
+			c.Text, c.Encoding = "", ""
+			comment = fmt.Sprintf("body not recorded: %v", err)
+		} else {
+			c.SetBody(body, c.MimeType)
+		}
+		c.Size, c.Compression, c.Comment, c.Truncated = size, compression, comment, truncated
+	}
+}
+
+func (o *options) transform(mimeType string, body []byte) ([]byte, error) {
+	for _, t := range o.transformers {
+		var err error
+		if body, err = t.Transform(mimeType, body); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,155 @@
+package harkit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyTransformers(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        BodyTransformer
+		mimeType string
+		body     string
+		want     string
+		err      bool
+	}{
+		{"drop fields", DropJSONFields("password", "$.cards[*].number"), "application/json; charset=utf-8",
+			`{"user":"ada","password":"x","cards":[{"number":"4111","exp":"01/30"},{"number":"5500"}]}`,
+			`{"cards":[{"exp":"01/30"},{}],"user":"ada"}`, false},
+		{"drop every element", DropJSONFields("items[*]"), "application/problem+json", `{"items":[1,2]}`, `{"items":[]}`, false},
+		{"drop fields of other types", DropJSONFields("password"), "text/plain", `{"password":"x"}`, `{"password":"x"}`, false},
+		{"drop fields of empty body", DropJSONFields("password"), "application/json", " ", " ", false},
+		{"drop fields of invalid json", DropJSONFields("password"), "application/json", `{"password":`, "", true},
+		{"truncate", TruncateTo(3), "text/plain", "hello", "hel", false},
+		{"truncate short", TruncateTo(10), "text/plain", "hello", "hello", false},
+		{"truncate negative", TruncateTo(-1), "text/plain", "hello", "", false},
+		{"hash", HashBody(), "text/plain", "hello", "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", false},
+		{"hash empty", HashBody(), "text/plain", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.t.Transform(tt.mimeType, []byte(tt.body))
+			if (err != nil) != tt.err || string(got) != tt.want {
+				t.Errorf("Transform = %q, %v, want %q, error %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestTransportBodyTransformer(t *testing.T) {
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"token":"secret","id":7}`)
+	}))
+	defer srv.Close()
+
+	var seen []string
+	tr := NewTransport(http.DefaultTransport,
+		WithBodyTransformer(
+			DropJSONFields("password", "token"),
+			BodyTransformerFunc(func(mimeType string, body []byte) ([]byte, error) {
+				seen = append(seen, string(body))
+				return body, nil
+			}),
+		))
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader(`{"user":"ada","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if received != `{"user":"ada","password":"hunter2"}` || string(got) != `{"token":"secret","id":7}` {
+		t.Errorf("application saw %q and %q", received, got)
+	}
+	e := tr.HAR().Log.Entries[0]
+	if pd := e.Request.PostData; pd.Text != `{"user":"ada"}` || e.Request.BodySize != int64(len(received)) {
+		t.Errorf("postData %q, bodySize %d", pd.Text, e.Request.BodySize)
+	}
+	if c := e.Response.Content; c.Text != `{"id":7}` || c.Size != int64(len(got)) {
+		t.Errorf("content %q, size %d", c.Text, c.Size)
+	}
+	// Transformers run in order, each on the output of the previous one.
+	if strings.Join(seen, " ") != `{"user":"ada"} {"id":7}` {
+		t.Errorf("second transformer saw %q", seen)
+	}
+}
+
+func TestTransportBodyTransformerError(t *testing.T) {
+	failing := BodyTransformerFunc(func(string, []byte) ([]byte, error) { return nil, errors.New("refused") })
+	tr := NewTransport(stubTransport{}, WithBodyTransformer(failing))
+	req, _ := http.NewRequest("POST", "https://example.com/", strings.NewReader("a=1&b=2"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(resp.Body); string(got) != "ok" {
+		t.Errorf("application got %q", got)
+	}
+	resp.Body.Close()
+
+	e := tr.HAR().Log.Entries[0]
+	if pd := e.Request.PostData; pd.Text != "" || len(pd.Params) != 0 || pd.Comment != "body not recorded: refused" {
+		t.Errorf("postData = %+v", pd)
+	}
+	if c := e.Response.Content; c.Text != "" || c.Size != 2 || c.Comment != "body not recorded: refused" {
+		t.Errorf("content = %+v", c)
+	}
+	if err := tr.HAR().Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestMiddlewareBodyTransformer(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(b)
+	}), WithBodyTransformer(HashBody()))
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "text/plain")
+	h.ServeHTTP(rec, req)
+	if rec.Body.String() != "hello" {
+		t.Errorf("client got %q", rec.Body.String())
+	}
+	const digest = "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	e := h.Snapshot().Log.Entries[0]
+	if e.Request.PostData.Text != digest || e.Response.Content.Text != digest || e.Response.Content.Size != 5 {
+		t.Errorf("recorded %q and %+v", e.Request.PostData.Text, e.Response.Content)
+	}
+}
+
+func TestBodyTransformerBinary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G', 0xff, 0x00})
+	}))
+	defer srv.Close()
+
+	tr := NewTransport(http.DefaultTransport, WithBodyTransformer(TruncateTo(4)))
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// The cut binary body is still base64 encoded.
+	c := tr.HAR().Log.Entries[0].Response.Content
+	body, err := c.DecodedBody()
+	if err != nil || string(body) != "\x89PNG" || c.Encoding != "base64" || c.Size != 6 {
+		t.Errorf("content = %+v, body %q, %v", c, body, err)
+	}
+}
@@ -0,0 +1,135 @@
+package harkit
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// ValidatedComment is set on the [harfile.Cache] of a 304 Not Modified
+// response by [CacheFromResponse]: the client revalidated its cached copy
+// with the server.
+const ValidatedComment = "cached copy validated by the server (304 Not Modified)"
+
+// CacheObserver reports the state of a client-side cache around a request,
+// for the [Transport] to record in Entry.Cache. It is given the request and
+// the response, whose body may not have been read yet, and returns the
+// cache state before and after the request, including hit counts. A nil
+// result records an empty cache object.
+type CacheObserver interface {
+	ObserveCache(req *http.Request, resp *http.Response) *harfile.Cache
+}
+
+// CacheObserverFunc adapts a function to a [CacheObserver].
+type CacheObserverFunc func(req *http.Request, resp *http.Response) *harfile.Cache
+
+// ObserveCache calls f(req, resp).
+func (f CacheObserverFunc) ObserveCache(req *http.Request, resp *http.Response) *harfile.Cache {
+	return f(req, resp)
+}
+
+// HeaderCacheObserver is a [CacheObserver] describing the cache from the
+// headers of the exchange, with [CacheFromResponse], for clients without a
+// cache of their own to report.
+var HeaderCacheObserver CacheObserver = CacheObserverFunc(func(req *http.Request, resp *http.Response) *harfile.Cache {
+	return CacheFromResponse(resp, time.Now())
+})
+
+// CacheFromHeaders describes how a private cache would store the response,
+// received at now, given the request headers reqH and response headers
+// respH. The afterRequest state is set unless Cache-Control forbids storing
+// the response (no-store): its eTag comes from ETag, and its expires from
+// Cache-Control max-age minus Age, or from Expires. A response already
+// stale is given an expiration of now. lastAccess is now and hitCount 0, as
+// the entry has just been written.
+func CacheFromHeaders(reqH, respH http.Header, now time.Time) *harfile.Cache {
+	return cacheFromHeaders(0, reqH, respH, now)
+}
+
+// CacheFromResponse is like [CacheFromHeaders], reading the headers of resp
+// and of its request. A 304 Not Modified response is flagged as a
+// validation of the cached copy: beforeRequest describes the copy the
+// client asked about, with the ETag sent in If-None-Match, afterRequest the
+// refreshed copy, opened once more, and Cache.Comment is
+// [ValidatedComment].
+func CacheFromResponse(resp *http.Response, now time.Time) *harfile.Cache {
+	var reqH http.Header
+	if resp.Request != nil {
+		reqH = resp.Request.Header
+	}
+	return cacheFromHeaders(resp.StatusCode, reqH, resp.Header, now)
+}
+
+func cacheFromHeaders(status int, reqH, respH http.Header, now time.Time) *harfile.Cache {
+	c := &harfile.Cache{}
+	lastAccess := now.Format(harfile.ISO8601)
+	if status == http.StatusNotModified {
+		c.Comment = ValidatedComment
+		c.BeforeRequest = &harfile.CacheData{
+			LastAccess: lastAccess,
+			ETag:       strings.TrimSpace(reqH.Get("If-None-Match")),
+		}
+	}
+	directives := cacheControl(strings.Join(respH.Values("Cache-Control"), ","))
+	_, noStore := directives["no-store"]
+	_, noStoreAsked := cacheControl(reqH.Get("Cache-Control"))["no-store"]
+	if noStore || noStoreAsked {
+		return c
+	}
+
+	after := &harfile.CacheData{
+		LastAccess: lastAccess,
+		ETag:       respH.Get("ETag"),
+	}
+	if after.ETag == "" && c.BeforeRequest != nil {
+		after.ETag = c.BeforeRequest.ETag
+	}
+	if c.BeforeRequest != nil {
+		after.HitCount = 1
+	}
+	if expires, ok := cacheExpiry(directives, respH, now); ok {
+		after.Expires = expires.Format(harfile.ISO8601)
+	}
+	c.AfterRequest = after
+	return c
+}
+
+// cacheExpiry returns when a response received at now stops being fresh,
+// if its headers say.
+func cacheExpiry(directives map[string]string, respH http.Header, now time.Time) (time.Time, bool) {
+	if _, ok := directives["no-cache"]; ok {
+		return now, true
+	}
+	if v, ok := directives["max-age"]; ok {
+		maxAge, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			age, _ := strconv.ParseInt(strings.TrimSpace(respH.Get("Age")), 10, 64)
+			return now.Add(time.Duration(max(maxAge-max(age, 0), 0)) * time.Second), true
+		}
+	}
+	if v := respH.Get("Expires"); v != "" {
+		t, err := http.ParseTime(v)
+		if err != nil || t.Before(now) {
+			// An invalid date, such as "0", means already expired.
+			return now, true
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// cacheControl parses a Cache-Control header into its directives, lower
+// cased, with their unquoted argument.
+func cacheControl(v string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(v, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return directives
+}
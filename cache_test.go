@@ -0,0 +1,141 @@
+package harkit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestCacheFromHeaders(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) string { return now.Add(d).Format(harfile.ISO8601) }
+	tests := []struct {
+		name    string
+		req     http.Header
+		resp    http.Header
+		stored  bool
+		expires string
+		eTag    string
+	}{
+		{"no headers", nil, http.Header{}, true, "", ""},
+		{"max-age", nil, http.Header{"Cache-Control": {"public, max-age=60"}, "Etag": {`"v1"`}}, true, at(time.Minute), `"v1"`},
+		{"max-age minus age", nil, http.Header{"Cache-Control": {"max-age=60"}, "Age": {"45"}}, true, at(15 * time.Second), ""},
+		{"older than max-age", nil, http.Header{"Cache-Control": {"max-age=60"}, "Age": {"90"}}, true, at(0), ""},
+		{"several headers", nil, http.Header{"Cache-Control": {"private", `MAX-AGE="30"`}}, true, at(30 * time.Second), ""},
+		{"max-age over expires", nil, http.Header{"Cache-Control": {"max-age=10"}, "Expires": {"Mon, 01 Jan 2024 13:00:00 GMT"}}, true, at(10 * time.Second), ""},
+		{"expires", nil, http.Header{"Expires": {"Mon, 01 Jan 2024 13:00:00 GMT"}}, true, at(time.Hour), ""},
+		{"expires in the past", nil, http.Header{"Expires": {"Mon, 01 Jan 2024 11:00:00 GMT"}}, true, at(0), ""},
+		{"invalid expires", nil, http.Header{"Expires": {"0"}}, true, at(0), ""},
+		{"bad max-age falls back", nil, http.Header{"Cache-Control": {"max-age=soon"}, "Expires": {"Mon, 01 Jan 2024 13:00:00 GMT"}}, true, at(time.Hour), ""},
+		{"no-cache", nil, http.Header{"Cache-Control": {"no-cache, max-age=60"}}, true, at(0), ""},
+		{"no-store", nil, http.Header{"Cache-Control": {"No-Store"}, "Etag": {`"v1"`}}, false, "", ""},
+		{"no-store asked", http.Header{"Cache-Control": {"no-store"}}, http.Header{"Cache-Control": {"max-age=60"}}, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CacheFromHeaders(tt.req, tt.resp, now)
+			if c.BeforeRequest != nil || c.Comment != "" {
+				t.Errorf("beforeRequest %+v, comment %q", c.BeforeRequest, c.Comment)
+			}
+			a := c.AfterRequest
+			if (a != nil) != tt.stored {
+				t.Fatalf("afterRequest = %+v, want stored %v", a, tt.stored)
+			}
+			if a == nil {
+				return
+			}
+			if a.Expires != tt.expires || a.ETag != tt.eTag || a.LastAccess != at(0) || a.HitCount != 0 {
+				t.Errorf("afterRequest = %+v, want expires %q, eTag %q", *a, tt.expires, tt.eTag)
+			}
+		})
+	}
+}
+
+func TestCacheFromResponse(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	req.Header.Set("If-None-Match", ` "v1" `)
+	resp := &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{"Cache-Control": {"max-age=60"}}, Request: req}
+
+	c := CacheFromResponse(resp, now)
+	if c.Comment != ValidatedComment {
+		t.Errorf("comment = %q", c.Comment)
+	}
+	if b := c.BeforeRequest; b == nil || b.ETag != `"v1"` || b.LastAccess != "2024-01-01T12:00:00.000Z" {
+		t.Errorf("beforeRequest = %+v", b)
+	}
+	if a := c.AfterRequest; a == nil || a.ETag != `"v1"` || a.HitCount != 1 || a.Expires != "2024-01-01T12:01:00.000Z" {
+		t.Errorf("afterRequest = %+v", a)
+	}
+
+	// A new ETag replaces the one sent, and a fresh response is no
+	// validation.
+	resp.Header.Set("ETag", `"v2"`)
+	if a := CacheFromResponse(resp, now).AfterRequest; a.ETag != `"v2"` {
+		t.Errorf("eTag = %q", a.ETag)
+	}
+	resp.StatusCode = http.StatusOK
+	if c := CacheFromResponse(resp, now); c.BeforeRequest != nil || c.Comment != "" || c.AfterRequest.HitCount != 0 {
+		t.Errorf("200 response = %+v", c)
+	}
+	resp.Request = nil
+	if c := CacheFromResponse(resp, now); c.AfterRequest == nil {
+		t.Error("response without request not stored")
+	}
+}
+
+func TestTransportCacheObserver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer srv.Close()
+
+	get := func(tr *Transport, etag string) *harfile.Cache {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		entries := tr.HAR().Log.Entries
+		return entries[len(entries)-1].Cache
+	}
+
+	if c := get(NewTransport(nil), ""); c == nil || c.BeforeRequest != nil || c.AfterRequest != nil {
+		t.Errorf("cache without observer = %+v", c)
+	}
+
+	tr := NewTransport(nil, WithCacheObserver(HeaderCacheObserver))
+	if c := get(tr, ""); c.AfterRequest == nil || c.AfterRequest.ETag != `"v1"` || c.AfterRequest.Expires == "" {
+		t.Errorf("first response cache = %+v", c)
+	}
+	if c := get(tr, `"v1"`); c.Comment != ValidatedComment || c.AfterRequest.HitCount != 1 {
+		t.Errorf("revalidated response cache = %+v", c)
+	}
+
+	calls := 0
+	nilObserver := CacheObserverFunc(func(req *http.Request, resp *http.Response) *harfile.Cache {
+		calls++
+		if req.URL.String() != srv.URL || resp.StatusCode != 200 {
+			t.Errorf("observed %s, status %d", req.URL, resp.StatusCode)
+		}
+		return nil
+	})
+	if c := get(NewTransport(nil, WithCacheObserver(nilObserver)), ""); c == nil || c.AfterRequest != nil || calls != 1 {
+		t.Errorf("nil observation = %+v after %d calls", c, calls)
+	}
+}
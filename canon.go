@@ -0,0 +1,119 @@
+package harkit
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// DefaultVolatileHeaders are the headers removed by [Canonicalize] when
+// CanonOptions.VolatileHeaders is nil.
+var DefaultVolatileHeaders = []string{"Date", "X-Request-Id", "CF-Ray", "Age"}
+
+// CanonOptions configures [Canonicalize].
+type CanonOptions struct {
+	VolatileHeaders []string // Headers removed, compared case insensitively. Nil means DefaultVolatileHeaders.
+	KeepCookies     bool     // Keep cookies, and the Cookie and Set-Cookie headers.
+	IgnoreJSONPaths []string // Values removed from JSON bodies, e.g. "$.meta.timestamp", see harfile.PostData.JSONPath.
+}
+
+// Canonicalize returns a copy of har stripped of what differs from one run
+// to the next, so that captures of the same traffic compare equal:
+//   - start times, times and timings are zeroed, in entries and pages, and
+//     the cache state, server address, connection and header sizes cleared;
+//   - volatile headers are removed, and cookies unless opts.KeepCookies;
+//   - headers and query parameters are sorted by name, then value;
+//   - JSON bodies are re-encoded by [harfile.CanonicalJSON], without the
+//     opts.IgnoreJSONPaths values, their sizes following.
+//
+// Request URLs are kept as recorded. har is not modified.
+func Canonicalize(har *harfile.HAR, opts CanonOptions) *harfile.HAR {
+	out := har.Clone()
+	if out == nil || out.Log == nil {
+		return out
+	}
+	volatile := opts.VolatileHeaders
+	if volatile == nil {
+		volatile = DefaultVolatileHeaders
+	}
+	if !opts.KeepCookies {
+		volatile = append(slices.Clip(volatile), "Cookie", "Set-Cookie")
+	}
+
+	for _, p := range out.Log.Pages {
+		if p == nil {
+			continue
+		}
+		p.StartedDateTime = time.Time{}
+		if p.PageTimings != nil {
+			p.PageTimings = &harfile.PageTimings{OnContentLoad: -1, OnLoad: -1}
+		}
+	}
+	for _, e := range out.Log.Entries {
+		if e == nil {
+			continue
+		}
+		e.StartedDateTime = time.Time{}
+		e.Time = 0
+		e.Timings = &harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}
+		e.Cache = &harfile.Cache{}
+		e.ServerIPAddress, e.Connection = "", ""
+
+		if req := e.Request; req != nil {
+			req.Headers = canonHeaders(req.Headers, volatile)
+			req.HeadersSize = -1
+			req.QueryString = canonHeaders(req.QueryString, nil)
+			if !opts.KeepCookies {
+				req.Cookies = []*harfile.Cookie{}
+			}
+			if pd := req.PostData; pd != nil && isJSONMime(pd.MimeType) {
+				if r, _, err := pd.BodyReader(); err == nil {
+					body, _ := io.ReadAll(r)
+					if canon, err := harfile.CanonicalJSON(body, opts.IgnoreJSONPaths...); err == nil {
+						pd.Text, pd.Encoding = string(canon), ""
+						req.BodySize = int64(len(canon))
+					}
+				}
+			}
+		}
+		if resp := e.Response; resp != nil {
+			encoding := headerValue(resp.Headers, "Content-Encoding")
+			resp.Headers = canonHeaders(resp.Headers, volatile)
+			resp.HeadersSize = -1
+			if !opts.KeepCookies {
+				resp.Cookies = []*harfile.Cookie{}
+			}
+			if c := resp.Content; c != nil && isJSONMime(c.MimeType) {
+				if body, err := c.DecodeBody(encoding); err == nil {
+					if canon, err := harfile.CanonicalJSON(body, opts.IgnoreJSONPaths...); err == nil {
+						c.Text, c.Encoding = string(canon), ""
+						c.Size, c.Compression = int64(len(canon)), 0
+						resp.BodySize = -1
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// canonHeaders returns headers, or query parameters, without the volatile
+// ones, sorted by name, then value.
+func canonHeaders(headers []*harfile.NameValuePair, volatile []string) []*harfile.NameValuePair {
+	out := slices.DeleteFunc(headers, func(h *harfile.NameValuePair) bool {
+		return h == nil || containsFold(volatile, h.Name)
+	})
+	slices.SortStableFunc(out, compareNameValues)
+	return out
+}
+
+func compareNameValues(a, b *harfile.NameValuePair) int {
+	return cmp.Or(
+		cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)),
+		cmp.Compare(a.Value, b.Value),
+	)
+}
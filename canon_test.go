@@ -0,0 +1,164 @@
+package harkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// canonHAR returns a capture of the same traffic as recorded by run n: the
+// times, volatile headers, cookies, header order and JSON member order
+// change from one run to the next.
+func canonHAR(n int) *harfile.HAR {
+	start := time.Date(2024, 1, 1, 0, 0, n, 0, time.UTC)
+	body := fmt.Sprintf(`{"id":1,"meta":{"generatedAt":"%d"},"name":"ada"}`, n)
+	query := []*harfile.NameValuePair{{Name: "b", Value: "2"}, {Name: "a", Value: "1"}}
+	if n%2 == 1 {
+		body = fmt.Sprintf(`{ "name": "ada", "meta": {"generatedAt": "%d"}, "id": 1 }`, n)
+		query = []*harfile.NameValuePair{query[1], query[0]}
+	}
+	e := &harfile.Entry{
+		StartedDateTime: start,
+		Time:            float64(10 * n),
+		Request: &harfile.Request{
+			Method: "POST",
+			URL:    "https://example.com/users?b=2&a=1",
+			Headers: []*harfile.NameValuePair{
+				{Name: "Cookie", Value: fmt.Sprintf("sid=%d", n)},
+				{Name: "Content-Type", Value: "application/json"},
+				{Name: "Accept", Value: "*/*"},
+			},
+			Cookies:     []*harfile.Cookie{{Name: "sid", Value: fmt.Sprint(n)}},
+			QueryString: query,
+			PostData:    &harfile.PostData{MimeType: "application/json", Text: body},
+			HeadersSize: int64(100 + n),
+		},
+		Response: &harfile.Response{
+			Status: 200,
+			Headers: []*harfile.NameValuePair{
+				{Name: "Date", Value: start.Format(time.RFC1123)},
+				{Name: "x-request-id", Value: fmt.Sprint(n)},
+				{Name: "Set-Cookie", Value: fmt.Sprintf("sid=%d", n)},
+				{Name: "Content-Type", Value: "application/json"},
+			},
+			Cookies:     []*harfile.Cookie{{Name: "sid", Value: fmt.Sprint(n)}},
+			Content:     &harfile.Content{MimeType: "application/json", Text: body, Size: int64(len(body))},
+			HeadersSize: int64(200 + n),
+		},
+		Cache:           &harfile.Cache{BeforeRequest: &harfile.CacheData{LastAccess: start.Format(time.RFC3339), HitCount: int64(n)}},
+		Timings:         &harfile.Timings{Blocked: float64(n), DNS: -1, Connect: -1, Ssl: -1, Wait: float64(10 * n)},
+		ServerIPAddress: fmt.Sprintf("203.0.113.%d", n),
+		Connection:      fmt.Sprint(5000 + n),
+	}
+	return &harfile.HAR{Log: &harfile.Log{
+		Version: "1.2",
+		Creator: &harfile.Creator{Name: "test", Version: "1"},
+		Pages:   []*harfile.Page{{ID: "page_1", StartedDateTime: start, PageTimings: &harfile.PageTimings{OnLoad: float64(n)}}},
+		Entries: []*harfile.Entry{e, nil},
+	}}
+}
+
+func marshalCanon(t *testing.T, h *harfile.HAR) string {
+	t.Helper()
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCanonicalize(t *testing.T) {
+	opts := CanonOptions{IgnoreJSONPaths: []string{"$.meta.generatedAt"}}
+	a, b := Canonicalize(canonHAR(1), opts), Canonicalize(canonHAR(2), opts)
+	if marshalCanon(t, a) != marshalCanon(t, b) {
+		t.Errorf("canonical forms differ:\n%s\n%s", marshalCanon(t, a), marshalCanon(t, b))
+	}
+
+	e := a.Log.Entries[0]
+	var headers []string
+	for _, h := range append(e.Request.Headers, e.Response.Headers...) {
+		headers = append(headers, h.Name)
+	}
+	if got := strings.Join(headers, " "); got != "Accept Content-Type Content-Type" {
+		t.Errorf("headers = %q", got)
+	}
+	if q := e.Request.QueryString; q[0].Name != "a" || q[1].Name != "b" || e.Request.URL != "https://example.com/users?b=2&a=1" {
+		t.Errorf("query %v, url %s", q, e.Request.URL)
+	}
+	if want := `{"id":1,"meta":{},"name":"ada"}`; e.Request.PostData.Text != want || e.Response.Content.Text != want {
+		t.Errorf("bodies = %s, %s", e.Request.PostData.Text, e.Response.Content.Text)
+	}
+	if len(e.Request.Cookies) != 0 || len(e.Response.Cookies) != 0 || e.Time != 0 || e.Timings.Blocked != -1 || e.Timings.Wait != 0 ||
+		e.ServerIPAddress != "" || e.Connection != "" || e.Cache.BeforeRequest != nil || e.Request.HeadersSize != -1 {
+		t.Errorf("volatile fields kept: %+v", e)
+	}
+	if p := a.Log.Pages[0]; !p.StartedDateTime.IsZero() || p.PageTimings.OnLoad != -1 {
+		t.Errorf("page = %+v", p)
+	}
+
+	// Without the ignored path, the generated values still differ.
+	if marshalCanon(t, Canonicalize(canonHAR(1), CanonOptions{})) == marshalCanon(t, Canonicalize(canonHAR(2), CanonOptions{})) {
+		t.Error("differing bodies compare equal")
+	}
+}
+
+func TestCanonicalizeOriginal(t *testing.T) {
+	h := canonHAR(1)
+	before := h.Clone()
+	Canonicalize(h, CanonOptions{IgnoreJSONPaths: []string{"meta"}, VolatileHeaders: []string{"Accept"}})
+	if !reflect.DeepEqual(h, before) {
+		t.Errorf("Canonicalize modified its argument:\n%s\nwant:\n%s", marshalCanon(t, h), marshalCanon(t, before))
+	}
+	if !reflect.DeepEqual(DefaultVolatileHeaders, []string{"Date", "X-Request-Id", "CF-Ray", "Age"}) {
+		t.Errorf("DefaultVolatileHeaders = %q", DefaultVolatileHeaders)
+	}
+}
+
+func TestCanonicalizeOptions(t *testing.T) {
+	c := Canonicalize(canonHAR(1), CanonOptions{KeepCookies: true, VolatileHeaders: []string{"content-type"}})
+	e := c.Log.Entries[0]
+	var headers []string
+	for _, h := range append(e.Request.Headers, e.Response.Headers...) {
+		headers = append(headers, h.Name)
+	}
+	if got := strings.Join(headers, " "); got != "Accept Cookie Date Set-Cookie x-request-id" {
+		t.Errorf("headers = %q", got)
+	}
+	if len(e.Request.Cookies) != 1 || len(e.Response.Cookies) != 1 {
+		t.Errorf("cookies = %v, %v", e.Request.Cookies, e.Response.Cookies)
+	}
+
+	if Canonicalize(nil, CanonOptions{}) != nil {
+		t.Error("nil HAR")
+	}
+	if c := Canonicalize(&harfile.HAR{}, CanonOptions{}); c == nil || c.Log != nil {
+		t.Errorf("HAR without log = %+v", c)
+	}
+}
+
+func TestDiffCanon(t *testing.T) {
+	a, b := canonHAR(1), canonHAR(2)
+	opts := CanonOptions{IgnoreJSONPaths: []string{"$.meta"}}
+	r := Diff(a, b, DiffOptions{Canon: &opts, SizeThreshold: 1, TimeThreshold: 100})
+	if len(r.Unchanged) != 1 || r.Unchanged[0].A != a.Log.Entries[0] || r.Unchanged[0].TimeDelta != 10 || r.Unchanged[0].ContentChanged {
+		t.Errorf("report = %s, unchanged %+v", r, r.Unchanged)
+	}
+
+	b.Log.Entries[0].Response.Content.Text = `{"id":2,"name":"ada"}`
+	r = Diff(a, b, DiffOptions{Canon: &opts, SizeThreshold: 1, TimeThreshold: 100})
+	if len(r.Changed) != 1 || !r.Changed[0].ContentChanged || !strings.Contains(r.String(), "content changed") {
+		t.Errorf("report = %s", r)
+	}
+	// Without Canon, bodies are not compared.
+	if r := Diff(a, b, DiffOptions{SizeThreshold: 1, TimeThreshold: 100}); len(r.Unchanged) != 1 || r.Unchanged[0].ContentChanged {
+		t.Errorf("report without Canon = %s", r)
+	}
+	if canonHAR(1).Log.Entries[0].Request.URL != a.Log.Entries[0].Request.URL || a.Log.Entries[0].Time != 10 {
+		t.Error("Diff modified its arguments")
+	}
+}
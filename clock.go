@@ -0,0 +1,147 @@
+package harkit
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Clock tells the time to the recorders, see [WithClock] and
+// [RecorderOptions].Clock. Implementations must be safe for concurrent
+// use.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer // A timer firing once d has elapsed.
+}
+
+// Timer is a timer created by a [Clock].
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool // Prevents the timer from firing, reporting whether it was pending.
+}
+
+// RealClock is the system clock, used by default.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+
+func (t realTimer) Stop() bool { return t.t.Stop() }
+
+// FakeClock is a [Clock] that only moves when told to, for tests: with it,
+// the dates and timings recorded are fully deterministic, every phase
+// lasting as long as the clock was advanced during it.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer firing once the clock has been advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers that fall due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.timers = slices.DeleteFunc(c.timers, func(t *fakeTimer) bool {
+		if t.at.After(c.now) {
+			return false
+		}
+		t.c <- t.at
+		return true
+	})
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.Index(c.timers, t)
+	if i < 0 {
+		return false
+	}
+	c.timers = slices.Delete(c.timers, i, i+1)
+	return true
+}
+
+// WithClock has the recorder tell the time with c instead of [RealClock],
+// e.g. a [FakeClock] for golden files. The [Recorder] the recorder creates
+// uses it too.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithTimingPrecision rounds the StartedDateTime and the timings of each
+// entry recorded to precision, e.g. time.Millisecond, so that captures
+// made with the real clock are stable under diff at that level. Time is
+// recomputed from the rounded timings. Zero, the default, keeps the
+// measured values.
+func WithTimingPrecision(precision time.Duration) Option {
+	return func(o *options) {
+		o.precision = precision
+	}
+}
+
+// roundTimings rounds the dates and timings of e to the precision of the
+// options, if any.
+func (o *options) roundTimings(e *harfile.Entry) {
+	if o.precision <= 0 {
+		return
+	}
+	e.StartedDateTime = e.StartedDateTime.Round(o.precision)
+	if e.Timings == nil {
+		return
+	}
+	unit := float64(o.precision) / float64(time.Millisecond)
+	t := e.Timings
+	for _, phase := range []*float64{&t.Blocked, &t.DNS, &t.Connect, &t.Ssl, &t.Send, &t.Wait, &t.Receive} {
+		if *phase > 0 {
+			*phase = math.Round(*phase/unit) * unit
+		}
+	}
+	e.ComputeTime()
+}
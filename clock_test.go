@@ -0,0 +1,187 @@
+package harkit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+var clockStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// roundTripFunc adapts a function to an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// fakeClockCapture records a request through a Middleware and a Transport
+// driven by a FakeClock, every step taking a fixed time, and returns the
+// encoded HAR.
+func fakeClockCapture(t *testing.T) []byte {
+	t.Helper()
+	clock := NewFakeClock(clockStart)
+	rec := NewRecorder(RecorderOptions{Clock: clock})
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clock.Advance(40 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		clock.Advance(5 * time.Millisecond)
+		io.WriteString(w, "served")
+	}), WithClock(clock), WithRecorder(rec))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/served", nil))
+
+	// The base transport reports its progress through the request's trace,
+	// as http.Transport does, every phase taking a fixed time.
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		trace := httptrace.ContextClientTrace(req.Context())
+		trace.GetConn(req.URL.Host)
+		clock.Advance(2 * time.Millisecond)
+		trace.ConnectStart("tcp", "192.0.2.1:80")
+		clock.Advance(10 * time.Millisecond)
+		trace.ConnectDone("tcp", "192.0.2.1:80", nil)
+		trace.GotConn(httptrace.GotConnInfo{})
+		clock.Advance(time.Millisecond)
+		trace.WroteRequest(httptrace.WroteRequestInfo{})
+		clock.Advance(25 * time.Millisecond)
+		trace.GotFirstResponseByte()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("fetched")),
+			Request:    req,
+		}, nil
+	})
+	client := &http.Client{Transport: NewTransport(base, WithClock(clock), WithRecorder(rec))}
+	clock.Advance(time.Second)
+	resp, err := client.Get("http://example.com/fetched")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(3 * time.Millisecond)
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	data, err := json.MarshalIndent(rec.Snapshot(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(data, '\n')
+}
+
+// TestFakeClockDeterministic checks that captures made with a FakeClock are
+// identical from run to run, and match a golden file.
+func TestFakeClockDeterministic(t *testing.T) {
+	first, second := fakeClockCapture(t), fakeClockCapture(t)
+	if string(first) != string(second) {
+		t.Fatalf("captures differ:\n%s\n%s", first, second)
+	}
+	checkGolden(t, "fake_clock.har", first)
+
+	h, err := harfile.Load(strings.NewReader(string(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	served, fetched := h.Log.Entries[0], h.Log.Entries[1]
+	if !served.StartedDateTime.Equal(clockStart) || served.Timings.Wait != 40 || served.Timings.Receive != 5 || served.Time != 45 {
+		t.Errorf("served at %v, timings %+v", served.StartedDateTime, served.Timings)
+	}
+	if want := clockStart.Add(1045 * time.Millisecond); !fetched.StartedDateTime.Equal(want) || fetched.Time != 41 ||
+		fetched.Timings.Blocked != 2 || fetched.Timings.Connect != 10 || fetched.Timings.Send != 1 || fetched.Timings.Wait != 25 || fetched.Timings.Receive != 3 {
+		t.Errorf("fetched at %v, time %v, timings %+v", fetched.StartedDateTime, fetched.Time, fetched.Timings)
+	}
+}
+
+func TestFakeClockTimers(t *testing.T) {
+	clock := NewFakeClock(clockStart)
+	fired := func(tm Timer) bool {
+		select {
+		case <-tm.C():
+			return true
+		default:
+			return false
+		}
+	}
+	now := clock.NewTimer(0)
+	short, long, stopped := clock.NewTimer(time.Second), clock.NewTimer(time.Minute), clock.NewTimer(time.Second)
+	if !fired(now) {
+		t.Error("timer of 0 did not fire at once")
+	}
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Stop did not report the pending timer once")
+	}
+	clock.Advance(999 * time.Millisecond)
+	if fired(short) {
+		t.Error("timer fired early")
+	}
+	clock.Advance(time.Millisecond)
+	if !fired(short) || fired(long) || fired(stopped) {
+		t.Error("Advance fired the wrong timers")
+	}
+	if short.Stop() {
+		t.Error("Stop reported a fired timer as pending")
+	}
+	if got := clock.Now(); !got.Equal(clockStart.Add(time.Second)) {
+		t.Errorf("Now = %v", got)
+	}
+}
+
+func TestTimingPrecision(t *testing.T) {
+	tests := []struct {
+		precision time.Duration
+		started   time.Time
+		timings   harfile.Timings
+		want      harfile.Timings
+		time      float64
+	}{
+		{
+			precision: 0,
+			started:   clockStart.Add(1234567 * time.Nanosecond),
+			timings:   harfile.Timings{Blocked: 0.4, DNS: -1, Connect: 1.26, Ssl: -1, Send: 0.05, Wait: 10.51, Receive: 2.49},
+			want:      harfile.Timings{Blocked: 0.4, DNS: -1, Connect: 1.26, Ssl: -1, Send: 0.05, Wait: 10.51, Receive: 2.49},
+			time:      14.71,
+		},
+		{
+			precision: time.Millisecond,
+			started:   clockStart.Add(1234567 * time.Nanosecond),
+			timings:   harfile.Timings{Blocked: 0.4, DNS: -1, Connect: 1.26, Ssl: -1, Send: 0.05, Wait: 10.51, Receive: 2.49},
+			want:      harfile.Timings{Blocked: 0, DNS: -1, Connect: 1, Ssl: -1, Send: 0, Wait: 11, Receive: 2},
+			time:      14,
+		},
+		{
+			precision: 10 * time.Millisecond,
+			started:   clockStart.Add(1234567 * time.Nanosecond),
+			timings:   harfile.Timings{Blocked: -1, DNS: 4, Connect: 16, Ssl: 9, Send: 0, Wait: 125, Receive: 3},
+			want:      harfile.Timings{Blocked: -1, DNS: 0, Connect: 20, Ssl: 10, Send: 0, Wait: 130, Receive: 0},
+			time:      150,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.precision.String(), func(t *testing.T) {
+			timings := tt.timings
+			e := &harfile.Entry{StartedDateTime: tt.started, Timings: &timings}
+			e.ComputeTime()
+			newOptions([]Option{WithTimingPrecision(tt.precision)}).roundTimings(e)
+			if want := tt.started.Round(max(tt.precision, 1)); !e.StartedDateTime.Equal(want) {
+				t.Errorf("StartedDateTime = %v, want %v", e.StartedDateTime, want)
+			}
+			got := *e.Timings
+			if got.Blocked != tt.want.Blocked || got.DNS != tt.want.DNS || got.Connect != tt.want.Connect || got.Ssl != tt.want.Ssl ||
+				got.Send != tt.want.Send || got.Wait != tt.want.Wait || got.Receive != tt.want.Receive {
+				t.Errorf("timings = %+v, want %+v", *e.Timings, tt.want)
+			}
+			if d := e.Time - tt.time; d > 1e-9 || d < -1e-9 {
+				t.Errorf("Time = %v, want %v", e.Time, tt.time)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package harkit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"go/format"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// CodegenOptions configures [GenerateStubs].
+type CodegenOptions struct {
+	Package     string   // Package of the generated file. Empty means "stubs".
+	FuncName    string   // Name of the generated handler constructor. Empty means "NewStubHandler".
+	Headers     []string // Response headers to reproduce. Nil means Content-Type only.
+	MaxBodySize int64    // Bodies larger than this many bytes are left out, with a comment. Zero keeps every body.
+}
+
+// GenerateStubs returns a gofmt-formatted Go source file defining a
+// constructor of an [http.Handler] that serves the recorded responses of
+// log: one route per method and path, answered with the status, selected
+// headers and decoded body of the first entry recorded for it. Hosts and
+// query strings are not part of the routes. Binary bodies are embedded
+// base64 encoded.
+func GenerateStubs(log *harfile.Log, opts CodegenOptions) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "stubs"
+	}
+	if opts.FuncName == "" {
+		opts.FuncName = "NewStubHandler"
+	}
+	if opts.Headers == nil {
+		opts.Headers = []string{"Content-Type"}
+	}
+
+	var routes, consts bytes.Buffer
+	seen := make(map[string]bool)
+	usesBase64 := false
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil || e.Response == nil || e.Response.Status < 100 {
+			continue
+		}
+		pattern, ok := routePattern(e.Request)
+		if !ok || seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+
+		fmt.Fprintf(&routes, "\tmux.HandleFunc(%q, func(w http.ResponseWriter, r *http.Request) {\n", pattern)
+		for _, name := range opts.Headers {
+			for _, h := range e.Response.Headers {
+				if h != nil && strings.EqualFold(h.Name, name) {
+					fmt.Fprintf(&routes, "\t\tw.Header().Add(%q, %q)\n", http.CanonicalHeaderKey(h.Name), h.Value)
+				}
+			}
+		}
+		fmt.Fprintf(&routes, "\t\tw.WriteHeader(%d)\n", e.Response.Status)
+
+		body, err := stubBody(e.Response)
+		if err != nil {
+			return nil, fmt.Errorf("harkit: %s: %w", pattern, err)
+		}
+		name := "body" + strconv.Itoa(len(seen))
+		switch {
+		case len(body) == 0:
+		case opts.MaxBodySize > 0 && int64(len(body)) > opts.MaxBodySize:
+			fmt.Fprintf(&routes, "\t\t// Body of %d bytes left out.\n", len(body))
+		case utf8.Valid(body):
+			fmt.Fprintf(&consts, "const %s = %s\n\n", name, quoteGo(string(body)))
+			fmt.Fprintf(&routes, "\t\tw.Write([]byte(%s))\n", name)
+		default:
+			usesBase64 = true
+			fmt.Fprintf(&consts, "var %s = mustDecode(%q)\n\n", name, base64.StdEncoding.EncodeToString(body))
+			fmt.Fprintf(&routes, "\t\tw.Write(%s)\n", name)
+		}
+		routes.WriteString("\t})\n")
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by harkit from a HAR capture. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+	if usesBase64 {
+		src.WriteString("import (\n\t\"encoding/base64\"\n\t\"net/http\"\n)\n\n")
+	} else {
+		src.WriteString("import \"net/http\"\n\n")
+	}
+	fmt.Fprintf(&src, "// %s returns a handler serving the responses recorded in the capture.\n", opts.FuncName)
+	fmt.Fprintf(&src, "func %s() http.Handler {\n\tmux := http.NewServeMux()\n", opts.FuncName)
+	src.Write(routes.Bytes())
+	src.WriteString("\treturn mux\n}\n\n")
+	src.Write(consts.Bytes())
+	if usesBase64 {
+		src.WriteString("func mustDecode(s string) []byte {\n\tb, err := base64.StdEncoding.DecodeString(s)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\treturn b\n}\n")
+	}
+	return format.Source(src.Bytes())
+}
+
+// routePattern returns the [http.ServeMux] pattern matching the method and
+// exact path of req. Paths that cannot be expressed as a pattern are
+// rejected.
+func routePattern(req *harfile.Request) (string, bool) {
+	u, err := url.Parse(req.URL)
+	if err != nil || strings.ContainsAny(u.Path, "{} \t") {
+		return "", false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if strings.HasSuffix(path, "/") {
+		path += "{$}" // Match the path only, not the subtree.
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return method + " " + path, true
+}
+
+// stubBody returns the decoded body of resp.
+func stubBody(resp *harfile.Response) ([]byte, error) {
+	if resp.Content == nil {
+		return nil, nil
+	}
+	var contentEncoding string
+	for _, h := range resp.Headers {
+		if h != nil && strings.EqualFold(h.Name, "Content-Encoding") {
+			contentEncoding = h.Value
+		}
+	}
+	return resp.Content.DecodeBody(contentEncoding)
+}
+
+// quoteGo returns s as a Go string literal, raw when that is readable.
+func quoteGo(s string) string {
+	if strings.ContainsAny(s, "`\r") || strings.ContainsFunc(s, func(r rune) bool { return r < ' ' && r != '\n' && r != '\t' }) {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,138 @@
+package harkit
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs, rerun with -update if intended:\n%s", name, got)
+	}
+}
+
+// typeCheck parses and type checks src against the standard library.
+func typeCheck(t *testing.T, src []byte) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "stubs.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, src)
+	}
+	return pkg
+}
+
+// stubEntry returns a recorded exchange answered with status, the body
+// of type mimeType and headers given as name, value pairs. A Content-Type
+// header is added for the body unless one is given.
+func stubEntry(method, url string, status int64, mimeType string, body []byte, headers ...string) *harfile.Entry {
+	resp := &harfile.Response{Status: status, HTTPVersion: "HTTP/1.1", Content: &harfile.Content{}}
+	hasType := false
+	for i := 0; i+1 < len(headers); i += 2 {
+		resp.Headers = append(resp.Headers, &harfile.NameValuePair{Name: headers[i], Value: headers[i+1]})
+		hasType = hasType || strings.EqualFold(headers[i], "Content-Type")
+	}
+	if mimeType != "" {
+		resp.Content.SetBody(body, mimeType)
+		if !hasType {
+			resp.Headers = append(resp.Headers, &harfile.NameValuePair{Name: "Content-Type", Value: mimeType})
+		}
+	}
+	return &harfile.Entry{
+		Request:  &harfile.Request{Method: method, URL: url, HTTPVersion: "HTTP/1.1"},
+		Response: resp,
+	}
+}
+
+func stubsLog() *harfile.Log {
+	png := []byte{0x89, 'P', 'N', 'G', 0, 1, 2, 0xff}
+	return &harfile.Log{Entries: []*harfile.Entry{
+		stubEntry("GET", "https://example.com/", 200, "text/html", []byte("<h1>`home`</h1>\n"),
+			"Content-Type", "text/html", "Set-Cookie", "s=1"),
+		stubEntry("GET", "https://example.com/api/users?page=2", 200, "application/json", []byte(`{"users":[{"name":"ada"}]}`)),
+		// Same route as above, answered by the first entry.
+		stubEntry("GET", "https://example.com/api/users?page=3", 500, "", nil),
+		stubEntry("POST", "https://example.com/api/users", 201, "", nil, "Location", "/api/users/7"),
+		stubEntry("GET", "https://cdn.example.com/logo.png", 200, "image/png", png),
+		stubEntry("GET", "https://example.com/big", 200, "text/plain", bytes.Repeat([]byte("x"), 2048)),
+		stubEntry("GET", "https://example.com/{bad}", 200, "", nil),
+		nil,
+	}}
+}
+
+func TestGenerateStubs(t *testing.T) {
+	src, err := GenerateStubs(stubsLog(), CodegenOptions{
+		Package:     "apistubs",
+		Headers:     []string{"content-type", "Location"},
+		MaxBodySize: 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "stubs.go.golden", src)
+
+	pkg := typeCheck(t, src)
+	if pkg.Name() != "apistubs" || pkg.Scope().Lookup("NewStubHandler") == nil {
+		t.Errorf("package %s lacks NewStubHandler", pkg.Name())
+	}
+	for _, want := range []string{`"GET /{$}"`, `"GET /api/users"`, `"POST /api/users"`, `mustDecode(`, "Body of 2048 bytes left out"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code lacks %s", want)
+		}
+	}
+	for _, unwanted := range []string{"Set-Cookie", "{bad}", "WriteHeader(500)"} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("generated code contains %s", unwanted)
+		}
+	}
+}
+
+func TestGenerateStubsDefaults(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{stubEntry("GET", "https://example.com/health", 204, "", nil)}}
+	src, err := GenerateStubs(log, CodegenOptions{FuncName: "Health"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := typeCheck(t, src)
+	if pkg.Name() != "stubs" || pkg.Scope().Lookup("Health") == nil {
+		t.Errorf("package %s lacks Health", pkg.Name())
+	}
+	if strings.Contains(string(src), "base64") {
+		t.Error("base64 imported without binary bodies")
+	}
+
+	src, err = GenerateStubs(&harfile.Log{}, CodegenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeCheck(t, src)
+}
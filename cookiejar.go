@@ -0,0 +1,83 @@
+package harkit
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"slices"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// CookieJarFromHAR returns a jar holding the cookies set by the responses
+// of log, applied in order of start so that later values win. Set-Cookie
+// headers are preferred; responses without them fall back to their cookie
+// list. The jar applies Domain, Path, Secure and expiry rules itself, so
+// cookies already expired are left out.
+//
+// The jar has no public suffix list: domain cookies are accepted for any
+// parent domain of the response host.
+func CookieJarFromHAR(log *harfile.Log) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := slices.DeleteFunc(slices.Clone(log.Entries), func(e *harfile.Entry) bool {
+		return e == nil || e.Request == nil || e.Response == nil
+	})
+	slices.SortStableFunc(entries, func(a, b *harfile.Entry) int {
+		return a.StartedDateTime.Compare(b.StartedDateTime)
+	})
+
+	for _, e := range entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			continue
+		}
+		header := make(http.Header)
+		for _, h := range e.Response.Headers {
+			if h != nil && http.CanonicalHeaderKey(h.Name) == "Set-Cookie" {
+				header.Add("Set-Cookie", h.Value)
+			}
+		}
+		cookies := (&http.Response{Header: header}).Cookies()
+		if len(cookies) == 0 {
+			for _, c := range e.Response.Cookies {
+				if c != nil {
+					cookies = append(cookies, c.ToHTTP())
+				}
+			}
+		}
+		if len(cookies) > 0 {
+			jar.SetCookies(u, cookies)
+		}
+	}
+	return jar, nil
+}
+
+// CookiesSnapshot returns the cookies jar would send to each of urls.
+// [http.CookieJar] only exposes names and values, so Domain is set to the
+// host of the URL the cookie was returned for and Path to "/"; a cookie sent
+// to several of urls on the same host is listed once.
+func CookiesSnapshot(jar http.CookieJar, urls []*url.URL) []*harfile.Cookie {
+	type key struct{ host, name string }
+	seen := make(map[key]bool)
+	var cookies []*harfile.Cookie
+	for _, u := range urls {
+		for _, c := range jar.Cookies(u) {
+			k := key{u.Hostname(), c.Name}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			cookies = append(cookies, &harfile.Cookie{
+				Name:   c.Name,
+				Value:  c.Value,
+				Domain: u.Hostname(),
+				Path:   "/",
+				Secure: u.Scheme == "https",
+			})
+		}
+	}
+	return cookies
+}
@@ -0,0 +1,100 @@
+package harkit
+
+import (
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// setCookies returns a GET of url started s seconds in, answered with a
+// Set-Cookie header per cookie.
+func setCookies(url string, s int, cookies ...string) *harfile.Entry {
+	e := &harfile.Entry{
+		StartedDateTime: time.Date(2024, 1, 1, 0, 0, s, 0, time.UTC),
+		Request:         &harfile.Request{Method: "GET", URL: url},
+		Response:        &harfile.Response{Status: 200},
+	}
+	for _, c := range cookies {
+		e.Response.Headers = append(e.Response.Headers, &harfile.NameValuePair{Name: "Set-Cookie", Value: c})
+	}
+	return e
+}
+
+func TestCookieJarFromHAR(t *testing.T) {
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC1123)
+	listed := setCookies("https://example.com/list", 6)
+	listed.Response.Cookies = []*harfile.Cookie{{Name: "listed", Value: "1", Path: "/"}}
+
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		// Recorded out of order: the later value must win.
+		setCookies("https://example.com/login", 5, "session=new; Path=/"),
+		setCookies("https://example.com/", 1, "session=old; Path=/", "gone=1; Path=/"),
+		setCookies("https://www.example.com/", 2, "shared=d; Domain=example.com; Path=/", "hostonly=h; Path=/"),
+		setCookies("https://example.com/", 3, "gone=2; Path=/; Expires="+past),
+		setCookies("https://example.com/", 4, "secure=s; Path=/; Secure", "scoped=p; Path=/account"),
+		listed,
+		nil,
+	}}
+
+	jar, err := CookieJarFromHAR(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/", "listed=1 secure=s session=new shared=d"},
+		{"http://example.com/", "listed=1 session=new shared=d"},
+		{"https://example.com/account/settings", "listed=1 scoped=p secure=s session=new shared=d"},
+		{"https://www.example.com/", "hostonly=h shared=d"},
+		{"https://api.example.com/", "shared=d"},
+		{"https://other.net/", ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		var got []string
+		for _, c := range jar.Cookies(u) {
+			got = append(got, c.Name+"="+c.Value)
+		}
+		slices.Sort(got)
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("cookies for %s = %v, want %s", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCookiesSnapshot(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		setCookies("https://example.com/", 0, "a=1; Path=/", "b=2; Domain=example.com; Path=/"),
+	}}
+	jar, err := CookieJarFromHAR(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []*url.URL
+	for _, s := range []string{"https://example.com/", "https://example.com/other", "http://api.example.com/"} {
+		u, _ := url.Parse(s)
+		urls = append(urls, u)
+	}
+	got := CookiesSnapshot(jar, urls)
+	slices.SortFunc(got, func(a, b *harfile.Cookie) int {
+		return strings.Compare(a.Domain+a.Name, b.Domain+b.Name)
+	})
+	want := []*harfile.Cookie{
+		{Name: "b", Value: "2", Domain: "api.example.com", Path: "/"},
+		{Name: "a", Value: "1", Domain: "example.com", Path: "/", Secure: true},
+		{Name: "b", Value: "2", Domain: "example.com", Path: "/", Secure: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CookiesSnapshot =")
+		for _, c := range got {
+			t.Errorf("  %+v", *c)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package harkit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// CorpusOptions configures [OpenCorpus].
+type CorpusOptions struct {
+	Recursive   bool                 // Look for files in subdirectories too.
+	Pattern     string               // Glob the file names must match besides their extension, e.g. "nightly-*", see [filepath.Match]. Empty matches every file.
+	Concurrency int                  // Files read at once. Zero means 1.
+	FileOptions []harfile.FileOption // Options of the readers, e.g. harfile.WithSniffedMimeTypes().
+}
+
+// CorpusFile is a HAR file of a [Corpus].
+type CorpusFile struct {
+	Path string
+	Size int64        // Size of the file, compressed for a .har.gz file.
+	Log  *harfile.Log // Metadata stored before the entries, without entries. Nil when Err is set.
+	Err  error        // Why the file cannot be read, in which case it is skipped.
+}
+
+// Corpus is a set of HAR files, such as the captures of nightly runs, read
+// one entry at a time rather than loaded in memory, see [OpenCorpus].
+type Corpus struct {
+	opts  CorpusOptions
+	files []CorpusFile
+}
+
+// OpenCorpus finds the .har and .har.gz files of dir, and of its
+// subdirectories with opts.Recursive, and reads their log metadata. Files
+// that cannot be read are kept with their error, see [Corpus.Files], and
+// skipped afterwards; only a bad pattern or a directory that cannot be
+// listed fail OpenCorpus.
+func OpenCorpus(dir string, opts CorpusOptions) (*Corpus, error) {
+	if _, err := filepath.Match(opts.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("harkit: corpus pattern %q: %w", opts.Pattern, err)
+	}
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && !opts.Recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if isCorpusFile(d.Name(), opts.Pattern) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("harkit: corpus: %w", err)
+	}
+
+	c := &Corpus{opts: opts, files: make([]CorpusFile, len(paths))}
+	c.parallel(func(i int) bool {
+		f := &c.files[i]
+		f.Path = paths[i]
+		if info, err := os.Stat(f.Path); err == nil {
+			f.Size = info.Size()
+		}
+		f.Err = c.read(f, func(er *harfile.EntryReader) error {
+			f.Log = er.Log()
+			return er.Err()
+		})
+		if f.Err != nil {
+			f.Log = nil
+		}
+		return true
+	})
+	return c, nil
+}
+
+// isCorpusFile reports whether name is a HAR file matching pattern.
+func isCorpusFile(name, pattern string) bool {
+	lower := strings.ToLower(name)
+	if !strings.HasSuffix(lower, ".har") && !strings.HasSuffix(lower, ".har.gz") {
+		return false
+	}
+	ok, _ := filepath.Match(pattern, name)
+	return pattern == "" || ok
+}
+
+// Files returns the files of c, sorted by path, including those that
+// cannot be read.
+func (c *Corpus) Files() []CorpusFile {
+	return slices.Clone(c.files)
+}
+
+// Each calls fn with every entry of the readable files of c, streaming them
+// file by file with a [harfile.EntryReader]. Entries of a file are passed
+// in order; with opts.Concurrency above 1, several files are read at once
+// and fn must be safe for concurrent use.
+//
+// A file found malformed along the way does not stop the walk: its
+// remaining entries are skipped and its error, prefixed with its path, is
+// joined in the returned error. An error returned by fn stops the walk and
+// is returned as well.
+func (c *Corpus) Each(fn func(file string, e *harfile.Entry) error) error {
+	var (
+		stopped atomic.Bool
+		mu      sync.Mutex
+		stopErr error
+	)
+	err := c.walk(func(f *CorpusFile, er *harfile.EntryReader) (bool, error) {
+		for !stopped.Load() {
+			e, err := er.Next()
+			if errors.Is(err, io.EOF) {
+				return true, nil
+			}
+			if err != nil {
+				return true, err
+			}
+			if err := fn(f.Path, e); err != nil {
+				mu.Lock()
+				if stopErr == nil {
+					stopErr = err
+				}
+				mu.Unlock()
+				stopped.Store(true)
+			}
+		}
+		return false, nil
+	})
+	return errors.Join(stopErr, err)
+}
+
+// CorpusSummary aggregates the entries of a [Corpus], see
+// [Corpus.Summarize].
+type CorpusSummary struct {
+	Files        int                         // Files read entirely.
+	Entries      int                         // Entries of the files read, including those of malformed files read before the error.
+	Pages        int                         // Pages of the files read entirely.
+	Statuses     map[harfile.StatusClass]int // Responses per status class, StatusUnknown counting requests without a response.
+	Bytes        int64                       // Decoded response bytes, of the responses whose size is known.
+	Time         float64                     // Total Entry.Time, in milliseconds.
+	Hosts        map[string]int              // Entries per request host.
+	ContentTypes map[string]int              // Responses per declared media type.
+	Creators     map[string]int              // Files per creator name and version.
+	First, Last  time.Time                   // Earliest and latest StartedDateTime.
+}
+
+// Summarize streams every entry of c, as [Corpus.Each] does, and
+// aggregates them. Malformed files are skipped and their errors joined in
+// the returned error, along with the summary of the others.
+func (c *Corpus) Summarize() (*CorpusSummary, error) {
+	var mu sync.Mutex
+	s := &CorpusSummary{
+		Statuses:     make(map[harfile.StatusClass]int),
+		Hosts:        make(map[string]int),
+		ContentTypes: make(map[string]int),
+		Creators:     make(map[string]int),
+	}
+	err := c.walk(func(f *CorpusFile, er *harfile.EntryReader) (bool, error) {
+		for {
+			e, err := er.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return true, err
+			}
+			mu.Lock()
+			s.add(e)
+			mu.Unlock()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		s.Files++
+		s.Pages += len(er.Log().Pages)
+		if creator := er.Log().Creator; creator != nil {
+			s.Creators[strings.TrimSpace(creator.Name+" "+creator.Version)]++
+		}
+		return true, nil
+	})
+	return s, err
+}
+
+// add counts e in s.
+func (s *CorpusSummary) add(e *harfile.Entry) {
+	s.Entries++
+	s.Time += max(e.Time, 0)
+	if !e.StartedDateTime.IsZero() {
+		if s.First.IsZero() || e.StartedDateTime.Before(s.First) {
+			s.First = e.StartedDateTime
+		}
+		if e.StartedDateTime.After(s.Last) {
+			s.Last = e.StartedDateTime
+		}
+	}
+	if e.Request != nil {
+		if u, err := url.Parse(e.Request.URL); err == nil && u.Host != "" {
+			s.Hosts[u.Hostname()]++
+		}
+	}
+	s.Statuses[e.Response.Class()]++
+	if e.Response == nil {
+		return
+	}
+	if size, ok := e.Response.Content.KnownSize(); ok {
+		s.Bytes += size
+	}
+	if e.Response.Content != nil {
+		if mediaType, _, _ := mime.ParseMediaType(e.Response.Content.MimeType); mediaType != "" {
+			s.ContentTypes[mediaType]++
+		}
+	}
+}
+
+// walk calls visit with a reader of each readable file of c, with up to
+// opts.Concurrency files at once, until visit returns false. The errors
+// visit returns for the files are joined.
+func (c *Corpus) walk(visit func(f *CorpusFile, er *harfile.EntryReader) (bool, error)) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	c.parallel(func(i int) bool {
+		f := &c.files[i]
+		if f.Err != nil {
+			return true
+		}
+		more := true
+		err := c.read(f, func(er *harfile.EntryReader) error {
+			var err error
+			more, err = visit(f, er)
+			return err
+		})
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+		return more
+	})
+	return errors.Join(errs...)
+}
+
+// read opens f and calls fn with a reader of it. Errors name the path of
+// f.
+func (c *Corpus) read(f *CorpusFile, fn func(er *harfile.EntryReader) error) error {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := fn(harfile.NewEntryReader(file, c.opts.FileOptions...)); err != nil {
+		return fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// parallel calls fn with the index of each file of c, with up to
+// opts.Concurrency calls at once, until fn returns false.
+func (c *Corpus) parallel(fn func(i int) bool) {
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, max(c.opts.Concurrency, 1))
+		stopped atomic.Bool
+	)
+	for i := range c.files {
+		sem <- struct{}{}
+		if stopped.Load() {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if !fn(i) {
+				stopped.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+}
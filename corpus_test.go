@@ -0,0 +1,201 @@
+package harkit
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// corpusEntry returns an entry for url answered with status and body,
+// started at second sec and taking 10ms.
+func corpusEntry(url string, sec int, status int64, body string) *harfile.Entry {
+	e := stubEntry("GET", url, status, "text/plain; charset=utf-8", []byte(body))
+	e.StartedDateTime = time.Date(2024, 1, 1, 0, 0, sec, 0, time.UTC)
+	e.Time = 10
+	return e
+}
+
+// corpusDir writes a corpus to a new directory:
+//
+//	a.har          two entries and a page
+//	b.har.gz       one entry, compressed
+//	corrupt.har    not a HAR
+//	truncated.har  one entry, then cut off
+//	notes.txt      not a HAR file
+//	sub/c.har      one entry, found recursively
+func corpusDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name string, entries ...*harfile.Entry) []byte {
+		log := NewLog(WithCreator("nightly", "2"))
+		log.Pages = []*harfile.Page{{ID: "p1", Title: name}}
+		log.Entries = entries
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&harfile.HAR{Log: log}).WriteFile(path); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		return data
+	}
+	write("a.har", corpusEntry("https://example.com/", 5, 200, "hello"), corpusEntry("https://cdn.example.com/x", 1, 404, "missing"))
+	write("b.har.gz", corpusEntry("https://example.com/b", 9, 500, "oops"))
+	write("sub/c.har", corpusEntry("https://example.org/", 3, 200, "c"))
+	data := write("truncated.har", corpusEntry("https://example.com/t", 2, 200, "t"), corpusEntry("https://example.com/u", 2, 200, "u"))
+	cut := strings.Index(string(data), `"https://example.com/u"`)
+	if err := os.WriteFile(filepath.Join(dir, "truncated.har"), data[:cut], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range map[string]string{"corrupt.har": "not a HAR", "notes.txt": "{}"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestOpenCorpus(t *testing.T) {
+	dir := corpusDir(t)
+	tests := []struct {
+		opts  CorpusOptions
+		files string // Base names, with "!" after the unreadable ones.
+	}{
+		{CorpusOptions{}, "a.har b.har.gz corrupt.har! truncated.har"},
+		{CorpusOptions{Recursive: true, Concurrency: 4}, "a.har b.har.gz c.har corrupt.har! truncated.har"},
+		{CorpusOptions{Recursive: true, Pattern: "[ac]*"}, "a.har c.har corrupt.har!"},
+	}
+	for _, tt := range tests {
+		c, err := OpenCorpus(dir, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range c.Files() {
+			name := filepath.Base(f.Path)
+			if f.Err != nil {
+				name += "!"
+			}
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if got := strings.Join(names, " "); got != tt.files {
+			t.Errorf("%+v: files %s, want %s", tt.opts, got, tt.files)
+		}
+	}
+
+	c, err := OpenCorpus(dir, CorpusOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range c.Files() {
+		switch filepath.Base(f.Path) {
+		case "a.har":
+			if f.Log == nil || f.Log.Creator.Name != "nightly" || f.Log.Entries != nil || f.Size == 0 {
+				t.Errorf("a.har = %+v", f)
+			}
+		case "corrupt.har":
+			if f.Log != nil || !strings.HasPrefix(f.Err.Error(), f.Path+": ") {
+				t.Errorf("corrupt.har = %+v", f)
+			}
+		}
+	}
+
+	if _, err := OpenCorpus(dir, CorpusOptions{Pattern: "["}); err == nil {
+		t.Error("bad pattern accepted")
+	}
+	if _, err := OpenCorpus(filepath.Join(dir, "absent"), CorpusOptions{}); err == nil {
+		t.Error("missing directory accepted")
+	}
+}
+
+// TestCorpusSkipsCorrupt checks that unreadable files do not stop the
+// walk over the others.
+func TestCorpusSkipsCorrupt(t *testing.T) {
+	dir := corpusDir(t)
+	for _, concurrency := range []int{0, 3} {
+		c, err := OpenCorpus(dir, CorpusOptions{Recursive: true, Concurrency: concurrency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var mu sync.Mutex
+		var urls []string
+		err = c.Each(func(file string, e *harfile.Entry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			urls = append(urls, filepath.Base(file)+" "+e.Request.URL)
+			return nil
+		})
+		slices.Sort(urls)
+		want := []string{
+			"a.har https://cdn.example.com/x",
+			"a.har https://example.com/",
+			"b.har.gz https://example.com/b",
+			"c.har https://example.org/",
+			"truncated.har https://example.com/t",
+		}
+		if !slices.Equal(urls, want) {
+			t.Errorf("concurrency %d: entries %q, want %q", concurrency, urls, want)
+		}
+		// Only the file found malformed while streaming is reported; the
+		// corrupt one was set aside by OpenCorpus.
+		if err == nil || !strings.Contains(err.Error(), "truncated.har: ") || strings.Contains(err.Error(), "corrupt.har") {
+			t.Errorf("concurrency %d: Each = %v", concurrency, err)
+		}
+	}
+}
+
+func TestCorpusEachStops(t *testing.T) {
+	c, err := OpenCorpus(corpusDir(t), CorpusOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	calls := 0
+	err = c.Each(func(string, *harfile.Entry) error {
+		calls++
+		return stop
+	})
+	if calls != 1 || !errors.Is(err, stop) {
+		t.Errorf("%d calls, Each = %v", calls, err)
+	}
+}
+
+func TestCorpusSummarize(t *testing.T) {
+	c, err := OpenCorpus(corpusDir(t), CorpusOptions{Recursive: true, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.Summarize()
+	if err == nil || !strings.Contains(err.Error(), "truncated.har") {
+		t.Errorf("Summarize error = %v", err)
+	}
+	if s.Files != 3 || s.Entries != 5 || s.Pages != 3 || s.Time != 50 {
+		t.Errorf("files %d, entries %d, pages %d, time %g", s.Files, s.Entries, s.Pages, s.Time)
+	}
+	wantStatuses := map[harfile.StatusClass]int{harfile.StatusSuccess: 3, harfile.StatusClientError: 1, harfile.StatusServerError: 1}
+	if !maps.Equal(s.Statuses, wantStatuses) {
+		t.Errorf("statuses = %v", s.Statuses)
+	}
+	if !maps.Equal(s.Hosts, map[string]int{"example.com": 3, "cdn.example.com": 1, "example.org": 1}) {
+		t.Errorf("hosts = %v", s.Hosts)
+	}
+	if !maps.Equal(s.ContentTypes, map[string]int{"text/plain": 5}) || !maps.Equal(s.Creators, map[string]int{"nightly 2": 3}) {
+		t.Errorf("content types %v, creators %v", s.ContentTypes, s.Creators)
+	}
+	if s.Bytes != int64(len("hello")+len("missing")+len("oops")+len("c")+len("t")) {
+		t.Errorf("bytes = %d", s.Bytes)
+	}
+	if s.First.Second() != 1 || s.Last.Second() != 9 {
+		t.Errorf("first %v, last %v", s.First, s.Last)
+	}
+}
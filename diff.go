@@ -0,0 +1,232 @@
+package harkit
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Defaults of [DiffOptions].
+const (
+	DefaultDiffSizeThreshold = 0.1
+	DefaultDiffTimeThreshold = 100
+)
+
+// DiffOptions configures [Diff].
+type DiffOptions struct {
+	Matcher           *Matcher // Components pairing entries. Nil means harfile.DefaultMatcher.
+	IgnoreQueryParams []string // Query parameters ignored when pairing, e.g. cache busters and timestamps.
+	SizeThreshold     float64  // Relative change of the response size reported as a change. Zero means DefaultDiffSizeThreshold.
+	TimeThreshold     float64  // Change of the entry time, in milliseconds, reported as a change. Zero means DefaultDiffTimeThreshold.
+	GroupBy           GroupBy  // Maps request paths before pairing, e.g. with PathTemplater so that /users/1 pairs with /users/2. Nil compares paths as recorded.
+
+	// Canon, when set, pairs entries by their canonical form, see
+	// Canonicalize, and compares their canonical response headers and
+	// bodies. The report still holds the original entries, and their
+	// times and sizes.
+	Canon *CanonOptions
+}
+
+// EntryDiff pairs an entry of each log.
+type EntryDiff struct {
+	Fingerprint string
+	A, B        *harfile.Entry
+
+	StatusChanged bool    // The response status differs.
+	SizeDelta     int64   // Change of the response size, in bytes.
+	TimeDelta     float64 // Change of the entry time, in milliseconds.
+
+	ContentChanged bool // With DiffOptions.Canon, the canonical response headers or body differ.
+}
+
+// DiffReport lists the differences between two logs. Entries appear in the
+// order they were recorded.
+type DiffReport struct {
+	OnlyInA   []*harfile.Entry
+	OnlyInB   []*harfile.Entry
+	Changed   []*EntryDiff // Pairs whose status or canonical content changed, or whose size or time moved beyond the thresholds.
+	Unchanged []*EntryDiff
+}
+
+// IsEmpty reports whether the logs have the same requests and no change.
+func (r *DiffReport) IsEmpty() bool {
+	return len(r.OnlyInA) == 0 && len(r.OnlyInB) == 0 && len(r.Changed) == 0
+}
+
+// Diff pairs the entries of a and b with the same fingerprint, in recorded
+// order: the first entry of a with a given fingerprint goes with the first
+// entry of b with that fingerprint, and so on. Entries left over are only
+// in one of the logs.
+func Diff(a, b *harfile.HAR, opts DiffOptions) *DiffReport {
+	m := harfile.DefaultMatcher
+	if opts.Matcher != nil {
+		m = *opts.Matcher
+	}
+	m.IgnoreQueryParams = slices.Concat(m.IgnoreQueryParams, opts.IgnoreQueryParams)
+	if opts.SizeThreshold == 0 {
+		opts.SizeThreshold = DefaultDiffSizeThreshold
+	}
+	if opts.TimeThreshold == 0 {
+		opts.TimeThreshold = DefaultDiffTimeThreshold
+	}
+
+	canon := make(map[*harfile.Entry]*harfile.Entry)
+	if opts.Canon != nil {
+		for _, h := range []*harfile.HAR{a, b} {
+			if h == nil || h.Log == nil {
+				continue
+			}
+			c := Canonicalize(h, *opts.Canon)
+			for i, e := range h.Log.Entries {
+				canon[e] = c.Log.Entries[i]
+			}
+		}
+	}
+	fingerprint := func(e *harfile.Entry) string {
+		return m.Fingerprint(groupRequest(cmp.Or(canon[e], e).Request, opts.GroupBy))
+	}
+
+	pending := make(map[string][]*harfile.Entry)
+	for _, e := range diffEntries(b) {
+		fp := fingerprint(e)
+		pending[fp] = append(pending[fp], e)
+	}
+
+	r := &DiffReport{}
+	paired := make(map[*harfile.Entry]bool)
+	for _, ea := range diffEntries(a) {
+		fp := fingerprint(ea)
+		if len(pending[fp]) == 0 {
+			r.OnlyInA = append(r.OnlyInA, ea)
+			continue
+		}
+		eb := pending[fp][0]
+		pending[fp] = pending[fp][1:]
+		paired[eb] = true
+
+		d := &EntryDiff{
+			Fingerprint:   fp,
+			A:             ea,
+			B:             eb,
+			StatusChanged: status(ea) != status(eb),
+			SizeDelta:     responseSize(eb) - responseSize(ea),
+			TimeDelta:     eb.Time - ea.Time,
+		}
+		if opts.Canon != nil {
+			d.ContentChanged = !sameResponse(canon[ea].Response, canon[eb].Response)
+		}
+		sizeMoved := math.Abs(float64(d.SizeDelta)) > opts.SizeThreshold*float64(max(responseSize(ea), 1))
+		if d.StatusChanged || d.ContentChanged || sizeMoved || math.Abs(d.TimeDelta) > opts.TimeThreshold {
+			r.Changed = append(r.Changed, d)
+		} else {
+			r.Unchanged = append(r.Unchanged, d)
+		}
+	}
+	for _, eb := range diffEntries(b) {
+		if !paired[eb] {
+			r.OnlyInB = append(r.OnlyInB, eb)
+		}
+	}
+	return r
+}
+
+// String renders the report for humans, one line per entry prefixed by "-"
+// for entries only in A, "+" for entries only in B and "~" for changes.
+func (r *DiffReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d only in A, %d only in B, %d changed, %d unchanged\n",
+		len(r.OnlyInA), len(r.OnlyInB), len(r.Changed), len(r.Unchanged))
+	for _, e := range r.OnlyInA {
+		fmt.Fprintf(&b, "- %s %s (%d, %d B)\n", e.Request.Method, e.Request.URL, status(e), responseSize(e))
+	}
+	for _, e := range r.OnlyInB {
+		fmt.Fprintf(&b, "+ %s %s (%d, %d B)\n", e.Request.Method, e.Request.URL, status(e), responseSize(e))
+	}
+	for _, d := range r.Changed {
+		var changes []string
+		if d.StatusChanged {
+			changes = append(changes, fmt.Sprintf("status %d -> %d", status(d.A), status(d.B)))
+		}
+		if d.ContentChanged {
+			changes = append(changes, "content changed")
+		}
+		if d.SizeDelta != 0 {
+			changes = append(changes, fmt.Sprintf("size %+d B", d.SizeDelta))
+		}
+		if d.TimeDelta != 0 {
+			changes = append(changes, fmt.Sprintf("time %+.0f ms", d.TimeDelta))
+		}
+		fmt.Fprintf(&b, "~ %s %s: %s\n", d.A.Request.Method, d.A.Request.URL, strings.Join(changes, ", "))
+	}
+	return b.String()
+}
+
+// diffEntries returns the entries of h that have a request.
+func diffEntries(h *harfile.HAR) []*harfile.Entry {
+	if h == nil || h.Log == nil {
+		return nil
+	}
+	var entries []*harfile.Entry
+	for _, e := range h.Log.Entries {
+		if e != nil && e.Request != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// sameResponse reports whether a and b have the same headers and body.
+func sameResponse(a, b *harfile.Response) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !slices.EqualFunc(a.Headers, b.Headers, func(x, y *harfile.NameValuePair) bool {
+		return strings.EqualFold(x.Name, y.Name) && x.Value == y.Value
+	}) {
+		return false
+	}
+	if a.Content == nil || b.Content == nil {
+		return a.Content == b.Content
+	}
+	return a.Content.Text == b.Content.Text && a.Content.Encoding == b.Content.Encoding
+}
+
+// groupRequest returns req, or a copy whose URL path is mapped by group.
+func groupRequest(req *harfile.Request, group GroupBy) *harfile.Request {
+	if group == nil {
+		return req
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return req
+	}
+	grouped := *req
+	u.Path, u.RawPath = group(u.EscapedPath()), ""
+	grouped.URL = u.String()
+	return &grouped
+}
+
+func status(e *harfile.Entry) int64 {
+	if e.Response == nil {
+		return 0
+	}
+	return e.Response.Status
+}
+
+// responseSize returns the decoded size of the response body, or the
+// transferred size when unknown.
+func responseSize(e *harfile.Entry) int64 {
+	if e.Response == nil {
+		return 0
+	}
+	if size, ok := e.Response.Content.KnownSize(); ok {
+		return size
+	}
+	size, _ := e.Response.KnownBodySize()
+	return size
+}
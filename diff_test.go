@@ -0,0 +1,131 @@
+package harkit
+
+import (
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// diffEntry returns a GET of url answered with status, size bytes, in ms.
+func diffEntry(url string, status, size int64, ms float64) *harfile.Entry {
+	return &harfile.Entry{
+		Time:     ms,
+		Request:  &harfile.Request{Method: "GET", URL: url},
+		Response: &harfile.Response{Status: status, Content: &harfile.Content{Size: size}},
+	}
+}
+
+func diffHAR(entries ...*harfile.Entry) *harfile.HAR {
+	return &harfile.HAR{Log: &harfile.Log{Entries: entries}}
+}
+
+func TestDiff(t *testing.T) {
+	a := diffHAR(
+		diffEntry("https://example.com/", 200, 1000, 50),
+		diffEntry("https://example.com/gone", 200, 10, 5),
+		diffEntry("https://example.com/api", 200, 100, 20),
+		diffEntry("https://example.com/slow", 200, 100, 20),
+		nil,
+	)
+	b := diffHAR(
+		diffEntry("https://example.com/", 200, 1050, 120),
+		diffEntry("https://example.com/api", 500, 100, 20),
+		diffEntry("https://example.com/slow", 200, 100, 200),
+		diffEntry("https://example.com/new", 200, 10, 5),
+	)
+	r := Diff(a, b, DiffOptions{})
+	if len(r.OnlyInA) != 1 || r.OnlyInA[0] != a.Log.Entries[1] {
+		t.Errorf("OnlyInA = %v", r.OnlyInA)
+	}
+	if len(r.OnlyInB) != 1 || r.OnlyInB[0] != b.Log.Entries[3] {
+		t.Errorf("OnlyInB = %v", r.OnlyInB)
+	}
+	if len(r.Unchanged) != 1 || r.Unchanged[0].A != a.Log.Entries[0] || r.Unchanged[0].SizeDelta != 50 || r.Unchanged[0].TimeDelta != 70 {
+		t.Errorf("Unchanged = %+v", r.Unchanged)
+	}
+	if len(r.Changed) != 2 || !r.Changed[0].StatusChanged || r.Changed[1].TimeDelta != 180 {
+		t.Errorf("Changed = %+v", r.Changed)
+	}
+	if r.IsEmpty() {
+		t.Error("IsEmpty")
+	}
+
+	want := "1 only in A, 1 only in B, 2 changed, 1 unchanged\n" +
+		"- GET https://example.com/gone (200, 10 B)\n" +
+		"+ GET https://example.com/new (200, 10 B)\n" +
+		"~ GET https://example.com/api: status 200 -> 500\n" +
+		"~ GET https://example.com/slow: time +180 ms\n"
+	if got := r.String(); got != want {
+		t.Errorf("String:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffThresholds(t *testing.T) {
+	a := diffHAR(diffEntry("https://example.com/", 200, 1000, 50))
+	b := diffHAR(diffEntry("https://example.com/", 200, 1200, 60))
+	if r := Diff(a, b, DiffOptions{}); len(r.Changed) != 1 || r.Changed[0].SizeDelta != 200 {
+		t.Errorf("default thresholds: %+v", r)
+	}
+	if r := Diff(a, b, DiffOptions{SizeThreshold: 0.5, TimeThreshold: 5}); len(r.Changed) != 1 || r.Changed[0].TimeDelta != 10 {
+		t.Errorf("custom thresholds: %+v", r)
+	}
+	if r := Diff(a, b, DiffOptions{SizeThreshold: 0.5}); !r.IsEmpty() {
+		t.Errorf("within thresholds: %s", r)
+	}
+}
+
+func TestDiffDuplicateFingerprints(t *testing.T) {
+	// Repeated requests pair in recorded order; the extra ones are left
+	// over.
+	a := diffHAR(
+		diffEntry("https://example.com/poll", 200, 1, 1),
+		diffEntry("https://example.com/poll", 200, 2, 1),
+		diffEntry("https://example.com/poll", 200, 3, 1),
+	)
+	b := diffHAR(
+		diffEntry("https://example.com/poll", 200, 1, 1),
+		diffEntry("https://example.com/poll", 404, 2, 1),
+	)
+	r := Diff(a, b, DiffOptions{})
+	if len(r.Unchanged) != 1 || r.Unchanged[0].A != a.Log.Entries[0] || r.Unchanged[0].B != b.Log.Entries[0] {
+		t.Errorf("Unchanged = %+v", r.Unchanged)
+	}
+	if len(r.Changed) != 1 || r.Changed[0].A != a.Log.Entries[1] || r.Changed[0].B != b.Log.Entries[1] {
+		t.Errorf("Changed = %+v", r.Changed)
+	}
+	if len(r.OnlyInA) != 1 || r.OnlyInA[0] != a.Log.Entries[2] || len(r.OnlyInB) != 0 {
+		t.Errorf("OnlyInA = %v, OnlyInB = %v", r.OnlyInA, r.OnlyInB)
+	}
+
+	// The other way round, the extra entry is only in B.
+	r = Diff(b, a, DiffOptions{})
+	if len(r.OnlyInB) != 1 || r.OnlyInB[0] != a.Log.Entries[2] || len(r.OnlyInA) != 0 {
+		t.Errorf("reversed: OnlyInA = %v, OnlyInB = %v", r.OnlyInA, r.OnlyInB)
+	}
+}
+
+func TestDiffIgnoreQueryParams(t *testing.T) {
+	a := diffHAR(diffEntry("https://example.com/api?q=go&_=1700000000", 200, 10, 1))
+	b := diffHAR(diffEntry("https://example.com/api?_=1700000999&q=go", 200, 10, 1))
+	if r := Diff(a, b, DiffOptions{}); len(r.OnlyInA) != 1 || len(r.OnlyInB) != 1 {
+		t.Errorf("cache buster compared: %s", r)
+	}
+	r := Diff(a, b, DiffOptions{IgnoreQueryParams: []string{"_"}})
+	if !r.IsEmpty() || len(r.Unchanged) != 1 {
+		t.Errorf("cache buster ignored: %s", r)
+	}
+	if r := Diff(diffHAR(diffEntry("https://example.com/api?q=go", 200, 10, 1)), diffHAR(diffEntry("https://example.com/api?q=rust", 200, 10, 1)), DiffOptions{IgnoreQueryParams: []string{"_"}}); r.IsEmpty() {
+		t.Error("other parameters still compared")
+	}
+
+	// The options add to the parameters the matcher already ignores,
+	// without changing the caller's matcher.
+	m := harfile.DefaultMatcher
+	m.IgnoreQueryParams = []string{"q"}
+	if r := Diff(a, b, DiffOptions{Matcher: &m, IgnoreQueryParams: []string{"_"}}); !r.IsEmpty() {
+		t.Errorf("matcher and options combined: %s", r)
+	}
+	if len(m.IgnoreQueryParams) != 1 {
+		t.Errorf("matcher changed: %q", m.IgnoreQueryParams)
+	}
+}
@@ -0,0 +1,3 @@
+// Package harkit records, transforms and analyzes HTTP traffic using the HAR
+// types of package [harfile].
+package harkit
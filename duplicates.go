@@ -0,0 +1,78 @@
+package harkit
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// DuplicateGroup is a set of responses carrying byte-identical bodies.
+type DuplicateGroup struct {
+	Hash    string // SHA-256 of the decoded body, as by [harfile.Content.Hash].
+	Size    int64  // Decoded body size.
+	Entries []int  // Indexes of the entries in the log, in order.
+	Wasted  int64  // Bytes of the repeated bodies, all but the first being redundant.
+}
+
+// FindDuplicateBodies groups the entries of log whose responses carried
+// the same non-empty body, most wasted bytes first. Entries without a
+// captured body, or whose body cannot be decoded, are skipped. Each body
+// is decoded once, even when entries share a Content, and entries are
+// left untouched.
+//
+// The body of a 206 Partial Content response is a piece of a resource: it
+// is only grouped with identical pieces of the same Content-Range. Pieces
+// overlapping partially are reported by [Ranges].
+func FindDuplicateBodies(log *harfile.Log) []DuplicateGroup {
+	type digest struct {
+		hash string
+		size int64
+	}
+	digests := make(map[*harfile.Content]digest)
+	groups := make(map[string]*DuplicateGroup)
+	var order []string
+	for i, e := range log.Entries {
+		if e == nil || e.Response == nil || e.Response.Content == nil || e.Response.Content.Text == "" {
+			continue
+		}
+		c := e.Response.Content
+		d, ok := digests[c]
+		if !ok {
+			body, err := c.DecodedBody()
+			if err == nil && len(body) > 0 {
+				sum := sha256.Sum256(body)
+				d = digest{hex.EncodeToString(sum[:]), int64(len(body))}
+			}
+			digests[c] = d
+		}
+		if d.hash == "" {
+			continue
+		}
+		key := d.hash
+		if e.Response.Status == 206 {
+			key += " " + headerValue(e.Response.Headers, "Content-Range")
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &DuplicateGroup{Hash: d.hash, Size: d.size}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.Entries = append(g.Entries, i)
+	}
+
+	var dups []DuplicateGroup
+	for _, key := range order {
+		if g := groups[key]; len(g.Entries) > 1 {
+			g.Wasted = g.Size * int64(len(g.Entries)-1)
+			dups = append(dups, *g)
+		}
+	}
+	slices.SortStableFunc(dups, func(a, b DuplicateGroup) int {
+		return cmp.Compare(b.Wasted, a.Wasted)
+	})
+	return dups
+}
@@ -0,0 +1,99 @@
+package harkit
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestFindDuplicateBodies(t *testing.T) {
+	small := strings.Repeat("s", 10)
+	large := strings.Repeat("L", 100)
+	shared := bodyEntry("https://example.com/shared", "text/plain", "shared body")
+	b64 := bodyEntry("https://example.com/b64", "application/octet-stream", "\xff\x00"+small)
+	binary := bodyEntry("https://example.com/bin", "application/octet-stream", "\xff\x00"+small)
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		bodyEntry("https://example.com/a", "text/plain", small), // 0
+		bodyEntry("https://example.com/b", "text/plain", large), // 1
+		nil, // 2
+		bodyEntry("https://example.com/c", "text/plain", small),                       // 3
+		bodyEntry("https://example.com/d", "text/plain", large),                       // 4
+		bodyEntry("https://example.com/e", "text/plain", "only"),                      // 5
+		bodyEntry("https://example.com/f", "text/plain", ""),                          // 6
+		bodyEntry("https://example.com/g", "text/plain", ""),                          // 7
+		{Request: &harfile.Request{Method: "GET", URL: "https://example.com/failed"}}, // 8
+		shared, // 9
+		{Request: shared.Request, Response: shared.Response},    // 10, same Content
+		bodyEntry("https://example.com/h", "text/plain", small), // 11
+		b64,    // 12
+		binary, // 13
+		{Response: &harfile.Response{Content: &harfile.Content{Text: "!", Encoding: "base64"}}}, // 14
+		{Response: &harfile.Response{Content: &harfile.Content{Text: "!", Encoding: "base64"}}}, // 15
+	}}
+	before, _ := log.Entries[0].Response.Content.Hash()
+
+	groups := FindDuplicateBodies(log)
+	if len(groups) != 4 {
+		t.Fatalf("got %d groups, want 4: %+v", len(groups), groups)
+	}
+	want := []struct {
+		entries []int
+		size    int64
+		wasted  int64
+	}{
+		{[]int{1, 4}, 100, 100},
+		{[]int{0, 3, 11}, 10, 20},
+		{[]int{12, 13}, 12, 12},
+		{[]int{9, 10}, 11, 11},
+	}
+	for i, g := range groups {
+		w := want[i]
+		if !slices.Equal(g.Entries, w.entries) || g.Size != w.size || g.Wasted != w.wasted {
+			t.Errorf("group %d = %+v, want entries %v, size %d, wasted %d", i, g, w.entries, w.size, w.wasted)
+		}
+		if h, _ := log.Entries[g.Entries[0]].Response.Content.Hash(); g.Hash != h {
+			t.Errorf("group %d hash %s, Content.Hash %s", i, g.Hash, h)
+		}
+	}
+	if groups[1].Hash != before {
+		t.Errorf("hash %s, want %s", groups[1].Hash, before)
+	}
+	if log.Entries[0].Response.Content.Text != small {
+		t.Error("FindDuplicateBodies changed an entry")
+	}
+
+	if groups := FindDuplicateBodies(&harfile.Log{}); groups != nil {
+		t.Errorf("empty log = %+v", groups)
+	}
+}
+
+func TestRenderReportDuplicates(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		bodyEntry("https://example.com/a?v=1", "text/plain", "same"),
+		bodyEntry("https://example.com/b", "text/plain", "same"),
+		bodyEntry("https://example.com/c", "text/plain", "other"),
+		bodyEntry("https://example.com/d", "text/plain", "other"),
+	}}
+	var b strings.Builder
+	if err := RenderReport(&b, log, ReportOptions{Duplicates: 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, out, _ := strings.Cut(b.String(), "## Duplicate bodies")
+	if !strings.Contains(out, "| URL | SHA-256 | Count | Size | Wasted |\n|---|---|---:|---:|---:|\n") {
+		t.Errorf("no duplicates table in\n%s", out)
+	}
+	if !strings.Contains(out, "| `https://example.com/c` | d9298a10d1b0 | 2 | 5 B | 5 B |\n") || strings.Contains(out, "example.com/a") {
+		t.Errorf("want only the largest group in\n%s", out)
+	}
+
+	b.Reset()
+	unique := &harfile.Log{Entries: log.Entries[1:3]}
+	if err := RenderReport(&b, unique, ReportOptions{Format: ReportText, Duplicates: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "Duplicate bodies") || !strings.Contains(b.String(), "None.\n") {
+		t.Errorf("no empty section in\n%s", b.String())
+	}
+}
@@ -0,0 +1,237 @@
+package harkit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// DefaultMaxEvents is the number of events recorded per event stream unless
+// [WithMaxEvents] says otherwise.
+const DefaultMaxEvents = 1000
+
+// WithMaxEvents caps the events recorded per text/event-stream response,
+// DefaultMaxEvents by default. Later events are counted in the comment of
+// the response content. n <= 0 records every event.
+func WithMaxEvents(n int) Option {
+	return func(o *options) {
+		o.maxEvents = n
+	}
+}
+
+// isEventStream reports whether contentType is text/event-stream.
+func isEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// eventStreamBody records the events of a text/event-stream body as they
+// are read into the entry recorded when the headers arrived.
+type eventStreamBody struct {
+	io.ReadCloser
+	opts    *options
+	entry   *harfile.Entry
+	tc      *TraceCollector
+	encoded bool // Whether the body size on the wire is unknown.
+
+	mu      sync.Mutex // Serializes Read and Close.
+	parser  eventParser
+	total   int64
+	events  int
+	dropped int
+	done    bool
+}
+
+func (b *eventStreamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total += int64(n)
+	now := b.opts.clock.Now()
+	var events []*harfile.EventStreamMessage
+	b.parser.feed(p[:n], func(event, id, data string) {
+		if b.opts.maxEvents > 0 && b.events+len(events) >= b.opts.maxEvents {
+			b.dropped++
+			return
+		}
+		if body, err := b.opts.transform("text/event-stream", []byte(data)); err == nil {
+			data = string(body)
+		} else {
+			data = ""
+		}
+		events = append(events, harfile.NewEventStreamMessage(event, id, data, b.entry.StartedDateTime, now))
+	})
+	if b.events == 0 && b.dropped == 0 && len(events) > 0 {
+		// Receive covers the wait for the first event.
+		b.tc.Done()
+	}
+	b.events += len(events)
+	if end := errors.Is(err, io.EOF); n > 0 || end {
+		b.flush(events, end)
+	}
+	return n, err
+}
+
+func (b *eventStreamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flush(nil, true)
+	return err
+}
+
+// flush appends events to the recorded entry and updates its sizes, and
+// its timings when the stream ended without events.
+func (b *eventStreamBody) flush(events []*harfile.EventStreamMessage, end bool) {
+	if b.done {
+		return
+	}
+	b.done = end
+	if end && b.events == 0 && b.dropped == 0 {
+		b.tc.Done()
+	}
+	timings := b.tc.Timings()
+	b.opts.recorder.update(func() {
+		e := b.entry
+		e.EventStreamMessages = append(e.EventStreamMessages, events...)
+		e.Timings = timings
+		e.Time = timings.Total()
+		b.opts.roundTimings(e)
+		if c := e.Response.Content; c != nil {
+			c.Size = b.total
+			if b.dropped > 0 {
+				c.Comment = fmt.Sprintf("%d events not recorded beyond the limit of %d", b.dropped, b.opts.maxEvents)
+			}
+		}
+		e.Response.BodySize = b.total
+		if b.encoded {
+			e.Response.BodySize = -1
+		}
+	})
+	if end {
+		b.opts.recorder.release(b.entry)
+	}
+}
+
+// eventParser splits a text/event-stream body into events, as specified by
+// the HTML Living Standard, across the chunks it is fed.
+type eventParser struct {
+	line  []byte
+	cr    bool // The last byte was a CR, which a LF may complete.
+	event string
+	data  strings.Builder
+	id    string
+}
+
+// feed parses b, calling dispatch for each complete event.
+func (p *eventParser) feed(b []byte, dispatch func(event, id, data string)) {
+	for _, c := range b {
+		if c == '\n' && p.cr {
+			p.cr = false
+			continue
+		}
+		p.cr = c == '\r'
+		if c == '\r' || c == '\n' {
+			p.processLine(dispatch)
+			p.line = p.line[:0]
+			continue
+		}
+		p.line = append(p.line, c)
+	}
+}
+
+func (p *eventParser) processLine(dispatch func(event, id, data string)) {
+	line := string(p.line)
+	if line == "" {
+		if data := p.data.String(); data != "" {
+			dispatch(p.event, p.id, strings.TrimSuffix(data, "\n"))
+		}
+		p.event = ""
+		p.data.Reset()
+		return
+	}
+	if strings.HasPrefix(line, ":") {
+		return
+	}
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "event":
+		p.event = value
+	case "data":
+		p.data.WriteString(value)
+		p.data.WriteByte('\n')
+	case "id":
+		if !strings.Contains(value, "\x00") {
+			p.id = value
+		}
+	}
+}
+
+// writeEventStream writes the recorded events of e to w, spaced as recorded
+// when pace is set, until they are all sent or the client goes away.
+func writeEventStream(w http.ResponseWriter, r *http.Request, e *harfile.Entry, pace bool) {
+	header, _ := recordedHeader(e.Response)
+	maps.Copy(w.Header(), header)
+	w.WriteHeader(int(e.Response.Status))
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	start := time.Now()
+	base := headersOffset(e)
+	for _, m := range e.EventStreamMessages {
+		if pace {
+			delay := time.Until(start.Add(time.Duration((m.Offset - base) * float64(time.Millisecond))))
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+		}
+		var b strings.Builder
+		if m.Event != "" {
+			fmt.Fprintf(&b, "event: %s\n", m.Event)
+		}
+		if m.ID != "" {
+			fmt.Fprintf(&b, "id: %s\n", m.ID)
+		}
+		for _, line := range strings.Split(m.Data, "\n") {
+			fmt.Fprintf(&b, "data: %s\n", line)
+		}
+		b.WriteByte('\n')
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// headersOffset returns the milliseconds between the start of e and the
+// arrival of its response headers: its timings but receive.
+func headersOffset(e *harfile.Entry) float64 {
+	t := e.Timings
+	if t == nil {
+		return 0
+	}
+	var total float64
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait} {
+		total += max(phase, 0)
+	}
+	return total
+}
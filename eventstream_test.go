@@ -0,0 +1,100 @@
+package harkit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestEventParser(t *testing.T) {
+	const stream = ": comment\r\n" +
+		"event: update\r\nid: 1\r\ndata: first\r\ndata:  second\r\n\r\n" +
+		"data: default type, same id\n\n" +
+		"id: bad\x00id\nretry: 10\nunknown\n\n" +
+		"id:2\rdata\r\r" +
+		"data: unterminated"
+	// Feeding a byte at a time splits lines and CRLF pairs across chunks.
+	for _, chunk := range []int{len(stream), 1} {
+		var p eventParser
+		var got []string
+		for b := []byte(stream); len(b) > 0; b = b[min(chunk, len(b)):] {
+			p.feed(b[:min(chunk, len(b))], func(event, id, data string) {
+				got = append(got, event+"|"+id+"|"+data)
+			})
+		}
+		want := "update|1|first\n second,|1|default type, same id,|2|"
+		if strings.Join(got, ",") != want {
+			t.Errorf("chunks of %d: events = %q, want %q", chunk, got, want)
+		}
+	}
+}
+
+func TestTransportEventStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{"one", "two", "three"} {
+			io.WriteString(w, "event: tick\ndata: "+data+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	tr := NewTransport(http.DefaultTransport, WithMaxEvents(2))
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	entries := tr.HAR().Log.Entries
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if len(e.EventStreamMessages) != 2 || e.EventStreamMessages[1].Data != "two" || e.EventStreamMessages[1].Event != "tick" {
+		t.Errorf("events = %+v", e.EventStreamMessages)
+	}
+	if c := e.Response.Content; c.Size != int64(len(body)) || c.Text != "" || c.Comment != "1 events not recorded beyond the limit of 2" {
+		t.Errorf("content = %+v", c)
+	}
+	if e.Response.BodySize != int64(len(body)) || e.Time != e.Timings.Total() {
+		t.Errorf("bodySize %d, time %v", e.Response.BodySize, e.Time)
+	}
+	for _, m := range e.EventStreamMessages {
+		if m.Offset < 0 || m.Offset > e.Time+1 {
+			t.Errorf("offset %v outside of the entry time %v", m.Offset, e.Time)
+		}
+	}
+}
+
+func TestReplayEventStream(t *testing.T) {
+	e := harfile.NewEntry().Get("https://example.com/events").RespondStatus(200).Build()
+	e.Response.Headers = []*harfile.NameValuePair{{Name: "Content-Type", Value: "text/event-stream"}}
+	e.Timings = &harfile.Timings{Wait: 10}
+	e.EventStreamMessages = []*harfile.EventStreamMessage{
+		{Offset: 10, Event: "tick", ID: "1", Data: "one"},
+		{Offset: 40, Data: "two\nlines"},
+	}
+	h := harfile.NewLog().Entries(e).HAR()
+
+	for _, pace := range []bool{false, true} {
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		NewReplayHandler(h, MatchOptions{PaceEvents: pace}).ServeHTTP(rec, httptest.NewRequest("GET", "https://example.com/events", nil))
+		elapsed := time.Since(start)
+
+		want := "event: tick\nid: 1\ndata: one\n\ndata: two\ndata: lines\n\n"
+		if rec.Body.String() != want || rec.Header().Get("Content-Type") != "text/event-stream" {
+			t.Errorf("pace %v: replayed %q, headers %v", pace, rec.Body.String(), rec.Header())
+		}
+		if pace && elapsed < 30*time.Millisecond {
+			t.Errorf("paced replay took %v, want at least 30ms", elapsed)
+		}
+	}
+}
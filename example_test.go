@@ -0,0 +1,200 @@
+package harkit_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit"
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func ExampleTransport() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello from %s", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	tr := harkit.NewTransport(nil)
+	client := &http.Client{Transport: tr}
+	for _, path := range []string{"/a", "/b?q=1"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			panic(err)
+		}
+		// Entries are recorded once the body is read or closed.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	har := tr.HAR()
+	fmt.Println(len(har.Log.Entries), "entries")
+	for _, e := range har.Log.Entries {
+		fmt.Println(e.Request.Method, strings.TrimPrefix(e.Request.URL, srv.URL), e.Response.Status, e.Response.Content.Text)
+	}
+	// Output:
+	// 2 entries
+	// GET /a 200 hello from /a
+	// GET /b?q=1 200 hello from /b
+}
+
+func ExampleWrapReverseProxy() {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "upstream got %s", r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL + "/v2")
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	rec := harkit.WrapReverseProxy(proxy)
+	front := httptest.NewServer(proxy)
+
+	resp, err := http.Get(front.URL + "/users")
+	if err != nil {
+		panic(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	fmt.Println("client got:", string(body))
+	// The entry is added once the proxy is done with the response, which
+	// closing the front server waits for.
+	front.Close()
+
+	// The entry describes the request as rewritten for the upstream.
+	e := rec.HAR().Log.Entries[0]
+	fmt.Println(e.Request.Method, strings.TrimPrefix(e.Request.URL, upstream.URL), e.Response.Status)
+	fmt.Println("server:", e.ServerIPAddress)
+	// Output:
+	// client got: upstream got /v2/users
+	// GET /v2/users 200
+	// server: 127.0.0.1
+}
+
+var exampleStart = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func ExampleNewReplayHandler() {
+	recorded := harfile.NewLog().Entries(
+		harfile.NewEntry().
+			Get("https://api.example.com/users/1").
+			StartedAt(exampleStart).
+			RespondStatus(200).
+			RespondJSON([]byte(`{"id":1,"name":"Ada"}`)).
+			Build(),
+	).HAR()
+
+	// The mock serves the recorded responses, whatever the host.
+	mock := httptest.NewServer(harkit.NewReplayHandler(recorded, harkit.MatchOptions{IgnoreHost: true}))
+	defer mock.Close()
+
+	for _, path := range []string{"/users/1", "/users/2"} {
+		resp, err := http.Get(mock.URL + path)
+		if err != nil {
+			panic(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		first, _, _ := strings.Cut(string(body), "\n")
+		fmt.Println(path, resp.StatusCode, first)
+	}
+	// Output:
+	// /users/1 200 {"id":1,"name":"Ada"}
+	// /users/2 404 harkit: no recorded request matches: GET /users/2
+}
+
+func ExampleRedact() {
+	har := harfile.NewLog().Entries(
+		harfile.NewEntry().
+			Get("https://example.com/account?token=s3cr3t&page=2").
+			StartedAt(exampleStart).
+			Header("Authorization", "Bearer abc").
+			Header("Accept", "text/html").
+			RespondStatus(200).
+			Build(),
+	).HAR()
+
+	report := harkit.Redact(har, harkit.RedactOptions{QueryParams: []string{"token"}})
+	req := har.Log.Entries[0].Request
+	fmt.Println(req.URL)
+	for _, h := range req.Headers {
+		fmt.Printf("%s: %s\n", h.Name, h.Value)
+	}
+	fmt.Println(report.Total(), "values redacted")
+	// Output:
+	// https://example.com/account?token=%5BREDACTED%5D&page=2
+	// Authorization: [REDACTED]
+	// Accept: text/html
+	// 2 values redacted
+}
+
+func ExampleWaterfall() {
+	entry := func(url string, offset time.Duration, timings harfile.Timings) *harfile.Entry {
+		return harfile.NewEntry().Get(url).StartedAt(exampleStart.Add(offset)).Timing(timings).RespondStatus(200).Build()
+	}
+	log := harfile.NewLog().Entries(
+		entry("https://example.com/", 0, harfile.Timings{Blocked: -1, DNS: 20, Connect: 30, Ssl: 20, Send: 0, Wait: 40, Receive: 10}),
+		entry("https://example.com/app.js", 100*time.Millisecond, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 0, Wait: 50, Receive: 50}),
+		entry("https://example.com/logo.png", 110*time.Millisecond, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 0, Wait: 20, Receive: 10}),
+	).Build()
+
+	t := harkit.Waterfall(log)
+	t.RenderText(os.Stdout, 20)
+	for _, r := range t.CriticalPath {
+		fmt.Println("critical:", r.Entry.Request.URL)
+	}
+	// Output:
+	// GET https://example.com/                 |ddcss....##         | 100ms
+	// GET https://example.com/app.js           |          .....#####| 100ms
+	// GET https://example.com/logo.png         |           ..##     | 30ms
+	// total 200ms
+	// critical: https://example.com/
+	// critical: https://example.com/app.js
+}
+
+func ExampleFakeClock() {
+	clock := harkit.NewFakeClock(exampleStart)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server takes 120ms to answer, as far as the recorder knows.
+		clock.Advance(120 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	tr := harkit.NewTransport(nil, harkit.WithClock(clock))
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		panic(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	e := tr.HAR().Log.Entries[0]
+	fmt.Println(e.StartedDateTime.Format(time.RFC3339Nano))
+	fmt.Println("wait", e.Timings.Wait, "ms, total", e.Time, "ms")
+	// Output:
+	// 2024-03-01T09:00:00Z
+	// wait 120 ms, total 120 ms
+}
+
+func ExampleRecorder_Freeze() {
+	rec := harkit.NewRecorder(harkit.RecorderOptions{})
+	rec.Append(harfile.NewEntry().Get("https://example.com/a").StartedAt(exampleStart).RespondStatus(200).Build())
+	rec.Append(harfile.NewEntry().Get("https://example.com/b").StartedAt(exampleStart).RespondStatus(500).Build())
+
+	view := rec.Freeze()
+	// Recording goes on without changing the view.
+	rec.Append(harfile.NewEntry().Get("https://example.com/c").StartedAt(exampleStart).RespondStatus(503).Build())
+
+	fmt.Println(view.EntryCount(), "entries in the view,", rec.Len(), "recorded")
+	for _, e := range view.Filter(harfile.ByStatusRange(500, 599)).All() {
+		fmt.Println("server error:", e.Request.URL)
+	}
+	// Output:
+	// 2 entries in the view, 3 recorded
+	// server error: https://example.com/b
+}
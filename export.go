@@ -0,0 +1,233 @@
+package harkit
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Field is a column of [ExportCSV] and a member of [ExportJSONL]: one of
+// the constants below, or an annotation, see [AnnotationField].
+type Field string
+
+const (
+	FieldStartedDateTime Field = "startedDateTime"
+	FieldMethod          Field = "method"
+	FieldURL             Field = "url"
+	FieldHost            Field = "host"
+	FieldPath            Field = "path"
+	FieldStatus          Field = "status"
+	FieldHTTPVersion     Field = "httpVersion" // Response protocol in its canonical spelling, e.g. "HTTP/2.0".
+	FieldMimeType        Field = "mimeType"
+	FieldBodySize        Field = "bodySize" // Response body size, missing when unknown.
+	FieldTime            Field = "time"
+	FieldBlocked         Field = "blocked"
+	FieldDNS             Field = "dns"
+	FieldConnect         Field = "connect"
+	FieldSSL             Field = "ssl"
+	FieldSend            Field = "send"
+	FieldWait            Field = "wait"
+	FieldReceive         Field = "receive"          // Timing phases are missing when unknown (-1).
+	FieldGraphQL         Field = "graphqlOperation" // GraphQL operation names, comma separated for batches.
+)
+
+// DefaultFields are exported when no fields are given.
+var DefaultFields = []Field{
+	FieldStartedDateTime, FieldMethod, FieldURL, FieldHost, FieldPath, FieldStatus,
+	FieldMimeType, FieldBodySize, FieldTime, FieldBlocked, FieldDNS, FieldConnect,
+	FieldSSL, FieldSend, FieldWait, FieldReceive, FieldGraphQL, FieldHTTPVersion,
+}
+
+// value returns the field of e, or nil when the object holding it is
+// missing.
+func (f Field) value(e *harfile.Entry) (any, error) {
+	switch f {
+	case FieldStartedDateTime:
+		if e.StartedDateTime.IsZero() {
+			return nil, nil
+		}
+		return e.StartedDateTime.Format(time.RFC3339Nano), nil
+	case FieldTime:
+		return e.Time, nil
+	case FieldMethod, FieldURL, FieldHost, FieldPath:
+		if e.Request == nil {
+			return nil, nil
+		}
+		switch f {
+		case FieldMethod:
+			return e.Request.Method, nil
+		case FieldURL:
+			return e.Request.URL, nil
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, nil
+		}
+		if f == FieldHost {
+			return u.Host, nil
+		}
+		return u.Path, nil
+	case FieldStatus, FieldBodySize:
+		if e.Response == nil {
+			return nil, nil
+		}
+		if f == FieldStatus {
+			return e.Response.Status, nil
+		}
+		if size, ok := e.Response.KnownBodySize(); ok {
+			return size, nil
+		}
+		return nil, nil
+	case FieldHTTPVersion:
+		if e.Response == nil {
+			return nil, nil
+		}
+		if p := e.Response.Protocol(); p != harfile.ProtocolUnknown {
+			return p.String(), nil
+		}
+		return e.Response.HTTPVersion, nil
+	case FieldMimeType:
+		if e.Response == nil || e.Response.Content == nil {
+			return nil, nil
+		}
+		return e.Response.Content.MimeType, nil
+	case FieldBlocked, FieldDNS, FieldConnect, FieldSSL, FieldSend, FieldWait, FieldReceive:
+		if ms, ok := e.Timings.Millis(string(f)); ok {
+			return ms, nil
+		}
+		return nil, nil
+	case FieldGraphQL:
+		if e.Request == nil || e.Request.PostData == nil {
+			return nil, nil
+		}
+		info, err := e.Request.PostData.GraphQL()
+		if err != nil {
+			return nil, nil
+		}
+		return strings.Join(info.Names(), ","), nil
+	}
+	if key, ok := strings.CutPrefix(string(f), annotationFieldPrefix); ok && key != "" {
+		if v, ok := e.Annotations[key]; ok {
+			return v, nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("harkit: unknown field %q", string(f))
+}
+
+// checkFields returns fields, or DefaultFields when empty, after checking
+// that every field is known.
+func checkFields(fields []Field) ([]Field, error) {
+	if len(fields) == 0 {
+		return DefaultFields, nil
+	}
+	for _, f := range fields {
+		if _, err := f.value(&harfile.Entry{}); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// ExportCSV writes one CSV row per entry of log, after a header row naming
+// fields, or DefaultFields when fields is empty. Values of missing objects,
+// such as the status of an entry without response, and unknown sizes and
+// timings are empty cells. Rows are written as they are produced.
+func ExportCSV(w io.Writer, log *harfile.Log, fields []Field) error {
+	fields, err := checkFields(fields)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	record := make([]string, len(fields))
+	for i, f := range fields {
+		record[i] = string(f)
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	for _, e := range log.Entries {
+		if e == nil {
+			continue
+		}
+		for i, f := range fields {
+			v, _ := f.value(e)
+			switch v := v.(type) {
+			case nil:
+				record[i] = ""
+			case string:
+				record[i] = v
+			case int64:
+				record[i] = strconv.FormatInt(v, 10)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// JSONLOptions configures [ExportJSONL].
+type JSONLOptions struct {
+	Fields []Field // Members of each object, in order. Empty means DefaultFields.
+
+	Progress harfile.Progress // Receives a StageExport step per entry written. Nil means harfile.NoProgress.
+}
+
+// ExportJSONL writes one flat JSON object per entry of log and per line,
+// with the fields selected by opts as members. Values of missing objects,
+// and unknown sizes and timings, are null. Lines are written as they are
+// produced.
+func ExportJSONL(w io.Writer, log *harfile.Log, opts JSONLOptions) error {
+	fields, err := checkFields(opts.Fields)
+	if err != nil {
+		return err
+	}
+	keys := make([][]byte, len(fields))
+	for i, f := range fields {
+		keys[i], _ = json.Marshal(string(f))
+	}
+
+	bw := bufio.NewWriter(w)
+	progress := cmp.Or(opts.Progress, harfile.NoProgress)
+	total := int64(len(log.Entries))
+	var line []byte
+	for i, e := range log.Entries {
+		progress.Step(StageExport, int64(i+1), total)
+		if e == nil {
+			continue
+		}
+		line = append(line[:0], '{')
+		for i, f := range fields {
+			v, _ := f.value(e)
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				line = append(line, ',')
+			}
+			line = append(line, keys[i]...)
+			line = append(line, ':')
+			line = append(line, data...)
+		}
+		line = append(line, '}', '\n')
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,161 @@
+package harkit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestExportGraphQLField(t *testing.T) {
+	post := func(text string) *harfile.Entry {
+		return &harfile.Entry{Request: &harfile.Request{Method: "POST", URL: "https://example.com/graphql",
+			PostData: &harfile.PostData{MimeType: "application/json", Text: text}}}
+	}
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		post(`{"query":"query Feed { items }"}`),
+		post(`[{"query":"query A { a }"},{"query":"{ b }"},{"query":"mutation C { c }"}]`),
+		post(`{"query":"{ me }"}`),
+		post(`user=ada`),
+		{Request: &harfile.Request{Method: "GET", URL: "https://example.com/"}},
+	}}
+	fields := []Field{FieldMethod, FieldGraphQL}
+
+	var csv bytes.Buffer
+	if err := ExportCSV(&csv, log, fields); err != nil {
+		t.Fatal(err)
+	}
+	want := "method,graphqlOperation\nPOST,Feed\nPOST,\"A,C\"\nPOST,\nPOST,\nGET,\n"
+	if csv.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", csv.String(), want)
+	}
+
+	var jsonl bytes.Buffer
+	if err := ExportJSONL(&jsonl, log, JSONLOptions{Fields: fields}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(jsonl.String(), "\n"), "\n")
+	wantLines := []string{
+		`{"method":"POST","graphqlOperation":"Feed"}`,
+		`{"method":"POST","graphqlOperation":"A,C"}`,
+		`{"method":"POST","graphqlOperation":""}`,
+		`{"method":"POST","graphqlOperation":null}`,
+		`{"method":"GET","graphqlOperation":null}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(wantLines, "\n") {
+		t.Errorf("JSONL =\n%s", jsonl.String())
+	}
+}
+
+func TestExportHTTPVersionField(t *testing.T) {
+	entry := func(version string) *harfile.Entry {
+		return &harfile.Entry{Request: &harfile.Request{Method: "GET"}, Response: &harfile.Response{Status: 200, HTTPVersion: version}}
+	}
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		entry("h2"), entry("http/1.1"), entry("spdy/3"), entry(""),
+		{Request: &harfile.Request{Method: "GET"}},
+	}}
+	var b bytes.Buffer
+	if err := ExportCSV(&b, log, []Field{FieldHTTPVersion}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "httpVersion\nHTTP/2.0\nHTTP/1.1\nspdy/3\n\n\n"; b.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+// unknownSizeEntry returns an entry whose response body size, content size and
+// dns and receive phases are all value.
+func unknownSizeEntry(value int64) *harfile.Entry {
+	e := harfile.NewEntry().Get("https://example.com/").Build()
+	e.Response.BodySize = value
+	e.Response.Content.Size = value
+	e.Timings = &harfile.Timings{Blocked: -1, DNS: float64(value), Connect: -1, Ssl: -1, Send: 1, Wait: 2, Receive: float64(value)}
+	e.ComputeTime()
+	return e
+}
+
+func TestExportUnknownSizes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int64
+		cell  string
+		json  string
+	}{
+		{"known", 120, "120", "120"},
+		{"zero", 0, "0", "0"},
+		{"unknown", harfile.SizeUnknown, "", "null"},
+	}
+	fields := []Field{FieldBodySize, FieldDNS, FieldReceive}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := harfile.NewLog().Entries(unknownSizeEntry(tt.value)).Build()
+
+			var b bytes.Buffer
+			if err := ExportCSV(&b, log, fields); err != nil {
+				t.Fatal(err)
+			}
+			rows, err := csv.NewReader(&b).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, cell := range rows[1] {
+				if cell != tt.cell {
+					t.Errorf("CSV %s = %q, want %q", fields[i], cell, tt.cell)
+				}
+			}
+
+			b.Reset()
+			if err := ExportJSONL(&b, log, JSONLOptions{Fields: fields}); err != nil {
+				t.Fatal(err)
+			}
+			var row map[string]json.RawMessage
+			if err := json.Unmarshal(b.Bytes(), &row); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range fields {
+				if got := string(row[string(f)]); got != tt.json {
+					t.Errorf("JSONL %s = %s, want %s", f, got, tt.json)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseSizeUnknown(t *testing.T) {
+	tests := []struct {
+		name        string
+		bodySize    int64
+		contentSize int64
+		report      int64 // Transferred size, falling back on the decoded one.
+		diff        int64 // Decoded size, falling back on the transferred one.
+	}{
+		{"known", 80, 100, 80, 100},
+		{"zero", 0, 0, 0, 0},
+		{"unknown body size", -1, 100, 100, 100},
+		{"unknown content size", 80, -1, 80, 80},
+		{"unknown", -1, -1, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := harfile.NewEntry().Get("https://example.com/").Build()
+			e.Response.BodySize = tt.bodySize
+			e.Response.Content.Size = tt.contentSize
+			if got := reportSize(e); got != tt.report {
+				t.Errorf("reportSize = %d, want %d", got, tt.report)
+			}
+			if got := responseSize(e); got != tt.diff {
+				t.Errorf("responseSize = %d, want %d", got, tt.diff)
+			}
+		})
+	}
+
+	e := harfile.NewEntry().Build()
+	e.Response = nil
+	if reportSize(e) != harfile.SizeUnknown || responseSize(e) != 0 {
+		t.Errorf("sizes of an entry without response = %d, %d", reportSize(e), responseSize(e))
+	}
+}
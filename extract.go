@@ -0,0 +1,184 @@
+package harkit
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// ExtractOptions configures [ExtractBodies].
+type ExtractOptions struct {
+	MimePrefixes   []string // Only bodies whose MIME type starts with one of these, e.g. "image/" or "application/json". Empty means every body.
+	MinSize        int64    // Bodies smaller than this many bytes are skipped.
+	KeepCompressed bool     // Write bodies stored compressed as is instead of decompressing them.
+
+	Progress harfile.Progress // Receives a StageExtract step per entry. Nil means harfile.NoProgress.
+}
+
+// Extracted describes a body written by [ExtractBodies].
+type Extracted struct {
+	Index  int    // Index of the entry in the log.
+	Path   string // Path of the file, under the target directory.
+	Size   int64  // Bytes written.
+	SHA256 string // Hex-encoded SHA-256 of the file.
+}
+
+// extensions maps common MIME types to the extension used when a URL path
+// has none; other types fall back to the system MIME table.
+var extensions = map[string]string{
+	"application/javascript": ".js",
+	"application/json":       ".json",
+	"application/pdf":        ".pdf",
+	"application/wasm":       ".wasm",
+	"application/xml":        ".xml",
+	"font/woff":              ".woff",
+	"font/woff2":             ".woff2",
+	"image/gif":              ".gif",
+	"image/jpeg":             ".jpg",
+	"image/png":              ".png",
+	"image/svg+xml":          ".svg",
+	"image/webp":             ".webp",
+	"text/css":               ".css",
+	"text/html":              ".html",
+	"text/javascript":        ".js",
+	"text/plain":             ".txt",
+	"text/xml":               ".xml",
+}
+
+// ExtractBodies writes the response body of each entry of log selected by
+// opts to a file under dir, and returns the manifest of written files in
+// entry order. Entries without a body are skipped.
+//
+// Files are laid out as "<host>/<path>", the URL path segments being joined
+// by '_' and characters not allowed in file names replaced, so hostile
+// URLs such as "/../../etc/passwd" cannot escape dir. An extension is
+// derived from the MIME type when the path has none, and names already
+// used are disambiguated with an index, as in "app-2.js".
+//
+// On error, the manifest of the files written so far is returned.
+func ExtractBodies(log *harfile.Log, dir string, opts ExtractOptions) ([]Extracted, error) {
+	var manifest []Extracted
+	used := make(map[string]bool)
+	progress := cmp.Or(opts.Progress, harfile.NoProgress)
+	total := int64(len(log.Entries))
+	defer func() { progress.Step(StageExtract, total, total) }()
+	for i, e := range log.Entries {
+		if i > 0 {
+			progress.Step(StageExtract, int64(i), total)
+		}
+		if e == nil || e.Request == nil || e.Response == nil || e.Response.Content == nil {
+			continue
+		}
+		c := e.Response.Content
+		mediaType, _, _ := mime.ParseMediaType(c.MimeType)
+		if len(opts.MimePrefixes) > 0 && !slices.ContainsFunc(opts.MimePrefixes, func(prefix string) bool {
+			return strings.HasPrefix(mediaType, strings.ToLower(prefix))
+		}) {
+			continue
+		}
+
+		var body []byte
+		var err error
+		if opts.KeepCompressed {
+			body, err = rawContent(c)
+		} else {
+			body, err = c.DecodeBody(headerValue(e.Response.Headers, "Content-Encoding"))
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("harkit: entry %d: %w", i, err)
+		}
+		if len(body) == 0 || int64(len(body)) < opts.MinSize {
+			continue
+		}
+
+		name := bodyFileName(e.Request.URL, mediaType)
+		ext := path.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = base + "-" + strconv.Itoa(n) + ext
+		}
+		used[strings.ToLower(name)] = true
+
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return manifest, err
+		}
+		if err := os.WriteFile(file, body, 0o644); err != nil {
+			return manifest, err
+		}
+		sum := sha256.Sum256(body)
+		manifest = append(manifest, Extracted{Index: i, Path: file, Size: int64(len(body)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	return manifest, nil
+}
+
+// rawContent returns the body held by c without decompressing it.
+func rawContent(c *harfile.Content) ([]byte, error) {
+	if strings.EqualFold(c.Encoding, "base64") {
+		return base64.StdEncoding.DecodeString(c.Text)
+	}
+	return []byte(c.Text), nil
+}
+
+// bodyFileName returns the slash-separated relative file name of a body
+// fetched from rawURL: a host directory and a file named from the path.
+func bodyFileName(rawURL, mediaType string) string {
+	host, name := "unknown", ""
+	if u, err := url.Parse(rawURL); err == nil {
+		if h := safeFileName(u.Host); h != "" {
+			host = h
+		}
+		var segments []string
+		for _, s := range strings.Split(u.Path, "/") {
+			if s = safeFileName(s); s != "" {
+				segments = append(segments, s)
+			}
+		}
+		name = strings.Join(segments, "_")
+	}
+	if name == "" {
+		name = "index"
+	}
+	if len(name) > 200 {
+		name = name[len(name)-200:]
+		for !utf8.RuneStart(name[0]) {
+			name = name[1:]
+		}
+	}
+	if path.Ext(name) == "" {
+		if ext, ok := extensions[mediaType]; ok {
+			name += ext
+		} else if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	return host + "/" + name
+}
+
+// safeFileName replaces the characters of s not allowed in file names on
+// common systems by '_', and returns "" for names made of dots only.
+func safeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	if strings.Trim(s, ".") == "" {
+		return ""
+	}
+	return s
+}
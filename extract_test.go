@@ -0,0 +1,161 @@
+package harkit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// bodyEntry returns a GET of url answered with data of type mimeType.
+func bodyEntry(url, mimeType, data string) *harfile.Entry {
+	e := &harfile.Entry{
+		Request:  &harfile.Request{Method: "GET", URL: url},
+		Response: &harfile.Response{Status: 200, Content: &harfile.Content{}},
+	}
+	e.Response.Content.SetBody([]byte(data), mimeType)
+	return e
+}
+
+// gzipString returns s gzip compressed.
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, s)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestExtractBodies(t *testing.T) {
+	dir := t.TempDir()
+	gz := gzipString(t, "compressed")
+	compressed := bodyEntry("https://example.com/data", "text/plain", gz)
+	compressed.Response.Headers = []*harfile.NameValuePair{{Name: "Content-Encoding", Value: "gzip"}}
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		bodyEntry("https://example.com/", "text/html; charset=utf-8", "<h1>home</h1>"),
+		bodyEntry("https://example.com/static/app.js", "text/javascript", "run()"),
+		bodyEntry("https://cdn.example.com/static/app.js", "text/javascript", "cdn()"),
+		bodyEntry("https://example.com/static/APP.js", "text/javascript", "again()"),
+		bodyEntry("https://example.com/logo", "image/png", "\x89PNG"),
+		bodyEntry("https://example.com/empty", "text/plain", ""),
+		compressed,
+		nil,
+		{Request: &harfile.Request{URL: "https://example.com/no-response"}},
+	}}
+	manifest, err := ExtractBodies(log, dir, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		index int
+		path  string
+		data  string
+	}{
+		{0, "example.com/index.html", "<h1>home</h1>"},
+		{1, "example.com/static_app.js", "run()"},
+		{2, "cdn.example.com/static_app.js", "cdn()"},
+		{3, "example.com/static_APP-2.js", "again()"},
+		{4, "example.com/logo.png", "\x89PNG"},
+		{6, "example.com/data.txt", "compressed"},
+	}
+	if len(manifest) != len(want) {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	for i, w := range want {
+		m := manifest[i]
+		sum := sha256.Sum256([]byte(w.data))
+		if m.Index != w.index || m.Path != filepath.Join(dir, filepath.FromSlash(w.path)) || m.Size != int64(len(w.data)) || m.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("manifest[%d] = %+v, want %s", i, m, w.path)
+		}
+		if data, err := os.ReadFile(m.Path); err != nil || string(data) != w.data {
+			t.Errorf("%s = %q, %v", w.path, data, err)
+		}
+	}
+}
+
+func TestExtractBodiesOptions(t *testing.T) {
+	gz := gzipString(t, "compressed")
+	compressed := bodyEntry("https://example.com/data", "text/plain", gz)
+	compressed.Response.Headers = []*harfile.NameValuePair{{Name: "Content-Encoding", Value: "gzip"}}
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		bodyEntry("https://example.com/a.png", "image/png", "\x89PNG"),
+		bodyEntry("https://example.com/b.json", "Application/JSON", strings.Repeat("1", 100)),
+		bodyEntry("https://example.com/c.json", "application/json", "1"),
+		compressed,
+	}}
+	manifest, err := ExtractBodies(log, t.TempDir(), ExtractOptions{MimePrefixes: []string{"APPLICATION/"}, MinSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != 1 || manifest[0].Index != 1 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+
+	manifest, err = ExtractBodies(log, t.TempDir(), ExtractOptions{MimePrefixes: []string{"text/"}, KeepCompressed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(manifest[0].Path); len(manifest) != 1 || string(data) != gz {
+		t.Errorf("compressed body written as %q", data)
+	}
+
+	bad := &harfile.Log{Entries: []*harfile.Entry{bodyEntry("https://example.com/", "text/plain", "x")}}
+	bad.Entries[0].Response.Content.Encoding = "base64"
+	bad.Entries[0].Response.Content.Text = "!"
+	if _, err := ExtractBodies(bad, t.TempDir(), ExtractOptions{}); err == nil || !strings.Contains(err.Error(), "entry 0") {
+		t.Errorf("invalid base64: %v", err)
+	}
+}
+
+// TestExtractBodiesTraversal checks that hostile URLs cannot write outside
+// the target directory.
+func TestExtractBodiesTraversal(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "out")
+	urls := []string{
+		"https://example.com/../../escaped",
+		"https://example.com/%2e%2e/%2e%2e/escaped",
+		"https://example.com/a/..%2f..%2fescaped",
+		"https://example.com/..%5C..%5Cescaped",
+		"https://../escaped",
+		"https://example.com/C:/escaped",
+		"https://example.com/.../....",
+		"https://example.com/" + strings.Repeat("é", 150),
+		"https://example.com/a%00b",
+		"not a url\x7f",
+	}
+	log := &harfile.Log{}
+	for _, u := range urls {
+		log.Entries = append(log.Entries, bodyEntry(u, "text/plain", "x"))
+	}
+	manifest, err := ExtractBodies(log, dir, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != len(urls) {
+		t.Fatalf("%d files written, want %d", len(manifest), len(urls))
+	}
+	for _, m := range manifest {
+		rel, err := filepath.Rel(dir, m.Path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || strings.Count(rel, string(filepath.Separator)) != 1 {
+			t.Errorf("%s written to %s", urls[m.Index], m.Path)
+		}
+		if base := filepath.Base(m.Path); len(base) > 210 {
+			t.Errorf("%s: file name of %d bytes", urls[m.Index], len(base))
+		}
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 1 || entries[0].Name() != "out" {
+		t.Errorf("files outside the target directory: %v", entries)
+	}
+}
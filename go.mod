@@ -1,3 +1,9 @@
 module github.com/Mathious6/harkit
 
 go 1.24.2
+
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/text v0.25.0
+)
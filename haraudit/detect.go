@@ -0,0 +1,204 @@
+package haraudit
+
+import (
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Category identifies a kind of sensitive data recognized by a [Detector].
+type Category string
+
+// Categories reported by the built-in detectors.
+const (
+	CategoryEmail      Category = "email"           // Email address.
+	CategoryPhone      Category = "phone"           // Phone number.
+	CategoryIP         Category = "ip"              // IPv4 or IPv6 address.
+	CategoryGeo        Category = "geolocation"     // Latitude/longitude pair.
+	CategoryPIIKey     Category = "pii-key"         // Value stored under a well-known personal data key (e.g. "firstName").
+	CategoryJWT        Category = "jwt"             // JSON Web Token.
+	CategoryBearer     Category = "bearer-token"    // Bearer or Basic credentials in an Authorization-like value.
+	CategoryAWSKey     Category = "aws-access-key"  // AWS access key ID.
+	CategoryAPIKey     Category = "api-key"         // Vendor API key with a recognizable prefix (GitHub, Slack, Stripe, Google).
+	CategoryPrivateKey Category = "private-key"     // PEM encoded private key.
+	CategorySecretKey  Category = "secret-key-name" // Value stored under a credential-like name (e.g. "password", "api_key").
+)
+
+// Detector classifies a single value. Name is the header, cookie, parameter
+// or JSON key the value was found under, in its original casing.
+type Detector struct {
+	Category Category
+	Match    func(name, value string) bool
+}
+
+// PIIDetectors recognize personal data. They favor precision over recall: a
+// value must match in its entirety, so free text mentioning an address is not
+// flagged.
+var PIIDetectors = []Detector{
+	{CategoryEmail, func(_, v string) bool { return IsEmail(v) }},
+	{CategoryPhone, func(_, v string) bool { return IsPhone(v) }},
+	{CategoryIP, func(_, v string) bool { return IsIP(v) }},
+	{CategoryGeo, func(_, v string) bool { return IsGeoPair(v) }},
+	{CategoryPIIKey, func(n, v string) bool { return v != "" && IsPIIKey(n) }},
+}
+
+// SecretDetectors recognize credentials.
+var SecretDetectors = []Detector{
+	{CategoryJWT, func(_, v string) bool { return IsJWT(v) }},
+	{CategoryBearer, func(_, v string) bool { return IsAuthScheme(v) }},
+	{CategoryAWSKey, func(_, v string) bool { return IsAWSAccessKey(v) }},
+	{CategoryAPIKey, func(_, v string) bool { return IsVendorAPIKey(v) }},
+	{CategoryPrivateKey, func(_, v string) bool { return IsPrivateKey(v) }},
+	{CategorySecretKey, func(n, v string) bool { return v != "" && IsSecretKey(n) }},
+}
+
+// DefaultDetectors is the detector set used by [DataInventory].
+var DefaultDetectors = append(append([]Detector{}, PIIDetectors...), SecretDetectors...)
+
+var (
+	emailRe  = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,24}$`)
+	phoneRe  = regexp.MustCompile(`^(\+[1-9]\d{7,14}|\+?(\d{1,3}[ .-]?)?\(?\d{2,4}\)?([ .-]\d{2,4}){2,4})$`)
+	dateRe   = regexp.MustCompile(`^(\d{4}[-./]\d{1,2}[-./]\d{1,2}|\d{1,2}[-./]\d{1,2}[-./]\d{4})$`)
+	geoRe    = regexp.MustCompile(`^(-?\d{1,2}\.\d{3,})\s*,\s*(-?\d{1,3}\.\d{3,})$`)
+	jwtRe    = regexp.MustCompile(`^eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}$`)
+	authRe   = regexp.MustCompile(`^(?i:bearer|basic|token|digest)\s+[A-Za-z0-9._~+/=-]{8,}`)
+	awsRe    = regexp.MustCompile(`^(AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}$`)
+	apiKeyRe = regexp.MustCompile(`^(gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{60,}|xox[abprs]-[A-Za-z0-9-]{10,}|(sk|rk|pk)_(live|test)_[A-Za-z0-9]{16,}|AIza[0-9A-Za-z_-]{35})$`)
+)
+
+// IsEmail reports whether v is an email address.
+func IsEmail(v string) bool {
+	return len(v) <= 254 && emailRe.MatchString(v)
+}
+
+// IsPhone reports whether v looks like a phone number. Bare digit runs are
+// rejected because they are indistinguishable from identifiers and
+// timestamps; a leading "+" or separators are required.
+func IsPhone(v string) bool {
+	if !phoneRe.MatchString(v) {
+		return false
+	}
+	digits := 0
+	for _, r := range v {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	if digits < 8 || digits > 15 {
+		return false
+	}
+	// Dates and IPv4 addresses share the shape of separated digit groups.
+	return !dateRe.MatchString(v) && !IsIP(v)
+}
+
+// IsIP reports whether v is an IPv4 or IPv6 address.
+func IsIP(v string) bool {
+	if !strings.ContainsAny(v, ".:") {
+		return false
+	}
+	addr, err := netip.ParseAddr(v)
+	if err != nil {
+		return false
+	}
+	return addr.Is6() || strings.Count(v, ".") == 3
+}
+
+// IsGeoPair reports whether v is a "latitude,longitude" pair with at least
+// three decimals on each coordinate.
+func IsGeoPair(v string) bool {
+	m := geoRe.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return false
+	}
+	lat, _ := strconv.ParseFloat(m[1], 64)
+	lng, _ := strconv.ParseFloat(m[2], 64)
+	return validLatLng(lat, lng)
+}
+
+func validLatLng(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180 && (lat != 0 || lng != 0)
+}
+
+// IsJWT reports whether v is a compact serialized JSON Web Token.
+func IsJWT(v string) bool {
+	return jwtRe.MatchString(v)
+}
+
+// IsAuthScheme reports whether v carries credentials prefixed with an HTTP
+// authentication scheme such as "Bearer".
+func IsAuthScheme(v string) bool {
+	return authRe.MatchString(v)
+}
+
+// IsAWSAccessKey reports whether v is an AWS access key ID.
+func IsAWSAccessKey(v string) bool {
+	return awsRe.MatchString(v)
+}
+
+// IsVendorAPIKey reports whether v is an API key with a well-known vendor
+// prefix.
+func IsVendorAPIKey(v string) bool {
+	return apiKeyRe.MatchString(v)
+}
+
+// IsPrivateKey reports whether v contains a PEM encoded private key.
+func IsPrivateKey(v string) bool {
+	return strings.Contains(v, "-----BEGIN ") && strings.Contains(v, "PRIVATE KEY-----")
+}
+
+var piiKeys = map[string]bool{
+	"email": true, "emailaddress": true, "mail": true,
+	"firstname": true, "lastname": true, "fullname": true, "givenname": true, "familyname": true, "surname": true,
+	"birthdate": true, "dateofbirth": true, "dob": true, "birthday": true,
+	"phone": true, "phonenumber": true, "mobile": true, "mobilenumber": true, "telephone": true,
+	"address": true, "streetaddress": true, "street": true, "postalcode": true, "zipcode": true, "zip": true,
+	"ssn": true, "socialsecuritynumber": true, "nationalid": true, "passport": true, "passportnumber": true, "taxid": true,
+	"gender": true, "iban": true, "creditcard": true, "cardnumber": true, "ccnumber": true,
+	"latitude": true, "longitude": true,
+}
+
+var secretKeys = map[string]bool{
+	"password": true, "passwd": true, "pwd": true, "secret": true, "clientsecret": true,
+	"token": true, "accesstoken": true, "refreshtoken": true, "idtoken": true, "authtoken": true, "sessiontoken": true,
+	"apikey": true, "xapikey": true, "apisecret": true, "privatekey": true,
+	"authorization": true, "proxyauthorization": true, "xauthtoken": true, "xamzsecuritytoken": true,
+}
+
+// IsPIIKey reports whether name is a well-known personal data field name.
+// Matching ignores case, "-" and "_".
+func IsPIIKey(name string) bool {
+	return piiKeys[normalizeKey(name)]
+}
+
+// IsSecretKey reports whether name is a well-known credential field name.
+// Matching ignores case, "-" and "_".
+func IsSecretKey(name string) bool {
+	return secretKeys[normalizeKey(name)]
+}
+
+func normalizeKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == '_' || r == '.':
+			return -1
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return r
+	}, name)
+}
+
+// Mask hides most of v, keeping a short prefix and suffix so samples remain
+// recognizable in a report.
+func Mask(v string) string {
+	r := []rune(v)
+	if len(r) <= 6 {
+		return strings.Repeat("*", len(r))
+	}
+	keep := 2
+	if len(r) > 16 {
+		keep = 4
+	}
+	return string(r[:keep]) + strings.Repeat("*", min(len(r)-2*keep, 8)) + string(r[len(r)-keep:])
+}
@@ -0,0 +1,310 @@
+// Package haraudit inspects HAR files for personal data and credentials
+// before they are shared.
+package haraudit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// maxSamples bounds the number of masked sample values kept per category.
+const maxSamples = 3
+
+// Location identifies the part of an entry a value was read from.
+type Location string
+
+// Locations scanned by [DataInventory].
+const (
+	RequestHeader  Location = "request.headers"
+	RequestCookie  Location = "request.cookies"
+	QueryParam     Location = "request.queryString"
+	RequestBody    Location = "request.postData"
+	ResponseHeader Location = "response.headers"
+	ResponseCookie Location = "response.cookies"
+	ResponseBody   Location = "response.content"
+)
+
+// Field identifies where a value was found, independently of the entry it
+// belongs to. For bodies, Name is a dotted JSON path where array elements are
+// written as "[]" (e.g. "users[].email").
+type Field struct {
+	Location Location
+	Name     string
+}
+
+// String returns the field path, e.g. "request.headers.Authorization".
+func (f Field) String() string {
+	return string(f.Location) + "." + f.Name
+}
+
+// CategoryReport summarizes the occurrences of one [Category].
+type CategoryReport struct {
+	Category Category
+	Count    int      // Number of values classified in this category.
+	Fields   []Field  // Distinct fields where the category appears, sorted by path.
+	Samples  []string // Masked sample values.
+}
+
+// Paths returns the field paths where the category appears.
+func (c *CategoryReport) Paths() []string {
+	paths := make([]string, len(c.Fields))
+	for i, f := range c.Fields {
+		paths[i] = f.String()
+	}
+	return paths
+}
+
+// Inventory describes the kinds of sensitive data contained in a HAR.
+type Inventory struct {
+	Entries    int                          // Number of entries scanned.
+	Categories map[Category]*CategoryReport // Findings per category. Categories without findings are absent.
+}
+
+// Sorted returns the category reports ordered by descending count, then by
+// category name.
+func (inv *Inventory) Sorted() []*CategoryReport {
+	reports := make([]*CategoryReport, 0, len(inv.Categories))
+	for _, r := range inv.Categories {
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Count != reports[j].Count {
+			return reports[i].Count > reports[j].Count
+		}
+		return reports[i].Category < reports[j].Category
+	})
+	return reports
+}
+
+// Rule asks a sanitizer to redact the value stored at Field.
+type Rule struct {
+	Field    Field
+	Category Category // Category that triggered the rule.
+}
+
+// ToRules converts the inventory into one redaction rule per distinct field,
+// sorted by field path. When several categories were found at the same field
+// the lexically smallest
+// category is reported.
+func (inv *Inventory) ToRules() []Rule {
+	seen := make(map[Field]Category)
+	for cat, r := range inv.Categories {
+		for _, f := range r.Fields {
+			if prev, ok := seen[f]; !ok || cat < prev {
+				seen[f] = cat
+			}
+		}
+	}
+	rules := make([]Rule, 0, len(seen))
+	for f, cat := range seen {
+		rules = append(rules, Rule{Field: f, Category: cat})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Field.String() < rules[j].Field.String()
+	})
+	return rules
+}
+
+// DataInventory scans headers, cookies, query parameters and JSON bodies of
+// every entry with [DefaultDetectors].
+func DataInventory(h *harfile.HAR) *Inventory {
+	return DataInventoryWith(h, DefaultDetectors)
+}
+
+// DataInventoryWith is like [DataInventory] but uses the given detectors.
+func DataInventoryWith(h *harfile.HAR, detectors []Detector) *Inventory {
+	s := &scanner{
+		detectors: detectors,
+		inv:       &Inventory{Categories: make(map[Category]*CategoryReport)},
+		fields:    make(map[Category]map[Field]bool),
+	}
+	if h != nil && h.Log != nil {
+		for _, e := range h.Log.Entries {
+			if e == nil {
+				continue
+			}
+			s.inv.Entries++
+			s.scanEntry(e)
+		}
+	}
+	for cat, r := range s.inv.Categories {
+		for f := range s.fields[cat] {
+			r.Fields = append(r.Fields, f)
+		}
+		sort.Slice(r.Fields, func(i, j int) bool {
+			return r.Fields[i].String() < r.Fields[j].String()
+		})
+	}
+	return s.inv
+}
+
+type scanner struct {
+	detectors []Detector
+	inv       *Inventory
+	fields    map[Category]map[Field]bool
+}
+
+func (s *scanner) scanEntry(e *harfile.Entry) {
+	if req := e.Request; req != nil {
+		s.scanPairs(RequestHeader, req.Headers)
+		s.scanCookies(RequestCookie, req.Cookies)
+		if len(req.QueryString) > 0 {
+			s.scanPairs(QueryParam, req.QueryString)
+		} else if u, err := url.Parse(req.URL); err == nil {
+			for name, values := range u.Query() {
+				for _, v := range values {
+					s.classify(Field{QueryParam, name}, name, v)
+				}
+			}
+		}
+		if pd := req.PostData; pd != nil {
+			for _, p := range pd.Params {
+				if p != nil {
+					s.classify(Field{RequestBody, p.Name}, p.Name, p.Value)
+				}
+			}
+			if body, err := pd.DecodedText(); err == nil && isJSON(pd.MimeType) {
+				s.scanJSON(RequestBody, body)
+			}
+		}
+	}
+	if resp := e.Response; resp != nil {
+		s.scanPairs(ResponseHeader, resp.Headers)
+		s.scanCookies(ResponseCookie, resp.Cookies)
+		if c := resp.Content; c != nil && isJSON(c.MimeType) {
+			body := []byte(c.Text)
+			if c.Encoding == "base64" {
+				decoded, err := base64.StdEncoding.DecodeString(c.Text)
+				if err != nil {
+					return
+				}
+				body = decoded
+			}
+			s.scanJSON(ResponseBody, body)
+		}
+	}
+}
+
+func (s *scanner) scanPairs(loc Location, pairs []*harfile.NameValuePair) {
+	for _, p := range pairs {
+		if p != nil {
+			s.classify(Field{loc, p.Name}, p.Name, p.Value)
+		}
+	}
+}
+
+func (s *scanner) scanCookies(loc Location, cookies []*harfile.Cookie) {
+	for _, c := range cookies {
+		if c != nil {
+			s.classify(Field{loc, c.Name}, c.Name, c.Value)
+		}
+	}
+}
+
+func (s *scanner) scanJSON(loc Location, body []byte) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return
+	}
+	s.walkJSON(loc, "", "", v)
+}
+
+func (s *scanner) walkJSON(loc Location, path, key string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		if lat, lng, ok := latLngObject(v); ok && validLatLng(lat, lng) {
+			s.record(CategoryGeo, Field{loc, jsonPath(path)}, "")
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			s.walkJSON(loc, joinPath(path, k), k, v[k])
+		}
+	case []any:
+		for _, elem := range v {
+			s.walkJSON(loc, path+"[]", key, elem)
+		}
+	case string:
+		s.classify(Field{loc, jsonPath(path)}, key, v)
+	case json.Number:
+		s.classify(Field{loc, jsonPath(path)}, key, v.String())
+	}
+}
+
+func (s *scanner) classify(f Field, name, value string) {
+	for _, d := range s.detectors {
+		if d.Match(name, value) {
+			s.record(d.Category, f, value)
+		}
+	}
+}
+
+func (s *scanner) record(cat Category, f Field, value string) {
+	r := s.inv.Categories[cat]
+	if r == nil {
+		r = &CategoryReport{Category: cat}
+		s.inv.Categories[cat] = r
+		s.fields[cat] = make(map[Field]bool)
+	}
+	r.Count++
+	s.fields[cat][f] = true
+	if value == "" || len(r.Samples) >= maxSamples {
+		return
+	}
+	if masked := Mask(value); !slices.Contains(r.Samples, masked) {
+		r.Samples = append(r.Samples, masked)
+	}
+}
+
+func latLngObject(m map[string]any) (lat, lng float64, ok bool) {
+	var hasLat, hasLng bool
+	for k, v := range m {
+		n, isNum := v.(json.Number)
+		if !isNum {
+			continue
+		}
+		f, err := n.Float64()
+		if err != nil {
+			continue
+		}
+		switch normalizeKey(k) {
+		case "lat", "latitude":
+			lat, hasLat = f, true
+		case "lng", "lon", "long", "longitude":
+			lng, hasLng = f, true
+		}
+	}
+	return lat, lng, hasLat && hasLng
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func jsonPath(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return path
+}
+
+func isJSON(mimeType string) bool {
+	mt, _, _ := strings.Cut(strings.ToLower(mimeType), ";")
+	mt = strings.TrimSpace(mt)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json") || mt == "text/json"
+}
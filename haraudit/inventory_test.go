@@ -0,0 +1,100 @@
+package haraudit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestDataInventory(t *testing.T) {
+	e := &harfile.Entry{
+		Request: &harfile.Request{
+			Method: "POST",
+			URL:    "https://example.com/api/users?email=ada@example.com&page=2",
+			Headers: []*harfile.NameValuePair{
+				{Name: "Authorization", Value: "Bearer abcdefgh12345678"},
+				{Name: "Accept", Value: "application/json"},
+			},
+			PostData: &harfile.PostData{
+				MimeType: "application/json",
+				Text:     `{"user":{"firstName":"Ada","phones":["+33612345678"]},"pos":{"lat":48.8583,"lng":2.2944}}`,
+			},
+		},
+		Response: &harfile.Response{
+			Status: 200,
+			Content: &harfile.Content{
+				MimeType: "application/json",
+				Text:     `{"token":"s3cr3t-value","items":[{"ip":"10.0.0.1"},{"ip":"10.0.0.2"}]}`,
+			},
+		},
+	}
+	inv := DataInventory(&harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e, nil}}})
+
+	if inv.Entries != 1 {
+		t.Errorf("Entries = %d, want 1", inv.Entries)
+	}
+	want := map[Category][]string{
+		CategoryEmail:     {"request.queryString.email"},
+		CategoryBearer:    {"request.headers.Authorization"},
+		CategorySecretKey: {"request.headers.Authorization", "response.content.token"},
+		CategoryPIIKey:    {"request.postData.user.firstName", "request.queryString.email"},
+		CategoryPhone:     {"request.postData.user.phones[]"},
+		CategoryGeo:       {"request.postData.pos"},
+		CategoryIP:        {"response.content.items[].ip"},
+	}
+	got := make(map[Category][]string)
+	for cat, r := range inv.Categories {
+		got[cat] = r.Paths()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("categories =\n%v\nwant\n%v", got, want)
+	}
+	if r := inv.Categories[CategoryIP]; r.Count != 2 || !reflect.DeepEqual(r.Samples, []string{"10****.1", "10****.2"}) {
+		t.Errorf("ip report = %+v", r)
+	}
+	var order []Category
+	for _, r := range inv.Sorted() {
+		order = append(order, r.Category)
+	}
+	wantOrder := []Category{CategoryIP, CategoryPIIKey, CategorySecretKey, CategoryBearer, CategoryEmail, CategoryGeo, CategoryPhone}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("Sorted = %v, want %v", order, wantOrder)
+	}
+
+	rules := inv.ToRules()
+	var fields []string
+	for _, r := range rules {
+		fields = append(fields, r.Field.String()+" "+string(r.Category))
+	}
+	wantFields := []string{
+		"request.headers.Authorization bearer-token",
+		"request.postData.pos geolocation",
+		"request.postData.user.firstName pii-key",
+		"request.postData.user.phones[] phone",
+		"request.queryString.email email",
+		"response.content.items[].ip ip",
+		"response.content.token secret-key-name",
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("ToRules =\n%q\nwant\n%q", fields, wantFields)
+	}
+}
+
+func TestDataInventoryNoFindings(t *testing.T) {
+	e := &harfile.Entry{
+		Request: &harfile.Request{
+			Method:  "GET",
+			URL:     "https://example.com/?page=2",
+			Headers: []*harfile.NameValuePair{{Name: "Accept", Value: "*/*"}},
+		},
+		Response: &harfile.Response{Status: 204, Content: &harfile.Content{}},
+	}
+	inv := DataInventory(&harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e}}})
+	if len(inv.Categories) != 0 || len(inv.ToRules()) != 0 {
+		t.Errorf("found %v in a clean capture", inv.Categories)
+	}
+	if inv := DataInventory(nil); inv.Entries != 0 {
+		t.Error("nil HAR scanned")
+	}
+}
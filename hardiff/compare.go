@@ -0,0 +1,99 @@
+package hardiff
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Comparison pairs the endpoints of two captures.
+type Comparison struct {
+	Added     []*EndpointStats  // Endpoints only present in the second capture.
+	Removed   []*EndpointStats  // Endpoints only present in the first capture.
+	Changed   []*EndpointChange // Endpoints present in both whose behavior differs.
+	Unchanged []*EndpointChange // Endpoints present in both that behave the same.
+}
+
+// EndpointChange holds the statistics of an endpoint in both captures.
+type EndpointChange struct {
+	Before *EndpointStats
+	After  *EndpointStats
+}
+
+// Endpoint returns the compared endpoint.
+func (c *EndpointChange) Endpoint() Endpoint {
+	return c.After.Endpoint
+}
+
+// LatencyDelta returns the difference of p95 latencies, in milliseconds.
+func (c *EndpointChange) LatencyDelta() float64 {
+	return c.After.P95 - c.Before.P95
+}
+
+// StatusChanged reports whether the endpoint answered with a different set
+// of status codes.
+func (c *EndpointChange) StatusChanged() bool {
+	return !slices.Equal(sortedKeys(c.Before.Statuses), sortedKeys(c.After.Statuses))
+}
+
+// Compare compares the endpoint coverage of a and b. An endpoint is changed
+// when its set of response statuses differs, when its p95 latency moved by
+// more than the latency threshold, or when its mean response size moved by
+// more than the size threshold. Every list is sorted by endpoint.
+func Compare(a, b *harfile.HAR, opts ...Option) *Comparison {
+	o := newOptions(opts)
+	before, after := Coverage(a, opts...), Coverage(b, opts...)
+
+	c := &Comparison{}
+	for ep, s := range after {
+		prev, ok := before[ep]
+		if !ok {
+			c.Added = append(c.Added, s)
+			continue
+		}
+		change := &EndpointChange{Before: prev, After: s}
+		if change.StatusChanged() || o.latencyChanged(change) || o.sizeChanged(change) {
+			c.Changed = append(c.Changed, change)
+		} else {
+			c.Unchanged = append(c.Unchanged, change)
+		}
+	}
+	for ep, s := range before {
+		if _, ok := after[ep]; !ok {
+			c.Removed = append(c.Removed, s)
+		}
+	}
+
+	sortStats(c.Added)
+	sortStats(c.Removed)
+	sortChanges(c.Changed)
+	sortChanges(c.Unchanged)
+	return c
+}
+
+func (o *options) latencyChanged(c *EndpointChange) bool {
+	delta := math.Abs(c.LatencyDelta())
+	return delta > o.latencyAbs && delta > o.latencyRel*c.Before.P95
+}
+
+func (o *options) sizeChanged(c *EndpointChange) bool {
+	before := float64(c.Before.Bytes) / float64(c.Before.Count)
+	after := float64(c.After.Bytes) / float64(c.After.Count)
+	return math.Abs(after-before) > o.sizeRel*before
+}
+
+func sortStats(s []*EndpointStats) {
+	sort.Slice(s, func(i, j int) bool { return s[i].Endpoint.less(s[j].Endpoint) })
+}
+
+func sortChanges(c []*EndpointChange) {
+	sort.Slice(c, func(i, j int) bool { return c[i].Endpoint().less(c[j].Endpoint()) })
+}
+
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	return slices.Sorted(maps.Keys(m))
+}
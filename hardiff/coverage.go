@@ -0,0 +1,128 @@
+// Package hardiff compares HAR captures, typically recorded by successive CI
+// runs, and summarizes how their traffic changed.
+package hardiff
+
+import (
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Endpoint identifies a request target independently of its query string.
+type Endpoint struct {
+	Method string
+	Host   string
+	Path   string
+}
+
+// String returns the endpoint as "METHOD host/path".
+func (e Endpoint) String() string {
+	return e.Method + " " + e.Host + e.Path
+}
+
+func (e Endpoint) less(o Endpoint) bool {
+	if e.Host != o.Host {
+		return e.Host < o.Host
+	}
+	if e.Path != o.Path {
+		return e.Path < o.Path
+	}
+	return e.Method < o.Method
+}
+
+// EndpointStats aggregates the entries sent to one [Endpoint].
+type EndpointStats struct {
+	Endpoint Endpoint
+	Count    int           // Number of entries.
+	Statuses map[int64]int // Number of entries per response status.
+	P50      float64       // Median of Entry.Time, in milliseconds.
+	P95      float64       // 95th percentile of Entry.Time, in milliseconds.
+	Bytes    int64         // Total response body bytes, counting only known sizes.
+	times    []float64
+}
+
+// Coverage groups the entries of h by endpoint. Only [WithGroupBy] applies
+// among opts.
+func Coverage(h *harfile.HAR, opts ...Option) map[Endpoint]*EndpointStats {
+	o := newOptions(opts)
+	stats := make(map[Endpoint]*EndpointStats)
+	for _, e := range entries(h) {
+		ep, ok := endpointOf(e, o.groupBy)
+		if !ok {
+			continue
+		}
+		s := stats[ep]
+		if s == nil {
+			s = &EndpointStats{Endpoint: ep, Statuses: make(map[int64]int)}
+			stats[ep] = s
+		}
+		s.Count++
+		if e.Response != nil {
+			s.Statuses[e.Response.Status]++
+		}
+		s.Bytes += responseBytes(e)
+		if e.Time >= 0 {
+			s.times = append(s.times, e.Time)
+		}
+	}
+	for _, s := range stats {
+		slices.Sort(s.times)
+		s.P50 = percentile(s.times, 50)
+		s.P95 = percentile(s.times, 95)
+	}
+	return stats
+}
+
+func entries(h *harfile.HAR) []*harfile.Entry {
+	if h == nil || h.Log == nil {
+		return nil
+	}
+	return h.Log.Entries
+}
+
+func endpointOf(e *harfile.Entry, group func(string) string) (Endpoint, bool) {
+	if e == nil || e.Request == nil {
+		return Endpoint{}, false
+	}
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return Endpoint{}, false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if group != nil {
+		path = group(path)
+	}
+	return Endpoint{
+		Method: strings.ToUpper(e.Request.Method),
+		Host:   strings.ToLower(u.Host),
+		Path:   path,
+	}, true
+}
+
+// responseBytes returns the transferred body size of e's response, falling
+// back to the content size, or 0 when neither is known.
+func responseBytes(e *harfile.Entry) int64 {
+	if size, ok := e.Response.KnownBodySize(); ok {
+		return size
+	}
+	if e.Response == nil {
+		return 0
+	}
+	size, _ := e.Response.Content.KnownSize()
+	return size
+}
+
+// percentile returns the nearest-rank percentile p of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
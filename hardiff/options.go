@@ -0,0 +1,71 @@
+package hardiff
+
+// Option configures [Compare] and [Summary].
+type Option func(*options)
+
+type options struct {
+	latencyAbs float64
+	latencyRel float64
+	sizeRel    float64
+	limit      int
+	topLatency int
+	firstParty []string
+	groupBy    func(path string) string
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		latencyAbs: 50,
+		latencyRel: 0.2,
+		sizeRel:    0.1,
+		limit:      10,
+		topLatency: 5,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithLatencyThreshold sets how much the p95 latency of an endpoint must
+// move to count as a change: by more than abs milliseconds and by more than
+// rel times the previous value. The default is 50 ms and 20%.
+func WithLatencyThreshold(abs, rel float64) Option {
+	return func(o *options) {
+		o.latencyAbs, o.latencyRel = abs, rel
+	}
+}
+
+// WithSizeThreshold sets the relative change of the mean response size above
+// which an endpoint counts as changed. The default is 10%.
+func WithSizeThreshold(rel float64) Option {
+	return func(o *options) {
+		o.sizeRel = rel
+	}
+}
+
+// WithLimit bounds the length of the lists reported by [Summary]. The
+// default is 10; latency regressions are always limited to the top 5.
+func WithLimit(n int) Option {
+	return func(o *options) {
+		o.limit = n
+	}
+}
+
+// WithFirstParty lists the domains considered first party; their subdomains
+// are first party too. By default the domain of the first request of each
+// capture is used.
+func WithFirstParty(domains ...string) Option {
+	return func(o *options) {
+		o.firstParty = append(o.firstParty, domains...)
+	}
+}
+
+// WithGroupBy maps the path of each endpoint with group, such as a
+// harkit.PathTemplater, so that "/users/1" and "/users/2" are compared as
+// one endpoint.
+func WithGroupBy(group func(path string) string) Option {
+	return func(o *options) {
+		o.groupBy = group
+	}
+}
@@ -0,0 +1,272 @@
+package hardiff
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+	"github.com/Mathious6/harkit/internal/markdown"
+)
+
+// Truncated is a bounded list. Omitted counts the items left out.
+type Truncated[T any] struct {
+	Items   []T `json:"items"`
+	Omitted int `json:"omitted,omitempty"`
+}
+
+// Len returns the number of items before truncation.
+func (t Truncated[T]) Len() int {
+	return len(t.Items) + t.Omitted
+}
+
+func truncate[T any](items []T, limit int) Truncated[T] {
+	if limit < 0 || len(items) <= limit {
+		return Truncated[T]{Items: items}
+	}
+	return Truncated[T]{Items: items[:limit], Omitted: len(items) - limit}
+}
+
+// LatencyRegression describes an endpoint whose p95 latency increased.
+type LatencyRegression struct {
+	Endpoint string  `json:"endpoint"`
+	Before   float64 `json:"before"` // p95 in the first capture, in milliseconds.
+	After    float64 `json:"after"`  // p95 in the second capture, in milliseconds.
+}
+
+// ChangeSummary is a bounded, deterministic description of how traffic
+// changed between two captures, suitable for a pull-request comment.
+type ChangeSummary struct {
+	Added       Truncated[string]            `json:"added"`       // Endpoints only in the second capture.
+	Removed     Truncated[string]            `json:"removed"`     // Endpoints only in the first capture.
+	Changed     Truncated[string]            `json:"changed"`     // Endpoints whose status, latency or size changed.
+	Regressions Truncated[LatencyRegression] `json:"regressions"` // Largest p95 latency increases first.
+	NewHosts    Truncated[string]            `json:"newHosts"`    // Third-party hosts only contacted by the second capture.
+	NewCookies  Truncated[string]            `json:"newCookies"`  // Cookies only set in the second capture.
+	BytesBefore int64                        `json:"bytesBefore"` // Total response bytes of the first capture.
+	BytesAfter  int64                        `json:"bytesAfter"`  // Total response bytes of the second capture.
+}
+
+// ByteDelta returns the change in total response bytes.
+func (s *ChangeSummary) ByteDelta() int64 {
+	return s.BytesAfter - s.BytesBefore
+}
+
+// IsEmpty reports whether the summary describes no change at all.
+func (s *ChangeSummary) IsEmpty() bool {
+	return s.Added.Len() == 0 && s.Removed.Len() == 0 && s.Changed.Len() == 0 &&
+		s.Regressions.Len() == 0 && s.NewHosts.Len() == 0 && s.NewCookies.Len() == 0 &&
+		s.ByteDelta() == 0
+}
+
+// Summary compares a and b with [Compare] and condenses the result. Lists are
+// sorted and limited to the configured length, latency regressions to the
+// five largest.
+func Summary(a, b *harfile.HAR, opts ...Option) *ChangeSummary {
+	o := newOptions(opts)
+	cmp := Compare(a, b, opts...)
+
+	s := &ChangeSummary{
+		Added:   truncate(endpointNames(cmp.Added), o.limit),
+		Removed: truncate(endpointNames(cmp.Removed), o.limit),
+	}
+
+	var changed []string
+	var regressions []LatencyRegression
+	for _, c := range cmp.Changed {
+		changed = append(changed, c.Endpoint().String())
+		if c.LatencyDelta() > 0 && o.latencyChanged(c) {
+			regressions = append(regressions, LatencyRegression{
+				Endpoint: c.Endpoint().String(),
+				Before:   c.Before.P95,
+				After:    c.After.P95,
+			})
+		}
+	}
+	sort.SliceStable(regressions, func(i, j int) bool {
+		return regressions[i].After-regressions[i].Before > regressions[j].After-regressions[j].Before
+	})
+	s.Changed = truncate(changed, o.limit)
+	s.Regressions = truncate(regressions, min(o.topLatency, o.limit))
+
+	firstParty := o.firstParty
+	if len(firstParty) == 0 {
+		firstParty = append(inferFirstParty(a), inferFirstParty(b)...)
+	}
+	hostsA := hosts(a)
+	var newHosts []string
+	for _, host := range sortedKeys(hosts(b)) {
+		if !hostsA[host] && !isFirstParty(host, firstParty) {
+			newHosts = append(newHosts, host)
+		}
+	}
+	s.NewHosts = truncate(newHosts, o.limit)
+
+	cookiesA := cookies(a)
+	var newCookies []string
+	for _, c := range sortedKeys(cookies(b)) {
+		if !cookiesA[c] {
+			newCookies = append(newCookies, c)
+		}
+	}
+	s.NewCookies = truncate(newCookies, o.limit)
+
+	for _, e := range entries(a) {
+		if e != nil {
+			s.BytesBefore += responseBytes(e)
+		}
+	}
+	for _, e := range entries(b) {
+		if e != nil {
+			s.BytesAfter += responseBytes(e)
+		}
+	}
+	return s
+}
+
+func endpointNames(stats []*EndpointStats) []string {
+	names := make([]string, len(stats))
+	for i, s := range stats {
+		names[i] = s.Endpoint.String()
+	}
+	return names
+}
+
+func hosts(h *harfile.HAR) map[string]bool {
+	set := make(map[string]bool)
+	for _, e := range entries(h) {
+		if ep, ok := endpointOf(e, nil); ok {
+			set[hostname(ep.Host)] = true
+		}
+	}
+	return set
+}
+
+func cookies(h *harfile.HAR) map[string]bool {
+	set := make(map[string]bool)
+	for _, e := range entries(h) {
+		if e == nil || e.Response == nil {
+			continue
+		}
+		for _, c := range e.Response.Cookies {
+			if c == nil {
+				continue
+			}
+			key := c.Name
+			if c.Domain != "" {
+				key += " (" + strings.TrimPrefix(c.Domain, ".") + ")"
+			}
+			set[key] = true
+		}
+	}
+	return set
+}
+
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
+
+// inferFirstParty returns the domain of the first request of h, reduced to
+// its last two labels.
+func inferFirstParty(h *harfile.HAR) []string {
+	for _, e := range entries(h) {
+		if ep, ok := endpointOf(e, nil); ok {
+			host := hostname(ep.Host)
+			if net.ParseIP(host) != nil {
+				return []string{host}
+			}
+			labels := strings.Split(host, ".")
+			return []string{strings.Join(labels[max(len(labels)-2, 0):], ".")}
+		}
+	}
+	return nil
+}
+
+func isFirstParty(host string, domains []string) bool {
+	return slices.ContainsFunc(domains, func(d string) bool {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		return host == d || strings.HasSuffix(host, "."+d)
+	})
+}
+
+// WriteMarkdown renders the summary as GitHub flavored Markdown.
+func (s *ChangeSummary) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("### Traffic changes\n\n")
+	if s.IsEmpty() {
+		b.WriteString("No traffic changes.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Change | Count |\n|---|---:|\n")
+	fmt.Fprintf(&b, "| Added endpoints | %d |\n", s.Added.Len())
+	fmt.Fprintf(&b, "| Removed endpoints | %d |\n", s.Removed.Len())
+	fmt.Fprintf(&b, "| Changed endpoints | %d |\n", s.Changed.Len())
+	fmt.Fprintf(&b, "| New third-party hosts | %d |\n", s.NewHosts.Len())
+	fmt.Fprintf(&b, "| New cookies | %d |\n", s.NewCookies.Len())
+	fmt.Fprintf(&b, "\n**Response bytes:** %s → %s (%s)\n", formatBytes(s.BytesBefore), formatBytes(s.BytesAfter), formatDelta(s.ByteDelta()))
+
+	if s.Regressions.Len() > 0 {
+		b.WriteString("\n#### Latency regressions (p95)\n\n| Endpoint | Before | After | Δ |\n|---|---:|---:|---:|\n")
+		for _, r := range s.Regressions.Items {
+			fmt.Fprintf(&b, "| %s | %.0f ms | %.0f ms | +%.0f ms |\n", markdown.Code(r.Endpoint), r.Before, r.After, r.After-r.Before)
+		}
+		writeOmitted(&b, s.Regressions.Omitted)
+	}
+	writeList(&b, "Added endpoints", s.Added)
+	writeList(&b, "Removed endpoints", s.Removed)
+	writeList(&b, "Changed endpoints", s.Changed)
+	writeList(&b, "New third-party hosts", s.NewHosts)
+	writeList(&b, "New cookies", s.NewCookies)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeList(b *strings.Builder, title string, list Truncated[string]) {
+	if list.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n#### %s\n\n", title)
+	for _, item := range list.Items {
+		fmt.Fprintf(b, "- %s\n", markdown.Code(item))
+	}
+	writeOmitted(b, list.Omitted)
+}
+
+func writeOmitted(b *strings.Builder, n int) {
+	if n > 0 {
+		fmt.Fprintf(b, "\n_…and %d more_\n", n)
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := abs / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatDelta(n int64) string {
+	if n >= 0 {
+		return "+" + formatBytes(n)
+	}
+	return formatBytes(n)
+}
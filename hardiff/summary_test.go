@@ -0,0 +1,139 @@
+package hardiff
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func entry(method, url string, status int, ms float64, size int64, cookies ...string) *harfile.Entry {
+	resp := &harfile.Response{Status: int64(status), Content: &harfile.Content{}, BodySize: size}
+	for _, c := range cookies {
+		resp.Headers = append(resp.Headers, &harfile.NameValuePair{Name: "Set-Cookie", Value: c + "=1; Domain=example.com"})
+		resp.Cookies = append(resp.Cookies, &harfile.Cookie{Name: c, Value: "1", Domain: "example.com"})
+	}
+	return &harfile.Entry{
+		StartedDateTime: start,
+		Time:            ms,
+		Request:         &harfile.Request{Method: method, URL: url},
+		Response:        resp,
+	}
+}
+
+func capture(entries ...*harfile.Entry) *harfile.HAR {
+	return &harfile.HAR{Log: &harfile.Log{Entries: entries}}
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs, rerun with -update if intended:\n%s", name, got)
+	}
+}
+
+func TestSummaryMarkdown(t *testing.T) {
+	before := capture(
+		entry("GET", "https://example.com/", 200, 100, 5000),
+		entry("GET", "https://example.com/api/users", 200, 80, 1200),
+		entry("GET", "https://example.com/api/slow", 200, 100, 300),
+		entry("GET", "https://example.com/api/slower", 200, 200, 300),
+		entry("GET", "https://example.com/legacy", 200, 40, 100),
+	)
+	after := capture(
+		entry("GET", "https://example.com/", 200, 110, 5200, "session"),
+		entry("GET", "https://example.com/api/users", 500, 85, 200),
+		entry("GET", "https://example.com/api/slow", 200, 400, 300),
+		entry("GET", "https://example.com/api/slower", 200, 900, 300),
+		entry("POST", "https://example.com/api/users", 201, 90, 50),
+		entry("GET", "https://cdn.tracker.io/pixel.gif?id=1", 200, 20, 43, "_tid"),
+		entry("GET", "https://static.example.com/app_v2.js", 200, 30, 90000),
+	)
+
+	s := Summary(before, after)
+	var b bytes.Buffer
+	if err := s.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "summary.md", b.Bytes())
+
+	// Limits add truncation markers.
+	b.Reset()
+	if err := Summary(before, after, WithLimit(1)).WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "summary_limit.md", b.Bytes())
+}
+
+func TestSummaryEmpty(t *testing.T) {
+	var b bytes.Buffer
+	if err := Summary(nil, nil).WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "### Traffic changes\n\nNo traffic changes.\n" {
+		t.Errorf("WriteMarkdown = %q", b.String())
+	}
+}
+
+// TestSummarySelf checks, over random captures, that a capture compared to
+// itself, or to a shuffled copy of itself, shows no change.
+func TestSummarySelf(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	hosts := []string{"example.com", "api.example.com", "cdn.other.net", "127.0.0.1:8080"}
+	methods := []string{"GET", "POST", "DELETE"}
+	for i := range 200 {
+		var entries []*harfile.Entry
+		for range rng.IntN(30) {
+			url := fmt.Sprintf("https://%s/p%d", hosts[rng.IntN(len(hosts))], rng.IntN(5))
+			entries = append(entries, entry(methods[rng.IntN(len(methods))], url, 200+100*rng.IntN(4), rng.Float64()*1000, rng.Int64N(10000), fmt.Sprintf("c%d", rng.IntN(3))))
+		}
+		h := capture(entries...)
+		shuffled := capture(entries...)
+		rng.Shuffle(len(shuffled.Log.Entries), func(i, j int) {
+			shuffled.Log.Entries[i], shuffled.Log.Entries[j] = shuffled.Log.Entries[j], shuffled.Log.Entries[i]
+		})
+		for _, other := range []*harfile.HAR{h, shuffled} {
+			if s := Summary(h, other); !s.IsEmpty() {
+				t.Fatalf("capture %d compared to itself: %+v", i, s)
+			}
+		}
+	}
+}
+
+func TestSummaryDeterministic(t *testing.T) {
+	a := capture(entry("GET", "https://example.com/a", 200, 10, 10))
+	var entries []*harfile.Entry
+	for i := range 30 {
+		entries = append(entries, entry("GET", fmt.Sprintf("https://t%d.example.net/x", i), 200, 10, 10, fmt.Sprintf("c%d", i)))
+	}
+	b := capture(entries...)
+	var first bytes.Buffer
+	Summary(a, b).WriteMarkdown(&first)
+	for range 20 {
+		var again bytes.Buffer
+		Summary(a, b).WriteMarkdown(&again)
+		if again.String() != first.String() {
+			t.Fatal("summary changed between runs")
+		}
+	}
+}
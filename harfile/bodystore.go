@@ -0,0 +1,191 @@
+package harfile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultBodyThreshold is the Text length above which [LoadWithBodyStore]
+// moves a response body to its store, unless [WithBodyThreshold] says
+// otherwise.
+const DefaultBodyThreshold = 64 << 10
+
+// BodyStore keeps response bodies outside of a HAR loaded by
+// [LoadWithBodyStore].
+type BodyStore interface {
+	// Put saves text, the Text of a Content, and returns a handle to read
+	// it back. It may be called concurrently.
+	Put(text string) (BodyHandle, error)
+}
+
+// BodyHandle gives access to a body held by a [BodyStore].
+type BodyHandle interface {
+	// Open returns a reader over the stored text. Several readers may be
+	// open at once.
+	Open() (io.ReadCloser, error)
+}
+
+// WithBodyThreshold sets the Text length, in bytes, above which
+// [LoadWithBodyStore] moves a body to its store.
+func WithBodyThreshold(n int) FileOption {
+	return func(o *fileOptions) {
+		o.bodyThreshold = n
+	}
+}
+
+// LoadWithBodyStore decodes a HAR document like [Load], but entry by entry,
+// moving each response body longer than the threshold to store as soon as
+// its entry is read. Only one large body is held in memory at a time, so
+// captures much larger than the available memory can be analyzed.
+//
+// A stored body leaves Content.Text empty: read it with [Content.Open], or
+// through the decoding methods of Content, which load it transparently.
+// Encoding a Content writes the stored body back inline, so the HAR can be
+// saved as usual. The store must outlive the HAR.
+func LoadWithBodyStore(r io.Reader, store BodyStore, opts ...FileOption) (*HAR, error) {
+	o := newFileOptions(opts)
+	threshold := o.bodyThreshold
+	if threshold <= 0 {
+		threshold = DefaultBodyThreshold
+	}
+	br := bufio.NewReader(o.reader(r))
+	if prefix, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	er := NewEntryReader(br, WithCompression(CompressionNone))
+	entries := []*Entry{}
+	for {
+		e, err := er.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if o.normalizeVersions {
+			normalizeVersions(e)
+		}
+		if o.sniffMimeTypes && e.Response != nil {
+			e.Response.CorrectMimeType()
+		}
+		if o.load.MaxBodyBytes > 0 {
+			fixCutBody(e)
+		}
+		if e.Response != nil && e.Response.Content != nil && len(e.Response.Content.Text) > threshold {
+			c := e.Response.Content
+			h, err := store.Put(c.Text)
+			if err != nil {
+				return nil, fmt.Errorf("harfile: storing body of entry %d: %w", len(entries), err)
+			}
+			c.Text, c.stored = "", h
+		}
+		entries = append(entries, e)
+	}
+	o.finish()
+	log := er.Log()
+	log.Entries = entries
+	return &HAR{Log: log}, nil
+}
+
+// Stored reports whether the body of c is held by a [BodyStore] rather than
+// in Text.
+func (c *Content) Stored() bool {
+	return c.stored != nil
+}
+
+// Open returns a reader over the body text of c, as Text holds it: from
+// memory, or from the [BodyStore] holding it.
+func (c *Content) Open() (io.ReadCloser, error) {
+	if c.stored != nil {
+		return c.stored.Open()
+	}
+	return io.NopCloser(strings.NewReader(c.Text)), nil
+}
+
+// Inline reads a stored body back into Text, after which c no longer
+// depends on its [BodyStore]. It does nothing for a body held in memory.
+func (c *Content) Inline() error {
+	if c.stored == nil {
+		return nil
+	}
+	text, err := c.text()
+	if err != nil {
+		return err
+	}
+	c.Text, c.stored = text, nil
+	return nil
+}
+
+// text returns Text, or the stored body.
+func (c *Content) text() (string, error) {
+	if c.stored == nil {
+		return c.Text, nil
+	}
+	rc, err := c.stored.Open()
+	if err != nil {
+		return "", fmt.Errorf("harfile: reading stored body: %w", err)
+	}
+	defer rc.Close()
+	var b strings.Builder
+	if _, err := io.Copy(&b, rc); err != nil {
+		return "", fmt.Errorf("harfile: reading stored body: %w", err)
+	}
+	return b.String(), nil
+}
+
+// FileBodyStore is a [BodyStore] appending bodies to a single temporary
+// file. Close removes the file.
+type FileBodyStore struct {
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewFileBodyStore creates a store backed by a new temporary file in dir,
+// or in the default directory for temporary files when dir is empty.
+func NewFileBodyStore(dir string) (*FileBodyStore, error) {
+	f, err := os.CreateTemp(dir, "harfile-bodies-*")
+	if err != nil {
+		return nil, err
+	}
+	return &FileBodyStore{f: f}, nil
+}
+
+// Put implements [BodyStore].
+func (s *FileBodyStore) Put(text string) (BodyHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, err := s.f.WriteAt([]byte(text), s.size)
+	if err != nil {
+		return nil, err
+	}
+	h := &fileBody{f: s.f, off: s.size, n: int64(n)}
+	s.size += int64(n)
+	return h, nil
+}
+
+// Close closes and removes the file. Bodies of the store can no longer be
+// read.
+func (s *FileBodyStore) Close() error {
+	err := s.f.Close()
+	if rmErr := os.Remove(s.f.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// fileBody is a body held at off in the file of a FileBodyStore.
+type fileBody struct {
+	f      *os.File
+	off, n int64
+}
+
+func (b *fileBody) Open() (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(b.f, b.off, b.n)), nil
+}
@@ -0,0 +1,210 @@
+package harfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// bodiesHAR returns the encoding of a log of n entries whose response
+// bodies alternate between size bytes of text, size bytes of binary data
+// and a short text.
+func bodiesHAR(t testing.TB, n, size int) []byte {
+	t.Helper()
+	entries := make([]*Entry, n)
+	for i := range entries {
+		b := NewEntry().Get(fmt.Sprintf("https://example.com/%d", i))
+		switch i % 3 {
+		case 0:
+			b = b.RespondBody("text/plain", bytes.Repeat([]byte{byte('a' + i%26)}, size))
+		case 1:
+			b = b.RespondBody("application/octet-stream", bytes.Repeat([]byte{byte(i), 0xff}, size/2))
+		case 2:
+			b = b.RespondJSON([]byte(`{"small":true}`))
+		}
+		entries[i] = b.Build()
+	}
+	var buf bytes.Buffer
+	if err := NewLog().Entries(entries...).HAR().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadWithBodyStore(t *testing.T) {
+	data := bodiesHAR(t, 9, 1000)
+	want, err := Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileBodyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := LoadWithBodyStore(bytes.NewReader(data), store, WithBodyThreshold(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, e := range h.Log.Entries {
+		c, wc := e.Response.Content, want.Log.Entries[i].Response.Content
+		if large := i%3 != 2; c.Stored() != large || large && c.Text != "" {
+			t.Errorf("entry %d: stored %v, text %d bytes", i, c.Stored(), len(c.Text))
+		}
+		rc, err := c.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(text) != wc.Text {
+			t.Errorf("entry %d: Open read %d bytes, %v", i, len(text), err)
+		}
+		body, err := c.DecodedBody()
+		wantBody, _ := wc.DecodedBody()
+		if err != nil || !bytes.Equal(body, wantBody) {
+			t.Errorf("entry %d: DecodedBody = %d bytes, %v", i, len(body), err)
+		}
+		resp, err := e.Response.ToHTTP()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		if !bytes.Equal(body, wantBody) {
+			t.Errorf("entry %d: ToHTTP body = %d bytes", i, len(body))
+		}
+	}
+
+	// Writing inlines the bodies again, giving the original document.
+	var out bytes.Buffer
+	if err := h.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("written HAR differs from the loaded one")
+	}
+	if !h.Log.Entries[0].Response.Content.Stored() {
+		t.Error("writing inlined the body in the loaded HAR")
+	}
+
+	c := h.Log.Entries[0].Response.Content
+	if err := c.Inline(); err != nil || c.Stored() || c.Text != want.Log.Entries[0].Response.Content.Text {
+		t.Errorf("Inline: stored %v, text %d bytes, %v", c.Stored(), len(c.Text), err)
+	}
+}
+
+func TestLoadWithBodyStoreDefaultThreshold(t *testing.T) {
+	data := bodiesHAR(t, 2, DefaultBodyThreshold+2)
+	store, err := NewFileBodyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := LoadWithBodyStore(bytes.NewReader(data), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The binary body is base64 encoded, so its Text is longer.
+	if !h.Log.Entries[0].Response.Content.Stored() || !h.Log.Entries[1].Response.Content.Stored() {
+		t.Error("bodies over the default threshold kept in memory")
+	}
+	small := bodiesHAR(t, 1, DefaultBodyThreshold)
+	if h, err = LoadWithBodyStore(bytes.NewReader(small), store); err != nil || h.Log.Entries[0].Response.Content.Stored() {
+		t.Errorf("body at the default threshold stored, %v", err)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Put(string) (BodyHandle, error) { return nil, errors.New("disk full") }
+
+func TestLoadWithBodyStoreErrors(t *testing.T) {
+	data := bodiesHAR(t, 3, 1000)
+	if _, err := LoadWithBodyStore(bytes.NewReader(data), failingStore{}, WithBodyThreshold(100)); err == nil ||
+		!strings.Contains(err.Error(), "entry 0: disk full") {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := LoadWithBodyStore(strings.NewReader(`{"log":`), failingStore{}); err == nil {
+		t.Error("no error for a truncated document")
+	}
+
+	// Once the store is closed, stored bodies cannot be read or written.
+	store, err := NewFileBodyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := LoadWithBodyStore(bytes.NewReader(data), store, WithBodyThreshold(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	if _, err := h.Log.Entries[0].Response.Content.DecodedBody(); err == nil {
+		t.Error("no error reading from a closed store")
+	}
+	if err := h.Write(io.Discard); err == nil {
+		t.Error("no error writing from a closed store")
+	}
+}
+
+func TestCompactDropsStoredBody(t *testing.T) {
+	store, err := NewFileBodyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := LoadWithBodyStore(bytes.NewReader(bodiesHAR(t, 3, 1000)), store, WithBodyThreshold(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Log.Compact(CompactOptions{MaxBodySize: 500})
+	for i, e := range h.Log.Entries {
+		if c := e.Response.Content; c.Stored() || i < 2 && c.Text != "" {
+			t.Errorf("entry %d: stored %v, text %q", i, c.Stored(), c.Text)
+		}
+	}
+}
+
+// BenchmarkLoadBodies loads a log of 200 entries with 256 KiB bodies and
+// reports the heap held by the HAR, with bodies in memory and in a
+// FileBodyStore.
+func BenchmarkLoadBodies(b *testing.B) {
+	data := bodiesHAR(b, 200, 256<<10)
+	for _, stored := range []bool{false, true} {
+		b.Run(fmt.Sprintf("stored=%v", stored), func(b *testing.B) {
+			store, err := NewFileBodyStore(b.TempDir())
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer store.Close()
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			var ms runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&ms)
+			base, heap := ms.HeapAlloc, uint64(0)
+			for range b.N {
+				var h *HAR
+				if stored {
+					h, err = LoadWithBodyStore(bytes.NewReader(data), store)
+				} else {
+					h, err = Load(bytes.NewReader(data))
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StopTimer()
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				heap = max(heap, ms.HeapAlloc-min(base, ms.HeapAlloc))
+				runtime.KeepAlive(h)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(heap)/(1<<20), "heap-MiB")
+		})
+	}
+}
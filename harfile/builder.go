@@ -0,0 +1,283 @@
+package harfile
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EntryBuilder builds an [Entry] step by step, for fixtures and hand-made
+// HARs. Every method returns the builder so calls can be chained:
+//
+//	e := harfile.NewEntry().
+//		Get("https://example.com/a?x=1").
+//		Header("Accept", "application/json").
+//		RespondStatus(200).
+//		RespondJSON([]byte(`{"ok":true}`)).
+//		Timing(harfile.Timings{Send: 1, Wait: 40, Receive: 2}).
+//		Build()
+//
+// Whatever is left unset gets a default that keeps the entry valid for
+// [HAR.Validate].
+type EntryBuilder struct {
+	e *Entry
+}
+
+// NewEntry returns a builder for a GET of http://localhost/ answered by an
+// empty 200 OK response, started now and with no measured timings.
+func NewEntry() *EntryBuilder {
+	return &EntryBuilder{e: &Entry{
+		StartedDateTime: time.Now().UTC(),
+		Request: &Request{
+			Method:      http.MethodGet,
+			URL:         "http://localhost/",
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []*Cookie{},
+			Headers:     []*NameValuePair{},
+			QueryString: []*NameValuePair{},
+		},
+		Response: &Response{
+			Status:      http.StatusOK,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []*Cookie{},
+			Headers:     []*NameValuePair{},
+			Content:     &Content{},
+		},
+		Cache:   &Cache{},
+		Timings: &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1},
+	}}
+}
+
+// Method sets the request method and URL. Any fragment is dropped from the
+// URL, as HAR URLs do not carry one.
+func (b *EntryBuilder) Method(method, rawURL string) *EntryBuilder {
+	rawURL, _, _ = strings.Cut(rawURL, "#")
+	b.e.Request.Method = method
+	b.e.Request.URL = rawURL
+	return b
+}
+
+// Get makes the request a GET of rawURL.
+func (b *EntryBuilder) Get(rawURL string) *EntryBuilder {
+	return b.Method(http.MethodGet, rawURL)
+}
+
+// Post makes the request a POST to rawURL. The body is set by
+// [EntryBuilder.Body].
+func (b *EntryBuilder) Post(rawURL string) *EntryBuilder {
+	return b.Method(http.MethodPost, rawURL)
+}
+
+// HTTPVersion sets the protocol of both the request and the response, e.g.
+// "HTTP/2.0". It defaults to "HTTP/1.1".
+func (b *EntryBuilder) HTTPVersion(version string) *EntryBuilder {
+	b.e.Request.HTTPVersion = version
+	b.e.Response.HTTPVersion = version
+	return b
+}
+
+// Header adds a request header. Cookie headers also fill Request.Cookies.
+func (b *EntryBuilder) Header(name, value string) *EntryBuilder {
+	b.e.Request.Headers = append(b.e.Request.Headers, &NameValuePair{Name: name, Value: value})
+	return b
+}
+
+// Body sets the request body as described by [PostDataFromBody], and the
+// Content-Type header unless one was given. A multipart body that cannot be
+// parsed is kept as plain text.
+func (b *EntryBuilder) Body(contentType string, data []byte) *EntryBuilder {
+	pd, err := PostDataFromBody(contentType, data)
+	if err != nil {
+		pd = rawPostData(contentType, data)
+	}
+	b.e.Request.PostData = pd
+	return b
+}
+
+// StartedAt sets the time the request started.
+func (b *EntryBuilder) StartedAt(t time.Time) *EntryBuilder {
+	b.e.StartedDateTime = t
+	return b
+}
+
+// Page makes page the parent page of the entry.
+func (b *EntryBuilder) Page(page *Page) *EntryBuilder {
+	b.e.AttachTo(page)
+	return b
+}
+
+// ServerIP sets the IP address of the server.
+func (b *EntryBuilder) ServerIP(addr string) *EntryBuilder {
+	b.e.ServerIPAddress = addr
+	return b
+}
+
+// RespondStatus sets the response status. The status text defaults to the
+// standard one for the code.
+func (b *EntryBuilder) RespondStatus(code int) *EntryBuilder {
+	b.e.Response.Status = int64(code)
+	return b
+}
+
+// RespondHeader adds a response header. Set-Cookie headers also fill
+// Response.Cookies, and Location sets RedirectURL.
+func (b *EntryBuilder) RespondHeader(name, value string) *EntryBuilder {
+	b.e.Response.Headers = append(b.e.Response.Headers, &NameValuePair{Name: name, Value: value})
+	return b
+}
+
+// RespondBody sets the decoded response body, and the Content-Type header
+// unless one was given.
+func (b *EntryBuilder) RespondBody(mimeType string, data []byte) *EntryBuilder {
+	b.e.Response.Content.SetBody(data, mimeType)
+	return b
+}
+
+// RespondJSON sets a JSON response body.
+func (b *EntryBuilder) RespondJSON(data []byte) *EntryBuilder {
+	return b.RespondBody("application/json", data)
+}
+
+// Timing sets the timings of the entry, in milliseconds. Phases left at 0
+// count as measured; set blocked, dns, connect and ssl to -1 when they do
+// not apply. Entry.Time is their total.
+func (b *EntryBuilder) Timing(t Timings) *EntryBuilder {
+	b.e.Timings = &t
+	return b
+}
+
+// Comment sets the comment of the entry.
+func (b *EntryBuilder) Comment(comment string) *EntryBuilder {
+	b.e.Comment = comment
+	return b
+}
+
+// Build returns the entry. QueryString is parsed from the URL, cookies from
+// the Cookie and Set-Cookie headers, and sizes and Time are computed. Each
+// call returns a new entry, so a builder can serve as a template for
+// several variations.
+func (b *EntryBuilder) Build() *Entry {
+	e := b.e.Clone()
+	req, resp := e.Request, e.Response
+
+	_, query, _ := strings.Cut(req.URL, "?")
+	req.QueryString = parseQuery(query, "&;")
+	if req.QueryString == nil {
+		req.QueryString = []*NameValuePair{}
+	}
+	if req.PostData != nil && req.PostData.MimeType != "" && !hasHeader(req.Headers, "Content-Type") {
+		req.Headers = append(req.Headers, &NameValuePair{Name: "Content-Type", Value: req.PostData.MimeType})
+	}
+	req.Cookies = []*Cookie{}
+	for _, v := range req.HeaderValues("Cookie") {
+		req.Cookies = append(req.Cookies, parseCookieHeader(v)...)
+	}
+	req.ComputeSizes()
+
+	if resp.StatusText == "" {
+		resp.StatusText = http.StatusText(int(resp.Status))
+	}
+	if resp.Content.MimeType != "" && !hasHeader(resp.Headers, "Content-Type") {
+		resp.Headers = append(resp.Headers, &NameValuePair{Name: "Content-Type", Value: resp.Content.MimeType})
+	}
+	h := make(http.Header)
+	for _, p := range resp.Headers {
+		h.Add(p.Name, p.Value)
+	}
+	resp.Cookies = CookiesFromResponse(h)
+	resp.RedirectURL = h.Get("Location")
+	resp.ComputeSizes()
+
+	e.ComputeTime()
+	return e
+}
+
+// PageBuilder builds a [Page]. Its methods return the builder so calls can
+// be chained.
+type PageBuilder struct {
+	p *Page
+}
+
+// NewPage returns a builder for the page id, started now and with no page
+// timings.
+func NewPage(id string) *PageBuilder {
+	return &PageBuilder{p: &Page{
+		StartedDateTime: time.Now().UTC(),
+		ID:              id,
+		PageTimings:     &PageTimings{OnContentLoad: -1, OnLoad: -1},
+	}}
+}
+
+// Title sets the page title.
+func (b *PageBuilder) Title(title string) *PageBuilder {
+	b.p.Title = title
+	return b
+}
+
+// StartedAt sets the time the page load started.
+func (b *PageBuilder) StartedAt(t time.Time) *PageBuilder {
+	b.p.StartedDateTime = t
+	return b
+}
+
+// Loaded sets the times of the DOMContentLoaded and load events, in
+// milliseconds since the start of the page load.
+func (b *PageBuilder) Loaded(onContentLoad, onLoad float64) *PageBuilder {
+	b.p.PageTimings = &PageTimings{OnContentLoad: onContentLoad, OnLoad: onLoad}
+	return b
+}
+
+// Build returns a new copy of the page.
+func (b *PageBuilder) Build() *Page {
+	return b.p.Clone()
+}
+
+// LogBuilder builds a [Log]. Its methods return the builder so calls can be
+// chained.
+type LogBuilder struct {
+	l *Log
+}
+
+// NewLog returns a builder for a HAR 1.2 log created by creator, or by
+// harkit as given by [NewCreator] when none is passed.
+func NewLog(creator ...*Creator) *LogBuilder {
+	c := NewCreator()
+	if len(creator) > 0 && creator[0] != nil {
+		c = creator[0]
+	}
+	return &LogBuilder{l: &Log{Version: "1.2", Creator: c, Entries: []*Entry{}}}
+}
+
+// Browser sets the browser of the log.
+func (b *LogBuilder) Browser(name, version string) *LogBuilder {
+	b.l.Browser = &Browser{Name: name, Version: version}
+	return b
+}
+
+// Pages appends pages to the log.
+func (b *LogBuilder) Pages(pages ...*Page) *LogBuilder {
+	b.l.Pages = append(b.l.Pages, pages...)
+	return b
+}
+
+// Entries appends entries to the log.
+func (b *LogBuilder) Entries(entries ...*Entry) *LogBuilder {
+	b.l.Entries = append(b.l.Entries, entries...)
+	return b
+}
+
+// Comment sets the comment of the log.
+func (b *LogBuilder) Comment(comment string) *LogBuilder {
+	b.l.Comment = comment
+	return b
+}
+
+// Build returns a new copy of the log.
+func (b *LogBuilder) Build() *Log {
+	return b.l.Clone()
+}
+
+// HAR returns a new HAR holding a copy of the log.
+func (b *LogBuilder) HAR() *HAR {
+	return &HAR{Log: b.Build()}
+}
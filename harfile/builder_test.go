@@ -0,0 +1,164 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestBuilderValid validates every entry the builder emits, alone, on a page
+// and after a JSON round trip.
+func TestBuilderValid(t *testing.T) {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	page := NewPage("page_1").Title("Home").StartedAt(started).Loaded(120, 340).Build()
+	tests := []struct {
+		name string
+		b    *EntryBuilder
+	}{
+		{"defaults", NewEntry()},
+		{"get", NewEntry().Get("https://example.com/")},
+		{"query and fragment", NewEntry().Get("https://example.com/a?x=1&y=&x=2;z#top")},
+		{"headers and cookies", NewEntry().Get("https://example.com/").Header("Accept", "*/*").Header("Cookie", "a=1; b=2")},
+		{"json post", NewEntry().Post("https://example.com/api").Body("application/json", []byte(`{"a":1}`))},
+		{"form post", NewEntry().Post("https://example.com/form").Body("application/x-www-form-urlencoded", []byte("a=1&b=%20"))},
+		{"multipart post", NewEntry().Post("https://example.com/upload").Body("multipart/form-data; boundary=x", []byte("--x\r\nContent-Disposition: form-data; name=\"f\"\r\n\r\nv\r\n--x--\r\n"))},
+		{"broken multipart", NewEntry().Post("https://example.com/upload").Body("multipart/form-data; boundary=x", []byte("garbage"))},
+		{"other method", NewEntry().Method(http.MethodDelete, "https://example.com/items/1").RespondStatus(204)},
+		{"redirect", NewEntry().Get("http://example.com/").RespondStatus(301).RespondHeader("Location", "https://example.com/")},
+		{"set cookies", NewEntry().RespondHeader("Set-Cookie", "s=1; Path=/; HttpOnly").RespondHeader("Set-Cookie", "t=2; Max-Age=60")},
+		{"json response", NewEntry().RespondJSON([]byte(`{"ok":true}`))},
+		{"binary response", NewEntry().RespondBody("image/png", []byte{0x89, 'P', 'N', 'G', 0, 0xff})},
+		{"unknown status", NewEntry().RespondStatus(599)},
+		{"timings", NewEntry().Timing(Timings{Blocked: -1, DNS: 1, Connect: 10, Ssl: 6, Send: 1, Wait: 40, Receive: 2})},
+		{"zero timings", NewEntry().Timing(Timings{})},
+		{"http2", NewEntry().HTTPVersion("HTTP/2.0").ServerIP("::1").Comment("c")},
+		{"on page", NewEntry().Page(page).StartedAt(started.Add(time.Second))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.b.Build()
+			h := NewLog().Browser("Firefox", "120").Pages(page).Entries(e).Comment("built").HAR()
+			if err := h.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			data, err := json.Marshal(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			back, err := Load(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := back.Validate(); err != nil {
+				t.Errorf("Validate after round trip: %v", err)
+			}
+
+			req, resp := e.Request, e.Response
+			if req.Cookies == nil || req.Headers == nil || req.QueryString == nil || resp.Cookies == nil || resp.Headers == nil {
+				t.Error("nil cookie, header or query slice")
+			}
+			if e.Cache == nil || e.Timings == nil || resp.Content == nil {
+				t.Error("nil cache, timings or content")
+			}
+			// Header sizes are only known for HTTP/1.x.
+			if req.HTTPVersion == "HTTP/1.1" && (req.HeadersSize <= 0 || resp.HeadersSize <= 0) {
+				t.Errorf("header sizes %d and %d", req.HeadersSize, resp.HeadersSize)
+			}
+			if e.Time != e.Timings.Total() {
+				t.Errorf("Time = %v, timings total %v", e.Time, e.Timings.Total())
+			}
+		})
+	}
+}
+
+func TestBuilderDerived(t *testing.T) {
+	e := NewEntry().
+		Post("https://example.com/a?x=1&y=2&x=3#frag").
+		Header("Cookie", "a=1; b=2").
+		Body("application/json", []byte(`{"a":1}`)).
+		RespondStatus(302).
+		RespondHeader("Location", "/next").
+		RespondHeader("Set-Cookie", "s=1; Path=/").
+		RespondJSON([]byte(`[]`)).
+		Timing(Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 2, Receive: 3}).
+		Build()
+
+	pairs := func(p []*NameValuePair) string {
+		var b bytes.Buffer
+		for _, nv := range p {
+			b.WriteString(nv.Name + "=" + nv.Value + " ")
+		}
+		return b.String()
+	}
+	cookies := func(c []*Cookie) string {
+		var b bytes.Buffer
+		for _, c := range c {
+			b.WriteString(c.Name + "=" + c.Value + " ")
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name, got, want string
+	}{
+		{"url", e.Request.URL, "https://example.com/a?x=1&y=2&x=3"},
+		{"query", pairs(e.Request.QueryString), "x=1 y=2 x=3 "},
+		{"request headers", pairs(e.Request.Headers), "Cookie=a=1; b=2 Content-Type=application/json "},
+		{"request cookies", cookies(e.Request.Cookies), "a=1 b=2 "},
+		{"post data", e.Request.PostData.Text, `{"a":1}`},
+		{"status text", e.Response.StatusText, "Found"},
+		{"redirect", e.Response.RedirectURL, "/next"},
+		{"response headers", pairs(e.Response.Headers), "Location=/next Set-Cookie=s=1; Path=/ Content-Type=application/json "},
+		{"response cookies", cookies(e.Response.Cookies), "s=1 "},
+		{"content", e.Response.Content.Text, "[]"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if e.Request.BodySize != 7 || e.Response.Content.Size != 2 || e.Time != 6 {
+		t.Errorf("request body %d, content %d, time %v", e.Request.BodySize, e.Response.Content.Size, e.Time)
+	}
+}
+
+// TestBuilderTemplate checks that a builder can be reused: each Build
+// returns an entry of its own.
+func TestBuilderTemplate(t *testing.T) {
+	b := NewEntry().Get("https://example.com/").Header("Accept", "*/*")
+	first := b.Build()
+	second := b.Header("X-Second", "1").Build()
+	first.Request.Headers[0].Value = "changed"
+	if len(first.Request.Headers) != 1 || len(second.Request.Headers) != 2 {
+		t.Errorf("headers %d and %d, want 1 and 2", len(first.Request.Headers), len(second.Request.Headers))
+	}
+	if second.Request.Headers[0].Value != "*/*" {
+		t.Error("entries built from one builder share headers")
+	}
+
+	lb := NewLog().Entries(first)
+	l1, l2 := lb.Build(), lb.Build()
+	if l1 == l2 || l1.Entries[0] == l2.Entries[0] || l1.Entries[0] == first {
+		t.Error("logs built from one builder share entries")
+	}
+	pb := NewPage("p")
+	if p1, p2 := pb.Build(), pb.Build(); p1 == p2 || p1.PageTimings == p2.PageTimings {
+		t.Error("pages built from one builder share timings")
+	}
+}
+
+func TestNewLogCreator(t *testing.T) {
+	if c := NewLog().Build().Creator; c == nil || c.Name != "harkit" {
+		t.Errorf("default creator = %+v", c)
+	}
+	if c := NewLog(nil).Build().Creator; c == nil || c.Name != "harkit" {
+		t.Errorf("creator for nil = %+v", c)
+	}
+	if c := NewLog(&Creator{Name: "tool", Version: "2"}).Build().Creator; c.Name != "tool" || c.Version != "2" {
+		t.Errorf("creator = %+v", c)
+	}
+	if l := NewLog().Build(); l.Version != "1.2" || l.Entries == nil {
+		t.Errorf("log = %+v", l)
+	}
+}
@@ -0,0 +1,131 @@
+package harfile
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+)
+
+// Initiator describes what triggered a request, as exported by Chrome in
+// Entry.Initiator. Only Type is always present; Stack holds the JavaScript
+// call frames as exported.
+type Initiator struct {
+	Type         string          `json:"type"`                   // "parser", "script", "preload", "preflight" or "other".
+	URL          string          `json:"url,omitempty"`          // URL of the document or script that started the request.
+	LineNumber   *int64          `json:"lineNumber,omitempty"`   // 0-based line of the initiating code.
+	ColumnNumber *int64          `json:"columnNumber,omitempty"` // 0-based column of the initiating code.
+	RequestID    string          `json:"requestId,omitempty"`    // Request that this preflight request was sent for.
+	Stack        json.RawMessage `json:"stack,omitempty"`        // Call stack of the initiating script.
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. Exporters other than Chrome
+// sometimes write the initiator as a plain string, which is kept as Type;
+// other shapes are ignored rather than failing the whole document.
+func (i *Initiator) UnmarshalJSON(data []byte) error {
+	type initiator Initiator
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) > 0 && data[0] == '{':
+		var v initiator
+		if err := json.Unmarshal(data, &v); err == nil {
+			*i = Initiator(v)
+		}
+	case len(data) > 0 && data[0] == '"':
+		return json.Unmarshal(data, &i.Type)
+	}
+	return nil
+}
+
+// Directions of a [WebSocketMessage], relative to the client.
+const (
+	WebSocketSend    = "send"
+	WebSocketReceive = "receive"
+)
+
+// WebSocket opcodes of the data and control frames recorded by Chrome.
+const (
+	OpcodeText   = 1
+	OpcodeBinary = 2
+	OpcodeClose  = 8
+	OpcodePing   = 9
+	OpcodePong   = 10
+)
+
+// WebSocketMessage is a WebSocket frame, as exported by Chrome in
+// Entry.WebSocketMessages.
+type WebSocketMessage struct {
+	Type   string  `json:"type"`   // WebSocketSend or WebSocketReceive.
+	Time   float64 `json:"time"`   // Seconds since the Unix epoch, with sub-second precision.
+	Opcode int     `json:"opcode"` // Frame opcode, e.g. OpcodeText or OpcodeBinary.
+	Data   string  `json:"data"`   // Payload, base64 encoded for binary frames.
+}
+
+// NewWebSocketMessage returns a message for a frame sent or received at t.
+// The payload of binary frames, and of other frames when it is not valid
+// UTF-8, is base64 encoded as Chrome does.
+func NewWebSocketMessage(direction string, opcode int, payload []byte, t time.Time) *WebSocketMessage {
+	m := &WebSocketMessage{
+		Type:   direction,
+		Time:   float64(t.UnixMicro()) / 1e6,
+		Opcode: opcode,
+	}
+	if opcode == OpcodeBinary || !utf8.Valid(payload) {
+		m.Data = base64.StdEncoding.EncodeToString(payload)
+	} else {
+		m.Data = string(payload)
+	}
+	return m
+}
+
+// Timestamp returns Time as a [time.Time].
+func (m *WebSocketMessage) Timestamp() time.Time {
+	return time.UnixMicro(int64(m.Time * 1e6))
+}
+
+// SecurityDetails describes the TLS connection of a request, as exported by
+// Chrome in Entry.SecurityDetails. Chrome members not modeled here, such as
+// keyExchange or signedCertificateTimestampList, are kept in Extras.
+type SecurityDetails struct {
+	Protocol    string   `json:"protocol"`             // Protocol version, e.g. "TLS 1.3".
+	Cipher      string   `json:"cipher"`               // Cipher suite name.
+	SubjectName string   `json:"subjectName"`          // Common name of the server certificate subject, or its whole name without one.
+	SanList     []string `json:"sanList"`              // Subject alternative names of the server certificate.
+	Issuer      string   `json:"issuer"`               // Common name of the server certificate issuer, or its whole name without one.
+	ValidFrom   int64    `json:"validFrom"`            // Start of the certificate validity, in seconds since the Unix epoch.
+	ValidTo     int64    `json:"validTo"`              // End of the certificate validity, in seconds since the Unix epoch.
+	ServerName  string   `json:"serverName,omitempty"` // Server name sent by the client (SNI). Not exported by Chrome.
+	Verified    bool     `json:"verified,omitempty"`   // Whether the certificate chain was verified. Not exported by Chrome.
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, kept verbatim on round trip.
+}
+
+// NewSecurityDetails describes the TLS connection cs, or returns nil when
+// cs is nil, as for plain HTTP.
+func NewSecurityDetails(cs *tls.ConnectionState) *SecurityDetails {
+	if cs == nil {
+		return nil
+	}
+	d := &SecurityDetails{
+		Protocol:   tls.VersionName(cs.Version),
+		Cipher:     tls.CipherSuiteName(cs.CipherSuite),
+		SanList:    []string{},
+		ServerName: cs.ServerName,
+		Verified:   len(cs.VerifiedChains) > 0,
+	}
+	if len(cs.PeerCertificates) > 0 {
+		cert := cs.PeerCertificates[0]
+		d.SubjectName = cmp.Or(cert.Subject.CommonName, cert.Subject.String())
+		d.Issuer = cmp.Or(cert.Issuer.CommonName, cert.Issuer.String())
+		d.ValidFrom = cert.NotBefore.Unix()
+		d.ValidTo = cert.NotAfter.Unix()
+		d.SanList = append(d.SanList, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			d.SanList = append(d.SanList, ip.String())
+		}
+	}
+	return d
+}
@@ -0,0 +1,252 @@
+package harfile
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chromeWebSocket is an entry of a WebSocket connection as exported by
+// Chrome 124, trimmed to a few frames.
+const chromeWebSocket = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "pages": [],
+    "entries": [
+      {
+        "_fromCache": null,
+        "_initiator": {"type": "script", "stack": {"callFrames": [{"functionName": "connect", "scriptId": "12", "url": "https://example.com/app.js", "lineNumber": 3, "columnNumber": 14}]}},
+        "_priority": "VeryHigh",
+        "_resourceType": "websocket",
+        "cache": {},
+        "connection": "183746",
+        "request": {
+          "method": "GET",
+          "url": "wss://echo.example.com/socket",
+          "httpVersion": "HTTP/1.1",
+          "headers": [
+            {"name": "Connection", "value": "Upgrade"},
+            {"name": "Sec-WebSocket-Key", "value": "dGhlIHNhbXBsZSBub25jZQ=="},
+            {"name": "Sec-WebSocket-Version", "value": "13"},
+            {"name": "Upgrade", "value": "websocket"}
+          ],
+          "queryString": [],
+          "cookies": [],
+          "headersSize": -1,
+          "bodySize": 0
+        },
+        "response": {
+          "status": 101,
+          "statusText": "Switching Protocols",
+          "httpVersion": "HTTP/1.1",
+          "headers": [
+            {"name": "Connection", "value": "Upgrade"},
+            {"name": "Sec-WebSocket-Accept", "value": "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="},
+            {"name": "Upgrade", "value": "websocket"}
+          ],
+          "cookies": [],
+          "content": {"size": 0, "mimeType": "x-unknown"},
+          "redirectURL": "",
+          "headersSize": -1,
+          "bodySize": 0,
+          "_transferSize": 0,
+          "_error": null
+        },
+        "serverIPAddress": "93.184.216.34",
+        "startedDateTime": "2024-05-02T09:14:07.512Z",
+        "time": 118.408,
+        "timings": {"blocked": -1, "dns": -1, "ssl": -1, "connect": -1, "send": 0, "wait": 118.408, "receive": 0},
+        "_webSocketMessages": [
+          {"type": "send", "time": 1714641247.659142, "opcode": 1, "data": "{\"op\":\"subscribe\",\"topic\":\"prices\"}"},
+          {"type": "receive", "time": 1714641247.731203, "opcode": 1, "data": "{\"op\":\"ack\"}"},
+          {"type": "receive", "time": 1714641248.001, "opcode": 2, "data": "AAECAw=="},
+          {"type": "send", "time": 1714641250.25, "opcode": 8, "data": ""}
+        ]
+      }
+    ]
+  }
+}`
+
+func TestLoadChromeWebSocket(t *testing.T) {
+	h, err := Load(strings.NewReader(chromeWebSocket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Log.Entries) != 1 {
+		t.Fatalf("got %d entries", len(h.Log.Entries))
+	}
+	msgs := h.Log.Entries[0].WebSocketMessages
+	if len(msgs) != 4 {
+		t.Fatalf("got %d messages, want 4", len(msgs))
+	}
+	if m := msgs[0]; m.Type != WebSocketSend || m.Opcode != OpcodeText || m.Data != `{"op":"subscribe","topic":"prices"}` {
+		t.Errorf("first message = %+v", m)
+	}
+	if m := msgs[2]; m.Type != WebSocketReceive || m.Opcode != OpcodeBinary || m.Data != "AAECAw==" {
+		t.Errorf("binary message = %+v", m)
+	}
+	if m := msgs[3]; m.Opcode != OpcodeClose {
+		t.Errorf("last message = %+v, want a close frame", m)
+	}
+	want := time.Date(2024, 5, 2, 9, 14, 7, 659142000, time.UTC)
+	if got := msgs[0].Timestamp(); !got.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", got, want)
+	}
+
+	// The messages survive a round trip under their underscore key.
+	out, err := h.Log.Entries[0].MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"_webSocketMessages":[{"type":"send"`) {
+		t.Errorf("messages lost on marshal: %s", out)
+	}
+}
+
+func TestNewWebSocketMessage(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 500_000_000, time.UTC)
+	tests := []struct {
+		name    string
+		opcode  int
+		payload []byte
+		want    string
+	}{
+		{"text", OpcodeText, []byte("héllo"), "héllo"},
+		{"binary", OpcodeBinary, []byte("abc"), "YWJj"},
+		{"invalid utf-8 text", OpcodeText, []byte{0xff}, "/w=="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewWebSocketMessage(WebSocketSend, tt.opcode, tt.payload, at)
+			if m.Data != tt.want || m.Opcode != tt.opcode || !m.Timestamp().Equal(at) {
+				t.Errorf("message = %+v, want data %q at %v", m, tt.want, at)
+			}
+		})
+	}
+}
+
+func TestLoadChromeExtensions(t *testing.T) {
+	h, err := LoadFile("testdata/chrome.har")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := h.Log.Entries[0]
+	if e.Initiator == nil || e.Initiator.Type != "other" || e.Priority != "VeryHigh" || e.ResourceType != "document" {
+		t.Errorf("initiator %+v, priority %q, resource type %q", e.Initiator, e.Priority, e.ResourceType)
+	}
+	if e.Response.TransferSize != 69 || e.Response.Error != "" {
+		t.Errorf("transfer size %d, error %q", e.Response.TransferSize, e.Response.Error)
+	}
+	if e := h.Log.Entries[1]; e.Initiator != nil || e.ResourceType != "script" {
+		t.Errorf("second entry: initiator %+v, resource type %q", e.Initiator, e.ResourceType)
+	}
+
+	// The extensions survive a round trip under their original names.
+	var b strings.Builder
+	if err := h.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"_initiator": {`, `"_priority": "VeryHigh"`, `"_resourceType": "document"`, `"_transferSize": 69`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("written HAR lacks %s", want)
+		}
+	}
+	if strings.Contains(b.String(), `"_error"`) {
+		t.Error("empty _error written")
+	}
+}
+
+func TestInitiatorUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		typ      string
+		url      string
+		line     int64
+		hasStack bool
+	}{
+		{"parser", `{"type":"parser","url":"https://example.com/","lineNumber":12,"columnNumber":4}`, "parser", "https://example.com/", 12, false},
+		{"script", `{"type":"script","stack":{"callFrames":[{"functionName":"load","lineNumber":3}]}}`, "script", "", -1, true},
+		{"preflight", `{"type":"preflight","url":"https://api.example.com/","requestId":"42.7"}`, "preflight", "https://api.example.com/", -1, false},
+		{"string", `"other"`, "other", "", -1, false},
+		{"number ignored", `7`, "", "", -1, false},
+		{"array ignored", `["script"]`, "", "", -1, false},
+		{"bad object ignored", `{"type":1}`, "", "", -1, false},
+		{"null", `null`, "", "", -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Entry
+			if err := json.Unmarshal([]byte(`{"_initiator":`+tt.json+`}`), &e); err != nil {
+				t.Fatal(err)
+			}
+			var got Initiator
+			if e.Initiator != nil {
+				got = *e.Initiator
+			}
+			line := int64(-1)
+			if got.LineNumber != nil {
+				line = *got.LineNumber
+			}
+			if got.Type != tt.typ || got.URL != tt.url || line != tt.line || (got.Stack != nil) != tt.hasStack {
+				t.Errorf("initiator = %+v, line %d", got, line)
+			}
+		})
+	}
+
+	var e Entry
+	json.Unmarshal([]byte(`{"_initiator":{"type":"preflight","requestId":"42.7"}}`), &e)
+	if e.Initiator.RequestID != "42.7" {
+		t.Errorf("RequestID = %q", e.Initiator.RequestID)
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	var r Response
+	if err := json.Unmarshal([]byte(`{"status":0,"_error":"net::ERR_CONNECTION_REFUSED","_transferSize":0}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Error != "net::ERR_CONNECTION_REFUSED" {
+		t.Errorf("Error = %q", r.Error)
+	}
+}
+
+func TestByResourceType(t *testing.T) {
+	entry := func(typ string) *Entry { return &Entry{ResourceType: typ} }
+	pred := ByResourceType("XHR", "fetch")
+	for typ, want := range map[string]bool{"xhr": true, "Fetch": true, "script": false, "": false} {
+		if got := pred(entry(typ)); got != want {
+			t.Errorf("ByResourceType(%q) = %v, want %v", typ, got, want)
+		}
+	}
+	if ByResourceType("")(entry("")) {
+		t.Error("entry without a resource type matched")
+	}
+}
+
+func TestSecurityDetailsJSON(t *testing.T) {
+	if NewSecurityDetails(nil) != nil {
+		t.Error("details for a nil connection state")
+	}
+	in := `{"protocol":"TLS 1.3","keyExchange":"","cipher":"AES_128_GCM","subjectName":"example.com","sanList":["example.com"],"issuer":"R3","validFrom":1700000000,"validTo":1710000000,"signedCertificateTimestampList":[]}`
+	var d SecurityDetails
+	if err := json.Unmarshal([]byte(in), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Protocol != "TLS 1.3" || d.Issuer != "R3" || d.ValidTo != 1710000000 || len(d.Extras) != 2 {
+		t.Errorf("details = %+v", d)
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want any
+	json.Unmarshal(out, &got)
+	json.Unmarshal([]byte(in), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip =\n%s\nwant\n%s", out, in)
+	}
+}
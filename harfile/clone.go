@@ -0,0 +1,216 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+)
+
+// The Clone methods return deep copies sharing no memory with the
+// original, so that either can be modified freely, e.g. to snapshot a log
+// that is still being recorded. Cloning nil returns nil, and nil slices and
+// maps stay nil.
+
+// cloneAll clones every element of s.
+func cloneAll[T interface{ Clone() T }](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	out := make([]T, len(s))
+	for i, v := range s {
+		out[i] = v.Clone()
+	}
+	return out
+}
+
+func cloneExtras(m map[string]json.RawMessage) map[string]json.RawMessage {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]json.RawMessage, len(m))
+	for k, v := range m {
+		out[k] = bytes.Clone(v)
+	}
+	return out
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// Clone returns a deep copy of h.
+func (h *HAR) Clone() *HAR {
+	if h == nil {
+		return nil
+	}
+	return &HAR{Log: h.Log.Clone(), Extras: cloneExtras(h.Extras)}
+}
+
+// Clone returns a deep copy of l.
+func (l *Log) Clone() *Log {
+	if l == nil {
+		return nil
+	}
+	c := *l
+	c.Creator = l.Creator.Clone()
+	c.Browser = l.Browser.Clone()
+	c.Pages = cloneAll(l.Pages)
+	c.Entries = cloneAll(l.Entries)
+	c.Extras = cloneExtras(l.Extras)
+	c.view = nil
+	return &c
+}
+
+// Clone returns a copy of c.
+func (c *Creator) Clone() *Creator { return clonePtr(c) }
+
+// Clone returns a copy of b.
+func (b *Browser) Clone() *Browser { return clonePtr(b) }
+
+// Clone returns a deep copy of p.
+func (p *Page) Clone() *Page {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.PageTimings = p.PageTimings.Clone()
+	c.Extras = cloneExtras(p.Extras)
+	return &c
+}
+
+// Clone returns a copy of t.
+func (t *PageTimings) Clone() *PageTimings { return clonePtr(t) }
+
+// Clone returns a deep copy of e.
+func (e *Entry) Clone() *Entry {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Request = e.Request.Clone()
+	c.Response = e.Response.Clone()
+	c.Cache = e.Cache.Clone()
+	c.Timings = e.Timings.Clone()
+	c.Initiator = e.Initiator.Clone()
+	c.WebSocketMessages = cloneAll(e.WebSocketMessages)
+	c.EventStreamMessages = cloneAll(e.EventStreamMessages)
+	c.SecurityDetails = e.SecurityDetails.Clone()
+	c.Tags = slices.Clone(e.Tags)
+	c.Annotations = maps.Clone(e.Annotations)
+	c.Extras = cloneExtras(e.Extras)
+	return &c
+}
+
+// Clone returns a deep copy of r.
+func (r *Request) Clone() *Request {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Cookies = cloneAll(r.Cookies)
+	c.Headers = cloneAll(r.Headers)
+	c.QueryString = cloneAll(r.QueryString)
+	c.PostData = r.PostData.Clone()
+	c.Extras = cloneExtras(r.Extras)
+	return &c
+}
+
+// Clone returns a deep copy of r.
+func (r *Response) Clone() *Response {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Cookies = cloneAll(r.Cookies)
+	c.Headers = cloneAll(r.Headers)
+	c.Content = r.Content.Clone()
+	c.Extras = cloneExtras(r.Extras)
+	return &c
+}
+
+// Clone returns a copy of c.
+func (c *Cookie) Clone() *Cookie { return clonePtr(c) }
+
+// Clone returns a copy of p.
+func (p *NameValuePair) Clone() *NameValuePair { return clonePtr(p) }
+
+// Clone returns a deep copy of pd.
+func (pd *PostData) Clone() *PostData {
+	if pd == nil {
+		return nil
+	}
+	c := *pd
+	c.Params = cloneAll(pd.Params)
+	return &c
+}
+
+// Clone returns a copy of p.
+func (p *Param) Clone() *Param { return clonePtr(p) }
+
+// Clone returns a deep copy of c.
+func (c *Content) Clone() *Content {
+	if c == nil {
+		return nil
+	}
+	out := *c
+	out.Extras = cloneExtras(c.Extras)
+	return &out
+}
+
+// Clone returns a deep copy of c.
+func (c *Cache) Clone() *Cache {
+	if c == nil {
+		return nil
+	}
+	out := *c
+	out.BeforeRequest = c.BeforeRequest.Clone()
+	out.AfterRequest = c.AfterRequest.Clone()
+	return &out
+}
+
+// Clone returns a copy of d.
+func (d *CacheData) Clone() *CacheData { return clonePtr(d) }
+
+// Clone returns a deep copy of t.
+func (t *Timings) Clone() *Timings {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	c.Extras = cloneExtras(t.Extras)
+	return &c
+}
+
+// Clone returns a deep copy of i.
+func (i *Initiator) Clone() *Initiator {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	c.LineNumber = clonePtr(i.LineNumber)
+	c.ColumnNumber = clonePtr(i.ColumnNumber)
+	c.Stack = bytes.Clone(i.Stack)
+	return &c
+}
+
+// Clone returns a copy of m.
+func (m *WebSocketMessage) Clone() *WebSocketMessage { return clonePtr(m) }
+
+// Clone returns a copy of m.
+func (m *EventStreamMessage) Clone() *EventStreamMessage { return clonePtr(m) }
+
+// Clone returns a deep copy of d.
+func (d *SecurityDetails) Clone() *SecurityDetails {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	c.SanList = slices.Clone(d.SanList)
+	c.Extras = cloneExtras(d.Extras)
+	return &c
+}
@@ -0,0 +1,259 @@
+package harfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fill sets every exported field reachable from v to a random value,
+// leaving some pointers, slices and maps nil.
+func fill(rng *rand.Rand, v reflect.Value, depth int) {
+	if v.Type() == timeType {
+		v.Set(reflect.ValueOf(time.Unix(rng.Int64N(1<<31), rng.Int64N(1e9)).UTC()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fmt.Sprintf("s%d", rng.IntN(1000)))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(1 + rng.Int64N(1000))
+	case reflect.Float64:
+		v.SetFloat(float64(1 + rng.IntN(1000)))
+	case reflect.Pointer:
+		if depth > 0 && rng.IntN(5) == 0 {
+			return
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(rng, v.Elem(), depth+1)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 { // json.RawMessage
+			v.SetBytes([]byte(fmt.Sprintf(`"r%d"`, rng.IntN(1000))))
+			return
+		}
+		if rng.IntN(5) == 0 {
+			return
+		}
+		n := 1 + rng.IntN(3)
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := range n {
+			fill(rng, v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		if rng.IntN(5) == 0 {
+			return
+		}
+		v.Set(reflect.MakeMap(v.Type()))
+		for range 1 + rng.IntN(3) {
+			key := reflect.New(v.Type().Key()).Elem()
+			fill(rng, key, depth+1)
+			val := reflect.New(v.Type().Elem()).Elem()
+			fill(rng, val, depth+1)
+			v.SetMapIndex(key, val)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				fill(rng, v.Field(i), depth)
+			}
+		}
+	}
+}
+
+// shared returns the path of memory reachable from both a and b, or "".
+func shared(path string, a, b reflect.Value) string {
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				return path + " (nil mismatch)"
+			}
+			return ""
+		}
+		if a.Pointer() == b.Pointer() {
+			return path
+		}
+		return shared(path, a.Elem(), b.Elem())
+	case reflect.Slice:
+		if a.Len() == 0 {
+			return ""
+		}
+		if a.Pointer() == b.Pointer() {
+			return path
+		}
+		for i := range a.Len() {
+			if p := shared(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i)); p != "" {
+				return p
+			}
+		}
+	case reflect.Map:
+		if a.Len() == 0 {
+			return ""
+		}
+		if a.Pointer() == b.Pointer() {
+			return path
+		}
+		for _, k := range a.MapKeys() {
+			if p := shared(fmt.Sprintf("%s[%v]", path, k), a.MapIndex(k), b.MapIndex(k)); p != "" {
+				return p
+			}
+		}
+	case reflect.Struct:
+		if a.Type() == timeType {
+			return ""
+		}
+		for i := range a.NumField() {
+			if f := a.Type().Field(i); f.IsExported() {
+				if p := shared(path+"."+f.Name, a.Field(i), b.Field(i)); p != "" {
+					return p
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// mutate changes every value reachable from v in place.
+func mutate(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(v.String() + "!")
+	case reflect.Bool:
+		v.SetBool(!v.Bool())
+	case reflect.Int, reflect.Int64:
+		v.SetInt(v.Int() + 1)
+	case reflect.Float64:
+		v.SetFloat(v.Float() + 1)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			mutate(v.Elem())
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Len() > 1 {
+				v.Index(1).SetUint('m')
+			}
+			return
+		}
+		for i := range v.Len() {
+			mutate(v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			val := reflect.New(v.Type().Elem()).Elem()
+			val.Set(v.MapIndex(k))
+			mutate(val)
+			v.SetMapIndex(k, val)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			v.Set(reflect.ValueOf(v.Interface().(time.Time).Add(time.Hour)))
+			return
+		}
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				mutate(v.Field(i))
+			}
+		}
+	}
+}
+
+// TestCloneIndependent fills random logs, checks that their clones share no
+// memory with them, then mutates every field of the clones and checks that
+// the originals are untouched.
+func TestCloneIndependent(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	for i := range 100 {
+		h := new(HAR)
+		fill(rng, reflect.ValueOf(h).Elem(), 0)
+		before, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := h.Clone()
+		if !reflect.DeepEqual(h, c) {
+			t.Fatalf("log %d: clone differs from the original", i)
+		}
+		if p := shared("HAR", reflect.ValueOf(h), reflect.ValueOf(c)); p != "" {
+			t.Fatalf("log %d: %s shared by the clone", i, p)
+		}
+		mutate(reflect.ValueOf(c))
+		after, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(before) != string(after) {
+			t.Fatalf("log %d changed through its clone:\n%s\n%s", i, before, after)
+		}
+	}
+}
+
+func TestCloneNil(t *testing.T) {
+	for _, c := range []any{
+		(*HAR)(nil).Clone(), (*Log)(nil).Clone(), (*Creator)(nil).Clone(), (*Browser)(nil).Clone(),
+		(*Page)(nil).Clone(), (*PageTimings)(nil).Clone(), (*Entry)(nil).Clone(), (*Request)(nil).Clone(),
+		(*Response)(nil).Clone(), (*Cookie)(nil).Clone(), (*NameValuePair)(nil).Clone(), (*PostData)(nil).Clone(),
+		(*Param)(nil).Clone(), (*Content)(nil).Clone(), (*Cache)(nil).Clone(), (*CacheData)(nil).Clone(),
+		(*Timings)(nil).Clone(), (*Initiator)(nil).Clone(), (*WebSocketMessage)(nil).Clone(),
+		(*SecurityDetails)(nil).Clone(), (*EventStreamMessage)(nil).Clone(),
+	} {
+		if v := reflect.ValueOf(c); !v.IsNil() {
+			t.Errorf("cloning a nil %s returned %v", v.Type(), c)
+		}
+	}
+
+	// Nil slices and maps stay nil, empty ones stay empty.
+	e := &Entry{Tags: []string{}, Request: &Request{Cookies: []*Cookie{}}}
+	c := e.Clone()
+	if c.Tags == nil || c.Request.Cookies == nil || c.Request.Headers != nil || c.Extras != nil || c.Annotations != nil {
+		t.Errorf("clone = %+v", c)
+	}
+}
+
+func TestCloneDropsView(t *testing.T) {
+	l := &Log{Entries: []*Entry{{Request: &Request{Method: "GET", URL: "https://example.com/"}}}}
+	l.Reindex()
+	if c := l.Clone(); c.view != nil {
+		t.Error("clone shares the cached view of the original")
+	}
+}
+
+// BenchmarkClone clones a log of 10k entries.
+func BenchmarkClone(b *testing.B) {
+	entries := make([]*Entry, 10_000)
+	for i := range entries {
+		entries[i] = &Entry{
+			Request: &Request{
+				Method:      "GET",
+				URL:         fmt.Sprintf("https://example.com/items/%d?page=2", i),
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []*NameValuePair{{Name: "Accept", Value: "application/json"}, {Name: "Cookie", Value: "session=abc"}},
+				Cookies:     []*Cookie{{Name: "session", Value: "abc"}},
+				QueryString: []*NameValuePair{{Name: "page", Value: "2"}},
+			},
+			Response: &Response{
+				Status:      200,
+				StatusText:  "OK",
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []*NameValuePair{{Name: "Content-Type", Value: "application/json"}},
+				Cookies:     []*Cookie{},
+				Content:     &Content{Size: 22, MimeType: "application/json", Text: `{"id":1,"name":"item"}`},
+			},
+			Cache:   &Cache{},
+			Timings: &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1},
+		}
+	}
+	l := &Log{Version: "1.2", Creator: &Creator{Name: "bench"}, Entries: entries}
+	b.ReportAllocs()
+	for range b.N {
+		if c := l.Clone(); len(c.Entries) != len(entries) {
+			b.Fatal(len(c.Entries))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package harfile
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SortEntries orders the entries of l by StartedDateTime, keeping the
+// recorded order of entries started at the same time. Nil entries go last.
+func (l *Log) SortEntries() {
+	slices.SortStableFunc(l.Entries, func(a, b *Entry) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		case b == nil:
+			return -1
+		}
+		return a.StartedDateTime.Compare(b.StartedDateTime)
+	})
+	l.Reindex()
+}
+
+// Dedupe removes the entries whose request has the same fingerprint under
+// key as an earlier entry, and returns how many were removed. Entries
+// without a request are kept.
+func (l *Log) Dedupe(key Matcher) int {
+	seen := make(map[string]bool)
+	before := len(l.Entries)
+	l.Entries = slices.DeleteFunc(l.Entries, func(e *Entry) bool {
+		if e == nil || e.Request == nil {
+			return false
+		}
+		fp := key.Fingerprint(e.Request)
+		if seen[fp] {
+			return true
+		}
+		seen[fp] = true
+		return false
+	})
+	l.Reindex()
+	return before - len(l.Entries)
+}
+
+// PruneBefore removes the entries of l started before t, wherever they are
+// in l.Entries, then the pages only removed entries referred to, and
+// returns the number of entries removed. Pages no entry referred to in the
+// first place are kept.
+func (l *Log) PruneBefore(t time.Time) int {
+	removed := make(map[string]bool)
+	before := len(l.Entries)
+	l.Entries = slices.DeleteFunc(l.Entries, func(e *Entry) bool {
+		if e == nil || !e.StartedDateTime.Before(t) {
+			return false
+		}
+		removed[e.Pageref] = true
+		return true
+	})
+	for _, e := range l.Entries {
+		if e != nil {
+			delete(removed, e.Pageref)
+		}
+	}
+	delete(removed, "")
+	if len(removed) > 0 {
+		l.Pages = slices.DeleteFunc(l.Pages, func(p *Page) bool {
+			return p != nil && removed[p.ID]
+		})
+	}
+	return before - len(l.Entries)
+}
+
+// CompactOptions selects what [Log.Compact] removes.
+type CompactOptions struct {
+	MaxBodySize      int64    // Response bodies larger than this many bytes are removed. Zero keeps every body.
+	DropMimePrefixes []string // Entries whose response MIME type starts with one of these, e.g. "image/" or "font/", are removed.
+	DropCache        bool     // Cache objects are emptied.
+	SniffMimeTypes   bool     // Response MIME types are first corrected from the bodies, see [Log.CorrectMimeTypes], so that DropMimePrefixes sees the actual types.
+}
+
+// Compact shrinks l in place as selected by opts. Removed bodies keep their
+// Size, even when unknown, leave a note in the content comment and tag
+// their entry with [TagBodyRemoved].
+func (l *Log) Compact(opts CompactOptions) {
+	if opts.SniffMimeTypes {
+		l.CorrectMimeTypes()
+	}
+	if len(opts.DropMimePrefixes) > 0 {
+		l.Entries = slices.DeleteFunc(l.Entries, func(e *Entry) bool {
+			if e == nil || e.Response == nil || e.Response.Content == nil {
+				return false
+			}
+			mime := strings.ToLower(strings.TrimSpace(e.Response.Content.MimeType))
+			return slices.ContainsFunc(opts.DropMimePrefixes, func(prefix string) bool {
+				return strings.HasPrefix(mime, strings.ToLower(prefix))
+			})
+		})
+	}
+	for _, e := range l.Entries {
+		if e == nil {
+			continue
+		}
+		if opts.DropCache && e.Cache != nil {
+			e.Cache = &Cache{}
+		}
+		if opts.MaxBodySize <= 0 || e.Response == nil || e.Response.Content == nil {
+			continue
+		}
+		c := e.Response.Content
+		if (c.Text != "" || c.stored != nil) && max(c.Size, int64(len(c.Text))) > opts.MaxBodySize {
+			c.Text, c.stored = "", nil
+			c.Encoding, c.ContentEncoding = "", ""
+			if c.Size >= 0 {
+				c.Comment = appendNote(c.Comment, fmt.Sprintf("body of %d bytes removed", c.Size))
+			} else {
+				c.Comment = appendNote(c.Comment, "body of unknown size removed")
+			}
+			e.AddTag(TagBodyRemoved)
+		}
+	}
+	l.Reindex()
+}
@@ -0,0 +1,150 @@
+package harfile
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortEntries(t *testing.T) {
+	at := func(sec int, url string) *Entry {
+		return &Entry{StartedDateTime: time.Date(2024, 1, 1, 0, 0, sec, 0, time.UTC), Request: &Request{URL: url}}
+	}
+	l := &Log{Entries: []*Entry{at(2, "c"), nil, at(1, "a"), at(2, "d"), at(0, "z")}}
+	l.SortEntries()
+	var got []string
+	for _, e := range l.Entries {
+		if e == nil {
+			got = append(got, "nil")
+			continue
+		}
+		got = append(got, e.Request.URL)
+	}
+	if strings.Join(got, " ") != "z a c d nil" {
+		t.Errorf("order = %q", got)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	get := func(url string) *Entry { return &Entry{Request: &Request{Method: "GET", URL: url}} }
+	first := get("https://example.com/a?x=1&y=2")
+	l := &Log{Entries: []*Entry{first, get("https://example.com/a?y=2&x=1"), nil, {}, get("https://example.com/b"), get("https://example.com/a?x=1&y=2&_=9")}}
+	if n := l.Dedupe(DefaultMatcher); n != 1 {
+		t.Errorf("removed %d, want 1", n)
+	}
+	if len(l.Entries) != 5 || l.Entries[0] != first {
+		t.Errorf("entries = %v", l.Entries)
+	}
+	if n := l.Dedupe(Matcher{Method: true, Path: true}); n != 1 {
+		t.Errorf("path only: removed %d, want 1", n)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	entry := func(mime, text string, size int64) *Entry {
+		return &Entry{
+			Request:  &Request{Method: "GET", URL: "https://example.com/"},
+			Response: &Response{Status: 200, Content: &Content{Size: size, MimeType: mime, Text: text}},
+			Cache:    &Cache{Comment: "warm"},
+		}
+	}
+	l := &Log{Entries: []*Entry{
+		entry("text/html", "<p>small</p>", 12),
+		entry("application/json", strings.Repeat("x", 100), 100),
+		entry("IMAGE/png", "iVBOR", 5),
+		entry("font/woff2", "d09G", 4),
+		entry("text/plain", strings.Repeat("y", 50), -1),
+		nil,
+	}}
+	l.Entries[1].Response.Content.Encoding = "base64"
+	l.Entries[1].Response.Content.ContentEncoding = "gzip"
+	l.Compact(CompactOptions{MaxBodySize: 20, DropMimePrefixes: []string{"image/", "font/"}, DropCache: true})
+
+	if len(l.Entries) != 4 {
+		t.Fatalf("%d entries left, want 4", len(l.Entries))
+	}
+	if c := l.Entries[0].Response.Content; c.Text != "<p>small</p>" || c.Comment != "" {
+		t.Errorf("small body = %+v", c)
+	}
+	if c := l.Entries[1].Response.Content; c.Text != "" || c.Encoding != "" || c.ContentEncoding != "" || c.Size != 100 || c.Comment != "body of 100 bytes removed" {
+		t.Errorf("large body = %+v", c)
+	}
+	if c := l.Entries[2].Response.Content; c.Text != "" || c.Size != -1 || c.Comment != "body of unknown size removed" {
+		t.Errorf("body of unknown size = %+v", c)
+	}
+	if l.Entries[0].Cache.Comment != "" {
+		t.Errorf("cache = %+v", l.Entries[0].Cache)
+	}
+
+	// Nothing selected, nothing changed.
+	keep := &Log{Entries: []*Entry{entry("image/png", strings.Repeat("z", 100), 100)}}
+	keep.Compact(CompactOptions{})
+	if len(keep.Entries) != 1 || keep.Entries[0].Response.Content.Text == "" || keep.Entries[0].Cache.Comment != "warm" {
+		t.Error("zero options changed the log")
+	}
+}
+
+func TestPruneBefore(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// entries builds a log with one entry per offset in minutes from base,
+	// in the given order, each on page "p<offset/10>" unless negative.
+	entries := func(offsets ...int) *Log {
+		l := &Log{Pages: []*Page{{ID: "p0"}, {ID: "p1"}, {ID: "p2"}, {ID: "unused"}}}
+		for _, m := range offsets {
+			e := NewEntry().Get(fmt.Sprintf("https://example.com/%d", m)).
+				StartedAt(base.Add(time.Duration(m) * time.Minute)).Build()
+			e.Pageref = fmt.Sprintf("p%d", m/10)
+			l.Entries = append(l.Entries, e)
+		}
+		return l
+	}
+	describe := func(l *Log) (string, string) {
+		var urls, pages []string
+		for _, e := range l.Entries {
+			if e == nil {
+				urls = append(urls, "nil")
+				continue
+			}
+			urls = append(urls, strings.TrimPrefix(e.Request.URL, "https://example.com/"))
+		}
+		for _, p := range l.Pages {
+			pages = append(pages, p.ID)
+		}
+		return strings.Join(urls, " "), strings.Join(pages, " ")
+	}
+	tests := []struct {
+		name    string
+		log     *Log
+		cutoff  int // Minutes after base.
+		removed int
+		kept    string
+		pages   string
+	}{
+		{"nothing old", entries(0, 5, 12), -1, 0, "0 5 12", "p0 p1 p2 unused"},
+		{"in order", entries(0, 5, 12, 25), 10, 2, "12 25", "p1 p2 unused"},
+		{"start equal to cutoff kept", entries(0, 10), 10, 1, "10", "p1 p2 unused"},
+		// A retry started early but finished, and so appended, late.
+		{"out of order", entries(12, 3, 25, 8, 14), 10, 2, "12 25 14", "p1 p2 unused"},
+		{"late entry within the window", entries(12, 25, 8), 6, 0, "12 25 8", "p0 p1 p2 unused"},
+		{"page left with a kept entry", entries(1, 12, 9), 5, 1, "12 9", "p0 p1 p2 unused"},
+		{"everything", entries(0, 12, 25), 60, 3, "", "unused"},
+		{"empty", &Log{}, 10, 0, "", ""},
+		{"nil entries kept", &Log{Entries: []*Entry{nil, entries(0).Entries[0], nil}}, 10, 1, "nil nil", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed := tt.log.PruneBefore(base.Add(time.Duration(tt.cutoff) * time.Minute))
+			if removed != tt.removed {
+				t.Errorf("PruneBefore = %d, want %d", removed, tt.removed)
+			}
+			kept, pages := describe(tt.log)
+			if kept != tt.kept {
+				t.Errorf("kept %q, want %q", kept, tt.kept)
+			}
+			if pages != tt.pages {
+				t.Errorf("pages %q, want %q", pages, tt.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package harfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Compression selects how HAR files and streams are compressed.
+type Compression int
+
+const (
+	// CompressionAuto detects gzip input from its magic bytes, and
+	// compresses files whose name ends in ".gz", such as "capture.har.gz".
+	// Streams are written uncompressed.
+	CompressionAuto Compression = iota
+	CompressionNone             // Plain JSON, even when the input looks compressed.
+	CompressionGzip             // Gzip, regardless of the file name.
+)
+
+// FileOption configures the loaders, the writers and the streams of HAR
+// documents.
+type FileOption func(*fileOptions)
+
+type fileOptions struct {
+	compression       Compression
+	bodyThreshold     int  // Used by LoadWithBodyStore.
+	normalizeVersions bool // Rewrite httpVersion values in their canonical spelling.
+	sniffMimeTypes    bool // Correct response MIME types from the bodies.
+	progress          Progress
+	load              LoadOptions // Parts of the document left out, see WithLoadOptions.
+
+	input *countingReader // Set by reader when reporting progress.
+}
+
+func newFileOptions(opts []FileOption) *fileOptions {
+	o := &fileOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCompression overrides the detection of compressed documents.
+func WithCompression(c Compression) FileOption {
+	return func(o *fileOptions) {
+		o.compression = c
+	}
+}
+
+// gzipped reports whether a file written to path should be compressed.
+func (o *fileOptions) gzipped(path string) bool {
+	if o.compression == CompressionAuto {
+		return strings.EqualFold(filepath.Ext(path), ".gz")
+	}
+	return o.compression == CompressionGzip
+}
+
+// reader returns a reader decompressing r when selected by the options. The
+// gzip header is only read on the first call to Read. The bytes read from r
+// are reported to the progress option, if any, until finish is called.
+func (o *fileOptions) reader(r io.Reader) io.Reader {
+	if o.progress != nil {
+		o.input = newCountingReader(r, o.progress)
+		r = o.input
+	}
+	if o.compression != CompressionNone {
+		r = &decompressReader{r: r, force: o.compression == CompressionGzip}
+	}
+	if o.load.active() {
+		r = newLoadFilter(r, o.load)
+	}
+	return r
+}
+
+// finish reports the last bytes read by the reader returned by reader.
+func (o *fileOptions) finish() {
+	if o.input != nil {
+		o.input.finish()
+	}
+}
+
+type decompressReader struct {
+	r     io.Reader
+	force bool
+	ready bool
+	err   error
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if !d.ready {
+		d.ready = true
+		br := bufio.NewReader(d.r)
+		d.r = br
+		if prefix, err := br.Peek(len(gzipMagic)); d.force || err == nil && bytes.Equal(prefix, gzipMagic) {
+			d.r, d.err = gzip.NewReader(br)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
@@ -0,0 +1,65 @@
+package harfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteFileGzip(t *testing.T) {
+	h := &HAR{Log: &Log{Version: "1.2", Creator: NewCreator(), Entries: []*Entry{streamEntry(0), streamEntry(1)}}}
+	var plain bytes.Buffer
+	if err := h.Write(&plain); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		opts []FileOption
+		gzip bool
+	}{
+		{"capture.har", nil, false},
+		{"capture.har.gz", nil, true},
+		{"capture.HAR.GZ", nil, true},
+		{"forced.har", []FileOption{WithCompression(CompressionGzip)}, true},
+		{"plain.har.gz", []FileOption{WithCompression(CompressionNone)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := h.WriteFile(path, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bytes.HasPrefix(data, gzipMagic); got != tt.gzip {
+				t.Fatalf("gzip = %v, want %v", got, tt.gzip)
+			}
+			// Auto detection reads both forms back.
+			back, err := LoadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(back, h) {
+				t.Error("loaded document differs")
+			}
+			var again bytes.Buffer
+			back.Write(&again)
+			if !bytes.Equal(again.Bytes(), plain.Bytes()) {
+				t.Error("round trip changed the JSON")
+			}
+		})
+	}
+
+	// Forcing plain JSON on a compressed file fails to decode.
+	if _, err := LoadFile(filepath.Join(dir, "capture.har.gz"), WithCompression(CompressionNone)); err == nil {
+		t.Error("compressed file decoded as plain JSON")
+	}
+	if _, err := LoadFile(filepath.Join(dir, "capture.har"), WithCompression(CompressionGzip)); err == nil {
+		t.Error("plain file decoded as gzip")
+	}
+}
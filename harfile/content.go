@@ -0,0 +1,211 @@
+package harfile
+
+import (
+	"bytes"
+	"cmp"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ErrUnsupportedEncoding is returned, wrapped, for a text encoding, content
+// coding or charset that cannot be decoded.
+var ErrUnsupportedEncoding = errors.New("harfile: unsupported encoding")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DecodedBody returns the response body held by c. Text is base64 decoded
+// when Encoding says so, then decompressed with ContentEncoding, or when it
+// starts with a gzip or zstd header, as left by tools that store the body
+// as received.
+func (c *Content) DecodedBody() ([]byte, error) {
+	return c.DecodeBody("")
+}
+
+// DecodeBody is like [Content.DecodedBody] but also takes the value of the
+// Content-Encoding response header. Most exporters store decompressed text
+// regardless of that header, so a coding is only reversed when the body
+// still looks encoded with it. ContentEncoding, when set, takes precedence.
+func (c *Content) DecodeBody(contentEncoding string) ([]byte, error) {
+	contentEncoding = cmp.Or(c.ContentEncoding, contentEncoding)
+	text, err := c.text()
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch strings.ToLower(c.Encoding) {
+	case "":
+		data = []byte(text)
+	case "base64":
+		if data, err = base64.StdEncoding.DecodeString(text); err != nil {
+			return nil, fmt.Errorf("harfile: decode content: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: text encoding %q", ErrUnsupportedEncoding, c.Encoding)
+	}
+
+	if contentEncoding == "" {
+		switch {
+		case bytes.HasPrefix(data, gzipMagic):
+			contentEncoding = "gzip"
+		case bytes.HasPrefix(data, zstdMagic):
+			contentEncoding = "zstd"
+		}
+	}
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if !looksEncoded(coding, data) {
+			continue
+		}
+		decoded, err := decodeCoding(coding, data)
+		switch {
+		case errors.Is(err, ErrUnsupportedEncoding):
+			return nil, err
+		case err != nil && coding == "br":
+			// Brotli has no header to sniff, so a failure means the text
+			// was already decoded.
+			return data, nil
+		case err != nil:
+			return nil, fmt.Errorf("harfile: decode content: %w", err)
+		}
+		data = decoded
+	}
+	return data, nil
+}
+
+// TextUTF8 returns the body decoded by [Content.DecodedBody] and transcoded
+// to UTF-8 from the charset parameter of MimeType. Bodies without a charset
+// are returned as is.
+func (c *Content) TextUTF8() (string, error) {
+	data, err := c.DecodedBody()
+	if err != nil {
+		return "", err
+	}
+	_, params, _ := mime.ParseMediaType(c.MimeType)
+	charset := strings.ToLower(params["charset"])
+	if charset == "" || charset == "utf-8" || charset == "utf8" {
+		return string(data), nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return "", fmt.Errorf("%w: charset %q", ErrUnsupportedEncoding, charset)
+	}
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("harfile: decode %s content: %w", charset, err)
+	}
+	return string(text), nil
+}
+
+// Hash returns the hex-encoded SHA-256 of the body decoded by
+// [Content.DecodedBody], so bodies stored differently but carrying the
+// same bytes hash alike.
+func (c *Content) Hash() (string, error) {
+	data, err := c.DecodedBody()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SetBody stores data as the content, as UTF-8 text when valid and base64
+// otherwise, and updates Size, MimeType and Encoding accordingly. Compression
+// and ContentEncoding are reset since data is taken to be the decoded body.
+func (c *Content) SetBody(data []byte, mimeType string) {
+	c.MimeType = mimeType
+	c.Size = int64(len(data))
+	c.Compression = 0
+	c.ContentEncoding = ""
+	c.stored = nil
+	if utf8.Valid(data) {
+		c.Text = string(data)
+		c.Encoding = ""
+	} else {
+		c.Text = base64.StdEncoding.EncodeToString(data)
+		c.Encoding = "base64"
+	}
+}
+
+// looksEncoded reports whether data may still be encoded with coding.
+// Codings without a recognizable header are assumed to apply.
+func looksEncoded(coding string, data []byte) bool {
+	switch coding {
+	case "", "identity":
+		return false
+	case "gzip", "x-gzip":
+		return bytes.HasPrefix(data, gzipMagic)
+	case "zstd":
+		return bytes.HasPrefix(data, zstdMagic)
+	case "deflate":
+		// A zlib header has a CM of 8 and a check value making the first
+		// two bytes a multiple of 31.
+		return len(data) >= 2 && data[0]&0x0f == 8 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0
+	}
+	return true
+}
+
+// decodeCoding reverses a single content coding.
+func decodeCoding(coding string, data []byte) ([]byte, error) {
+	var (
+		rd  io.Reader
+		err error
+	)
+	switch coding {
+	case "", "identity":
+		return data, nil
+	case "gzip", "x-gzip":
+		rd, err = gzip.NewReader(bytes.NewReader(data))
+	case "deflate":
+		// Servers disagree on whether deflate means zlib or raw DEFLATE.
+		rd, err = zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			rd, err = flate.NewReader(bytes.NewReader(data)), nil
+		}
+	case "br":
+		rd = brotli.NewReader(bytes.NewReader(data))
+	case "zstd":
+		dec, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		return dec.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("%w: content coding %q", ErrUnsupportedEncoding, coding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(rd)
+}
+
+// decodeContentEncoding reverses the codings listed in a Content-Encoding
+// header value, last applied first.
+func decodeContentEncoding(header string, data []byte) ([]byte, error) {
+	codings := strings.Split(header, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		if data, err = decodeCoding(strings.ToLower(strings.TrimSpace(codings[i])), data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,166 @@
+package harfile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func brotlied(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	bw := brotli.NewWriter(&b)
+	bw.Write([]byte(s))
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func zstded(t *testing.T, s string) string {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	return string(enc.EncodeAll([]byte(s), nil))
+}
+
+func zlibbed(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write([]byte(s))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestDecodeBody(t *testing.T) {
+	const text = "hello, hello, hello"
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name     string
+		content  Content
+		encoding string // Content-Encoding header.
+		want     string
+	}{
+		{"plain", Content{Text: text}, "", text},
+		{"base64", Content{Text: b64(text), Encoding: "base64"}, "", text},
+		{"gzip sniffed", Content{Text: b64(gzipped(t, text)), Encoding: "base64"}, "", text},
+		{"zstd sniffed", Content{Text: b64(zstded(t, text)), Encoding: "base64"}, "", text},
+		{"br", Content{Text: b64(brotlied(t, text)), Encoding: "BASE64"}, "br", text},
+		{"br already decoded", Content{Text: text}, "br", text},
+		{"zstd", Content{Text: b64(zstded(t, text)), Encoding: "base64"}, "zstd", text},
+		{"zlib deflate", Content{Text: b64(zlibbed(t, text)), Encoding: "base64"}, "deflate", text},
+		{"gzip already decoded", Content{Text: text}, "gzip", text},
+		{"stacked", Content{Text: b64(brotlied(t, gzipped(t, text))), Encoding: "base64"}, "gzip, br", text},
+		{"identity", Content{Text: text}, "identity", text},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.content.DecodeBody(tt.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("DecodeBody(%q) = %q, want %q", tt.encoding, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     Content
+		encoding    string
+		unsupported bool
+	}{
+		{"text encoding", Content{Text: "68656c6c6f", Encoding: "hex"}, "", true},
+		{"content coding", Content{Text: "abc"}, "snappy", true},
+		{"bad base64", Content{Text: "not base64!", Encoding: "base64"}, "", false},
+		{"bad gzip", Content{Text: "\x1f\x8bnot gzip"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.content.DecodeBody(tt.encoding)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if errors.Is(err, ErrUnsupportedEncoding) != tt.unsupported {
+				t.Errorf("err = %v, ErrUnsupportedEncoding %v", err, tt.unsupported)
+			}
+		})
+	}
+}
+
+func TestTextUTF8(t *testing.T) {
+	tests := []struct {
+		name    string
+		content Content
+		want    string
+	}{
+		{"no charset", Content{MimeType: "text/plain", Text: "café"}, "café"},
+		{"utf-8", Content{MimeType: "text/html; charset=UTF-8", Text: "café"}, "café"},
+		{"latin-1", Content{MimeType: "text/plain; charset=ISO-8859-1", Text: base64.StdEncoding.EncodeToString([]byte("caf\xe9")), Encoding: "base64"}, "café"},
+		{"windows-1252", Content{MimeType: "text/plain; charset=windows-1252", Text: base64.StdEncoding.EncodeToString([]byte("\x80 5")), Encoding: "base64"}, "€ 5"},
+		{"shift_jis", Content{MimeType: "text/plain; charset=shift_jis", Text: base64.StdEncoding.EncodeToString([]byte("\x93\xfa\x96\x7b")), Encoding: "base64"}, "日本"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.content.TextUTF8()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("TextUTF8 = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	c := Content{MimeType: "text/plain; charset=x-klingon", Text: "abc"}
+	if _, err := c.TextUTF8(); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("unknown charset: err = %v", err)
+	}
+	c = Content{Text: "abc", Encoding: "hex"}
+	if _, err := c.TextUTF8(); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("unknown text encoding: err = %v", err)
+	}
+}
+
+func TestSetBody(t *testing.T) {
+	c := &Content{Compression: 10}
+	c.SetBody([]byte("hi"), "text/plain")
+	if c.Text != "hi" || c.Encoding != "" || c.Size != 2 || c.Compression != 0 || c.MimeType != "text/plain" {
+		t.Errorf("text body = %+v", *c)
+	}
+	c.SetBody([]byte{0xff, 0}, "application/octet-stream")
+	if c.Text != "/wA=" || c.Encoding != "base64" || c.Size != 2 {
+		t.Errorf("binary body = %+v", *c)
+	}
+	if got, _ := c.DecodedBody(); !bytes.Equal(got, []byte{0xff, 0}) {
+		t.Errorf("DecodedBody = %q", got)
+	}
+}
+
+func TestHash(t *testing.T) {
+	const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	text := &Content{Text: "hello"}
+	b64 := &Content{Text: "aGVsbG8=", Encoding: "base64"}
+	for name, c := range map[string]*Content{"text": text, "base64": b64} {
+		if got, err := c.Hash(); err != nil || got != helloSHA256 {
+			t.Errorf("%s: Hash = %q, %v", name, got, err)
+		}
+	}
+	if _, err := (&Content{Text: "x", Encoding: "hex"}).Hash(); err == nil {
+		t.Error("unsupported encoding hashed")
+	}
+}
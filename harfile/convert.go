@@ -0,0 +1,268 @@
+package harfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ISO8601 is the timestamp layout used for string dates in HAR files.
+const ISO8601 = "2006-01-02T15:04:05.000Z07:00"
+
+// FromHTTPResponse converts resp into a [Response]. body is the response body
+// exactly as read from resp.Body, since resp.Body itself is not consumed.
+//
+// When a Content-Encoding header is present the body is decoded so that
+// Content.Size and Content.Text describe the decoded payload while BodySize
+// keeps the transferred length, and Compression holds the difference.
+// Bodies that are not valid UTF-8 are stored base64 encoded.
+func FromHTTPResponse(resp *http.Response, body []byte) (*Response, error) {
+	if resp == nil {
+		return nil, fmt.Errorf("harfile: nil response")
+	}
+
+	r := &Response{
+		Status:      int64(resp.StatusCode),
+		StatusText:  statusText(resp),
+		HTTPVersion: resp.Proto,
+		Cookies:     CookiesFromResponse(resp.Header),
+		Headers:     HeadersFromHTTP(resp.Header, nil),
+		RedirectURL: resp.Header.Get("Location"),
+		BodySize:    int64(len(body)),
+	}
+
+	decoded := body
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+		if d, err := decodeContentEncoding(enc, body); err == nil {
+			decoded = d
+		}
+	}
+	r.Content = &Content{
+		Size:     int64(len(decoded)),
+		MimeType: resp.Header.Get("Content-Type"),
+	}
+	if compression := int64(len(decoded)) - r.BodySize; compression != 0 {
+		r.Content.Compression = compression
+	}
+	bodySize := r.BodySize
+	r.ComputeSizes()
+	r.BodySize = bodySize // Keep the measured size, even for statuses that should have no body.
+	if utf8.Valid(decoded) {
+		r.Content.Text = string(decoded)
+	} else {
+		r.Content.Text = base64.StdEncoding.EncodeToString(decoded)
+		r.Content.Encoding = "base64"
+	}
+	return r, nil
+}
+
+// ConvertOption configures [Request.ToHTTP] and [Response.ToHTTP].
+type ConvertOption func(*convertOptions)
+
+type convertOptions struct {
+	preserveCase bool
+	rewriters    []RequestRewriter
+}
+
+// PreserveHeaderCase keeps the recorded header names as header map keys
+// instead of canonicalizing them, for clients that send keys as is to
+// reproduce a fingerprint. Methods of [http.Header] such as Get expect
+// canonical keys and may miss such headers.
+func PreserveHeaderCase() ConvertOption {
+	return func(o *convertOptions) { o.preserveCase = true }
+}
+
+func newConvertOptions(opts []ConvertOption) *convertOptions {
+	o := &convertOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// add adds the values of a recorded header to h, one value per line of
+// the recorded value; see [Request.HeaderValues].
+func (o *convertOptions) add(h http.Header, name, value string) {
+	for _, v := range splitHeaderValue(value) {
+		if o.preserveCase {
+			h[name] = append(h[name], v)
+		} else {
+			h.Add(name, v)
+		}
+	}
+}
+
+// ToHTTP converts r into an [http.Response] whose Body reads the decoded
+// content. Content-Encoding and Content-Length headers are dropped because
+// the body no longer matches them; ContentLength is set instead. Repeated
+// headers, such as Set-Cookie, are kept as separate values, never joined.
+func (r *Response) ToHTTP(opts ...ConvertOption) (*http.Response, error) {
+	o := newConvertOptions(opts)
+	var body []byte
+	if r.Content != nil {
+		text, err := r.Content.text()
+		if err != nil {
+			return nil, err
+		}
+		if r.Content.Encoding == "base64" {
+			b, err := base64.StdEncoding.DecodeString(text)
+			if err != nil {
+				return nil, fmt.Errorf("harfile: decoding response content: %w", err)
+			}
+			body = b
+		} else {
+			body = []byte(text)
+		}
+		if r.Content.ContentEncoding != "" {
+			if body, err = decodeContentEncoding(r.Content.ContentEncoding, body); err != nil {
+				return nil, fmt.Errorf("harfile: decoding response content: %w", err)
+			}
+		}
+	}
+
+	major, minor, ok := http.ParseHTTPVersion(r.HTTPVersion)
+	if !ok {
+		major, minor = 1, 1
+	}
+	resp := &http.Response{
+		Status:        strings.TrimSpace(strconv.FormatInt(r.Status, 10) + " " + r.StatusText),
+		StatusCode:    int(r.Status),
+		Proto:         fmt.Sprintf("HTTP/%d.%d", major, minor),
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        make(http.Header, len(r.Headers)),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+	for _, h := range r.Headers {
+		if h != nil {
+			o.add(resp.Header, h.Name, h.Value)
+		}
+	}
+	if headerValue(r.Headers, "Content-Encoding") != "" {
+		deleteHeader(resp.Header, "Content-Encoding")
+		resp.Uncompressed = true
+	}
+	deleteHeader(resp.Header, "Content-Length")
+	return resp, nil
+}
+
+// deleteHeader removes the values of name from h, whatever the case of
+// its keys.
+func deleteHeader(h http.Header, name string) {
+	for key := range h {
+		if strings.EqualFold(key, name) {
+			delete(h, key)
+		}
+	}
+}
+
+func statusText(resp *http.Response) string {
+	if _, text, ok := strings.Cut(resp.Status, " "); ok {
+		return text
+	}
+	return http.StatusText(resp.StatusCode)
+}
+
+// FromHTTPRequest converts req into a [Request]. body is the request body,
+// since req.Body itself is not consumed. The Host header, which net/http
+// keeps outside of req.Header, is listed first, and the other headers follow
+// the order given under [HeaderOrderKey], if any, as in [HeadersFromHTTP].
+// Header names are recorded as keyed in req.Header, not canonicalized.
+func FromHTTPRequest(req *http.Request, body []byte) (*Request, error) {
+	if req == nil || req.URL == nil {
+		return nil, fmt.Errorf("harfile: nil request")
+	}
+	proto := req.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	r := &Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: proto,
+		Cookies:     CookiesFromRequest(req.Header),
+		Headers:     []*NameValuePair{},
+		BodySize:    int64(len(body)),
+	}
+	if err := r.ParseQueryString(); err != nil {
+		return nil, err
+	}
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	if host != "" && req.Header.Get("Host") == "" {
+		r.Headers = append(r.Headers, &NameValuePair{Name: "Host", Value: host})
+	}
+	r.Headers = append(r.Headers, HeadersFromHTTP(req.Header, nil)...)
+
+	if len(body) > 0 {
+		contentType := req.Header.Get("Content-Type")
+		pd, err := PostDataFromBody(contentType, body)
+		if err != nil {
+			// Keep malformed multipart bodies rather than failing the conversion.
+			pd = rawPostData(contentType, body)
+		}
+		r.PostData = pd
+	}
+	r.ComputeSizes()
+	return r, nil
+}
+
+// ToHTTP converts r into an [http.Request] carrying its method, URL, headers
+// and body. HTTP/2 pseudo headers are skipped, the Host header becomes the
+// request Host, Content-Length is recomputed from the body, and cookies
+// are sent in a Cookie header when the headers have none. Repeated headers
+// are kept as separate values, never joined. The rewriters given by
+// [WithRewriters] are applied last.
+func (r *Request) ToHTTP(ctx context.Context, opts ...ConvertOption) (*http.Request, error) {
+	o := newConvertOptions(opts)
+	var body io.Reader
+	var contentType string
+	if r.PostData != nil {
+		var err error
+		if body, contentType, err = r.PostData.BodyReader(); err != nil {
+			return nil, err
+		}
+	}
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range r.Headers {
+		if h == nil || strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		switch http.CanonicalHeaderKey(h.Name) {
+		case "Host":
+			req.Host = h.Value
+		case "Content-Length":
+		default:
+			o.add(req.Header, h.Name, h.Value)
+		}
+	}
+	if contentType != "" && !hasHeader(r.Headers, "Content-Type") {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(r.Cookies) > 0 && !hasHeader(r.Headers, "Cookie") {
+		req.Header.Set("Cookie", ToCookieHeader(r.Cookies))
+	}
+	if err := RewriteRequest(ctx, req, o.rewriters...); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
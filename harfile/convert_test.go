@@ -0,0 +1,161 @@
+package harfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// httpResponse returns a response read from raw, with its body read.
+func httpResponse(t *testing.T, raw string) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(raw)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte(s))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func deflated(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	fw, _ := flate.NewWriter(&b, flate.BestCompression)
+	fw.Write([]byte(s))
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestFromHTTPResponse(t *testing.T) {
+	text := strings.Repeat("hello, world ", 20)
+	tests := []struct {
+		name        string
+		raw         string
+		size        int64
+		compression int64
+		text        string
+		encoding    string
+	}{
+		{"plain", "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\nhello", 5, 0, "hello", ""},
+		{"gzip", "HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\n\r\n" + gzipped(t, text), int64(len(text)), int64(len(text) - len(gzipped(t, text))), text, ""},
+		{"raw deflate", "HTTP/1.1 200 OK\r\nContent-Encoding: deflate\r\n\r\n" + deflated(t, text), int64(len(text)), int64(len(text) - len(deflated(t, text))), text, ""},
+		{"unsupported coding kept", "HTTP/1.1 200 OK\r\nContent-Encoding: snappy\r\n\r\nabc", 3, 0, "abc", ""},
+		{"binary", "HTTP/1.1 200 OK\r\nContent-Type: image/png\r\n\r\n\x89PNG\xff", 5, 0, "iVBOR/8=", "base64"},
+		{"empty", "HTTP/1.1 204 No Content\r\n\r\n", 0, 0, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := httpResponse(t, tt.raw)
+			r, err := FromHTTPResponse(resp, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := r.Content
+			if c.Size != tt.size || c.Compression != tt.compression || c.Text != tt.text || c.Encoding != tt.encoding {
+				t.Errorf("content = size %d, compression %d, %q, encoding %q", c.Size, c.Compression, c.Text, c.Encoding)
+			}
+			if r.BodySize != int64(len(body)) {
+				t.Errorf("BodySize = %d, want %d", r.BodySize, len(body))
+			}
+		})
+	}
+
+	if _, err := FromHTTPResponse(nil, nil); err == nil {
+		t.Error("no error for a nil response")
+	}
+}
+
+func TestFromHTTPResponseFields(t *testing.T) {
+	raw := "HTTP/1.1 302 Moved Elsewhere\r\n" +
+		"Location: /next\r\n" +
+		"Set-Cookie: id=1; Path=/; Domain=example.com; HttpOnly; Secure; Expires=Wed, 01 May 2024 12:00:00 GMT\r\n" +
+		"Set-Cookie: theme=dark\r\n" +
+		"Content-Length: 0\r\n\r\n"
+	resp, body := httpResponse(t, raw)
+	r, err := FromHTTPResponse(resp, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != 302 || r.StatusText != "Moved Elsewhere" || r.HTTPVersion != "HTTP/1.1" || r.RedirectURL != "/next" {
+		t.Errorf("status line = %d %q %s, redirect %q", r.Status, r.StatusText, r.HTTPVersion, r.RedirectURL)
+	}
+	if len(r.Cookies) != 2 {
+		t.Fatalf("%d cookies, want 2", len(r.Cookies))
+	}
+	want := Cookie{Name: "id", Value: "1", Path: "/", Domain: "example.com", Expires: "2024-05-01T12:00:00.000Z", HTTPOnly: true, Secure: true}
+	if *r.Cookies[0] != want {
+		t.Errorf("cookie = %+v, want %+v", *r.Cookies[0], want)
+	}
+	// Status line, four headers and the final CRLF.
+	wantSize := len("HTTP/1.1 302 Moved Elsewhere\r\n") + 2
+	for _, h := range r.Headers {
+		wantSize += len(h.Name) + len(": ") + len(h.Value) + 2
+	}
+	if r.HeadersSize != int64(wantSize) {
+		t.Errorf("HeadersSize = %d, want %d", r.HeadersSize, wantSize)
+	}
+
+	resp.Proto = "HTTP/2.0"
+	if r, _ := FromHTTPResponse(resp, body); r.HeadersSize != -1 {
+		t.Errorf("HTTP/2 HeadersSize = %d, want -1", r.HeadersSize)
+	}
+}
+
+func TestResponseToHTTP(t *testing.T) {
+	text := strings.Repeat("hello, world ", 20)
+	resp, body := httpResponse(t, "HTTP/1.1 201 Created\r\nContent-Encoding: gzip\r\nX-Id: 7\r\n\r\n"+gzipped(t, text))
+	r, err := FromHTTPResponse(resp, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Headers = append(r.Headers, &NameValuePair{Name: "Content-Length", Value: "9"})
+	out, err := r.ToHTTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(out.Body)
+	if string(got) != text || out.ContentLength != int64(len(text)) {
+		t.Errorf("body %q, ContentLength %d", got, out.ContentLength)
+	}
+	if out.Header.Get("Content-Encoding") != "" || out.Header.Get("Content-Length") != "" || !out.Uncompressed {
+		t.Errorf("headers %v, Uncompressed %v", out.Header, out.Uncompressed)
+	}
+	if out.StatusCode != 201 || out.Status != "201 Created" || out.Proto != "HTTP/1.1" || out.Header.Get("X-Id") != "7" {
+		t.Errorf("response = %d %q %s %v", out.StatusCode, out.Status, out.Proto, out.Header)
+	}
+
+	bin := &Response{Status: 200, HTTPVersion: "h3", Content: &Content{Text: "iVBOR/8=", Encoding: "base64"}}
+	out, err = bin.ToHTTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(out.Body); string(got) != "\x89PNG\xff" || out.Proto != "HTTP/1.1" {
+		t.Errorf("base64 body %q, proto %s", got, out.Proto)
+	}
+
+	bad := &Response{Status: 200, Content: &Content{Text: "not base64!", Encoding: "base64"}}
+	if _, err := bad.ToHTTP(); err == nil {
+		t.Error("no error for invalid base64 content")
+	}
+}
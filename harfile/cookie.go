@@ -0,0 +1,238 @@
+package harfile
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cookieTimeLayouts are the expiration formats found in HAR files produced by
+// common exporters, tried in order.
+var cookieTimeLayouts = []string{
+	time.RFC3339Nano,
+	ISO8601,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	time.RFC1123,
+	time.RFC1123Z,
+	"Mon, 02-Jan-2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04:05 -0700 (MST)",
+	time.RFC850,
+	"Monday, 02-Jan-2006 15:04:05 MST",
+	time.ANSIC,
+	time.UnixDate,
+}
+
+// ParseCookieTime parses a cookie expiration date written in ISO 8601 or in
+// one of the formats used by Set-Cookie headers (RFC 1123, RFC 850, ANSI C).
+func ParseCookieTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range cookieTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IsSession reports whether the cookie has no expiration date, i.e. Expires
+// is empty or the "Infinity" value written by Firefox.
+func (c *Cookie) IsSession() bool {
+	e := strings.TrimSpace(c.Expires)
+	return e == "" || strings.EqualFold(e, "Infinity")
+}
+
+// ExpiresTime returns the parsed expiration date. The second result is false
+// for session cookies and for dates in an unknown format.
+func (c *Cookie) ExpiresTime() (time.Time, bool) {
+	if c.IsSession() {
+		return time.Time{}, false
+	}
+	return ParseCookieTime(c.Expires)
+}
+
+// SetExpires sets the expiration date, formatted as ISO 8601. A zero t makes
+// the cookie a session cookie.
+func (c *Cookie) SetExpires(t time.Time) {
+	if t.IsZero() {
+		c.Expires = ""
+		return
+	}
+	c.Expires = t.Format(ISO8601)
+}
+
+type cookieAlias Cookie
+
+// MarshalJSON writes Expires as ISO 8601 when it can be parsed, and as is
+// otherwise, so that values from unknown exporters are not lost.
+func (c Cookie) MarshalJSON() ([]byte, error) {
+	if t, ok := c.ExpiresTime(); ok {
+		c.Expires = t.Format(ISO8601)
+	}
+	return marshalUnescaped(cookieAlias(c))
+}
+
+// UnmarshalJSON accepts any string for expires, as well as null and numeric
+// Unix timestamps in seconds.
+func (c *Cookie) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		*cookieAlias
+		Expires json.RawMessage `json:"expires,omitempty"`
+	}
+	aux.cookieAlias = (*cookieAlias)(c)
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	c.Expires = ""
+	switch raw := strings.TrimSpace(string(aux.Expires)); {
+	case raw == "" || raw == "null":
+	case raw[0] == '"':
+		return json.Unmarshal(aux.Expires, &c.Expires)
+	default:
+		var secs float64
+		if err := json.Unmarshal(aux.Expires, &secs); err != nil {
+			return err
+		}
+		whole, frac := math.Modf(secs)
+		c.SetExpires(time.Unix(int64(whole), int64(frac*1e9)).UTC())
+	}
+	return nil
+}
+
+// CookiesFromRequest returns the cookies sent in the Cookie headers of h, in
+// order. Values containing "=" are kept whole. A pair that is not a valid
+// cookie is kept with the raw text as Value and a note in Comment.
+func CookiesFromRequest(h http.Header) []*Cookie {
+	cookies := []*Cookie{}
+	for _, line := range h.Values("Cookie") {
+		cookies = append(cookies, parseCookieHeader(line)...)
+	}
+	return cookies
+}
+
+// parseCookieHeader parses the value of a Cookie request header.
+func parseCookieHeader(v string) []*Cookie {
+	var cookies []*Cookie
+	for _, part := range strings.Split(v, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !validCookieName(name) {
+			cookies = append(cookies, &Cookie{Value: part, Comment: "Malformed cookie, raw value kept."})
+			continue
+		}
+		cookies = append(cookies, &Cookie{Name: name, Value: strings.Trim(strings.TrimSpace(value), `"`)})
+	}
+	return cookies
+}
+
+// CookiesFromResponse returns the cookies set by the Set-Cookie headers of h,
+// with their Path, Domain, Expires, HttpOnly and Secure attributes. A header
+// that cannot be parsed is kept with the raw text as Value and a note in
+// Comment.
+func CookiesFromResponse(h http.Header) []*Cookie {
+	cookies := []*Cookie{}
+	for _, line := range h.Values("Set-Cookie") {
+		c, err := http.ParseSetCookie(line)
+		if err != nil {
+			cookies = append(cookies, &Cookie{Value: line, Comment: "Malformed Set-Cookie header, raw value kept: " + err.Error()})
+			continue
+		}
+		cookies = append(cookies, cookieFromHTTP(c))
+	}
+	return cookies
+}
+
+func cookieFromHTTP(c *http.Cookie) *Cookie {
+	cookie := &Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		HTTPOnly: c.HttpOnly,
+		Secure:   c.Secure,
+	}
+	cookie.SetExpires(c.Expires.UTC())
+	return cookie
+}
+
+// ToHTTP converts c into an [http.Cookie].
+func (c *Cookie) ToHTTP() *http.Cookie {
+	hc := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		HttpOnly: c.HTTPOnly,
+		Secure:   c.Secure,
+	}
+	if t, ok := c.ExpiresTime(); ok {
+		hc.Expires = t
+	}
+	return hc
+}
+
+// ToSetCookieHeader returns the Set-Cookie header value describing c.
+// Malformed cookies, which have no name, are returned raw.
+func (c *Cookie) ToSetCookieHeader() string {
+	if c.Name == "" {
+		return c.Value
+	}
+	var b strings.Builder
+	b.WriteString(c.Name)
+	b.WriteByte('=')
+	b.WriteString(c.Value)
+	if c.Path != "" {
+		b.WriteString("; Path=" + c.Path)
+	}
+	if c.Domain != "" {
+		b.WriteString("; Domain=" + c.Domain)
+	}
+	if t, ok := c.ExpiresTime(); ok {
+		b.WriteString("; Expires=" + t.UTC().Format(http.TimeFormat))
+	}
+	if c.HTTPOnly {
+		b.WriteString("; HttpOnly")
+	}
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+	return b.String()
+}
+
+// ToCookieHeader returns the Cookie request header value sending cookies.
+func ToCookieHeader(cookies []*Cookie) string {
+	parts := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		switch {
+		case c == nil:
+		case c.Name == "":
+			parts = append(parts, c.Value)
+		default:
+			parts = append(parts, c.Name+"="+c.Value)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// validCookieName reports whether name is an RFC 6265 token.
+func validCookieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= 0x20 || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?={}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
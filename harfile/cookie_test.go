@@ -0,0 +1,214 @@
+package harfile
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiresTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 12, 30, 15, 0, time.UTC)
+	tests := []struct {
+		name    string
+		expires string
+		want    time.Time
+		ok      bool
+	}{
+		{"iso 8601 milliseconds", "2024-05-01T12:30:15.000Z", want, true},
+		{"rfc 3339 nanoseconds", "2024-05-01T12:30:15.000000000Z", want, true},
+		{"iso 8601 offset", "2024-05-01T14:30:15.000+02:00", want, true},
+		{"offset without colon", "2024-05-01T14:30:15.000+0200", want, true},
+		{"no fraction, offset without colon", "2024-05-01T14:30:15+0200", want, true},
+		{"no zone", "2024-05-01T12:30:15", want, true},
+		{"space separator", "2024-05-01 12:30:15Z", want, true},
+		{"rfc 1123", "Wed, 01 May 2024 12:30:15 GMT", want, true},
+		{"rfc 1123 numeric zone", "Wed, 01 May 2024 14:30:15 +0200", want, true},
+		{"dashed rfc 1123", "Wed, 01-May-2024 12:30:15 GMT", want, true},
+		{"numeric zone and name", "Wed, 01 May 2024 14:30:15 +0200 (CEST)", want, true},
+		{"rfc 850", "Wednesday, 01-May-24 12:30:15 GMT", want, true},
+		{"four digit rfc 850", "Wednesday, 01-May-2024 12:30:15 GMT", want, true},
+		{"ansi c", "Wed May  1 12:30:15 2024", want, true},
+		{"unix date", "Wed May  1 12:30:15 UTC 2024", want, true},
+		{"surrounding spaces", "  2024-05-01T12:30:15.000Z ", want, true},
+		{"session", "", time.Time{}, false},
+		{"firefox session", "Infinity", time.Time{}, false},
+		{"unknown format", "next tuesday", time.Time{}, false},
+		{"date only", "2024-05-01", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cookie{Name: "id", Expires: tt.expires}
+			got, ok := c.ExpiresTime()
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("ExpiresTime(%q) = %v, %v, want %v, %v", tt.expires, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsSession(t *testing.T) {
+	for expires, want := range map[string]bool{"": true, " ": true, "infinity": true, "Infinity": true, "2024-05-01T12:30:15.000Z": false, "garbage": false} {
+		if got := (&Cookie{Expires: expires}).IsSession(); got != want {
+			t.Errorf("IsSession(%q) = %v, want %v", expires, got, want)
+		}
+	}
+}
+
+func TestSetExpires(t *testing.T) {
+	c := &Cookie{Name: "id"}
+	c.SetExpires(time.Date(2024, 5, 1, 14, 30, 15, 123e6, time.FixedZone("CEST", 2*3600)))
+	if c.Expires != "2024-05-01T14:30:15.123+02:00" {
+		t.Errorf("Expires = %q", c.Expires)
+	}
+	c.SetExpires(time.Time{})
+	if c.Expires != "" || !c.IsSession() {
+		t.Errorf("zero time: Expires = %q", c.Expires)
+	}
+}
+
+func TestCookieJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // Expires after decoding and encoding again.
+	}{
+		{"iso 8601", `"2024-05-01T12:30:15.000Z"`, "2024-05-01T12:30:15.000Z"},
+		{"rfc 1123 normalized", `"Wed, 01 May 2024 12:30:15 GMT"`, "2024-05-01T12:30:15.000Z"},
+		{"unknown kept", `"soon"`, "soon"},
+		{"null", `null`, ""},
+		{"unix seconds", `1714566615`, "2024-05-01T12:30:15.000Z"},
+		{"fractional seconds", `1714566615.25`, "2024-05-01T12:30:15.250Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Cookie
+			if err := json.Unmarshal([]byte(`{"name":"id","value":"1","expires":`+tt.in+`,"httpOnly":true}`), &c); err != nil {
+				t.Fatal(err)
+			}
+			if c.Name != "id" || c.Value != "1" || !c.HTTPOnly {
+				t.Errorf("cookie = %+v", c)
+			}
+			data, err := json.Marshal(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out struct{ Expires string }
+			json.Unmarshal(data, &out)
+			if out.Expires != tt.want {
+				t.Errorf("expires = %q, want %q", out.Expires, tt.want)
+			}
+		})
+	}
+
+	var c Cookie
+	if err := json.Unmarshal([]byte(`{"name":"id","expires":true}`), &c); err == nil {
+		t.Error("boolean expires accepted")
+	}
+}
+
+func TestCookiesFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   []Cookie
+	}{
+		{"pairs", []string{"a=1; b=2"}, []Cookie{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}},
+		{"several headers", []string{"a=1", "b=2"}, []Cookie{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}},
+		{"value with equals", []string{"token=abc==; x=a=b"}, []Cookie{{Name: "token", Value: "abc=="}, {Name: "x", Value: "a=b"}}},
+		{"quoted and spaced", []string{` a = "1" ;;b=`}, []Cookie{{Name: "a", Value: "1"}, {Name: "b", Value: ""}}},
+		{"no name", []string{"=bad; ok=1"}, []Cookie{{Value: "=bad", Comment: "Malformed cookie, raw value kept."}, {Name: "ok", Value: "1"}}},
+		{"no equals", []string{"flag"}, []Cookie{{Value: "flag", Comment: "Malformed cookie, raw value kept."}}},
+		{"invalid name", []string{"a b=1"}, []Cookie{{Value: "a b=1", Comment: "Malformed cookie, raw value kept."}}},
+		{"none", nil, []Cookie{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{"Cookie": tt.header}
+			got := CookiesFromRequest(h)
+			if got == nil || len(got) != len(tt.want) {
+				t.Fatalf("got %d cookies, want %d", len(got), len(tt.want))
+			}
+			for i, c := range got {
+				if *c != tt.want[i] {
+					t.Errorf("cookie %d = %+v, want %+v", i, *c, tt.want[i])
+				}
+			}
+			// The header written back sends the same bytes for each pair.
+			back := CookiesFromRequest(http.Header{"Cookie": {ToCookieHeader(got)}})
+			if len(back) != len(got) {
+				t.Errorf("ToCookieHeader round trip gave %d cookies, want %d", len(back), len(got))
+			}
+		})
+	}
+}
+
+func TestCookiesFromResponse(t *testing.T) {
+	h := http.Header{"Set-Cookie": {
+		"id=1; Path=/; Domain=example.com; Expires=Wed, 01 May 2024 12:30:15 GMT; HttpOnly; Secure",
+		"theme=dark",
+		"=bad",
+	}}
+	got := CookiesFromResponse(h)
+	if len(got) != 3 {
+		t.Fatalf("got %d cookies, want 3", len(got))
+	}
+	want := Cookie{Name: "id", Value: "1", Path: "/", Domain: "example.com", Expires: "2024-05-01T12:30:15.000Z", HTTPOnly: true, Secure: true}
+	if *got[0] != want {
+		t.Errorf("cookie = %+v, want %+v", *got[0], want)
+	}
+	if *got[1] != (Cookie{Name: "theme", Value: "dark"}) {
+		t.Errorf("session cookie = %+v", *got[1])
+	}
+	if bad := got[2]; bad.Name != "" || bad.Value != "=bad" || !strings.HasPrefix(bad.Comment, "Malformed Set-Cookie header, raw value kept: ") {
+		t.Errorf("malformed cookie = %+v", *bad)
+	}
+	if CookiesFromResponse(http.Header{}) == nil {
+		t.Error("nil slice for a response without cookies")
+	}
+}
+
+func TestToSetCookieHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie Cookie
+		want   string
+	}{
+		{"session", Cookie{Name: "a", Value: "1"}, "a=1"},
+		{"attributes", Cookie{Name: "id", Value: "1", Path: "/", Domain: "example.com", Expires: "2024-05-01T14:30:15.000+02:00", HTTPOnly: true, Secure: true},
+			"id=1; Path=/; Domain=example.com; Expires=Wed, 01 May 2024 12:30:15 GMT; HttpOnly; Secure"},
+		{"unknown expires dropped", Cookie{Name: "a", Value: "1", Expires: "soon"}, "a=1"},
+		{"malformed kept raw", Cookie{Value: "=bad", Comment: "Malformed cookie, raw value kept."}, "=bad"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cookie.ToSetCookieHeader()
+			if got != tt.want {
+				t.Errorf("ToSetCookieHeader = %q, want %q", got, tt.want)
+			}
+			if tt.cookie.Name == "" {
+				return
+			}
+			back := CookiesFromResponse(http.Header{"Set-Cookie": {got}})
+			if len(back) != 1 || back[0].Name != tt.cookie.Name || back[0].Path != tt.cookie.Path || back[0].HTTPOnly != tt.cookie.HTTPOnly {
+				t.Errorf("round trip = %+v", back)
+			}
+		})
+	}
+}
+
+func TestCookieToHTTP(t *testing.T) {
+	c := &Cookie{Name: "id", Value: "1", Path: "/", Domain: "example.com", Expires: "Wed, 01 May 2024 12:30:15 GMT", HTTPOnly: true, Secure: true}
+	hc := c.ToHTTP()
+	if hc.Name != "id" || hc.Value != "1" || hc.Path != "/" || hc.Domain != "example.com" || !hc.HttpOnly || !hc.Secure ||
+		!hc.Expires.Equal(time.Date(2024, 5, 1, 12, 30, 15, 0, time.UTC)) {
+		t.Errorf("ToHTTP = %+v", hc)
+	}
+	if hc := (&Cookie{Name: "s", Expires: "Infinity"}).ToHTTP(); !hc.Expires.IsZero() {
+		t.Errorf("session cookie expires %v", hc.Expires)
+	}
+	if got := ToCookieHeader([]*Cookie{{Name: "a", Value: "1"}, nil, {Value: "raw"}}); got != "a=1; raw" {
+		t.Errorf("ToCookieHeader = %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+package harfile
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Shell selects the quoting rules of a generated command line.
+type Shell int
+
+const (
+	POSIX      Shell = iota // sh, bash, zsh.
+	PowerShell              // Windows PowerShell and pwsh, invoking curl.exe.
+)
+
+// ErrBinaryBody is returned when a request body cannot be represented on a
+// command line for the requested shell.
+var ErrBinaryBody = errors.New("harfile: binary body cannot be passed on the command line")
+
+// CurlOptions configures [Entry.ToCurl].
+type CurlOptions struct {
+	Shell                Shell // Quoting rules; POSIX by default.
+	Multiline            bool  // Put each option on its own line, with line continuations.
+	MaxTime              bool  // Add --max-time derived from Entry.Time, rounded up to the second.
+	IncludeHost          bool  // Keep the Host header, which curl derives from the URL.
+	IncludeContentLength bool  // Keep the Content-Length header, which curl computes itself.
+}
+
+// ToCurl returns a curl command line reproducing the request of e. Cookies
+// are passed with -b and a compressed response is requested with
+// --compressed when the request sent Accept-Encoding. With the POSIX shell,
+// bodies that are not valid UTF-8 are piped from printf with
+// --data-binary @-.
+func (e *Entry) ToCurl(opts CurlOptions) (string, error) {
+	req := e.Request
+	if req == nil {
+		return "", errors.New("harfile: entry has no request")
+	}
+	quote := quotePOSIX
+	prog := "curl"
+	if opts.Shell == PowerShell {
+		quote = quotePowerShell
+		prog = "curl.exe"
+	}
+
+	var body string
+	var form []string
+	var hasBody, binary bool
+	if pd := req.PostData; pd != nil {
+		switch {
+		case pd.Text != "":
+			data, err := pd.DecodedText()
+			if err != nil {
+				return "", err
+			}
+			body, hasBody = string(data), true
+			binary = !utf8.ValidString(body) || strings.ContainsRune(body, 0)
+		case strings.HasPrefix(strings.ToLower(pd.MimeType), "multipart/form-data"):
+			for _, p := range pd.Params {
+				if p == nil {
+					continue
+				}
+				if p.FileName != "" {
+					form = append(form, "-F "+quote(p.Name+"=@"+p.FileName))
+				} else {
+					// -F would read a file for a value starting with @ or <.
+					form = append(form, "--form-string "+quote(p.Name+"="+p.Value))
+				}
+			}
+			hasBody = len(form) > 0
+		case len(pd.Params) > 0:
+			pairs := make([]string, 0, len(pd.Params))
+			for _, p := range pd.Params {
+				if p != nil {
+					pairs = append(pairs, url.QueryEscape(p.Name)+"="+url.QueryEscape(p.Value))
+				}
+			}
+			body, hasBody = strings.Join(pairs, "&"), true
+		}
+	}
+	if binary && opts.Shell == PowerShell {
+		return "", ErrBinaryBody
+	}
+
+	var args []string
+	method := strings.ToUpper(req.Method)
+	switch {
+	case method == "HEAD":
+		args = append(args, "--head")
+	case method == "" || method == "GET" && !hasBody, method == "POST" && hasBody:
+	default:
+		args = append(args, "-X "+quote(method))
+	}
+	switch req.Protocol() {
+	case ProtocolHTTP10:
+		args = append(args, "--http1.0")
+	case ProtocolHTTP2:
+		args = append(args, "--http2")
+	}
+
+	var cookies []string
+	compressed := false
+	for _, h := range req.Headers {
+		if h == nil {
+			continue
+		}
+		switch strings.ToLower(h.Name) {
+		case "host":
+			if !opts.IncludeHost {
+				continue
+			}
+		case "content-length":
+			if !opts.IncludeContentLength {
+				continue
+			}
+		case "cookie":
+			cookies = append(cookies, h.Value)
+			continue
+		case "accept-encoding":
+			compressed = true
+		}
+		if strings.HasPrefix(h.Name, ":") {
+			continue // HTTP/2 pseudo-headers are derived from the URL.
+		}
+		if len(form) > 0 && strings.EqualFold(h.Name, "Content-Type") {
+			continue // curl generates the multipart boundary.
+		}
+		args = append(args, "-H "+quote(h.Name+": "+h.Value))
+	}
+	if len(cookies) == 0 {
+		for _, c := range req.Cookies {
+			if c != nil {
+				cookies = append(cookies, c.Name+"="+c.Value)
+			}
+		}
+	}
+	if len(cookies) > 0 {
+		args = append(args, "-b "+quote(strings.Join(cookies, "; ")))
+	}
+	if compressed {
+		args = append(args, "--compressed")
+	}
+	switch {
+	case len(form) > 0:
+		args = append(args, form...)
+	case binary:
+		args = append(args, "--data-binary @-")
+	case hasBody:
+		args = append(args, "--data-raw "+quote(body))
+	}
+	if opts.MaxTime && e.Time > 0 {
+		args = append(args, fmt.Sprintf("--max-time %d", int64(math.Ceil(e.Time/1000))))
+	}
+
+	sep := " "
+	if opts.Multiline && len(args) > 0 {
+		sep = " \\\n  "
+		if opts.Shell == PowerShell {
+			sep = " `\n  "
+		}
+	}
+	cmd := prog + " " + quote(req.URL)
+	if len(args) > 0 {
+		cmd += sep + strings.Join(args, sep)
+	}
+	if binary {
+		cmd = "printf '" + octalEscape(body) + "' | " + cmd
+	}
+	return cmd, nil
+}
+
+// quotePOSIX single-quotes s for a POSIX shell.
+func quotePOSIX(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quotePowerShell single-quotes s for PowerShell, where a literal quote is
+// written twice.
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// octalEscape writes s as a printf format reproducing it byte for byte.
+func octalEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c < 0x7f && c != '\'' && c != '\\' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, `\%03o`, c)
+	}
+	return b.String()
+}
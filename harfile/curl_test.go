@@ -0,0 +1,149 @@
+package harfile
+
+import (
+	"errors"
+	"testing"
+)
+
+// curlEntry returns an entry for a request with the given headers, given as
+// name and value pairs, and an optional body typed by its Content-Type.
+func curlEntry(method, url string, headers ...string) *Entry {
+	req := &Request{Method: method, URL: url, HTTPVersion: "HTTP/1.1"}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Headers = append(req.Headers, &NameValuePair{Name: headers[i], Value: headers[i+1]})
+	}
+	return &Entry{Request: req}
+}
+
+// withBody sets the request body of e and returns e.
+func withBody(e *Entry, mimeType, text string) *Entry {
+	e.Request.Headers = append(e.Request.Headers, &NameValuePair{Name: "Content-Type", Value: mimeType})
+	e.Request.PostData = &PostData{MimeType: mimeType, Text: text}
+	return e
+}
+
+func TestToCurl(t *testing.T) {
+	form := curlEntry("POST", "https://example.com/upload")
+	form.Request.PostData = &PostData{
+		MimeType: "multipart/form-data; boundary=x",
+		Params: []*Param{
+			{Name: "file", FileName: "report.pdf"},
+			{Name: "note", Value: "@/etc/passwd"},
+			{Name: "other", Value: "</etc/hosts"},
+		},
+	}
+	form.Request.Headers = append(form.Request.Headers, &NameValuePair{Name: "Content-Type", Value: "multipart/form-data; boundary=x"})
+
+	tests := []struct {
+		name string
+		e    *Entry
+		opts CurlOptions
+		want string
+	}{
+		{
+			name: "get",
+			e:    curlEntry("GET", "https://example.com/?q=it's", "Accept", "*/*", "Host", "example.com"),
+			want: `curl 'https://example.com/?q=it'\''s' -H 'Accept: */*'`,
+		},
+		{
+			name: "host kept",
+			e:    curlEntry("GET", "https://example.com/", "Host", "example.com"),
+			opts: CurlOptions{IncludeHost: true},
+			want: `curl 'https://example.com/' -H 'Host: example.com'`,
+		},
+		{
+			name: "post with cookies and compression",
+			e: withBody(curlEntry("POST", "https://example.com/api", "Cookie", "a=1; b=2", "Accept-Encoding", "gzip"),
+				"application/json", `{"a":1}`),
+			want: `curl 'https://example.com/api' -H 'Accept-Encoding: gzip' -H 'Content-Type: application/json' -b 'a=1; b=2' --compressed --data-raw '{"a":1}'`,
+		},
+		{
+			name: "put with max time",
+			e: func() *Entry {
+				e := withBody(curlEntry("PUT", "https://example.com/x"), "text/plain", "hi")
+				e.Time = 1200
+				return e
+			}(),
+			opts: CurlOptions{MaxTime: true},
+			want: `curl 'https://example.com/x' -X 'PUT' -H 'Content-Type: text/plain' --data-raw 'hi' --max-time 2`,
+		},
+		{
+			name: "form values are not read as files",
+			e:    form,
+			want: `curl 'https://example.com/upload' -F 'file=@report.pdf' --form-string 'note=@/etc/passwd' --form-string 'other=</etc/hosts'`,
+		},
+		{
+			name: "binary body",
+			e: func() *Entry {
+				e := curlEntry("POST", "https://example.com/bin", "Content-Type", "application/octet-stream")
+				e.Request.PostData = &PostData{MimeType: "application/octet-stream", Text: "\x00\xff%'"}
+				return e
+			}(),
+			want: `printf '\000\377\045\047' | curl 'https://example.com/bin' -H 'Content-Type: application/octet-stream' --data-binary @-`,
+		},
+		{
+			name: "base64 body",
+			e: func() *Entry {
+				e := withBody(curlEntry("POST", "https://example.com/bin"), "application/octet-stream", "AP9h")
+				e.Request.PostData.Encoding = "base64"
+				return e
+			}(),
+			want: `printf '\000\377a' | curl 'https://example.com/bin' -H 'Content-Type: application/octet-stream' --data-binary @-`,
+		},
+		{
+			name: "powershell multiline",
+			e:    curlEntry("GET", "https://example.com/it's", "A", "1", "B", "2"),
+			opts: CurlOptions{Shell: PowerShell, Multiline: true},
+			want: "curl.exe 'https://example.com/it''s' `\n  -H 'A: 1' `\n  -H 'B: 2'",
+		},
+		{
+			name: "posix multiline",
+			e: func() *Entry {
+				e := curlEntry("HEAD", "https://example.com/")
+				e.Request.HTTPVersion = "HTTP/2.0"
+				return e
+			}(),
+			opts: CurlOptions{Multiline: true},
+			want: "curl 'https://example.com/' \\\n  --head \\\n  --http2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.e.ToCurl(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ToCurl =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToCurlBinaryPowerShell(t *testing.T) {
+	e := curlEntry("POST", "https://example.com/bin")
+	e.Request.PostData = &PostData{MimeType: "application/octet-stream", Text: "\x00\xff"}
+	if _, err := e.ToCurl(CurlOptions{Shell: PowerShell}); !errors.Is(err, ErrBinaryBody) {
+		t.Errorf("err = %v, want ErrBinaryBody", err)
+	}
+}
+
+func TestToCurlRoundTrip(t *testing.T) {
+	e := curlEntry("POST", "https://example.com/upload", "X-Note", "it's")
+	e.Request.PostData = &PostData{MimeType: "multipart/form-data", Params: []*Param{{Name: "note", Value: "@not-a-file"}}}
+	cmd, err := e.ToCurl(CurlOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromCurl(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := headerValue(back.Request.Headers, "X-Note"); got != "it's" {
+		t.Errorf("X-Note = %q, want %q", got, "it's")
+	}
+	params := back.Request.PostData.Params
+	if len(params) != 1 || params[0].Value != "@not-a-file" || params[0].FileName != "" {
+		t.Errorf("params = %+v, want the literal value", params)
+	}
+}
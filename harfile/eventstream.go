@@ -0,0 +1,23 @@
+package harfile
+
+import "time"
+
+// EventStreamMessage is an event of a text/event-stream response, recorded
+// by harkit in Entry.EventStreamMessages as it arrives.
+type EventStreamMessage struct {
+	Offset float64 `json:"offset"`          // Milliseconds between Entry.StartedDateTime and the arrival of the event.
+	Event  string  `json:"event,omitempty"` // Event type, left out for the default "message".
+	ID     string  `json:"id,omitempty"`    // Last event ID set by the stream.
+	Data   string  `json:"data"`            // Data lines, joined by newlines.
+}
+
+// NewEventStreamMessage returns an event received at t for the entry
+// started at started.
+func NewEventStreamMessage(event, id, data string, started, t time.Time) *EventStreamMessage {
+	return &EventStreamMessage{
+		Offset: float64(t.Sub(started)) / float64(time.Millisecond),
+		Event:  event,
+		ID:     id,
+		Data:   data,
+	}
+}
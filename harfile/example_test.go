@@ -0,0 +1,69 @@
+package harfile_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func ExampleLoad() {
+	const doc = `{"log": {
+  "version": "1.2",
+  "creator": {"name": "example", "version": "1.0"},
+  "entries": [{
+    "startedDateTime": "2024-03-01T09:00:00Z",
+    "time": 42,
+    "request": {"method": "GET", "url": "https://example.com/", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+    "response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 2, "mimeType": "text/plain", "text": "hi"}, "redirectURL": "", "headersSize": -1, "bodySize": 2},
+    "cache": {},
+    "timings": {"send": 1, "wait": 40, "receive": 1}
+  }]
+}}`
+	h, err := harfile.Load(strings.NewReader(doc))
+	if err != nil {
+		panic(err)
+	}
+	if err := h.Validate(); err != nil {
+		panic(err)
+	}
+	e := h.Log.Entries[0]
+	fmt.Println(e.Request.Method, e.Request.URL, e.Response.Status, e.Time)
+
+	// Validate reports every problem found, with its path.
+	e.Request.Method = ""
+	fmt.Println(h.Validate())
+	// Output:
+	// GET https://example.com/ 200 42
+	// harfile: invalid HAR: log.entries[0].request.method: empty
+}
+
+func ExampleMerge() {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	capture := func(url string, offset time.Duration) *harfile.HAR {
+		l := harfile.NewLog().Entries(harfile.NewEntry().Get(url).StartedAt(start.Add(offset)).RespondStatus(200).Build()).Build()
+		l.AddPage("page_1", url, start.Add(offset))
+		l.Entries[0].Pageref = "page_1"
+		return &harfile.HAR{Log: l}
+	}
+
+	merged, err := harfile.Merge(
+		capture("https://example.com/second", time.Minute),
+		capture("https://example.com/first", 0),
+	)
+	if err != nil {
+		panic(err)
+	}
+	for _, p := range merged.Log.Pages {
+		fmt.Println("page", p.ID, p.Title)
+	}
+	for _, e := range merged.Log.Entries {
+		fmt.Println("entry", e.Pageref, e.Request.URL)
+	}
+	// Output:
+	// page page_1 https://example.com/second
+	// page page_1-2 https://example.com/first
+	// entry page_1-2 https://example.com/first
+	// entry page_1 https://example.com/second
+}
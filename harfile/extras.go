@@ -0,0 +1,366 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// fieldSet holds the JSON member names of a struct type.
+type fieldSet struct {
+	exact map[string]bool
+	names []string
+}
+
+func fieldsOf(t reflect.Type) *fieldSet {
+	fs := &fieldSet{exact: make(map[string]bool)}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs.exact[name] = true
+		fs.names = append(fs.names, name)
+	}
+	return fs
+}
+
+// known reports whether encoding/json would store the member key in a field,
+// which includes case insensitive matches.
+func (fs *fieldSet) known(key []byte) bool {
+	if fs.exact[string(key)] {
+		return true
+	}
+	for _, name := range fs.names {
+		if bytes.EqualFold(key, []byte(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	harFields      = fieldsOf(reflect.TypeFor[HAR]())
+	logFields      = fieldsOf(reflect.TypeFor[Log]())
+	pageFields     = fieldsOf(reflect.TypeFor[Page]())
+	entryFields    = fieldsOf(reflect.TypeFor[Entry]())
+	requestFields  = fieldsOf(reflect.TypeFor[Request]())
+	responseFields = fieldsOf(reflect.TypeFor[Response]())
+	contentFields  = fieldsOf(reflect.TypeFor[Content]())
+	timingsFields  = fieldsOf(reflect.TypeFor[Timings]())
+
+	securityDetailsFields = fieldsOf(reflect.TypeFor[SecurityDetails]())
+)
+
+// collectExtras returns the members of the JSON object data that are not in
+// fs, or nil when there are none. data must be valid JSON, as already
+// accepted by [json.Unmarshal]; the scan only walks the top level and does
+// not allocate unless an unknown member is found.
+func collectExtras(data []byte, fs *fieldSet) map[string]json.RawMessage {
+	i := scanSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return nil
+	}
+	var extras map[string]json.RawMessage
+	i++
+	for {
+		i = scanSpace(data, i)
+		if i >= len(data) || data[i] == '}' {
+			return extras
+		}
+		keyStart := i
+		i = scanString(data, i)
+		key := data[keyStart+1 : i-1]
+		i = scanSpace(data, i) + 1 // ':'
+		i = scanSpace(data, i)
+		valueStart := i
+		i = scanValue(data, i)
+		if !fs.known(key) {
+			name := string(key)
+			if bytes.IndexByte(key, '\\') >= 0 {
+				json.Unmarshal(data[keyStart:keyStart+len(key)+2], &name)
+			}
+			if extras == nil {
+				extras = make(map[string]json.RawMessage)
+			}
+			extras[name] = slices.Clone(data[valueStart:i])
+		}
+		i = scanSpace(data, i)
+		if i < len(data) && data[i] == ',' {
+			i++
+		}
+	}
+}
+
+func scanSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// scanString returns the index following the string starting at data[i].
+// It jumps from quote to quote, so that long bodies are skipped quickly, and
+// counts the backslashes before each to tell whether it is escaped.
+func scanString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		j := bytes.IndexByte(data[i:], '"')
+		if j < 0 {
+			return len(data)
+		}
+		i += j
+		escapes := 0
+		for k := i - 1; k >= 0 && data[k] == '\\'; k-- {
+			escapes++
+		}
+		if escapes%2 == 0 {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// scanValue returns the index following the value starting at data[i].
+func scanValue(data []byte, i int) int {
+	depth := 0
+	for i < len(data) {
+		switch data[i] {
+		case '"':
+			i = scanString(data, i)
+			if depth == 0 {
+				return i
+			}
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case ',', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return i
+}
+
+// marshalWithExtras encodes v, a JSON object, followed by extras in sorted
+// order. Extras shadowed by a field of v are dropped.
+func marshalWithExtras(v any, extras map[string]json.RawMessage, fs *fieldSet) ([]byte, error) {
+	data, err := marshalUnescaped(v)
+	if err != nil || len(extras) == 0 {
+		return data, err
+	}
+	keys := make([]string, 0, len(extras))
+	for k := range extras {
+		if !fs.known([]byte(k)) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	data = data[:len(data)-1] // '}'
+	for _, k := range keys {
+		if len(data) > 1 {
+			data = append(data, ',')
+		}
+		name, _ := json.Marshal(k)
+		data = append(data, name...)
+		data = append(data, ':')
+		if raw := extras[k]; len(raw) > 0 {
+			data = append(data, raw...)
+		} else {
+			data = append(data, "null"...)
+		}
+	}
+	return append(data, '}'), nil
+}
+
+// marshalUnescaped is like [json.Marshal] but leaves '<', '>' and '&'
+// unescaped, so that encoders configured not to escape them, as used by
+// [HAR.Write], get readable text. Encoders escaping them still do so.
+func marshalUnescaped(v any) ([]byte, error) {
+	enc := encoderPool.Get().(*unescapedEncoder)
+	defer encoderPool.Put(enc)
+	enc.buf.Reset()
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	data := bytes.TrimSuffix(enc.buf.Bytes(), []byte("\n"))
+	// One spare byte lets marshalWithExtras reuse the slice for a ','.
+	return append(make([]byte, 0, len(data)+1), data...), nil
+}
+
+// unescapedEncoder is an encoder writing to its own buffer, pooled as
+// MarshalJSON runs once per object of a log.
+type unescapedEncoder struct {
+	buf bytes.Buffer
+	*json.Encoder
+}
+
+var encoderPool = sync.Pool{New: func() any {
+	enc := new(unescapedEncoder)
+	enc.Encoder = json.NewEncoder(&enc.buf)
+	enc.SetEscapeHTML(false)
+	return enc
+}}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (h *HAR) UnmarshalJSON(data []byte) error {
+	type har HAR
+	if err := json.Unmarshal(data, (*har)(h)); err != nil {
+		return err
+	}
+	h.Extras = collectExtras(data, harFields)
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (h HAR) MarshalJSON() ([]byte, error) {
+	type har HAR
+	return marshalWithExtras(har(h), h.Extras, harFields)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (l *Log) UnmarshalJSON(data []byte) error {
+	type log Log
+	if err := json.Unmarshal(data, (*log)(l)); err != nil {
+		return err
+	}
+	l.Extras = collectExtras(data, logFields)
+	l.view = nil
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (l Log) MarshalJSON() ([]byte, error) {
+	type log Log
+	return marshalWithExtras(log(l), l.Extras, logFields)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (p *Page) UnmarshalJSON(data []byte) error {
+	type page Page
+	if err := json.Unmarshal(data, (*page)(p)); err != nil {
+		return err
+	}
+	p.Extras = collectExtras(data, pageFields)
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (p Page) MarshalJSON() ([]byte, error) {
+	type page Page
+	return marshalWithExtras(page(p), p.Extras, pageFields)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	type entry Entry
+	if err := json.Unmarshal(data, (*entry)(e)); err != nil {
+		return err
+	}
+	e.Extras = collectExtras(data, entryFields)
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type entry Entry
+	return marshalWithExtras(entry(e), e.Extras, entryFields)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	type request Request
+	if err := json.Unmarshal(data, (*request)(r)); err != nil {
+		return err
+	}
+	r.Extras = collectExtras(data, requestFields)
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (r Request) MarshalJSON() ([]byte, error) {
+	type request Request
+	return marshalWithExtras(request(r), r.Extras, requestFields)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type response Response
+	if err := json.Unmarshal(data, (*response)(r)); err != nil {
+		return err
+	}
+	r.Extras = collectExtras(data, responseFields)
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (r Response) MarshalJSON() ([]byte, error) {
+	type response Response
+	return marshalWithExtras(response(r), r.Extras, responseFields)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (c *Content) UnmarshalJSON(data []byte) error {
+	type content Content
+	if err := json.Unmarshal(data, (*content)(c)); err != nil {
+		return err
+	}
+	c.Extras = collectExtras(data, contentFields)
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back. A body held
+// by a [BodyStore] is written inline.
+func (c Content) MarshalJSON() ([]byte, error) {
+	if err := c.Inline(); err != nil {
+		return nil, err
+	}
+	type content Content
+	return marshalWithExtras(content(c), c.Extras, contentFields)
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (t Timings) MarshalJSON() ([]byte, error) {
+	return marshalWithExtras(timingsAlias(t), t.Extras, timingsFields)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping unknown members in
+// Extras.
+func (d *SecurityDetails) UnmarshalJSON(data []byte) error {
+	type securityDetails SecurityDetails
+	if err := json.Unmarshal(data, (*securityDetails)(d)); err != nil {
+		return err
+	}
+	d.Extras = collectExtras(data, securityDetailsFields)
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], writing Extras back.
+func (d SecurityDetails) MarshalJSON() ([]byte, error) {
+	type securityDetails SecurityDetails
+	return marshalWithExtras(securityDetails(d), d.Extras, securityDetailsFields)
+}
@@ -0,0 +1,270 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeTree decodes data into generic values, keeping numbers as written.
+func decodeTree(t testing.TB, data []byte) any {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// lost returns the path of a member of want missing or changed in got, or
+// "" when got holds everything want does. Numbers must be written the same
+// way; timestamps only need to denote the same instant. Members written as
+// null, "" or [] may be left out, as the types omit empty fields.
+func lost(path string, want, got any) string {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return path
+		}
+		for k, v := range w {
+			gv, ok := g[k]
+			if !ok && !omittable(v) {
+				return path + "." + k
+			}
+			if !ok {
+				continue
+			}
+			if p := lost(path+"."+k, v, gv); p != "" {
+				return p
+			}
+		}
+		return ""
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return path
+		}
+		for i := range w {
+			if p := lost(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); p != "" {
+				return p
+			}
+		}
+		return ""
+	case string:
+		g, ok := got.(string)
+		if ok && g == w {
+			return ""
+		}
+		wt, err1 := time.Parse(time.RFC3339Nano, w)
+		gt, err2 := time.Parse(time.RFC3339Nano, g)
+		if ok && err1 == nil && err2 == nil && wt.Equal(gt) {
+			return ""
+		}
+		return path
+	}
+	if !reflect.DeepEqual(want, got) {
+		return path
+	}
+	return ""
+}
+
+func omittable(v any) bool {
+	a, isArray := v.([]any)
+	return v == nil || v == "" || isArray && len(a) == 0
+}
+
+// TestExtrasCorpus round trips exports of several tools, each with its own
+// extensions, and checks that every member comes back.
+func TestExtrasCorpus(t *testing.T) {
+	for _, file := range []string{"chrome.har", "firefox.har", "charles.har"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h, err := Load(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := h.Write(&out); err != nil {
+				t.Fatal(err)
+			}
+			if p := lost("", decodeTree(t, data), decodeTree(t, out.Bytes())); p != "" {
+				t.Errorf("%s lost on round trip:\n%s", p, out.Bytes())
+			}
+
+			// A second round trip is byte for byte stable.
+			again, err := Load(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out2 bytes.Buffer
+			if err := again.Write(&out2); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), out2.Bytes()) {
+				t.Errorf("second round trip differs:\n%s\n%s", out.Bytes(), out2.Bytes())
+			}
+		})
+	}
+}
+
+func TestCollectExtras(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{"none", `{"status":200,"statusText":"OK"}`, nil},
+		{"empty object", ` { } `, nil},
+		{"not an object", `[1,2]`, nil},
+		{"known in any case", `{"STATUS":200,"Cookies":[]}`, nil},
+		{"unknown", `{"status":200,"_failed":null,"_x":{"a":[1,"}"]}}`, map[string]string{"_failed": "null", "_x": `{"a":[1,"}"]}`}},
+		{"string values", `{"_a":"x\"y","status":1,"_b":"\\"}`, map[string]string{"_a": `"x\"y"`, "_b": `"\\"`}},
+		{"escaped key", `{"_é":1}`, map[string]string{"_é": "1"}},
+		{"whitespace", "{\n\t\"_n\" :\t-1.5e3 ,\r\n\"status\": 2\n}", map[string]string{"_n": "-1.5e3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collectExtras([]byte(tt.data), responseFields)
+			if len(got) != len(tt.want) {
+				t.Fatalf("collectExtras = %s, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if string(got[k]) != v {
+					t.Errorf("%s = %s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestMarshalWithExtras(t *testing.T) {
+	c := Content{Size: 2, MimeType: "text/plain", Text: "<>", Extras: map[string]json.RawMessage{
+		"_b":       json.RawMessage(`[1]`),
+		"_a":       nil,
+		"mimeType": json.RawMessage(`"shadowed"`),
+	}}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"size":2,"mimeType":"text/plain","text":"\u003c\u003e","_a":null,"_b":[1]}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	c.Extras = nil
+	data, _ = json.Marshal(c)
+	if strings.Contains(string(data), "_") {
+		t.Errorf("Marshal without extras = %s", data)
+	}
+}
+
+// benchmarkHAR returns the encoding of a log of n entries, with Chrome
+// style extensions when extensions is set.
+func benchmarkHAR(b *testing.B, n int, extensions bool) []byte {
+	b.Helper()
+	entries := make([]*Entry, n)
+	for i := range entries {
+		e := &Entry{
+			StartedDateTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Request: &Request{
+				Method:      "POST",
+				URL:         fmt.Sprintf("https://example.com/api/items/%d?page=2", i),
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []*NameValuePair{{Name: "Accept", Value: "application/json"}},
+				QueryString: []*NameValuePair{{Name: "page", Value: "2"}},
+				PostData:    &PostData{MimeType: "application/json", Text: `{"name":"item","tags":["a","b"]}`},
+				HeadersSize: -1,
+				BodySize:    32,
+			},
+			Response: &Response{
+				Status:      200,
+				StatusText:  "OK",
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []*NameValuePair{{Name: "Content-Type", Value: "application/json"}},
+				Content:     &Content{MimeType: "application/json", Text: strings.Repeat(`{"id":1,"text":"lorem ipsum"},`, 20) + `{}`},
+				HeadersSize: -1,
+			},
+			Cache:   &Cache{},
+			Timings: &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 10, Receive: 1},
+		}
+		e.Response.Content.Size = int64(len(e.Response.Content.Text))
+		e.Response.BodySize = e.Response.Content.Size
+		if extensions {
+			e.Extras = map[string]json.RawMessage{"_fromCache": json.RawMessage(`"memory"`), "_connectionId": json.RawMessage(`"42"`)}
+			e.Response.Extras = map[string]json.RawMessage{"_fetchedViaServiceWorker": json.RawMessage(`false`)}
+			e.Timings.Extras = map[string]json.RawMessage{"_blocked_queueing": json.RawMessage(`0.4`)}
+		}
+		entries[i] = e
+	}
+	data, err := json.Marshal(&HAR{Log: &Log{Version: "1.2", Creator: &Creator{Name: "bench", Version: "1"}, Entries: entries}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, extensions := range []bool{false, true} {
+		b.Run(fmt.Sprintf("extensions=%v", extensions), func(b *testing.B) {
+			data := benchmarkHAR(b, 200, extensions)
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for range b.N {
+				var h HAR
+				if err := json.Unmarshal(data, &h); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, extensions := range []bool{false, true} {
+		b.Run(fmt.Sprintf("extensions=%v", extensions), func(b *testing.B) {
+			data := benchmarkHAR(b, 200, extensions)
+			var h HAR
+			if err := json.Unmarshal(data, &h); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := json.Marshal(&h); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCollectExtras measures the scan for unknown members alone, on an
+// entry without any.
+func BenchmarkCollectExtras(b *testing.B) {
+	var h HAR
+	if err := json.Unmarshal(benchmarkHAR(b, 1, false), &h); err != nil {
+		b.Fatal(err)
+	}
+	data, err := json.Marshal(h.Log.Entries[0])
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for range b.N {
+		if extras := collectExtras(data, entryFields); extras != nil {
+			b.Fatal(extras)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package harfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrNoLog is returned by [Load] for a JSON document without a "log" member.
+var ErrNoLog = errors.New("harfile: document has no log")
+
+// utf8BOM is written by some Windows exporters in front of the document.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// Load decodes a HAR document from r, skipping a leading UTF-8 byte order
+// mark. Gzip compressed documents are decompressed; see [WithCompression].
+func Load(r io.Reader, opts ...FileOption) (*HAR, error) {
+	o := newFileOptions(opts)
+	br := bufio.NewReader(o.reader(r))
+	if prefix, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	var h HAR
+	if err := json.NewDecoder(br).Decode(&h); err != nil {
+		return nil, fmt.Errorf("harfile: decode: %w", err)
+	}
+	o.finish()
+	if h.Log == nil {
+		return nil, ErrNoLog
+	}
+	if o.normalizeVersions {
+		h.NormalizeHTTPVersions()
+	}
+	if o.sniffMimeTypes {
+		h.Log.CorrectMimeTypes()
+	}
+	if o.load.MaxBodyBytes > 0 {
+		for _, e := range h.Log.Entries {
+			if e != nil {
+				fixCutBody(e)
+			}
+		}
+	}
+	return &h, nil
+}
+
+// LoadFile decodes the HAR file at path, see [Load].
+func LoadFile(path string, opts ...FileOption) (*HAR, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f, opts...)
+}
+
+// Write encodes h to w as indented JSON. Unlike [json.Marshal], characters
+// such as '<' and '&' are not escaped, so bodies stay readable.
+func (h *HAR) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h)
+}
+
+// WriteFile writes h to the file at path, see [HAR.Write]. The file is gzip
+// compressed when its name ends in ".gz"; see [WithCompression].
+func (h *HAR) WriteFile(path string, opts ...FileOption) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	var w io.Writer = bw
+	var gz *gzip.Writer
+	if newFileOptions(opts).gzipped(path) {
+		gz = gzip.NewWriter(bw)
+		w = gz
+	}
+	if err := h.Write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,158 @@
+package harfile
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Predicate selects entries. Predicates must accept entries with a nil
+// Request or Response.
+type Predicate func(*Entry) bool
+
+// FilterOption configures [Log.Filter].
+type FilterOption func(*filterOptions)
+
+type filterOptions struct {
+	keepPages bool
+}
+
+// KeepAllPages keeps every page in the filtered log, including pages no
+// selected entry refers to.
+func KeepAllPages() FilterOption {
+	return func(o *filterOptions) { o.keepPages = true }
+}
+
+// Filter returns a new Log holding the entries selected by pred, in order.
+// The entries and pages are shared with l, not copied. Pages no selected
+// entry refers to are dropped unless [KeepAllPages] is given; other log
+// fields are copied as is.
+func (l *Log) Filter(pred Predicate, opts ...FilterOption) *Log {
+	o := &filterOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	out := *l
+	out.view = nil
+	out.Entries = make([]*Entry, 0, len(l.Entries))
+	refs := make(map[string]bool)
+	for _, e := range l.Entries {
+		if e != nil && pred(e) {
+			out.Entries = append(out.Entries, e)
+			refs[e.Pageref] = true
+		}
+	}
+	if !o.keepPages && l.Pages != nil {
+		out.Pages = make([]*Page, 0, len(refs))
+		for _, p := range l.Pages {
+			if p != nil && refs[p.ID] {
+				out.Pages = append(out.Pages, p)
+			}
+		}
+	}
+	return &out
+}
+
+// And returns a predicate selecting entries matched by every pred.
+func And(preds ...Predicate) Predicate {
+	return func(e *Entry) bool {
+		for _, p := range preds {
+			if !p(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a predicate selecting entries matched by at least one pred.
+func Or(preds ...Predicate) Predicate {
+	return func(e *Entry) bool {
+		for _, p := range preds {
+			if p(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a predicate selecting entries not matched by pred.
+func Not(pred Predicate) Predicate {
+	return func(e *Entry) bool { return !pred(e) }
+}
+
+// ByHost selects requests whose host matches glob, using [path.Match]
+// syntax, case insensitively. The port is ignored unless glob contains one.
+// For instance "*.example.com" selects "api.example.com".
+func ByHost(glob string) Predicate {
+	glob = strings.ToLower(glob)
+	withPort := strings.Contains(glob, ":")
+	return func(e *Entry) bool {
+		if e.Request == nil {
+			return false
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return false
+		}
+		host := u.Host
+		if !withPort {
+			host = u.Hostname()
+		}
+		ok, _ := path.Match(glob, strings.ToLower(host))
+		return ok
+	}
+}
+
+// ByStatusRange selects responses whose status is within [lo, hi].
+func ByStatusRange(lo, hi int) Predicate {
+	return func(e *Entry) bool {
+		return e.Response != nil && e.Response.Status >= int64(lo) && e.Response.Status <= int64(hi)
+	}
+}
+
+// Failed selects entries whose request got no response, such as DNS
+// failures, refused connections and aborted requests, written by browsers
+// with status 0 and an _error, see [Response.Error].
+func Failed() Predicate {
+	return func(e *Entry) bool {
+		return e.Response == nil || e.Response.Status == 0 || e.Response.Error != ""
+	}
+}
+
+// ByMimeType selects responses whose content MIME type starts with prefix,
+// case insensitively, e.g. "application/json" or "image/".
+func ByMimeType(prefix string) Predicate {
+	prefix = strings.ToLower(prefix)
+	return func(e *Entry) bool {
+		if e.Response == nil || e.Response.Content == nil {
+			return false
+		}
+		return strings.HasPrefix(strings.ToLower(strings.TrimSpace(e.Response.Content.MimeType)), prefix)
+	}
+}
+
+// ByResourceType selects entries whose Chrome resource type is one of
+// types, compared case insensitively, e.g. ByResourceType("xhr", "fetch").
+// Entries exported without a resource type never match.
+func ByResourceType(types ...string) Predicate {
+	return func(e *Entry) bool {
+		for _, t := range types {
+			if e.ResourceType != "" && strings.EqualFold(e.ResourceType, t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByTimeRange selects entries started within [from, to). A zero bound is
+// unbounded.
+func ByTimeRange(from, to time.Time) Predicate {
+	return func(e *Entry) bool {
+		t := e.StartedDateTime
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+	}
+}
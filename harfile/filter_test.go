@@ -0,0 +1,126 @@
+package harfile
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPredicates(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	full := &Entry{
+		StartedDateTime: start,
+		Request:         &Request{Method: "GET", URL: "https://API.example.com:8443/v1/users"},
+		Response:        &Response{Status: 404, Content: &Content{MimeType: " Application/JSON; charset=utf-8"}},
+	}
+	entries := map[string]*Entry{
+		"full":         full,
+		"nil request":  {StartedDateTime: start, Response: full.Response},
+		"nil response": {StartedDateTime: start, Request: full.Request},
+		"nil content":  {StartedDateTime: start, Request: full.Request, Response: &Response{Status: 404}},
+		"bad url":      {StartedDateTime: start, Request: &Request{URL: "http://[::1"}, Response: full.Response},
+		"empty":        {},
+	}
+	tests := []struct {
+		name string
+		pred Predicate
+		want string // Names of the selected entries, sorted.
+	}{
+		{"host glob", ByHost("*.example.com"), "full nil content nil response"},
+		{"host with port", ByHost("api.example.com:8443"), "full nil content nil response"},
+		{"host other port", ByHost("api.example.com:443"), ""},
+		{"status range", ByStatusRange(400, 499), "bad url full nil content nil request"},
+		{"status outside", ByStatusRange(200, 299), ""},
+		{"mime type", ByMimeType("application/json"), "bad url full nil request"},
+		{"mime prefix", ByMimeType("IMAGE/"), ""},
+		{"time range", ByTimeRange(start, start.Add(time.Second)), "bad url full nil content nil request nil response"},
+		{"time unbounded", ByTimeRange(time.Time{}, time.Time{}), "bad url empty full nil content nil request nil response"},
+		{"time end excluded", ByTimeRange(time.Time{}, start), "empty"},
+		{"and", And(ByHost("*.example.com"), ByStatusRange(404, 404)), "full nil content"},
+		{"or", Or(ByMimeType("application/json"), ByHost("*.example.com")), "bad url full nil content nil request nil response"},
+		{"not", Not(ByStatusRange(0, 599)), "empty nil response"},
+		{"empty and", And(), "bad url empty full nil content nil request nil response"},
+		{"empty or", Or(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for name, e := range entries {
+				if tt.pred(e) {
+					got = append(got, name)
+				}
+			}
+			slices.Sort(got)
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("selected %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entry := func(url, pageref string) *Entry {
+		return &Entry{Pageref: pageref, Request: &Request{Method: "GET", URL: url}, Response: &Response{Status: 200}}
+	}
+	l := &Log{
+		Version: "1.2",
+		Comment: "capture",
+		Pages:   []*Page{{ID: "a"}, nil, {ID: "b"}, {ID: "c"}},
+		Entries: []*Entry{
+			entry("https://example.com/1", "a"),
+			nil,
+			entry("https://cdn.example.net/2", "b"),
+			entry("https://example.com/3", ""),
+		},
+	}
+	ids := func(l *Log) string {
+		var s []string
+		for _, p := range l.Pages {
+			s = append(s, p.ID)
+		}
+		return strings.Join(s, " ")
+	}
+
+	out := l.Filter(ByHost("example.com"))
+	if len(out.Entries) != 2 || out.Entries[0] != l.Entries[0] || out.Entries[1] != l.Entries[3] {
+		t.Errorf("entries = %v", out.Entries)
+	}
+	if ids(out) != "a" || out.Comment != "capture" || out.Version != "1.2" {
+		t.Errorf("pages %q, comment %q, version %q", ids(out), out.Comment, out.Version)
+	}
+	if len(l.Entries) != 4 || len(l.Pages) != 4 {
+		t.Error("Filter changed its receiver")
+	}
+
+	if out := l.Filter(ByHost("example.com"), KeepAllPages()); len(out.Pages) != 4 {
+		t.Errorf("KeepAllPages kept %d pages", len(out.Pages))
+	}
+	if out := l.Filter(Or()); len(out.Entries) != 0 || len(out.Pages) != 0 || out.Pages == nil {
+		t.Errorf("nothing selected: %d entries, pages %v", len(out.Entries), out.Pages)
+	}
+	if out := (&Log{Entries: l.Entries}).Filter(And()); out.Pages != nil || len(out.Entries) != 3 {
+		t.Errorf("log without pages: %d entries, pages %v", len(out.Entries), out.Pages)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	entries := map[string]*Entry{
+		"ok":          {Response: &Response{Status: 200}},
+		"error page":  {Response: &Response{Status: 502}},
+		"no response": {},
+		"status 0":    {Response: &Response{}},
+		"chrome":      {Response: &Response{Error: "net::ERR_CONNECTION_REFUSED"}},
+		"aborted":     {Response: &Response{Status: 200, Error: "net::ERR_ABORTED"}},
+	}
+	var got []string
+	for name, e := range entries {
+		if Failed()(e) {
+			got = append(got, name)
+		}
+	}
+	slices.Sort(got)
+	if want := "aborted chrome no response status 0"; strings.Join(got, " ") != want {
+		t.Errorf("selected %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,466 @@
+package harfile
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// curlArgFlags are curl options taking an argument that FromCurl does not
+// translate. They are reported in the entry comment with their argument.
+var curlArgFlags = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"--retry": true, "-x": true, "--proxy": true, "--cacert": true, "-E": true, "--cert": true,
+	"--key": true, "-w": true, "--write-out": true, "--resolve": true, "--limit-rate": true,
+	"-T": true, "--upload-file": true, "-r": true, "--range": true, "-c": true, "--cookie-jar": true,
+	"--interface": true, "-K": true, "--config": true,
+}
+
+// curlIgnoredFlags only change how curl behaves locally and have no
+// counterpart in a HAR request.
+var curlIgnoredFlags = map[string]bool{
+	"--compressed": true, "-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-L": true, "--location": true, "-k": true, "--insecure": true, "-i": true, "--include": true,
+	"-v": true, "--verbose": true, "-f": true, "--fail": true, "-g": true, "--globoff": true,
+}
+
+// FromCurl parses a curl command line, such as the ones produced by the
+// "Copy as cURL" action of browsers, into an entry. Single, double and
+// ANSI-C ($'...') quoting and backslash line continuations are understood.
+//
+// Supported options are -X/--request, -H/--header, -b/--cookie,
+// -d/--data/--data-raw/--data-binary/--data-ascii/--data-urlencode,
+// -F/--form, --form-string, -u/--user, -A/--user-agent, -e/--referer,
+// -G/--get, -I/--head, --url, --http1.0 and --http2. Other options are listed in the entry
+// Comment instead of failing the conversion. The response, cache and timings
+// are empty stubs.
+func FromCurl(cmd string) (*Entry, error) {
+	args, err := splitShell(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || (args[0] != "curl" && args[0] != "curl.exe") {
+		return nil, errors.New("harfile: not a curl command")
+	}
+	args = args[1:]
+
+	var (
+		method, rawURL, httpVersion string
+		headers                     []*NameValuePair
+		cookies                     []string
+		data                        []string
+		form                        []*Param
+		getData, head               bool
+		unsupported                 []string
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, inline := splitCurlFlag(arg)
+		next := func() (string, error) {
+			if inline {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("harfile: curl option %s requires an argument", name)
+			}
+			i++
+			return args[i], nil
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if rawURL == "" {
+				rawURL = arg
+			} else {
+				unsupported = append(unsupported, arg)
+			}
+			continue
+		}
+
+		var v string
+		switch name {
+		case "-X", "--request", "-H", "--header", "-b", "--cookie", "-d", "--data", "--data-raw",
+			"--data-binary", "--data-ascii", "--data-urlencode", "-F", "--form", "--form-string", "-u", "--user",
+			"-A", "--user-agent", "-e", "--referer", "--url":
+			if v, err = next(); err != nil {
+				return nil, err
+			}
+		}
+		switch name {
+		case "-X", "--request":
+			method = strings.ToUpper(v)
+		case "-H", "--header":
+			if h := parseCurlHeader(v); h != nil {
+				headers = append(headers, h)
+			}
+		case "-b", "--cookie":
+			if strings.Contains(v, "=") {
+				cookies = append(cookies, v)
+			} else {
+				unsupported = append(unsupported, name+" "+v)
+			}
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(v, "@") {
+				unsupported = append(unsupported, name+" "+v)
+			}
+			if name != "--data-binary" {
+				v = strings.NewReplacer("\r", "", "\n", "").Replace(v)
+			}
+			data = append(data, v)
+		case "--data-raw":
+			data = append(data, v)
+		case "--data-urlencode":
+			data = append(data, curlURLEncode(v))
+		case "-F", "--form":
+			form = append(form, parseCurlForm(v))
+		case "--form-string":
+			name, value, _ := strings.Cut(v, "=")
+			form = append(form, &Param{Name: name, Value: value})
+		case "-u", "--user":
+			headers = append(headers, &NameValuePair{Name: "Authorization", Value: "Basic " + base64.StdEncoding.EncodeToString([]byte(v))})
+		case "-A", "--user-agent":
+			headers = append(headers, &NameValuePair{Name: "User-Agent", Value: v})
+		case "-e", "--referer":
+			headers = append(headers, &NameValuePair{Name: "Referer", Value: v})
+		case "--url":
+			rawURL = v
+		case "-G", "--get":
+			getData = true
+		case "-I", "--head":
+			head = true
+		case "--http1.0":
+			httpVersion = "HTTP/1.0"
+		case "--http1.1":
+			httpVersion = "HTTP/1.1"
+		case "--http2", "--http2-prior-knowledge":
+			httpVersion = "HTTP/2.0"
+		default:
+			switch {
+			case curlIgnoredFlags[name]:
+			case curlArgFlags[name]:
+				v, err := next()
+				if err != nil {
+					return nil, err
+				}
+				unsupported = append(unsupported, name+" "+v)
+			default:
+				unsupported = append(unsupported, arg)
+			}
+		}
+	}
+	if rawURL == "" {
+		return nil, errors.New("harfile: curl command has no URL")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("harfile: invalid URL in curl command: %w", err)
+	}
+
+	body := strings.Join(data, "&")
+	if getData && len(data) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += body
+		data, body = nil, ""
+	}
+	switch {
+	case method != "":
+	case head:
+		method = "HEAD"
+	case len(data) > 0 || len(form) > 0:
+		method = "POST"
+	default:
+		method = "GET"
+	}
+	if httpVersion == "" {
+		httpVersion = "HTTP/1.1"
+	}
+
+	req := &Request{
+		Method:      method,
+		URL:         u.String(),
+		HTTPVersion: httpVersion,
+		Cookies:     []*Cookie{},
+		Headers:     headers,
+		QueryString: parseQuery(u.RawQuery, "&;"),
+		HeadersSize: -1,
+	}
+	if req.Headers == nil {
+		req.Headers = []*NameValuePair{}
+	}
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, "Cookie") {
+			cookies = append(cookies, h.Value)
+		}
+	}
+	for _, c := range cookies {
+		req.Cookies = append(req.Cookies, parseCookieHeader(c)...)
+	}
+	if len(cookies) > 0 && !hasHeader(headers, "Cookie") {
+		req.Headers = append(req.Headers, &NameValuePair{Name: "Cookie", Value: strings.Join(cookies, "; ")})
+	}
+
+	switch {
+	case len(form) > 0:
+		req.PostData = &PostData{MimeType: "multipart/form-data", Params: form}
+		req.BodySize = -1
+	case len(data) > 0:
+		mimeType := headerValue(req.Headers, "Content-Type")
+		if mimeType == "" {
+			mimeType = "application/x-www-form-urlencoded"
+		}
+		req.PostData = &PostData{MimeType: mimeType, Params: []*Param{}, Text: body}
+		if strings.HasPrefix(strings.ToLower(mimeType), "application/x-www-form-urlencoded") {
+			for _, q := range queryFromRaw(body) {
+				req.PostData.Params = append(req.PostData.Params, &Param{Name: q.Name, Value: q.Value})
+			}
+		}
+		req.BodySize = int64(len(body))
+	}
+
+	e := &Entry{
+		StartedDateTime: time.Now().UTC(),
+		Time:            0,
+		Request:         req,
+		Response: &Response{
+			Cookies:     []*Cookie{},
+			Headers:     []*NameValuePair{},
+			Content:     &Content{MimeType: "x-unknown"},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Cache:   &Cache{},
+		Timings: &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1},
+	}
+	if len(unsupported) > 0 {
+		e.Comment = "Unsupported curl options: " + strings.Join(unsupported, " ")
+	}
+	return e, nil
+}
+
+// splitCurlFlag splits "--header=value" and "-Hvalue" forms.
+func splitCurlFlag(arg string) (name, value string, inline bool) {
+	if strings.HasPrefix(arg, "--") {
+		if name, value, ok := strings.Cut(arg, "="); ok {
+			return name, value, true
+		}
+		return arg, "", false
+	}
+	if len(arg) > 2 && strings.HasPrefix(arg, "-") {
+		return arg[:2], arg[2:], true
+	}
+	return arg, "", false
+}
+
+func parseCurlHeader(v string) *NameValuePair {
+	name, value, ok := strings.Cut(v, ":")
+	if !ok {
+		// "Name;" sends a header with an empty value.
+		if name, ok := strings.CutSuffix(strings.TrimSpace(v), ";"); ok {
+			return &NameValuePair{Name: name}
+		}
+		return &NameValuePair{Name: strings.TrimSpace(v)}
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil // "Name:" removes a header curl would send.
+	}
+	return &NameValuePair{Name: strings.TrimSpace(name), Value: value}
+}
+
+func parseCurlForm(v string) *Param {
+	name, value, _ := strings.Cut(v, "=")
+	p := &Param{Name: name}
+	parts := strings.Split(value, ";")
+	value = parts[0]
+	for _, attr := range parts[1:] {
+		if t, ok := strings.CutPrefix(strings.TrimSpace(attr), "type="); ok {
+			p.ContentType = t
+		}
+	}
+	switch {
+	case strings.HasPrefix(value, "@"):
+		p.FileName = value[1:]
+	case strings.HasPrefix(value, "<"):
+		p.FileName = value[1:]
+	default:
+		p.Value = value
+	}
+	return p
+}
+
+// curlURLEncode implements the encoding rules of --data-urlencode.
+func curlURLEncode(v string) string {
+	if name, content, ok := strings.Cut(v, "="); ok {
+		if name == "" {
+			return url.QueryEscape(content)
+		}
+		return name + "=" + url.QueryEscape(content)
+	}
+	return url.QueryEscape(v)
+}
+
+func hasHeader(headers []*NameValuePair, name string) bool {
+	for _, h := range headers {
+		if h != nil && strings.EqualFold(h.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func headerValue(headers []*NameValuePair, name string) string {
+	for _, h := range headers {
+		if h != nil && strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// splitShell splits a POSIX shell command line into words.
+func splitShell(s string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++ // Line continuation.
+				continue
+			}
+			if i+2 < len(s) && s[i+1] == '\r' && s[i+2] == '\n' {
+				i += 2
+				continue
+			}
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("harfile: unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := ansiCString(s[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1 // The loop steps over the closing quote.
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("harfile: unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ansiCString decodes the body of a $'...' string, returning the number of
+// bytes consumed including the closing quote.
+func ansiCString(s string, w *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' {
+			return i + 1, nil
+		}
+		if c != '\\' || i+1 >= len(s) {
+			w.WriteByte(c)
+			continue
+		}
+		i++
+		switch e := s[i]; e {
+		case 'n':
+			w.WriteByte('\n')
+		case 't':
+			w.WriteByte('\t')
+		case 'r':
+			w.WriteByte('\r')
+		case 'a':
+			w.WriteByte('\a')
+		case 'b':
+			w.WriteByte('\b')
+		case 'e', 'E':
+			w.WriteByte(0x1b)
+		case 'f':
+			w.WriteByte('\f')
+		case 'v':
+			w.WriteByte('\v')
+		case '\\', '\'', '"', '?':
+			w.WriteByte(e)
+		case 'x', 'u', 'U':
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+			j := i + 1
+			for j < len(s) && j < i+1+size && isHex(s[j]) {
+				j++
+			}
+			if j == i+1 {
+				w.WriteByte('\\')
+				w.WriteByte(e)
+				continue
+			}
+			n, _ := strconv.ParseUint(s[i+1:j], 16, 32)
+			if e == 'x' {
+				w.WriteByte(byte(n))
+			} else {
+				w.WriteString(string(rune(n)))
+			}
+			i = j - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(s[i:j], 8, 8)
+			w.WriteByte(byte(n))
+			i = j - 1
+		default:
+			w.WriteByte('\\')
+			w.WriteByte(e)
+		}
+	}
+	return 0, errors.New("harfile: unterminated $'...' string")
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -0,0 +1,175 @@
+package harfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitShell(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"plain", `curl -s https://example.com`, []string{"curl", "-s", "https://example.com"}},
+		{"single quotes", `curl -H 'a: b c'`, []string{"curl", "-H", "a: b c"}},
+		{"double quotes", `curl -d "a \"b\" \$c \d"`, []string{"curl", "-d", `a "b" $c \d`}},
+		{"adjacent quotes", `curl -H 'a'"b"c`, []string{"curl", "-H", "abc"}},
+		{"ansi-c", `curl -H $'a: b\n\tc\'d'`, []string{"curl", "-H", "a: b\n\tc'd"}},
+		{"ansi-c followed by word", `curl -H $'a: b' -H 'c: d'`, []string{"curl", "-H", "a: b", "-H", "c: d"}},
+		{"ansi-c at end", `curl --data-raw $'x'`, []string{"curl", "--data-raw", "x"}},
+		{"ansi-c hex and unicode", `curl -d $'\x41é\101'`, []string{"curl", "-d", "AéA"}},
+		{"line continuation", "curl 'https://example.com' \\\n  -H 'a: b' \\\r\n  --compressed", []string{"curl", "https://example.com", "-H", "a: b", "--compressed"}},
+		{"escaped space", `curl a\ b`, []string{"curl", "a b"}},
+		{"empty word", `curl -d ''`, []string{"curl", "-d", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShell(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitShell(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitShellErrors(t *testing.T) {
+	for _, in := range []string{`curl 'a`, `curl "a`, `curl $'a`} {
+		if _, err := splitShell(in); err == nil {
+			t.Errorf("splitShell(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestFromCurl(t *testing.T) {
+	tests := []struct {
+		name        string
+		cmd         string
+		method      string
+		url         string
+		headers     map[string]string
+		cookies     []string
+		query       []string
+		body        string
+		mimeType    string
+		unsupported string
+	}{
+		{
+			name: "chrome get",
+			cmd: `curl 'https://example.com/search?q=har&page=2' \
+  -H 'accept: text/html,application/xhtml+xml' \
+  -H 'accept-language: en-US,en;q=0.9' \
+  -b 'sid=abc123; theme=dark' \
+  -H 'user-agent: Mozilla/5.0 (X11; Linux x86_64) Chrome/124.0.0.0'`,
+			method:  "GET",
+			url:     "https://example.com/search?q=har&page=2",
+			headers: map[string]string{"accept-language": "en-US,en;q=0.9", "Cookie": "sid=abc123; theme=dark"},
+			cookies: []string{"sid=abc123", "theme=dark"},
+			query:   []string{"q=har", "page=2"},
+		},
+		{
+			name: "chrome post with ansi-c strings",
+			cmd: `curl 'https://example.com/api/items' \
+  -H 'content-type: application/json' \
+  -H $'x-note: it\'s fine' \
+  -H 'origin: https://example.com' \
+  --data-raw $'{"name":"caf\\u00e9","note":"line\\nbreak"}' \
+  --compressed`,
+			method:   "POST",
+			url:      "https://example.com/api/items",
+			headers:  map[string]string{"x-note": "it's fine", "origin": "https://example.com"},
+			body:     `{"name":"caf\u00e9","note":"line\nbreak"}`,
+			mimeType: "application/json",
+		},
+		{
+			name:    "chrome ansi-c header then quoted header",
+			cmd:     `curl 'https://example.com/' -H $'a: b' -H 'c: d'`,
+			method:  "GET",
+			url:     "https://example.com/",
+			headers: map[string]string{"a": "b", "c": "d"},
+		},
+		{
+			name:     "firefox post",
+			cmd:      `curl 'https://example.com/login' -X POST -H 'User-Agent: Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0' -H 'Accept: */*' -H 'Content-Type: application/x-www-form-urlencoded' -H 'Cookie: a=1; b=2' --data-raw 'user=joe&pass=s%26cret'`,
+			method:   "POST",
+			url:      "https://example.com/login",
+			headers:  map[string]string{"Accept": "*/*", "Cookie": "a=1; b=2"},
+			cookies:  []string{"a=1", "b=2"},
+			body:     "user=joe&pass=s%26cret",
+			mimeType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:        "firefox get with unsupported options",
+			cmd:         `curl 'https://example.com/file.zip' --globoff -o out.zip --max-time 5 -H 'Accept: */*'`,
+			method:      "GET",
+			url:         "https://example.com/file.zip",
+			headers:     map[string]string{"Accept": "*/*"},
+			unsupported: "Unsupported curl options: -o out.zip --max-time 5",
+		},
+		{
+			name:    "user and get data",
+			cmd:     `curl -G -u joe:secret --url example.com/q -d a=1 -d b=2`,
+			method:  "GET",
+			url:     "http://example.com/q?a=1&b=2",
+			headers: map[string]string{"Authorization": "Basic am9lOnNlY3JldA=="},
+			query:   []string{"a=1", "b=2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := FromCurl(tt.cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := e.Request
+			if req.Method != tt.method || req.URL != tt.url {
+				t.Errorf("request = %s %s, want %s %s", req.Method, req.URL, tt.method, tt.url)
+			}
+			for name, want := range tt.headers {
+				if got := headerValue(req.Headers, name); got != want {
+					t.Errorf("header %s = %q, want %q", name, got, want)
+				}
+			}
+			var cookies []string
+			for _, c := range req.Cookies {
+				cookies = append(cookies, c.Name+"="+c.Value)
+			}
+			if !reflect.DeepEqual(cookies, tt.cookies) {
+				t.Errorf("cookies = %q, want %q", cookies, tt.cookies)
+			}
+			var query []string
+			for _, q := range req.QueryString {
+				query = append(query, q.Name+"="+q.Value)
+			}
+			if !reflect.DeepEqual(query, tt.query) {
+				t.Errorf("query = %q, want %q", query, tt.query)
+			}
+			switch {
+			case tt.body == "" && req.PostData != nil:
+				t.Errorf("postData = %+v, want none", req.PostData)
+			case tt.body != "" && (req.PostData == nil || req.PostData.Text != tt.body || req.PostData.MimeType != tt.mimeType):
+				t.Errorf("postData = %+v, want %s %q", req.PostData, tt.mimeType, tt.body)
+			}
+			if e.Comment != tt.unsupported {
+				t.Errorf("comment = %q, want %q", e.Comment, tt.unsupported)
+			}
+			if err := (&HAR{Log: &Log{Creator: &Creator{Name: "test"}, Entries: []*Entry{e}}}).Validate(); err != nil {
+				t.Errorf("entry does not validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestFromCurlErrors(t *testing.T) {
+	for _, cmd := range []string{"", "wget https://example.com", "curl -H 'a: b'", "curl https://example.com -H", "curl 'https://example.com"} {
+		if _, err := FromCurl(cmd); err == nil {
+			t.Errorf("FromCurl(%q) succeeded, want an error", cmd)
+		} else if !strings.HasPrefix(err.Error(), "harfile: ") {
+			t.Errorf("FromCurl(%q) error %q lacks the package prefix", cmd, err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotGraphQL is returned by [PostData.GraphQL] for a body that is not a
+// GraphQL request.
+var ErrNotGraphQL = errors.New("harfile: not a GraphQL request")
+
+// GraphQLInfo describes the GraphQL request held by a [PostData].
+type GraphQLInfo struct {
+	Batched    bool                // The body is an array of operations.
+	Operations []*GraphQLOperation // One operation, or one per batch element.
+}
+
+// Names returns the names of the operations, skipping anonymous ones.
+func (g *GraphQLInfo) Names() []string {
+	var names []string
+	for _, op := range g.Operations {
+		if op.Name != "" {
+			names = append(names, op.Name)
+		}
+	}
+	return names
+}
+
+// GraphQLOperation is an operation of a GraphQL request.
+type GraphQLOperation struct {
+	Name      string          // operationName, or the name given in the query.
+	Type      string          // "query", "mutation" or "subscription"; empty for persisted queries sent without their text.
+	Query     string          // Query document; empty for persisted queries sent without their text.
+	Variables json.RawMessage // Variables as sent, nil when absent.
+
+	// PersistedHash is the SHA-256 hash of an automatic persisted query
+	// (extensions.persistedQuery.sha256Hash), when the request uses one.
+	PersistedHash string
+}
+
+type graphQLBody struct {
+	Query         *string         `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+	Extensions    struct {
+		PersistedQuery struct {
+			SHA256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// GraphQL parses pd as a GraphQL request: a JSON object with a query, or
+// an array of them for batched requests. Persisted queries, which send a
+// hash instead of the query text, are reported with PersistedHash set and
+// no Type. Operation types come from a light parse of the query, so a
+// document selecting its operation through operationName gets the type of
+// that operation. Bodies that are not GraphQL give [ErrNotGraphQL].
+func (pd *PostData) GraphQL() (*GraphQLInfo, error) {
+	text := bytes.TrimSpace([]byte(pd.Text))
+	if pd.Encoding != "" || len(text) == 0 {
+		return nil, ErrNotGraphQL
+	}
+	info := &GraphQLInfo{}
+	var bodies []graphQLBody
+	if text[0] == '[' {
+		info.Batched = true
+		if err := json.Unmarshal(text, &bodies); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotGraphQL, err)
+		}
+	} else {
+		var body graphQLBody
+		if err := json.Unmarshal(text, &body); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotGraphQL, err)
+		}
+		bodies = append(bodies, body)
+	}
+	if len(bodies) == 0 {
+		return nil, ErrNotGraphQL
+	}
+
+	for _, b := range bodies {
+		op := &GraphQLOperation{
+			Name:          b.OperationName,
+			PersistedHash: b.Extensions.PersistedQuery.SHA256Hash,
+		}
+		if len(b.Variables) > 0 && string(b.Variables) != "null" {
+			op.Variables = b.Variables
+		}
+		switch {
+		case b.Query != nil && *b.Query != "":
+			op.Query = *b.Query
+			op.Type, op.Name = graphQLOperationType(op.Query, op.Name)
+		case op.PersistedHash == "":
+			return nil, ErrNotGraphQL
+		}
+		info.Operations = append(info.Operations, op)
+	}
+	return info, nil
+}
+
+// graphQLOperationType returns the type and name of the operation named
+// name in the query document, or of its first operation when name is empty
+// or not found.
+func graphQLOperationType(query, name string) (string, string) {
+	var first [2]string
+	depth := 0
+	inDefinition := false // Between a definition keyword and its selection set.
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '"':
+			i = skipGraphQLString(query, i)
+			continue
+		case c == '{' || c == '(' || c == '[':
+			if c == '{' && depth == 0 {
+				if !inDefinition && first[0] == "" {
+					first = [2]string{"query", ""} // Query shorthand.
+				}
+				inDefinition = false
+			}
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		case depth == 0 && isGraphQLNameStart(c):
+			word := graphQLName(query[i:])
+			i += len(word)
+			switch word {
+			case "fragment":
+				inDefinition = true
+			case "query", "mutation", "subscription":
+				inDefinition = true
+				rest := strings.TrimLeft(query[i:], " \t\r\n,")
+				opName := graphQLName(rest)
+				if name != "" && opName == name {
+					return word, name
+				}
+				if first[0] == "" {
+					first = [2]string{word, opName}
+				}
+			}
+			continue
+		}
+		i++
+	}
+	if name == "" {
+		name = first[1]
+	}
+	return first[0], name
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// graphQLName returns the name at the start of s, if any.
+func graphQLName(s string) string {
+	if s == "" || !isGraphQLNameStart(s[0]) {
+		return ""
+	}
+	n := 1
+	for n < len(s) && (isGraphQLNameStart(s[n]) || s[n] >= '0' && s[n] <= '9') {
+		n++
+	}
+	return s[:n]
+}
+
+// skipGraphQLString returns the index following the string or block string
+// starting at query[i].
+func skipGraphQLString(query string, i int) int {
+	if strings.HasPrefix(query[i:], `"""`) {
+		if end := strings.Index(query[i+3:], `"""`); end >= 0 {
+			return i + 3 + end + 3
+		}
+		return len(query)
+	}
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"', '\n':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// ByGraphQLOperation selects GraphQL requests with an operation named name,
+// including batched requests where any operation matches.
+func ByGraphQLOperation(name string) Predicate {
+	return func(e *Entry) bool {
+		if e.Request == nil || e.Request.PostData == nil {
+			return false
+		}
+		info, err := e.Request.PostData.GraphQL()
+		if err != nil {
+			return false
+		}
+		for _, op := range info.Operations {
+			if op.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,108 @@
+package harfile
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		batched bool
+		want    []GraphQLOperation // Query is only checked to be non-empty.
+	}{
+		{"named query", `{"query":"query GetUser($id: ID!) { user(id: $id) { name } }","variables":{"id":"1"}}`, false,
+			[]GraphQLOperation{{Name: "GetUser", Type: "query", Variables: []byte(`{"id":"1"}`)}}},
+		{"shorthand", `{"query":"{ me { id } }"}`, false,
+			[]GraphQLOperation{{Type: "query"}}},
+		{"operationName selects", `{"query":"query A { a } mutation B { b } subscription C { c }","operationName":"B"}`, false,
+			[]GraphQLOperation{{Name: "B", Type: "mutation"}}},
+		{"unknown operationName", `{"query":"mutation A { a }","operationName":"Z"}`, false,
+			[]GraphQLOperation{{Name: "Z", Type: "mutation"}}},
+		{"first operation", `{"query":"mutation Save { save } query Load { load }"}`, false,
+			[]GraphQLOperation{{Name: "Save", Type: "mutation"}}},
+		{"fragment first", `{"query":"fragment F on User { name } subscription Watch { user { ...F } }"}`, false,
+			[]GraphQLOperation{{Name: "Watch", Type: "subscription"}}},
+		{"keywords in comments and strings", `{"query":"# mutation Fake { x }\nquery Real { a(s: \"mutation X {\", b: \"\"\"subscription { \"\"\") }"}`, false,
+			[]GraphQLOperation{{Name: "Real", Type: "query"}}},
+		{"null variables", `{"query":"query Q { q }","variables":null}`, false,
+			[]GraphQLOperation{{Name: "Q", Type: "query"}}},
+		{"persisted", `{"operationName":"Feed","extensions":{"persistedQuery":{"version":1,"sha256Hash":"abc123"}}}`, false,
+			[]GraphQLOperation{{Name: "Feed", PersistedHash: "abc123"}}},
+		{"batch", `[{"query":"query One { a }"},{"query":"mutation { b }"}]`, true,
+			[]GraphQLOperation{{Name: "One", Type: "query"}, {Type: "mutation"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := (&PostData{MimeType: "application/json", Text: tt.text}).GraphQL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Batched != tt.batched || len(info.Operations) != len(tt.want) {
+				t.Fatalf("batched %v with %d operations, want %v with %d", info.Batched, len(info.Operations), tt.batched, len(tt.want))
+			}
+			for i, op := range info.Operations {
+				w := tt.want[i]
+				if op.Name != w.Name || op.Type != w.Type || string(op.Variables) != string(w.Variables) || op.PersistedHash != w.PersistedHash {
+					t.Errorf("operation %d = %+v, want %+v", i, *op, w)
+				}
+				if (op.Query == "") != (w.PersistedHash != "") {
+					t.Errorf("operation %d query = %q", i, op.Query)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQLNotGraphQL(t *testing.T) {
+	for name, pd := range map[string]*PostData{
+		"empty":        {},
+		"blank":        {Text: " \n"},
+		"form":         {Text: "a=1&b=2"},
+		"json":         {Text: `{"user":"ada"}`},
+		"empty query":  {Text: `{"query":""}`},
+		"empty batch":  {Text: `[]`},
+		"batch member": {Text: `[{"query":"{ a }"},{"id":1}]`},
+		"base64":       {Text: "eyJxdWVyeSI6InsgYSB9In0=", Encoding: "base64"},
+	} {
+		if info, err := pd.GraphQL(); !errors.Is(err, ErrNotGraphQL) {
+			t.Errorf("%s: GraphQL() = %+v, %v", name, info, err)
+		}
+	}
+}
+
+func TestGraphQLNames(t *testing.T) {
+	info, err := (&PostData{Text: `[{"query":"query A { a }"},{"query":"{ b }"},{"query":"query C { c }"}]`}).GraphQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Names(); !slices.Equal(got, []string{"A", "C"}) {
+		t.Errorf("Names = %q", got)
+	}
+}
+
+func TestByGraphQLOperation(t *testing.T) {
+	entry := func(text string) *Entry {
+		return &Entry{Request: &Request{Method: "POST", PostData: &PostData{MimeType: "application/json", Text: text}}}
+	}
+	pred := ByGraphQLOperation("Load")
+	for _, tt := range []struct {
+		name string
+		e    *Entry
+		want bool
+	}{
+		{"named", entry(`{"query":"query Load { a }"}`), true},
+		{"operationName", entry(`{"query":"query Save { a } query Load { b }","operationName":"Load"}`), true},
+		{"batch member", entry(`[{"query":"query Save { a }"},{"query":"query Load { b }"}]`), true},
+		{"other", entry(`{"query":"query Save { a }"}`), false},
+		{"not graphql", entry(`{"Load":1}`), false},
+		{"no body", &Entry{Request: &Request{Method: "GET"}}, false},
+		{"no request", &Entry{}, false},
+	} {
+		if got := pred(tt.e); got != tt.want {
+			t.Errorf("%s: selected %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
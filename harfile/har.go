@@ -2,11 +2,16 @@
 // See: http://www.softwareishard.com/blog/har-12-spec/
 package harfile
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // HAR parent container for log.
 type HAR struct {
 	Log *Log `json:"log"` //
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
 }
 
 // Log represents the root of exported data.
@@ -17,6 +22,10 @@ type Log struct {
 	Pages   []*Page  `json:"pages,omitempty"`   // List of all exported (tracked) pages. Leave out this field if the application does not support grouping by pages.
 	Entries []*Entry `json:"entries"`           // List of all exported (tracked) requests.
 	Comment string   `json:"comment,omitempty"` // A comment provided by the user or the application.
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
+
+	view *entryView // Cached orders and search text, see Reindex.
 }
 
 // Creator creator and browser objects share the same structure.
@@ -40,15 +49,17 @@ type Page struct {
 	Title           string       `json:"title"`             // Page title.
 	PageTimings     *PageTimings `json:"pageTimings"`       // Detailed timing info about page load.
 	Comment         string       `json:"comment,omitempty"` // A comment provided by the user or the application.
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
 }
 
 // PageTimings describes timings for various events (states) fired during the
 // page load. All times are specified in milliseconds. If a time info is not
 // available appropriate field is set to -1.
 type PageTimings struct {
-	OnContentLoad float64 `json:"onContentLoad,omitempty,omitzero"` // Content of the page loaded. Number of milliseconds since page load started (page.startedDateTime). Use -1 if the timing does not apply to the current request.
-	OnLoad        float64 `json:"onLoad,omitempty,omitzero"`        // Page is loaded (onLoad event fired). Number of milliseconds since page load started (page.startedDateTime). Use -1 if the timing does not apply to the current request.
-	Comment       string  `json:"comment,omitempty"`                // A comment provided by the user or the application.
+	OnContentLoad float64 `json:"onContentLoad"`     // Content of the page loaded. Number of milliseconds since page load started (page.startedDateTime). Use -1 if the timing does not apply to the current request.
+	OnLoad        float64 `json:"onLoad"`            // Page is loaded (onLoad event fired). Number of milliseconds since page load started (page.startedDateTime). Use -1 if the timing does not apply to the current request.
+	Comment       string  `json:"comment,omitempty"` // A comment provided by the user or the application.
 }
 
 // Entry represents an array with all exported HTTP requests. Sorting entries
@@ -66,6 +77,20 @@ type Entry struct {
 	ServerIPAddress string    `json:"serverIPAddress,omitempty"` // IP address of the server that was connected (result of DNS resolution).
 	Connection      string    `json:"connection,omitempty"`      // Unique ID of the parent TCP/IP connection, can be the client or server port number. Note that a port number doesn't have to be unique identifier in cases where the port is shared for more connections. If the port isn't available for the application, any other unique connection ID can be used instead (e.g. connection index). Leave out this field if the application doesn't support this info.
 	Comment         string    `json:"comment,omitempty"`         // A comment provided by the user or the application.
+
+	// Chrome DevTools extensions.
+	Initiator    *Initiator `json:"_initiator,omitempty"`    // What triggered the request.
+	Priority     string     `json:"_priority,omitempty"`     // Loading priority, e.g. "VeryHigh", "High", "Medium", "Low" or "VeryLow".
+	ResourceType string     `json:"_resourceType,omitempty"` // Resource type as seen by the renderer, e.g. "document", "script", "xhr" or "fetch".
+
+	WebSocketMessages   []*WebSocketMessage   `json:"_webSocketMessages,omitempty"`   // Frames exchanged after a WebSocket upgrade, in order.
+	EventStreamMessages []*EventStreamMessage `json:"_eventStreamMessages,omitempty"` // Events of a text/event-stream response, in order.
+	SecurityDetails     *SecurityDetails      `json:"_securityDetails,omitempty"`     // TLS connection details. Left out for plain HTTP.
+
+	Tags        []string          `json:"_tags,omitempty"`        // Labels left by harkit transforms, such as "redacted", and by users; see [Entry.AddTag].
+	Annotations map[string]string `json:"_annotations,omitempty"` // Values correlating the entry with other sources, such as a trace ID; see harkit.Annotate.
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
 }
 
 // Request contains detailed info about performed request.
@@ -80,6 +105,8 @@ type Request struct {
 	HeadersSize int64            `json:"headersSize"`        // Total number of bytes from the start of the HTTP request message until (and including) the double CRLF before the body. Set to -1 if the info is not available.
 	BodySize    int64            `json:"bodySize"`           // Size of the request body (POST data payload) in bytes. Set to -1 if the info is not available.
 	Comment     string           `json:"comment,omitempty"`  // A comment provided by the user or the application.
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
 }
 
 // Response contains detailed info about the response.
@@ -94,6 +121,12 @@ type Response struct {
 	HeadersSize int64            `json:"headersSize"`       // Total number of bytes from the start of the HTTP response message until (and including) the double CRLF before the body. Set to -1 if the info is not available.
 	BodySize    int64            `json:"bodySize"`          // Size of the received response body in bytes. Set to zero in case of responses coming from the cache (304). Set to -1 if the info is not available.
 	Comment     string           `json:"comment,omitempty"` // A comment provided by the user or the application.
+
+	// Chrome DevTools extensions.
+	TransferSize int64  `json:"_transferSize,omitempty"` // Bytes received over the network, headers included.
+	Error        string `json:"_error,omitempty"`        // Network error that ended the request, e.g. "net::ERR_CONNECTION_REFUSED".
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
 }
 
 // Cookie contains list of all cookies (used in [Request] and [Response]
@@ -103,7 +136,7 @@ type Cookie struct {
 	Value    string `json:"value"`             // The cookie value.
 	Path     string `json:"path,omitempty"`    // The path pertaining to the cookie.
 	Domain   string `json:"domain,omitempty"`  // The host of the cookie.
-	Expires  string `json:"expires,omitempty"` // Cookie expiration time. (ISO 8601 - YYYY-MM-DDThh:mm:ss.sTZD, e.g. 2009-07-24T19:20:30.123+02:00).
+	Expires  string `json:"expires,omitempty"` // Cookie expiration time. (ISO 8601 - YYYY-MM-DDThh:mm:ss.sTZD, e.g. 2009-07-24T19:20:30.123+02:00). See [Cookie.ExpiresTime].
 	HTTPOnly bool   `json:"httpOnly"`          // Set to true if the cookie is HTTP only, false otherwise.
 	Secure   bool   `json:"secure"`            // True if the cookie was transmitted over ssl, false otherwise.
 	Comment  string `json:"comment,omitempty"` // A comment provided by the user or the application.
@@ -122,6 +155,9 @@ type PostData struct {
 	Params   []*Param `json:"params"`            // List of posted parameters (in case of URL encoded parameters).
 	Text     string   `json:"text"`              // Plain text posted data
 	Comment  string   `json:"comment,omitempty"` // A comment provided by the user or the application.
+
+	Encoding  string `json:"_encoding,omitempty"`  // "base64" when Text holds binary data base64 encoded, as Content.Encoding does for responses.
+	Truncated bool   `json:"_truncated,omitempty"` // Text holds only a prefix of the body, see Request.BodySize for its full size.
 }
 
 // Param list of posted parameters, if any (embedded in [PostData] object).
@@ -131,6 +167,7 @@ type Param struct {
 	FileName    string `json:"fileName,omitempty"`    // Name of a posted file.
 	ContentType string `json:"contentType,omitempty"` // Content type of a posted file.
 	Comment     string `json:"comment,omitempty"`     // A comment provided by the user or the application.
+	Encoding    string `json:"_encoding,omitempty"`   // "base64" when Value holds binary data base64 encoded.
 }
 
 // Content describes details about response content (embedded in [Response]
@@ -142,6 +179,17 @@ type Content struct {
 	Text        string `json:"text,omitempty"`        // Response body sent from the server or loaded from the browser cache. This field is populated with textual content only. The text field is either HTTP decoded text or a encoded (e.g. "base64") representation of the response body. Leave out this field if the information is not available.
 	Encoding    string `json:"encoding,omitempty"`    // Encoding used for response text field e.g "base64". Leave out this field if the text field is HTTP decoded (decompressed & unchunked), than trans-coded from its original character set into UTF-8.
 	Comment     string `json:"comment,omitempty"`     // A comment provided by the user or the application.
+
+	Truncated bool `json:"_truncated,omitempty"` // Text holds only a prefix of the body, Size being the full size.
+
+	// ContentEncoding is the Content-Encoding still applied to the body
+	// held by Text, e.g. "gzip", when it was stored as received rather
+	// than decoded. [Content.DecodedBody] reverses it.
+	ContentEncoding string `json:"_contentEncoding,omitempty"`
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
+
+	stored BodyHandle // Text moved out by LoadWithBodyStore, nil when Text holds the body.
 }
 
 // Cache contains info about a request coming from browser cache.
@@ -163,12 +211,14 @@ type CacheData struct {
 // Timings describes various phases within request-response round trip. All
 // times are specified in milliseconds.
 type Timings struct {
-	Blocked float64 `json:"blocked,omitempty,omitzero"` // Time spent in a queue waiting for a network connection. Use -1 if the timing does not apply to the current request.
-	DNS     float64 `json:"dns,omitempty,omitzero"`     // DNS resolution time. The time required to resolve a host name. Use -1 if the timing does not apply to the current request.
-	Connect float64 `json:"connect,omitempty,omitzero"` // Time required to create TCP connection. Use -1 if the timing does not apply to the current request.
-	Send    float64 `json:"send"`                       // Time required to send HTTP request to the server.
-	Wait    float64 `json:"wait"`                       // Waiting for a response from the server.
-	Receive float64 `json:"receive"`                    // Time required to read entire response from the server (or cache).
-	Ssl     float64 `json:"ssl,omitempty,omitzero"`     // Time required for SSL/TLS negotiation. If this field is defined then the time is also included in the connect field (to ensure backward compatibility with HAR 1.1). Use -1 if the timing does not apply to the current request.
-	Comment string  `json:"comment,omitempty"`          // A comment provided by the user or the application.
+	Blocked float64 `json:"blocked"`           // Time spent in a queue waiting for a network connection. Use -1 if the timing does not apply to the current request.
+	DNS     float64 `json:"dns"`               // DNS resolution time. The time required to resolve a host name. Use -1 if the timing does not apply to the current request.
+	Connect float64 `json:"connect"`           // Time required to create TCP connection. Use -1 if the timing does not apply to the current request.
+	Send    float64 `json:"send"`              // Time required to send HTTP request to the server.
+	Wait    float64 `json:"wait"`              // Waiting for a response from the server.
+	Receive float64 `json:"receive"`           // Time required to read entire response from the server (or cache).
+	Ssl     float64 `json:"ssl"`               // Time required for SSL/TLS negotiation. If this field is defined then the time is also included in the connect field (to ensure backward compatibility with HAR 1.1). Use -1 if the timing does not apply to the current request.
+	Comment string  `json:"comment,omitempty"` // A comment provided by the user or the application.
+
+	Extras map[string]json.RawMessage `json:"-"` // Members not modeled by this type, such as exporter extensions, kept verbatim on round trip.
 }
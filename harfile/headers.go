@@ -0,0 +1,164 @@
+package harfile
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Keys under which fhttp-style clients (e.g. bogdanfinn/fhttp and
+// tls-client) read the order of regular and HTTP/2 pseudo headers from a
+// header map. Their values are lowercase header names.
+const (
+	HeaderOrderKey  = "Header-Order:"
+	PHeaderOrderKey = "PHeader-Order:"
+)
+
+// HeadersFromHTTP flattens h into name/value pairs, one pair per value. The
+// headers listed in order, compared case insensitively, come first and in
+// that order; the others follow sorted by name. A nil order falls back to the
+// values under [HeaderOrderKey] in h. The order keys themselves are never
+// returned.
+func HeadersFromHTTP(h http.Header, order []string) []*NameValuePair {
+	if order == nil {
+		order = h[HeaderOrderKey]
+	}
+	names := make([]string, 0, len(h))
+	for name := range h {
+		if name != HeaderOrderKey && name != PHeaderOrderKey {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	pairs := make([]*NameValuePair, 0, len(h))
+	done := make(map[string]bool, len(names))
+	emit := func(name string) {
+		if done[name] {
+			return
+		}
+		done[name] = true
+		for _, v := range h[name] {
+			pairs = append(pairs, &NameValuePair{Name: name, Value: v})
+		}
+	}
+	for _, want := range order {
+		for _, name := range names {
+			if strings.EqualFold(name, want) {
+				emit(name)
+			}
+		}
+	}
+	for _, name := range names {
+		emit(name)
+	}
+	return pairs
+}
+
+// HeaderValues returns the values of the headers of r named name, compared
+// case insensitively, in recorded order. A recorded value spanning several
+// lines, as Firefox stores repeated Set-Cookie headers, gives one value per
+// line, and obsolete line folding is unfolded. Values are not split on
+// commas, which Set-Cookie values may contain.
+func (r *Request) HeaderValues(name string) []string {
+	return headerValues(r.Headers, name)
+}
+
+// HeaderValues returns the values of the headers of r named name; see
+// [Request.HeaderValues].
+func (r *Response) HeaderValues(name string) []string {
+	return headerValues(r.Headers, name)
+}
+
+func headerValues(headers []*NameValuePair, name string) []string {
+	var values []string
+	for _, h := range headers {
+		if h != nil && strings.EqualFold(h.Name, name) {
+			values = append(values, splitHeaderValue(h.Value)...)
+		}
+	}
+	return values
+}
+
+// splitHeaderValue splits a recorded value holding several header lines
+// into values, joining folded continuation lines to the previous one.
+func splitHeaderValue(v string) []string {
+	if !strings.ContainsAny(v, "\r\n") {
+		return []string{v}
+	}
+	var values []string
+	for _, line := range strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n") {
+		folded := len(values) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"))
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		switch {
+		case line == "":
+		case folded:
+			values[len(values)-1] += " " + line
+		default:
+			values = append(values, line)
+		}
+	}
+	return values
+}
+
+// HeaderOrder returns the names of the regular headers of r in the order
+// they were recorded, each name once. HTTP/2 pseudo headers are left out, see
+// [Request.PseudoHeaders].
+func (r *Request) HeaderOrder() []string {
+	var order []string
+	seen := make(map[string]bool)
+	for _, h := range r.Headers {
+		if h == nil || strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		if key := strings.ToLower(h.Name); !seen[key] {
+			seen[key] = true
+			order = append(order, h.Name)
+		}
+	}
+	return order
+}
+
+// PseudoHeaders returns the HTTP/2 pseudo headers of r, such as ":method" and
+// ":authority", in the order they were recorded.
+func (r *Request) PseudoHeaders() []*NameValuePair {
+	var pseudo []*NameValuePair
+	for _, h := range r.Headers {
+		if h != nil && strings.HasPrefix(h.Name, ":") {
+			pseudo = append(pseudo, h)
+		}
+	}
+	return pseudo
+}
+
+// ApplyHeaderOrder stores the recorded order of the headers of r in h, under
+// [HeaderOrderKey] and, when r has pseudo headers, [PHeaderOrderKey], for
+// clients that honor them. net/http rejects these keys, so h must be sent by
+// such a client.
+func (r *Request) ApplyHeaderOrder(h http.Header) {
+	order := r.HeaderOrder()
+	for i, name := range order {
+		order[i] = strings.ToLower(name)
+	}
+	h[HeaderOrderKey] = order
+	if pseudo := r.PseudoHeaders(); len(pseudo) > 0 {
+		names := make([]string, len(pseudo))
+		for i, p := range pseudo {
+			names[i] = p.Name
+		}
+		h[PHeaderOrderKey] = names
+	}
+}
+
+// OrderedHeader returns the regular headers of r as a header map carrying
+// their recorded order, as set by [Request.ApplyHeaderOrder].
+func (r *Request) OrderedHeader() http.Header {
+	h := make(http.Header, len(r.Headers)+2)
+	for _, p := range r.Headers {
+		if p != nil && !strings.HasPrefix(p.Name, ":") {
+			h.Add(p.Name, p.Value)
+		}
+	}
+	r.ApplyHeaderOrder(h)
+	return h
+}
@@ -0,0 +1,243 @@
+package harfile
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeaderValues(t *testing.T) {
+	r := &Response{Headers: []*NameValuePair{
+		{Name: "Set-Cookie", Value: "a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT"},
+		{Name: "set-cookie", Value: "b=2"},
+		// Firefox stores repeated headers as one value, a line each.
+		{Name: "Set-Cookie", Value: "c=3\nd=4; Path=/"},
+		{Name: "Cache-Control", Value: "no-cache,\r\n\tmax-age=0"},
+		{Name: "Vary", Value: "Accept, Origin"},
+		{Name: "Vary", Value: "Cookie"},
+		nil,
+	}}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Set-Cookie", []string{"a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT", "b=2", "c=3", "d=4; Path=/"}},
+		{"cache-control", []string{"no-cache, max-age=0"}},
+		{"VARY", []string{"Accept, Origin", "Cookie"}},
+		{"Link", nil},
+	}
+	for _, tt := range tests {
+		if got := r.HeaderValues(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("HeaderValues(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	req := &Request{Headers: []*NameValuePair{{Name: "accept", Value: "text/html"}, {Name: "Accept", Value: "*/*"}}}
+	if got := req.HeaderValues("Accept"); !reflect.DeepEqual(got, []string{"text/html", "*/*"}) {
+		t.Errorf("Request.HeaderValues = %q", got)
+	}
+}
+
+func TestResponseToHTTPRepeatedHeaders(t *testing.T) {
+	r := &Response{
+		Status:      200,
+		HTTPVersion: "HTTP/1.1",
+		Headers: []*NameValuePair{
+			{Name: "Set-Cookie", Value: "a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT"},
+			{Name: "Set-Cookie", Value: "b=2\nc=3"},
+			{Name: "Cache-Control", Value: "no-cache,\n max-age=0"},
+			{Name: "x-Custom", Value: "v"},
+			{Name: "Content-Length", Value: "99"},
+		},
+		Content: &Content{Text: "ok"},
+	}
+
+	resp, err := r.ToHTTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Values("Set-Cookie"); len(got) != 3 || got[0] != "a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT" {
+		t.Errorf("Set-Cookie = %q, want 3 separate values", got)
+	}
+	if got := resp.Cookies(); len(got) != 3 || got[2].Name != "c" {
+		t.Errorf("Cookies = %v", got)
+	}
+	if got := resp.Header.Values("Cache-Control"); !reflect.DeepEqual(got, []string{"no-cache, max-age=0"}) {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if resp.Header.Get("X-Custom") != "v" || resp.Header.Get("Content-Length") != "" || resp.ContentLength != 2 {
+		t.Errorf("headers = %v, ContentLength = %d", resp.Header, resp.ContentLength)
+	}
+
+	resp, err = r.ToHTTP(PreserveHeaderCase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Header["x-Custom"]; !ok {
+		t.Errorf("recorded case lost: %v", resp.Header)
+	}
+	if len(resp.Header["Set-Cookie"]) != 3 {
+		t.Errorf("Set-Cookie = %q", resp.Header["Set-Cookie"])
+	}
+}
+
+func TestRequestToHTTPRepeatedHeaders(t *testing.T) {
+	r := &Request{
+		Method: "GET",
+		URL:    "https://example.com/",
+		Headers: []*NameValuePair{
+			{Name: ":authority", Value: "example.com"},
+			{Name: "host", Value: "example.org"},
+			{Name: "accept", Value: "text/html"},
+			{Name: "Accept", Value: "*/*"},
+			{Name: "X-Fingerprint", Value: "1"},
+		},
+		Cookies: []*Cookie{{Name: "s", Value: "1"}},
+	}
+	req, err := r.ToHTTP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Values("Accept"); !reflect.DeepEqual(got, []string{"text/html", "*/*"}) {
+		t.Errorf("Accept = %q", got)
+	}
+	if req.Host != "example.org" || req.Header.Get(":authority") != "" || req.Header.Get("Cookie") != "s=1" {
+		t.Errorf("host %q, headers %v", req.Host, req.Header)
+	}
+
+	req, err = r.ToHTTP(context.Background(), PreserveHeaderCase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(req.Header["accept"], []string{"text/html"}) || !reflect.DeepEqual(req.Header["Accept"], []string{"*/*"}) {
+		t.Errorf("recorded case lost: %v", req.Header)
+	}
+}
+
+func TestFromHTTPRepeatedHeaders(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 200,
+		Status:     "200 OK",
+		Proto:      "HTTP/1.1",
+		Header: http.Header{
+			"Set-Cookie":    {"a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT", "b=2"},
+			"Cache-Control": {"no-cache", "max-age=0"},
+		},
+		Body: io.NopCloser(strings.NewReader("")),
+	}
+	r, err := FromHTTPResponse(resp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.HeaderValues("Set-Cookie"); !reflect.DeepEqual(got, resp.Header["Set-Cookie"]) {
+		t.Errorf("Set-Cookie = %q", got)
+	}
+	if len(r.Cookies) != 2 || r.Cookies[0].Name != "a" || r.Cookies[1].Name != "b" {
+		t.Errorf("cookies = %v", r.Cookies)
+	}
+
+	// Converting back gives the same header map.
+	back, err := r.ToHTTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	back.Header.Del("Content-Type")
+	if !reflect.DeepEqual(back.Header, resp.Header) {
+		t.Errorf("round trip = %v, want %v", back.Header, resp.Header)
+	}
+}
+
+func headerNames(pairs []*NameValuePair) string {
+	var names []string
+	for _, p := range pairs {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestHeadersFromHTTP(t *testing.T) {
+	h := http.Header{
+		"Accept":          {"*/*"},
+		"User-Agent":      {"test"},
+		"X-B":             {"1", "2"},
+		"X-A":             {"3"},
+		HeaderOrderKey:    {"user-agent", "x-b", "missing"},
+		PHeaderOrderKey:   {":method", ":path"},
+		"Accept-Encoding": {"gzip"},
+	}
+	tests := []struct {
+		name  string
+		order []string
+		want  string
+	}{
+		{"order key", nil, "User-Agent X-B X-B Accept Accept-Encoding X-A"},
+		{"explicit order", []string{"X-A", "ACCEPT"}, "X-A Accept Accept-Encoding User-Agent X-B X-B"},
+		{"empty order sorts", []string{}, "Accept Accept-Encoding User-Agent X-A X-B X-B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headerNames(HeadersFromHTTP(h, tt.order)); got != tt.want {
+				t.Errorf("names = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderOrder(t *testing.T) {
+	r := &Request{Headers: []*NameValuePair{
+		{Name: ":method", Value: "GET"},
+		{Name: ":authority", Value: "example.com"},
+		{Name: "user-agent", Value: "test"},
+		nil,
+		{Name: "Accept", Value: "*/*"},
+		{Name: "cookie", Value: "a=1"},
+		{Name: "Cookie", Value: "b=2"},
+		{Name: ":path", Value: "/"},
+	}}
+	if got := r.HeaderOrder(); !reflect.DeepEqual(got, []string{"user-agent", "Accept", "cookie"}) {
+		t.Errorf("HeaderOrder = %q", got)
+	}
+	if got := headerNames(r.PseudoHeaders()); got != ":method :authority :path" {
+		t.Errorf("PseudoHeaders = %q", got)
+	}
+
+	h := r.OrderedHeader()
+	if !reflect.DeepEqual(h[HeaderOrderKey], []string{"user-agent", "accept", "cookie"}) {
+		t.Errorf("%s = %q", HeaderOrderKey, h[HeaderOrderKey])
+	}
+	if !reflect.DeepEqual(h[PHeaderOrderKey], []string{":method", ":authority", ":path"}) {
+		t.Errorf("%s = %q", PHeaderOrderKey, h[PHeaderOrderKey])
+	}
+	if h.Get(":method") != "" || !reflect.DeepEqual(h.Values("Cookie"), []string{"a=1", "b=2"}) {
+		t.Errorf("header = %v", h)
+	}
+
+	plain := (&Request{Headers: []*NameValuePair{{Name: "Accept", Value: "*/*"}}}).OrderedHeader()
+	if _, ok := plain[PHeaderOrderKey]; ok {
+		t.Error("pseudo header order set without pseudo headers")
+	}
+}
+
+func TestHeaderOrderRoundTrip(t *testing.T) {
+	// A recorded request keeps its header order through OrderedHeader and
+	// FromHTTPRequest.
+	recorded := &Request{Headers: []*NameValuePair{
+		{Name: "User-Agent", Value: "test"},
+		{Name: "Sec-Ch-Ua", Value: `"Chromium"`},
+		{Name: "Accept", Value: "*/*"},
+		{Name: "Accept-Language", Value: "en"},
+		{Name: "Accept", Value: "text/html"},
+	}}
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	req.Header = recorded.OrderedHeader()
+	r, err := FromHTTPRequest(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Host User-Agent Sec-Ch-Ua Accept Accept Accept-Language"
+	if got := headerNames(r.Headers); got != want {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,331 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrNotJSON is returned, possibly wrapped, by the JSON helpers of
+// [Content] and [PostData] for a body whose MIME type is not JSON or whose
+// text does not parse as JSON.
+var ErrNotJSON = errors.New("harfile: not JSON")
+
+// ErrPathNotFound is returned, wrapped, by JSONPath when the document has
+// no value at the path.
+var ErrPathNotFound = errors.New("harfile: JSON path not found")
+
+// JSON decodes the response body, as by [Content.TextUTF8], and unmarshals
+// it into v.
+func (c *Content) JSON(v any) error {
+	data, err := c.jsonBody()
+	if err != nil {
+		return err
+	}
+	return unmarshalJSONBody(data, v)
+}
+
+// PrettyJSON returns the response body indented with two spaces.
+func (c *Content) PrettyJSON() (string, error) {
+	data, err := c.jsonBody()
+	if err != nil {
+		return "", err
+	}
+	return prettyJSON(data)
+}
+
+// JSONPath returns the value at path in the response body, see
+// [PostData.JSONPath] for the syntax.
+func (c *Content) JSONPath(path string) (json.RawMessage, error) {
+	data, err := c.jsonBody()
+	if err != nil {
+		return nil, err
+	}
+	return lookupJSONPath(data, path)
+}
+
+func (c *Content) jsonBody() ([]byte, error) {
+	if !isJSONMimeType(c.MimeType) {
+		return nil, fmt.Errorf("%w: MIME type %q", ErrNotJSON, c.MimeType)
+	}
+	text, err := c.TextUTF8()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(text), nil
+}
+
+// JSON unmarshals the request body into v. A base64 encoded Text is
+// decoded first.
+func (pd *PostData) JSON(v any) error {
+	data, err := pd.jsonBody()
+	if err != nil {
+		return err
+	}
+	return unmarshalJSONBody(data, v)
+}
+
+// PrettyJSON returns the request body indented with two spaces.
+func (pd *PostData) PrettyJSON() (string, error) {
+	data, err := pd.jsonBody()
+	if err != nil {
+		return "", err
+	}
+	return prettyJSON(data)
+}
+
+// JSONPath returns the value at path in the request body. A path is a
+// sequence of member names separated by dots and of bracketed array
+// indexes, e.g. "error.code" or "items[0].id", optionally starting with
+// "$" or "."; names containing dots or brackets are written as quoted
+// brackets, as in `headers["x.y"]`. A missing value gives
+// [ErrPathNotFound].
+func (pd *PostData) JSONPath(path string) (json.RawMessage, error) {
+	data, err := pd.jsonBody()
+	if err != nil {
+		return nil, err
+	}
+	return lookupJSONPath(data, path)
+}
+
+func (pd *PostData) jsonBody() ([]byte, error) {
+	if !isJSONMimeType(pd.MimeType) {
+		return nil, fmt.Errorf("%w: MIME type %q", ErrNotJSON, pd.MimeType)
+	}
+	r, _, err := pd.BodyReader()
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// isJSONMimeType reports whether mimeType is application/json, text/json
+// or a +json structured syntax such as application/problem+json.
+func isJSONMimeType(mimeType string) bool {
+	mt, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || mt == "text/json" || strings.HasSuffix(mt, "+json")
+}
+
+func unmarshalJSONBody(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return fmt.Errorf("%w: %v", ErrNotJSON, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func prettyJSON(data []byte) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "  "); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNotJSON, err)
+	}
+	return buf.String(), nil
+}
+
+// jsonPathStep is a member name or, when index >= 0, an array index, or
+// every element when index is anyIndex.
+type jsonPathStep struct {
+	name  string
+	index int
+}
+
+// anyIndex is the index of the "[*]" step, matching every array element.
+const anyIndex = -2
+
+// lookupJSONPath returns the value at path in the JSON document data.
+func lookupJSONPath(data []byte, path string) (json.RawMessage, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(steps, func(s jsonPathStep) bool { return s.index == anyIndex }) {
+		return nil, fmt.Errorf("harfile: JSON path %q: [*] only applies to CanonicalJSON", path)
+	}
+	value := json.RawMessage(bytes.TrimSpace(data))
+	if !json.Valid(value) {
+		return nil, ErrNotJSON
+	}
+	for i, step := range steps {
+		var ok bool
+		if step.index >= 0 {
+			var arr []json.RawMessage
+			if json.Unmarshal(value, &arr) == nil && step.index < len(arr) {
+				value, ok = arr[step.index], true
+			}
+		} else {
+			var obj map[string]json.RawMessage
+			if json.Unmarshal(value, &obj) == nil {
+				value, ok = obj[step.name]
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, formatJSONPath(steps[:i+1]))
+		}
+	}
+	return value, nil
+}
+
+// parseJSONPath splits path into its steps.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	invalid := func() ([]jsonPathStep, error) {
+		return nil, fmt.Errorf("harfile: invalid JSON path %q", path)
+	}
+	s := strings.TrimPrefix(path, "$")
+	var steps []jsonPathStep
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			if s == "" || s[0] == '.' || s[0] == '[' {
+				return invalid()
+			}
+		case '[':
+			if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+				// A quoted name may itself contain dots and brackets.
+				closing := strings.Index(s[2:], string(s[1])+"]")
+				if closing < 0 {
+					return invalid()
+				}
+				steps = append(steps, jsonPathStep{name: s[2 : 2+closing], index: -1})
+				s = s[2+closing+2:]
+				continue
+			}
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return invalid()
+			}
+			if s[1:end] == "*" {
+				steps = append(steps, jsonPathStep{index: anyIndex})
+				s = s[end+1:]
+				continue
+			}
+			n, err := strconv.Atoi(s[1:end])
+			if err != nil || n < 0 {
+				return invalid()
+			}
+			steps = append(steps, jsonPathStep{index: n})
+			s = s[end+1:]
+			continue
+		}
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			return invalid()
+		}
+		steps = append(steps, jsonPathStep{name: s[:end], index: -1})
+		s = s[end:]
+	}
+	return steps, nil
+}
+
+// formatJSONPath writes steps back as a path, for error messages.
+func formatJSONPath(steps []jsonPathStep) string {
+	var b strings.Builder
+	for _, step := range steps {
+		switch {
+		case step.index == anyIndex:
+			b.WriteString("[*]")
+		case step.index >= 0:
+			fmt.Fprintf(&b, "[%d]", step.index)
+		case strings.ContainsAny(step.name, ".[]"):
+			fmt.Fprintf(&b, "[%q]", step.name)
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(step.name)
+		}
+	}
+	return b.String()
+}
+
+// CanonicalJSON returns the JSON document data re-encoded with object
+// members sorted by name and without insignificant whitespace, so that
+// equivalent documents compare equal, leaving out the values at the ignore
+// paths, see [PostData.JSONPath] for their syntax. Paths without a value
+// are skipped; removing an array element shifts the following ones. Paths
+// may also use "[*]" for every element of an array, as in
+// "items[*].secret".
+func CanonicalJSON(data []byte, ignore ...string) ([]byte, error) {
+	paths := make([][]jsonPathStep, 0, len(ignore))
+	for _, p := range ignore {
+		steps, err := parseJSONPath(p)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, steps)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotJSON, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w: data after the document", ErrNotJSON)
+	}
+	for _, steps := range paths {
+		v = removeJSONPath(v, steps)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// removeJSONPath returns v without the value at steps. An empty path
+// removes nothing.
+func removeJSONPath(v any, steps []jsonPathStep) any {
+	if len(steps) == 0 {
+		return v
+	}
+	step, last := steps[0], len(steps) == 1
+	switch v := v.(type) {
+	case map[string]any:
+		if step.index != -1 {
+			return v
+		}
+		if child, ok := v[step.name]; ok {
+			if last {
+				delete(v, step.name)
+			} else {
+				v[step.name] = removeJSONPath(child, steps[1:])
+			}
+		}
+	case []any:
+		if step.index == anyIndex {
+			if last {
+				return v[:0]
+			}
+			for i := range v {
+				v[i] = removeJSONPath(v[i], steps[1:])
+			}
+			return v
+		}
+		if step.index < 0 || step.index >= len(v) {
+			return v
+		}
+		if last {
+			return slices.Delete(v, step.index, step.index+1)
+		}
+		v[step.index] = removeJSONPath(v[step.index], steps[1:])
+	}
+	return v
+}
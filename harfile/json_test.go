@@ -0,0 +1,167 @@
+package harfile
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const jsonDoc = `{"error": {"code": 42, "message": "nope"}, "items": [{"id": 1}, {"a.b": "dotted", "x[0]": true}], "": "empty"}`
+
+func TestJSONPath(t *testing.T) {
+	c := &Content{MimeType: "application/json; charset=utf-8", Text: jsonDoc}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"error.code", `42`},
+		{"$.error.message", `"nope"`},
+		{".error", `{"code": 42, "message": "nope"}`},
+		{"items[0].id", `1`},
+		{`items[1]["a.b"]`, `"dotted"`},
+		{`items[1]['x[0]']`, `true`},
+		{`[""]`, `"empty"`},
+		{"", jsonDoc},
+		{"$", jsonDoc},
+	}
+	for _, tt := range tests {
+		got, err := c.JSONPath(tt.path)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("JSONPath(%q) = %s, %v, want %s", tt.path, got, err, tt.want)
+		}
+	}
+
+	for path, msg := range map[string]string{
+		"error.status":  "error.status",
+		"items[2]":      "items[2]",
+		"items[1].a.b":  "items[1].a",
+		"error[0]":      "error[0]",
+		"items.id":      "items.id",
+		`items[1]["x"]`: `items[1].x`,
+		`x["a.b"]`:      `x`,
+	} {
+		_, err := c.JSONPath(path)
+		if !errors.Is(err, ErrPathNotFound) || !strings.HasSuffix(err.Error(), ": "+msg) {
+			t.Errorf("JSONPath(%q) error = %v, want not found at %s", path, err, msg)
+		}
+	}
+
+	for _, path := range []string{"a..b", "a.", ".[0]", "[x]", "[-1]", `["a`, "items[0", "a[]"} {
+		if _, err := c.JSONPath(path); err == nil || errors.Is(err, ErrPathNotFound) {
+			t.Errorf("JSONPath(%q) error = %v, want an invalid path", path, err)
+		}
+	}
+}
+
+func TestJSONNotJSON(t *testing.T) {
+	tests := map[string]*Content{
+		"html":       {MimeType: "text/html", Text: `{"a":1}`},
+		"jsonp":      {MimeType: "application/jsonp", Text: `{"a":1}`},
+		"no type":    {Text: `{"a":1}`},
+		"bad syntax": {MimeType: "application/json", Text: `{"a":`},
+		"not json":   {MimeType: "application/json", Text: `<html>`},
+	}
+	for name, c := range tests {
+		var v any
+		if err := c.JSON(&v); !errors.Is(err, ErrNotJSON) {
+			t.Errorf("%s: JSON error = %v", name, err)
+		}
+		if _, err := c.PrettyJSON(); !errors.Is(err, ErrNotJSON) {
+			t.Errorf("%s: PrettyJSON error = %v", name, err)
+		}
+		if _, err := c.JSONPath("a"); !errors.Is(err, ErrNotJSON) {
+			t.Errorf("%s: JSONPath error = %v", name, err)
+		}
+	}
+
+	// A type mismatch is not a body problem.
+	var n int
+	if err := (&Content{MimeType: "application/json", Text: `"text"`}).JSON(&n); err == nil || errors.Is(err, ErrNotJSON) {
+		t.Errorf("type mismatch error = %v", err)
+	}
+}
+
+func TestContentJSON(t *testing.T) {
+	for _, mimeType := range []string{"application/json", "text/json", "application/problem+json", "Application/JSON; charset=UTF-8"} {
+		var v struct{ A int }
+		if err := (&Content{MimeType: mimeType, Text: `{"a": 1}`}).JSON(&v); err != nil || v.A != 1 {
+			t.Errorf("%s: JSON = %+v, %v", mimeType, v, err)
+		}
+	}
+
+	b64 := &Content{MimeType: "application/json", Text: base64.StdEncoding.EncodeToString([]byte(`{"a":[1,2]}`)), Encoding: "base64"}
+	pretty, err := b64.PrettyJSON()
+	if want := "{\n  \"a\": [\n    1,\n    2\n  ]\n}"; err != nil || pretty != want {
+		t.Errorf("PrettyJSON = %q, %v, want %q", pretty, err, want)
+	}
+
+	latin1 := &Content{MimeType: "application/json; charset=iso-8859-1", Text: base64.StdEncoding.EncodeToString([]byte("{\"name\":\"caf\xe9\"}")), Encoding: "base64"}
+	if got, err := latin1.JSONPath("name"); err != nil || string(got) != `"café"` {
+		t.Errorf("latin-1 body: %s, %v", got, err)
+	}
+}
+
+func TestPostDataJSON(t *testing.T) {
+	pd := &PostData{MimeType: "application/json", Text: ` {"user": {"name": "ada"}} `}
+	var v struct{ User struct{ Name string } }
+	if err := pd.JSON(&v); err != nil || v.User.Name != "ada" {
+		t.Errorf("JSON = %+v, %v", v, err)
+	}
+	if got, err := pd.JSONPath("user.name"); err != nil || string(got) != `"ada"` {
+		t.Errorf("JSONPath = %s, %v", got, err)
+	}
+	if pretty, err := pd.PrettyJSON(); err != nil || pretty != "{\n  \"user\": {\n    \"name\": \"ada\"\n  }\n}" {
+		t.Errorf("PrettyJSON = %q, %v", pretty, err)
+	}
+
+	b64 := &PostData{MimeType: "application/json", Text: base64.StdEncoding.EncodeToString([]byte(`[true]`)), Encoding: "base64"}
+	if got, err := b64.JSONPath("[0]"); err != nil || string(got) != "true" {
+		t.Errorf("base64 JSONPath = %s, %v", got, err)
+	}
+	form := &PostData{MimeType: "application/x-www-form-urlencoded", Text: "a=1"}
+	if _, err := form.JSONPath("a"); !errors.Is(err, ErrNotJSON) {
+		t.Errorf("form error = %v", err)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		ignore []string
+		want   string
+	}{
+		{"sorted members", `{"b": 1, "a": {"d": [3, 1], "c": null}}`, nil, `{"a":{"c":null,"d":[3,1]},"b":1}`},
+		{"numbers kept", `[1.50, 1e3, 12345678901234567890]`, nil, `[1.50,1e3,12345678901234567890]`},
+		{"html not escaped", `{"html": "<b>&</b>"}`, nil, `{"html":"<b>&</b>"}`},
+		{"member ignored", jsonDoc, []string{"$.error.code", "items[1]"}, `{"":"empty","error":{"message":"nope"},"items":[{"id":1}]}`},
+		{"missing path skipped", `{"a":1}`, []string{"b.c", "a[0]", "$[2]"}, `{"a":1}`},
+		{"array element ignored", `[1, 2, 3]`, []string{"$[0]", "$[0]"}, `[3]`},
+		{"whole document", `{"a":1}`, []string{"$"}, `{"a":1}`},
+		{"every element", `{"items":[{"id":1,"secret":"a"},{"secret":"b"},3]}`, []string{"items[*].secret"}, `{"items":[{"id":1},{},3]}`},
+		{"every element removed", `{"items":[1,2],"n":[]}`, []string{"items[*]", "n[*]"}, `{"items":[],"n":[]}`},
+		{"every element of an object", `{"a":{"b":1}}`, []string{"a[*]"}, `{"a":{"b":1}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalJSON([]byte(tt.data), tt.ignore...)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("CanonicalJSON = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+
+	for _, data := range []string{`{"a":`, `{} {}`, `text`} {
+		if _, err := CanonicalJSON([]byte(data)); !errors.Is(err, ErrNotJSON) {
+			t.Errorf("CanonicalJSON(%q) error = %v, want ErrNotJSON", data, err)
+		}
+	}
+	if _, err := CanonicalJSON([]byte(`{}`), "a["); err == nil || errors.Is(err, ErrNotJSON) {
+		t.Errorf("invalid path error = %v", err)
+	}
+	// "[*]" selects several values, so it only applies to removal.
+	if _, err := (&Content{MimeType: "application/json", Text: jsonDoc}).JSONPath("items[*].id"); err == nil {
+		t.Error("JSONPath accepted [*]")
+	}
+}
@@ -0,0 +1,282 @@
+package harfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Warning describes a value coerced by [LoadLenient].
+type Warning struct {
+	Path    string // Location of the coerced value, e.g. "log.entries[2].time".
+	Message string // What was wrong and how it was fixed.
+}
+
+func (w Warning) String() string {
+	return w.Path + ": " + w.Message
+}
+
+// LoadLenient decodes a HAR document like [Load] but coerces the mistakes
+// commonly found in real-world exports, reporting each one as a Warning:
+//
+//   - numbers written as strings, e.g. "status": "200";
+//   - fractional values for integer fields, which are rounded;
+//   - numbers where strings are expected;
+//   - timestamps in other layouts, such as a space instead of 'T';
+//   - null instead of an array;
+//   - members left out by some exporters, filled by [NormalizeVendor];
+//   - pages sharing an ID and pagerefs naming no page, repaired by
+//     [Log.RepairRefs] so that every pageref names exactly one page,
+//     unless [LoadOptions].EntriesOnly left the pages out.
+//
+// Values that cannot be coerced are dropped with a warning, leaving the
+// field at its default. Only malformed JSON, or values of an entirely
+// different shape, such as an object in place of an array, are errors.
+//
+// The document is held in memory twice while being normalized; prefer
+// [Load] for well-formed input, which also surfaces data bugs instead of
+// hiding them.
+func LoadLenient(r io.Reader, opts ...FileOption) (*HAR, []Warning, error) {
+	o := newFileOptions(opts)
+	br := bufio.NewReader(o.reader(r))
+	if prefix, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("harfile: decode: %w", err)
+	}
+	o.finish()
+
+	l := &lenient{}
+	doc, _ = l.value("", doc, reflect.TypeFor[HAR]())
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, l.warnings, fmt.Errorf("harfile: decode: %w", err)
+	}
+	var h HAR
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, l.warnings, fmt.Errorf("harfile: decode: %w", err)
+	}
+	if h.Log == nil {
+		return nil, l.warnings, ErrNoLog
+	}
+	l.warnings = append(l.warnings, NormalizeVendor(&h)...)
+	if !o.load.EntriesOnly {
+		// Pages left out on purpose would all be stubbed back otherwise.
+		for _, issue := range h.Log.RepairRefs(RepairOptions{Dangling: StubDangling, RenameDuplicates: true}) {
+			l.warnings = append(l.warnings, refWarning(issue))
+		}
+	}
+	if o.normalizeVersions {
+		h.NormalizeHTTPVersions()
+	}
+	if o.sniffMimeTypes {
+		h.Log.CorrectMimeTypes()
+	}
+	if o.load.MaxBodyBytes > 0 {
+		for _, e := range h.Log.Entries {
+			if e != nil {
+				fixCutBody(e)
+			}
+		}
+	}
+	return &h, l.warnings, nil
+}
+
+// refWarning reports an issue fixed by LoadLenient at its first
+// occurrence.
+func refWarning(issue RefIssue) Warning {
+	if issue.Kind == RefDangling {
+		return Warning{
+			Path:    fmt.Sprintf("log.entries[%d].pageref", issue.Entries[0]),
+			Message: issue.String() + ", stub page added",
+		}
+	}
+	return Warning{
+		Path:    fmt.Sprintf("log.pages[%d].id", issue.Pages[1]),
+		Message: issue.String() + ", later pages renamed",
+	}
+}
+
+var (
+	timeType    = reflect.TypeFor[time.Time]()
+	rawJSONType = reflect.TypeFor[json.RawMessage]()
+)
+
+type lenient struct {
+	warnings []Warning
+}
+
+func (l *lenient) warn(path, format string, args ...any) {
+	l.warnings = append(l.warnings, Warning{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// value coerces v, found at path, to the JSON shape of t. It returns false
+// when v should be dropped.
+func (l *lenient) value(path string, v any, t reflect.Type) (any, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if v == nil || t == rawJSONType || t == reflect.TypeFor[Initiator]() {
+		if v == nil && t.Kind() == reflect.Slice {
+			l.warn(path, "null array replaced by an empty one")
+			return []any{}, true
+		}
+		return v, true
+	}
+	if t == timeType {
+		return l.timestamp(path, v)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return v, true
+		}
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			member := obj[key]
+			f, ok := jsonField(t, key)
+			if !ok {
+				continue
+			}
+			if coerced, keep := l.value(joinPath(path, key), member, f.Type); keep {
+				obj[key] = coerced
+			} else {
+				delete(obj, key)
+			}
+		}
+		return obj, true
+	case reflect.Slice:
+		arr, ok := v.([]any)
+		if !ok {
+			return v, true
+		}
+		out := arr[:0]
+		for i, elem := range arr {
+			if coerced, keep := l.value(fmt.Sprintf("%s[%d]", path, i), elem, t.Elem()); keep {
+				out = append(out, coerced)
+			}
+		}
+		return out, true
+	case reflect.String:
+		switch x := v.(type) {
+		case json.Number:
+			l.warn(path, "number %s converted to a string", x)
+			return x.String(), true
+		case bool:
+			l.warn(path, "boolean %t converted to a string", x)
+			return strconv.FormatBool(x), true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !isScalar(v) {
+			return v, true
+		}
+		f, ok := l.number(path, v)
+		if !ok {
+			return nil, false
+		}
+		if f != math.Trunc(f) {
+			l.warn(path, "fractional value %v rounded", f)
+			f = math.Round(f)
+		}
+		return json.Number(strconv.FormatInt(int64(f), 10)), true
+	case reflect.Float32, reflect.Float64:
+		if !isScalar(v) {
+			return v, true
+		}
+		if f, ok := l.number(path, v); ok {
+			return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), true
+		}
+		return nil, false
+	}
+	return v, true
+}
+
+// isScalar reports whether v is a number or a string, the values number
+// can read.
+func isScalar(v any) bool {
+	switch v.(type) {
+	case json.Number, string:
+		return true
+	}
+	return false
+}
+
+// number reads v, a number or a numeric string, as a float.
+func (l *lenient) number(path string, v any) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	s := v.(string)
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		l.warn(path, "non-numeric value %q dropped", s)
+		return 0, false
+	}
+	l.warn(path, "numeric string %q converted to a number", s)
+	return f, true
+}
+
+// timestamp normalizes a date to RFC 3339, the layout expected by
+// [time.Time].
+func (l *lenient) timestamp(path string, v any) (any, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return v, true
+	}
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return s, true
+	}
+	t, ok := ParseCookieTime(s)
+	if !ok {
+		l.warn(path, "unreadable date %q dropped", s)
+		return nil, false
+	}
+	l.warn(path, "date %q converted to ISO 8601", s)
+	return t.Format(time.RFC3339Nano), true
+}
+
+// jsonField returns the field of t that encoding/json fills from the member
+// key.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var fold reflect.StructField
+	var folded bool
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f, true
+		}
+		if !folded && strings.EqualFold(name, key) {
+			fold, folded = f, true
+		}
+	}
+	return fold, folded
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
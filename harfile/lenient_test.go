@@ -0,0 +1,170 @@
+package harfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lenientDoc wraps entry, a JSON object, into a document.
+func lenientDoc(entry string) string {
+	return `{"log": {"version": "1.2", "creator": {"name": "test", "version": "1"}, "entries": [` + entry + `]}}`
+}
+
+func TestLoadLenient(t *testing.T) {
+	doc := lenientDoc(`{
+		"startedDateTime": "2024-03-01 10:00:00",
+		"time": "12.5",
+		"request": {"method": "GET", "url": "https://example.com/", "httpVersion": 2, "cookies": null, "headers": [], "queryString": [], "headersSize": 38.6, "bodySize": "0"},
+		"response": {"status": "200", "statusText": true, "httpVersion": "HTTP/2", "cookies": [], "headers": [], "content": {"size": "n/a", "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": 0},
+		"cache": {}, "timings": {"send": "1", "wait": 10, "receive": 1.5}
+	}`)
+	h, warnings, err := LoadLenient(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := h.Log.Entries[0]
+	if !e.StartedDateTime.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) || e.Time != 12.5 {
+		t.Errorf("started %v, time %v", e.StartedDateTime, e.Time)
+	}
+	if e.Request.HTTPVersion != "2" || e.Request.Cookies == nil || e.Request.HeadersSize != 39 || e.Request.BodySize != 0 {
+		t.Errorf("request = %+v", e.Request)
+	}
+	if e.Response.Status != 200 || e.Response.StatusText != "true" || e.Response.Content.Size != 0 {
+		t.Errorf("response = %+v, content %+v", e.Response, e.Response.Content)
+	}
+	if e.Timings.Send != 1 || e.Timings.Receive != 1.5 {
+		t.Errorf("timings = %+v", e.Timings)
+	}
+
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.String())
+	}
+	want := []string{
+		`log.entries[0].request.bodySize: numeric string "0" converted to a number`,
+		`log.entries[0].request.cookies: null array replaced by an empty one`,
+		`log.entries[0].request.headersSize: fractional value 38.6 rounded`,
+		`log.entries[0].request.httpVersion: number 2 converted to a string`,
+		`log.entries[0].response.content.size: non-numeric value "n/a" dropped`,
+		`log.entries[0].response.status: numeric string "200" converted to a number`,
+		`log.entries[0].response.statusText: boolean true converted to a string`,
+		`log.entries[0].startedDateTime: date "2024-03-01 10:00:00" converted to ISO 8601`,
+		`log.entries[0].time: numeric string "12.5" converted to a number`,
+		`log.entries[0].timings.send: numeric string "1" converted to a number`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoadLenientWellFormed(t *testing.T) {
+	doc := lenientDoc(`{"startedDateTime": "2024-03-01T10:00:00.000Z", "time": 1,
+		"_initiator": "parser", "_custom": {"n": "1"},
+		"request": {"method": "GET", "url": "https://example.com/", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+		"response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 0, "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": 0},
+		"cache": {}, "timings": {"send": 0, "wait": 1, "receive": 0}}`)
+	h, warnings, err := LoadLenient(strings.NewReader("\ufeff" + doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings for a valid document: %v", warnings)
+	}
+	if e := h.Log.Entries[0]; e.Initiator.Type != "parser" || string(e.Extras["_custom"]) != `{"n":"1"}` {
+		t.Errorf("initiator %+v, extras %s", e.Initiator, e.Extras["_custom"])
+	}
+}
+
+func TestLoadLenientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"malformed json", `{"log": {`},
+		{"object for array", lenientDoc(`{"request": {"headers": {"a": "b"}}}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := LoadLenient(strings.NewReader(tt.doc)); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+	if _, _, err := LoadLenient(strings.NewReader(`{"other": 1}`)); !errors.Is(err, ErrNoLog) {
+		t.Errorf("err = %v, want ErrNoLog", err)
+	}
+	h, warnings, err := LoadLenient(strings.NewReader(lenientDoc(`{"startedDateTime": "yesterday", "cache": {}, "timings": {"send": 0, "wait": 0, "receive": 0}}`)))
+	if err != nil || !h.Log.Entries[0].StartedDateTime.IsZero() || len(warnings) != 1 || warnings[0].Message != `unreadable date "yesterday" dropped` {
+		t.Errorf("unreadable date: %v, %v", warnings, err)
+	}
+}
+
+// brokenPages has two pages sharing an ID, after a careless merge, and an
+// entry referring to a page that was never exported.
+const brokenPages = `{"log": {
+  "version": "1.2",
+  "creator": {"name": "test", "version": "1"},
+  "pages": [
+    {"startedDateTime": "2024-01-01T12:00:00Z", "id": "page_1", "title": "a", "pageTimings": {}},
+    {"startedDateTime": "2024-01-01T12:01:00Z", "id": "page_1", "title": "b", "pageTimings": {}}
+  ],
+  "entries": [
+    {"pageref": "page_1", "startedDateTime": "2024-01-01T12:00:01Z", "time": 1,
+     "request": {"method": "GET", "url": "https://example.com/a", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+     "response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 0, "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": 0},
+     "cache": {}, "timings": {"send": 0, "wait": 1, "receive": 0}},
+    {"pageref": "page_1", "startedDateTime": "2024-01-01T12:01:01Z", "time": 1,
+     "request": {"method": "GET", "url": "https://example.com/b", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+     "response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 0, "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": 0},
+     "cache": {}, "timings": {"send": 0, "wait": 1, "receive": 0}},
+    {"pageref": "page_9", "startedDateTime": "2024-01-01T12:02:00Z", "time": 1,
+     "request": {"method": "GET", "url": "https://example.com/c", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "queryString": [], "headersSize": -1, "bodySize": 0},
+     "response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [], "headers": [], "content": {"size": 0, "mimeType": "text/html"}, "redirectURL": "", "headersSize": -1, "bodySize": 0},
+     "cache": {}, "timings": {"send": 0, "wait": 1, "receive": 0}}
+  ]
+}}`
+
+func TestLoadLenientRepairsPages(t *testing.T) {
+	h, warnings, err := LoadLenient(strings.NewReader(brokenPages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := h.Log.CheckRefs(); len(issues) != 0 {
+		t.Errorf("issues left after LoadLenient: %v", issues)
+	}
+	var ids, refs []string
+	for _, p := range h.Log.Pages {
+		ids = append(ids, p.ID)
+	}
+	for _, e := range h.Log.Entries {
+		refs = append(refs, e.Pageref)
+	}
+	if strings.Join(ids, " ") != "page_1 page_1-2 page_9" || strings.Join(refs, " ") != "page_1 page_1-2 page_9" {
+		t.Errorf("pages = %q, pagerefs = %q", ids, refs)
+	}
+
+	var got []string
+	for _, w := range warnings {
+		if strings.Contains(w.Message, "page") {
+			got = append(got, w.String())
+		}
+	}
+	want := []string{
+		`log.pages[1].id: duplicate page id "page_1" on 2 pages, later pages renamed`,
+		`log.entries[2].pageref: dangling pageref "page_9" in 1 entry, stub page added`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Load leaves the graph as written.
+	h, err = Load(strings.NewReader(brokenPages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Log.CheckRefs()) == 0 {
+		t.Error("Load repaired the pages")
+	}
+}
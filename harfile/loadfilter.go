@@ -0,0 +1,392 @@
+package harfile
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// LoadOptions selects the parts of a HAR document the loaders leave out,
+// for analyses that only need URLs, statuses and timings; see
+// [WithLoadOptions]. Skipped values are dropped from the input before it
+// is decoded, so they are never allocated.
+type LoadOptions struct {
+	SkipResponseBodies bool  // Response content.text is left out; size and mimeType are kept.
+	SkipRequestBodies  bool  // Request postData.text and params are left out; mimeType is kept.
+	SkipCache          bool  // Entry cache objects are read empty.
+	EntriesOnly        bool  // Log pages and browser are left out.
+	MaxBodyBytes       int64 // Bodies kept are cut to about this many bytes of JSON text and marked _truncated. Zero keeps whole bodies.
+}
+
+func (o LoadOptions) active() bool {
+	return o.SkipResponseBodies || o.SkipRequestBodies || o.SkipCache || o.EntriesOnly || o.MaxBodyBytes > 0
+}
+
+// WithLoadOptions makes [Load], [LoadLenient], [LoadWithBodyStore] and
+// [EntryReader] leave out the parts of the document selected by lo.
+func WithLoadOptions(lo LoadOptions) FileOption {
+	return func(o *fileOptions) {
+		o.load = lo
+	}
+}
+
+// fixCutBody trims the base64 bodies of e cut by MaxBodyBytes to whole
+// 4-character groups, so that they still decode.
+func fixCutBody(e *Entry) {
+	if e.Request != nil && e.Request.PostData != nil {
+		if pd := e.Request.PostData; pd.Truncated && pd.Encoding == "base64" {
+			pd.Text = pd.Text[:len(pd.Text)/4*4]
+		}
+	}
+	if e.Response != nil && e.Response.Content != nil {
+		if c := e.Response.Content; c.Truncated && c.Encoding == "base64" {
+			c.Text = c.Text[:len(c.Text)/4*4]
+		}
+	}
+}
+
+// filterAction is what a [loadFilter] does with a value.
+type filterAction int
+
+const (
+	actionKeep   filterAction = iota
+	actionNull                // Replaced by null.
+	actionObject              // Replaced by an empty object.
+	actionArray               // Replaced by an empty array.
+	actionCut                 // A string cut to MaxBodyBytes.
+)
+
+type filterState int
+
+const (
+	stateValue      filterState = iota // Expecting a value.
+	stateKey                           // Expecting a member name or the end of an object.
+	stateKeyString                     // Inside a member name.
+	stateColon                         // Expecting the colon after a member name.
+	stateAfter                         // After a value.
+	stateString                        // Inside a string value.
+	stateScalar                        // Inside a number, true, false or null.
+	stateSkip                          // Inside a value being dropped.
+	stateSkipScalar                    // Inside a scalar being dropped.
+	stateCut                           // Inside a string being cut.
+)
+
+// filterFrame is an object or array the filter is in.
+type filterFrame struct {
+	object bool
+	key    string // Lowercased name of the current member, "[]" in arrays.
+}
+
+// loadFilter rewrites a HAR document on the fly, replacing the values
+// selected by its options. It follows the JSON structure byte by byte,
+// without decoding, and tolerates malformed input, which is left for the
+// decoder to report.
+type loadFilter struct {
+	src  io.Reader
+	opts LoadOptions
+	in   []byte
+	out  []byte
+	pos  int // Bytes of out already read.
+	err  error
+
+	state filterState
+	stack []filterFrame
+	key   []byte
+	esc   int   // In a string: 1 after a backslash, the hex digits left in a \u escape, or 0.
+	depth int   // Nesting of the container being dropped.
+	str   bool  // Inside a string of the container being dropped.
+	kept  int64 // Bytes of the string being cut kept so far.
+}
+
+func newLoadFilter(r io.Reader, opts LoadOptions) *loadFilter {
+	return &loadFilter{src: r, opts: opts, in: make([]byte, 32<<10)}
+}
+
+func (f *loadFilter) Read(p []byte) (int, error) {
+	for f.pos == len(f.out) {
+		if f.err != nil {
+			return 0, f.err
+		}
+		f.out, f.pos = f.out[:0], 0
+		n, err := f.src.Read(f.in)
+		f.process(f.in[:n])
+		f.err = err
+	}
+	n := copy(p, f.out[f.pos:])
+	f.pos += n
+	return n, nil
+}
+
+// at reports whether the value about to be read is at path, a list of
+// member names and "[]" for array elements.
+func (f *loadFilter) at(path ...string) bool {
+	if len(f.stack) != len(path) {
+		return false
+	}
+	for i, frame := range f.stack {
+		if frame.key != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// action returns what to do with the value about to be read.
+func (f *loadFilter) action() filterAction {
+	o := f.opts
+	switch len(f.stack) {
+	case 2:
+		if o.EntriesOnly && (f.at("log", "pages") || f.at("log", "browser")) {
+			return actionNull
+		}
+	case 4:
+		if o.SkipCache && f.at("log", "entries", "[]", "cache") {
+			return actionObject
+		}
+	case 6:
+		var skip bool
+		switch {
+		case f.at("log", "entries", "[]", "response", "content", "text"):
+			skip = o.SkipResponseBodies
+		case f.at("log", "entries", "[]", "request", "postdata", "text"):
+			skip = o.SkipRequestBodies
+		case f.at("log", "entries", "[]", "request", "postdata", "params"):
+			if o.SkipRequestBodies {
+				return actionArray
+			}
+			return actionKeep
+		default:
+			return actionKeep
+		}
+		if skip {
+			return actionNull
+		}
+		if o.MaxBodyBytes > 0 {
+			return actionCut
+		}
+	}
+	return actionKeep
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isDelimiter(c byte) bool {
+	return isSpace(c) || strings.IndexByte(`{}[],:"`, c) >= 0
+}
+
+// process filters a chunk of input, appending the result to f.out.
+func (f *loadFilter) process(data []byte) {
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch f.state {
+		case stateValue:
+			if isSpace(c) {
+				f.out = append(f.out, c)
+				i++
+				continue
+			}
+			if c == ']' || c == '}' {
+				f.state = stateAfter
+				continue
+			}
+			switch action := f.action(); action {
+			case actionNull, actionObject, actionArray:
+				f.out = append(f.out, [...]string{actionNull: "null", actionObject: "{}", actionArray: "[]"}[action]...)
+				f.depth, f.str, f.esc = 0, false, 0
+				switch c {
+				case '{', '[':
+					f.depth = 1
+					f.state = stateSkip
+				case '"':
+					f.str = true
+					f.state = stateSkip
+				default:
+					f.state = stateSkipScalar
+				}
+				i++
+				continue
+			case actionCut:
+				if c == '"' {
+					f.out = append(f.out, c)
+					f.kept, f.esc = 0, 0
+					f.state = stateCut
+					i++
+					continue
+				}
+			}
+			f.out = append(f.out, c)
+			i++
+			switch c {
+			case '{':
+				f.stack = append(f.stack, filterFrame{object: true})
+				f.state = stateKey
+			case '[':
+				f.stack = append(f.stack, filterFrame{key: "[]"})
+			case '"':
+				f.esc = 0
+				f.state = stateString
+			default:
+				f.state = stateScalar
+			}
+
+		case stateKey:
+			f.out = append(f.out, c)
+			i++
+			switch c {
+			case '"':
+				f.key, f.esc = f.key[:0], 0
+				f.state = stateKeyString
+			case '}':
+				f.pop()
+			}
+
+		case stateKeyString:
+			f.out = append(f.out, c)
+			i++
+			switch {
+			case f.esc > 0:
+				f.esc = 0
+				f.key = append(f.key, c)
+			case c == '\\':
+				f.esc = 1
+			case c == '"':
+				if n := len(f.stack); n > 0 {
+					f.stack[n-1].key = string(bytes.ToLower(f.key))
+				}
+				f.state = stateColon
+			default:
+				f.key = append(f.key, c)
+			}
+
+		case stateColon:
+			f.out = append(f.out, c)
+			i++
+			if c == ':' {
+				f.state = stateValue
+			}
+
+		case stateAfter:
+			switch {
+			case c == ',':
+				f.state = stateValue
+				if n := len(f.stack); n > 0 && f.stack[n-1].object {
+					f.state = stateKey
+				}
+			case c == '}' || c == ']':
+				f.pop()
+			case !isSpace(c):
+				// Not valid JSON: read it as a value, for the decoder to
+				// report.
+				f.state = stateValue
+				continue
+			}
+			f.out = append(f.out, c)
+			i++
+
+		case stateString:
+			if f.esc == 0 {
+				// Copy up to the next quote or backslash at once.
+				j := bytes.IndexAny(data[i:], `"\`)
+				if j < 0 {
+					f.out = append(f.out, data[i:]...)
+					i = len(data)
+					continue
+				}
+				f.out = append(f.out, data[i:i+j]...)
+				i += j
+				c = data[i]
+			}
+			f.out = append(f.out, c)
+			i++
+			switch {
+			case f.esc > 0:
+				f.esc = 0
+			case c == '\\':
+				f.esc = 1
+			case c == '"':
+				f.state = stateAfter
+			}
+
+		case stateScalar:
+			if isDelimiter(c) {
+				f.state = stateAfter
+				continue
+			}
+			f.out = append(f.out, c)
+			i++
+
+		case stateSkip:
+			if f.str && f.esc == 0 {
+				j := bytes.IndexAny(data[i:], `"\`)
+				if j < 0 {
+					i = len(data)
+					continue
+				}
+				i += j
+				c = data[i]
+			}
+			i++
+			switch {
+			case f.str && f.esc > 0:
+				f.esc = 0
+			case f.str && c == '\\':
+				f.esc = 1
+			case f.str && c == '"':
+				f.str = false
+				if f.depth == 0 {
+					f.state = stateAfter
+				}
+			case f.str:
+			case c == '"':
+				f.str = true
+			case c == '{' || c == '[':
+				f.depth++
+			case c == '}' || c == ']':
+				if f.depth--; f.depth <= 0 {
+					f.state = stateAfter
+				}
+			}
+
+		case stateSkipScalar:
+			if isDelimiter(c) {
+				f.state = stateAfter
+				continue
+			}
+			i++
+
+		case stateCut:
+			if f.kept >= f.opts.MaxBodyBytes && f.esc == 0 && c != '"' && c&0xc0 != 0x80 {
+				// Cut between characters and escapes, then drop the rest
+				// of the string.
+				f.out = append(f.out, `","_truncated":true`...)
+				f.str, f.depth = true, 0
+				f.state = stateSkip
+				continue
+			}
+			f.out = append(f.out, c)
+			i++
+			switch {
+			case f.esc == 1 && c == 'u':
+				f.esc = 4
+			case f.esc > 0:
+				f.esc--
+			case c == '\\':
+				f.esc = 1
+			case c == '"':
+				f.state = stateAfter
+				continue
+			}
+			f.kept++
+		}
+	}
+}
+
+// pop leaves the current object or array.
+func (f *loadFilter) pop() {
+	if n := len(f.stack); n > 0 {
+		f.stack = f.stack[:n-1]
+	}
+	f.state = stateAfter
+}
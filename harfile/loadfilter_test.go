@@ -0,0 +1,253 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// loadOptionsHAR returns a document with a page, browser, bodies holding
+// escapes and a cache, and members named like the filtered ones elsewhere.
+func loadOptionsHAR(t testing.TB) []byte {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLog().Build()
+	l.Browser = &Browser{Name: "Firefox", Version: "125"}
+	page := l.AddPage("page_1", "Home", start)
+	form := NewEntry().Post("https://example.com/form").StartedAt(start).Page(page).
+		Header("Content-Type", "application/x-www-form-urlencoded").
+		Body("application/x-www-form-urlencoded", []byte("a=1&text=%22quoted%22")).
+		RespondStatus(200).
+		RespondBody("text/plain", []byte(strings.Repeat(`say "héllo" \ wörld `, 20))).
+		Build()
+	form.Cache = &Cache{BeforeRequest: &CacheData{LastAccess: "x", ETag: `"abc"`, HitCount: 2}}
+	binary := NewEntry().Post("https://example.com/image").StartedAt(start).
+		Body("application/octet-stream", bytes.Repeat([]byte{0xfe, 0x00, 0x7f}, 100)).
+		RespondStatus(200).
+		RespondBody("image/png", bytes.Repeat([]byte{0x89, 0xff, 0x00, 0x10}, 100)).
+		Build()
+	binary.Response.Content.Comment = "text"
+	l.Entries = []*Entry{form, binary}
+	data, err := json.Marshal(&HAR{Log: l})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// loaders load a document with each loader honoring LoadOptions.
+var loaders = []struct {
+	name string
+	load func(io.Reader, ...FileOption) (*HAR, error)
+}{
+	{"Load", Load},
+	{"LoadLenient", func(r io.Reader, opts ...FileOption) (*HAR, error) {
+		h, _, err := LoadLenient(r, opts...)
+		return h, err
+	}},
+	{"EntryReader", func(r io.Reader, opts ...FileOption) (*HAR, error) {
+		er := NewEntryReader(r, opts...)
+		var entries []*Entry
+		for e, err := range er.All() {
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, e)
+		}
+		l := er.Log()
+		l.Entries = entries
+		return &HAR{Log: l}, nil
+	}},
+}
+
+func TestLoadOptions(t *testing.T) {
+	data := loadOptionsHAR(t)
+	full, err := Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullText := full.Log.Entries[0].Response.Content.Text
+
+	tests := []struct {
+		name  string
+		opts  LoadOptions
+		check func(t *testing.T, h *HAR)
+	}{
+		{"none", LoadOptions{}, func(t *testing.T, h *HAR) {
+			if h.Log.Entries[0].Response.Content.Text != fullText || len(h.Log.Pages) != 1 || h.Log.Browser == nil {
+				t.Error("document changed without options")
+			}
+		}},
+		{"skip response bodies", LoadOptions{SkipResponseBodies: true}, func(t *testing.T, h *HAR) {
+			for _, e := range h.Log.Entries {
+				c := e.Response.Content
+				if c.Text != "" || c.Size == 0 || c.MimeType == "" {
+					t.Errorf("content = %+v", c)
+				}
+			}
+			if h.Log.Entries[1].Response.Content.Comment != "text" {
+				t.Error("member named text dropped elsewhere")
+			}
+			if pd := h.Log.Entries[0].Request.PostData; pd.Text == "" || len(pd.Params) != 2 {
+				t.Errorf("request body dropped: %+v", pd)
+			}
+		}},
+		{"skip request bodies", LoadOptions{SkipRequestBodies: true}, func(t *testing.T, h *HAR) {
+			pd := h.Log.Entries[0].Request.PostData
+			if pd.Text != "" || len(pd.Params) != 0 || pd.MimeType != "application/x-www-form-urlencoded" {
+				t.Errorf("postData = %+v", pd)
+			}
+			if h.Log.Entries[0].Request.BodySize == 0 || h.Log.Entries[0].Response.Content.Text != fullText {
+				t.Error("more than the request body dropped")
+			}
+		}},
+		{"skip cache", LoadOptions{SkipCache: true}, func(t *testing.T, h *HAR) {
+			if c := h.Log.Entries[0].Cache; c == nil || c.BeforeRequest != nil {
+				t.Errorf("cache = %+v", c)
+			}
+		}},
+		{"entries only", LoadOptions{EntriesOnly: true}, func(t *testing.T, h *HAR) {
+			if len(h.Log.Pages) != 0 || h.Log.Browser != nil || len(h.Log.Entries) != 2 || h.Log.Entries[0].Pageref != "page_1" {
+				t.Errorf("pages %v, browser %v, %d entries", h.Log.Pages, h.Log.Browser, len(h.Log.Entries))
+			}
+		}},
+		{"max body bytes", LoadOptions{MaxBodyBytes: 41}, func(t *testing.T, h *HAR) {
+			form, binary := h.Log.Entries[0], h.Log.Entries[1]
+			c := form.Response.Content
+			if !c.Truncated || c.Text == "" || len(c.Text) > 41 || !strings.HasPrefix(fullText, c.Text) || c.Size != full.Log.Entries[0].Response.Content.Size {
+				t.Errorf("cut text %q of size %d", c.Text, c.Size)
+			}
+			if pd := form.Request.PostData; pd.Truncated || pd.Text != "a=1&text=%22quoted%22" {
+				t.Errorf("short request body cut: %+v", pd)
+			}
+			bc := binary.Response.Content
+			if !bc.Truncated || len(bc.Text)%4 != 0 {
+				t.Errorf("base64 text %q not cut to whole groups", bc.Text)
+			}
+			if _, err := bc.DecodedBody(); err != nil {
+				t.Errorf("cut base64 body: %v", err)
+			}
+			if pd := binary.Request.PostData; !pd.Truncated || pd.Encoding != "base64" || len(pd.Text)%4 != 0 {
+				t.Errorf("base64 request body %q not cut to whole groups", pd.Text)
+			} else if _, err := pd.DecodedText(); err != nil {
+				t.Errorf("cut base64 request body: %v", err)
+			}
+		}},
+		{"everything", LoadOptions{SkipResponseBodies: true, SkipRequestBodies: true, SkipCache: true, EntriesOnly: true, MaxBodyBytes: 1}, func(t *testing.T, h *HAR) {
+			e := h.Log.Entries[0]
+			if e.Request.URL != "https://example.com/form" || e.Response.Status != 200 || e.Response.Content.Text != "" || e.Request.PostData.Text != "" {
+				t.Errorf("entry = %+v", e)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		for _, l := range loaders {
+			t.Run(tt.name+"/"+l.name, func(t *testing.T) {
+				h, err := l.load(bytes.NewReader(data), WithLoadOptions(tt.opts))
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.check(t, h)
+
+				// The filter gives the same result whatever the chunks it
+				// is given.
+				slow, err := l.load(iotest.OneByteReader(bytes.NewReader(data)), WithLoadOptions(tt.opts))
+				if err != nil {
+					t.Fatal(err)
+				}
+				a, _ := json.Marshal(h)
+				b, _ := json.Marshal(slow)
+				if !bytes.Equal(a, b) {
+					t.Errorf("byte by byte load differs:\n%s\n%s", a, b)
+				}
+			})
+		}
+	}
+}
+
+// TestLoadOptionsMalformed checks that malformed input is still reported
+// by the decoder through the filter.
+func TestLoadOptionsMalformed(t *testing.T) {
+	data := string(loadOptionsHAR(t))
+	opts := WithLoadOptions(LoadOptions{SkipResponseBodies: true, MaxBodyBytes: 10})
+	for _, input := range []string{
+		data[:len(data)/2],
+		strings.Replace(data, `"text":"say`, `"text":say`, 1),
+		`{"log":{"entries":[{"response":{"content":{"text":"unterminated`,
+		`{"log":`,
+	} {
+		if _, err := Load(strings.NewReader(input), opts); err == nil {
+			t.Errorf("no error loading %.60q", input)
+		}
+	}
+}
+
+// FuzzLoadOptions checks that the filter never turns a document that loads
+// into one that does not, and keeps its entries.
+func FuzzLoadOptions(f *testing.F) {
+	f.Add(loadOptionsHAR(f), uint8(0xff), int64(7))
+	f.Add([]byte(`{"log":{"entries":[{"response":{"content":{"text":"aé\"b"}}}]}}`), uint8(0x10), int64(3))
+	f.Add([]byte(`{"log":{"pages":[{"id":"p"}],"entries":[{"cache":{"x":[1,{"y":"}"}]}}]}}`), uint8(0x0c), int64(0))
+	f.Fuzz(func(t *testing.T, data []byte, flags uint8, max int64) {
+		full, err := Load(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		opts := LoadOptions{
+			SkipResponseBodies: flags&1 != 0,
+			SkipRequestBodies:  flags&2 != 0,
+			SkipCache:          flags&4 != 0,
+			EntriesOnly:        flags&8 != 0,
+			MaxBodyBytes:       max,
+		}
+		h, err := Load(bytes.NewReader(data), WithLoadOptions(opts))
+		if err != nil {
+			t.Fatalf("Load with %+v: %v", opts, err)
+		}
+		if len(h.Log.Entries) != len(full.Log.Entries) {
+			t.Fatalf("%d entries with %+v, want %d", len(h.Log.Entries), opts, len(full.Log.Entries))
+		}
+	})
+}
+
+// BenchmarkLoadOptions loads a body-heavy capture in full and with its
+// bodies skipped or cut, reporting the heap held by the result.
+func BenchmarkLoadOptions(b *testing.B) {
+	data := bodiesHAR(b, 200, 256<<10)
+	for _, tt := range []struct {
+		name string
+		opts LoadOptions
+	}{
+		{"full", LoadOptions{}},
+		{"skip bodies", LoadOptions{SkipResponseBodies: true, SkipRequestBodies: true}},
+		{"max 1KiB", LoadOptions{MaxBodyBytes: 1 << 10}},
+		{"entries only", LoadOptions{SkipResponseBodies: true, SkipRequestBodies: true, SkipCache: true, EntriesOnly: true}},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			var ms runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&ms)
+			base, heap := ms.HeapAlloc, uint64(0)
+			for range b.N {
+				h, err := Load(bytes.NewReader(data), WithLoadOptions(tt.opts))
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StopTimer()
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				heap = max(heap, ms.HeapAlloc-min(base, ms.HeapAlloc))
+				runtime.KeepAlive(h)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(heap)/(1<<20), "heap-MiB")
+		})
+	}
+}
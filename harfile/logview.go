@@ -0,0 +1,63 @@
+package harfile
+
+import (
+	"iter"
+	"slices"
+)
+
+// LogView is a read-only log, safe for concurrent use, such as the frozen
+// state of a log still being recorded; see harkit's Recorder.Freeze.
+//
+// Nothing reachable from a LogView may be modified, neither by its owner
+// nor by its readers: entries and pages are shared, not copied, which is
+// what makes views cheap. Functions taking a *Log read a view through
+// [LogView.Log].
+type LogView struct {
+	log *Log
+}
+
+// NewLogView returns a view of l, which must no longer be modified: its
+// entries and pages, but also its Entries and Pages slices, are shared
+// with the view.
+func NewLogView(l *Log) *LogView {
+	v := *l
+	v.view = nil
+	return &LogView{log: &v}
+}
+
+// EntryCount returns the number of entries of v.
+func (v *LogView) EntryCount() int {
+	return len(v.log.Entries)
+}
+
+// EntryAt returns the entry at index i, or nil when out of range.
+func (v *LogView) EntryAt(i int) *Entry {
+	return v.log.EntryAt(i)
+}
+
+// All returns an iterator over the indexes and entries of v, in order.
+func (v *LogView) All() iter.Seq2[int, *Entry] {
+	return func(yield func(int, *Entry) bool) {
+		for i, e := range v.log.Entries {
+			if !yield(i, e) {
+				return
+			}
+		}
+	}
+}
+
+// Log returns v as a Log, for the functions reading one, such as harkit's
+// Histogram or RenderReport. The Log is a shallow copy sharing the entries
+// and pages of v, which must not be modified; its own slices may be.
+func (v *LogView) Log() *Log {
+	l := *v.log
+	l.Entries = slices.Clone(v.log.Entries)
+	l.Pages = slices.Clone(v.log.Pages)
+	return &l
+}
+
+// Filter returns a view of the entries of v selected by pred, see
+// [Log.Filter].
+func (v *LogView) Filter(pred Predicate, opts ...FilterOption) *LogView {
+	return &LogView{log: v.log.Filter(pred, opts...)}
+}
@@ -0,0 +1,137 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MarshalOptions configures [HAR.MarshalWith].
+type MarshalOptions struct {
+	Indent          string // Indentation of each level, e.g. "  ". Empty means compact output.
+	SortHeaders     bool   // Sort request and response headers by name, case insensitively, keeping the order of repeated names.
+	SortQueryParams bool   // Sort query parameters by name, keeping the order of repeated names.
+	TrailingNewline bool   // End the output with a newline.
+	Normalize       bool   // Fill the members required by the spec, see [HAR.Normalize].
+}
+
+// MarshalWith encodes h as JSON in a stable form suited to files kept under
+// version control: the same content always gives the same bytes. Members
+// keep their usual order, characters such as '<' are not escaped, and
+// numbers are written in plain decimal notation with as few digits as
+// needed to read back the same value. h itself is not modified.
+func (h *HAR) MarshalWith(opts MarshalOptions) ([]byte, error) {
+	if opts.Normalize {
+		h = h.Clone()
+		h.Normalize()
+	}
+	if (opts.SortHeaders || opts.SortQueryParams) && h.Log != nil {
+		if !opts.Normalize {
+			h = h.Clone()
+		}
+		for _, e := range h.Log.Entries {
+			if e == nil {
+				continue
+			}
+			if e.Request != nil {
+				if opts.SortHeaders {
+					sortPairs(e.Request.Headers, strings.ToLower)
+				}
+				if opts.SortQueryParams {
+					sortPairs(e.Request.QueryString, func(s string) string { return s })
+				}
+			}
+			if e.Response != nil && opts.SortHeaders {
+				sortPairs(e.Response.Headers, strings.ToLower)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(h); err != nil {
+		return nil, err
+	}
+	data := plainNumbers(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	if opts.Indent != "" {
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", opts.Indent); err != nil {
+			return nil, err
+		}
+		data = out.Bytes()
+	}
+	if opts.TrailingNewline {
+		data = append(data, '\n')
+	}
+	return data, nil
+}
+
+// sortPairs sorts pairs by key(name), keeping the order of equal keys. Nil
+// pairs go last.
+func sortPairs(pairs []*NameValuePair, key func(string) string) {
+	slices.SortStableFunc(pairs, func(a, b *NameValuePair) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		case b == nil:
+			return -1
+		}
+		return strings.Compare(key(a.Name), key(b.Name))
+	})
+}
+
+// plainNumbers rewrites the numbers of the JSON document data that use an
+// exponent, such as 1e-7 or 1e+21, in plain decimal notation.
+func plainNumbers(data []byte) []byte {
+	var out []byte
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '-' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(data) && strings.IndexByte("+-.0123456789eE", data[end]) >= 0 {
+				end++
+			}
+			num := data[i:end]
+			if bytes.ContainsAny(num, "eE") {
+				if f, err := strconv.ParseFloat(string(num), 64); err == nil {
+					if out == nil {
+						out = append(make([]byte, 0, len(data)), data[:i]...)
+					}
+					out = strconv.AppendFloat(out, f, 'f', -1, 64)
+					i = end - 1
+					continue
+				}
+			}
+			if out != nil {
+				out = append(out, num...)
+			}
+			i = end - 1
+			continue
+		}
+		if out != nil {
+			out = append(out, c)
+		}
+	}
+	if out == nil {
+		return data
+	}
+	return out
+}
@@ -0,0 +1,214 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// marshalEntry returns a GET of rawURL, with its query string in order,
+// answered with a 200.
+func marshalEntry(rawURL string) *Entry {
+	e := streamEntry(0)
+	e.Request.URL = rawURL
+	e.Request.Headers = []*NameValuePair{}
+	if _, query, ok := strings.Cut(rawURL, "?"); ok {
+		for _, pair := range strings.Split(query, "&") {
+			name, value, _ := strings.Cut(pair, "=")
+			e.Request.QueryString = append(e.Request.QueryString, &NameValuePair{Name: name, Value: value})
+		}
+	}
+	return e
+}
+
+// marshalHAR returns a log of entries.
+func marshalHAR(entries ...*Entry) *HAR {
+	return &HAR{Log: &Log{Version: "1.2", Creator: NewCreator(), Entries: entries}}
+}
+
+// TestMarshalWithDeterministic marshals random logs twice, and once more
+// after reading the output back, and checks that the bytes never change.
+func TestMarshalWithDeterministic(t *testing.T) {
+	opts := MarshalOptions{Indent: "  ", SortHeaders: true, SortQueryParams: true, TrailingNewline: true}
+	rng := rand.New(rand.NewPCG(7, 8))
+	for i := range 50 {
+		h := new(HAR)
+		fill(rng, reflect.ValueOf(h).Elem(), 0)
+		first, err := h.MarshalWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := h.MarshalWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Fatalf("log %d: two marshals differ:\n%s\n%s", i, first, second)
+		}
+
+		again, err := Load(bytes.NewReader(first))
+		if err != nil {
+			t.Fatalf("log %d: %v\n%s", i, err, first)
+		}
+		third, err := again.MarshalWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, third) {
+			t.Fatalf("log %d: marshal after reading back differs:\n%s\n%s", i, first, third)
+		}
+	}
+}
+
+func TestMarshalWithNumbers(t *testing.T) {
+	tenth, fifth := 0.1, 0.2
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{-1, "-1"},
+		{12.5, "12.5"},
+		{tenth + fifth, "0.30000000000000004"},
+		{1e-7, "0.0000001"},
+		{-2.5e-9, "-0.0000000025"},
+		{1e21, "1000000000000000000000"},
+		{123456789.125, "123456789.125"},
+	}
+	for _, tt := range tests {
+		e := marshalEntry("https://example.com/")
+		e.Time = tt.value
+		data, err := marshalHAR(e).MarshalWith(MarshalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `"time":` + tt.want + `,`; !bytes.Contains(data, []byte(want)) {
+			t.Errorf("time %v written as %s, want %s", tt.value, data, want)
+		}
+	}
+
+	// Exponents inside strings are left alone.
+	e := marshalEntry("https://example.com/?n=1e-7")
+	e.Comment = `1e+21 "2E5"`
+	data, err := marshalHAR(e).MarshalWith(MarshalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`?n=1e-7`, `"comment":"1e+21 \"2E5\""`} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("%s missing from %s", s, data)
+		}
+	}
+}
+
+func TestMarshalWithSort(t *testing.T) {
+	e := marshalEntry("https://example.com/?b=2&a=1&b=1&C=3")
+	e.Request.Headers = []*NameValuePair{
+		{Name: "X-B", Value: "1"},
+		{Name: "accept", Value: "*/*"},
+		{Name: "x-b", Value: "2"},
+		{Name: "Accept", Value: "text/html"},
+	}
+	e.Response.Headers = []*NameValuePair{
+		{Name: "Set-Cookie", Value: "z=1"},
+		{Name: "Content-Type", Value: "text/plain"},
+		{Name: "Set-Cookie", Value: "a=1"},
+	}
+	h := marshalHAR(e)
+	before, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(pairs []*NameValuePair) string {
+		var s []string
+		for _, p := range pairs {
+			s = append(s, p.Name+"="+p.Value)
+		}
+		return strings.Join(s, " ")
+	}
+	tests := []struct {
+		name                      string
+		opts                      MarshalOptions
+		headers, query, responses string
+	}{
+		{
+			name:      "unsorted",
+			headers:   "X-B=1 accept=*/* x-b=2 Accept=text/html",
+			query:     "b=2 a=1 b=1 C=3",
+			responses: "Set-Cookie=z=1 Content-Type=text/plain Set-Cookie=a=1",
+		},
+		{
+			name:      "headers",
+			opts:      MarshalOptions{SortHeaders: true},
+			headers:   "accept=*/* Accept=text/html X-B=1 x-b=2",
+			query:     "b=2 a=1 b=1 C=3",
+			responses: "Content-Type=text/plain Set-Cookie=z=1 Set-Cookie=a=1",
+		},
+		{
+			name:      "query",
+			opts:      MarshalOptions{SortQueryParams: true},
+			headers:   "X-B=1 accept=*/* x-b=2 Accept=text/html",
+			query:     "C=3 a=1 b=2 b=1",
+			responses: "Set-Cookie=z=1 Content-Type=text/plain Set-Cookie=a=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := h.MarshalWith(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Load(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ge := got.Log.Entries[0]
+			if s := names(ge.Request.Headers); s != tt.headers {
+				t.Errorf("request headers = %s, want %s", s, tt.headers)
+			}
+			if s := names(ge.Request.QueryString); s != tt.query {
+				t.Errorf("query = %s, want %s", s, tt.query)
+			}
+			if s := names(ge.Response.Headers); s != tt.responses {
+				t.Errorf("response headers = %s, want %s", s, tt.responses)
+			}
+		})
+	}
+
+	after, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("MarshalWith modified the log:\n%s\n%s", before, after)
+	}
+}
+
+func TestMarshalWithLayout(t *testing.T) {
+	h := marshalHAR(marshalEntry("https://example.com/?q=<a>&b"))
+	tests := []struct {
+		name string
+		opts MarshalOptions
+		test func([]byte) bool
+	}{
+		{"compact", MarshalOptions{}, func(b []byte) bool { return !bytes.ContainsAny(b, "\n\t") && b[len(b)-1] == '}' }},
+		{"indent", MarshalOptions{Indent: "\t"}, func(b []byte) bool { return bytes.HasPrefix(b, []byte("{\n\t\"log\": {\n\t\t")) && b[len(b)-1] == '}' }},
+		{"trailing newline", MarshalOptions{Indent: "  ", TrailingNewline: true}, func(b []byte) bool { return bytes.HasSuffix(b, []byte("}\n")) && !bytes.HasSuffix(b, []byte("\n\n")) }},
+		{"html unescaped", MarshalOptions{}, func(b []byte) bool { return bytes.Contains(b, []byte("?q=<a>&b")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := h.MarshalWith(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.test(data) {
+				t.Errorf("MarshalWith = %s", data)
+			}
+		})
+	}
+}
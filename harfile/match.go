@@ -0,0 +1,157 @@
+package harfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Matcher selects the request components that make up a fingerprint. Two
+// requests correspond when their fingerprints are equal.
+type Matcher struct {
+	Method            bool     // Compare methods.
+	Scheme            bool     // Compare URL schemes.
+	Host              bool     // Compare hosts, including the port.
+	Path              bool     // Compare URL paths.
+	Query             bool     // Compare query parameters, in any order.
+	IgnoreQueryParams []string // Query parameters left out when Query is set.
+	Headers           []string // Names of headers whose values are compared.
+	Body              bool     // Compare request bodies by hash.
+}
+
+// DefaultMatcher compares the method and the full URL, ignoring the order of
+// query parameters.
+var DefaultMatcher = Matcher{Method: true, Scheme: true, Host: true, Path: true, Query: true}
+
+// Fingerprint returns the key of a recorded request under m.
+func (m Matcher) Fingerprint(req *Request) string {
+	if req == nil {
+		return ""
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		u = &url.URL{Path: req.URL}
+	}
+	var body []byte
+	if m.Body && req.PostData != nil {
+		if rd, _, err := req.PostData.BodyReader(); err == nil {
+			body, _ = io.ReadAll(rd)
+		}
+	}
+	return m.fingerprint(req.Method, u, func(name string) string {
+		return strings.Join(req.HeaderValues(name), ", ")
+	}, body)
+}
+
+// RequestFingerprint returns the key of a live request under m, comparable
+// with [Matcher.Fingerprint]. Server requests, whose URL has no host, are
+// taken to target req.Host. When m compares bodies, req.Body is read and
+// replaced by an equivalent reader.
+func (m Matcher) RequestFingerprint(req *http.Request) (string, error) {
+	u := *req.URL
+	if u.Host == "" {
+		u.Host = req.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if req.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+	var body []byte
+	if m.Body && req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return "", err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	header := func(name string) string {
+		if strings.EqualFold(name, "Host") {
+			return u.Host
+		}
+		return strings.Join(req.Header.Values(name), ", ")
+	}
+	return m.fingerprint(req.Method, &u, header, body), nil
+}
+
+func (m Matcher) fingerprint(method string, u *url.URL, header func(string) string, body []byte) string {
+	var b strings.Builder
+	if m.Method {
+		b.WriteString(strings.ToUpper(method))
+	}
+	b.WriteByte(' ')
+	if m.Scheme {
+		b.WriteString(strings.ToLower(u.Scheme))
+		b.WriteString("://")
+	}
+	if m.Host {
+		b.WriteString(strings.ToLower(u.Host))
+	}
+	if m.Path {
+		b.WriteString(u.EscapedPath())
+	}
+	if m.Query {
+		q := u.Query()
+		for _, name := range m.IgnoreQueryParams {
+			q.Del(name)
+		}
+		if len(q) > 0 {
+			b.WriteByte('?')
+			b.WriteString(q.Encode()) // Encode sorts by key.
+		}
+	}
+	for _, name := range m.Headers {
+		b.WriteByte('\n')
+		b.WriteString(strings.ToLower(name))
+		b.WriteString(": ")
+		b.WriteString(header(name))
+	}
+	if m.Body {
+		sum := sha256.Sum256(body)
+		b.WriteString("\nbody: ")
+		b.WriteString(hex.EncodeToString(sum[:]))
+	}
+	return b.String()
+}
+
+// EntryIndex finds the entries of a log corresponding to a request. It is
+// a snapshot: entries added to the log afterwards are not indexed.
+type EntryIndex struct {
+	matcher Matcher
+	entries map[string][]*Entry
+}
+
+// Index returns an index of the entries of l keyed by their fingerprint
+// under m.
+func (l *Log) Index(m Matcher) *EntryIndex {
+	ix := &EntryIndex{matcher: m, entries: make(map[string][]*Entry)}
+	for _, e := range l.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		fp := m.Fingerprint(e.Request)
+		ix.entries[fp] = append(ix.entries[fp], e)
+	}
+	return ix
+}
+
+// Lookup returns the entries corresponding to req, in recorded order.
+func (ix *EntryIndex) Lookup(req *http.Request) []*Entry {
+	fp, err := ix.matcher.RequestFingerprint(req)
+	if err != nil {
+		return nil
+	}
+	return ix.LookupFingerprint(fp)
+}
+
+// LookupFingerprint returns the entries with the given fingerprint, in
+// recorded order.
+func (ix *EntryIndex) LookupFingerprint(fp string) []*Entry {
+	return append([]*Entry(nil), ix.entries[fp]...)
+}
@@ -0,0 +1,111 @@
+package harfile
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// matchEntry returns an entry for method and url answered with status.
+func matchEntry(method, url string, status int64) *Entry {
+	return &Entry{Request: &Request{Method: method, URL: url}, Response: &Response{Status: status}}
+}
+
+func TestMatcherFingerprint(t *testing.T) {
+	recorded := &Request{
+		Method:   "POST",
+		URL:      "https://Example.com:8443/api/items?b=2&a=1&token=x",
+		Headers:  []*NameValuePair{{Name: "X-Tenant", Value: "acme"}},
+		PostData: &PostData{MimeType: "application/json", Text: `{"n":1}`},
+	}
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		method  string
+		url     string
+		tenant  string
+		body    string
+		match   bool
+	}{
+		{"same request", DefaultMatcher, "POST", "https://example.com:8443/api/items?b=2&a=1&token=x", "", "", true},
+		{"query order", DefaultMatcher, "post", "https://example.com:8443/api/items?token=x&a=1&b=2", "", "", true},
+		{"query value", DefaultMatcher, "POST", "https://example.com:8443/api/items?a=1&b=3&token=x", "", "", false},
+		{"method", DefaultMatcher, "GET", "https://example.com:8443/api/items?a=1&b=2&token=x", "", "", false},
+		{"scheme", DefaultMatcher, "POST", "http://example.com:8443/api/items?a=1&b=2&token=x", "", "", false},
+		{"scheme ignored", Matcher{Method: true, Host: true, Path: true}, "POST", "http://example.com:8443/api/items", "", "", true},
+		{"port", DefaultMatcher, "POST", "https://example.com/api/items?a=1&b=2&token=x", "", "", false},
+		{"ignored param", Matcher{Method: true, Path: true, Query: true, IgnoreQueryParams: []string{"token"}}, "POST", "https://other.net/api/items?a=1&b=2&token=y", "", "", true},
+		{"header", Matcher{Path: true, Headers: []string{"x-tenant"}}, "GET", "https://example.com/api/items", "acme", "", true},
+		{"header value", Matcher{Path: true, Headers: []string{"X-Tenant"}}, "GET", "https://example.com/api/items", "other", "", false},
+		{"body", Matcher{Path: true, Body: true}, "POST", "https://example.com/api/items", "", `{"n":1}`, true},
+		{"body differs", Matcher{Path: true, Body: true}, "POST", "https://example.com/api/items", "", `{"n":2}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant", tt.tenant)
+			}
+			fp, err := tt.matcher.RequestFingerprint(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.matcher.Fingerprint(recorded); (fp == want) != tt.match {
+				t.Errorf("fingerprints %q and %q, want match %v", fp, want, tt.match)
+			}
+			if body, _ := io.ReadAll(req.Body); string(body) != tt.body {
+				t.Errorf("request body = %q after fingerprinting, want %q", body, tt.body)
+			}
+		})
+	}
+	if fp := DefaultMatcher.Fingerprint(nil); fp != "" {
+		t.Errorf("Fingerprint(nil) = %q", fp)
+	}
+}
+
+func TestMatcherServerRequest(t *testing.T) {
+	recorded := matchEntry("GET", "http://example.com/a?y=2&x=1", 0).Request
+	req := httptest.NewRequest("GET", "/a?x=1&y=2", nil)
+	req.Host = "example.com"
+	fp, err := DefaultMatcher.RequestFingerprint(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := DefaultMatcher.Fingerprint(recorded); fp != want {
+		t.Errorf("server request fingerprint %q, want %q", fp, want)
+	}
+}
+
+func TestLogIndex(t *testing.T) {
+	first := matchEntry("GET", "https://example.com/items?page=1&sort=asc", 200)
+	other := matchEntry("GET", "https://example.com/other", 200)
+	second := matchEntry("GET", "https://example.com/items?sort=asc&page=1", 304)
+	l := &Log{Entries: []*Entry{first, nil, other, second}}
+
+	ix := l.Index(DefaultMatcher)
+	req, _ := http.NewRequest("GET", "https://example.com/items?sort=asc&page=1", nil)
+	got := ix.Lookup(req)
+	if len(got) != 2 || got[0] != first || got[1] != second {
+		t.Fatalf("Lookup = %v, want both /items entries in recorded order", got)
+	}
+	got[0] = nil
+	if ix.Lookup(req)[0] != first {
+		t.Error("Lookup result shares the index storage")
+	}
+	miss, _ := http.NewRequest("GET", "https://example.com/items?page=2&sort=asc", nil)
+	if got := ix.Lookup(miss); len(got) != 0 {
+		t.Errorf("Lookup = %v, want no entry", got)
+	}
+
+	// The index is a snapshot of the log.
+	l.Entries = append(l.Entries, matchEntry("GET", "https://example.com/items?page=1&sort=asc", 200))
+	if got := ix.Lookup(req); len(got) != 2 {
+		t.Errorf("Lookup = %d entries after append, want 2", len(got))
+	}
+	if got := l.Index(DefaultMatcher).Lookup(req); len(got) != 3 {
+		t.Errorf("rebuilt index: %d entries, want 3", len(got))
+	}
+}
@@ -0,0 +1,214 @@
+package harfile
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CreatorName is the name harkit writes in Creator objects it produces.
+const CreatorName = "harkit"
+
+// CreatorVersion is the version harkit writes in Creator objects it
+// produces: the version of the harkit module the program was built with,
+// or "devel" when unknown, e.g. in tests or when built from a checkout.
+// Set it for reproducible output, such as golden files.
+var CreatorVersion = moduleVersion()
+
+// modulePath is the path of the harkit module.
+const modulePath = "github.com/Mathious6/harkit"
+
+// moduleVersion returns the version of the harkit module from the build
+// information of the program, or "devel".
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+			if dep.Replace != nil {
+				version = dep.Replace.Version
+			}
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return "devel"
+	}
+	return version
+}
+
+// NewCreator returns the Creator identifying harkit, at CreatorVersion.
+func NewCreator() *Creator {
+	return &Creator{Name: CreatorName, Version: CreatorVersion}
+}
+
+// Merge combines several HARs into a new one. Pages and entries are
+// concatenated, and entries are stably sorted by StartedDateTime. A page
+// whose ID is already used by an earlier HAR is given a "-2", "-3", ...
+// suffix and the entries of its HAR referring to it are updated.
+//
+// The result is checked by [Log.CheckRefs]: dangling pagerefs, orphan
+// pages and pages sharing an ID within a HAR, which keep sharing it, are
+// summarized in Log.Comment, to be fixed by [Log.RepairRefs].
+//
+// The result is created by harkit: the original creators are listed in
+// Creator.Comment. Its version is the highest of the input versions, an
+// empty version counting as "1.1". Browser is kept when all inputs reporting one agree.
+//
+// Pages and entries are copied, so the inputs are left untouched, but the
+// copies share their requests, responses and other sub-objects with them.
+func Merge(hars ...*HAR) (*HAR, error) {
+	return merge(hars, nil)
+}
+
+// MergeFiles loads the HAR files at paths, see [LoadFile], and merges them
+// as [Merge] does. Each entry is tagged with [TagMergedFrom] followed by the
+// base name of its file, e.g. "merged-from:run1.har". A [StageMerge] step
+// is reported to [WithProgress] once each file is loaded.
+func MergeFiles(paths []string, opts ...FileOption) (*HAR, error) {
+	progress := cmp.Or(newFileOptions(opts).progress, NoProgress)
+	hars := make([]*HAR, len(paths))
+	sources := make([]string, len(paths))
+	for i, path := range paths {
+		h, err := LoadFile(path, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("harfile: merge %s: %w", path, err)
+		}
+		hars[i], sources[i] = h, filepath.Base(path)
+		progress.Step(StageMerge, int64(i+1), int64(len(paths)))
+	}
+	return merge(hars, sources)
+}
+
+// merge implements Merge, tagging the entries of hars[i] with sources[i]
+// when given.
+func merge(hars []*HAR, sources []string) (*HAR, error) {
+	out := &Log{Creator: NewCreator(), Entries: []*Entry{}}
+	var (
+		creators   []string
+		comments   []string
+		browser    *Browser
+		browsers   int
+		version    = [2]int{-1, -1}
+		versionStr string
+		taken      = make(map[string]bool)
+	)
+	for i, h := range hars {
+		if h == nil || h.Log == nil {
+			return nil, fmt.Errorf("harfile: merge input %d has no log", i)
+		}
+		log := h.Log
+
+		v := log.Version
+		if v == "" {
+			v = "1.1"
+		}
+		parsed, err := parseLogVersion(v)
+		if err != nil {
+			return nil, fmt.Errorf("harfile: merge input %d: %w", i, err)
+		}
+		if parsed[0] > version[0] || parsed[0] == version[0] && parsed[1] > version[1] {
+			version, versionStr = parsed, v
+		}
+
+		if c := log.Creator; c != nil {
+			creators = append(creators, strings.TrimSpace(c.Name+" "+c.Version))
+		}
+		if log.Comment != "" {
+			comments = append(comments, log.Comment)
+		}
+		if log.Browser != nil {
+			if browsers == 0 || reflect.DeepEqual(browser, log.Browser) {
+				browser = log.Browser
+			} else {
+				browser = nil
+			}
+			browsers++
+		}
+
+		renamed := make(map[string]string)
+		for _, p := range log.Pages {
+			if p == nil {
+				continue
+			}
+			page := *p
+			if id, ok := renamed[p.ID]; ok {
+				page.ID = id
+			} else {
+				if taken[page.ID] {
+					page.ID = nextFreeID(page.ID, taken)
+				}
+				renamed[p.ID] = page.ID
+			}
+			taken[page.ID] = true
+			out.Pages = append(out.Pages, &page)
+		}
+		for _, e := range log.Entries {
+			if e == nil {
+				continue
+			}
+			entry := *e
+			if id, ok := renamed[entry.Pageref]; ok {
+				entry.Pageref = id
+			}
+			if sources != nil {
+				entry.Tags = slices.Clone(entry.Tags)
+				entry.AddTag(TagMergedFrom + sources[i])
+			}
+			out.Entries = append(out.Entries, &entry)
+		}
+	}
+
+	out.Version = versionStr
+	if out.Version == "" {
+		out.Version = "1.2"
+	}
+	out.Browser = browser
+	if len(creators) > 0 {
+		out.Creator.Comment = "Merged from: " + strings.Join(creators, "; ")
+	}
+	out.Comment = strings.Join(comments, "\n")
+	sort.SliceStable(out.Entries, func(i, j int) bool {
+		return out.Entries[i].StartedDateTime.Before(out.Entries[j].StartedDateTime)
+	})
+	if issues := out.CheckRefs(); len(issues) > 0 {
+		out.Comment = appendNote(out.Comment, refsNote(issues))
+	}
+	return &HAR{Log: out}, nil
+}
+
+func nextFreeID(id string, taken map[string]bool) string {
+	for n := 2; ; n++ {
+		if candidate := id + "-" + strconv.Itoa(n); !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// parseLogVersion parses a "major.minor" log version.
+func parseLogVersion(v string) ([2]int, error) {
+	majorStr, minorStr, _ := strings.Cut(v, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return [2]int{}, fmt.Errorf("invalid log version %q", v)
+	}
+	minor := 0
+	if minorStr != "" {
+		if minor, err = strconv.Atoi(minorStr); err != nil {
+			return [2]int{}, fmt.Errorf("invalid log version %q", v)
+		}
+	}
+	return [2]int{major, minor}, nil
+}
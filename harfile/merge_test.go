@@ -0,0 +1,171 @@
+package harfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	at := func(s int) time.Time { return time.Date(2024, 1, 1, 0, 0, s, 0, time.UTC) }
+	entry := func(url, pageref string, s int) *Entry {
+		return &Entry{
+			Pageref:         pageref,
+			StartedDateTime: at(s),
+			Request:         &Request{Method: "GET", URL: "https://example.com" + url},
+			Response:        &Response{Status: 200, Content: &Content{}},
+		}
+	}
+	log := func(version string, pages []*Page, entries ...*Entry) *HAR {
+		return &HAR{Log: &Log{
+			Version: version,
+			Creator: &Creator{Name: "worker", Version: version},
+			Pages:   pages,
+			Entries: entries,
+		}}
+	}
+	page := func(id string, s int) *Page {
+		return &Page{ID: id, Title: id, StartedDateTime: at(s), PageTimings: &PageTimings{}}
+	}
+
+	tests := []struct {
+		name     string
+		in       []*HAR
+		version  string
+		pages    string // Page IDs in order.
+		entries  string // URL and pageref of each entry, in order.
+		creators string
+	}{
+		{
+			name: "duplicate page ids",
+			in: []*HAR{
+				log("1.2", []*Page{page("page_1", 0)}, entry("/a", "page_1", 0), entry("/c", "page_1", 4)),
+				log("1.2", []*Page{page("page_1", 1), page("page_1-2", 2)}, entry("/b", "page_1", 2), entry("/d", "page_1-2", 5)),
+				log("1.2", []*Page{page("page_1", 3)}, entry("/e", "page_1", 3)),
+			},
+			version:  "1.2",
+			pages:    "page_1 page_1-2 page_1-2-2 page_1-3",
+			entries:  "/a@page_1 /b@page_1-2 /e@page_1-3 /c@page_1 /d@page_1-2-2",
+			creators: "Merged from: worker 1.2; worker 1.2; worker 1.2",
+		},
+		{
+			name: "empty logs",
+			in: []*HAR{
+				log("1.1", nil),
+				log("1.2", []*Page{page("p", 0)}, entry("/a", "p", 0)),
+				log("", nil),
+			},
+			version:  "1.2",
+			pages:    "p",
+			entries:  "/a@p",
+			creators: "Merged from: worker 1.1; worker 1.2; worker",
+		},
+		{
+			name: "no pages",
+			in: []*HAR{
+				log("1.1", nil, entry("/b", "", 2), entry("/a", "", 1)),
+				log("1.1", nil, entry("/c", "", 1)),
+			},
+			version:  "1.1",
+			entries:  "/a@ /c@ /b@",
+			creators: "Merged from: worker 1.1; worker 1.1",
+		},
+		{
+			name:    "nothing",
+			version: "1.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Merge(tt.in...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pages, entries []string
+			for _, p := range h.Log.Pages {
+				pages = append(pages, p.ID)
+			}
+			for _, e := range h.Log.Entries {
+				entries = append(entries, strings.TrimPrefix(e.Request.URL, "https://example.com")+"@"+e.Pageref)
+			}
+			if got := strings.Join(pages, " "); got != tt.pages {
+				t.Errorf("pages = %q, want %q", got, tt.pages)
+			}
+			if got := strings.Join(entries, " "); got != tt.entries {
+				t.Errorf("entries = %q, want %q", got, tt.entries)
+			}
+			if h.Log.Version != tt.version || h.Log.Creator.Name != CreatorName || h.Log.Creator.Comment != tt.creators {
+				t.Errorf("version %q, creator %+v", h.Log.Version, h.Log.Creator)
+			}
+			ids := make(map[string]bool)
+			for _, p := range h.Log.Pages {
+				if ids[p.ID] {
+					t.Errorf("page id %q used twice", p.ID)
+				}
+				ids[p.ID] = true
+			}
+			for _, e := range h.Log.Entries {
+				if e.Pageref != "" && !ids[e.Pageref] {
+					t.Errorf("pageref %q names no page", e.Pageref)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeLeavesInputs(t *testing.T) {
+	input := func(url string) *HAR {
+		return &HAR{Log: &Log{
+			Version: "1.2",
+			Pages:   []*Page{{ID: "p", Title: "p", PageTimings: &PageTimings{}}},
+			Entries: []*Entry{{Pageref: "p", Request: &Request{Method: "GET", URL: url}}},
+		}}
+	}
+	a, b := input("https://example.com/"), input("https://example.com/b")
+
+	if _, err := Merge(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Log.Pages[0].ID != "p" || b.Log.Entries[0].Pageref != "p" {
+		t.Error("Merge renamed the pages of its input")
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	if _, err := Merge(&HAR{Log: &Log{Version: "1.2"}}, nil); err == nil || !strings.HasPrefix(err.Error(), "harfile: ") {
+		t.Errorf("nil input: err = %v", err)
+	}
+	bad := &HAR{Log: &Log{Version: "1.2"}}
+	bad.Log.Version = "one"
+	if _, err := Merge(bad); err == nil {
+		t.Error("invalid version accepted")
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"run1.har", "run2.har"} {
+		h := NewLog().Entries(NewEntry().Get("https://example.com/" + name).Build()).HAR()
+		path := filepath.Join(dir, name)
+		if err := h.WriteFile(path); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	h, err := MergeFiles(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range h.Log.Entries {
+		if want := TagMergedFrom + filepath.Base(paths[i]); !e.HasTag(want) {
+			t.Errorf("entry %d tags = %v, want %s", i, e.Tags, want)
+		}
+	}
+	if _, err := MergeFiles([]string{filepath.Join(dir, "missing.har")}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package harfile
+
+import "net/http"
+
+// Normalize fills in the members the spec makes mandatory, for consumers
+// that reject null arrays and missing objects, as left by structs built by
+// hand:
+//
+//   - a missing log is created, with version "1.2" and the harkit creator
+//     when they are missing too;
+//   - missing cookie, header, query string, entry and post data parameter
+//     arrays become empty ones;
+//   - missing requests, responses, contents, caches, timings and page
+//     timings get their empty form, timings counting the entry time as
+//     wait and page timings being -1 (not available);
+//   - header sizes of 0, impossible for a real message, and sizes below -1
+//     become -1 (not available);
+//   - a missing status text is derived from the status.
+//
+// Nil entries and pages are left alone. Normalize is idempotent: calling
+// it on its own result changes nothing.
+func (h *HAR) Normalize() {
+	if h.Log == nil {
+		h.Log = &Log{}
+	}
+	l := h.Log
+	if l.Version == "" {
+		l.Version = "1.2"
+	}
+	if l.Creator == nil {
+		l.Creator = NewCreator()
+	}
+	if l.Entries == nil {
+		l.Entries = []*Entry{}
+	}
+	for _, p := range l.Pages {
+		if p != nil && p.PageTimings == nil {
+			p.PageTimings = &PageTimings{OnContentLoad: -1, OnLoad: -1}
+		}
+	}
+	noWarn := func(string, string, ...any) {}
+	for _, e := range l.Entries {
+		if e == nil {
+			continue
+		}
+		if e.Request == nil {
+			e.Request = &Request{}
+		}
+		if e.Response == nil {
+			e.Response = &Response{}
+		}
+		if e.Cache == nil {
+			e.Cache = &Cache{}
+		}
+		if e.Timings == nil {
+			e.Timings = &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Wait: max(e.Time, 0)}
+		}
+
+		req := e.Request
+		fillArrays("", &req.Cookies, &req.Headers, noWarn)
+		if req.QueryString == nil {
+			req.QueryString = []*NameValuePair{}
+		}
+		if req.PostData != nil && req.PostData.Params == nil {
+			req.PostData.Params = []*Param{}
+		}
+		req.HeadersSize = unknownSize(req.HeadersSize, true)
+		req.BodySize = unknownSize(req.BodySize, false)
+
+		resp := e.Response
+		fillArrays("", &resp.Cookies, &resp.Headers, noWarn)
+		if resp.Content == nil {
+			resp.Content = &Content{Size: SizeUnknown, MimeType: headerValue(resp.Headers, "Content-Type")}
+			if size, ok := resp.KnownBodySize(); ok {
+				resp.Content.Size = size
+			}
+		}
+		if resp.StatusText == "" && resp.Status != 0 {
+			resp.StatusText = http.StatusText(int(resp.Status))
+		}
+		resp.HeadersSize = unknownSize(resp.HeadersSize, true)
+		resp.BodySize = unknownSize(resp.BodySize, false)
+	}
+}
+
+// unknownSize returns -1 for a size that cannot be right: below -1, or 0
+// for the size of headers.
+func unknownSize(n int64, headers bool) int64 {
+	if n < -1 || headers && n == 0 {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		har  *HAR
+		want []string // Fragments of the compact encoding.
+	}{
+		{
+			name: "empty",
+			har:  &HAR{},
+			want: []string{`"version":"1.2"`, `"creator":{"name":"harkit"`, `"entries":[]`},
+		},
+		{
+			name: "kept version and creator",
+			har:  &HAR{Log: &Log{Version: "1.1", Creator: &Creator{Name: "tool", Version: "2"}}},
+			want: []string{`"version":"1.1"`, `"creator":{"name":"tool","version":"2"}`},
+		},
+		{
+			name: "bare entry",
+			har:  &HAR{Log: &Log{Entries: []*Entry{{Time: 12}}}},
+			want: []string{
+				`"request":{"method":"","url":"","httpVersion":"","cookies":[],"headers":[],"queryString":[],"headersSize":-1,"bodySize":0}`,
+				`"response":{"status":0,"statusText":"","httpVersion":"","cookies":[],"headers":[],"content":{"size":0,"mimeType":""},"redirectURL":"","headersSize":-1,"bodySize":0}`,
+				`"cache":{}`,
+				`"timings":{"blocked":-1,"dns":-1,"connect":-1,"send":0,"wait":12,"receive":0,"ssl":-1}`,
+			},
+		},
+		{
+			name: "content from headers",
+			har: &HAR{Log: &Log{Entries: []*Entry{{
+				Request:  &Request{Method: "POST", PostData: &PostData{MimeType: "text/plain", Text: "a"}, HeadersSize: -7, BodySize: 1},
+				Response: &Response{Status: 404, Headers: []*NameValuePair{{Name: "content-type", Value: "text/html"}}, BodySize: 9, HeadersSize: 20},
+			}}}},
+			want: []string{
+				`"postData":{"mimeType":"text/plain","params":[],"text":"a"}`,
+				`"headersSize":-1,"bodySize":1`,
+				`"statusText":"Not Found"`,
+				`"content":{"size":9,"mimeType":"text/html"}`,
+				`"headersSize":20,"bodySize":9`,
+			},
+		},
+		{
+			name: "pages",
+			har:  &HAR{Log: &Log{Pages: []*Page{{ID: "p"}, nil}}},
+			want: []string{`"pageTimings":{"onContentLoad":-1,"onLoad":-1}`, `null]`},
+		},
+		{
+			name: "nil entry kept",
+			har:  &HAR{Log: &Log{Entries: []*Entry{nil}}},
+			want: []string{`"entries":[null]`},
+		},
+		{
+			name: "content of unknown size",
+			har:  &HAR{Log: &Log{Entries: []*Entry{{Response: &Response{BodySize: -1}}}}},
+			want: []string{`"content":{"size":-1,"mimeType":""}`},
+		},
+		{
+			name: "unknown status text kept empty",
+			har:  &HAR{Log: &Log{Entries: []*Entry{{Response: &Response{Status: 599}}}}},
+			want: []string{`"status":599,"statusText":""`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.har.Normalize()
+			data, err := json.Marshal(tt.har)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !bytes.Contains(data, []byte(w)) {
+					t.Errorf("%s missing from %s", w, data)
+				}
+			}
+			if bytes.Contains(data, []byte(`":null`)) {
+				t.Errorf("null member left in %s", data)
+			}
+		})
+	}
+}
+
+// TestNormalizeIdempotent normalizes random logs, some of their members
+// nil, and checks that normalizing again, or after a round trip, changes
+// nothing.
+func TestNormalizeIdempotent(t *testing.T) {
+	rng := rand.New(rand.NewPCG(9, 10))
+	for i := range 100 {
+		h := new(HAR)
+		fill(rng, reflect.ValueOf(h).Elem(), 0)
+		h.Normalize()
+		once, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Normalize()
+		twice, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(once, twice) {
+			t.Fatalf("log %d: second Normalize changed the log:\n%s\n%s", i, once, twice)
+		}
+
+		back, err := Load(bytes.NewReader(once))
+		if err != nil {
+			t.Fatal(err)
+		}
+		back.Normalize()
+		again, err := json.Marshal(back)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(once, again) {
+			t.Fatalf("log %d: Normalize after a round trip changed the log:\n%s\n%s", i, once, again)
+		}
+	}
+}
+
+func TestNormalizeValidates(t *testing.T) {
+	h := &HAR{Log: &Log{Entries: []*Entry{{
+		StartedDateTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Time:            5,
+		Request:         &Request{Method: "GET", URL: "https://example.com/", HTTPVersion: "HTTP/1.1"},
+		Response:        &Response{Status: 204, HTTPVersion: "HTTP/1.1"},
+	}}}}
+	if err := h.Validate(); err == nil {
+		t.Error("hand-built log validates before Normalize")
+	}
+	h.Normalize()
+	if err := h.Validate(); err != nil {
+		t.Errorf("Validate after Normalize: %v", err)
+	}
+}
+
+func TestMarshalWithNormalize(t *testing.T) {
+	h := &HAR{Log: &Log{Entries: []*Entry{{Request: &Request{URL: "https://example.com/"}}}}}
+	plain, err := h.MarshalWith(MarshalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, err := h.MarshalWith(MarshalOptions{Normalize: true, SortHeaders: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(plain), `"cache":null`) || strings.Contains(string(normalized), "null") {
+		t.Errorf("MarshalWith = %s\nwith Normalize = %s", plain, normalized)
+	}
+	if h.Log.Entries[0].Cache != nil || h.Log.Version != "" {
+		t.Error("MarshalWith with Normalize modified the log")
+	}
+}
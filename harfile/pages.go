@@ -0,0 +1,41 @@
+package harfile
+
+import (
+	"strconv"
+	"time"
+)
+
+// AddPage appends a page to l and returns it. An empty id is replaced by
+// "page_N", N being the position of the page, and an id already used by
+// another page gets a "-N" suffix. Page timings start as -1, not available.
+func (l *Log) AddPage(id, title string, start time.Time) *Page {
+	taken := make(map[string]bool, len(l.Pages))
+	for _, p := range l.Pages {
+		if p != nil {
+			taken[p.ID] = true
+		}
+	}
+	if id == "" {
+		id = "page_" + strconv.Itoa(len(l.Pages)+1)
+	}
+	if taken[id] {
+		id = nextFreeID(id, taken)
+	}
+	p := &Page{
+		StartedDateTime: start,
+		ID:              id,
+		Title:           title,
+		PageTimings:     &PageTimings{OnContentLoad: -1, OnLoad: -1},
+	}
+	l.Pages = append(l.Pages, p)
+	return p
+}
+
+// AttachTo makes page the parent page of e. A nil page detaches e.
+func (e *Entry) AttachTo(page *Page) {
+	if page == nil {
+		e.Pageref = ""
+		return
+	}
+	e.Pageref = page.ID
+}
@@ -0,0 +1,52 @@
+package harfile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddPage(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := &Log{}
+	tests := []struct {
+		id, want string
+	}{
+		{"", "page_1"},
+		{"home", "home"},
+		{"home", "home-2"},
+		{"", "page_4"},
+		{"page_4", "page_4-2"},
+		{"home", "home-3"},
+	}
+	for _, tt := range tests {
+		p := l.AddPage(tt.id, "title", start)
+		if p.ID != tt.want {
+			t.Errorf("AddPage(%q) id = %q, want %q", tt.id, p.ID, tt.want)
+		}
+		if l.Pages[len(l.Pages)-1] != p {
+			t.Error("page not appended")
+		}
+	}
+	p := l.Pages[0]
+	if p.Title != "title" || !p.StartedDateTime.Equal(start) || p.PageTimings.OnLoad != -1 || p.PageTimings.OnContentLoad != -1 {
+		t.Errorf("page = %+v, timings %+v", p, p.PageTimings)
+	}
+
+	// Nil pages leave a hole in the numbering but do not clash.
+	l = &Log{Pages: []*Page{nil, {ID: "page_3"}}}
+	if p := l.AddPage("", "", start); p.ID != "page_3-2" {
+		t.Errorf("id = %q", p.ID)
+	}
+}
+
+func TestAttachTo(t *testing.T) {
+	e := &Entry{}
+	e.AttachTo(&Page{ID: "page_1"})
+	if e.Pageref != "page_1" {
+		t.Errorf("Pageref = %q", e.Pageref)
+	}
+	e.AttachTo(nil)
+	if e.Pageref != "" {
+		t.Errorf("detached Pageref = %q", e.Pageref)
+	}
+}
@@ -0,0 +1,210 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxMultipartSize is the number of part bytes [PostDataFromBody] copies into
+// Params from a multipart body. Parts past the limit are truncated, the raw
+// Text is dropped and the PostData comment says so.
+var MaxMultipartSize int64 = 10 << 20
+
+// PostDataFromBody describes a request body of the given Content-Type.
+//
+// URL encoded forms keep the raw Text and get their decoded pairs in Params.
+// Multipart forms get one Param per part, with text values inline and binary
+// values base64 encoded, and keep the raw Text when it is valid UTF-8 and no
+// part was truncated. Other bodies only set MimeType and Text, base64 encoded
+// when not valid UTF-8.
+// Base64 values and texts have their Encoding set to "base64".
+func PostDataFromBody(contentType string, body []byte) (*PostData, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		pd := &PostData{MimeType: contentType, Params: []*Param{}, Text: string(body)}
+		for _, q := range queryFromRaw(pd.Text) {
+			pd.Params = append(pd.Params, &Param{Name: q.Name, Value: q.Value, Comment: q.Comment})
+		}
+		return pd, nil
+	case "multipart/form-data":
+		return multipartPostData(contentType, params["boundary"], body)
+	}
+	return rawPostData(contentType, body), nil
+}
+
+func rawPostData(contentType string, body []byte) *PostData {
+	pd := &PostData{MimeType: contentType, Params: []*Param{}}
+	if utf8.Valid(body) {
+		pd.Text = string(body)
+	} else {
+		pd.Text = base64.StdEncoding.EncodeToString(body)
+		pd.Encoding = "base64"
+	}
+	return pd
+}
+
+func multipartPostData(contentType, boundary string, body []byte) (*PostData, error) {
+	if boundary == "" {
+		return nil, errors.New("harfile: multipart body without boundary")
+	}
+	pd := &PostData{MimeType: contentType, Params: []*Param{}}
+	if utf8.Valid(body) {
+		pd.Text = string(body)
+	}
+
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	remaining := MaxMultipartSize
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("harfile: read multipart body: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(part, remaining+1))
+		if err != nil {
+			return nil, fmt.Errorf("harfile: read multipart part %q: %w", part.FormName(), err)
+		}
+		truncated := int64(len(data)) > remaining
+		if truncated {
+			data = data[:remaining]
+		}
+		remaining -= int64(len(data))
+
+		p := &Param{
+			Name:        part.FormName(),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+		}
+		if utf8.Valid(data) {
+			p.Value = string(data)
+		} else {
+			p.Value = base64.StdEncoding.EncodeToString(data)
+			p.Encoding = "base64"
+		}
+		pd.Params = append(pd.Params, p)
+		if truncated {
+			// Text would replay the whole body while Params hold a prefix.
+			pd.Text = ""
+			pd.Comment = fmt.Sprintf("multipart body truncated after %d bytes", MaxMultipartSize)
+			break
+		}
+	}
+	return pd, nil
+}
+
+// BodyReader regenerates the request body described by pd, for replay, and
+// returns it with its Content-Type.
+//
+// Text is used when present, decoded as Encoding says.
+// Otherwise the body is rebuilt from Params: multipart forms reuse the
+// boundary of MimeType when it has one and get a new one otherwise, and
+// anything else is URL encoded.
+func (pd *PostData) BodyReader() (io.Reader, string, error) {
+	if pd.Text != "" {
+		data, err := pd.DecodedText()
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(data), pd.MimeType, nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(pd.MimeType)
+	if mediaType == "multipart/form-data" {
+		return pd.multipartBody(params["boundary"])
+	}
+
+	pairs := make([]string, 0, len(pd.Params))
+	for _, p := range pd.Params {
+		if p != nil {
+			pairs = append(pairs, url.QueryEscape(p.Name)+"="+url.QueryEscape(p.Value))
+		}
+	}
+	contentType := pd.MimeType
+	if contentType == "" && len(pairs) > 0 {
+		contentType = "application/x-www-form-urlencoded"
+	}
+	return strings.NewReader(strings.Join(pairs, "&")), contentType, nil
+}
+
+// DecodedText returns the body held by Text, base64 decoded when Encoding
+// says so.
+func (pd *PostData) DecodedText() ([]byte, error) {
+	return decodeText(pd.Text, pd.Encoding, "post data")
+}
+
+// DecodedValue returns the value of p, base64 decoded when Encoding says
+// so.
+func (p *Param) DecodedValue() ([]byte, error) {
+	return decodeText(p.Value, p.Encoding, "param")
+}
+
+func decodeText(text, encoding, what string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "":
+		return []byte(text), nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("harfile: decode %s: %w", what, err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: %s encoding %q", ErrUnsupportedEncoding, what, encoding)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (pd *PostData) multipartBody(boundary string) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if boundary != "" {
+		if err := w.SetBoundary(boundary); err != nil {
+			return nil, "", fmt.Errorf("harfile: multipart boundary %q: %w", boundary, err)
+		}
+	}
+	for _, p := range pd.Params {
+		if p == nil {
+			continue
+		}
+		value, err := p.DecodedValue()
+		if err != nil {
+			return nil, "", fmt.Errorf("harfile: part %q: %w", p.Name, err)
+		}
+
+		h := make(textproto.MIMEHeader)
+		disposition := `form-data; name="` + quoteEscaper.Replace(p.Name) + `"`
+		if p.FileName != "" {
+			disposition += `; filename="` + quoteEscaper.Replace(p.FileName) + `"`
+		}
+		h.Set("Content-Disposition", disposition)
+		switch {
+		case p.ContentType != "":
+			h.Set("Content-Type", p.ContentType)
+		case p.FileName != "":
+			h.Set("Content-Type", "application/octet-stream")
+		}
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(value); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
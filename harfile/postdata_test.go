@@ -0,0 +1,198 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func TestPostDataFromBodyURLEncoded(t *testing.T) {
+	pd, err := PostDataFromBody("application/x-www-form-urlencoded", []byte("a=1&b=x+y&c=%26"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.Text != "a=1&b=x+y&c=%26" || pd.Encoding != "" {
+		t.Errorf("Text = %q, Encoding = %q", pd.Text, pd.Encoding)
+	}
+	var got []string
+	for _, p := range pd.Params {
+		got = append(got, p.Name+"="+p.Value)
+	}
+	if strings.Join(got, " ") != "a=1 b=x y c=&" {
+		t.Errorf("Params = %q", got)
+	}
+}
+
+func TestPostDataFromBodyRaw(t *testing.T) {
+	pd, err := PostDataFromBody("application/octet-stream", []byte{0xff, 0x00, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.Text != "/wAB" || pd.Encoding != "base64" {
+		t.Errorf("Text = %q, Encoding = %q, want base64", pd.Text, pd.Encoding)
+	}
+	pd, _ = PostDataFromBody("text/plain", []byte("héllo"))
+	if pd.Text != "héllo" || pd.Encoding != "" {
+		t.Errorf("Text = %q, Encoding = %q, want plain text", pd.Text, pd.Encoding)
+	}
+}
+
+func multipartBody(t *testing.T, parts ...[3]string) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		var part io.Writer
+		var err error
+		if p[1] != "" {
+			part, err = w.CreateFormFile(p[0], p[1])
+		} else {
+			part, err = w.CreateFormField(p[0])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(part, p[2])
+	}
+	w.Close()
+	return buf.Bytes(), w.FormDataContentType()
+}
+
+func TestPostDataFromBodyMultipart(t *testing.T) {
+	body, contentType := multipartBody(t,
+		[3]string{"title", "", "hello"},
+		[3]string{"file", "a.bin", "\xff\xfe\x00"},
+	)
+	pd, err := PostDataFromBody(contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pd.Params) != 2 {
+		t.Fatalf("Params = %+v", pd.Params)
+	}
+	if p := pd.Params[0]; p.Name != "title" || p.Value != "hello" || p.Encoding != "" {
+		t.Errorf("text part = %+v", p)
+	}
+	if p := pd.Params[1]; p.Name != "file" || p.FileName != "a.bin" || p.ContentType != "application/octet-stream" || p.Encoding != "base64" || p.Value != "//4A" {
+		t.Errorf("file part = %+v", p)
+	}
+	if pd.Text != "" {
+		t.Error("Text kept for a body that is not valid UTF-8")
+	}
+
+	// The body is rebuilt with the original boundary and binary part.
+	r, gotType, err := pd.BodyReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotType != contentType {
+		t.Errorf("Content-Type = %q, want %q", gotType, contentType)
+	}
+	_, params, _ := mime.ParseMediaType(gotType)
+	form, err := multipart.NewReader(r, params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := form.File["file"][0].Open()
+	data, _ := io.ReadAll(f)
+	if form.Value["title"][0] != "hello" || string(data) != "\xff\xfe\x00" {
+		t.Errorf("rebuilt form = %v, file %q", form.Value, data)
+	}
+}
+
+func TestPostDataFromBodyMultipartLimit(t *testing.T) {
+	defer func(n int64) { MaxMultipartSize = n }(MaxMultipartSize)
+	MaxMultipartSize = 8
+	body, contentType := multipartBody(t,
+		[3]string{"a", "", "12345"},
+		[3]string{"b", "", "67890"},
+		[3]string{"c", "", "never read"},
+	)
+	pd, err := PostDataFromBody(contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pd.Params) != 2 || pd.Params[1].Value != "678" {
+		t.Errorf("Params = %+v, want a and the first 3 bytes of b", pd.Params)
+	}
+	if !strings.Contains(pd.Comment, "truncated after 8 bytes") {
+		t.Errorf("Comment = %q, want a truncation marker", pd.Comment)
+	}
+	if pd.Text != "" {
+		t.Errorf("Text kept the whole body of %d bytes", len(pd.Text))
+	}
+}
+
+func TestPostDataBodyReaderInvalidBoundary(t *testing.T) {
+	pd := &PostData{
+		MimeType: `multipart/form-data; boundary="bad boundary "`,
+		Params:   []*Param{{Name: "a", Value: "1"}},
+	}
+	if _, _, err := pd.BodyReader(); err == nil || !strings.Contains(err.Error(), "boundary") {
+		t.Errorf("err = %v, want a boundary error", err)
+	}
+}
+
+func TestPostDataFromBodyMultipartWithoutBoundary(t *testing.T) {
+	if _, err := PostDataFromBody("multipart/form-data", []byte("x")); err == nil {
+		t.Error("want an error")
+	}
+}
+
+func TestPostDataEncodingSurvivesComment(t *testing.T) {
+	// A comment set afterwards, e.g. noting truncation, must not change how
+	// Text decodes.
+	pd, _ := PostDataFromBody("application/octet-stream", []byte{0xff, 0x00})
+	pd.Comment = "body truncated to 2 of 10 bytes"
+	data, err := pd.DecodedText()
+	if err != nil || !bytes.Equal(data, []byte{0xff, 0x00}) {
+		t.Errorf("DecodedText = %q, %v", data, err)
+	}
+
+	out, err := json.Marshal(pd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back PostData
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Encoding != "base64" || !strings.Contains(string(out), `"_encoding":"base64"`) {
+		t.Errorf("encoding lost in JSON: %s", out)
+	}
+}
+
+func TestPostDataBodyReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		pd       *PostData
+		body     string
+		mimeType string
+	}{
+		{"text", &PostData{MimeType: "text/plain", Text: "hi"}, "hi", "text/plain"},
+		{"base64", &PostData{MimeType: "application/octet-stream", Text: "/wA=", Encoding: "base64"}, "\xff\x00", "application/octet-stream"},
+		{"params", &PostData{Params: []*Param{{Name: "a b", Value: "&"}, nil}}, "a+b=%26", "application/x-www-form-urlencoded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mimeType, err := tt.pd.BodyReader()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(r)
+			if string(data) != tt.body || mimeType != tt.mimeType {
+				t.Errorf("BodyReader = %q %q, want %q %q", data, mimeType, tt.body, tt.mimeType)
+			}
+		})
+	}
+	if _, _, err := (&PostData{Text: "!", Encoding: "base64"}).BodyReader(); err == nil {
+		t.Error("invalid base64 text decoded")
+	}
+	if _, _, err := (&PostData{Text: "x", Encoding: "rot13"}).BodyReader(); err == nil {
+		t.Error("unknown encoding accepted")
+	}
+}
@@ -0,0 +1,171 @@
+package harfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress receives the progress of long operations, such as loading a
+// large document, see [WithProgress]. done counts the units of stage
+// processed so far, out of total, or -1 when the total is unknown, e.g.
+// for a streamed input. Implementations may be called from several
+// goroutines at once.
+type Progress interface {
+	Step(stage string, done, total int64)
+}
+
+// ProgressFunc adapts a function to a [Progress].
+type ProgressFunc func(stage string, done, total int64)
+
+// Step calls f(stage, done, total).
+func (f ProgressFunc) Step(stage string, done, total int64) {
+	f(stage, done, total)
+}
+
+// NoProgress reports nothing. It is the default of every operation taking
+// a Progress.
+var NoProgress Progress = ProgressFunc(func(string, int64, int64) {})
+
+// Stages reported by this package.
+const (
+	StageRead  = "read"  // Bytes of a document read, compressed ones for a compressed document.
+	StageWrite = "write" // Entries written by a StreamWriter, out of an unknown total.
+	StageMerge = "merge" // Files merged by MergeFiles.
+)
+
+// progressBytes is the least number of bytes read between two StageRead
+// steps, so that reads too small to matter are not reported.
+const progressBytes = 1 << 20
+
+// WithProgress reports the progress of loading, streaming and merging
+// documents to p: [StageRead] steps as a document is read, out of its size
+// when r is a file or an in-memory reader, [StageWrite] steps as a
+// [StreamWriter] writes entries, and [StageMerge] steps as [MergeFiles]
+// loads files. Read steps are at least 1 MiB apart.
+func WithProgress(p Progress) FileOption {
+	return func(o *fileOptions) {
+		o.progress = p
+	}
+}
+
+// countingReader counts the bytes read from r and reports them to progress,
+// when set.
+type countingReader struct {
+	r        io.Reader
+	n        atomic.Int64
+	progress Progress
+	total    int64
+	reported int64
+}
+
+func newCountingReader(r io.Reader, progress Progress) *countingReader {
+	return &countingReader{r: r, progress: progress, total: sizeOf(r), reported: -1}
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	done := c.n.Add(int64(n))
+	if c.progress != nil && (done-c.reported >= progressBytes || errors.Is(err, io.EOF)) {
+		c.report(done)
+	}
+	return n, err
+}
+
+// finish reports the bytes read since the last step, for decoders that stop
+// reading before io.EOF.
+func (c *countingReader) finish() {
+	if done := c.n.Load(); c.progress != nil && done != c.reported {
+		c.report(done)
+	}
+}
+
+func (c *countingReader) report(done int64) {
+	c.reported = done
+	c.progress.Step(StageRead, done, c.total)
+}
+
+// sizeOf returns the bytes left to read from r, or -1 when unknown.
+func sizeOf(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }: // bytes.Reader, strings.Reader, bytes.Buffer.
+		return int64(r.Len())
+	case interface{ Stat() (fs.FileInfo, error) }: // os.File.
+		if fi, err := r.Stat(); err == nil && fi.Mode().IsRegular() {
+			if s, ok := r.(io.Seeker); ok {
+				if offset, err := s.Seek(0, io.SeekCurrent); err == nil {
+					return fi.Size() - offset
+				}
+			}
+			return fi.Size()
+		}
+	}
+	return -1
+}
+
+// TerminalProgress returns a Progress rendering a single progress line on
+// w, typically os.Stderr, rewritten in place with a carriage return at most
+// ten times a second, and ended by a newline when a stage completes. Read
+// stages are shown in bytes.
+func TerminalProgress(w io.Writer) Progress {
+	return &terminalProgress{w: w}
+}
+
+type terminalProgress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	stage string
+	last  time.Time
+	width int
+}
+
+func (t *terminalProgress) Step(stage string, done, total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	complete := total >= 0 && done >= total
+	now := time.Now()
+	if stage == t.stage && !complete && now.Sub(t.last) < 100*time.Millisecond {
+		return
+	}
+	t.stage, t.last = stage, now
+
+	count := func(n int64) string {
+		if stage == StageRead {
+			return formatSize(n)
+		}
+		return fmt.Sprint(n)
+	}
+	line := fmt.Sprintf("%s %s", stage, count(done))
+	if total >= 0 {
+		percent := int64(100)
+		if total > 0 {
+			percent = min(done*100/total, 100)
+		}
+		line += fmt.Sprintf(" / %s (%d%%)", count(total), percent)
+	}
+	pad := max(t.width-len(line), 0)
+	t.width = len(line)
+	fmt.Fprintf(t.w, "\r%s%*s", line, pad, "")
+	if complete {
+		fmt.Fprintln(t.w)
+		t.stage, t.width = "", 0
+	}
+}
+
+// formatSize renders n bytes with a binary unit, e.g. "12.5 MiB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
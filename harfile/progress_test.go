@@ -0,0 +1,273 @@
+package harfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// progressSteps records the steps it receives.
+type progressSteps struct {
+	mu    sync.Mutex
+	steps []string
+	done  []int64
+}
+
+func (p *progressSteps) Step(stage string, done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, fmt.Sprintf("%s %d/%d", stage, done, total))
+	p.done = append(p.done, done)
+}
+
+func (p *progressSteps) last() string {
+	if len(p.steps) == 0 {
+		return ""
+	}
+	return p.steps[len(p.steps)-1]
+}
+
+// onlyReader hides the Len method of a reader, so that its size is
+// unknown.
+type onlyReader struct{ io.Reader }
+
+func TestWithProgressLoad(t *testing.T) {
+	data := bodiesHAR(t, 30, 256<<10) // About 8 MiB.
+	var p progressSteps
+	if _, err := Load(bytes.NewReader(data), WithProgress(&p)); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("read %d/%d", len(data), len(data)); p.last() != want {
+		t.Errorf("last step %q, want %q", p.last(), want)
+	}
+	if len(p.steps) < 4 {
+		t.Errorf("steps = %q", p.steps)
+	}
+	for i := 1; i < len(p.done)-1; i++ {
+		if p.done[i]-p.done[i-1] < progressBytes {
+			t.Errorf("steps %d and %d are %d bytes apart", i-1, i, p.done[i]-p.done[i-1])
+		}
+	}
+
+	// A stream has an unknown size.
+	p = progressSteps{}
+	if _, err := Load(onlyReader{bytes.NewReader(data)}, WithProgress(&p)); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("read %d/-1", len(data)); p.last() != want {
+		t.Errorf("last step of a stream %q, want %q", p.last(), want)
+	}
+
+	// A small document still reports once.
+	p = progressSteps{}
+	if _, err := Load(strings.NewReader(versionDoc("1.2")), WithProgress(&p)); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.steps) != 1 || !strings.HasPrefix(p.last(), "read ") {
+		t.Errorf("steps of a small document = %q", p.steps)
+	}
+}
+
+func TestWithProgressFile(t *testing.T) {
+	data := bodiesHAR(t, 3, 1000)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "a.har.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Compressed bytes are counted, out of the size of the file.
+	var p progressSteps
+	if _, err := LoadFile(path, WithProgress(&p)); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("read %d/%d", buf.Len(), buf.Len()); p.last() != want {
+		t.Errorf("last step %q, want %q", p.last(), want)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Seek(10, io.SeekStart)
+	if got := sizeOf(f); got != int64(buf.Len()-10) {
+		t.Errorf("size of a file read from offset 10 = %d", got)
+	}
+	if got := sizeOf(onlyReader{f}); got != -1 {
+		t.Errorf("size of a stream = %d", got)
+	}
+}
+
+func TestEntryReaderProgress(t *testing.T) {
+	data := bodiesHAR(t, 9, 1000)
+	var p progressSteps
+	er := NewEntryReader(bytes.NewReader(data), WithProgress(&p))
+	if er.BytesRead() != 0 || er.EntriesRead() != 0 {
+		t.Errorf("read %d bytes and %d entries before Next", er.BytesRead(), er.EntriesRead())
+	}
+	if _, err := er.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if er.BytesRead() == 0 || er.EntriesRead() != 1 {
+		t.Errorf("read %d bytes and %d entries after one Next", er.BytesRead(), er.EntriesRead())
+	}
+	for {
+		if _, err := er.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if er.EntriesRead() != 9 || er.BytesRead() != int64(len(data)) {
+		t.Errorf("read %d bytes and %d entries, want %d and 9", er.BytesRead(), er.EntriesRead(), len(data))
+	}
+	if want := fmt.Sprintf("read %d/%d", len(data), len(data)); p.last() != want {
+		t.Errorf("last step %q, want %q", p.last(), want)
+	}
+
+	// Without the option, bytes are counted all the same.
+	er = NewEntryReader(bytes.NewReader(data))
+	for range er.All() {
+	}
+	if er.BytesRead() != int64(len(data)) {
+		t.Errorf("read %d bytes without progress", er.BytesRead())
+	}
+}
+
+func TestStreamWriterProgress(t *testing.T) {
+	var p progressSteps
+	sw := NewStreamWriter(io.Discard, NewCreator(), WithProgress(&p))
+	for i := range 3 {
+		if err := sw.WriteEntry(streamEntry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sw.Close()
+	if got := strings.Join(p.steps, ", "); got != "write 1/-1, write 2/-1, write 3/-1" {
+		t.Errorf("steps = %q", got)
+	}
+}
+
+func TestMergeFilesProgress(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.har", "b.har"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, bodiesHAR(t, 1, 10), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	var p progressSteps
+	if _, err := MergeFiles(paths, WithProgress(&p)); err != nil {
+		t.Fatal(err)
+	}
+	var merges []string
+	for _, s := range p.steps {
+		if strings.HasPrefix(s, StageMerge) {
+			merges = append(merges, s)
+		}
+	}
+	if got := strings.Join(merges, ", "); got != "merge 1/2, merge 2/2" {
+		t.Errorf("merge steps = %q", got)
+	}
+}
+
+func TestTerminalProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := TerminalProgress(&buf)
+	p.Step(StageRead, 512, 3<<20)
+	p.Step(StageRead, 1<<20, 3<<20) // Within 100ms of the previous step.
+	p.Step(StageRead, 3<<20, 3<<20)
+	p.Step("replay", 7, -1)
+	p.Step("replay", 8, -1)
+	p.Step("export", 0, 0)
+	want := "\rread 512 B / 3.0 MiB (0%)" +
+		"\rread 3.0 MiB / 3.0 MiB (100%)\n" +
+		"\rreplay 7" +
+		"\rexport 0 / 0 (100%)\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	// A shorter line blanks the end of the previous one.
+	buf.Reset()
+	p.Step("replay", 1000, 2000)
+	p.Step("extract", 1, 2)
+	if want := "\rreplay 1000 / 2000 (50%)\rextract 1 / 2 (50%)     "; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1024:             "1.0 KiB",
+		1536:             "1.5 KiB",
+		5 << 20:          "5.0 MiB",
+		3 << 30:          "3.0 GiB",
+		1<<40 + 1<<39:    "1.5 TiB",
+		1<<62 + 1<<61:    "6.0 EiB",
+		12*1<<20 + 1<<19: "12.5 MiB",
+	} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// benchmarkOverhead times load without and with a Progress in turn, so
+// that both runs see the same machine load, and reports the extra time
+// taken with one in percent.
+func benchmarkOverhead(b *testing.B, load func(data []byte, opts ...FileOption) error) {
+	data := benchmarkHAR(b, 2000, false)
+	progress := WithProgress(ProgressFunc(func(string, int64, int64) {}))
+	var without, with time.Duration
+	b.SetBytes(2 * int64(len(data)))
+	b.ReportAllocs()
+	for range b.N {
+		start := time.Now()
+		if err := load(data); err != nil {
+			b.Fatal(err)
+		}
+		mid := time.Now()
+		if err := load(data, progress); err != nil {
+			b.Fatal(err)
+		}
+		without, with = without+mid.Sub(start), with+time.Since(mid)
+	}
+	b.ReportMetric(100*float64(with-without)/float64(without), "%overhead")
+}
+
+// BenchmarkLoadProgress measures what a Progress adds to Load, which must
+// stay under 2%.
+func BenchmarkLoadProgress(b *testing.B) {
+	benchmarkOverhead(b, func(data []byte, opts ...FileOption) error {
+		_, err := Load(bytes.NewReader(data), opts...)
+		return err
+	})
+}
+
+// BenchmarkEntryReaderProgress does the same for an EntryReader.
+func BenchmarkEntryReaderProgress(b *testing.B) {
+	benchmarkOverhead(b, func(data []byte, opts ...FileOption) error {
+		for _, err := range NewEntryReader(bytes.NewReader(data), opts...).All() {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+package harfile
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RawValueComment is set on query and form parameters whose name or value
+// is not valid percent-encoding. Such parameters are kept verbatim rather
+// than dropped, and [Request.SyncURL] writes them back unchanged.
+const RawValueComment = "invalid percent-encoding, kept raw"
+
+// ParseQueryString replaces QueryString with the parameters of the URL query,
+// in order and including repeated names. Both '&' and ';' separate
+// parameters, and a name without '=' gets an empty value.
+func (r *Request) ParseQueryString() error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	r.QueryString = parseQuery(u.RawQuery, "&;")
+	return nil
+}
+
+// SyncURL rewrites the query component of URL from QueryString, leaving the
+// rest of the URL untouched. The query is removed when QueryString is empty.
+func (r *Request) SyncURL() {
+	base, fragment, hasFragment := strings.Cut(r.URL, "#")
+	base, _, _ = strings.Cut(base, "?")
+
+	var b strings.Builder
+	b.WriteString(base)
+	sep := byte('?')
+	for _, q := range r.QueryString {
+		if q == nil {
+			continue
+		}
+		b.WriteByte(sep)
+		sep = '&'
+		if q.Comment == RawValueComment {
+			b.WriteString(q.Name)
+			b.WriteByte('=')
+			b.WriteString(q.Value)
+			continue
+		}
+		b.WriteString(url.QueryEscape(q.Name))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(q.Value))
+	}
+	if hasFragment {
+		b.WriteByte('#')
+		b.WriteString(fragment)
+	}
+	r.URL = b.String()
+}
+
+// queryFromRaw decodes an application/x-www-form-urlencoded body, preserving
+// parameter order and repeated names.
+func queryFromRaw(raw string) []*NameValuePair {
+	return parseQuery(raw, "&")
+}
+
+// parseQuery splits raw on any of seps and decodes each name=value pair.
+// Pairs that cannot be decoded are kept raw and flagged with
+// [RawValueComment].
+func parseQuery(raw, seps string) []*NameValuePair {
+	pairs := []*NameValuePair{}
+	for part := range strings.FieldsFuncSeq(raw, func(c rune) bool { return strings.ContainsRune(seps, c) }) {
+		name, value, _ := strings.Cut(part, "=")
+		n, errName := url.QueryUnescape(name)
+		v, errValue := url.QueryUnescape(value)
+		if errName != nil || errValue != nil {
+			pairs = append(pairs, &NameValuePair{Name: name, Value: value, Comment: RawValueComment})
+			continue
+		}
+		pairs = append(pairs, &NameValuePair{Name: n, Value: v})
+	}
+	return pairs
+}
@@ -0,0 +1,77 @@
+package harfile
+
+import "testing"
+
+func TestParseQueryString(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want []NameValuePair
+	}{
+		{"ampersand", "https://example.com/?a=1&b=2", []NameValuePair{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}},
+		{"semicolon", "https://example.com/?a=1;b=2&c=3", []NameValuePair{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}, {Name: "c", Value: "3"}}},
+		{"repeated names", "https://example.com/?a=1&a=2", []NameValuePair{{Name: "a", Value: "1"}, {Name: "a", Value: "2"}}},
+		{"no value", "https://example.com/?flag&x=", []NameValuePair{{Name: "flag"}, {Name: "x"}}},
+		{"decoded", "https://example.com/?q=a+b%26c&%C3%A9=1", []NameValuePair{{Name: "q", Value: "a b&c"}, {Name: "é", Value: "1"}}},
+		{"invalid escape kept raw", "https://example.com/?p=100%&ok=1", []NameValuePair{{Name: "p", Value: "100%", Comment: RawValueComment}, {Name: "ok", Value: "1"}}},
+		{"invalid name kept raw", "https://example.com/?%zz=1", []NameValuePair{{Name: "%zz", Value: "1", Comment: RawValueComment}}},
+		{"empty pairs skipped", "https://example.com/?&&a=1;;", []NameValuePair{{Name: "a", Value: "1"}}},
+		{"no query", "https://example.com/", []NameValuePair{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Request{URL: tt.url}
+			if err := r.ParseQueryString(); err != nil {
+				t.Fatal(err)
+			}
+			if r.QueryString == nil || len(r.QueryString) != len(tt.want) {
+				t.Fatalf("QueryString = %v, want %v", r.QueryString, tt.want)
+			}
+			for i, q := range r.QueryString {
+				if *q != tt.want[i] {
+					t.Errorf("param %d = %+v, want %+v", i, *q, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if err := (&Request{URL: "http://[::1"}).ParseQueryString(); err == nil {
+		t.Error("no error for an invalid URL")
+	}
+}
+
+func TestSyncURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   string
+		query []*NameValuePair
+		want  string
+	}{
+		{"replaced", "https://example.com/p?old=1#top", []*NameValuePair{{Name: "a", Value: "x y"}, {Name: "b", Value: "&"}}, "https://example.com/p?a=x+y&b=%26#top"},
+		{"removed", "https://example.com/p?old=1", nil, "https://example.com/p"},
+		{"added", "https://example.com/p#frag", []*NameValuePair{{Name: "a", Value: "1"}}, "https://example.com/p?a=1#frag"},
+		{"raw kept", "https://example.com/p", []*NameValuePair{{Name: "p", Value: "100%", Comment: RawValueComment}, nil}, "https://example.com/p?p=100%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Request{URL: tt.url, QueryString: tt.query}
+			r.SyncURL()
+			if r.URL != tt.want {
+				t.Errorf("URL = %q, want %q", r.URL, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryStringRoundTrip(t *testing.T) {
+	// Separators are normalized to '&', everything else survives a parse
+	// and sync, including pairs that were not valid percent-encoding.
+	r := &Request{URL: "https://example.com/s?q=a+b;p=100%&%zz=1&x=%C3%A9#f"}
+	if err := r.ParseQueryString(); err != nil {
+		t.Fatal(err)
+	}
+	r.SyncURL()
+	if want := "https://example.com/s?q=a+b&p=100%&%zz=1&x=%C3%A9#f"; r.URL != want {
+		t.Errorf("URL = %q, want %q", r.URL, want)
+	}
+}
@@ -0,0 +1,239 @@
+package harfile
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DumpHTTP1 renders r as an HTTP/1.1 message: request line, headers in
+// their recorded order and casing, blank line and body.
+//
+// Requests recorded over HTTP/2 or HTTP/3 are rendered as if sent over
+// HTTP/1.1 under a leading "#" comment line, as used by .http files: pseudo
+// headers are dropped, the Host header comes from :authority or the URL,
+// and a Content-Length is added when the body has no framing. Bodies sent
+// with Transfer-Encoding: chunked are re-chunked.
+func (r *Request) DumpHTTP1() ([]byte, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, fmt.Errorf("harfile: dump request: %w", err)
+	}
+	var body []byte
+	if r.PostData != nil {
+		br, _, err := r.PostData.BodyReader()
+		if err != nil {
+			return nil, err
+		}
+		if body, err = io.ReadAll(br); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	version := r.HTTPVersion
+	if !isHTTP1(version) {
+		fmt.Fprintf(&buf, "# %s request rendered as HTTP/1.1\r\n", version)
+		version = ""
+	}
+	fmt.Fprintf(&buf, "%s %s %s\r\n", r.Method, u.RequestURI(), cmp.Or(version, "HTTP/1.1"))
+
+	headers := r.Headers
+	if !isHTTP1(r.HTTPVersion) {
+		headers = make([]*NameValuePair, 0, len(r.Headers)+2)
+		if !hasHeader(r.Headers, "Host") {
+			host := u.Host
+			for _, p := range r.PseudoHeaders() {
+				if p.Name == ":authority" {
+					host = p.Value
+				}
+			}
+			headers = append(headers, &NameValuePair{Name: "Host", Value: host})
+		}
+		headers = append(headers, r.Headers...)
+		if len(body) > 0 && !hasHeader(headers, "Content-Length") && !hasHeader(headers, "Transfer-Encoding") {
+			headers = append(headers, &NameValuePair{Name: "Content-Length", Value: strconv.Itoa(len(body))})
+		}
+	}
+	return dumpMessage(&buf, headers, body)
+}
+
+// DumpHTTP1 renders r as an HTTP/1.1 message: status line, headers in
+// their recorded order and casing, blank line and body.
+//
+// The body is the decoded content, so Content-Encoding is dropped and
+// Content-Length, when present, is set to its length; bodies sent with
+// Transfer-Encoding: chunked are re-chunked. Responses received over
+// HTTP/2 or HTTP/3 are rendered as if received over HTTP/1.1 under a
+// leading "#" comment line.
+func (r *Response) DumpHTTP1() ([]byte, error) {
+	var body []byte
+	if r.Content != nil {
+		var err error
+		if body, err = r.Content.DecodeBody(headerValue(r.Headers, "Content-Encoding")); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	version := r.HTTPVersion
+	if !isHTTP1(version) {
+		fmt.Fprintf(&buf, "# %s response rendered as HTTP/1.1\r\n", version)
+		version = ""
+	}
+	fmt.Fprintf(&buf, "%s %d %s\r\n", cmp.Or(version, "HTTP/1.1"), r.Status, cmp.Or(r.StatusText, http.StatusText(int(r.Status))))
+
+	headers := make([]*NameValuePair, 0, len(r.Headers)+1)
+	for _, h := range r.Headers {
+		switch {
+		case h == nil || strings.EqualFold(h.Name, "Content-Encoding"):
+		case strings.EqualFold(h.Name, "Content-Length"):
+			headers = append(headers, &NameValuePair{Name: h.Name, Value: strconv.Itoa(len(body))})
+		default:
+			headers = append(headers, h)
+		}
+	}
+	if !isHTTP1(r.HTTPVersion) && len(body) > 0 && !hasHeader(headers, "Content-Length") && !hasHeader(headers, "Transfer-Encoding") {
+		headers = append(headers, &NameValuePair{Name: "Content-Length", Value: strconv.Itoa(len(body))})
+	}
+	return dumpMessage(&buf, headers, body)
+}
+
+// dumpMessage appends headers, skipping pseudo headers, and body to buf,
+// chunking the body when the headers ask for it.
+func dumpMessage(buf *bytes.Buffer, headers []*NameValuePair, body []byte) ([]byte, error) {
+	for _, h := range headers {
+		if h != nil && !strings.HasPrefix(h.Name, ":") {
+			fmt.Fprintf(buf, "%s: %s\r\n", h.Name, h.Value)
+		}
+	}
+	buf.WriteString("\r\n")
+	if !strings.EqualFold(headerValue(headers, "Transfer-Encoding"), "chunked") {
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+	w := httputil.NewChunkedWriter(buf)
+	if len(body) > 0 {
+		w.Write(body)
+	}
+	w.Close()
+	buf.WriteString("\r\n") // No trailers.
+	return buf.Bytes(), nil
+}
+
+// ParseRawRequest parses an HTTP/1.x request message, as rendered by
+// [Request.DumpHTTP1], keeping the header order and casing. Leading "#"
+// comment lines are skipped. The URL is rebuilt from the Host header,
+// with the https scheme for port 443 and http otherwise. Chunked bodies
+// are decoded; HeadersSize counts the message up to the blank line and
+// BodySize the decoded body.
+func ParseRawRequest(data []byte) (*Request, error) {
+	data = skipComments(data)
+	headers, headerSize, err := rawHeaders(data)
+	if err != nil {
+		return nil, fmt.Errorf("harfile: parse request: %w", err)
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("harfile: parse request: %w", err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("harfile: parse request body: %w", err)
+	}
+	if !req.URL.IsAbs() {
+		req.URL.Host = req.Host
+		req.URL.Scheme = "http"
+		if req.URL.Port() == "443" {
+			req.URL.Scheme = "https"
+		}
+	}
+
+	r, err := FromHTTPRequest(req, body)
+	if err != nil {
+		return nil, err
+	}
+	r.Headers = headers
+	r.HeadersSize = int64(headerSize)
+	r.BodySize = int64(len(body))
+	return r, nil
+}
+
+// ParseRawResponse parses an HTTP/1.x response message, as rendered by
+// [Response.DumpHTTP1], keeping the header order and casing. Leading "#"
+// comment lines are skipped. Chunked bodies are decoded and encoded
+// contents are decoded as by [FromHTTPResponse]; HeadersSize counts the
+// message up to the blank line.
+func ParseRawResponse(data []byte) (*Response, error) {
+	data = skipComments(data)
+	headers, headerSize, err := rawHeaders(data)
+	if err != nil {
+		return nil, fmt.Errorf("harfile: parse response: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("harfile: parse response: %w", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("harfile: parse response body: %w", err)
+	}
+
+	r, err := FromHTTPResponse(resp, body)
+	if err != nil {
+		return nil, err
+	}
+	r.Headers = headers
+	r.HeadersSize = int64(headerSize)
+	return r, nil
+}
+
+func skipComments(data []byte) []byte {
+	for len(data) > 0 && (data[0] == '#' || data[0] == '\r' || data[0] == '\n') {
+		_, rest, found := bytes.Cut(data, []byte("\n"))
+		if !found {
+			return nil
+		}
+		data = rest
+	}
+	return data
+}
+
+// rawHeaders returns the headers of the message in data, in order, and the
+// length of the message up to and including the blank line. Obsolete line
+// folding is unfolded.
+func rawHeaders(data []byte) ([]*NameValuePair, int, error) {
+	headers := []*NameValuePair{}
+	rest := data
+	first := true
+	for {
+		line, next, found := bytes.Cut(rest, []byte("\n"))
+		if !found {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		rest = next
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		switch {
+		case first:
+			first = false
+		case len(line) == 0:
+			return headers, len(data) - len(rest), nil
+		case (line[0] == ' ' || line[0] == '\t') && len(headers) > 0:
+			last := headers[len(headers)-1]
+			last.Value += " " + string(bytes.TrimSpace(line))
+		default:
+			name, value, ok := bytes.Cut(line, []byte(":"))
+			if !ok {
+				return nil, 0, fmt.Errorf("malformed header line %q", line)
+			}
+			headers = append(headers, &NameValuePair{Name: string(name), Value: string(bytes.TrimSpace(value))})
+		}
+	}
+}
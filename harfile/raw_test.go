@@ -0,0 +1,165 @@
+package harfile
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRequestDumpHTTP1(t *testing.T) {
+	pairs := func(kv ...string) []*NameValuePair {
+		var out []*NameValuePair
+		for i := 0; i+1 < len(kv); i += 2 {
+			out = append(out, &NameValuePair{Name: kv[i], Value: kv[i+1]})
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+		url  string // Parsed back from want, with the scheme guessed from the port.
+	}{
+		{
+			"http/1.1",
+			&Request{Method: "POST", URL: "https://example.com/login?next=%2F", HTTPVersion: "HTTP/1.1",
+				Headers:  pairs("Host", "example.com", "content-type", "application/x-www-form-urlencoded", "Content-Length", "8", "X-B", "2", "x-a", "1"),
+				PostData: &PostData{MimeType: "application/x-www-form-urlencoded", Text: "user=ada"}},
+			"POST /login?next=%2F HTTP/1.1\r\nHost: example.com\r\ncontent-type: application/x-www-form-urlencoded\r\nContent-Length: 8\r\nX-B: 2\r\nx-a: 1\r\n\r\nuser=ada",
+			"http://example.com/login?next=%2F",
+		},
+		{
+			"http/2",
+			&Request{Method: "PUT", URL: "https://example.com/items/1", HTTPVersion: "HTTP/2",
+				Headers:  pairs(":method", "PUT", ":authority", "api.example.com", ":path", "/items/1", "content-type", "application/json"),
+				PostData: &PostData{MimeType: "application/json", Text: `{"a":1}`}},
+			"# HTTP/2 request rendered as HTTP/1.1\r\nPUT /items/1 HTTP/1.1\r\nHost: api.example.com\r\ncontent-type: application/json\r\nContent-Length: 7\r\n\r\n{\"a\":1}",
+			"http://api.example.com/items/1",
+		},
+		{
+			"http/3 without body",
+			&Request{Method: "GET", URL: "https://example.com/", HTTPVersion: "h3"},
+			"# h3 request rendered as HTTP/1.1\r\nGET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			"http://example.com/",
+		},
+		{
+			"chunked",
+			&Request{Method: "POST", URL: "http://example.com/upload", HTTPVersion: "HTTP/1.1",
+				Headers:  pairs("Host", "example.com", "Transfer-Encoding", "chunked"),
+				PostData: &PostData{MimeType: "text/plain", Text: "hello world"}},
+			"POST /upload HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\nb\r\nhello world\r\n0\r\n\r\n",
+			"http://example.com/upload",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.req.DumpHTTP1()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("DumpHTTP1 =\n%q\nwant\n%q", got, tt.want)
+			}
+
+			back, err := ParseRawRequest(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var text string
+			if back.PostData != nil {
+				text = back.PostData.Text
+			}
+			if back.Method != tt.req.Method || back.URL != tt.url || back.HTTPVersion != "HTTP/1.1" {
+				t.Errorf("parsed %s %s %s", back.Method, back.URL, back.HTTPVersion)
+			}
+			if tt.req.PostData != nil && text != tt.req.PostData.Text {
+				t.Errorf("parsed body %q", text)
+			}
+		})
+	}
+
+	if _, err := (&Request{Method: "GET", URL: "http://[::1"}).DumpHTTP1(); err == nil {
+		t.Error("invalid URL dumped")
+	}
+}
+
+func TestParseRawRequest(t *testing.T) {
+	raw := "# saved from a proxy\r\n\r\nPOST /upload?kind=raw HTTP/1.1\r\nHost: example.com:443\r\nX-Long: a\r\n  b\r\nTransfer-Encoding: chunked\r\nContent-Type: text/plain\r\nCookie: id=1\r\n\r\n" +
+		"5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n"
+	r, err := ParseRawRequest([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.URL != "https://example.com:443/upload?kind=raw" || len(r.QueryString) != 1 || len(r.Cookies) != 1 {
+		t.Errorf("url %s, query %v, cookies %v", r.URL, r.QueryString, r.Cookies)
+	}
+	if got := headerNames(r.Headers); got != "Host X-Long Transfer-Encoding Content-Type Cookie" {
+		t.Errorf("headers = %s", got)
+	}
+	if r.Headers[1].Value != "a b" {
+		t.Errorf("folded header = %q", r.Headers[1].Value)
+	}
+	head := strings.Index(raw, "\r\n\r\n5") + 4 - len("# saved from a proxy\r\n\r\n")
+	if r.HeadersSize != int64(head) || r.BodySize != 11 || r.PostData.Text != "hello world" {
+		t.Errorf("headersSize %d, want %d, bodySize %d, text %q", r.HeadersSize, head, r.BodySize, r.PostData.Text)
+	}
+
+	for _, bad := range []string{"", "GET / HTTP/1.1\r\nHost: x\r\n", "GET / HTTP/1.1\r\nno colon\r\n\r\n", "BROKEN\r\n\r\n"} {
+		if _, err := ParseRawRequest([]byte(bad)); err == nil {
+			t.Errorf("ParseRawRequest(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestResponseDumpHTTP1(t *testing.T) {
+	body := gzipped(t, "hello world")
+	raw := "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Encoding: gzip\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\nX-Trace: a\r\n\r\n" + body
+	r, err := ParseRawResponse([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Content.Text != "hello world" || r.HeadersSize != int64(strings.Index(raw, "\r\n\r\n")+4) {
+		t.Errorf("text %q, headersSize %d", r.Content.Text, r.HeadersSize)
+	}
+	got, err := r.DumpHTTP1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 11\r\nX-Trace: a\r\n\r\nhello world"
+	if string(got) != want {
+		t.Errorf("DumpHTTP1 =\n%q\nwant\n%q", got, want)
+	}
+
+	chunked := "HTTP/1.1 201 Created\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n"
+	r, err = ParseRawResponse([]byte(chunked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != 201 || r.Content.Text != "hello world" {
+		t.Errorf("chunked = %d %q", r.Status, r.Content.Text)
+	}
+	got, err = r.DumpHTTP1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "HTTP/1.1 201 Created\r\nTransfer-Encoding: chunked\r\n\r\nb\r\nhello world\r\n0\r\n\r\n"; string(got) != want {
+		t.Errorf("chunked DumpHTTP1 =\n%q\nwant\n%q", got, want)
+	}
+
+	h2 := &Response{Status: 404, HTTPVersion: "HTTP/2", Headers: []*NameValuePair{{Name: "content-type", Value: "text/plain"}}, Content: &Content{Text: "gone"}}
+	got, err = h2.DumpHTTP1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# HTTP/2 response rendered as HTTP/1.1\r\nHTTP/1.1 404 Not Found\r\ncontent-type: text/plain\r\nContent-Length: 4\r\n\r\ngone"; string(got) != want {
+		t.Errorf("HTTP/2 DumpHTTP1 =\n%q\nwant\n%q", got, want)
+	}
+	back, err := ParseRawResponse(got)
+	if err != nil || back.Status != 404 || back.Content.Text != "gone" {
+		t.Errorf("parsed back %+v, %v", back, err)
+	}
+
+	if _, err := ParseRawResponse([]byte("HTTP/1.1 200 OK\r\n")); err == nil {
+		t.Error("truncated head parsed")
+	}
+}
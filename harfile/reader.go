@@ -0,0 +1,269 @@
+package harfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync/atomic"
+)
+
+// EntryReader reads the entries of a HAR one at a time, without loading the
+// whole document in memory. Only the current entry and the log metadata
+// (version, creator, browser, pages and comment) are kept.
+//
+// Metadata stored before "entries" in the document is available from Log as
+// soon as the first entry can be read; metadata stored after it, such as the
+// pages written by [StreamWriter], is only complete once Next returned
+// io.EOF.
+type EntryReader struct {
+	dec     *json.Decoder
+	opts    *fileOptions
+	in      *countingReader
+	entries atomic.Int64
+	log     *Log
+	state   readerState
+	err     error
+}
+
+type readerState int
+
+const (
+	stateStart   readerState = iota // Nothing read yet.
+	stateLog                        // Inside the log object, between members.
+	stateEntries                    // Inside the entries array.
+	stateDone                       // Document fully read.
+)
+
+// NewEntryReader returns an EntryReader reading a HAR document from r.
+// Gzip compressed documents are decompressed; see [WithCompression]. The
+// progress of the reading is reported to [WithProgress].
+func NewEntryReader(r io.Reader, opts ...FileOption) *EntryReader {
+	o := newFileOptions(opts)
+	in := newCountingReader(r, o.progress)
+	o.progress = nil // Reported by in.
+	return &EntryReader{dec: json.NewDecoder(o.reader(in)), opts: o, in: in, log: &Log{}}
+}
+
+// BytesRead returns the number of bytes read from the underlying reader so
+// far, compressed ones for a compressed document. The decoder reads ahead,
+// so it may exceed the bytes of the entries returned. It is safe to call
+// while another goroutine reads entries.
+func (er *EntryReader) BytesRead() int64 {
+	return er.in.n.Load()
+}
+
+// EntriesRead returns the number of entries returned by Next so far. It is
+// safe to call while another goroutine reads entries.
+func (er *EntryReader) EntriesRead() int64 {
+	return er.entries.Load()
+}
+
+// Log returns the log metadata read so far, without entries. Before the
+// first call to Next, it reads ahead to the beginning of the entries array.
+func (er *EntryReader) Log() *Log {
+	if er.state == stateStart || er.state == stateLog {
+		er.err = er.advance()
+	}
+	return er.log
+}
+
+// Err returns the error that stopped the reader, if any, such as a
+// malformed document found by Log. Reaching the end of the document is not
+// an error.
+func (er *EntryReader) Err() error {
+	return er.err
+}
+
+// Next returns the next entry, or io.EOF once the document has been fully
+// read. Any other error is sticky.
+func (er *EntryReader) Next() (*Entry, error) {
+	if er.err != nil {
+		return nil, er.err
+	}
+	for {
+		switch er.state {
+		case stateDone:
+			return nil, io.EOF
+		case stateEntries:
+			if !er.dec.More() {
+				if err := er.expectDelim(']'); err != nil {
+					return nil, er.fail(err)
+				}
+				er.in.finish()
+				er.state = stateLog
+				continue
+			}
+			var e Entry
+			if err := er.dec.Decode(&e); err != nil {
+				return nil, er.fail(fmt.Errorf("harfile: decoding entry: %w", err))
+			}
+			if er.opts.normalizeVersions {
+				normalizeVersions(&e)
+			}
+			if er.opts.sniffMimeTypes && e.Response != nil {
+				e.Response.CorrectMimeType()
+			}
+			if er.opts.load.MaxBodyBytes > 0 {
+				fixCutBody(&e)
+			}
+			er.entries.Add(1)
+			return &e, nil
+		default:
+			if err := er.advance(); err != nil {
+				return nil, er.fail(err)
+			}
+		}
+	}
+}
+
+// All returns an iterator over the remaining entries. Iteration stops after
+// yielding the first error other than io.EOF.
+func (er *EntryReader) All() iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		for {
+			e, err := er.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if !yield(e, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (er *EntryReader) fail(err error) error {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	er.err = err
+	return err
+}
+
+// advance reads log members until the entries array is opened or the
+// document ends, decoding metadata along the way.
+func (er *EntryReader) advance() error {
+	if er.err != nil {
+		return er.err
+	}
+	if er.state == stateStart {
+		if err := er.enterLog(); err != nil {
+			return err
+		}
+		er.state = stateLog
+	}
+	for er.state == stateLog {
+		if !er.dec.More() {
+			if err := er.expectDelim('}'); err != nil {
+				return err
+			}
+			er.state = stateDone
+			return nil
+		}
+		key, err := er.key()
+		if err != nil {
+			return err
+		}
+		var target any
+		switch key {
+		case "entries":
+			tok, err := er.dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil {
+				continue
+			}
+			if d, ok := tok.(json.Delim); !ok || d != '[' {
+				return fmt.Errorf("harfile: log.entries is not an array")
+			}
+			er.state = stateEntries
+			return nil
+		case "version":
+			target = &er.log.Version
+		case "creator":
+			target = &er.log.Creator
+		case "browser":
+			target = &er.log.Browser
+		case "pages":
+			target = &er.log.Pages
+		case "comment":
+			target = &er.log.Comment
+		default:
+			if err := skipValue(er.dec); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := er.dec.Decode(target); err != nil {
+			return fmt.Errorf("harfile: decoding log.%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// enterLog positions the decoder inside the "log" object, skipping any other
+// top-level member.
+func (er *EntryReader) enterLog() error {
+	if err := er.expectDelim('{'); err != nil {
+		return err
+	}
+	for er.dec.More() {
+		key, err := er.key()
+		if err != nil {
+			return err
+		}
+		if key == "log" {
+			return er.expectDelim('{')
+		}
+		if err := skipValue(er.dec); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("harfile: missing log object")
+}
+
+func (er *EntryReader) key() (string, error) {
+	tok, err := er.dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("harfile: unexpected token %v", tok)
+	}
+	return key, nil
+}
+
+func (er *EntryReader) expectDelim(want json.Delim) error {
+	tok, err := er.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("harfile: expected %v, got %v", want, tok)
+	}
+	return nil
+}
+
+// skipValue consumes the next JSON value without retaining it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
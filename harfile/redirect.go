@@ -0,0 +1,58 @@
+package harfile
+
+import "net/url"
+
+// RedirectTarget returns the absolute URL a 3xx response of e points to,
+// taken from RedirectURL or else the Location header and resolved against
+// the request URL. The second result is false when e is not a redirect.
+func (e *Entry) RedirectTarget() (string, bool) {
+	if e.Request == nil || e.Response == nil || e.Response.Status < 300 || e.Response.Status > 399 {
+		return "", false
+	}
+	location := e.Response.RedirectURL
+	if location == "" {
+		location = headerValue(e.Response.Headers, "Location")
+	}
+	if location == "" {
+		return "", false
+	}
+	base, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return "", false
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", false
+	}
+	return normalizeRedirectURL(base.ResolveReference(ref)), true
+}
+
+// FollowedBy returns the entry of l that satisfied the redirect of e: the
+// first entry started no earlier than e whose request URL is the redirect
+// target. It returns nil when e is not a redirect or the target was not
+// recorded.
+func (e *Entry) FollowedBy(l *Log) *Entry {
+	target, ok := e.RedirectTarget()
+	if !ok {
+		return nil
+	}
+	var next *Entry
+	for _, c := range l.Entries {
+		if c == nil || c == e || c.Request == nil || c.StartedDateTime.Before(e.StartedDateTime) {
+			continue
+		}
+		if next != nil && !c.StartedDateTime.Before(next.StartedDateTime) {
+			continue
+		}
+		if u, err := url.Parse(c.Request.URL); err == nil && normalizeRedirectURL(u) == target {
+			next = c
+		}
+	}
+	return next
+}
+
+// normalizeRedirectURL drops the fragment, which is never sent.
+func normalizeRedirectURL(u *url.URL) string {
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
@@ -0,0 +1,79 @@
+package harfile
+
+import (
+	"testing"
+	"time"
+)
+
+// hop returns an entry for url started sec seconds in, redirecting to
+// location when it is not empty.
+func hop(sec int, url, location string) *Entry {
+	e := &Entry{
+		StartedDateTime: time.Date(2024, 1, 1, 0, 0, sec, 0, time.UTC),
+		Request:         &Request{Method: "GET", URL: url},
+		Response:        &Response{Status: 200},
+	}
+	if location != "" {
+		e.Response.Status = 302
+		e.Response.Headers = []*NameValuePair{{Name: "location", Value: location}}
+	}
+	return e
+}
+
+func TestRedirectTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		e    *Entry
+		want string
+		ok   bool
+	}{
+		{"absolute", hop(0, "https://a.example/x", "https://b.example/y"), "https://b.example/y", true},
+		{"relative", hop(0, "https://a.example/dir/x?q=1", "../y?r=2"), "https://a.example/y?r=2", true},
+		{"fragment dropped", hop(0, "https://a.example/x", "/y#top"), "https://a.example/y", true},
+		{"redirectURL first", func() *Entry {
+			e := hop(0, "https://a.example/x", "/from-header")
+			e.Response.RedirectURL = "/from-field"
+			return e
+		}(), "https://a.example/from-field", true},
+		{"not a redirect", hop(0, "https://a.example/x", ""), "", false},
+		{"no location", &Entry{Request: &Request{URL: "https://a.example/"}, Response: &Response{Status: 301}}, "", false},
+		{"no response", &Entry{Request: &Request{URL: "https://a.example/"}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.e.RedirectTarget()
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("RedirectTarget = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFollowedBy(t *testing.T) {
+	login := hop(1, "https://a.example/login", "/home")
+	early := hop(0, "https://a.example/home", "")
+	home := hop(3, "https://a.example/home#main", "")
+	first := hop(2, "https://a.example/home", "")
+	l := &Log{Entries: []*Entry{early, login, home, nil, first, {}}}
+
+	// The earliest match started after the redirect, fragment ignored.
+	if got := login.FollowedBy(l); got != first {
+		t.Errorf("FollowedBy = %v, want the entry started at 2s", got)
+	}
+	l.Entries = []*Entry{early, login, home}
+	if got := login.FollowedBy(l); got != home {
+		t.Errorf("FollowedBy = %v, want the entry started at 3s", got)
+	}
+	if got := hop(4, "https://a.example/x", "/missing").FollowedBy(l); got != nil {
+		t.Errorf("dangling redirect followed by %v", got)
+	}
+	if got := home.FollowedBy(l); got != nil {
+		t.Errorf("non-redirect followed by %v", got)
+	}
+
+	// A redirect to itself is not its own follow-up.
+	self := hop(5, "https://a.example/self", "/self")
+	if got := self.FollowedBy(&Log{Entries: []*Entry{self}}); got != nil {
+		t.Errorf("self redirect followed by %v", got)
+	}
+}
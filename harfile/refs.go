@@ -0,0 +1,220 @@
+package harfile
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kinds of [RefIssue].
+const (
+	RefDangling  = "dangling"  // Entries refer to a page that does not exist.
+	RefOrphan    = "orphan"    // No entry refers to the page.
+	RefDuplicate = "duplicate" // Several pages share an ID.
+)
+
+// RefIssue describes a broken link between the pages and the entries of a
+// log, see [Log.CheckRefs].
+type RefIssue struct {
+	Kind    string // RefDangling, RefOrphan or RefDuplicate.
+	PageID  string // The pageref, or ID, at fault.
+	Pages   []int  // Indexes of the pages with that ID in Log.Pages.
+	Entries []int  // Indexes of the entries referring to it in Log.Entries.
+}
+
+func (i RefIssue) String() string {
+	switch i.Kind {
+	case RefDangling:
+		if len(i.Entries) == 1 {
+			return fmt.Sprintf("dangling pageref %q in 1 entry", i.PageID)
+		}
+		return fmt.Sprintf("dangling pageref %q in %d entries", i.PageID, len(i.Entries))
+	case RefOrphan:
+		return fmt.Sprintf("orphan page %q", i.PageID)
+	case RefDuplicate:
+		return fmt.Sprintf("duplicate page id %q on %d pages", i.PageID, len(i.Pages))
+	}
+	return fmt.Sprintf("%s page %q", i.Kind, i.PageID)
+}
+
+// CheckRefs lists the broken links between the pages and the entries of l:
+// pagerefs naming no page, pages no entry refers to and page IDs used more
+// than once, in that order. Dangling pagerefs are reported once per page
+// ID, in order of first use, and the others in page order. Nil pages and
+// entries are skipped.
+func (l *Log) CheckRefs() []RefIssue {
+	pages := make(map[string][]int)
+	var ids []string
+	for i, p := range l.Pages {
+		if p == nil {
+			continue
+		}
+		if pages[p.ID] == nil {
+			ids = append(ids, p.ID)
+		}
+		pages[p.ID] = append(pages[p.ID], i)
+	}
+	refs := make(map[string][]int)
+	var dangling []string
+	for i, e := range l.Entries {
+		if e == nil || e.Pageref == "" {
+			continue
+		}
+		if pages[e.Pageref] == nil && refs[e.Pageref] == nil {
+			dangling = append(dangling, e.Pageref)
+		}
+		refs[e.Pageref] = append(refs[e.Pageref], i)
+	}
+
+	var issues []RefIssue
+	for _, id := range dangling {
+		issues = append(issues, RefIssue{Kind: RefDangling, PageID: id, Entries: refs[id]})
+	}
+	for _, id := range ids {
+		if refs[id] == nil {
+			issues = append(issues, RefIssue{Kind: RefOrphan, PageID: id, Pages: pages[id]})
+		}
+	}
+	for _, id := range ids {
+		if len(pages[id]) > 1 {
+			issues = append(issues, RefIssue{Kind: RefDuplicate, PageID: id, Pages: pages[id], Entries: refs[id]})
+		}
+	}
+	return issues
+}
+
+// DanglingRefs tells [Log.RepairRefs] what to do with dangling pagerefs.
+type DanglingRefs int
+
+const (
+	KeepDangling  DanglingRefs = iota // Leave dangling pagerefs alone.
+	ClearDangling                     // Detach the entries from any page.
+	StubDangling                      // Create the missing pages.
+)
+
+// RepairOptions configures [Log.RepairRefs].
+type RepairOptions struct {
+	Dangling         DanglingRefs
+	DropOrphans      bool // Remove the pages no entry refers to.
+	RenameDuplicates bool // Give pages sharing an ID IDs of their own.
+}
+
+// RepairRefs fixes the issues reported by [Log.CheckRefs] as opts asks and
+// returns the issues fixed:
+//
+//   - duplicate pages after the first get a "-2", "-3", ... suffix, and each
+//     entry referring to their ID is moved to the last of them started at
+//     or before it, or to the earliest when it started before them all;
+//   - dangling pagerefs are cleared, or stub pages created for them, titled
+//     after their ID and started with the earliest entry referring to them,
+//     appended in order of first use;
+//   - orphan pages are dropped, once the above is done.
+func (l *Log) RepairRefs(opts RepairOptions) []RefIssue {
+	var fixed []RefIssue
+	if opts.RenameDuplicates {
+		for _, issue := range l.CheckRefs() {
+			if issue.Kind == RefDuplicate {
+				l.splitDuplicate(issue)
+				fixed = append(fixed, issue)
+			}
+		}
+	}
+	if opts.Dangling != KeepDangling {
+		for _, issue := range l.CheckRefs() {
+			if issue.Kind != RefDangling {
+				continue
+			}
+			if opts.Dangling == ClearDangling {
+				for _, i := range issue.Entries {
+					l.Entries[i].Pageref = ""
+				}
+			} else {
+				start := l.Entries[issue.Entries[0]].StartedDateTime
+				for _, i := range issue.Entries[1:] {
+					if t := l.Entries[i].StartedDateTime; t.Before(start) {
+						start = t
+					}
+				}
+				p := l.AddPage(issue.PageID, issue.PageID, start)
+				p.Comment = "Stub created for a dangling pageref."
+			}
+			fixed = append(fixed, issue)
+		}
+	}
+	if opts.DropOrphans {
+		drop := make(map[int]bool)
+		for _, issue := range l.CheckRefs() {
+			if issue.Kind == RefOrphan {
+				for _, i := range issue.Pages {
+					drop[i] = true
+				}
+				fixed = append(fixed, issue)
+			}
+		}
+		if len(drop) > 0 {
+			pages := l.Pages[:0]
+			for i, p := range l.Pages {
+				if !drop[i] {
+					pages = append(pages, p)
+				}
+			}
+			clear(l.Pages[len(pages):])
+			l.Pages = pages
+		}
+	}
+	return fixed
+}
+
+// splitDuplicate renames the pages of a RefDuplicate issue but the first
+// and spreads the entries referring to them by start time.
+func (l *Log) splitDuplicate(issue RefIssue) {
+	taken := make(map[string]bool, len(l.Pages))
+	for _, p := range l.Pages {
+		if p != nil {
+			taken[p.ID] = true
+		}
+	}
+	pages := make([]*Page, len(issue.Pages))
+	for n, i := range issue.Pages {
+		p := l.Pages[i]
+		if n > 0 {
+			p.ID = nextFreeID(p.ID, taken)
+			taken[p.ID] = true
+		}
+		pages[n] = p
+	}
+	for _, i := range issue.Entries {
+		e := l.Entries[i]
+		e.Pageref = closestPage(pages, e.StartedDateTime).ID
+	}
+}
+
+// closestPage returns the page of pages that started last at or before t,
+// or the earliest page when t precedes them all. Ties keep document order.
+func closestPage(pages []*Page, t time.Time) *Page {
+	var best, earliest *Page
+	for _, p := range pages {
+		if earliest == nil || p.StartedDateTime.Before(earliest.StartedDateTime) {
+			earliest = p
+		}
+		if p.StartedDateTime.After(t) {
+			continue
+		}
+		if best == nil || p.StartedDateTime.After(best.StartedDateTime) {
+			best = p
+		}
+	}
+	if best == nil {
+		return earliest
+	}
+	return best
+}
+
+// refsNote summarizes issues for the comment of a log.
+func refsNote(issues []RefIssue) string {
+	notes := make([]string, len(issues))
+	for i, issue := range issues {
+		notes[i] = issue.String()
+	}
+	return "Pageref issues: " + strings.Join(notes, "; ") + "."
+}
@@ -0,0 +1,129 @@
+package harfile
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var refsStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func refEntry(pageref string, offset time.Duration) *Entry {
+	e := NewEntry().Get("https://example.com/").StartedAt(refsStart.Add(offset)).RespondStatus(200).Build()
+	e.Pageref = pageref
+	return e
+}
+
+func refPage(id string, offset time.Duration) *Page {
+	return &Page{StartedDateTime: refsStart.Add(offset), ID: id, Title: id, PageTimings: &PageTimings{}}
+}
+
+func TestCheckRefs(t *testing.T) {
+	l := NewLog().Entries(
+		refEntry("a", 0),
+		refEntry("gone", 0),
+		refEntry("dup", 0),
+		refEntry("gone", 0),
+		nil,
+	).Build()
+	l.Pages = []*Page{refPage("a", 0), refPage("dup", 0), refPage("unused", 0), nil, refPage("dup", time.Second)}
+
+	var got []string
+	for _, issue := range l.CheckRefs() {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		`dangling pageref "gone" in 2 entries`,
+		`orphan page "unused"`,
+		`duplicate page id "dup" on 2 pages`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CheckRefs =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRepairRefs(t *testing.T) {
+	newLog := func() *Log {
+		l := NewLog().Entries(
+			refEntry("dup", 90*time.Second),
+			refEntry("dup", -time.Second),
+			refEntry("dup", 30*time.Second),
+			refEntry("gone", 10*time.Second),
+			refEntry("gone", 5*time.Second),
+		).Build()
+		// The second "dup" page started first: entries go to the closest
+		// earlier page, whichever its position.
+		l.Pages = []*Page{refPage("dup", time.Minute), refPage("dup", 0), refPage("unused", 0)}
+		return l
+	}
+	refs := func(l *Log) string {
+		var s []string
+		for _, e := range l.Entries {
+			s = append(s, e.Pageref)
+		}
+		return strings.Join(s, " ")
+	}
+	ids := func(l *Log) string {
+		var s []string
+		for _, p := range l.Pages {
+			s = append(s, p.ID)
+		}
+		return strings.Join(s, " ")
+	}
+
+	tests := []struct {
+		name  string
+		opts  RepairOptions
+		fixed int
+		ids   string
+		refs  string
+	}{
+		{"nothing", RepairOptions{}, 0, "dup dup unused", "dup dup dup gone gone"},
+		{"rename", RepairOptions{RenameDuplicates: true}, 1, "dup dup-2 unused", "dup dup-2 dup-2 gone gone"},
+		{"clear", RepairOptions{Dangling: ClearDangling}, 1, "dup dup unused", "dup dup dup  "},
+		{"stub", RepairOptions{Dangling: StubDangling}, 1, "dup dup unused gone", "dup dup dup gone gone"},
+		{"drop", RepairOptions{DropOrphans: true}, 1, "dup dup", "dup dup dup gone gone"},
+		{"all", RepairOptions{RenameDuplicates: true, Dangling: StubDangling, DropOrphans: true}, 3, "dup dup-2 gone", "dup dup-2 dup-2 gone gone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLog()
+			fixed := l.RepairRefs(tt.opts)
+			if len(fixed) != tt.fixed || ids(l) != tt.ids || refs(l) != tt.refs {
+				t.Errorf("RepairRefs = %d fixed, pages %q, pagerefs %q; want %d, %q, %q", len(fixed), ids(l), refs(l), tt.fixed, tt.ids, tt.refs)
+			}
+		})
+	}
+
+	l := newLog()
+	l.RepairRefs(RepairOptions{Dangling: StubDangling})
+	if stub := l.Pages[len(l.Pages)-1]; !stub.StartedDateTime.Equal(refsStart.Add(5 * time.Second)) {
+		t.Errorf("stub started at %v, want its earliest entry", stub.StartedDateTime)
+	}
+}
+
+func TestMergeReportsRefs(t *testing.T) {
+	// A careless merge: both inputs were already merged by hand and share
+	// a page ID within the first one.
+	a := NewLog().Entries(refEntry("p", 0), refEntry("p", time.Minute)).Build()
+	a.Pages = []*Page{refPage("p", 0), refPage("p", 50*time.Second)}
+	b := NewLog().Entries(refEntry("q", 0), refEntry("nowhere", 0)).Build()
+	b.Pages = []*Page{refPage("q", 0)}
+
+	merged, err := Merge(&HAR{Log: a}, &HAR{Log: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`duplicate page id "p" on 2 pages`, `dangling pageref "nowhere" in 1 entry`} {
+		if !strings.Contains(merged.Log.Comment, want) {
+			t.Errorf("Comment = %q, want it to report %s", merged.Log.Comment, want)
+		}
+	}
+	merged.Log.RepairRefs(RepairOptions{RenameDuplicates: true, Dangling: ClearDangling})
+	if issues := merged.Log.CheckRefs(); len(issues) != 0 {
+		t.Errorf("issues left after repair: %v", issues)
+	}
+	if err := merged.Validate(); err != nil {
+		t.Error(err)
+	}
+}
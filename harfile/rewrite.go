@@ -0,0 +1,116 @@
+package harfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RequestRewriter rewrites a request rebuilt from a recording before it is
+// sent, e.g. to renew a stale bearer token or signature. See
+// [WithRewriters] and [RewriteRequest].
+type RequestRewriter interface {
+	Rewrite(ctx context.Context, req *http.Request) error
+}
+
+// RequestRewriterFunc adapts a function to a [RequestRewriter].
+type RequestRewriterFunc func(ctx context.Context, req *http.Request) error
+
+// Rewrite calls f(ctx, req).
+func (f RequestRewriterFunc) Rewrite(ctx context.Context, req *http.Request) error {
+	return f(ctx, req)
+}
+
+// WithRewriters applies rewriters, in order, to the request built by
+// [Request.ToHTTP], as [RewriteRequest] does. [Response.ToHTTP] ignores
+// them.
+func WithRewriters(rewriters ...RequestRewriter) ConvertOption {
+	return func(o *convertOptions) {
+		o.rewriters = append(o.rewriters, rewriters...)
+	}
+}
+
+// RewriteRequest applies rewriters to req in order, stopping at the first
+// error. A rewriter may read req.Body, e.g. to sign a hash of the payload,
+// as long as it replaces it: whenever a rewriter leaves a different body,
+// that body is read into memory so that req.ContentLength and req.GetBody
+// match it, and the next rewriter sees the final payload.
+//
+// Redacted recordings hold placeholders such as "[REDACTED]" instead of
+// credentials, which the rebuilt request sends as is: a [BearerToken] or
+// [HeaderOverride] rewriter puts back a usable value, and [StripHeaders]
+// drops the header instead.
+func RewriteRequest(ctx context.Context, req *http.Request, rewriters ...RequestRewriter) error {
+	for _, rw := range rewriters {
+		body := req.Body
+		if err := rw.Rewrite(ctx, req); err != nil {
+			return fmt.Errorf("harfile: rewrite request: %w", err)
+		}
+		if req.Body != body {
+			if err := bufferBody(req); err != nil {
+				return fmt.Errorf("harfile: rewrite request: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// bufferBody reads req.Body into memory and sets the length and GetBody
+// of req to match.
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	if len(data) == 0 {
+		req.Body = http.NoBody
+	}
+	deleteHeader(req.Header, "Content-Length")
+	return nil
+}
+
+// BearerToken returns a rewriter setting the Authorization header to a
+// bearer token, replacing the recorded one.
+func BearerToken(token string) RequestRewriter {
+	return HeaderOverride(map[string]string{"Authorization": "Bearer " + token})
+}
+
+// HeaderOverride returns a rewriter setting each header of headers,
+// replacing the recorded values whatever the case of their names.
+func HeaderOverride(headers map[string]string) RequestRewriter {
+	return RequestRewriterFunc(func(_ context.Context, req *http.Request) error {
+		for name, value := range headers {
+			if strings.EqualFold(name, "Host") {
+				req.Host = value
+				continue
+			}
+			deleteHeader(req.Header, name)
+			req.Header.Set(name, value)
+		}
+		return nil
+	})
+}
+
+// StripHeaders returns a rewriter removing the named headers, whatever the
+// case of their names.
+func StripHeaders(names ...string) RequestRewriter {
+	return RequestRewriterFunc(func(_ context.Context, req *http.Request) error {
+		for _, name := range names {
+			deleteHeader(req.Header, name)
+		}
+		return nil
+	})
+}
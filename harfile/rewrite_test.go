@@ -0,0 +1,187 @@
+package harfile
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRewriters(t *testing.T) {
+	tests := []struct {
+		name      string
+		rewriters []RequestRewriter
+		want      http.Header
+		host      string
+	}{
+		{
+			name: "none",
+			want: http.Header{"authorization": {"[REDACTED]"}, "X-Api-Key": {"[REDACTED]"}, "Accept": {"*/*"}},
+		},
+		{
+			name:      "bearer token over a redacted value",
+			rewriters: []RequestRewriter{BearerToken("fresh")},
+			want:      http.Header{"Authorization": {"Bearer fresh"}, "X-Api-Key": {"[REDACTED]"}, "Accept": {"*/*"}},
+		},
+		{
+			name:      "override",
+			rewriters: []RequestRewriter{HeaderOverride(map[string]string{"x-api-key": "k", "Accept": "text/html", "X-New": "1", "host": "other.example"})},
+			want:      http.Header{"authorization": {"[REDACTED]"}, "X-Api-Key": {"k"}, "Accept": {"text/html"}, "X-New": {"1"}},
+			host:      "other.example",
+		},
+		{
+			name:      "strip",
+			rewriters: []RequestRewriter{StripHeaders("Authorization", "x-api-key", "X-Missing")},
+			want:      http.Header{"Accept": {"*/*"}},
+		},
+		{
+			name:      "in order",
+			rewriters: []RequestRewriter{BearerToken("a"), StripHeaders("authorization"), BearerToken("b")},
+			want:      http.Header{"Authorization": {"Bearer b"}, "X-Api-Key": {"[REDACTED]"}, "Accept": {"*/*"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewEntry().Get("https://api.example.com/items").
+				Header("authorization", "[REDACTED]").
+				Header("X-Api-Key", "[REDACTED]").
+				Header("Accept", "*/*").
+				Build().Request
+			req, err := r.ToHTTP(context.Background(), PreserveHeaderCase(), WithRewriters(tt.rewriters...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Del("Content-Length")
+			if len(req.Header) != len(tt.want) {
+				t.Errorf("headers = %v, want %v", req.Header, tt.want)
+			}
+			for name, want := range tt.want {
+				if got := req.Header[name]; strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Errorf("%s = %q, want %q (headers %v)", name, got, want, req.Header)
+				}
+			}
+			if host := tt.host; host != "" && req.Host != host {
+				t.Errorf("Host = %q, want %q", req.Host, host)
+			}
+		})
+	}
+}
+
+// hmacSigner signs the final body, as signature schemes hashing the
+// payload do.
+func hmacSigner(key string) RequestRewriter {
+	return RequestRewriterFunc(func(_ context.Context, req *http.Request) error {
+		var body []byte
+		if req.GetBody != nil {
+			rc, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+			if body, err = io.ReadAll(rc); err != nil {
+				return err
+			}
+		}
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(body)
+		req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	})
+}
+
+func sign(key, body string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestRewriteRequestBody(t *testing.T) {
+	stamp := RequestRewriterFunc(func(_ context.Context, req *http.Request) error {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(strings.NewReader(strings.Replace(string(data), `"nonce":"old"`, `"nonce":"fresh-nonce"`, 1)))
+		return nil
+	})
+	empty := RequestRewriterFunc(func(_ context.Context, req *http.Request) error {
+		req.Body = io.NopCloser(strings.NewReader(""))
+		return nil
+	})
+	tests := []struct {
+		name      string
+		rewriters []RequestRewriter
+		body      string
+	}{
+		{"unchanged", []RequestRewriter{hmacSigner("k")}, `{"nonce":"old","n":1}`},
+		{"longer body", []RequestRewriter{stamp, hmacSigner("k")}, `{"nonce":"fresh-nonce","n":1}`},
+		{"emptied body", []RequestRewriter{empty, hmacSigner("k")}, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewEntry().Post("https://api.example.com/orders").
+				Header("Content-Length", "21").
+				Body("application/json", []byte(`{"nonce":"old","n":1}`)).
+				Build().Request
+			req, err := r.ToHTTP(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := RewriteRequest(context.Background(), req, tt.rewriters...); err != nil {
+				t.Fatal(err)
+			}
+			if req.ContentLength != int64(len(tt.body)) {
+				t.Errorf("ContentLength = %d, want %d", req.ContentLength, len(tt.body))
+			}
+			if tt.body != `{"nonce":"old","n":1}` && req.Header.Get("Content-Length") != "" {
+				t.Errorf("stale Content-Length header %s", req.Header.Get("Content-Length"))
+			}
+			got, _ := io.ReadAll(req.Body)
+			if string(got) != tt.body {
+				t.Errorf("body = %s, want %s", got, tt.body)
+			}
+			if req.GetBody != nil {
+				again, _ := req.GetBody()
+				if got, _ := io.ReadAll(again); string(got) != tt.body {
+					t.Errorf("GetBody = %s, want %s", got, tt.body)
+				}
+			} else if tt.body != "" {
+				t.Error("no GetBody")
+			}
+			if got, want := req.Header.Get("X-Signature"), sign("k", tt.body); got != want {
+				t.Errorf("signature %s, want %s of the final body", got, want)
+			}
+		})
+	}
+}
+
+func TestRewriteRequestError(t *testing.T) {
+	errStale := errors.New("credentials expired")
+	called := false
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	err := RewriteRequest(context.Background(), req,
+		RequestRewriterFunc(func(context.Context, *http.Request) error { return errStale }),
+		RequestRewriterFunc(func(context.Context, *http.Request) error { called = true; return nil }),
+	)
+	if !errors.Is(err, errStale) || !strings.HasPrefix(err.Error(), "harfile: rewrite request: ") || called {
+		t.Errorf("RewriteRequest = %v, later rewriter called %v", err, called)
+	}
+	r := NewEntry().Get("https://example.com/").Build().Request
+	if _, err := r.ToHTTP(context.Background(), WithRewriters(RequestRewriterFunc(func(context.Context, *http.Request) error {
+		return errStale
+	}))); !errors.Is(err, errStale) {
+		t.Errorf("ToHTTP = %v", err)
+	}
+
+	// Response.ToHTTP ignores rewriters.
+	resp := NewEntry().RespondHeader("Authorization", "x").Build().Response
+	hr, err := resp.ToHTTP(WithRewriters(StripHeaders("Authorization")))
+	if err != nil || hr.Header.Get("Authorization") != "x" {
+		t.Errorf("Response.ToHTTP = %v, %v", hr.Header, err)
+	}
+}
@@ -0,0 +1,149 @@
+package harfile
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SizeUnknown is the value of the sizes and timings that are not
+// available, such as the headersSize of an HTTP/2 request or the dns phase
+// of a reused connection. Use the Known accessors, such as
+// [Response.KnownBodySize] and [Timings.Millis], rather than adding such
+// fields up directly.
+const SizeUnknown = -1
+
+// KnownHeadersSize returns HeadersSize and whether it is known.
+func (r *Request) KnownHeadersSize() (int64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	return known(r.HeadersSize)
+}
+
+// KnownBodySize returns BodySize and whether it is known.
+func (r *Request) KnownBodySize() (int64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	return known(r.BodySize)
+}
+
+// KnownHeadersSize returns HeadersSize and whether it is known.
+func (r *Response) KnownHeadersSize() (int64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	return known(r.HeadersSize)
+}
+
+// KnownBodySize returns BodySize, the bytes received, and whether it is
+// known.
+func (r *Response) KnownBodySize() (int64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	return known(r.BodySize)
+}
+
+// KnownSize returns Size, the decoded length, and whether it is known.
+func (c *Content) KnownSize() (int64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	return known(c.Size)
+}
+
+// known returns n and whether it is a size rather than SizeUnknown, or an
+// invalid negative value.
+func known(n int64) (int64, bool) {
+	if n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// isHTTP1 reports whether version names HTTP/1.0 or HTTP/1.1, whose header
+// size can be derived from the headers. An empty version is assumed to be
+// HTTP/1.1.
+func isHTTP1(version string) bool {
+	v := strings.ToUpper(strings.TrimSpace(version))
+	return v == "" || strings.HasPrefix(v, "HTTP/1")
+}
+
+// ComputeSizes sets HeadersSize and BodySize from the request content.
+//
+// For HTTP/1.x, HeadersSize counts the request line and the headers, each
+// terminated by CRLF, and the final CRLF. Other protocols compress headers
+// on the wire, so their size cannot be derived and is set to -1. BodySize is
+// the length of PostData.Text, decoded as PostData.Encoding says,
+// 0 without PostData, and -1 when the body is only described by Params.
+func (r *Request) ComputeSizes() {
+	if isHTTP1(r.HTTPVersion) {
+		target := r.URL
+		if u, err := url.Parse(r.URL); err == nil {
+			target = u.RequestURI()
+		}
+		version := r.HTTPVersion
+		if version == "" {
+			version = "HTTP/1.1"
+		}
+		n := len(r.Method) + 1 + len(target) + 1 + len(version) + 2
+		r.HeadersSize = int64(n + headerBlockSize(r.Headers))
+	} else {
+		r.HeadersSize = SizeUnknown
+	}
+
+	switch pd := r.PostData; {
+	case pd == nil:
+		r.BodySize = 0
+	case pd.Text == "" && len(pd.Params) > 0:
+		r.BodySize = SizeUnknown
+	case pd.Encoding != "":
+		data, _ := pd.DecodedText()
+		r.BodySize = int64(len(data))
+	default:
+		r.BodySize = int64(len(pd.Text))
+	}
+}
+
+// ComputeSizes sets HeadersSize and BodySize from the response content.
+//
+// HeadersSize follows the rules of [Request.ComputeSizes] with the status
+// line. BodySize is 0 for statuses without a body (1xx, 204 and 304),
+// Content.Size minus Content.Compression otherwise, and -1 when the content
+// size is unknown or the compression exceeds it.
+func (r *Response) ComputeSizes() {
+	if isHTTP1(r.HTTPVersion) {
+		version := r.HTTPVersion
+		if version == "" {
+			version = "HTTP/1.1"
+		}
+		n := len(version) + 1 + len(strconv.FormatInt(r.Status, 10)) + 1 + len(r.StatusText) + 2
+		r.HeadersSize = int64(n + headerBlockSize(r.Headers))
+	} else {
+		r.HeadersSize = SizeUnknown
+	}
+
+	size, ok := r.Content.KnownSize()
+	switch {
+	case r.Status >= 100 && r.Status < 200, r.Status == 204, r.Status == 304:
+		r.BodySize = 0
+	case !ok || r.Content.Compression > size:
+		r.BodySize = SizeUnknown
+	default:
+		r.BodySize = size - r.Content.Compression
+	}
+}
+
+// headerBlockSize returns the size of "Name: value\r\n" lines followed by the
+// empty line ending the header block.
+func headerBlockSize(headers []*NameValuePair) int {
+	n := 2
+	for _, h := range headers {
+		if h != nil && !strings.HasPrefix(h.Name, ":") {
+			n += len(h.Name) + 2 + len(h.Value) + 2
+		}
+	}
+	return n
+}
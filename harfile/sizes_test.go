@@ -0,0 +1,123 @@
+package harfile
+
+import (
+	"io"
+	"testing"
+)
+
+func TestKnownSizes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int64
+		want  int64
+		known bool
+	}{
+		{"known", 120, 120, true},
+		{"zero", 0, 0, true},
+		{"unknown", SizeUnknown, 0, false},
+		{"invalid", -7, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{HeadersSize: tt.value, BodySize: tt.value}
+			resp := &Response{HeadersSize: tt.value, BodySize: tt.value}
+			content := &Content{Size: tt.value}
+			accessors := map[string]func() (int64, bool){
+				"Request.KnownHeadersSize":  req.KnownHeadersSize,
+				"Request.KnownBodySize":     req.KnownBodySize,
+				"Response.KnownHeadersSize": resp.KnownHeadersSize,
+				"Response.KnownBodySize":    resp.KnownBodySize,
+				"Content.KnownSize":         content.KnownSize,
+			}
+			for name, known := range accessors {
+				if got, ok := known(); got != tt.want || ok != tt.known {
+					t.Errorf("%s of %d = %d, %v, want %d, %v", name, tt.value, got, ok, tt.want, tt.known)
+				}
+			}
+		})
+	}
+
+	var req *Request
+	var resp *Response
+	var content *Content
+	for name, known := range map[string]func() (int64, bool){
+		"Request.KnownHeadersSize":  req.KnownHeadersSize,
+		"Request.KnownBodySize":     req.KnownBodySize,
+		"Response.KnownHeadersSize": resp.KnownHeadersSize,
+		"Response.KnownBodySize":    resp.KnownBodySize,
+		"Content.KnownSize":         content.KnownSize,
+	} {
+		if got, ok := known(); got != 0 || ok {
+			t.Errorf("%s of nil = %d, %v", name, got, ok)
+		}
+	}
+}
+
+func TestResponseComputeSizesUnknown(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int64
+		content *Content
+		want    int64
+	}{
+		{"known", 200, &Content{Size: 100}, 100},
+		{"compressed", 200, &Content{Size: 100, Compression: 30}, 70},
+		{"zero", 200, &Content{Size: 0}, 0},
+		{"unknown", 200, &Content{Size: SizeUnknown}, SizeUnknown},
+		{"no content", 200, nil, SizeUnknown},
+		{"compression above size", 200, &Content{Size: 10, Compression: 30}, SizeUnknown},
+		{"unknown without body", 204, &Content{Size: SizeUnknown}, 0},
+		{"unknown not modified", 304, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Response{Status: tt.status, HTTPVersion: "HTTP/2", Content: tt.content}
+			r.ComputeSizes()
+			if r.BodySize != tt.want {
+				t.Errorf("BodySize = %d, want %d", r.BodySize, tt.want)
+			}
+			if r.HeadersSize != SizeUnknown {
+				t.Errorf("HTTP/2 HeadersSize = %d, want %d", r.HeadersSize, SizeUnknown)
+			}
+		})
+	}
+}
+
+// TestToHTTPUnknownSize checks that unknown sizes never reach the
+// Content-Length of converted messages.
+func TestToHTTPUnknownSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		bodySize int64
+		size     int64
+		text     string
+	}{
+		{"known", 5, 5, "hello"},
+		{"zero", 0, 0, ""},
+		{"unknown body size", SizeUnknown, 5, "hello"},
+		{"unknown sizes", SizeUnknown, SizeUnknown, "hello"},
+		{"unknown empty", SizeUnknown, SizeUnknown, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Response{
+				Status:      200,
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []*NameValuePair{{Name: "Content-Length", Value: "-1"}},
+				Content:     &Content{Size: tt.size, MimeType: "text/plain", Text: tt.text},
+				BodySize:    tt.bodySize,
+				HeadersSize: SizeUnknown,
+			}
+			resp, err := r.ToHTTP()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.ContentLength != int64(len(tt.text)) || resp.Header.Get("Content-Length") != "" {
+				t.Errorf("ContentLength %d, header %q, want %d", resp.ContentLength, resp.Header.Get("Content-Length"), len(tt.text))
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != tt.text {
+				t.Errorf("body = %q", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package harfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of bytes looked at for markup, as by
+// [http.DetectContentType].
+const sniffLen = 512
+
+// signatures complete the ones known to [http.DetectContentType].
+var signatures = []struct {
+	prefix   string
+	mimeType string
+}{
+	{"\x00asm", "application/wasm"},
+	{"wOFF", "font/woff"},
+	{"wOF2", "font/woff2"},
+	{"OTTO", "font/otf"},
+	{"\x00\x01\x00\x00", "font/ttf"},
+	{"ttcf", "font/collection"},
+}
+
+// SniffedMimeType returns the media type of the response body judged from
+// its bytes, decoded by [Content.DecodedBody]: the types recognized by
+// [http.DetectContentType], plus JSON, XML, SVG, fonts and WebAssembly.
+// MimeType is returned unchanged when it agrees with the body, when the
+// body is empty or cannot be decoded, and when sniffing is inconclusive:
+// binary data of unknown type, or plain text under a textual MimeType. A
+// sniffed textual type keeps the charset of MimeType.
+func (c *Content) SniffedMimeType() string {
+	return c.sniff("")
+}
+
+// SniffedMimeType is like [Content.SniffedMimeType] but also reverses the
+// Content-Encoding of the response before sniffing.
+func (r *Response) SniffedMimeType() string {
+	if r.Content == nil {
+		return ""
+	}
+	return r.Content.sniff(headerValue(r.Headers, "Content-Encoding"))
+}
+
+// CorrectMimeType replaces the MimeType of the content with the sniffed
+// one, see [Response.SniffedMimeType], when they disagree, noting the
+// declared type in the content comment. It reports whether it did.
+func (r *Response) CorrectMimeType() bool {
+	sniffed := r.SniffedMimeType()
+	if r.Content == nil || sniffed == r.Content.MimeType {
+		return false
+	}
+	c := r.Content
+	c.Comment = appendNote(c.Comment, fmt.Sprintf("declared mimeType %q, sniffed %q", c.MimeType, sniffed))
+	c.MimeType = sniffed
+	return true
+}
+
+// CorrectMimeTypes calls [Response.CorrectMimeType] on every response of l
+// and returns how many were corrected.
+func (l *Log) CorrectMimeTypes() int {
+	n := 0
+	for _, e := range l.Entries {
+		if e != nil && e.Response != nil && e.Response.CorrectMimeType() {
+			n++
+		}
+	}
+	return n
+}
+
+// WithSniffedMimeTypes makes [Load], [LoadLenient], [LoadWithBodyStore] and
+// [EntryReader] correct the MimeType of response bodies from their bytes,
+// as [Log.CorrectMimeTypes] does.
+func WithSniffedMimeTypes() FileOption {
+	return func(o *fileOptions) {
+		o.sniffMimeTypes = true
+	}
+}
+
+func (c *Content) sniff(contentEncoding string) string {
+	data, err := c.DecodeBody(contentEncoding)
+	if err != nil || len(data) == 0 {
+		return c.MimeType
+	}
+	sniffed := sniffMimeType(data)
+	declared, params, _ := mime.ParseMediaType(c.MimeType)
+	if declared == "" {
+		return sniffed
+	}
+	if compatibleMimeTypes(declared, sniffed) {
+		return c.MimeType
+	}
+	if charset := params["charset"]; charset != "" && isTextualMimeType(sniffed) {
+		return mime.FormatMediaType(sniffed, map[string]string{"charset": charset})
+	}
+	return sniffed
+}
+
+// sniffMimeType returns the media type of data, without parameters.
+func sniffMimeType(data []byte) string {
+	for _, sig := range signatures {
+		if bytes.HasPrefix(data, []byte(sig.prefix)) {
+			return sig.mimeType
+		}
+	}
+	text := bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	if len(text) > 0 && (text[0] == '{' || text[0] == '[') && json.Valid(text) {
+		return "application/json"
+	}
+	head := bytes.ToLower(text[:min(len(text), sniffLen)])
+	if bytes.HasPrefix(head, []byte("<svg")) || bytes.HasPrefix(head, []byte("<?xml")) && bytes.Contains(head, []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	if bytes.HasPrefix(head, []byte("<?xml")) {
+		return "application/xml"
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return mediaType
+}
+
+// compatibleMimeTypes reports whether the sniffed media type does not
+// contradict the declared one.
+func compatibleMimeTypes(declared, sniffed string) bool {
+	switch {
+	case declared == sniffed:
+		return true
+	case sniffed == "application/octet-stream":
+		// Unknown binary data: nothing to say.
+		return true
+	case sniffed == "text/plain":
+		return isTextualMimeType(declared)
+	case sniffed == "application/json":
+		return isJSONMimeType(declared)
+	case sniffed == "application/xml", sniffed == "text/xml":
+		return isXMLMimeType(declared)
+	case sniffed == "text/html":
+		return declared == "application/xhtml+xml"
+	case sniffed == "image/x-icon":
+		return declared == "image/vnd.microsoft.icon"
+	case strings.HasPrefix(sniffed, "font/"):
+		return strings.HasPrefix(declared, "font/") || strings.HasPrefix(declared, "application/font-") ||
+			strings.HasPrefix(declared, "application/x-font-")
+	}
+	return false
+}
+
+// isTextualMimeType reports whether bodies of mediaType are text.
+func isTextualMimeType(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), isJSONMimeType(mediaType), isXMLMimeType(mediaType):
+		return true
+	}
+	switch mediaType {
+	case "application/javascript", "application/x-javascript", "application/ecmascript",
+		"application/x-www-form-urlencoded", "application/graphql":
+		return true
+	}
+	return false
+}
+
+// isXMLMimeType reports whether mediaType is XML, including the +xml
+// structured syntaxes such as image/svg+xml.
+func isXMLMimeType(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
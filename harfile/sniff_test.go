@@ -0,0 +1,113 @@
+package harfile
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestSniffedMimeType(t *testing.T) {
+	tests := []struct {
+		name    string
+		content Content
+		want    string
+	}{
+		{"json under html", Content{MimeType: "text/html; charset=utf-8", Text: `{"a":1}`}, "application/json; charset=utf-8"},
+		{"json array under plain text", Content{MimeType: "text/plain", Text: ` [1, 2]`}, "application/json"},
+		{"json with bom", Content{MimeType: "text/html", Text: "\ufeff{}"}, "application/json"},
+		{"json under +json", Content{MimeType: "application/vnd.api+json", Text: `{"data":[]}`}, "application/vnd.api+json"},
+		{"invalid json is text", Content{MimeType: "application/json", Text: `{"a":`}, "application/json"},
+		{"text under javascript", Content{MimeType: "application/javascript", Text: "let a = 1;"}, "application/javascript"},
+		{"png under jpeg", Content{MimeType: "image/jpeg", Text: pngHeader}, "image/png"},
+		{"base64 png", Content{MimeType: "text/html", Text: base64.StdEncoding.EncodeToString([]byte(pngHeader)), Encoding: "base64"}, "image/png"},
+		{"gzipped json", Content{MimeType: "text/html", Text: gzipped(t, `{"a":1}`)}, "application/json"},
+		{"zstd json", Content{MimeType: "text/html", Text: zstded(t, `{"a":1}`)}, "application/json"},
+		{"svg", Content{MimeType: "text/plain; charset=utf-8", Text: `<svg xmlns="http://www.w3.org/2000/svg"/>`}, "image/svg+xml; charset=utf-8"},
+		{"svg with prolog", Content{MimeType: "application/xml", Text: `<?xml version="1.0"?><SVG/>`}, "image/svg+xml"},
+		{"xml", Content{MimeType: "text/html", Text: `<?xml version="1.0"?><feed/>`}, "application/xml"},
+		{"xml under +xml", Content{MimeType: "application/atom+xml", Text: `<?xml version="1.0"?><feed/>`}, "application/atom+xml"},
+		{"html under xhtml", Content{MimeType: "application/xhtml+xml", Text: "<!DOCTYPE html><html></html>"}, "application/xhtml+xml"},
+		{"html under json", Content{MimeType: "application/json", Text: "<!DOCTYPE html><p>Error</p>"}, "text/html"},
+		{"wasm", Content{MimeType: "application/octet-stream", Text: "\x00asm\x01\x00\x00\x00"}, "application/wasm"},
+		{"woff under legacy type", Content{MimeType: "application/x-font-woff", Text: "wOFF\x00\x01\x00\x00"}, "application/x-font-woff"},
+		{"woff2 under woff", Content{MimeType: "font/woff", Text: "wOF2\x00\x01\x00\x00"}, "font/woff"},
+		{"unknown binary", Content{MimeType: "image/avif", Text: "\x00\x01\x02\x03"}, "image/avif"},
+		{"no declared type", Content{Text: `{"a":1}`}, "application/json"},
+		{"empty body", Content{MimeType: "image/png"}, "image/png"},
+		{"bad base64", Content{MimeType: "image/png", Text: "!!", Encoding: "base64"}, "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.content.SniffedMimeType(); got != tt.want {
+				t.Errorf("SniffedMimeType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseSniffedMimeType(t *testing.T) {
+	r := &Response{
+		Headers: []*NameValuePair{{Name: "Content-Encoding", Value: "br"}},
+		Content: &Content{MimeType: "text/html", Text: brotlied(t, `{"a":1}`)},
+	}
+	if got := r.SniffedMimeType(); got != "application/json" {
+		t.Errorf("brotli body sniffed as %q", got)
+	}
+	if got := r.Content.SniffedMimeType(); got != "text/html" {
+		t.Errorf("content without the header sniffed as %q", got)
+	}
+	if got := (&Response{}).SniffedMimeType(); got != "" {
+		t.Errorf("no content sniffed as %q", got)
+	}
+}
+
+func TestCorrectMimeTypes(t *testing.T) {
+	png := &Entry{Response: &Response{Content: &Content{MimeType: "image/jpeg", Text: pngHeader, Comment: "logo"}}}
+	json := &Entry{Response: &Response{Content: &Content{MimeType: "application/json", Text: `{}`}}}
+	l := &Log{Entries: []*Entry{png, json, nil, {}, {Response: &Response{}}}}
+	if n := l.CorrectMimeTypes(); n != 1 {
+		t.Errorf("corrected %d, want 1", n)
+	}
+	if c := png.Response.Content; c.MimeType != "image/png" || c.Comment != "logo\ndeclared mimeType \"image/jpeg\", sniffed \"image/png\"" {
+		t.Errorf("content = %q, comment %q", c.MimeType, c.Comment)
+	}
+	if c := json.Response.Content; c.MimeType != "application/json" || c.Comment != "" {
+		t.Errorf("agreeing content = %q, comment %q", c.MimeType, c.Comment)
+	}
+	if n := l.CorrectMimeTypes(); n != 0 {
+		t.Errorf("second pass corrected %d", n)
+	}
+}
+
+func TestSniffOnLoadAndCompact(t *testing.T) {
+	doc := `{"log":{"version":"1.2","creator":{"name":"t","version":"1"},"entries":[` +
+		`{"startedDateTime":"2024-01-01T00:00:00Z","time":1,"request":{"method":"GET","url":"https://example.com/a.png","httpVersion":"HTTP/1.1","cookies":[],"headers":[],"queryString":[],"headersSize":-1,"bodySize":0},` +
+		`"response":{"status":200,"statusText":"OK","httpVersion":"HTTP/1.1","cookies":[],"headers":[],"content":{"size":8,"mimeType":"text/plain","text":"iVBORw0KGgo=","encoding":"base64"},"redirectURL":"","headersSize":-1,"bodySize":8},` +
+		`"cache":{},"timings":{"send":0,"wait":1,"receive":0}}]}}`
+	h, err := Load(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Log.Entries[0].Response.Content.MimeType; got != "text/plain" {
+		t.Errorf("loaded without the option: %q", got)
+	}
+	h, err = Load(strings.NewReader(doc), WithSniffedMimeTypes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Log.Entries[0].Response.Content.MimeType; got != "image/png" {
+		t.Errorf("loaded with the option: %q", got)
+	}
+
+	l := &Log{Entries: []*Entry{{Response: &Response{Content: &Content{MimeType: "text/plain", Text: pngHeader}}}}}
+	l.Compact(CompactOptions{DropMimePrefixes: []string{"image/"}})
+	if len(l.Entries) != 1 {
+		t.Fatal("image dropped without sniffing")
+	}
+	l.Compact(CompactOptions{DropMimePrefixes: []string{"image/"}, SniffMimeTypes: true})
+	if len(l.Entries) != 0 {
+		t.Error("sniffed image kept")
+	}
+}
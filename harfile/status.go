@@ -0,0 +1,94 @@
+package harfile
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// StatusClass is the class of a response status, given by its first digit.
+type StatusClass int
+
+const (
+	StatusUnknown       StatusClass = iota // No response, status 0, or a status outside 100-599.
+	StatusInformational                    // 1xx.
+	StatusSuccess                          // 2xx.
+	StatusRedirection                      // 3xx.
+	StatusClientError                      // 4xx.
+	StatusServerError                      // 5xx.
+)
+
+func (c StatusClass) String() string {
+	if c >= StatusInformational && c <= StatusServerError {
+		return fmt.Sprintf("%dxx", int(c))
+	}
+	return "unknown"
+}
+
+// Class returns the class of the status of r. Non-standard codes such as
+// 499 or 599 belong to the class of their first digit.
+func (r *Response) Class() StatusClass {
+	if r == nil || r.Status < 100 || r.Status > 599 {
+		return StatusUnknown
+	}
+	return StatusClass(r.Status / 100)
+}
+
+// IsError reports whether the status of r is 4xx or 5xx. Requests that got
+// no response, with status 0, are not errors here but [Failed].
+func (r *Response) IsError() bool {
+	c := r.Class()
+	return c == StatusClientError || c == StatusServerError
+}
+
+// IsRedirect reports whether the status of r is 3xx, other than 304 Not
+// Modified which answers a conditional request.
+func (r *Response) IsRedirect() bool {
+	return r.Class() == StatusRedirection && r.Status != http.StatusNotModified
+}
+
+// ByClass selects responses whose status belongs to one of classes.
+func ByClass(classes ...StatusClass) Predicate {
+	return func(e *Entry) bool {
+		return e.Response != nil && slices.Contains(classes, e.Response.Class())
+	}
+}
+
+// IsError selects responses with a 4xx or 5xx status, see
+// [Response.IsError].
+func IsError() Predicate {
+	return func(e *Entry) bool {
+		return e.Response.IsError()
+	}
+}
+
+// BackfillStatusText sets the empty StatusText of the responses of log to
+// the reason phrase of their status, e.g. "Not Found" for 404, and returns
+// the number of responses changed. A StatusText differing from the reason
+// phrase, ignoring case, is kept and noted in the response comment.
+// Non-standard statuses have no reason phrase and are left alone.
+func BackfillStatusText(log *Log) int {
+	n := 0
+	for _, e := range log.Entries {
+		if e == nil || e.Response == nil {
+			continue
+		}
+		r := e.Response
+		text := http.StatusText(int(r.Status))
+		switch got := strings.TrimSpace(r.StatusText); {
+		case text == "" || strings.EqualFold(got, text):
+			continue
+		case got == "":
+			r.StatusText = text
+		default:
+			note := fmt.Sprintf("statusText %q differs from %q for status %d", r.StatusText, text, r.Status)
+			if strings.Contains(r.Comment, note) {
+				continue
+			}
+			r.Comment = appendNote(r.Comment, note)
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,95 @@
+package harfile
+
+import "testing"
+
+func TestResponseClass(t *testing.T) {
+	tests := []struct {
+		status   int64
+		class    StatusClass
+		name     string
+		error    bool
+		redirect bool
+	}{
+		{0, StatusUnknown, "unknown", false, false},
+		{99, StatusUnknown, "unknown", false, false},
+		{100, StatusInformational, "1xx", false, false},
+		{200, StatusSuccess, "2xx", false, false},
+		{299, StatusSuccess, "2xx", false, false}, // Non-standard.
+		{301, StatusRedirection, "3xx", false, true},
+		{304, StatusRedirection, "3xx", false, false},
+		{404, StatusClientError, "4xx", true, false},
+		{499, StatusClientError, "4xx", true, false},
+		{599, StatusServerError, "5xx", true, false},
+		{600, StatusUnknown, "unknown", false, false},
+		{999, StatusUnknown, "unknown", false, false},
+		{-1, StatusUnknown, "unknown", false, false},
+	}
+	for _, tt := range tests {
+		r := &Response{Status: tt.status}
+		if c := r.Class(); c != tt.class || c.String() != tt.name {
+			t.Errorf("status %d: class %v, want %v", tt.status, c, tt.name)
+		}
+		if r.IsError() != tt.error || r.IsRedirect() != tt.redirect {
+			t.Errorf("status %d: IsError %v, IsRedirect %v", tt.status, r.IsError(), r.IsRedirect())
+		}
+	}
+	if c := (*Response)(nil).Class(); c != StatusUnknown || (*Response)(nil).IsError() {
+		t.Errorf("nil response: class %v", c)
+	}
+	if s := StatusClass(9).String(); s != "unknown" {
+		t.Errorf("StatusClass(9) = %q", s)
+	}
+}
+
+func TestByClass(t *testing.T) {
+	l := &Log{Entries: []*Entry{
+		{Response: &Response{Status: 200}},
+		{Response: &Response{Status: 302}},
+		{},
+		{Response: &Response{Status: 404}},
+		{Response: &Response{Status: 999}},
+		{Response: &Response{Status: 503}},
+	}}
+	count := func(p Predicate) int {
+		return len(l.Filter(p).Entries)
+	}
+	if n := count(ByClass(StatusSuccess, StatusRedirection)); n != 2 {
+		t.Errorf("2xx and 3xx: %d entries", n)
+	}
+	if n := count(ByClass(StatusUnknown)); n != 1 {
+		t.Errorf("unknown: %d entries, want the 999 one only", n)
+	}
+	if n := count(IsError()); n != 2 {
+		t.Errorf("errors: %d entries", n)
+	}
+}
+
+func TestBackfillStatusText(t *testing.T) {
+	tests := []struct {
+		status  int64
+		text    string
+		comment string
+		want    string // StatusText afterwards.
+		note    string // Comment afterwards.
+		changed bool
+	}{
+		{404, "", "", "Not Found", "", true},
+		{404, "  ", "", "Not Found", "", true},
+		{200, "OK", "", "OK", "", false},
+		{200, "ok", "", "ok", "", false},
+		{200, "Fine", "cached", "Fine", "cached\nstatusText \"Fine\" differs from \"OK\" for status 200", true},
+		{200, "Fine", `statusText "Fine" differs from "OK" for status 200`, "Fine", `statusText "Fine" differs from "OK" for status 200`, false},
+		{299, "", "", "", "", false},
+		{299, "Custom", "", "Custom", "", false},
+		{999, "", "", "", "", false},
+		{999, "Weird", "", "Weird", "", false},
+		{0, "", "", "", "", false},
+	}
+	for _, tt := range tests {
+		r := &Response{Status: tt.status, StatusText: tt.text, Comment: tt.comment}
+		n := BackfillStatusText(&Log{Entries: []*Entry{{Response: r}, nil, {}}})
+		if r.StatusText != tt.want || r.Comment != tt.note || (n == 1) != tt.changed {
+			t.Errorf("status %d %q: got %q, comment %q, %d changed", tt.status, tt.text, r.StatusText, r.Comment, n)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package harfile
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrWriterClosed is returned when writing to a closed [StreamWriter].
+var ErrWriterClosed = errors.New("harfile: write to closed StreamWriter")
+
+// StreamWriter writes a HAR incrementally, so that entries do not have to be
+// held in memory before being serialized. Each entry is written to the
+// underlying writer as soon as it is passed to WriteEntry.
+//
+// Pages are small and usually few, so they are buffered and emitted by Close
+// after the entries. If the process dies before Close, [Recover] turns the
+// truncated output back into a valid HAR containing every entry that was
+// completely written.
+type StreamWriter struct {
+	w        io.Writer
+	gz       *gzip.Writer
+	creator  *Creator
+	buf      bytes.Buffer
+	enc      *json.Encoder
+	pages    []*Page
+	entries  int
+	progress Progress
+	started  bool
+	closed   bool
+	err      error
+}
+
+// NewStreamWriter returns a StreamWriter writing a HAR 1.2 log created by
+// creator to w. Nothing is written until the first call to WriteEntry,
+// WritePage or Close.
+//
+// With [CompressionGzip], the output is gzip compressed and flushed after
+// every entry, so that a truncated stream still decompresses up to the last
+// entry written.
+func NewStreamWriter(w io.Writer, creator *Creator, opts ...FileOption) *StreamWriter {
+	o := newFileOptions(opts)
+	sw := &StreamWriter{w: w, creator: creator, progress: cmp.Or(o.progress, NoProgress)}
+	if o.compression == CompressionGzip {
+		sw.gz = gzip.NewWriter(w)
+		sw.w = sw.gz
+	}
+	sw.enc = json.NewEncoder(&sw.buf)
+	return sw
+}
+
+// WriteEntry serializes e and writes it to the underlying writer.
+func (sw *StreamWriter) WriteEntry(e *Entry) error {
+	if err := sw.check(); err != nil {
+		return err
+	}
+	sw.buf.Reset()
+	if sw.entries > 0 {
+		sw.buf.WriteByte(',')
+	}
+	if err := sw.enc.Encode(e); err != nil {
+		return err // Nothing was written, the stream is still usable.
+	}
+	sw.entries++
+	if err := sw.flush(); err != nil {
+		return err
+	}
+	sw.progress.Step(StageWrite, int64(sw.entries), -1)
+	return nil
+}
+
+// WritePage records p, to be written by Close.
+func (sw *StreamWriter) WritePage(p *Page) error {
+	if err := sw.check(); err != nil {
+		return err
+	}
+	sw.pages = append(sw.pages, p)
+	return nil
+}
+
+// Entries returns the number of entries written so far.
+func (sw *StreamWriter) Entries() int {
+	return sw.entries
+}
+
+// Close writes the buffered pages and terminates the JSON document, and the
+// gzip stream if compressed. It does not close the underlying writer.
+func (sw *StreamWriter) Close() error {
+	if sw.closed {
+		return sw.err
+	}
+	if err := sw.check(); err != nil {
+		return err
+	}
+	sw.closed = true
+	sw.buf.Reset()
+	sw.buf.WriteByte(']')
+	if len(sw.pages) > 0 {
+		sw.buf.WriteString(`,"pages":`)
+		if err := sw.enc.Encode(sw.pages); err != nil {
+			sw.err = err
+			return err
+		}
+	}
+	sw.buf.WriteString("}}\n")
+	if err := sw.flush(); err != nil {
+		return err
+	}
+	if sw.gz != nil {
+		if err := sw.gz.Close(); err != nil {
+			sw.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// check reports a sticky error and writes the log header on first use.
+func (sw *StreamWriter) check() error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if sw.closed {
+		return ErrWriterClosed
+	}
+	if sw.started {
+		return nil
+	}
+	sw.started = true
+	sw.buf.Reset()
+	sw.buf.WriteString(`{"log":{"version":"1.2","creator":`)
+	if err := sw.enc.Encode(sw.creator); err != nil {
+		sw.err = err
+		return err
+	}
+	sw.buf.WriteString(`,"entries":[`)
+	return sw.flush()
+}
+
+func (sw *StreamWriter) flush() error {
+	if _, err := sw.w.Write(sw.buf.Bytes()); err != nil {
+		sw.err = err
+		return err
+	}
+	if sw.gz != nil {
+		if err := sw.gz.Flush(); err != nil {
+			sw.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Recover returns a reader yielding the JSON document read from r, cut after
+// its last complete value and with every open object and array closed. It is
+// meant to repair the output of a [StreamWriter] that was never closed, but
+// works for any truncated JSON. A complete document is passed through
+// unchanged.
+//
+// A reader failing with io.ErrUnexpectedEOF, such as a gzip reader of a
+// truncated file, is read as ending there.
+//
+// The elements of log.entries and log.pages, and the log.creator and
+// log.browser objects, are only kept whole: a document cut inside an entry
+// is cut back to the end of the previous one, so that every entry
+// recovered is complete.
+//
+// Input is processed as it is read; only the bytes following the last
+// complete value are buffered.
+func Recover(r io.Reader) io.Reader {
+	return &recoverReader{r: r}
+}
+
+type recoverFrame struct {
+	object    bool
+	expectKey bool
+	key       string // Name of the current member of an object.
+}
+
+type recoverReader struct {
+	r   io.Reader
+	in  []byte
+	out []byte
+
+	pending   []byte         // Bytes read after the last safe cut point.
+	stack     []recoverFrame // Containers open at the current position.
+	safe      []byte         // Closing brackets needed at the last safe cut point.
+	key       []byte         // Member name being read.
+	inString  bool
+	isKey     bool
+	escaped   bool
+	inScalar  bool
+	eof       bool
+	finalized bool
+}
+
+func (rr *recoverReader) Read(p []byte) (int, error) {
+	for len(rr.out) == 0 {
+		if rr.finalized {
+			return 0, io.EOF
+		}
+		if rr.eof {
+			rr.finalize()
+			continue
+		}
+		if rr.in == nil {
+			rr.in = make([]byte, 32*1024)
+		}
+		n, err := rr.r.Read(rr.in)
+		for _, c := range rr.in[:n] {
+			rr.feed(c)
+		}
+		// A truncated gzip stream ends with io.ErrUnexpectedEOF.
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			rr.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, rr.out)
+	rr.out = rr.out[n:]
+	return n, nil
+}
+
+func (rr *recoverReader) feed(c byte) {
+	rr.pending = append(rr.pending, c)
+	if rr.inString {
+		switch {
+		case rr.escaped:
+			rr.escaped = false
+			rr.keyByte(c)
+		case c == '\\':
+			rr.escaped = true
+		case c == '"':
+			rr.inString = false
+			if rr.isKey {
+				rr.stack[len(rr.stack)-1].key = string(rr.key)
+			} else {
+				rr.markSafe(len(rr.pending))
+			}
+		default:
+			rr.keyByte(c)
+		}
+		return
+	}
+	if rr.inScalar {
+		switch c {
+		case ' ', '\t', '\r', '\n', ',', ']', '}':
+			rr.inScalar = false
+			rr.markSafe(len(rr.pending) - 1)
+		default:
+			return
+		}
+	}
+	switch c {
+	case '{', '[':
+		rr.stack = append(rr.stack, recoverFrame{object: c == '{', expectKey: c == '{'})
+		rr.markSafe(len(rr.pending))
+	case '}', ']':
+		if len(rr.stack) > 0 {
+			rr.stack = rr.stack[:len(rr.stack)-1]
+		}
+		rr.markSafe(len(rr.pending))
+	case '"':
+		rr.inString = true
+		rr.isKey = len(rr.stack) > 0 && rr.stack[len(rr.stack)-1].object && rr.stack[len(rr.stack)-1].expectKey
+		rr.key = rr.key[:0]
+	case ':':
+		if len(rr.stack) > 0 {
+			rr.stack[len(rr.stack)-1].expectKey = false
+		}
+	case ',':
+		if len(rr.stack) > 0 && rr.stack[len(rr.stack)-1].object {
+			rr.stack[len(rr.stack)-1].expectKey = true
+		}
+	case ' ', '\t', '\r', '\n':
+	default:
+		rr.inScalar = true
+	}
+}
+
+func (rr *recoverReader) keyByte(c byte) {
+	if rr.isKey {
+		rr.key = append(rr.key, c)
+	}
+}
+
+// whole reports whether the current position is inside a value only kept
+// whole: an element of log.entries or log.pages, or log.creator or
+// log.browser.
+func (rr *recoverReader) whole() bool {
+	s := rr.stack
+	if len(s) < 3 || !s[0].object || s[0].key != "log" || !s[1].object {
+		return false
+	}
+	switch s[1].key {
+	case "creator", "browser":
+		return true
+	case "entries", "pages":
+		return len(s) > 3
+	}
+	return false
+}
+
+// markSafe records that the document can be cut after pending[:n] and then
+// closed with the brackets of the containers currently open, unless that
+// would split a value only kept whole.
+func (rr *recoverReader) markSafe(n int) {
+	if rr.whole() {
+		return
+	}
+	rr.out = append(rr.out, rr.pending[:n]...)
+	rr.pending = append(rr.pending[:0], rr.pending[n:]...)
+	rr.safe = rr.safe[:0]
+	for i := len(rr.stack) - 1; i >= 0; i-- {
+		if rr.stack[i].object {
+			rr.safe = append(rr.safe, '}')
+		} else {
+			rr.safe = append(rr.safe, ']')
+		}
+	}
+}
+
+func (rr *recoverReader) finalize() {
+	rr.finalized = true
+	if len(rr.stack) == 0 && !rr.inString {
+		// A complete document, possibly ending with a top-level scalar or
+		// trailing whitespace.
+		rr.out = append(rr.out, rr.pending...)
+		return
+	}
+	rr.out = append(rr.out, rr.safe...)
+}
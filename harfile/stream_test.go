@@ -0,0 +1,262 @@
+package harfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func streamEntry(i int) *Entry {
+	return &Entry{
+		StartedDateTime: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+		Request: &Request{
+			Method:      "GET",
+			URL:         fmt.Sprintf("https://example.com/items/%d?q=\"x\"", i),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []*Cookie{},
+			Headers:     []*NameValuePair{{Name: "Accept", Value: "application/json"}},
+			QueryString: []*NameValuePair{},
+		},
+		Response: &Response{
+			Status:      200,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []*Cookie{},
+			Headers:     []*NameValuePair{},
+			Content: &Content{
+				MimeType: "application/json",
+				Text:     fmt.Sprintf(`{"id":%d,"tags":["a","b"]}`, i),
+			},
+		},
+		Cache:   &Cache{},
+		Timings: &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1},
+	}
+}
+
+// decodeHAR decodes a whole document from r.
+func decodeHAR(r io.Reader) (*HAR, error) {
+	var h HAR
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func TestStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf, &Creator{Name: "test", Version: "1"})
+	page := &Page{StartedDateTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ID: "page_1", Title: "home", PageTimings: &PageTimings{}}
+	if err := sw.WritePage(page); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		e := streamEntry(i)
+		e.Pageref = page.ID
+		if err := sw.WriteEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sw.Entries() != 3 {
+		t.Errorf("Entries = %d, want 3", sw.Entries())
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.WriteEntry(streamEntry(3)); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("WriteEntry after Close = %v, want ErrWriterClosed", err)
+	}
+
+	h, err := decodeHAR(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Log.Entries) != 3 || len(h.Log.Pages) != 1 || h.Log.Creator.Name != "test" {
+		t.Errorf("log = %d entries, %d pages, creator %+v", len(h.Log.Entries), len(h.Log.Pages), h.Log.Creator)
+	}
+
+	// A complete document goes through Recover unchanged.
+	recovered, err := io.ReadAll(Recover(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(recovered, buf.Bytes()) {
+		t.Errorf("Recover changed a complete document:\n%s\n%s", buf.Bytes(), recovered)
+	}
+}
+
+func TestStreamWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewStreamWriter(&buf, &Creator{Name: "test", Version: "1"}).Close(); err != nil {
+		t.Fatal(err)
+	}
+	h, err := decodeHAR(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Log.Entries) != 0 {
+		t.Errorf("got %d entries", len(h.Log.Entries))
+	}
+}
+
+func TestEntryReaderErr(t *testing.T) {
+	er := NewEntryReader(strings.NewReader(`{"log":{"version":"1.2","entries":[`))
+	if er.Log(); er.Err() != nil {
+		t.Errorf("Err = %v before any entry", er.Err())
+	}
+	er = NewEntryReader(strings.NewReader("not a HAR"))
+	if er.Log(); er.Err() == nil {
+		t.Error("malformed document read without error")
+	}
+	if _, err := er.Next(); err != er.Err() {
+		t.Errorf("Next = %v, want the error of Log %v", err, er.Err())
+	}
+}
+
+// writeTruncatable writes n entries without closing the writer and returns
+// the output and the offset at which each entry is complete.
+func writeTruncatable(t *testing.T, n int) ([]byte, []int) {
+	t.Helper()
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf, &Creator{Name: "test", Version: "1"})
+	var ends []int
+	for i := range n {
+		if err := sw.WriteEntry(streamEntry(i)); err != nil {
+			t.Fatal(err)
+		}
+		ends = append(ends, bytes.LastIndexByte(buf.Bytes(), '}')+1)
+	}
+	return buf.Bytes(), ends
+}
+
+func TestRecoverEveryCut(t *testing.T) {
+	out, ends := writeTruncatable(t, 3)
+	header := bytes.Index(out, []byte(`"entries":[`)) + len(`"entries":[`)
+	for n := 0; n <= len(out); n++ {
+		recovered, err := io.ReadAll(Recover(bytes.NewReader(out[:n])))
+		if err != nil {
+			t.Fatalf("cut at %d: %v", n, err)
+		}
+		if n > 0 && !json.Valid(recovered) {
+			t.Fatalf("cut at %d: invalid JSON %s", n, recovered)
+		}
+		if n < header {
+			continue
+		}
+		h, err := decodeHAR(bytes.NewReader(recovered))
+		if err != nil {
+			t.Fatalf("cut at %d: %v\n%s", n, err, recovered)
+		}
+		if err := h.Validate(); err != nil {
+			t.Fatalf("cut at %d: %v\n%s", n, err, recovered)
+		}
+		want := 0
+		for _, end := range ends {
+			if n >= end {
+				want++
+			}
+		}
+		if len(h.Log.Entries) != want {
+			t.Fatalf("cut at %d: recovered %d entries, want %d", n, len(h.Log.Entries), want)
+		}
+	}
+}
+
+func TestRecoverGeneric(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`{"a":[1,2,{"b":"c`, `{"a":[1,2,{}]}`},
+		{`{"a":[1,2,{"b":"c"`, `{"a":[1,2,{"b":"c"}]}`},
+		{`[1,2,3`, `[1,2]`},
+		{`[1,2,3,`, `[1,2,3]`},
+		{`{"k\"ey":tru`, `{}`},
+		{`"str"`, `"str"`},
+	}
+	for _, tt := range tests {
+		got, err := io.ReadAll(Recover(bytes.NewReader([]byte(tt.in))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Recover(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkStreamWriter writes 100k entries per iteration and reports the
+// peak heap, which stays flat since entries are not retained.
+func BenchmarkStreamWriter(b *testing.B) {
+	e := streamEntry(0)
+	b.ReportAllocs()
+	var peak uint64
+	for range b.N {
+		sw := NewStreamWriter(io.Discard, &Creator{Name: "test", Version: "1"})
+		for i := range 100_000 {
+			if err := sw.WriteEntry(e); err != nil {
+				b.Fatal(err)
+			}
+			if i%10_000 == 0 {
+				peak = max(peak, heapInUse())
+			}
+		}
+		if err := sw.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+}
+
+// BenchmarkMarshalLog is the in-memory baseline of BenchmarkStreamWriter:
+// the 100k entries are held in a log and marshaled at once.
+func BenchmarkMarshalLog(b *testing.B) {
+	b.ReportAllocs()
+	var peak uint64
+	for range b.N {
+		h := &HAR{Log: &Log{Version: "1.2", Creator: &Creator{Name: "test", Version: "1"}}}
+		for i := range 100_000 {
+			h.Log.Entries = append(h.Log.Entries, streamEntry(i))
+		}
+		data, err := json.Marshal(h)
+		if err != nil {
+			b.Fatal(err)
+		}
+		peak = max(peak, heapInUse())
+		io.Discard.Write(data)
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+}
+
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
+
+func TestRecoverGzip(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf, NewCreator(), WithCompression(CompressionGzip))
+	for i := range 5 {
+		if err := sw.WriteEntry(streamEntry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The process dies: the gzip stream is flushed but never closed.
+	zr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := Load(Recover(zr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Log.Entries) != 5 {
+		t.Errorf("recovered %d entries, want 5", len(h.Log.Entries))
+	}
+}
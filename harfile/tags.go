@@ -0,0 +1,64 @@
+package harfile
+
+import (
+	"slices"
+	"strings"
+)
+
+// Tags left by the transforms of harfile and harkit.
+const (
+	TagRedacted    = "redacted"     // Values were replaced by Redact or a harkit Policy.
+	TagTruncated   = "truncated"    // A body was cut by a recorder body limit.
+	TagBodyRemoved = "body-removed" // The response body was removed by [Log.Compact].
+	TagMergedFrom  = "merged-from:" // Prefix of the tag naming the file an entry was merged from, see [MergeFiles].
+)
+
+// AddTag adds tag to the tags of e, unless already there.
+func (e *Entry) AddTag(tag string) {
+	if !slices.Contains(e.Tags, tag) {
+		e.Tags = append(e.Tags, tag)
+	}
+}
+
+// HasTag reports whether e is tagged with tag.
+func (e *Entry) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
+}
+
+// RemoveTag removes tag from the tags of e.
+func (e *Entry) RemoveTag(tag string) {
+	e.Tags = slices.DeleteFunc(e.Tags, func(t string) bool { return t == tag })
+	if len(e.Tags) == 0 {
+		e.Tags = nil
+	}
+}
+
+// AddNote appends note to the comment of e, on a line of its own, keeping
+// whatever the comment already says.
+func (e *Entry) AddNote(note string) {
+	e.Comment = appendNote(e.Comment, note)
+}
+
+// appendNote returns comment followed by note on a new line.
+func appendNote(comment, note string) string {
+	if comment = strings.TrimRight(comment, "\n"); comment == "" {
+		return note
+	}
+	return comment + "\n" + note
+}
+
+// ByTag selects entries tagged with tag.
+func ByTag(tag string) Predicate {
+	return func(e *Entry) bool {
+		return e.HasTag(tag)
+	}
+}
+
+// ByAnnotation selects entries whose annotation key is value, see
+// [Entry.Annotations].
+func ByAnnotation(key, value string) Predicate {
+	return func(e *Entry) bool {
+		v, ok := e.Annotations[key]
+		return ok && v == value
+	}
+}
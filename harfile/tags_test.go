@@ -0,0 +1,144 @@
+package harfile
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	e := &Entry{}
+	if e.HasTag(TagRedacted) {
+		t.Error("untagged entry has a tag")
+	}
+	e.AddTag(TagRedacted)
+	e.AddTag("mine")
+	e.AddTag(TagRedacted)
+	if !slices.Equal(e.Tags, []string{TagRedacted, "mine"}) || !e.HasTag("mine") {
+		t.Errorf("tags = %q", e.Tags)
+	}
+	e.RemoveTag(TagRedacted)
+	e.RemoveTag("absent")
+	if !slices.Equal(e.Tags, []string{"mine"}) || e.HasTag(TagRedacted) {
+		t.Errorf("tags after removal = %q", e.Tags)
+	}
+	e.RemoveTag("mine")
+	if e.Tags != nil {
+		t.Errorf("tags = %#v, want nil once empty", e.Tags)
+	}
+}
+
+func TestTagsJSON(t *testing.T) {
+	var e Entry
+	if err := json.Unmarshal([]byte(`{"startedDateTime":"2024-01-01T00:00:00Z","_tags":["a","b"],"_other":1}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(e.Tags, []string{"a", "b"}) || string(e.Extras["_other"]) != "1" {
+		t.Errorf("tags %q, extras %s", e.Tags, e.Extras)
+	}
+	if _, ok := e.Extras["_tags"]; ok {
+		t.Error("_tags kept in Extras too")
+	}
+	data, err := json.Marshal(&e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"_tags":["a","b"]`) {
+		t.Errorf("marshaled %s", data)
+	}
+	if data, _ := json.Marshal(&Entry{}); strings.Contains(string(data), "_tags") {
+		t.Errorf("untagged entry marshaled as %s", data)
+	}
+}
+
+func TestAddNote(t *testing.T) {
+	e := &Entry{}
+	e.AddNote("first")
+	e.AddNote("second")
+	if e.Comment != "first\nsecond" {
+		t.Errorf("comment = %q", e.Comment)
+	}
+	e = &Entry{Comment: "kept\n\n"}
+	e.AddNote("added")
+	if e.Comment != "kept\nadded" {
+		t.Errorf("comment = %q", e.Comment)
+	}
+}
+
+func TestByTag(t *testing.T) {
+	l := &Log{Entries: []*Entry{
+		{Tags: []string{"x", TagTruncated}},
+		{Tags: []string{"x"}},
+		{},
+	}}
+	if got := l.Filter(ByTag(TagTruncated)).Entries; len(got) != 1 || got[0] != l.Entries[0] {
+		t.Errorf("ByTag selected %d entries", len(got))
+	}
+	if got := l.Filter(ByTag("x")).Entries; len(got) != 2 {
+		t.Errorf("ByTag selected %d entries, want 2", len(got))
+	}
+}
+
+func TestByAnnotation(t *testing.T) {
+	l := &Log{Entries: []*Entry{
+		{Annotations: map[string]string{"team": "ads"}},
+		{Annotations: map[string]string{"team": ""}},
+		{},
+	}}
+	if got := l.Filter(ByAnnotation("team", "ads")).Entries; len(got) != 1 || got[0] != l.Entries[0] {
+		t.Errorf("ByAnnotation selected %d entries", len(got))
+	}
+	if got := l.Filter(ByAnnotation("team", "")).Entries; len(got) != 1 || got[0] != l.Entries[1] {
+		t.Errorf("ByAnnotation of an empty value selected %d entries", len(got))
+	}
+
+	// Annotations survive a clone, unshared.
+	c := l.Entries[0].Clone()
+	c.Annotations["team"] = "search"
+	if l.Entries[0].Annotations["team"] != "ads" {
+		t.Error("clone shares the annotations of the original")
+	}
+}
+
+func TestCompactTags(t *testing.T) {
+	e := &Entry{
+		Comment:  "entry note",
+		Response: &Response{Content: &Content{Size: 100, Text: strings.Repeat("x", 100), Comment: "from the browser"}},
+	}
+	small := &Entry{Response: &Response{Content: &Content{Size: 2, Text: "ok"}}}
+	l := &Log{Entries: []*Entry{e, small}}
+	l.Compact(CompactOptions{MaxBodySize: 10})
+	if c := e.Response.Content; c.Comment != "from the browser\nbody of 100 bytes removed" || !e.HasTag(TagBodyRemoved) {
+		t.Errorf("comment %q, tags %q", c.Comment, e.Tags)
+	}
+	if e.Comment != "entry note" {
+		t.Errorf("entry comment = %q", e.Comment)
+	}
+	if small.Tags != nil {
+		t.Errorf("kept body tagged %q", small.Tags)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	entry := &Entry{Tags: []string{"mine"}}
+	a := &HAR{Log: &Log{Entries: []*Entry{entry}}}
+	h, err := Merge(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Log.Entries[0].Tags; !slices.Equal(got, []string{"mine"}) {
+		t.Errorf("Merge tags = %q", got)
+	}
+
+	h, err = merge([]*HAR{a}, []string{"a.har"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Log.Entries[0].Tags; !slices.Equal(got, []string{"mine", TagMergedFrom + "a.har"}) {
+		t.Errorf("merged tags = %q", got)
+	}
+	if !slices.Equal(entry.Tags, []string{"mine"}) {
+		t.Errorf("input tags changed to %q", entry.Tags)
+	}
+}
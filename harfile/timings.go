@@ -0,0 +1,91 @@
+package harfile
+
+import "encoding/json"
+
+// Total returns the total elapsed time described by the timings, in
+// milliseconds: the sum of every phase that applies (value >= 0). Since
+// ssl is included in connect, it is only counted when connect is not
+// available.
+func (t *Timings) Total() float64 {
+	if t == nil {
+		return 0
+	}
+	var total float64
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v >= 0 {
+			total += v
+		}
+	}
+	if t.Connect < 0 && t.Ssl > 0 {
+		total += t.Ssl
+	}
+	return total
+}
+
+// Millis returns the duration of phase, one of "blocked", "dns",
+// "connect", "ssl", "send", "wait" and "receive", in milliseconds, and
+// whether it is known: phases that do not apply are [SizeUnknown].
+func (t *Timings) Millis(phase string) (float64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	var v float64
+	switch phase {
+	case "blocked":
+		v = t.Blocked
+	case "dns":
+		v = t.DNS
+	case "connect":
+		v = t.Connect
+	case "ssl":
+		v = t.Ssl
+	case "send":
+		v = t.Send
+	case "wait":
+		v = t.Wait
+	case "receive":
+		v = t.Receive
+	default:
+		return 0, false
+	}
+	if v < 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+// ComputeTime sets Time to the total of Timings. It does nothing when
+// Timings is nil.
+func (e *Entry) ComputeTime() {
+	if e.Timings != nil {
+		e.Time = e.Timings.Total()
+	}
+}
+
+type timingsAlias Timings
+
+// UnmarshalJSON decodes timings, defaulting the optional blocked, dns,
+// connect and ssl phases to -1 when they are absent, so that a missing phase
+// is not mistaken for a measured 0 ms. Unknown members are kept in Extras.
+func (t *Timings) UnmarshalJSON(data []byte) error {
+	aux := timingsAlias{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*t = Timings(aux)
+	t.Extras = collectExtras(data, timingsFields)
+	return nil
+}
+
+type pageTimingsAlias PageTimings
+
+// UnmarshalJSON decodes page timings, defaulting onContentLoad and onLoad to
+// -1 when they are absent.
+func (t *PageTimings) UnmarshalJSON(data []byte) error {
+	aux := pageTimingsAlias{OnContentLoad: -1, OnLoad: -1}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*t = PageTimings(aux)
+	return nil
+}
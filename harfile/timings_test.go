@@ -0,0 +1,222 @@
+package harfile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimingsTotal(t *testing.T) {
+	tests := []struct {
+		name    string
+		timings *Timings
+		want    float64
+	}{
+		{"nil", nil, 0},
+		{"all zero", &Timings{}, 0},
+		{"all unknown", &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}, 0},
+		{"every phase", &Timings{Blocked: 1, DNS: 2, Connect: 3, Send: 4, Wait: 5, Receive: 6}, 21},
+		{"unknown phases skipped", &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 2, Receive: 3}, 6},
+		{"ssl inside connect", &Timings{Blocked: -1, DNS: -1, Connect: 30, Ssl: 20, Send: 1, Wait: 2, Receive: 3}, 36},
+		{"ssl without connect", &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: 20, Send: 1, Wait: 2, Receive: 3}, 26},
+		{"zero connect keeps ssl out", &Timings{Connect: 0, Ssl: 0, Send: 1}, 1},
+		{"fractions", &Timings{Blocked: 0.1, DNS: 0.2, Connect: -1, Ssl: -1, Send: 0.3, Wait: 0.4, Receive: 0.5}, 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.timings.Total(); got < tt.want-1e-9 || got > tt.want+1e-9 {
+				t.Errorf("Total = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeTime(t *testing.T) {
+	e := &Entry{Time: 7, Timings: &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: 5, Send: 1, Wait: 2, Receive: 3}}
+	e.ComputeTime()
+	if e.Time != 11 {
+		t.Errorf("Time = %v, want 11", e.Time)
+	}
+	e.Timings = nil
+	e.ComputeTime()
+	if e.Time != 11 {
+		t.Errorf("Time = %v after ComputeTime without timings, want it unchanged", e.Time)
+	}
+}
+
+func TestValidateTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		time    float64
+		timings Timings
+		paths   []string
+	}{
+		{"exact", 6, Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 2, Receive: 3}, nil},
+		{"rounded by exporter", 0.6, Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 0.1, Wait: 0.2, Receive: 0.3}, nil},
+		{"within tolerance", 6.009, Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 2, Receive: 3}, nil},
+		{"beyond tolerance", 6.02, Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 2, Receive: 3}, []string{"log.entries[0].time"}},
+		{"ssl counted twice", 56, Timings{Blocked: -1, DNS: -1, Connect: 30, Ssl: 20, Send: 1, Wait: 2, Receive: 3}, []string{"log.entries[0].time"}},
+		{"ssl exceeds connect", 13, Timings{Blocked: -1, DNS: -1, Connect: 10, Ssl: 20, Send: 1, Wait: 2, Receive: 0}, []string{"log.entries[0].timings.ssl"}},
+		{"bad optional value", 6, Timings{Blocked: -2, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 2, Receive: 3}, []string{"log.entries[0].timings.blocked"}},
+		{"negative send", 1, Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: -1, Wait: 0, Receive: 1}, []string{"log.entries[0].timings.send"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timings := tt.timings
+			e := &Entry{
+				StartedDateTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Time:            tt.time,
+				Request:         &Request{Method: "GET", URL: "https://example.com/", Cookies: []*Cookie{}, Headers: []*NameValuePair{}, QueryString: []*NameValuePair{}},
+				Response:        &Response{Status: 200, Cookies: []*Cookie{}, Headers: []*NameValuePair{}, Content: &Content{}},
+				Cache:           &Cache{},
+				Timings:         &timings,
+			}
+			h := &HAR{Log: &Log{Creator: &Creator{Name: "test"}, Entries: []*Entry{e}}}
+			err := h.Validate()
+			var paths []string
+			var verrs ValidationErrors
+			if errors.As(err, &verrs) {
+				for _, ve := range verrs {
+					paths = append(paths, ve.Path)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != len(tt.paths) {
+				t.Fatalf("Validate = %v, want errors at %v", err, tt.paths)
+			}
+			for i := range paths {
+				if paths[i] != tt.paths[i] {
+					t.Errorf("error %d at %s, want %s", i, paths[i], tt.paths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTimingsUnmarshalDefaults(t *testing.T) {
+	var tm Timings
+	if err := json.Unmarshal([]byte(`{"send":1,"wait":2,"receive":3}`), &tm); err != nil {
+		t.Fatal(err)
+	}
+	if tm.Blocked != -1 || tm.DNS != -1 || tm.Connect != -1 || tm.Ssl != -1 {
+		t.Errorf("absent phases = %+v, want -1", tm)
+	}
+	if tm.Total() != 6 {
+		t.Errorf("Total = %v, want 6", tm.Total())
+	}
+	var pt PageTimings
+	if err := json.Unmarshal([]byte(`{}`), &pt); err != nil {
+		t.Fatal(err)
+	}
+	if pt.OnContentLoad != -1 || pt.OnLoad != -1 {
+		t.Errorf("page timings = %+v, want -1", pt)
+	}
+}
+
+// TestTimingsRoundTrip loads browser exports where phases measure 0 ms and
+// checks that the zeros survive a round trip, while absent phases come back
+// as -1.
+func TestTimingsRoundTrip(t *testing.T) {
+	type phases map[string]float64
+	tests := []struct {
+		file    string
+		timings []phases // Every phase written back, per entry.
+		pages   []phases
+	}{
+		{
+			file: "chrome.har",
+			timings: []phases{
+				{"blocked": 0.5, "dns": 0, "connect": 0, "ssl": -1, "send": 0, "wait": 2.5, "receive": 0.25},
+				{"blocked": 0, "dns": -1, "connect": -1, "ssl": -1, "send": 0, "wait": 0, "receive": 0},
+			},
+			pages: []phases{{"onContentLoad": 0, "onLoad": 41.2}},
+		},
+		{
+			file: "firefox.har",
+			timings: []phases{
+				{"blocked": 0, "dns": 0, "connect": 0, "ssl": 0, "send": 0, "wait": 1, "receive": 0},
+				{"blocked": -1, "dns": -1, "connect": -1, "ssl": -1, "send": 0, "wait": 2, "receive": 0},
+			},
+			pages: []phases{{"onContentLoad": 12, "onLoad": 0}, {"onContentLoad": -1, "onLoad": -1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := new(HAR)
+			if err := json.Unmarshal(data, h); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Validate(); err != nil {
+				t.Errorf("export does not validate: %v", err)
+			}
+			for pass := range 2 {
+				data, err := json.Marshal(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var doc struct {
+					Log struct {
+						Pages []struct {
+							PageTimings phases `json:"pageTimings"`
+						} `json:"pages"`
+						Entries []struct {
+							Timings map[string]any `json:"timings"`
+						} `json:"entries"`
+					} `json:"log"`
+				}
+				if err := json.Unmarshal(data, &doc); err != nil {
+					t.Fatal(err)
+				}
+				for i, e := range doc.Log.Entries {
+					for name, want := range tt.timings[i] {
+						if got, ok := e.Timings[name]; !ok || got != want {
+							t.Errorf("pass %d: entry %d %s = %v, want %v", pass, i, name, got, want)
+						}
+					}
+				}
+				for i, p := range doc.Log.Pages {
+					if !reflect.DeepEqual(p.PageTimings, tt.pages[i]) {
+						t.Errorf("pass %d: page %d timings = %v, want %v", pass, i, p.PageTimings, tt.pages[i])
+					}
+				}
+				h = new(HAR)
+				if err := json.Unmarshal(data, h); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestTimingsMillis(t *testing.T) {
+	tm := &Timings{Blocked: -1, DNS: 0, Connect: 12.5, Ssl: -1, Send: 1, Wait: 2, Receive: 3}
+	tests := []struct {
+		phase string
+		want  float64
+		ok    bool
+	}{
+		{"blocked", 0, false},
+		{"dns", 0, true},
+		{"connect", 12.5, true},
+		{"ssl", 0, false},
+		{"receive", 3, true},
+		{"total", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := tm.Millis(tt.phase); got != tt.want || ok != tt.ok {
+			t.Errorf("Millis(%q) = %v, %v, want %v, %v", tt.phase, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, ok := (*Timings)(nil).Millis("send"); ok {
+		t.Error("nil timings have a send phase")
+	}
+}
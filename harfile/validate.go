@@ -0,0 +1,237 @@
+package harfile
+
+import (
+	"fmt"
+	"math"
+)
+
+// TimeTolerance is the difference, in milliseconds, tolerated by Validate
+// between Entry.Time and the total of its timings, to absorb the rounding
+// performed by exporters.
+const TimeTolerance = 0.01
+
+// ValidationError describes a violation of the HAR 1.2 specification.
+type ValidationError struct {
+	Path    string // Location of the offending field, e.g. "log.entries[2].time".
+	Message string // Description of the violation.
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors lists every violation found by [HAR.Validate].
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "harfile: no validation error"
+	case 1:
+		return "harfile: invalid HAR: " + e[0].Error()
+	}
+	return fmt.Sprintf("harfile: invalid HAR: %s (and %d more)", e[0].Error(), len(e)-1)
+}
+
+// Validate checks h against the requirements of the HAR 1.2 specification:
+// required objects and arrays are present, pagerefs name existing pages,
+// timings are within their allowed ranges and each entry's time matches the
+// total of its timings. It returns nil or a [ValidationErrors].
+func (h *HAR) Validate() error {
+	v := &validator{}
+	if h.Log == nil {
+		v.fail("log", "missing")
+		return v.result()
+	}
+	v.log(h.Log)
+	return v.result()
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) result() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *validator) log(l *Log) {
+	if l.Creator == nil {
+		v.fail("log.creator", "missing")
+	} else if l.Creator.Name == "" {
+		v.fail("log.creator.name", "empty")
+	}
+	if l.Entries == nil {
+		v.fail("log.entries", "missing")
+	}
+
+	pages := make(map[string]bool, len(l.Pages))
+	for i, p := range l.Pages {
+		path := fmt.Sprintf("log.pages[%d]", i)
+		if p == nil {
+			v.fail(path, "null page")
+			continue
+		}
+		switch {
+		case p.ID == "":
+			v.fail(path+".id", "empty")
+		case pages[p.ID]:
+			v.fail(path+".id", "duplicate page id %q", p.ID)
+		}
+		pages[p.ID] = true
+		if p.StartedDateTime.IsZero() {
+			v.fail(path+".startedDateTime", "missing")
+		}
+		if p.PageTimings == nil {
+			v.fail(path+".pageTimings", "missing")
+		}
+	}
+
+	for i, e := range l.Entries {
+		path := fmt.Sprintf("log.entries[%d]", i)
+		if e == nil {
+			v.fail(path, "null entry")
+			continue
+		}
+		if e.Pageref != "" && !pages[e.Pageref] {
+			v.fail(path+".pageref", "unknown page %q", e.Pageref)
+		}
+		v.entry(path, e)
+	}
+}
+
+func (v *validator) entry(path string, e *Entry) {
+	if e.StartedDateTime.IsZero() {
+		v.fail(path+".startedDateTime", "missing")
+	}
+	if e.Time < 0 {
+		v.fail(path+".time", "negative")
+	}
+	if e.Request == nil {
+		v.fail(path+".request", "missing")
+	} else {
+		v.request(path+".request", e.Request)
+	}
+	if e.Response == nil {
+		v.fail(path+".response", "missing")
+	} else {
+		v.response(path+".response", e.Response)
+	}
+	if e.Cache == nil {
+		v.fail(path+".cache", "missing")
+	} else {
+		v.cacheData(path+".cache.beforeRequest", e.Cache.BeforeRequest)
+		v.cacheData(path+".cache.afterRequest", e.Cache.AfterRequest)
+	}
+	if e.Timings == nil {
+		v.fail(path+".timings", "missing")
+		return
+	}
+	v.timings(path+".timings", e.Timings)
+	if total := e.Timings.Total(); math.Abs(total-e.Time) > TimeTolerance {
+		v.fail(path+".time", "%v does not match the total of timings %v", e.Time, total)
+	}
+}
+
+func (v *validator) request(path string, r *Request) {
+	if r.Method == "" {
+		v.fail(path+".method", "empty")
+	}
+	if r.URL == "" {
+		v.fail(path+".url", "empty")
+	}
+	v.arrays(path, r.Cookies == nil, r.Headers == nil)
+	if r.QueryString == nil {
+		v.fail(path+".queryString", "missing")
+	}
+	if r.HeadersSize < -1 {
+		v.fail(path+".headersSize", "must be -1 or positive")
+	}
+	if r.BodySize < -1 {
+		v.fail(path+".bodySize", "must be -1 or positive")
+	}
+}
+
+func (v *validator) response(path string, r *Response) {
+	// Status 0 stands for no response: browsers write failed requests that
+	// way, with the failure in _error, and importers requests never sent.
+	if r.Status != 0 && (r.Status < 100 || r.Status > 999) {
+		v.fail(path+".status", "%d is not an HTTP status", r.Status)
+	}
+	v.arrays(path, r.Cookies == nil, r.Headers == nil)
+	if r.Content == nil {
+		v.fail(path+".content", "missing")
+	} else if r.Content.Size < -1 {
+		v.fail(path+".content.size", "must be -1 or positive")
+	}
+	if r.HeadersSize < -1 {
+		v.fail(path+".headersSize", "must be -1 or positive")
+	}
+	if r.BodySize < -1 {
+		v.fail(path+".bodySize", "must be -1 or positive")
+	}
+	// Without compression the sizes may legitimately differ (exporters
+	// disagree on whether bodySize includes framing), so they are only
+	// compared when compression is reported.
+	if c := r.Content; c != nil && c.Compression != 0 && c.Size >= 0 && r.BodySize >= 0 {
+		if c.Size-c.Compression != r.BodySize {
+			v.fail(path+".content.compression", "content.size %d minus compression %d does not match bodySize %d", c.Size, c.Compression, r.BodySize)
+		}
+	}
+}
+
+// cacheData checks the members the spec requires in a cache state, when
+// present. eTag is always written, an empty one meaning none.
+func (v *validator) cacheData(path string, d *CacheData) {
+	if d == nil {
+		return
+	}
+	if d.LastAccess == "" {
+		v.fail(path+".lastAccess", "empty")
+	}
+	if d.HitCount < 0 {
+		v.fail(path+".hitCount", "negative")
+	}
+}
+
+func (v *validator) arrays(path string, nilCookies, nilHeaders bool) {
+	if nilCookies {
+		v.fail(path+".cookies", "missing")
+	}
+	if nilHeaders {
+		v.fail(path+".headers", "missing")
+	}
+}
+
+func (v *validator) timings(path string, t *Timings) {
+	for _, f := range []struct {
+		name     string
+		value    float64
+		optional bool
+	}{
+		{"blocked", t.Blocked, true},
+		{"dns", t.DNS, true},
+		{"connect", t.Connect, true},
+		{"send", t.Send, false},
+		{"wait", t.Wait, false},
+		{"receive", t.Receive, false},
+		{"ssl", t.Ssl, true},
+	} {
+		switch {
+		case f.optional && f.value < 0 && f.value != -1:
+			v.fail(path+"."+f.name, "must be -1 or positive, got %v", f.value)
+		case !f.optional && f.value < 0:
+			v.fail(path+"."+f.name, "must not be negative, got %v", f.value)
+		}
+	}
+	if t.Ssl > 0 && t.Connect >= 0 && t.Ssl > t.Connect+TimeTolerance {
+		v.fail(path+".ssl", "%v exceeds connect %v, which must include it", t.Ssl, t.Connect)
+	}
+}
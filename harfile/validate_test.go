@@ -0,0 +1,80 @@
+package harfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateCache(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache *Cache
+		want  string // Paths of the errors, space separated.
+	}{
+		{"empty", &Cache{}, ""},
+		{"states", &Cache{
+			BeforeRequest: &CacheData{LastAccess: "2024-01-01T00:00:00.000Z", HitCount: 2},
+			AfterRequest:  &CacheData{LastAccess: "2024-01-01T00:00:01.000Z", ETag: `"v1"`, HitCount: 3},
+		}, ""},
+		{"missing lastAccess", &Cache{AfterRequest: &CacheData{ETag: `"v1"`}}, "log.entries[0].cache.afterRequest.lastAccess"},
+		{"negative hitCount", &Cache{BeforeRequest: &CacheData{LastAccess: "2024-01-01T00:00:00.000Z", HitCount: -1}}, "log.entries[0].cache.beforeRequest.hitCount"},
+		{"missing", nil, "log.entries[0].cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEntry().Get("https://example.com/").RespondStatus(200).Build()
+			e.Cache = tt.cache
+			err := NewLog().Entries(e).HAR().Validate()
+			var paths []string
+			var verrs ValidationErrors
+			if errors.As(err, &verrs) {
+				for _, ve := range verrs {
+					paths = append(paths, ve.Path)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(paths, " "); got != tt.want {
+				t.Errorf("errors at %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int64
+		err    string // Error reported, empty when valid.
+		want   string // Paths of the errors, space separated.
+	}{
+		{"ok", 200, "", ""},
+		{"informational", 100, "", ""},
+		{"highest", 999, "", ""},
+		{"failed request", 0, "net::ERR_NAME_NOT_RESOLVED", ""},
+		{"never sent", 0, "", ""},
+		{"below 100", 99, "", "log.entries[0].response.status"},
+		{"above 999", 1000, "", "log.entries[0].response.status"},
+		{"negative", -1, "", "log.entries[0].response.status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEntry().Get("https://example.com/").RespondStatus(200).Build()
+			e.Response.Status, e.Response.Error = tt.status, tt.err
+			err := NewLog().Entries(e).HAR().Validate()
+			var paths []string
+			var verrs ValidationErrors
+			if errors.As(err, &verrs) {
+				for _, ve := range verrs {
+					paths = append(paths, ve.Path)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(paths, " "); got != tt.want {
+				t.Errorf("errors at %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
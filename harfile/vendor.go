@@ -0,0 +1,127 @@
+package harfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Vendors whose exports [NormalizeVendor] knows how to repair, as returned
+// by [Log.Vendor].
+const (
+	VendorCharles = "charles" // Charles Proxy.
+	VendorSafari  = "safari"  // Safari Web Inspector.
+)
+
+// Vendor returns the exporter of l, detected from its creator: one of the
+// Vendor constants, or "" when it is not recognized.
+func (l *Log) Vendor() string {
+	if l.Creator == nil {
+		return ""
+	}
+	name := strings.ToLower(l.Creator.Name)
+	switch {
+	case strings.Contains(name, "charles"):
+		return VendorCharles
+	case strings.Contains(name, "webkit"), strings.Contains(name, "safari"):
+		return VendorSafari
+	}
+	return ""
+}
+
+// NormalizeVendor fills in the members that some exporters leave out, so
+// that code walking h does not meet nil objects the spec makes mandatory,
+// and reports each fix as a Warning. Charles and Safari are the usual
+// culprits, Safari omitting cache and timings altogether:
+//
+//   - a missing cache becomes an empty one;
+//   - missing timings become send and receive of 0 and a wait covering the
+//     entry time, the other phases being -1;
+//   - missing page timings, content and cookie, header or query string
+//     arrays get their empty form;
+//   - Safari entries whose _fetchType says they were served from the memory
+//     or disk cache get a response bodySize of 0, as the spec asks.
+//
+// Vendor extension members, such as _fetchType, are kept in Extras like any
+// other unknown member. [LoadLenient] calls NormalizeVendor after decoding;
+// timestamps and numbers written in unusual forms are fixed there, as they
+// cannot be decoded otherwise.
+func NormalizeVendor(h *HAR) []Warning {
+	if h == nil || h.Log == nil {
+		return nil
+	}
+	l := h.Log
+	var warnings []Warning
+	warn := func(path, format string, args ...any) {
+		warnings = append(warnings, Warning{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	vendor := l.Vendor()
+
+	if l.Entries == nil {
+		l.Entries = []*Entry{}
+		warn("log.entries", "missing array replaced by an empty one")
+	}
+	for i, p := range l.Pages {
+		if p != nil && p.PageTimings == nil {
+			p.PageTimings = &PageTimings{OnContentLoad: -1, OnLoad: -1}
+			warn(fmt.Sprintf("log.pages[%d].pageTimings", i), "missing, set to -1 (not available)")
+		}
+	}
+	for i, e := range l.Entries {
+		if e == nil {
+			continue
+		}
+		path := fmt.Sprintf("log.entries[%d]", i)
+		if e.Cache == nil {
+			e.Cache = &Cache{}
+			warn(path+".cache", "missing, replaced by an empty one")
+		}
+		if e.Timings == nil {
+			e.Timings = &Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Wait: max(e.Time, 0)}
+			warn(path+".timings", "missing, the entry time of %v ms counted as wait", e.Time)
+		}
+		if r := e.Request; r != nil {
+			fillArrays(path+".request", &r.Cookies, &r.Headers, warn)
+			if r.QueryString == nil {
+				r.QueryString = []*NameValuePair{}
+				warn(path+".request.queryString", "missing array replaced by an empty one")
+			}
+		}
+		if r := e.Response; r != nil {
+			fillArrays(path+".response", &r.Cookies, &r.Headers, warn)
+			if r.Content == nil {
+				r.Content = &Content{Size: SizeUnknown, MimeType: headerValue(r.Headers, "Content-Type")}
+				if size, ok := r.KnownBodySize(); ok {
+					r.Content.Size = size
+				}
+				warn(path+".response.content", "missing, described from the response headers")
+			}
+			if vendor == VendorSafari && r.BodySize != 0 && safariFromCache(e) {
+				warn(path+".response.bodySize", "%d set to 0 for a response served from the cache", r.BodySize)
+				r.BodySize = 0
+			}
+		}
+	}
+	return warnings
+}
+
+func fillArrays(path string, cookies *[]*Cookie, headers *[]*NameValuePair, warn func(string, string, ...any)) {
+	if *cookies == nil {
+		*cookies = []*Cookie{}
+		warn(path+".cookies", "missing array replaced by an empty one")
+	}
+	if *headers == nil {
+		*headers = []*NameValuePair{}
+		warn(path+".headers", "missing array replaced by an empty one")
+	}
+}
+
+// safariFromCache reports whether the _fetchType of e, written by Safari,
+// says the response came from the memory or disk cache.
+func safariFromCache(e *Entry) bool {
+	var fetchType string
+	if err := json.Unmarshal(e.Extras["_fetchType"], &fetchType); err != nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(fetchType), "cache")
+}
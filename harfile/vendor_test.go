@@ -0,0 +1,180 @@
+package harfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogVendor(t *testing.T) {
+	tests := []struct {
+		creator *Creator
+		want    string
+	}{
+		{nil, ""},
+		{&Creator{Name: "Charles Proxy"}, VendorCharles},
+		{&Creator{Name: "charles"}, VendorCharles},
+		{&Creator{Name: "WebKit Web Inspector"}, VendorSafari},
+		{&Creator{Name: "Safari"}, VendorSafari},
+		{&Creator{Name: "WebInspector"}, ""},
+		{&Creator{Name: "Firefox"}, ""},
+	}
+	for _, tt := range tests {
+		if got := (&Log{Creator: tt.creator}).Vendor(); got != tt.want {
+			t.Errorf("Vendor for %+v = %q, want %q", tt.creator, got, tt.want)
+		}
+	}
+}
+
+// TestLoadLenientVendor loads exports of Charles and Safari that strict
+// loading rejects or leaves with nil members, and checks what LoadLenient
+// repairs.
+func TestLoadLenientVendor(t *testing.T) {
+	tests := []struct {
+		file     string
+		vendor   string
+		strict   bool     // Whether Load accepts the file.
+		warnings []string // Paths of the expected warnings.
+		check    func(*testing.T, *Log)
+	}{
+		{
+			file:   "charles_quirks.har",
+			vendor: VendorCharles,
+			warnings: []string{
+				"log.entries[0].startedDateTime",
+				"log.entries[0].time",
+				"log.entries[0].request.headersSize",
+				"log.entries[0].request.bodySize",
+				"log.entries[0].response.status",
+				"log.entries[0].response.content.size",
+				"log.entries[0].response.headersSize",
+				"log.entries[0].response.bodySize",
+				"log.entries[0].timings.wait",
+				"log.entries[1].startedDateTime",
+				"log.entries[1].request.cookies",
+				"log.entries[1].request.headers",
+				"log.entries[1].request.queryString",
+				"log.entries[0].cache",
+				"log.entries[1].response.content",
+			},
+			check: func(t *testing.T, l *Log) {
+				e := l.Entries[0]
+				want := time.Date(2024, 3, 1, 9, 0, 1, 250e6, time.UTC)
+				if !e.StartedDateTime.Equal(want) || !l.Entries[1].StartedDateTime.Equal(want.Add(750*time.Millisecond)) {
+					t.Errorf("started at %v and %v", e.StartedDateTime, l.Entries[1].StartedDateTime)
+				}
+				if e.Time != 42 || e.Request.HeadersSize != -1 || e.Request.BodySize != -1 || e.Response.Status != 200 ||
+					e.Response.Content.Size != 2 || e.Response.BodySize != 2 || e.Timings.Wait != 40 {
+					t.Errorf("coerced numbers: time %v, request sizes %d %d, status %d, content %d, body %d, wait %v",
+						e.Time, e.Request.HeadersSize, e.Request.BodySize, e.Response.Status,
+						e.Response.Content.Size, e.Response.BodySize, e.Timings.Wait)
+				}
+				if string(e.Response.Extras["_charlesStatus"]) != `"COMPLETE"` {
+					t.Errorf("response extras = %s", e.Response.Extras)
+				}
+				if c := l.Entries[1].Response.Content; c.Size != SizeUnknown || c.MimeType != "" {
+					t.Errorf("content from headers = %+v", c)
+				}
+			},
+		},
+		{
+			file:   "safari.har",
+			vendor: VendorSafari,
+			strict: true,
+			warnings: []string{
+				"log.pages[0].pageTimings",
+				"log.entries[0].cache",
+				"log.entries[0].timings",
+				"log.entries[1].cache",
+				"log.entries[1].timings",
+				"log.entries[1].response.content",
+				"log.entries[1].response.bodySize",
+			},
+			check: func(t *testing.T, l *Log) {
+				if pt := l.Pages[0].PageTimings; pt.OnContentLoad != -1 || pt.OnLoad != -1 {
+					t.Errorf("page timings = %+v", pt)
+				}
+				network, cached := l.Entries[0], l.Entries[1]
+				if tm := network.Timings; tm.Wait != 85.5 || tm.Send != 0 || tm.Receive != 0 || tm.Blocked != -1 || tm.Total() != network.Time {
+					t.Errorf("timings = %+v", tm)
+				}
+				if network.Response.BodySize != 15 || cached.Response.BodySize != 0 {
+					t.Errorf("body sizes %d and %d, want 15 and 0", network.Response.BodySize, cached.Response.BodySize)
+				}
+				if c := cached.Response.Content; c.Size != 812 || c.MimeType != "text/css" {
+					t.Errorf("content from headers = %+v", c)
+				}
+				if string(cached.Extras["_fetchType"]) != `"Memory Cache"` {
+					t.Errorf("entry extras = %s", cached.Extras)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(strings.NewReader(string(data))); (err == nil) != tt.strict {
+				t.Errorf("Load error = %v", err)
+			}
+
+			h, warnings, err := LoadLenient(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := h.Log.Vendor(); v != tt.vendor {
+				t.Errorf("Vendor = %q, want %q", v, tt.vendor)
+			}
+			var paths []string
+			for _, w := range warnings {
+				paths = append(paths, w.Path)
+			}
+			slices.Sort(paths)
+			want := slices.Clone(tt.warnings)
+			slices.Sort(want)
+			if !slices.Equal(paths, want) {
+				t.Errorf("warnings:\n%s\nwant at:\n%s", strings.Join(paths, "\n"), strings.Join(want, "\n"))
+			}
+
+			for _, e := range h.Log.Entries {
+				if e.Cache == nil || e.Timings == nil || e.Request.Cookies == nil || e.Request.Headers == nil ||
+					e.Request.QueryString == nil || e.Response.Content == nil {
+					t.Errorf("%s: nil member left", e.Request.URL)
+				}
+			}
+			if err := h.Validate(); err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+			tt.check(t, h.Log)
+
+			// A repaired log has nothing left to repair.
+			if again := NormalizeVendor(h); len(again) != 0 {
+				t.Errorf("second NormalizeVendor warned: %v", again)
+			}
+		})
+	}
+}
+
+func TestNormalizeVendorOnlySafariCache(t *testing.T) {
+	// Only Safari's _fetchType zeroes bodySize; another exporter's is left
+	// alone.
+	e := NewEntry().Get("https://example.com/").RespondBody("text/plain", []byte("abc")).Build()
+	e.Extras = map[string]json.RawMessage{"_fetchType": json.RawMessage(`"Disk Cache"`)}
+	h := NewLog(&Creator{Name: "Other", Version: "1"}).Entries(e).HAR()
+	if w := NormalizeVendor(h); len(w) != 0 || h.Log.Entries[0].Response.BodySize != 3 {
+		t.Errorf("warnings %v, bodySize %d", w, h.Log.Entries[0].Response.BodySize)
+	}
+	h.Log.Creator.Name = "Safari"
+	if w := NormalizeVendor(h); len(w) != 1 || h.Log.Entries[0].Response.BodySize != 0 {
+		t.Errorf("warnings %v, bodySize %d", w, h.Log.Entries[0].Response.BodySize)
+	}
+	if NormalizeVendor(nil) != nil || NormalizeVendor(&HAR{}) != nil {
+		t.Error("warnings for a HAR without log")
+	}
+}
@@ -0,0 +1,133 @@
+package harfile
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Protocol is an HTTP version, as read from the httpVersion of a request or
+// response by [Request.Protocol] and [Response.Protocol].
+type Protocol int
+
+const (
+	ProtocolUnknown Protocol = iota // Blank or unrecognized version.
+	ProtocolHTTP10                  // HTTP/1.0.
+	ProtocolHTTP11                  // HTTP/1.1.
+	ProtocolHTTP2                   // HTTP/2, "h2" in ALPN.
+	ProtocolHTTP3                   // HTTP/3, "h3" in ALPN.
+)
+
+// String returns the canonical httpVersion spelling of p, e.g. "HTTP/2.0",
+// or "" for ProtocolUnknown.
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolHTTP10:
+		return "HTTP/1.0"
+	case ProtocolHTTP11:
+		return "HTTP/1.1"
+	case ProtocolHTTP2:
+		return "HTTP/2.0"
+	case ProtocolHTTP3:
+		return "HTTP/3.0"
+	}
+	return ""
+}
+
+// ParseHTTPVersion parses an HTTP version in any of the spellings found in
+// HAR files: "HTTP/1.1", "http/2.0", "HTTP/2", ALPN identifiers such as
+// "h2", "h2c", "h3" or its drafts ("h3-29"), and "http/1.1". Unlike
+// [http.ParseHTTPVersion], a version without minor number is accepted, with
+// a minor of 0.
+func ParseHTTPVersion(s string) (major, minor int, ok bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(s, "http/"):
+		s = s[len("http/"):]
+	case strings.HasPrefix(s, "h") && len(s) > 1:
+		s = strings.TrimSuffix(s[1:], "c") // h2c is HTTP/2 over cleartext.
+		s, _, _ = strings.Cut(s, "-")      // h3-29 is a draft of HTTP/3.
+		if strings.Contains(s, ".") {
+			return 0, 0, false
+		}
+	default:
+		return 0, 0, false
+	}
+	majorText, minorText, hasMinor := strings.Cut(s, ".")
+	major, err := strconv.Atoi(majorText)
+	if err != nil || major < 0 || majorText[0] == '+' {
+		return 0, 0, false
+	}
+	if hasMinor {
+		if minor, err = strconv.Atoi(minorText); err != nil || minor < 0 || minorText[0] == '+' {
+			return 0, 0, false
+		}
+	}
+	return major, minor, true
+}
+
+// ProtocolOf returns the protocol named by version, in any spelling
+// accepted by [ParseHTTPVersion].
+func ProtocolOf(version string) Protocol {
+	major, minor, ok := ParseHTTPVersion(version)
+	switch {
+	case !ok:
+		return ProtocolUnknown
+	case major == 1 && minor == 0:
+		return ProtocolHTTP10
+	case major == 1 && minor == 1:
+		return ProtocolHTTP11
+	case major == 2:
+		return ProtocolHTTP2
+	case major == 3:
+		return ProtocolHTTP3
+	}
+	return ProtocolUnknown
+}
+
+// Protocol returns the protocol of the request, read from HTTPVersion.
+func (r *Request) Protocol() Protocol {
+	return ProtocolOf(r.HTTPVersion)
+}
+
+// Protocol returns the protocol of the response, read from HTTPVersion.
+func (r *Response) Protocol() Protocol {
+	return ProtocolOf(r.HTTPVersion)
+}
+
+// NormalizeHTTPVersions rewrites the httpVersion of every request and
+// response in the canonical spelling of its protocol, e.g. "h2" and
+// "http/2" as "HTTP/2.0". Blank and unrecognized versions are left as they
+// are.
+func (h *HAR) NormalizeHTTPVersions() {
+	if h.Log == nil {
+		return
+	}
+	for _, e := range h.Log.Entries {
+		normalizeVersions(e)
+	}
+}
+
+func normalizeVersions(e *Entry) {
+	if e == nil {
+		return
+	}
+	if e.Request != nil {
+		if p := e.Request.Protocol(); p != ProtocolUnknown {
+			e.Request.HTTPVersion = p.String()
+		}
+	}
+	if e.Response != nil {
+		if p := e.Response.Protocol(); p != ProtocolUnknown {
+			e.Response.HTTPVersion = p.String()
+		}
+	}
+}
+
+// WithNormalizedHTTPVersions makes [Load], [LoadLenient],
+// [LoadWithBodyStore] and [EntryReader] rewrite httpVersion values in their
+// canonical spelling, as [HAR.NormalizeHTTPVersions] does.
+func WithNormalizedHTTPVersions() FileOption {
+	return func(o *fileOptions) {
+		o.normalizeVersions = true
+	}
+}
@@ -0,0 +1,142 @@
+package harfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHTTPVersion(t *testing.T) {
+	tests := []struct {
+		in           string
+		major, minor int
+		ok           bool
+	}{
+		{"HTTP/1.1", 1, 1, true},
+		{"http/1.0", 1, 0, true},
+		{" HTTP/2.0 ", 2, 0, true},
+		{"HTTP/2", 2, 0, true},
+		{"http/3", 3, 0, true},
+		{"h2", 2, 0, true},
+		{"H2C", 2, 0, true},
+		{"h3", 3, 0, true},
+		{"h3-29", 3, 0, true},
+		{"", 0, 0, false},
+		{"h", 0, 0, false},
+		{"h2.0", 0, 0, false},
+		{"HTTP/", 0, 0, false},
+		{"HTTP/x.1", 0, 0, false},
+		{"HTTP/1.x", 0, 0, false},
+		{"HTTP/+1.1", 0, 0, false},
+		{"HTTP/1.-1", 0, 0, false},
+		{"HTTP/1.1.1", 0, 0, false},
+		{"spdy/3.1", 0, 0, false},
+		{"unknown", 0, 0, false},
+	}
+	for _, tt := range tests {
+		major, minor, ok := ParseHTTPVersion(tt.in)
+		if major != tt.major || minor != tt.minor || ok != tt.ok {
+			t.Errorf("ParseHTTPVersion(%q) = %d, %d, %v, want %d, %d, %v", tt.in, major, minor, ok, tt.major, tt.minor, tt.ok)
+		}
+	}
+}
+
+func TestProtocolOf(t *testing.T) {
+	tests := map[string]Protocol{
+		"HTTP/1.0": ProtocolHTTP10,
+		"http/1.1": ProtocolHTTP11,
+		"h2":       ProtocolHTTP2,
+		"HTTP/2":   ProtocolHTTP2,
+		"h3-29":    ProtocolHTTP3,
+		"HTTP/0.9": ProtocolUnknown,
+		"HTTP/1.2": ProtocolUnknown,
+		"HTTP/4":   ProtocolUnknown,
+		"":         ProtocolUnknown,
+	}
+	for in, want := range tests {
+		if got := ProtocolOf(in); got != want {
+			t.Errorf("ProtocolOf(%q) = %v, want %v", in, got, want)
+		}
+	}
+	for p, want := range map[Protocol]string{ProtocolUnknown: "", ProtocolHTTP10: "HTTP/1.0", ProtocolHTTP11: "HTTP/1.1", ProtocolHTTP2: "HTTP/2.0", ProtocolHTTP3: "HTTP/3.0"} {
+		if p.String() != want {
+			t.Errorf("String() = %q, want %q", p.String(), want)
+		}
+		if p != ProtocolUnknown && ProtocolOf(p.String()) != p {
+			t.Errorf("%q does not read back", p.String())
+		}
+	}
+	if p := (&Request{HTTPVersion: "h2"}).Protocol(); p != ProtocolHTTP2 {
+		t.Errorf("Request.Protocol = %v", p)
+	}
+	if p := (&Response{HTTPVersion: "http/1.1"}).Protocol(); p != ProtocolHTTP11 {
+		t.Errorf("Response.Protocol = %v", p)
+	}
+}
+
+// versionDoc is a log whose entries use the given request and response
+// versions, in pairs.
+func versionDoc(versions ...string) string {
+	var entries []string
+	for i := 0; i+1 < len(versions); i += 2 {
+		e := streamEntry(i)
+		e.Request.HTTPVersion, e.Response.HTTPVersion = versions[i], versions[i+1]
+		data, err := e.MarshalJSON()
+		if err != nil {
+			panic(err)
+		}
+		entries = append(entries, string(data))
+	}
+	return `{"log": {"version": "1.2", "creator": {"name": "test", "version": "1"}, "entries": [` + strings.Join(entries, ",") + `]}}`
+}
+
+func TestNormalizeHTTPVersions(t *testing.T) {
+	doc := versionDoc("h2", "http/2", "HTTP/1.1", "http/1.1", "", "spdy/3")
+	want := []string{"HTTP/2.0", "HTTP/2.0", "HTTP/1.1", "HTTP/1.1", "", "spdy/3"}
+	versions := func(entries []*Entry) []string {
+		var v []string
+		for _, e := range entries {
+			v = append(v, e.Request.HTTPVersion, e.Response.HTTPVersion)
+		}
+		return v
+	}
+	check := func(name string, got []string) {
+		t.Helper()
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: versions = %q, want %q", name, got, want)
+		}
+	}
+
+	h, err := Load(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(h.Log.Entries); got[0] != "h2" {
+		t.Errorf("Load without option rewrote versions: %q", got)
+	}
+	h.NormalizeHTTPVersions()
+	check("NormalizeHTTPVersions", versions(h.Log.Entries))
+
+	h, err = Load(strings.NewReader(doc), WithNormalizedHTTPVersions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Load", versions(h.Log.Entries))
+
+	h, _, err = LoadLenient(strings.NewReader(doc), WithNormalizedHTTPVersions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("LoadLenient", versions(h.Log.Entries))
+
+	var read []*Entry
+	for e, err := range NewEntryReader(strings.NewReader(doc), WithNormalizedHTTPVersions()).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		read = append(read, e)
+	}
+	check("EntryReader", versions(read))
+
+	(&HAR{}).NormalizeHTTPVersions()
+	(&HAR{Log: &Log{Entries: []*Entry{nil, {}}}}).NormalizeHTTPVersions()
+}
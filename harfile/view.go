@@ -0,0 +1,202 @@
+package harfile
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SortKey orders the entries returned by [Log.EntriesPage].
+type SortKey int
+
+const (
+	SortByOrder    SortKey = iota // Order of Log.Entries.
+	SortByTime                    // StartedDateTime.
+	SortByDuration                // Entry.Time.
+	SortBySize                    // Response content.size, or bodySize when unknown.
+	SortByStatus                  // Response status.
+	SortByURL                     // Request URL.
+)
+
+// SearchHeaders are the request and response headers whose values
+// [Log.Search] matches, besides the request URL.
+var SearchHeaders = []string{"Content-Type", "Location", "Referer", "User-Agent"}
+
+// entryView caches the orders of the entries of a log and the text they
+// are searched by, so that paging through a large log does not sort it
+// again on each call.
+type entryView struct {
+	mu     sync.Mutex
+	n      int // Number of entries indexed.
+	orders map[SortKey][]int
+	text   []string // Lowercased URL and SearchHeaders values per entry.
+}
+
+// Reindex drops the cached orders and search text of l, to be called
+// after changing its entries. The methods of Log changing them, such as
+// SortEntries, Dedupe and Compact, call it themselves. The cache is
+// otherwise rebuilt, on first use, only when the number of entries
+// changes. Once Reindex has been called,
+// EntriesPage, EntryAt and Search are safe for concurrent use as long as
+// the entries are left unchanged.
+func (l *Log) Reindex() {
+	l.view = &entryView{n: len(l.Entries), orders: make(map[SortKey][]int)}
+}
+
+// entryView returns the cached view of l, building an empty one when
+// missing or stale.
+func (l *Log) entryView() *entryView {
+	if l.view == nil || l.view.n != len(l.Entries) {
+		l.Reindex()
+	}
+	return l.view
+}
+
+// EntryAt returns the entry at index i of l.Entries, or nil when out of
+// range.
+func (l *Log) EntryAt(i int) *Entry {
+	if l == nil || i < 0 || i >= len(l.Entries) {
+		return nil
+	}
+	return l.Entries[i]
+}
+
+// EntriesPage returns up to limit entries of l, skipping the first offset
+// ones once sorted by key. Sorts are stable, so entries with the same key
+// keep the order of l.Entries, and nil entries go last. Each order is
+// computed once and cached until [Log.Reindex]; l.Entries itself is left
+// as is. limit <= 0 returns every entry from offset.
+func (l *Log) EntriesPage(offset, limit int, key SortKey) []*Entry {
+	order := l.entryView().order(l.Entries, key)
+	offset = min(max(offset, 0), len(order))
+	end := len(order)
+	if limit > 0 {
+		end = min(offset+limit, end)
+	}
+	page := make([]*Entry, 0, end-offset)
+	for _, i := range order[offset:end] {
+		page = append(page, l.Entries[i])
+	}
+	return page
+}
+
+// Search returns the indexes in l.Entries of the entries whose request URL,
+// or a value of one of their [SearchHeaders], contains q, ignoring case.
+// The lowercased text searched is cached until [Log.Reindex].
+func (l *Log) Search(q string) []int {
+	text := l.entryView().searchText(l.Entries)
+	q = strings.ToLower(q)
+	var matches []int
+	for i, t := range text {
+		if strings.Contains(t, q) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+func (v *entryView) order(entries []*Entry, key SortKey) []int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if order, ok := v.orders[key]; ok {
+		return order
+	}
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	if key != SortByOrder {
+		slices.SortStableFunc(order, func(i, j int) int {
+			a, b := entries[i], entries[j]
+			switch {
+			case a == nil && b == nil:
+				return 0
+			case a == nil:
+				return 1
+			case b == nil:
+				return -1
+			}
+			return compareEntries(a, b, key)
+		})
+	}
+	v.orders[key] = order
+	return order
+}
+
+// compareEntries compares a and b by key.
+func compareEntries(a, b *Entry, key SortKey) int {
+	switch key {
+	case SortByTime:
+		return a.StartedDateTime.Compare(b.StartedDateTime)
+	case SortByDuration:
+		return cmp.Compare(a.Time, b.Time)
+	case SortBySize:
+		return cmp.Compare(responseSize(a), responseSize(b))
+	case SortByStatus:
+		return cmp.Compare(responseStatus(a), responseStatus(b))
+	case SortByURL:
+		return strings.Compare(requestURL(a), requestURL(b))
+	}
+	return 0
+}
+
+func responseSize(e *Entry) int64 {
+	if e.Response == nil {
+		return -1
+	}
+	if size, ok := e.Response.Content.KnownSize(); ok {
+		return size
+	}
+	if size, ok := e.Response.KnownBodySize(); ok {
+		return size
+	}
+	return SizeUnknown
+}
+
+func responseStatus(e *Entry) int64 {
+	if e.Response == nil {
+		return 0
+	}
+	return e.Response.Status
+}
+
+func requestURL(e *Entry) string {
+	if e.Request == nil {
+		return ""
+	}
+	return e.Request.URL
+}
+
+func (v *entryView) searchText(entries []*Entry) []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.text != nil {
+		return v.text
+	}
+	v.text = make([]string, len(entries))
+	var b strings.Builder
+	for i, e := range entries {
+		if e == nil {
+			continue
+		}
+		b.Reset()
+		b.WriteString(requestURL(e))
+		for _, name := range SearchHeaders {
+			if e.Request != nil {
+				if value := headerValue(e.Request.Headers, name); value != "" {
+					b.WriteByte('\n')
+					b.WriteString(value)
+				}
+			}
+			if e.Response != nil {
+				if value := headerValue(e.Response.Headers, name); value != "" {
+					b.WriteByte('\n')
+					b.WriteString(value)
+				}
+			}
+		}
+		v.text[i] = strings.ToLower(b.String())
+	}
+	return v.text
+}
@@ -0,0 +1,245 @@
+package harfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// viewEntry returns an entry for url started at second sec, taking ms
+// milliseconds and answered with status and size bytes.
+func viewEntry(url string, sec int, ms float64, status, size int64) *Entry {
+	return &Entry{
+		StartedDateTime: time.Date(2024, 1, 1, 0, 0, sec, 0, time.UTC),
+		Time:            ms,
+		Request:         &Request{Method: "GET", URL: url},
+		Response:        &Response{Status: status, BodySize: size, Content: &Content{Size: size}},
+	}
+}
+
+func viewLog() *Log {
+	unknown := viewEntry("https://example.com/e", 4, 40, 200, 70)
+	unknown.Response.Content.Size = -1
+	return &Log{Entries: []*Entry{
+		viewEntry("https://example.com/c", 3, 10, 404, 300),
+		nil,
+		viewEntry("https://example.com/a", 1, 30, 200, 100),
+		{Request: &Request{URL: "https://example.com/d"}},
+		viewEntry("https://example.com/b", 1, 20, 500, 200),
+		unknown,
+	}}
+}
+
+// urls returns the last letter of the URL of each entry, "-" for nil ones.
+func urls(entries []*Entry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		if e == nil {
+			b.WriteByte('-')
+		} else {
+			b.WriteByte(e.Request.URL[len(e.Request.URL)-1])
+		}
+	}
+	return b.String()
+}
+
+func TestEntriesPage(t *testing.T) {
+	tests := []struct {
+		key  SortKey
+		want string
+	}{
+		{SortByOrder, "c-adbe"},
+		{SortByTime, "dabce-"}, // The zero time of d comes first; a and b started together.
+		{SortByDuration, "dcbae-"},
+		{SortBySize, "deabc-"}, // e has an unknown content.size, and d no response.
+		{SortByStatus, "daecb-"},
+		{SortByURL, "abcde-"},
+	}
+	l := viewLog()
+	for _, tt := range tests {
+		if got := urls(l.EntriesPage(0, 0, tt.key)); got != tt.want {
+			t.Errorf("sort key %d: %s, want %s", tt.key, got, tt.want)
+		}
+	}
+	if got := urls(l.Entries); got != "c-adbe" {
+		t.Errorf("EntriesPage reordered l.Entries: %s", got)
+	}
+
+	pages := []struct {
+		offset, limit int
+		want          string
+	}{
+		{0, 2, "ab"},
+		{2, 2, "cd"},
+		{4, 2, "e-"},
+		{5, 10, "-"},
+		{6, 1, ""},
+		{100, 1, ""},
+		{-3, 1, "a"},
+		{3, -1, "de-"},
+	}
+	for _, p := range pages {
+		if got := urls(l.EntriesPage(p.offset, p.limit, SortByURL)); got != p.want {
+			t.Errorf("EntriesPage(%d, %d) = %s, want %s", p.offset, p.limit, got, p.want)
+		}
+	}
+	if got := (&Log{}).EntriesPage(0, 10, SortByTime); len(got) != 0 {
+		t.Errorf("empty log = %v", got)
+	}
+}
+
+func TestEntryAt(t *testing.T) {
+	l := viewLog()
+	if l.EntryAt(2) != l.Entries[2] || l.EntryAt(1) != nil || l.EntryAt(-1) != nil || l.EntryAt(6) != nil {
+		t.Error("EntryAt returned the wrong entries")
+	}
+	if (*Log)(nil).EntryAt(0) != nil {
+		t.Error("nil log")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	l := viewLog()
+	l.Entries[0].Request.Headers = []*NameValuePair{{Name: "user-agent", Value: "Mozilla/5.0 Firefox"}, {Name: "Accept", Value: "text/html"}}
+	l.Entries[2].Response.Headers = []*NameValuePair{{Name: "Location", Value: "/Login"}}
+	tests := []struct {
+		q    string
+		want []int
+	}{
+		{"EXAMPLE.com/a", []int{2}},
+		{"firefox", []int{0}},
+		{"/login", []int{2}},
+		{"text/html", nil}, // Accept is not searched.
+		{"https://", []int{0, 2, 3, 4, 5}},
+		{"", []int{0, 1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		if got := l.Search(tt.q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+// TestViewReindex checks that the methods changing the entries of a log
+// drop its cached orders and search text.
+func TestViewReindex(t *testing.T) {
+	l := viewLog()
+	l.EntriesPage(0, 0, SortByOrder)
+	l.Search("a")
+	l.SortEntries()
+	if got := urls(l.EntriesPage(0, 0, SortByOrder)); got != "dabce-" {
+		t.Errorf("after SortEntries: %s", got)
+	}
+	if got := l.Search("example.com/a"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("search after SortEntries = %v", got)
+	}
+
+	l = viewLog()
+	l.Entries[5].Request.URL = "https://example.com/a"
+	l.Search("a")
+	l.Dedupe(DefaultMatcher)
+	if got := l.Search("example.com/b"); !reflect.DeepEqual(got, []int{4}) {
+		t.Errorf("search after Dedupe = %v", got)
+	}
+
+	l = viewLog()
+	l.Entries[4].Response.Content.MimeType = "image/png"
+	l.Search("a")
+	l.Compact(CompactOptions{DropMimePrefixes: []string{"image/"}})
+	if got := l.Search("example.com/e"); !reflect.DeepEqual(got, []int{4}) {
+		t.Errorf("search after Compact = %v", got)
+	}
+
+	// Decoding into a log replaces its entries, even as many.
+	l = viewLog()
+	l.Search("a")
+	data, err := json.Marshal(&Log{Entries: []*Entry{
+		viewEntry("https://example.com/z", 0, 0, 200, 0), nil, nil, nil, nil, nil,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Search("example.com/z"); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("search after decoding = %v", got)
+	}
+
+	// Changing an entry in place needs Reindex.
+	l = viewLog()
+	l.Search("a")
+	l.Entries[0].Request.URL = "https://example.com/y"
+	if got := l.Search("example.com/y"); got != nil {
+		t.Errorf("search before Reindex = %v", got)
+	}
+	l.Reindex()
+	if got := l.Search("example.com/y"); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("search after Reindex = %v", got)
+	}
+}
+
+func TestFilterDropsView(t *testing.T) {
+	l := viewLog()
+	l.Search("a")
+	f := l.Filter(func(e *Entry) bool { return e.Response != nil && e.Response.Status == 200 })
+	if f.view != nil {
+		t.Fatal("filtered log shares the cached view of the original")
+	}
+	if got := f.Search("example.com/e"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("search of the filtered log = %v", got)
+	}
+	if got := l.Search("example.com/e"); !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("search of the original = %v", got)
+	}
+}
+
+// benchmarkViewLog returns a log of 100k entries with varied URLs, times
+// and sizes.
+func benchmarkViewLog() *Log {
+	l := &Log{Entries: make([]*Entry, 100_000)}
+	for i := range l.Entries {
+		e := viewEntry(fmt.Sprintf("https://example.com/items/%d?page=%d", i*7919%100_000, i%50), i%3600, float64(i%997), 200, int64(i*31%10_000))
+		e.Request.Headers = []*NameValuePair{{Name: "User-Agent", Value: "Mozilla/5.0"}}
+		l.Entries[i] = e
+	}
+	return l
+}
+
+// BenchmarkEntriesPage pages through 100k entries sorted by size, once the
+// order is cached.
+func BenchmarkEntriesPage(b *testing.B) {
+	l := benchmarkViewLog()
+	l.EntriesPage(0, 50, SortBySize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		l.EntriesPage(i*50%len(l.Entries), 50, SortBySize)
+	}
+}
+
+// BenchmarkEntriesPageSort measures the first page under a new sort key,
+// which sorts the 100k entries.
+func BenchmarkEntriesPageSort(b *testing.B) {
+	l := benchmarkViewLog()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		l.Reindex()
+		l.EntriesPage(0, 50, SortByURL)
+	}
+}
+
+// BenchmarkSearch searches 100k entries, once their text is cached.
+func BenchmarkSearch(b *testing.B) {
+	l := benchmarkViewLog()
+	l.Search("")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		l.Search("items/4242?")
+	}
+}
@@ -0,0 +1,101 @@
+package harmatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// IndexSuffix is appended to a cassette path to name its persisted index.
+const IndexSuffix = ".idx"
+
+// Cassette is a recorded HAR used to answer requests in tests, together with
+// its match index.
+type Cassette struct {
+	HAR   *harfile.HAR
+	Index *Index
+}
+
+// OpenCassette loads the HAR stored at path (conventionally
+// testdata/<name>.har) and its index. The index is read from path +
+// [IndexSuffix] when its recorded checksum matches the HAR content; otherwise
+// it is rebuilt and persisted for the next run. Failing to persist the index
+// is not an error, so read-only test directories keep working.
+func OpenCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("harmatch: reading cassette: %w", err)
+	}
+	var h harfile.HAR
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("harmatch: decoding cassette %s: %w", path, err)
+	}
+
+	sum := Checksum(data)
+	if ix, err := loadIndexFile(path+IndexSuffix, sum); err == nil {
+		return &Cassette{HAR: &h, Index: ix}, nil
+	}
+	ix := BuildIndex(&h)
+	ix.Checksum = sum
+	_ = saveIndexFile(path+IndexSuffix, ix)
+	return &Cassette{HAR: &h, Index: ix}, nil
+}
+
+func loadIndexFile(path, checksum string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ix, err := Load(f)
+	if err != nil {
+		return nil, err
+	}
+	if ix.Checksum != checksum {
+		return nil, errors.New("harmatch: stale index")
+	}
+	return ix, nil
+}
+
+func saveIndexFile(path string, ix *Index) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".harmatch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := ix.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Match returns the first recorded entry matching req, comparing request
+// bodies when req has one. req.Body is restored so it can be read again.
+func (c *Cassette) Match(req *http.Request) (*harfile.Entry, bool) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, false
+		}
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		body = b
+	}
+	positions := c.Index.Lookup(RequestFingerprint(req), HashBody(body))
+	if len(positions) == 0 {
+		return nil, false
+	}
+	return c.HAR.Log.Entries[positions[0]], true
+}
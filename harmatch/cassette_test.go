@@ -0,0 +1,123 @@
+package harmatch
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCassette(t testing.TB, dir string, n int) string {
+	t.Helper()
+	data, err := json.Marshal(cassetteHAR(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "api.har")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenCassette(t *testing.T) {
+	path := writeCassette(t, t.TempDir(), 50)
+
+	c, err := OpenCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path + IndexSuffix)
+	if err != nil {
+		t.Fatalf("index not persisted: %v", err)
+	}
+
+	// The persisted index is used while the cassette is unchanged.
+	stat, _ := os.Stat(path + IndexSuffix)
+	warm, err := OpenCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warm.Index.Checksum != c.Index.Checksum || warm.Index.Len() != 50 {
+		t.Errorf("warm index = %s, %d entries", warm.Index.Checksum, warm.Index.Len())
+	}
+	if again, _ := os.Stat(path + IndexSuffix); !again.ModTime().Equal(stat.ModTime()) {
+		t.Error("index rewritten although up to date")
+	}
+
+	e := c.HAR.Log.Entries[0]
+	body := string(requestBody(e.Request))
+	req, _ := http.NewRequest(e.Request.Method, e.Request.URL, strings.NewReader(body))
+	got, ok := warm.Match(req)
+	if !ok || got.Request.URL != e.Request.URL {
+		t.Errorf("Match = %v, %v", got, ok)
+	}
+	if rest, _ := io.ReadAll(req.Body); string(rest) != body {
+		t.Errorf("request body = %q after Match, want %q", rest, body)
+	}
+
+	// A changed cassette makes the index stale: it is rebuilt and saved.
+	writeCassette(t, filepath.Dir(path), 60)
+	fresh, err := OpenCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Index.Len() != 60 || fresh.Index.Checksum == c.Index.Checksum {
+		t.Errorf("stale index reused: %d entries", fresh.Index.Len())
+	}
+	if now, _ := os.ReadFile(path + IndexSuffix); string(now) == string(saved) {
+		t.Error("rebuilt index not persisted")
+	}
+}
+
+func TestOpenCassetteCorruptIndex(t *testing.T) {
+	path := writeCassette(t, t.TempDir(), 10)
+	os.WriteFile(path+IndexSuffix, []byte("garbage"), 0o644)
+	c, err := OpenCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Index.Len() != 10 {
+		t.Errorf("Len = %d, want 10", c.Index.Len())
+	}
+}
+
+func TestOpenCassetteErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := OpenCassette(filepath.Join(dir, "missing.har")); err == nil {
+		t.Error("missing cassette opened")
+	}
+	bad := filepath.Join(dir, "bad.har")
+	os.WriteFile(bad, []byte("{"), 0o644)
+	if _, err := OpenCassette(bad); err == nil {
+		t.Error("malformed cassette opened")
+	}
+}
+
+// BenchmarkOpenCassette compares opening a 20k-entry cassette with its index
+// persisted (warm) and rebuilding it on every open (cold).
+func BenchmarkOpenCassette(b *testing.B) {
+	path := writeCassette(b, b.TempDir(), 20_000)
+	b.Run("cold", func(b *testing.B) {
+		for range b.N {
+			os.Remove(path + IndexSuffix)
+			if _, err := OpenCassette(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("warm", func(b *testing.B) {
+		if _, err := OpenCassette(path); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for range b.N {
+			if _, err := OpenCassette(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
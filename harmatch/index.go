@@ -0,0 +1,152 @@
+// Package harmatch looks up recorded HAR entries that correspond to live
+// requests.
+package harmatch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// indexVersion is bumped whenever the serialized form of [Index] or the
+// fingerprint algorithm changes, invalidating persisted indexes.
+const indexVersion = 1
+
+// Fingerprint returns the match key of a recorded request: the method and the
+// URL without fragment, with query parameters sorted by name. It is the
+// fingerprint of [harfile.DefaultMatcher].
+func Fingerprint(req *harfile.Request) string {
+	return harfile.DefaultMatcher.Fingerprint(req)
+}
+
+// RequestFingerprint returns the match key of a live request, comparable with
+// [Fingerprint].
+func RequestFingerprint(req *http.Request) string {
+	fp, _ := harfile.DefaultMatcher.RequestFingerprint(req) // Fails only when reading the body.
+	return fp
+}
+
+// HashBody returns the hex encoded SHA-256 of body, or "" for an empty body.
+func HashBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func requestBody(req *harfile.Request) []byte {
+	if req == nil || req.PostData == nil {
+		return nil
+	}
+	if body, err := req.PostData.DecodedText(); err == nil {
+		return body
+	}
+	return []byte(req.PostData.Text)
+}
+
+// Index maps fingerprints to the positions of the entries that carry them.
+type Index struct {
+	Checksum string // Checksum of the HAR source the index was built from, see [Checksum].
+
+	entries int
+	keys    map[string][]int
+	bodies  []string
+}
+
+// BuildIndex indexes every entry of h by fingerprint and request body hash.
+func BuildIndex(h *harfile.HAR) *Index {
+	ix := &Index{keys: make(map[string][]int)}
+	if h == nil || h.Log == nil {
+		return ix
+	}
+	ix.entries = len(h.Log.Entries)
+	ix.bodies = make([]string, ix.entries)
+	for i, e := range h.Log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		fp := Fingerprint(e.Request)
+		ix.keys[fp] = append(ix.keys[fp], i)
+		ix.bodies[i] = HashBody(requestBody(e.Request))
+	}
+	return ix
+}
+
+// Len returns the number of entries covered by the index.
+func (ix *Index) Len() int {
+	return ix.entries
+}
+
+// Lookup returns the positions of the entries matching fingerprint in
+// recorded order. When bodyHash is not empty, only entries whose request
+// body has that hash are returned.
+func (ix *Index) Lookup(fingerprint, bodyHash string) []int {
+	positions := ix.keys[fingerprint]
+	if bodyHash == "" {
+		return slices.Clone(positions)
+	}
+	var matched []int
+	for _, i := range positions {
+		if ix.bodies[i] == bodyHash {
+			matched = append(matched, i)
+		}
+	}
+	return matched
+}
+
+type indexFile struct {
+	Version  int              `json:"version"`
+	Checksum string           `json:"checksum"`
+	Entries  int              `json:"entries"`
+	Keys     map[string][]int `json:"keys"`
+	Bodies   []string         `json:"bodies"`
+}
+
+// Save writes the index in a compact JSON form readable by [Load].
+func (ix *Index) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(&indexFile{
+		Version:  indexVersion,
+		Checksum: ix.Checksum,
+		Entries:  ix.entries,
+		Keys:     ix.keys,
+		Bodies:   ix.bodies,
+	})
+}
+
+// Load reads an index written by [Index.Save].
+func Load(r io.Reader) (*Index, error) {
+	var f indexFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("harmatch: decoding index: %w", err)
+	}
+	if f.Version != indexVersion {
+		return nil, fmt.Errorf("harmatch: unsupported index version %d", f.Version)
+	}
+	if len(f.Bodies) != f.Entries {
+		return nil, fmt.Errorf("harmatch: corrupt index: %d body hashes for %d entries", len(f.Bodies), f.Entries)
+	}
+	for key, positions := range f.Keys {
+		for _, i := range positions {
+			if i < 0 || i >= f.Entries {
+				return nil, fmt.Errorf("harmatch: corrupt index: position %d out of range for %q", i, key)
+			}
+		}
+	}
+	if f.Keys == nil {
+		f.Keys = make(map[string][]int)
+	}
+	return &Index{Checksum: f.Checksum, entries: f.Entries, keys: f.Keys, bodies: f.Bodies}, nil
+}
+
+// Checksum returns the checksum identifying a HAR source file's content.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
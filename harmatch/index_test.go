@@ -0,0 +1,128 @@
+package harmatch
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// cassetteHAR returns n entries spread over a few methods, paths, query
+// orders and bodies, so that fingerprints repeat.
+func cassetteHAR(n int) *harfile.HAR {
+	rng := rand.New(rand.NewPCG(1, 2))
+	entries := make([]*harfile.Entry, n)
+	for i := range entries {
+		path := fmt.Sprintf("/items/%d", rng.IntN(n/4+1))
+		query := []string{"a=1", fmt.Sprintf("b=%d", rng.IntN(3)), "c=x"}
+		rng.Shuffle(len(query), func(i, j int) { query[i], query[j] = query[j], query[i] })
+		req := &harfile.Request{Method: "GET", URL: "https://example.com" + path + "?" + strings.Join(query, "&")}
+		if rng.IntN(3) == 0 {
+			req.Method = "POST"
+			req.PostData = &harfile.PostData{MimeType: "application/json", Text: fmt.Sprintf(`{"n":%d}`, rng.IntN(3))}
+		}
+		entries[i] = &harfile.Entry{Request: req, Response: &harfile.Response{Status: 200}}
+	}
+	return &harfile.HAR{Log: &harfile.Log{Entries: entries}}
+}
+
+// scan is the reference the index must agree with: a linear search over the
+// entries.
+func scan(h *harfile.HAR, req *http.Request, body []byte) []int {
+	fp := RequestFingerprint(req)
+	var matched []int
+	for i, e := range h.Log.Entries {
+		if e == nil || e.Request == nil || Fingerprint(e.Request) != fp {
+			continue
+		}
+		if len(body) > 0 && !bytes.Equal(requestBody(e.Request), body) {
+			continue
+		}
+		matched = append(matched, i)
+	}
+	return matched
+}
+
+func TestIndexMatchesScan(t *testing.T) {
+	h := cassetteHAR(400)
+	built := BuildIndex(h)
+	var buf bytes.Buffer
+	if err := built.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probes := 0
+	for _, e := range h.Log.Entries {
+		// Probe with the recorded request, its query reversed, and a body
+		// that was never recorded.
+		u := e.Request.URL
+		base, query, _ := strings.Cut(u, "?")
+		params := strings.Split(query, "&")
+		for i, j := 0, len(params)-1; i < j; i, j = i+1, j-1 {
+			params[i], params[j] = params[j], params[i]
+		}
+		body := requestBody(e.Request)
+		for _, probe := range []struct {
+			url  string
+			body []byte
+		}{
+			{u, body},
+			{base + "?" + strings.Join(params, "&"), body},
+			{u, []byte(`{"n":99}`)},
+		} {
+			req, _ := http.NewRequest(e.Request.Method, probe.url, bytes.NewReader(probe.body))
+			want := scan(h, req, probe.body)
+			for name, ix := range map[string]*Index{"built": built, "loaded": loaded} {
+				got := ix.Lookup(RequestFingerprint(req), HashBody(probe.body))
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s index: %s %s = %v, scan finds %v", name, req.Method, probe.url, got, want)
+				}
+			}
+			probes++
+		}
+	}
+	if probes == 0 {
+		t.Fatal("no probe")
+	}
+}
+
+func TestIndexNilEntries(t *testing.T) {
+	e := &harfile.Entry{Request: &harfile.Request{Method: "GET", URL: "https://example.com/"}}
+	h := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{nil, e}}}
+	ix := BuildIndex(h)
+	if ix.Len() != 2 {
+		t.Errorf("Len = %d, want 2", ix.Len())
+	}
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	if got := ix.Lookup(RequestFingerprint(req), ""); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Lookup = %v, want [1]", got)
+	}
+	if BuildIndex(nil).Len() != 0 {
+		t.Error("nil HAR indexed")
+	}
+}
+
+func TestLoadCorruptIndex(t *testing.T) {
+	tests := []struct{ name, data string }{
+		{"not json", "{"},
+		{"version", `{"version":99,"entries":0,"bodies":[]}`},
+		{"bodies", `{"version":1,"entries":2,"bodies":[""]}`},
+		{"position", `{"version":1,"entries":1,"keys":{"GET x":[3]},"bodies":[""]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(strings.NewReader(tt.data)); err == nil || !strings.HasPrefix(err.Error(), "harmatch: ") {
+				t.Errorf("Load = %v, want a harmatch error", err)
+			}
+		})
+	}
+}
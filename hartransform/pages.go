@@ -0,0 +1,122 @@
+// Package hartransform provides in-place repairs and rewrites of HAR files.
+package hartransform
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// OrphanPolicy tells [FixPageGraph] what to do with entries referencing a
+// page that does not exist.
+type OrphanPolicy int
+
+const (
+	// SynthesizePages adds a placeholder page for each dangling pageref.
+	SynthesizePages OrphanPolicy = iota
+	// ClearPagerefs removes dangling pagerefs, leaving the entries ungrouped.
+	ClearPagerefs
+)
+
+// PageGraphOption configures [FixPageGraph].
+type PageGraphOption func(*pageGraphOptions)
+
+type pageGraphOptions struct {
+	orphans   OrphanPolicy
+	dropEmpty bool
+}
+
+// WithOrphanPolicy selects how dangling pagerefs are repaired. The default
+// is [SynthesizePages].
+func WithOrphanPolicy(p OrphanPolicy) PageGraphOption {
+	return func(o *pageGraphOptions) { o.orphans = p }
+}
+
+// WithDropEmptyPages removes pages no entry refers to.
+func WithDropEmptyPages() PageGraphOption {
+	return func(o *pageGraphOptions) { o.dropEmpty = true }
+}
+
+// PageRename records a duplicate page ID that was given a new identifier.
+type PageRename struct {
+	Index int    // Position of the page in Log.Pages.
+	Old   string // Identifier shared with an earlier page.
+	New   string // Identifier assigned to the page.
+}
+
+// FixReport lists the repairs performed by [FixPageGraph].
+type FixReport struct {
+	Renamed     []PageRename // Duplicate pages given a new identifier.
+	Reassigned  int          // Entries whose pageref moved to a renamed page.
+	Synthesized []string     // Identifiers of placeholder pages added for dangling pagerefs.
+	Cleared     int          // Entries whose dangling pageref was removed.
+	Dropped     []string     // Identifiers of pages removed because no entry referred to them.
+}
+
+// Changed reports whether any repair was performed.
+func (r *FixReport) Changed() bool {
+	return len(r.Renamed) > 0 || r.Reassigned > 0 || len(r.Synthesized) > 0 || r.Cleared > 0 || len(r.Dropped) > 0
+}
+
+// FixPageGraph makes the pages and pagerefs of h consistent, in place, with
+// [harfile.Log.RepairRefs], and reports the repairs:
+//
+//   - pages sharing an ID keep it for the first occurrence while the others
+//     get a "-2", "-3", ... suffix; each entry referring to the duplicated ID
+//     is then assigned to the closest of those pages that started before it;
+//   - dangling pagerefs are handled according to the [OrphanPolicy];
+//   - with [WithDropEmptyPages], pages without entries are removed.
+//
+// Once repaired, every non-empty pageref names exactly one page.
+func FixPageGraph(h *harfile.HAR, opts ...PageGraphOption) *FixReport {
+	o := &pageGraphOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	report := &FixReport{}
+	if h == nil || h.Log == nil {
+		return report
+	}
+	log := h.Log
+
+	refs := make([]string, len(log.Entries))
+	for i, e := range log.Entries {
+		if e != nil {
+			refs[i] = e.Pageref
+		}
+	}
+	dangling := harfile.StubDangling
+	if o.orphans == ClearPagerefs {
+		dangling = harfile.ClearDangling
+	}
+	for _, issue := range log.RepairRefs(harfile.RepairOptions{Dangling: dangling, RenameDuplicates: true}) {
+		switch issue.Kind {
+		case harfile.RefDuplicate:
+			for _, i := range issue.Pages[1:] {
+				report.Renamed = append(report.Renamed, PageRename{Index: i, Old: issue.PageID, New: log.Pages[i].ID})
+			}
+			for _, i := range issue.Entries {
+				if log.Entries[i].Pageref != refs[i] {
+					report.Reassigned++
+				}
+			}
+		case harfile.RefDangling:
+			if dangling == harfile.ClearDangling {
+				report.Cleared += len(issue.Entries)
+			} else {
+				report.Synthesized = append(report.Synthesized, issue.PageID)
+			}
+		}
+	}
+	slices.SortFunc(report.Renamed, func(a, b PageRename) int { return cmp.Compare(a.Index, b.Index) })
+
+	if o.dropEmpty {
+		for _, issue := range log.RepairRefs(harfile.RepairOptions{DropOrphans: true}) {
+			for range issue.Pages {
+				report.Dropped = append(report.Dropped, issue.PageID)
+			}
+		}
+	}
+	return report
+}
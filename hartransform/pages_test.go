@@ -0,0 +1,147 @@
+package hartransform
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+var t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func page(id string, offset time.Duration) *harfile.Page {
+	return &harfile.Page{StartedDateTime: t0.Add(offset), ID: id, Title: id, PageTimings: &harfile.PageTimings{OnContentLoad: -1, OnLoad: -1}}
+}
+
+func pageEntry(pageref string, offset time.Duration) *harfile.Entry {
+	return &harfile.Entry{
+		Pageref:         pageref,
+		StartedDateTime: t0.Add(offset),
+		Request:         &harfile.Request{Method: "GET", URL: "https://example.com/"},
+		Response:        &harfile.Response{Status: 200, Content: &harfile.Content{}},
+	}
+}
+
+func pagerefs(l *harfile.Log) []string {
+	var refs []string
+	for _, e := range l.Entries {
+		refs = append(refs, e.Pageref)
+	}
+	return refs
+}
+
+// graphIssues lists the page IDs used more than once and the pagerefs
+// naming no page.
+func graphIssues(l *harfile.Log) []string {
+	var issues []string
+	seen := make(map[string]bool)
+	for _, p := range l.Pages {
+		if seen[p.ID] {
+			issues = append(issues, "duplicate "+p.ID)
+		}
+		seen[p.ID] = true
+	}
+	for _, e := range l.Entries {
+		if e != nil && e.Pageref != "" && !seen[e.Pageref] {
+			issues = append(issues, "dangling "+e.Pageref)
+		}
+	}
+	return issues
+}
+
+func pageIDs(l *harfile.Log) []string {
+	var ids []string
+	for _, p := range l.Pages {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestFixPageGraphDuplicates(t *testing.T) {
+	h := &harfile.HAR{Log: &harfile.Log{}}
+	h.Log.Pages = []*harfile.Page{page("p", 0), page("q", 5*time.Second), page("p", 10*time.Second), page("p", 20*time.Second)}
+	h.Log.Entries = []*harfile.Entry{
+		pageEntry("p", time.Second),
+		pageEntry("p", 12*time.Second),
+		pageEntry("q", 13*time.Second),
+		pageEntry("p", 25*time.Second),
+		pageEntry("p", -time.Second),
+	}
+
+	report := FixPageGraph(h)
+	if got, want := pageIDs(h.Log), []string{"p", "q", "p-2", "p-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %q, want %q", got, want)
+	}
+	if got, want := pagerefs(h.Log), []string{"p", "p-2", "q", "p-3", "p"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pagerefs = %q, want %q", got, want)
+	}
+	want := []PageRename{{Index: 2, Old: "p", New: "p-2"}, {Index: 3, Old: "p", New: "p-3"}}
+	if !reflect.DeepEqual(report.Renamed, want) || report.Reassigned != 2 || !report.Changed() {
+		t.Errorf("report = %+v", report)
+	}
+	if issues := graphIssues(h.Log); len(issues) != 0 {
+		t.Errorf("issues left: %v", issues)
+	}
+}
+
+func TestFixPageGraphDangling(t *testing.T) {
+	newHAR := func() *harfile.HAR {
+		h := &harfile.HAR{Log: &harfile.Log{}}
+		h.Log.Pages = []*harfile.Page{page("p", 0), page("empty", time.Second)}
+		h.Log.Entries = []*harfile.Entry{
+			pageEntry("p", time.Second),
+			pageEntry("gone", 3*time.Second),
+			pageEntry("gone", 2*time.Second),
+			pageEntry("", 4*time.Second),
+		}
+		return h
+	}
+
+	t.Run("synthesize", func(t *testing.T) {
+		h := newHAR()
+		report := FixPageGraph(h)
+		if !reflect.DeepEqual(report.Synthesized, []string{"gone"}) || report.Cleared != 0 {
+			t.Errorf("report = %+v", report)
+		}
+		stub := h.Log.Pages[len(h.Log.Pages)-1]
+		if stub.ID != "gone" || !stub.StartedDateTime.Equal(t0.Add(2*time.Second)) {
+			t.Errorf("stub = %+v, want started with its earliest entry", stub)
+		}
+		if stub.PageTimings == nil || len(graphIssues(h.Log)) != 0 {
+			t.Errorf("stub = %+v, issues %v", stub, graphIssues(h.Log))
+		}
+	})
+	t.Run("clear", func(t *testing.T) {
+		h := newHAR()
+		report := FixPageGraph(h, WithOrphanPolicy(ClearPagerefs))
+		if report.Cleared != 2 || len(report.Synthesized) != 0 {
+			t.Errorf("report = %+v", report)
+		}
+		if got, want := pagerefs(h.Log), []string{"p", "", "", ""}; !reflect.DeepEqual(got, want) {
+			t.Errorf("pagerefs = %q, want %q", got, want)
+		}
+	})
+	t.Run("drop empty", func(t *testing.T) {
+		h := newHAR()
+		report := FixPageGraph(h, WithOrphanPolicy(ClearPagerefs), WithDropEmptyPages())
+		if !reflect.DeepEqual(report.Dropped, []string{"empty"}) {
+			t.Errorf("report = %+v", report)
+		}
+		if got := pageIDs(h.Log); !reflect.DeepEqual(got, []string{"p"}) {
+			t.Errorf("pages = %q", got)
+		}
+	})
+}
+
+func TestFixPageGraphConsistent(t *testing.T) {
+	h := &harfile.HAR{Log: &harfile.Log{}}
+	h.Log.Pages = []*harfile.Page{page("p", 0)}
+	h.Log.Entries = []*harfile.Entry{pageEntry("p", 0), nil}
+	if report := FixPageGraph(h); report.Changed() {
+		t.Errorf("report = %+v, want no repair", report)
+	}
+	if report := FixPageGraph(nil); report.Changed() {
+		t.Error("nil HAR repaired")
+	}
+}
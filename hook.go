@@ -0,0 +1,114 @@
+package harkit
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Hook receives entries and pages as they are recorded, e.g. to ship them
+// to a message queue. Hooks are called synchronously, on the goroutine that
+// recorded the entry, after the lock of the [Recorder] is released: a slow
+// hook slows the traffic being recorded, see [AsyncHook].
+//
+// Each call receives a deep copy, which the hook owns and may keep or
+// modify. Later changes to the recorded entry, such as WebSocket frames
+// added by [Transport.RecordWSMessage], are not seen by hooks.
+type Hook interface {
+	OnEntry(e *harfile.Entry)
+	OnPage(p *harfile.Page)
+}
+
+// EntryHook adapts a function to a [Hook] receiving entries only. Spooling
+// entries to disk as they are captured looks like:
+//
+//	sw := harfile.NewStreamWriter(f, harfile.NewCreator())
+//	var mu sync.Mutex
+//	t := harkit.NewTransport(nil, harkit.WithHook(harkit.EntryHook(func(e *harfile.Entry) {
+//		mu.Lock()
+//		defer mu.Unlock()
+//		sw.WriteEntry(e)
+//	})))
+type EntryHook func(e *harfile.Entry)
+
+// OnEntry calls f(e).
+func (f EntryHook) OnEntry(e *harfile.Entry) { f(e) }
+
+// OnPage does nothing.
+func (f EntryHook) OnPage(*harfile.Page) {}
+
+// AsyncHook runs a [Hook] on a goroutine of its own, so that recording
+// never waits for it. Entries and pages are queued in a bounded buffer;
+// when the consumer falls behind and the buffer is full, new items are
+// dropped, never blocking the recorder, and counted by Dropped. Close
+// drains the buffer and stops the goroutine.
+type AsyncHook struct {
+	next    Hook
+	queue   chan func()
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAsyncHook starts delivering to next the items passed to the returned
+// hook, buffering up to size of them. A size below 1 means 1.
+func NewAsyncHook(next Hook, size int) *AsyncHook {
+	h := &AsyncHook{
+		next:  next,
+		queue: make(chan func(), max(size, 1)),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(h.done)
+		for f := range h.queue {
+			f()
+		}
+	}()
+	return h
+}
+
+// OnEntry queues e for delivery, or drops it when the buffer is full or the
+// hook closed.
+func (h *AsyncHook) OnEntry(e *harfile.Entry) {
+	h.enqueue(func() { h.next.OnEntry(e) })
+}
+
+// OnPage queues p for delivery, or drops it when the buffer is full or the
+// hook closed.
+func (h *AsyncHook) OnPage(p *harfile.Page) {
+	h.enqueue(func() { h.next.OnPage(p) })
+}
+
+func (h *AsyncHook) enqueue(f func()) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		h.dropped.Add(1)
+		return
+	}
+	select {
+	case h.queue <- f:
+	default:
+		h.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of entries and pages dropped so far.
+func (h *AsyncHook) Dropped() int64 {
+	return h.dropped.Load()
+}
+
+// Close stops accepting items, waits for the queued ones to be delivered
+// and stops the goroutine. Items passed after Close are dropped.
+func (h *AsyncHook) Close() {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+	}
+	h.mu.Unlock()
+	<-h.done
+}
@@ -0,0 +1,201 @@
+package harkit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// TestHookSpool pipes the entries of a Transport and a Middleware sharing a
+// Recorder into a StreamWriter as they are recorded, and reads the spooled
+// file back.
+func TestHookSpool(t *testing.T) {
+	var buf bytes.Buffer
+	sw := harfile.NewStreamWriter(&buf, harfile.NewCreator())
+	var mu sync.Mutex
+	spool := EntryHook(func(e *harfile.Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := sw.WriteEntry(e); err != nil {
+			t.Error(err)
+		}
+	})
+	rec := NewRecorder(RecorderOptions{Hooks: []Hook{spool}})
+
+	srv := httptest.NewServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}), WithRecorder(rec)))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport(nil, WithRecorder(rec))}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, client, fmt.Sprintf("%s/%d", srv.URL, i))
+		}()
+	}
+	wg.Wait()
+	srv.Close() // Waits for the middleware to record.
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	spooled, err := harfile.Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := rec.Snapshot().Log.Entries
+	if len(spooled.Log.Entries) != 20 || len(recorded) != 20 {
+		t.Fatalf("spooled %d entries, recorded %d, want 20", len(spooled.Log.Entries), len(recorded))
+	}
+	urls := func(entries []*harfile.Entry) []string {
+		var s []string
+		for _, e := range entries {
+			s = append(s, e.Request.URL)
+		}
+		slices.Sort(s)
+		return s
+	}
+	if !slices.Equal(urls(spooled.Log.Entries), urls(recorded)) {
+		t.Errorf("spooled %v, recorded %v", urls(spooled.Log.Entries), urls(recorded))
+	}
+}
+
+// recordingHook keeps what it receives.
+type recordingHook struct {
+	mu      sync.Mutex
+	entries []*harfile.Entry
+	pages   []*harfile.Page
+}
+
+func (h *recordingHook) OnEntry(e *harfile.Entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+}
+
+func (h *recordingHook) OnPage(p *harfile.Page) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pages = append(h.pages, p)
+}
+
+func TestHookCopies(t *testing.T) {
+	shared, own := &recordingHook{}, &recordingHook{}
+	rec := NewRecorder(RecorderOptions{Hooks: []Hook{shared, shared}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	rec.AppendPage(harfile.NewPage("page_1").Build())
+	get(t, &http.Client{Transport: NewTransport(nil, WithRecorder(rec), WithHook(own))}, srv.URL+"/own")
+	get(t, &http.Client{Transport: NewTransport(nil, WithRecorder(rec))}, srv.URL+"/other")
+	rec.Append(harfile.NewEntry().Get("https://example.com/appended").Build())
+
+	if len(shared.entries) != 6 || len(shared.pages) != 2 {
+		t.Fatalf("recorder hooks got %d entries and %d pages, want 6 and 2", len(shared.entries), len(shared.pages))
+	}
+	if len(own.entries) != 1 || own.entries[0].Request.URL != srv.URL+"/own" || len(own.pages) != 0 {
+		t.Fatalf("transport hook got %d entries and %d pages", len(own.entries), len(own.pages))
+	}
+
+	// Every hook owns its copy: changing it changes neither the log nor
+	// the copy of another hook.
+	if shared.entries[0] == shared.entries[1] || shared.entries[0] == own.entries[0] || shared.pages[0] == shared.pages[1] {
+		t.Error("hooks share a copy")
+	}
+	for _, e := range append(shared.entries, own.entries...) {
+		e.Request.URL = "changed"
+		e.Response.Status = 0
+	}
+	shared.pages[0].ID = "changed"
+	for _, e := range rec.Snapshot().Log.Entries {
+		if e.Request.URL == "changed" || e.Response.Status == 0 {
+			t.Errorf("hook changed the recorded entry %+v", e.Request)
+		}
+	}
+	if id := rec.Snapshot().Log.Pages[0].ID; id != "page_1" {
+		t.Errorf("hook changed the recorded page to %q", id)
+	}
+}
+
+func TestAsyncHook(t *testing.T) {
+	next := &recordingHook{}
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	h := NewAsyncHook(EntryHook(func(e *harfile.Entry) {
+		if e.Request.URL == "https://example.com/0" {
+			close(blocked)
+			<-release
+		}
+		next.OnEntry(e)
+	}), 2)
+	rec := NewRecorder(RecorderOptions{Hooks: []Hook{h}})
+
+	rec.Append(harfile.NewEntry().Get("https://example.com/0").Build())
+	<-blocked // The consumer holds entry 0; the queue is empty.
+	for i := 1; i <= 4; i++ {
+		rec.Append(harfile.NewEntry().Get(fmt.Sprintf("https://example.com/%d", i)).Build())
+	}
+	rec.AppendPage(harfile.NewPage("p").Build())
+	if d := h.Dropped(); d != 3 {
+		t.Errorf("Dropped = %d with a full queue, want 3", d)
+	}
+	if rec.Len() != 5 {
+		t.Errorf("recorder kept %d entries, want 5", rec.Len())
+	}
+
+	close(release)
+	h.Close()
+	var got []string
+	for _, e := range next.entries {
+		got = append(got, e.Request.URL)
+	}
+	want := []string{"https://example.com/0", "https://example.com/1", "https://example.com/2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+
+	rec.Append(harfile.NewEntry().Build())
+	h.OnPage(harfile.NewPage("late").Build())
+	h.Close() // Closing twice is harmless.
+	if d := h.Dropped(); d != 5 {
+		t.Errorf("Dropped = %d after Close, want 5", d)
+	}
+}
+
+// TestAsyncHookConcurrent records from many goroutines through a slow
+// consumer, for -race, and checks that every item is delivered or counted.
+func TestAsyncHookConcurrent(t *testing.T) {
+	var delivered sync.WaitGroup
+	var mu sync.Mutex
+	n := 0
+	h := NewAsyncHook(EntryHook(func(*harfile.Entry) {
+		mu.Lock()
+		n++
+		mu.Unlock()
+	}), 8)
+	rec := NewRecorder(RecorderOptions{Hooks: []Hook{h}, MaxEntries: 10})
+	for w := range 8 {
+		delivered.Add(1)
+		go func() {
+			defer delivered.Done()
+			for i := range 100 {
+				rec.Append(harfile.NewEntry().Get(fmt.Sprintf("https://example.com/%d/%d", w, i)).Build())
+			}
+		}()
+	}
+	delivered.Wait()
+	h.Close()
+	if got := int64(n) + h.Dropped(); got != 800 {
+		t.Errorf("%d delivered and %d dropped, want 800 in all", n, h.Dropped())
+	}
+}
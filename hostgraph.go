@@ -0,0 +1,275 @@
+package harkit
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Kinds of [GraphNode].
+const (
+	NodePage   = "page"
+	NodeDomain = "domain"
+)
+
+// HostGraphOptions configures [HostGraph].
+type HostGraphOptions struct {
+	// FirstParty lists domains first party on every page, their subdomains
+	// included, besides the domain of the document of each page.
+	FirstParty []string
+	// Domain returns the registrable domain of a host, e.g.
+	// publicsuffix.EffectiveTLDPlusOne from golang.org/x/net. Nil keeps the
+	// last two labels of host names. Hosts it fails on are kept whole.
+	Domain func(host string) (string, error)
+}
+
+// Graph shows which domains a capture contacted and what led to them, see
+// [HostGraph].
+type Graph struct {
+	Nodes []*GraphNode `json:"nodes"` // Pages in log order, then domains by decreasing request count.
+	Edges []*GraphEdge `json:"edges"` // Sorted by source, then target.
+
+	FirstPartyRequests int `json:"firstPartyRequests"`
+	ThirdPartyRequests int `json:"thirdPartyRequests"`
+}
+
+// GraphNode is a page or a registrable domain.
+type GraphNode struct {
+	ID         string   `json:"id"`              // Domain, or "page:" followed by the page ID, "page:" alone for entries without a page.
+	Kind       string   `json:"kind"`            // NodePage or NodeDomain.
+	Label      string   `json:"label"`           // Page title, or domain.
+	FirstParty bool     `json:"firstParty"`      // The domain is first party for a page that requested it.
+	Requests   int      `json:"requests"`        // Entries sent to the domain.
+	Bytes      int64    `json:"bytes"`           // Response bytes received from the domain, counting only known sizes.
+	Hosts      []string `json:"hosts,omitempty"` // Hosts of the domain, sorted.
+}
+
+// GraphEdge counts the requests a node led to another.
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// HostGraph groups the entries of log by registrable domain and links each
+// domain to the domains that triggered its requests: the URL of the Chrome
+// initiator when exported, otherwise the Referer header. Requests with
+// neither, such as page documents, are linked to their page. Requests
+// between hosts of the same domain add no edge.
+//
+// An entry is first party when its domain is the one of the document of
+// its page, the first "document" entry of the page or else its first entry,
+// or under one of opts.FirstParty.
+func HostGraph(log *harfile.Log, opts HostGraphOptions) *Graph {
+	domainOf := func(host string) string {
+		host = strings.ToLower(hostname(host))
+		if opts.Domain == nil || net.ParseIP(host) != nil {
+			return lastLabels(host)
+		}
+		if d, err := opts.Domain(host); err == nil && d != "" {
+			return d
+		}
+		return host
+	}
+
+	g := &Graph{}
+	nodes := make(map[string]*GraphNode)
+	node := func(id, kind, label string) *GraphNode {
+		n := nodes[id]
+		if n == nil {
+			n = &GraphNode{ID: id, Kind: kind, Label: label}
+			nodes[id] = n
+			g.Nodes = append(g.Nodes, n)
+		}
+		return n
+	}
+	for _, p := range log.Pages {
+		if p != nil {
+			node("page:"+p.ID, NodePage, cmp.Or(p.Title, p.ID))
+		}
+	}
+
+	// The document of each page gives its first-party domain.
+	documents := pageDocuments(log)
+
+	edges := make(map[[2]string]*GraphEdge)
+	hosts := make(map[string]map[string]bool)
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		host := requestHost(e.Request.URL)
+		if host == "" {
+			continue
+		}
+		domain := domainOf(host)
+		n := node(domain, NodeDomain, domain)
+		n.Requests++
+		if size := reportSize(e); size > 0 {
+			n.Bytes += size
+		}
+		if hosts[domain] == nil {
+			hosts[domain] = make(map[string]bool)
+		}
+		hosts[domain][host] = true
+
+		if domainOf(requestHost(documents[e.Pageref])) == domain || isUnder(strings.ToLower(hostname(host)), opts.FirstParty) {
+			n.FirstParty = true
+			g.FirstPartyRequests++
+		} else {
+			g.ThirdPartyRequests++
+		}
+
+		from := "page:" + e.Pageref
+		if source := requestHost(initiatorURL(e)); source != "" {
+			from = domainOf(source)
+			node(from, NodeDomain, from)
+		} else {
+			node(from, NodePage, cmp.Or(e.Pageref, "(no page)"))
+		}
+		if from == domain {
+			continue
+		}
+		key := [2]string{from, domain}
+		if edges[key] == nil {
+			edges[key] = &GraphEdge{From: from, To: domain}
+			g.Edges = append(g.Edges, edges[key])
+		}
+		edges[key].Count++
+	}
+
+	for _, n := range g.Nodes {
+		for h := range hosts[n.ID] {
+			n.Hosts = append(n.Hosts, h)
+		}
+		slices.Sort(n.Hosts)
+	}
+	slices.SortStableFunc(g.Nodes, func(a, b *GraphNode) int {
+		if a.Kind != b.Kind {
+			return cmp.Compare(b.Kind, a.Kind) // Pages first.
+		}
+		if a.Kind == NodePage {
+			return 0
+		}
+		return cmp.Or(cmp.Compare(b.Requests, a.Requests), cmp.Compare(a.ID, b.ID))
+	})
+	slices.SortFunc(g.Edges, func(a, b *GraphEdge) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To))
+	})
+	return g
+}
+
+// pageDocuments returns the URL of the document of each page of log, keyed
+// by page ID: its first "document" entry, or else its first entry with a
+// host. Entries without a page are grouped under "".
+func pageDocuments(log *harfile.Log) map[string]string {
+	documents := make(map[string]string)
+	isDocument := make(map[string]bool)
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil || requestHost(e.Request.URL) == "" {
+			continue
+		}
+		doc := e.ResourceType == "document"
+		if _, ok := documents[e.Pageref]; !ok || doc && !isDocument[e.Pageref] {
+			documents[e.Pageref], isDocument[e.Pageref] = e.Request.URL, doc
+		}
+	}
+	return documents
+}
+
+// DOT renders the graph in the Graphviz DOT language, e.g. for
+// "dot -Tsvg". Pages are boxes and third-party domains are filled.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph hosts {\n\trankdir=LR;\n\tnode [fontname=\"sans-serif\"];\n")
+	for _, n := range g.Nodes {
+		if n.Kind == NodePage {
+			fmt.Fprintf(&b, "\t%s [shape=box, label=%s];\n", dotQuote(n.ID), dotQuote(n.Label))
+			continue
+		}
+		requests := "requests"
+		if n.Requests == 1 {
+			requests = "request"
+		}
+		label := fmt.Sprintf("%s\n%d %s, %s", n.Label, n.Requests, requests, formatBytes(n.Bytes))
+		style := ""
+		if !n.FirstParty {
+			style = `, style=filled, fillcolor="#fde2e1"`
+		}
+		fmt.Fprintf(&b, "\t%s [shape=ellipse, label=%s%s];\n", dotQuote(n.ID), dotQuote(label), style)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\t%s -> %s [label=\"%d\"];\n", dotQuote(e.From), dotQuote(e.To), e.Count)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote returns s as a DOT string literal.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// initiatorURL returns the URL of what triggered e: the Chrome initiator,
+// or the top frame of its stack, else the Referer header.
+func initiatorURL(e *harfile.Entry) string {
+	if i := e.Initiator; i != nil {
+		if i.URL != "" {
+			return i.URL
+		}
+		var stack struct {
+			CallFrames []struct {
+				URL string `json:"url"`
+			} `json:"callFrames"`
+		}
+		if len(i.Stack) > 0 && json.Unmarshal(i.Stack, &stack) == nil {
+			for _, f := range stack.CallFrames {
+				if f.URL != "" {
+					return f.URL
+				}
+			}
+		}
+	}
+	return headerValue(e.Request.Headers, "Referer")
+}
+
+// requestHost returns the host of u, port included, or "".
+func requestHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// hostname returns host without its port.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.Trim(host, "[]")
+}
+
+// lastLabels returns the last two labels of a host name, or host itself
+// when it is an IP address.
+func lastLabels(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	return strings.Join(labels[max(len(labels)-2, 0):], ".")
+}
+
+// isUnder reports whether domain is one of domains or a subdomain of one.
+func isUnder(domain string, domains []string) bool {
+	return slices.ContainsFunc(domains, func(d string) bool {
+		d = strings.ToLower(strings.Trim(d, "."))
+		return d != "" && (domain == d || strings.HasSuffix(domain, "."+d))
+	})
+}
@@ -0,0 +1,157 @@
+package harkit
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// graphEntry returns an entry of page for url, answered with size bytes.
+func graphEntry(page, url string, size int64) *harfile.Entry {
+	return &harfile.Entry{
+		Pageref:  page,
+		Request:  &harfile.Request{Method: "GET", URL: url},
+		Response: &harfile.Response{Status: 200, BodySize: size, Content: &harfile.Content{Size: size}},
+	}
+}
+
+// graphLog returns a page of shop.example.com loading a script from a CDN,
+// which loads an ad, a tracker and an API call on a subdomain, plus an
+// entry without a page.
+func graphLog() *harfile.Log {
+	doc := graphEntry("page_1", "https://www.shop.example.com/", 1000)
+	doc.ResourceType = "document"
+	css := graphEntry("page_1", "https://static.shopassets.com/app.css", 200)
+	css.Request.Headers = []*harfile.NameValuePair{{Name: "Referer", Value: "https://www.shop.example.com/"}}
+	script := graphEntry("page_1", "https://cdn.jsdelivr.net/lib.js", 500)
+	script.Initiator = &harfile.Initiator{Type: "parser", URL: "https://www.shop.example.com/"}
+	ad := graphEntry("page_1", "https://ads.tracker.io/ad?id=1", 50)
+	ad.Initiator = &harfile.Initiator{Type: "script", Stack: json.RawMessage(`{"callFrames":[{"url":""},{"url":"https://cdn.jsdelivr.net/lib.js"}]}`)}
+	pixel := graphEntry("page_1", "https://pixel.tracker.io/p.gif", -1)
+	pixel.Initiator = &harfile.Initiator{Type: "script", URL: "https://cdn.jsdelivr.net/lib.js"}
+	api := graphEntry("page_1", "https://api.shopcdn.net:8443/v1/cart", 80)
+	api.Request.Headers = []*harfile.NameValuePair{{Name: "Referer", Value: "https://www.shop.example.com/"}}
+	return &harfile.Log{
+		Pages: []*harfile.Page{{ID: "page_1", Title: "Shop \"home\""}},
+		Entries: []*harfile.Entry{
+			// The document gives the first-party domain, not the first entry.
+			css, doc, script, ad, pixel, api,
+			graphEntry("", "http://192.0.2.1:8080/health", 2),
+			nil,
+			{Request: &harfile.Request{URL: "about:blank"}},
+		},
+	}
+}
+
+func TestHostGraph(t *testing.T) {
+	g := HostGraph(graphLog(), HostGraphOptions{FirstParty: []string{"shopcdn.net"}})
+
+	var nodes []string
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n.ID)
+	}
+	if got := strings.Join(nodes, " "); got != "page:page_1 page: tracker.io 192.0.2.1 example.com jsdelivr.net shopassets.com shopcdn.net" {
+		t.Errorf("nodes = %q", got)
+	}
+	node := func(id string) *GraphNode {
+		for _, n := range g.Nodes {
+			if n.ID == id {
+				return n
+			}
+		}
+		t.Fatalf("no node %q", id)
+		return nil
+	}
+	if n := node("example.com"); n.Requests != 1 || n.Bytes != 1000 || !n.FirstParty || strings.Join(n.Hosts, " ") != "www.shop.example.com" {
+		t.Errorf("example.com = %+v", n)
+	}
+	if n := node("tracker.io"); n.Requests != 2 || n.Bytes != 50 || n.FirstParty {
+		t.Errorf("tracker.io = %+v", n)
+	}
+	if n := node("shopassets.com"); n.FirstParty {
+		t.Errorf("shopassets.com = %+v", n)
+	}
+	if n := node("shopcdn.net"); !n.FirstParty || strings.Join(n.Hosts, " ") != "api.shopcdn.net:8443" {
+		t.Errorf("shopcdn.net = %+v", n)
+	}
+	if n := node("page:"); n.Kind != NodePage || n.Label != "(no page)" {
+		t.Errorf("no page = %+v", n)
+	}
+	// The only entry without a page is its own document.
+	if n := node("192.0.2.1"); !n.FirstParty {
+		t.Errorf("192.0.2.1 = %+v", n)
+	}
+	if g.FirstPartyRequests != 3 || g.ThirdPartyRequests != 4 {
+		t.Errorf("first party %d, third party %d", g.FirstPartyRequests, g.ThirdPartyRequests)
+	}
+
+	var edges []string
+	for _, e := range g.Edges {
+		edges = append(edges, e.From+">"+e.To)
+	}
+	want := "example.com>jsdelivr.net example.com>shopassets.com example.com>shopcdn.net jsdelivr.net>tracker.io page:>192.0.2.1 page:page_1>example.com"
+	if got := strings.Join(edges, " "); got != want {
+		t.Errorf("edges = %q, want %q", got, want)
+	}
+	if e := g.Edges[3]; e.Count != 2 {
+		t.Errorf("tracker.io edge count = %d", e.Count)
+	}
+}
+
+func TestHostGraphDomain(t *testing.T) {
+	// A public suffix list knows co.uk; the fallback keeps two labels.
+	domain := func(host string) (string, error) {
+		if strings.HasSuffix(host, ".co.uk") {
+			labels := strings.Split(host, ".")
+			return strings.Join(labels[len(labels)-3:], "."), nil
+		}
+		if host == "localhost" {
+			return "", errors.New("no suffix")
+		}
+		return lastLabels(host), nil
+	}
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		graphEntry("", "https://www.shop.co.uk/", 1),
+		graphEntry("", "https://img.shop.co.uk/a.png", 1),
+		graphEntry("", "https://www.other.co.uk/", 1),
+		graphEntry("", "http://localhost:3000/", 1),
+		graphEntry("", "http://[2001:db8::1]/", 1),
+	}}
+	var ids []string
+	for _, n := range HostGraph(log, HostGraphOptions{Domain: domain}).Nodes {
+		ids = append(ids, n.ID)
+	}
+	if got := strings.Join(ids, " "); got != "page: shop.co.uk 2001:db8::1 localhost other.co.uk" {
+		t.Errorf("with Domain: %q", got)
+	}
+	ids = ids[:0]
+	for _, n := range HostGraph(log, HostGraphOptions{}).Nodes {
+		ids = append(ids, n.ID)
+	}
+	if got := strings.Join(ids, " "); got != "page: co.uk 2001:db8::1 localhost" {
+		t.Errorf("without Domain: %q", got)
+	}
+}
+
+func TestHostGraphDOT(t *testing.T) {
+	checkGolden(t, "hostgraph.dot", []byte(HostGraph(graphLog(), HostGraphOptions{}).DOT()))
+}
+
+func TestHostGraphJSON(t *testing.T) {
+	g := HostGraph(graphLog(), HostGraphOptions{})
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Graph
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Nodes) != len(g.Nodes) || len(back.Edges) != len(g.Edges) || back.ThirdPartyRequests != g.ThirdPartyRequests ||
+		!strings.Contains(string(data), `"kind":"page"`) || !strings.Contains(string(data), `"firstPartyRequests"`) {
+		t.Errorf("JSON = %s", data)
+	}
+}
@@ -0,0 +1,42 @@
+// Package markdown holds helpers shared by the Markdown renderers.
+package markdown
+
+import "strings"
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", "&lt;",
+	">", "&gt;",
+	"|", `\|`,
+	"#", `\#`,
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// Escape makes s safe to embed in Markdown text and table cells: characters
+// with inline meaning are backslash-escaped, HTML is neutralized and line
+// breaks, which would end a table row, are replaced by spaces.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Code wraps s in an inline code span, choosing a backtick fence longer than
+// any run of backticks in s. Pipes are still escaped so the span can be used
+// in a table cell.
+func Code(s string) string {
+	s = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "|", `\|`).Replace(s)
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
@@ -0,0 +1,150 @@
+package harkit
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// LatencyHistogram counts entries by total time, see [HistogramBy].
+type LatencyHistogram struct {
+	Buckets []float64        `json:"buckets"` // Upper bounds of the buckets in milliseconds, ascending.
+	Counts  map[string][]int `json:"counts"`  // Entries per bucket for each key; the last count is for entries slower than every bound.
+	Skipped int              `json:"skipped"` // Entries left out for having no timings or a time <= 0.
+}
+
+// Histogram counts the entries of log by Entry.Time for each host. It is
+// [HistogramBy] with [ByHost], keeping only the counts; use HistogramBy for
+// the number of skipped entries or another grouping.
+func Histogram(log *harfile.Log, buckets []float64) map[string][]int {
+	return HistogramBy(log, buckets, ByHost).Counts
+}
+
+// HistogramBy counts the entries of log by Entry.Time for each key given by
+// by, ByHost when nil. An entry falls in the first bucket whose bound it
+// does not exceed; buckets are sorted first. Entries without timings or
+// with a time <= 0 were not measured: they are left out and counted in
+// Skipped. Entries without a key are counted under [OrphanKey]. Grouping by
+// path template looks like:
+//
+//	h := harkit.HistogramBy(log, buckets, harkit.ByPathGroup(harkit.PathTemplater(opts)))
+func HistogramBy(log *harfile.Log, buckets []float64, by SplitKey) *LatencyHistogram {
+	if by == nil {
+		by = ByHost
+	}
+	h := &LatencyHistogram{Buckets: slices.Sorted(slices.Values(buckets)), Counts: make(map[string][]int)}
+	for _, e := range log.Entries {
+		if e == nil {
+			continue
+		}
+		if !measured(e) {
+			h.Skipped++
+			continue
+		}
+		key := cmp.Or(by(e), OrphanKey)
+		counts, ok := h.Counts[key]
+		if !ok {
+			counts = make([]int, len(h.Buckets)+1)
+			h.Counts[key] = counts
+		}
+		i, _ := slices.BinarySearch(h.Buckets, e.Time)
+		counts[i]++
+	}
+	return h
+}
+
+// measured reports whether e has a usable total time.
+func measured(e *harfile.Entry) bool {
+	return e.Timings != nil && e.Time > 0
+}
+
+// SLARule is a latency objective: the Percentile of the times of the
+// entries whose URL matches URLPattern must not exceed Threshold.
+type SLARule struct {
+	Name       string  `json:"name,omitempty"`
+	URLPattern string  `json:"urlPattern"` // Regular expression matched against the request URL. Empty matches every URL.
+	Percentile float64 `json:"percentile"` // In (0, 100], e.g. 95; 100 means no request may be slower.
+	Threshold  float64 `json:"threshold"`  // Milliseconds.
+}
+
+// SLAViolation reports a rule whose objective is not met, or that could not
+// be checked.
+type SLAViolation struct {
+	Rule     SLARule    `json:"rule"`
+	Error    string     `json:"error,omitempty"` // Why the rule is invalid; nothing else is then set.
+	Observed float64    `json:"observed"`        // Time at the percentile of the rule, in milliseconds.
+	Matched  int        `json:"matched"`         // Measured entries matching the rule.
+	Skipped  int        `json:"skipped"`         // Matching entries left out for having no timings or a time <= 0.
+	Entries  []SLAEntry `json:"entries"`         // Matching entries slower than the threshold, slowest first.
+}
+
+// SLAEntry is an entry slower than the threshold of a rule.
+type SLAEntry struct {
+	Index int     `json:"index"` // Position in the log.
+	URL   string  `json:"url"`
+	Time  float64 `json:"time"`
+}
+
+// CheckSLA evaluates rules against the entries of log and returns the
+// violated ones, in the order of rules. Percentiles use the nearest-rank
+// method: with 100 entries, the 95th percentile is the 95th fastest time.
+// Unmeasured entries, without timings or with a time <= 0, are left out of
+// the computation and counted in Skipped. A rule matching no measured entry
+// is not violated. A rule with an invalid pattern or percentile is reported
+// as a violation with Error set, so that a CI check fails rather than
+// passing a rule it never evaluated.
+func CheckSLA(log *harfile.Log, rules []SLARule) []SLAViolation {
+	var violations []SLAViolation
+	for _, rule := range rules {
+		re, err := rule.compile()
+		if err != nil {
+			violations = append(violations, SLAViolation{Rule: rule, Error: err.Error(), Entries: []SLAEntry{}})
+			continue
+		}
+
+		v := SLAViolation{Rule: rule, Entries: []SLAEntry{}}
+		var times []float64
+		for i, e := range log.Entries {
+			if e == nil || e.Request == nil || !re.MatchString(e.Request.URL) {
+				continue
+			}
+			if !measured(e) {
+				v.Skipped++
+				continue
+			}
+			times = append(times, e.Time)
+			if e.Time > rule.Threshold {
+				v.Entries = append(v.Entries, SLAEntry{Index: i, URL: e.Request.URL, Time: e.Time})
+			}
+		}
+		if len(times) == 0 {
+			continue
+		}
+		slices.Sort(times)
+		rank := int(math.Ceil(rule.Percentile / 100 * float64(len(times))))
+		v.Observed = times[max(rank, 1)-1]
+		v.Matched = len(times)
+		if v.Observed <= rule.Threshold {
+			continue
+		}
+		slices.SortStableFunc(v.Entries, func(a, b SLAEntry) int { return cmp.Compare(b.Time, a.Time) })
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// compile checks the percentile of r and compiles its pattern.
+func (r SLARule) compile() (*regexp.Regexp, error) {
+	if r.Percentile <= 0 || r.Percentile > 100 || math.IsNaN(r.Percentile) {
+		return nil, fmt.Errorf("harkit: SLA rule %q: percentile %v out of (0, 100]", r.Name, r.Percentile)
+	}
+	re, err := regexp.Compile(r.URLPattern)
+	if err != nil {
+		return nil, fmt.Errorf("harkit: SLA rule %q: %w", r.Name, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,159 @@
+package harkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// latencyEntry returns an entry for url taking ms milliseconds.
+func latencyEntry(url string, ms float64) *harfile.Entry {
+	return &harfile.Entry{
+		Request:  &harfile.Request{Method: "GET", URL: url},
+		Response: &harfile.Response{Status: 200},
+		Time:     ms,
+		Timings:  &harfile.Timings{Send: 0, Wait: ms, Receive: 0},
+	}
+}
+
+func latencyLog() *harfile.Log {
+	unmeasured := latencyEntry("https://api.example.com/users", 0)
+	untimed := latencyEntry("https://api.example.com/users", 40)
+	untimed.Timings = nil
+	return &harfile.Log{Entries: []*harfile.Entry{
+		latencyEntry("https://api.example.com/users", 10),
+		latencyEntry("https://api.example.com/users/1", 50),
+		latencyEntry("https://api.example.com/users/2", 100),
+		latencyEntry("https://api.example.com/users/3", 250),
+		latencyEntry("https://cdn.example.com/app.js", 5),
+		unmeasured,
+		untimed,
+		nil,
+		{Time: 30, Timings: &harfile.Timings{Wait: 30}},
+	}}
+}
+
+func TestHistogram(t *testing.T) {
+	got := Histogram(latencyLog(), []float64{100, 10})
+	want := map[string][]int{
+		"api.example.com": {1, 2, 1},
+		"cdn.example.com": {1, 0, 0},
+		OrphanKey:         {0, 1, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Histogram = %v, want %v", got, want)
+	}
+
+	h := HistogramBy(latencyLog(), []float64{100, 10}, nil)
+	if !reflect.DeepEqual(h.Counts, want) || h.Skipped != 2 || !reflect.DeepEqual(h.Buckets, []float64{10, 100}) {
+		t.Errorf("HistogramBy = %+v", h)
+	}
+	byMethod := HistogramBy(latencyLog(), nil, func(e *harfile.Entry) string { return fmt.Sprint(e.Time > 60) })
+	if !reflect.DeepEqual(byMethod.Counts, map[string][]int{"false": {4}, "true": {2}}) {
+		t.Errorf("custom key counts = %v", byMethod.Counts)
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil || !strings.Contains(string(data), `"buckets":[10,100]`) || !strings.Contains(string(data), `"skipped":2`) {
+		t.Errorf("JSON = %s, %v", data, err)
+	}
+	if got := Histogram(&harfile.Log{}, []float64{10}); len(got) != 0 {
+		t.Errorf("empty log = %v", got)
+	}
+}
+
+func TestCheckSLA(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     SLARule
+		observed float64 // Zero when the rule is met.
+		slow     string  // URLs of the entries attached to the violation.
+	}{
+		{"p50 met", SLARule{URLPattern: `/users`, Percentile: 50, Threshold: 50}, 0, ""},
+		{"p75 violated", SLARule{URLPattern: `/users`, Percentile: 75, Threshold: 50}, 100, "/users/3 /users/2"},
+		{"p100 violated", SLARule{URLPattern: `/users`, Percentile: 100, Threshold: 200}, 250, "/users/3"},
+		{"nearest rank rounds up", SLARule{URLPattern: `/users`, Percentile: 51, Threshold: 50}, 100, "/users/3 /users/2"},
+		{"small percentile", SLARule{URLPattern: `/users`, Percentile: 0.1, Threshold: 5}, 10, "/users/3 /users/2 /users/1 /users"},
+		{"threshold equal", SLARule{URLPattern: `/users`, Percentile: 100, Threshold: 250}, 0, ""},
+		{"other host", SLARule{URLPattern: `^https://cdn\.`, Percentile: 95, Threshold: 1}, 5, "/app.js"},
+		{"no match", SLARule{URLPattern: `/orders`, Percentile: 95, Threshold: 1}, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckSLA(latencyLog(), []SLARule{tt.rule})
+			if tt.observed == 0 {
+				if len(got) != 0 {
+					t.Errorf("violations = %+v", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d violations, want 1", len(got))
+			}
+			v := got[0]
+			var slow []string
+			for _, e := range v.Entries {
+				slow = append(slow, e.URL[strings.Index(e.URL, ".com")+4:])
+			}
+			if v.Observed != tt.observed || strings.Join(slow, " ") != tt.slow || v.Error != "" {
+				t.Errorf("observed %v, slow %q, error %q, want %v, %q", v.Observed, slow, v.Error, tt.observed, tt.slow)
+			}
+		})
+	}
+}
+
+func TestCheckSLASkipped(t *testing.T) {
+	got := CheckSLA(latencyLog(), []SLARule{{Name: "api", URLPattern: `api\.example\.com`, Percentile: 95, Threshold: 200}})
+	if len(got) != 1 {
+		t.Fatalf("got %d violations, want 1", len(got))
+	}
+	v := got[0]
+	if v.Matched != 4 || v.Skipped != 2 || v.Observed != 250 {
+		t.Errorf("matched %d, skipped %d, observed %v", v.Matched, v.Skipped, v.Observed)
+	}
+	if len(v.Entries) != 1 || v.Entries[0] != (SLAEntry{Index: 3, URL: "https://api.example.com/users/3", Time: 250}) {
+		t.Errorf("entries = %+v", v.Entries)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back []SLAViolation
+	if err := json.Unmarshal(data, &back); err != nil || !reflect.DeepEqual(back, got) {
+		t.Errorf("JSON round trip = %+v, %v", back, err)
+	}
+	if strings.Contains(string(data), `"error"`) {
+		t.Errorf("JSON = %s", data)
+	}
+
+	// Entries are only skipped when the rule matches them.
+	if got := CheckSLA(latencyLog(), []SLARule{{URLPattern: `cdn`, Percentile: 50, Threshold: 1}}); len(got) != 1 || got[0].Skipped != 0 {
+		t.Errorf("cdn violations = %+v", got)
+	}
+}
+
+func TestCheckSLAInvalidRules(t *testing.T) {
+	rules := []SLARule{
+		{Name: "zero", Percentile: 0, Threshold: 10},
+		{Name: "above 100", Percentile: 101, Threshold: 10},
+		{Name: "pattern", URLPattern: `(`, Percentile: 50, Threshold: 10},
+		{Name: "met", Percentile: 50, Threshold: 1000},
+	}
+	got := CheckSLA(latencyLog(), rules)
+	if len(got) != 3 {
+		t.Fatalf("got %d violations, want 3", len(got))
+	}
+	for i, v := range got {
+		if v.Rule != rules[i] || !strings.HasPrefix(v.Error, fmt.Sprintf("harkit: SLA rule %q: ", rules[i].Name)) || v.Matched != 0 {
+			t.Errorf("violation %d = %+v", i, v)
+		}
+	}
+	if CheckSLA(latencyLog(), nil) != nil {
+		t.Error("violations without rules")
+	}
+}
@@ -0,0 +1,218 @@
+package harkit
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// LoadTestOptions configures [ToK6Script] and [ToVegetaTargets].
+type LoadTestOptions struct {
+	BaseURL          string   // Scheme and host, e.g. "http://localhost:8080", replacing the recorded ones.
+	StripCookies     bool     // Drop Cookie headers.
+	SkipMimePrefixes []string // Entries whose response MIME type starts with one of these, e.g. "image/" or "font/", are skipped.
+}
+
+// loadTarget is a request prepared for a load-test script.
+type loadTarget struct {
+	method  string
+	url     string
+	headers []*harfile.NameValuePair
+	body    []byte
+	start   time.Time
+	end     time.Time
+	pageref string
+}
+
+// loadTargets returns the requests of log selected by opts, in start order.
+func loadTargets(log *harfile.Log, opts LoadTestOptions) ([]*loadTarget, error) {
+	var base *url.URL
+	if opts.BaseURL != "" {
+		var err error
+		if base, err = url.Parse(opts.BaseURL); err != nil {
+			return nil, fmt.Errorf("harkit: base URL: %w", err)
+		}
+	}
+
+	var targets []*loadTarget
+	for i, e := range log.Entries {
+		if e == nil || e.Request == nil || skipMime(e, opts.SkipMimePrefixes) {
+			continue
+		}
+		t := &loadTarget{
+			method:  e.Request.Method,
+			url:     e.Request.URL,
+			start:   e.StartedDateTime,
+			end:     e.StartedDateTime.Add(time.Duration(max(e.Time, 0) * float64(time.Millisecond))),
+			pageref: e.Pageref,
+		}
+		if base != nil {
+			u, err := url.Parse(t.url)
+			if err != nil {
+				return nil, fmt.Errorf("harkit: entry %d: %w", i, err)
+			}
+			u.Scheme, u.Host = base.Scheme, base.Host
+			t.url = u.String()
+		}
+		for _, h := range e.Request.Headers {
+			if h == nil || strings.HasPrefix(h.Name, ":") ||
+				strings.EqualFold(h.Name, "Host") || strings.EqualFold(h.Name, "Content-Length") ||
+				opts.StripCookies && strings.EqualFold(h.Name, "Cookie") {
+				continue
+			}
+			t.headers = append(t.headers, h)
+		}
+		if e.Request.PostData != nil {
+			r, _, err := e.Request.PostData.BodyReader()
+			if err != nil {
+				return nil, fmt.Errorf("harkit: entry %d: %w", i, err)
+			}
+			if t.body, err = io.ReadAll(r); err != nil {
+				return nil, fmt.Errorf("harkit: entry %d: %w", i, err)
+			}
+		}
+		targets = append(targets, t)
+	}
+	slices.SortStableFunc(targets, func(a, b *loadTarget) int {
+		return a.start.Compare(b.start)
+	})
+	return targets, nil
+}
+
+func skipMime(e *harfile.Entry, prefixes []string) bool {
+	if e.Response == nil || e.Response.Content == nil {
+		return false
+	}
+	mime := strings.ToLower(strings.TrimSpace(e.Response.Content.MimeType))
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return strings.HasPrefix(mime, strings.ToLower(prefix))
+	})
+}
+
+// ToK6Script generates a k6 script replaying log as one virtual user
+// iteration: one http.request per entry, in start order, with sleep calls
+// for the recorded idle time between requests. Consecutive entries of the
+// same page are wrapped in a group named after the page title. Binary
+// bodies are embedded base64 encoded.
+func ToK6Script(log *harfile.Log, opts LoadTestOptions) ([]byte, error) {
+	targets, err := loadTargets(log, opts)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string)
+	for _, p := range log.Pages {
+		if p != nil {
+			titles[p.ID] = p.Title
+		}
+	}
+
+	var body bytes.Buffer
+	var usesEncoding bool
+	var group string
+	var lastEnd time.Time
+	indent := "  "
+	for _, t := range targets {
+		if t.pageref != group {
+			if group != "" {
+				body.WriteString("  });\n")
+			}
+			group, indent = t.pageref, "  "
+			if group != "" {
+				fmt.Fprintf(&body, "  group(%s, function () {\n", jsString(cmp.Or(titles[group], group)))
+				indent = "    "
+			}
+		}
+		if !lastEnd.IsZero() {
+			if gap := t.start.Sub(lastEnd); gap >= time.Millisecond {
+				fmt.Fprintf(&body, "%ssleep(%.3f);\n", indent, gap.Seconds())
+			}
+		}
+		if t.end.After(lastEnd) {
+			lastEnd = t.end
+		}
+
+		payload := "null"
+		switch {
+		case len(t.body) == 0:
+		case utf8.Valid(t.body):
+			payload = jsString(string(t.body))
+		default:
+			usesEncoding = true
+			payload = "encoding.b64decode(" + jsString(base64.StdEncoding.EncodeToString(t.body)) + ")"
+		}
+		fmt.Fprintf(&body, "%shttp.request(%s, %s, %s, {\n%s  headers: {\n", indent, jsString(t.method), jsString(t.url), payload, indent)
+		for _, h := range t.headers {
+			fmt.Fprintf(&body, "%s    %s: %s,\n", indent, jsString(h.Name), jsString(h.Value))
+		}
+		fmt.Fprintf(&body, "%s  },\n%s});\n", indent, indent)
+	}
+	if group != "" {
+		body.WriteString("  });\n")
+	}
+
+	var script bytes.Buffer
+	script.WriteString("// Generated by harkit from a HAR capture.\n")
+	script.WriteString("import http from 'k6/http';\n")
+	if usesEncoding {
+		script.WriteString("import encoding from 'k6/encoding';\n")
+	}
+	script.WriteString("import { group, sleep } from 'k6';\n\n")
+	script.WriteString("export default function () {\n")
+	script.Write(body.Bytes())
+	script.WriteString("}\n")
+	return script.Bytes(), nil
+}
+
+// jsString returns s as a JavaScript string literal.
+func jsString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// vegetaTarget is a target of the vegeta JSON format.
+type vegetaTarget struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Body   []byte              `json:"body,omitempty"`
+	Header map[string][]string `json:"header,omitempty"`
+}
+
+// ToVegetaTargets generates vegeta targets in its JSON format, one line per
+// entry in start order.
+func ToVegetaTargets(log *harfile.Log, opts LoadTestOptions) ([]byte, error) {
+	targets, err := loadTargets(log, opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, t := range targets {
+		vt := vegetaTarget{Method: t.method, URL: t.url, Body: t.body}
+		if len(t.headers) > 0 {
+			vt.Header = make(map[string][]string)
+			for _, h := range t.headers {
+				name := http.CanonicalHeaderKey(h.Name)
+				vt.Header[name] = append(vt.Header[name], h.Value)
+			}
+		}
+		if err := enc.Encode(vt); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package harkit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// loadTestLog records a login page followed by an API call, with gaps
+// between requests and a static asset.
+func loadTestLog() *harfile.Log {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	entry := func(e *harfile.Entry, page *harfile.Page, ms int, elapsed float64) *harfile.Entry {
+		e.StartedDateTime, e.Time = at(ms), elapsed
+		e.AttachTo(page)
+		return e
+	}
+	l := &harfile.Log{Version: "1.2", Creator: &harfile.Creator{Name: "harkit", Version: "test"}}
+	page := l.AddPage("page_1", "Login 'page'", start)
+	l.Entries = []*harfile.Entry{
+		entry(respond(exportEntry("GET", "https://example.com/login", start, "", nil, 200,
+			"Host", "example.com", "Cookie", "session=abc", "Accept", "text/html"),
+			"text/html", []byte("<form>")), page, 0, 100),
+		// Overlaps the page load: no sleep.
+		entry(respond(exportEntry("GET", "https://example.com/logo.png", start, "", nil, 200),
+			"image/png", []byte{0x89}), page, 50, 20),
+		entry(exportEntry("POST", "https://example.com/login", start, "application/x-www-form-urlencoded", []byte("user=ada&pass=l0ve%26"), 302,
+			"Content-Type", "application/x-www-form-urlencoded", "Content-Length", "22"), page, 1600, 40),
+		entry(exportEntry("POST", "https://api.example.com/v1/upload?kind=raw", start, "application/octet-stream", []byte{0, 1, 2, 0xff}, 201,
+			"Content-Type", "application/octet-stream", "X-Trace", "a", "x-trace", "b"), nil, 2640, 10),
+		nil,
+	}
+	return l
+}
+
+func TestToK6Script(t *testing.T) {
+	tests := []struct {
+		golden string
+		opts   LoadTestOptions
+	}{
+		{"k6.js", LoadTestOptions{}},
+		{"k6_options.js", LoadTestOptions{BaseURL: "http://localhost:8080", StripCookies: true, SkipMimePrefixes: []string{"IMAGE/"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			script, err := ToK6Script(loadTestLog(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, script)
+		})
+	}
+}
+
+func TestToVegetaTargets(t *testing.T) {
+	tests := []struct {
+		golden string
+		opts   LoadTestOptions
+	}{
+		{"vegeta.jsonl", LoadTestOptions{}},
+		{"vegeta_options.jsonl", LoadTestOptions{BaseURL: "http://localhost:8080", StripCookies: true, SkipMimePrefixes: []string{"image/"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			targets, err := ToVegetaTargets(loadTestLog(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, targets)
+			for _, line := range strings.Split(strings.TrimSuffix(string(targets), "\n"), "\n") {
+				var target struct {
+					Method string `json:"method"`
+					URL    string `json:"url"`
+				}
+				if err := json.Unmarshal([]byte(line), &target); err != nil || target.Method == "" || target.URL == "" {
+					t.Errorf("invalid target %s: %v", line, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTestBadBaseURL(t *testing.T) {
+	if _, err := ToK6Script(loadTestLog(), LoadTestOptions{BaseURL: "http://[::1"}); err == nil || !strings.HasPrefix(err.Error(), "harkit: ") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package harkit
+
+import "github.com/Mathious6/harkit/harfile"
+
+// LogOption configures the log created by [NewLog].
+type LogOption func(*harfile.Log)
+
+// NewLog returns an empty HAR 1.2 log created by harkit, at
+// harfile.CreatorVersion, with opts applied. The recorders create their
+// log this way, see [RecorderOptions].LogOptions and [WithLogOptions].
+func NewLog(opts ...LogOption) *harfile.Log {
+	log := &harfile.Log{
+		Version: "1.2",
+		Creator: harfile.NewCreator(),
+		Entries: []*harfile.Entry{},
+	}
+	for _, opt := range opts {
+		opt(log)
+	}
+	return log
+}
+
+// WithCreator names the application creating the log instead of harkit,
+// e.g. the tool built on it.
+func WithCreator(name, version string) LogOption {
+	return func(l *harfile.Log) {
+		l.Creator = &harfile.Creator{Name: name, Version: version}
+	}
+}
+
+// WithBrowser sets the browser of the log, e.g. the client whose traffic is
+// recorded.
+func WithBrowser(name, version string) LogOption {
+	return func(l *harfile.Log) {
+		l.Browser = &harfile.Browser{Name: name, Version: version}
+	}
+}
+
+// WithComment sets the comment of the log.
+func WithComment(comment string) LogOption {
+	return func(l *harfile.Log) {
+		l.Comment = comment
+	}
+}
@@ -0,0 +1,68 @@
+package harkit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestNewLog(t *testing.T) {
+	log := NewLog()
+	if log.Version != "1.2" || log.Entries == nil || log.Browser != nil || log.Comment != "" {
+		t.Errorf("NewLog() = %+v", log)
+	}
+	if log.Creator.Name != harfile.CreatorName || log.Creator.Version != harfile.CreatorVersion {
+		t.Errorf("creator = %+v", log.Creator)
+	}
+
+	log = NewLog(WithCreator("tool", "1.0"), WithBrowser("Firefox", "120.0"), WithComment("capture"))
+	if *log.Creator != (harfile.Creator{Name: "tool", Version: "1.0"}) ||
+		*log.Browser != (harfile.Browser{Name: "Firefox", Version: "120.0"}) || log.Comment != "capture" {
+		t.Errorf("creator %+v, browser %+v, comment %q", log.Creator, log.Browser, log.Comment)
+	}
+}
+
+func TestCreatorVersion(t *testing.T) {
+	// Tests run without the version of a harkit dependency.
+	if harfile.CreatorVersion != "devel" {
+		t.Errorf("CreatorVersion = %q", harfile.CreatorVersion)
+	}
+	defer func(v string) { harfile.CreatorVersion = v }(harfile.CreatorVersion)
+	harfile.CreatorVersion = "v1.2.3"
+	if got := NewLog().Creator.Version; got != "v1.2.3" {
+		t.Errorf("creator version = %q", got)
+	}
+}
+
+func TestRecorderLogOptions(t *testing.T) {
+	rec := NewRecorder(RecorderOptions{LogOptions: []LogOption{WithComment("capture")}})
+	rec.Append(stubEntry("GET", "https://example.com/", 200, "text/plain", nil))
+	if got := rec.Snapshot().Log.Comment; got != "capture" {
+		t.Errorf("comment = %q", got)
+	}
+	rec.Reset()
+	if log := rec.Snapshot().Log; log.Comment != "capture" || len(log.Entries) != 0 {
+		t.Errorf("after Reset: comment %q, %d entries", log.Comment, len(log.Entries))
+	}
+}
+
+func TestTransportLogOptions(t *testing.T) {
+	tr := NewTransport(stubTransport{}, WithLogOptions(WithBrowser("curl", "8.0")))
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if b := tr.HAR().Log.Browser; b == nil || b.Name != "curl" {
+		t.Errorf("browser = %+v", b)
+	}
+
+	// A shared recorder keeps its own options.
+	rec := NewRecorder(RecorderOptions{})
+	tr = NewTransport(stubTransport{}, WithRecorder(rec), WithLogOptions(WithBrowser("curl", "8.0")))
+	if b := tr.HAR().Log.Browser; b != nil {
+		t.Errorf("browser with a shared recorder = %+v", b)
+	}
+}
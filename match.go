@@ -0,0 +1,13 @@
+package harkit
+
+import "github.com/Mathious6/harkit/harfile"
+
+// Matcher selects the request components compared when looking up recorded
+// entries, see [harfile.Log.Index].
+type Matcher = harfile.Matcher
+
+// Fingerprint returns the key of req under [harfile.DefaultMatcher]: its
+// method and URL, with query parameters in any order.
+func Fingerprint(req *harfile.Request) string {
+	return harfile.DefaultMatcher.Fingerprint(req)
+}
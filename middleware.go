@@ -0,0 +1,190 @@
+package harkit
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// RecordingHandler is the [http.Handler] returned by [Middleware]. It is safe
+// for concurrent use.
+type RecordingHandler struct {
+	next http.Handler
+	opts *options
+}
+
+// Middleware wraps next so that every request it serves is recorded as a HAR
+// entry, with the request body as read by next and the response as written
+// by next. Entries are added once next returns; use
+// [RecordingHandler.Snapshot] to retrieve them.
+func Middleware(next http.Handler, opts ...Option) *RecordingHandler {
+	return &RecordingHandler{next: next, opts: newOptions(opts)}
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *RecordingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.opts.records(r.Host, r.URL.Path) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	started := h.opts.clock.Now()
+
+	body := &recordingBody{
+		ReadCloser: r.Body,
+		buf:        h.opts.bodyBuffer(r.Header.Get("Content-Type"), h.opts.maxRequestBodySize),
+		finish:     func(*limitedBuffer) {},
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = body
+	}
+	rw := &recordingWriter{ResponseWriter: w, opts: h.opts}
+	h.next.ServeHTTP(rw, r)
+	if rw.hijacked && rw.status == 0 {
+		// The response went straight to the connection. An upgrade is
+		// recorded as such, anything else cannot be described.
+		if r.Header.Get("Upgrade") == "" {
+			return
+		}
+		rw.status = http.StatusSwitchingProtocols
+		rw.header = http.Header{}
+		rw.wroteHeader = time.Now()
+	}
+	if rw.status == 0 {
+		// net/http sends an implicit 200 when next wrote nothing.
+		rw.status = http.StatusOK
+		rw.header = w.Header().Clone()
+		rw.wroteHeader = h.opts.clock.Now()
+	}
+	h.record(r, &body.buf, rw, started, h.opts.clock.Now())
+}
+
+func (h *RecordingHandler) record(r *http.Request, reqBody *limitedBuffer, rw *recordingWriter, started, done time.Time) {
+	// Server requests carry the path only; restore the absolute URL.
+	req := r.Clone(r.Context())
+	req.URL.Host = r.Host
+	req.URL.Scheme = "http"
+	if r.TLS != nil {
+		req.URL.Scheme = "https"
+	}
+	hreq, err := requestFromHTTP(req, reqBody)
+	if err != nil {
+		return
+	}
+	hresp, err := responseFromHTTP(&http.Response{
+		StatusCode: rw.status,
+		Proto:      r.Proto,
+		ProtoMajor: r.ProtoMajor,
+		ProtoMinor: r.ProtoMinor,
+		Header:     rw.header,
+	}, &rw.body, false)
+	if err != nil {
+		return
+	}
+	hreq.HTTPVersion = httpVersion(r.ProtoMajor, r.ProtoMinor, r.Proto, r.TLS)
+	hresp.HTTPVersion = hreq.HTTPVersion
+	h.opts.transformBodies(hreq, hresp)
+
+	timings := &harfile.Timings{
+		Blocked: -1,
+		DNS:     -1,
+		Connect: -1,
+		Ssl:     -1,
+		Wait:    span(started, rw.wroteHeader),
+		Receive: span(rw.wroteHeader, done),
+	}
+	entry := &harfile.Entry{
+		StartedDateTime: started,
+		Time:            timings.Total(),
+		Request:         hreq,
+		Response:        hresp,
+		Cache:           &harfile.Cache{},
+		Timings:         timings,
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			entry.ServerIPAddress = host
+		}
+	}
+	if _, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		entry.Connection = port
+	}
+	tagTruncated(entry)
+	h.opts.roundTimings(entry)
+	if !h.opts.keeps(entry) {
+		return
+	}
+	if h.opts.policy != nil {
+		h.opts.policy.entry(entry)
+	}
+
+	h.opts.recorder.add(entry, h.opts.hooks, false)
+}
+
+// Recorder returns the [Recorder] holding the recorded entries.
+func (h *RecordingHandler) Recorder() *Recorder {
+	return h.opts.recorder
+}
+
+// Snapshot returns a copy of the entries recorded so far.
+func (h *RecordingHandler) Snapshot() *harfile.HAR {
+	return h.opts.recorder.Snapshot()
+}
+
+// recordingWriter captures the status, headers and body written through an
+// [http.ResponseWriter].
+type recordingWriter struct {
+	http.ResponseWriter
+	opts        *options
+	status      int
+	header      http.Header
+	wroteHeader time.Time
+	body        limitedBuffer
+	hijacked    bool
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		// Informational responses precede the final one.
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	w.header = w.ResponseWriter.Header().Clone()
+	w.wroteHeader = w.opts.clock.Now()
+	w.body = w.opts.bodyBuffer(w.header.Get("Content-Type"), w.opts.maxResponseBodySize)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	n, err := w.ResponseWriter.Write(p)
+	w.body.Write(p[:n])
+	return n, err
+}
+
+// Flush implements [http.Flusher] when the underlying writer supports it.
+func (w *recordingWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements [http.Hijacker] when the underlying writer supports it,
+// so that WebSocket and other upgrade handlers keep working.
+func (w *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, brw, err
+}
+
+// Unwrap lets [http.ResponseController] reach the underlying writer.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
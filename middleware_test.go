@@ -0,0 +1,202 @@
+package harkit
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestMiddleware(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "got "+string(body))
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/items?x=1", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	entries := h.Snapshot().Log.Entries
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Request.Method != "POST" || e.Request.URL != srv.URL+"/items?x=1" || e.Request.PostData.Text != "hello" {
+		t.Errorf("request = %s %s %+v", e.Request.Method, e.Request.URL, e.Request.PostData)
+	}
+	if e.Response.Status != 201 || e.Response.Content.Text != "got hello" || e.Response.Content.MimeType != "text/plain" {
+		t.Errorf("response = %d %+v", e.Response.Status, e.Response.Content)
+	}
+	if e.ServerIPAddress != "127.0.0.1" || e.Connection == "" {
+		t.Errorf("server %q, connection %q", e.ServerIPAddress, e.Connection)
+	}
+}
+
+func TestMiddlewareImplicitStatus(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Id", "7")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	e := h.Snapshot().Log.Entries[0]
+	if e.Response.Status != 200 || e.Response.Headers[0].Value != "7" {
+		t.Errorf("response = %d %v", e.Response.Status, e.Response.Headers)
+	}
+}
+
+func TestMiddlewareMaxBodySize(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}), WithMaxBodySize(4))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("0123456789")))
+	e := h.Snapshot().Log.Entries[0]
+	if pd := e.Request.PostData; pd.Text != "0123" || e.Request.BodySize != 10 || pd.Comment != "body truncated to 4 of 10 bytes" {
+		t.Errorf("request body %q, size %d, comment %q", pd.Text, e.Request.BodySize, pd.Comment)
+	}
+	if c := e.Response.Content; c.Text != "0123" || e.Response.BodySize != 10 || c.Comment != "body truncated to 4 of 10 bytes" {
+		t.Errorf("response body %q, size %d, comment %q", c.Text, e.Response.BodySize, c.Comment)
+	}
+	if !e.HasTag(harfile.TagTruncated) {
+		t.Errorf("tags = %q", e.Tags)
+	}
+}
+
+func TestMiddlewareExclusions(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name   string
+		opts   []Option
+		target string
+		want   int
+	}{
+		{"recorded", nil, "http://api.example.com/v1", 1},
+		{"excluded host", []Option{WithExcludeHosts("*.EXAMPLE.com")}, "http://api.example.com:8080/v1", 0},
+		{"other host", []Option{WithExcludeHosts("*.internal")}, "http://api.example.com/v1", 1},
+		{"excluded path", []Option{WithExcludePaths("/healthz", "/static/*")}, "http://example.com/static/app.js", 0},
+		{"nested path", []Option{WithExcludePaths("/static/*")}, "http://example.com/static/js/app.js", 1},
+		{"excluded path regexp", []Option{WithExcludePathsRegexp(regexp.MustCompile(`^/(metrics|collect)\b`))}, "http://example.com/metrics/cpu", 0},
+		{"other path regexp", []Option{WithExcludePathsRegexp(regexp.MustCompile(`^/(metrics|collect)\b`))}, "http://example.com/metricsx", 1},
+		{"included host", []Option{WithIncludeHosts("*.Example.com")}, "http://api.example.com:8080/v1", 1},
+		{"not included host", []Option{WithIncludeHosts("*.example.com")}, "http://example.org/v1", 0},
+		{"included path", []Option{WithIncludePathsRegexp(regexp.MustCompile(`^/v1/`), regexp.MustCompile(`^/v2/`))}, "http://example.com/v2/users", 1},
+		{"not included path", []Option{WithIncludePathsRegexp(regexp.MustCompile(`^/v1/`))}, "http://example.com/v2/users", 0},
+		{"included then excluded", []Option{WithIncludeHosts("*.example.com"), WithExcludePaths("/healthz")}, "http://api.example.com/healthz", 0},
+		{"sampled out", []Option{WithSampleRate(0)}, "http://example.com/", 0},
+		{"sampled in", []Option{WithSampleRate(1)}, "http://example.com/", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Middleware(ok, tt.opts...)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+			if rec.Code != 200 {
+				t.Errorf("status %d, want the request served", rec.Code)
+			}
+			if n := len(h.Snapshot().Log.Entries); n != tt.want {
+				t.Errorf("%d entries, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareEntryFilter(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}), WithEntryFilter(func(e *harfile.Entry) bool { return e.Response.Status < 400 }))
+	for _, target := range []string{"/", "/missing", "/other"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if target == "/missing" && rec.Code != 404 {
+			t.Errorf("status %d, want the filtered request served", rec.Code)
+		}
+	}
+	var paths []string
+	for _, e := range h.Snapshot().Log.Entries {
+		paths = append(paths, strings.TrimPrefix(e.Request.URL, "http://example.com"))
+	}
+	if got := strings.Join(paths, " "); got != "/ /other" {
+		t.Errorf("recorded %q", got)
+	}
+}
+
+func TestMiddlewareSampleRate(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), WithSampleRate(0.5))
+	for range 1000 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	// Far outside any plausible binomial deviation.
+	if n := len(h.Snapshot().Log.Entries); n < 350 || n > 650 {
+		t.Errorf("%d of 1000 requests recorded at rate 0.5", n)
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("writer does not implement http.Hijacker")
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		brw.Flush()
+		line, _ := brw.ReadString('\n')
+		brw.WriteString(line)
+		brw.Flush()
+	}))
+	// The server does not wait for hijacked connections on Close.
+	served := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		served <- struct{}{}
+	}))
+	defer srv.Close()
+
+	for _, upgrade := range []string{"echo", ""} {
+		req, _ := http.NewRequest("GET", srv.URL+"/ws", nil)
+		if upgrade != "" {
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", upgrade)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("status %d", resp.StatusCode)
+		}
+		rwc := resp.Body.(io.ReadWriteCloser)
+		io.WriteString(rwc, "ping\n")
+		if line, _ := bufio.NewReader(rwc).ReadString('\n'); line != "ping\n" {
+			t.Errorf("echo = %q", line)
+		}
+		rwc.Close()
+		<-served
+	}
+
+	// Only the upgrade request can be described.
+	entries := h.Snapshot().Log.Entries
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(entries))
+	}
+	if e := entries[0]; e.Response.Status != http.StatusSwitchingProtocols || e.Request.URL != srv.URL+"/ws" {
+		t.Errorf("entry = %d %s", e.Response.Status, e.Request.URL)
+	}
+}
@@ -0,0 +1,437 @@
+package harkit
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// ErrNotMitmproxy is returned by [FromMitmproxy] for inputs that are not
+// mitmproxy flow dumps.
+var ErrNotMitmproxy = errors.New("harkit: not a mitmproxy flow dump")
+
+// FromMitmproxy converts a mitmproxy flow dump, as written by "mitmdump -w"
+// or the "w" key of mitmproxy (.flow or .mitm files), into a HAR, one
+// entry per HTTP flow in dump order. Flows are read in the serialization
+// of recent mitmproxy versions.
+//
+// Headers keep their recorded order and case. Bodies are decoded from
+// their Content-Encoding. The float epoch timestamps of mitmproxy give
+// StartedDateTime, when the request started, and the timings: connect and
+// ssl for the first flow of each server connection, -1 on reuse, then
+// send, wait and receive. The server address gives ServerIPAddress and
+// the server TLS handshake gives _securityDetails. A flow that failed
+// without a response gets status 0 and its error in _error.
+//
+// Other flows, such as tcp, udp or dns ones, are skipped and counted in a
+// warning, as are the flows that cannot be converted.
+func FromMitmproxy(r io.Reader) (*harfile.HAR, []harfile.Warning, error) {
+	br := bufio.NewReader(r)
+	imp := &mitmImporter{log: NewLog(), conns: make(map[string]bool), skipped: make(map[string]int)}
+	for i := 0; ; i++ {
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+		v, err := readTNetString(br)
+		if err != nil {
+			if i == 0 {
+				return nil, nil, fmt.Errorf("%w: %v", ErrNotMitmproxy, err)
+			}
+			return nil, nil, fmt.Errorf("harkit: decode mitmproxy flow %d: %w", i, err)
+		}
+		flow, ok := v.(map[string]any)
+		if !ok {
+			if i == 0 {
+				return nil, nil, ErrNotMitmproxy
+			}
+			return nil, nil, fmt.Errorf("harkit: decode mitmproxy flow %d: not a dictionary", i)
+		}
+		imp.flow(fmt.Sprintf("flows[%d]", i), flow)
+	}
+
+	if len(imp.skipped) > 0 {
+		var parts []string
+		total := 0
+		for _, kind := range slices.Sorted(maps.Keys(imp.skipped)) {
+			parts = append(parts, fmt.Sprintf("%s %d", kind, imp.skipped[kind]))
+			total += imp.skipped[kind]
+		}
+		imp.warnings = append(imp.warnings, harfile.Warning{
+			Path:    "flows",
+			Message: fmt.Sprintf("%d non-HTTP flows skipped (%s)", total, strings.Join(parts, ", ")),
+		})
+	}
+	return &harfile.HAR{Log: imp.log}, imp.warnings, nil
+}
+
+type mitmImporter struct {
+	log      *harfile.Log
+	conns    map[string]bool // Server connections seen, whose later flows reuse them.
+	skipped  map[string]int  // Flows skipped by type.
+	warnings []harfile.Warning
+}
+
+func (imp *mitmImporter) warn(path, format string, args ...any) {
+	imp.warnings = append(imp.warnings, harfile.Warning{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (imp *mitmImporter) flow(path string, flow map[string]any) {
+	if kind := mitmString(flow, "type"); kind != "http" {
+		imp.skipped[cmp.Or(kind, "unknown")]++
+		return
+	}
+	reqState := mitmDict(flow, "request")
+	if reqState == nil {
+		imp.warn(path, "HTTP flow without a request skipped")
+		return
+	}
+	req, err := imp.request(reqState)
+	if err != nil {
+		imp.warn(path+".request", "%v; flow skipped", err)
+		return
+	}
+
+	server := mitmDict(flow, "server_conn")
+	reqStart, reqEnd := mitmNumber(reqState, "timestamp_start"), mitmNumber(reqState, "timestamp_end")
+	e := &harfile.Entry{
+		StartedDateTime: mitmTime(reqStart),
+		Request:         req,
+		Cache:           &harfile.Cache{},
+		Timings:         &harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1},
+	}
+	if comment := mitmString(flow, "comment"); comment != "" {
+		e.Comment = comment
+	}
+
+	e.Response = &harfile.Response{
+		Cookies:     []*harfile.Cookie{},
+		Headers:     []*harfile.NameValuePair{},
+		Content:     &harfile.Content{MimeType: "x-unknown"},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if respState := mitmDict(flow, "response"); respState != nil {
+		if resp, err := imp.response(respState); err != nil {
+			imp.warn(path+".response", "%v; response left out", err)
+		} else {
+			e.Response = resp
+		}
+		respStart, respEnd := mitmNumber(respState, "timestamp_start"), mitmNumber(respState, "timestamp_end")
+		e.Timings.Wait = mitmSpan(reqEnd, respStart)
+		e.Timings.Receive = mitmSpan(respStart, respEnd)
+	} else if flowErr := mitmDict(flow, "error"); flowErr != nil {
+		e.Response.Error = mitmString(flowErr, "msg")
+	}
+	e.Timings.Send = mitmSpan(reqStart, reqEnd)
+
+	if server != nil {
+		imp.server(e, server)
+	}
+	e.ComputeTime()
+	imp.log.Entries = append(imp.log.Entries, e)
+}
+
+// server fills the connection details of e from the server connection of
+// its flow.
+func (imp *mitmImporter) server(e *harfile.Entry, server map[string]any) {
+	if peer := mitmList(server, "peername"); len(peer) > 0 {
+		e.ServerIPAddress = mitmValueString(peer[0])
+	}
+	if sock := mitmList(server, "sockname"); len(sock) > 1 {
+		e.Connection = mitmValueString(sock[1])
+	}
+	id := mitmString(server, "id")
+	if id != "" && !imp.conns[id] {
+		imp.conns[id] = true
+		start := mitmNumber(server, "timestamp_start")
+		tcp := mitmNumber(server, "timestamp_tcp_setup")
+		tlsSetup := mitmNumber(server, "timestamp_tls_setup")
+		e.Timings.Connect = mitmSpan(start, cmp.Or(tlsSetup, tcp))
+		if tlsSetup > 0 {
+			e.Timings.Ssl = mitmSpan(tcp, tlsSetup)
+		}
+	}
+	if mitmString(server, "tls_version") != "" {
+		e.SecurityDetails = mitmSecurityDetails(server)
+	}
+}
+
+func (imp *mitmImporter) request(state map[string]any) (*harfile.Request, error) {
+	method := mitmString(state, "method")
+	if method == "" {
+		return nil, errors.New("no method")
+	}
+	scheme := cmp.Or(mitmString(state, "scheme"), "http")
+	host := mitmString(state, "authority")
+	if host == "" {
+		host = mitmString(state, "host")
+		port := int(mitmNumber(state, "port"))
+		switch {
+		case port > 0 && !(scheme == "http" && port == 80 || scheme == "https" && port == 443):
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]"
+		}
+	}
+	u := &url.URL{Scheme: scheme, Host: host}
+	target := mitmString(state, "path")
+	if method == http.MethodConnect || strings.HasPrefix(target, "*") {
+		target = ""
+	}
+	raw := u.String() + target
+	if _, err := url.Parse(raw); err != nil {
+		return nil, err
+	}
+
+	headers, header := mitmHeaders(state)
+	r := &harfile.Request{
+		Method:      method,
+		URL:         raw,
+		HTTPVersion: cmp.Or(mitmString(state, "http_version"), "HTTP/1.1"),
+		Cookies:     harfile.CookiesFromRequest(header),
+		Headers:     headers,
+	}
+	if err := r.ParseQueryString(); err != nil {
+		return nil, err
+	}
+	if body := mitmBytes(state, "content"); len(body) > 0 {
+		pd, err := harfile.PostDataFromBody(header.Get("Content-Type"), body)
+		if err != nil {
+			// Keep malformed multipart bodies raw rather than dropping them.
+			if pd, err = harfile.PostDataFromBody("application/octet-stream", body); err == nil {
+				pd.MimeType = header.Get("Content-Type")
+			}
+		}
+		r.PostData = pd
+	}
+	r.ComputeSizes()
+	return r, nil
+}
+
+func (imp *mitmImporter) response(state map[string]any) (*harfile.Response, error) {
+	headers, header := mitmHeaders(state)
+	status := int(mitmNumber(state, "status_code"))
+	hresp := &http.Response{
+		StatusCode: status,
+		Status:     strings.TrimSpace(strconv.Itoa(status) + " " + mitmString(state, "reason")),
+		Proto:      cmp.Or(mitmString(state, "http_version"), "HTTP/1.1"),
+		Header:     header,
+	}
+	body := mitmBytes(state, "content")
+	resp, err := harfile.FromHTTPResponse(hresp, body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers = headers
+	resp.ComputeSizes()
+	resp.BodySize = int64(len(body))
+	if v, ok := state["content"]; ok && v == nil {
+		resp.BodySize = -1
+		resp.Content.Comment = "body not kept by mitmproxy"
+	}
+	return resp, nil
+}
+
+// mitmHeaders returns the headers of a request or response state in
+// recorded order, and as an [http.Header].
+func mitmHeaders(state map[string]any) ([]*harfile.NameValuePair, http.Header) {
+	pairs := []*harfile.NameValuePair{}
+	header := make(http.Header)
+	for _, h := range mitmList(state, "headers") {
+		kv, ok := h.([]any)
+		if !ok || len(kv) != 2 {
+			continue
+		}
+		name, value := mitmValueString(kv[0]), mitmValueString(kv[1])
+		pairs = append(pairs, &harfile.NameValuePair{Name: name, Value: value})
+		header.Add(name, value)
+	}
+	return pairs, header
+}
+
+// mitmSecurityDetails describes the TLS connection of a server connection
+// state.
+func mitmSecurityDetails(server map[string]any) *harfile.SecurityDetails {
+	d := &harfile.SecurityDetails{
+		Protocol:   strings.Replace(mitmString(server, "tls_version"), "TLSv", "TLS ", 1),
+		Cipher:     mitmString(server, "cipher"),
+		SanList:    []string{},
+		ServerName: mitmString(server, "sni"),
+	}
+	certs := mitmList(server, "certificate_list")
+	if len(certs) == 0 {
+		return d
+	}
+	block, _ := pem.Decode([]byte(mitmValueString(certs[0])))
+	if block == nil {
+		return d
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return d
+	}
+	d.SubjectName = cmp.Or(cert.Subject.CommonName, cert.Subject.String())
+	d.Issuer = cmp.Or(cert.Issuer.CommonName, cert.Issuer.String())
+	d.ValidFrom = cert.NotBefore.Unix()
+	d.ValidTo = cert.NotAfter.Unix()
+	d.SanList = append(d.SanList, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		d.SanList = append(d.SanList, ip.String())
+	}
+	return d
+}
+
+// mitmTime converts an epoch in seconds into a time.
+func mitmTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(int64(math.Round(seconds * 1e6)))
+}
+
+// mitmSpan returns the milliseconds between two epochs in seconds, or -1
+// when either is unknown.
+func mitmSpan(from, to float64) float64 {
+	if from <= 0 || to <= 0 {
+		return -1
+	}
+	return max(math.Round((to-from)*1e6)/1e3, 0)
+}
+
+func mitmDict(m map[string]any, key string) map[string]any {
+	d, _ := m[key].(map[string]any)
+	return d
+}
+
+func mitmList(m map[string]any, key string) []any {
+	l, _ := m[key].([]any)
+	return l
+}
+
+func mitmString(m map[string]any, key string) string {
+	return mitmValueString(m[key])
+}
+
+func mitmValueString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
+
+func mitmBytes(m map[string]any, key string) []byte {
+	switch v := m[key].(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+func mitmNumber(m map[string]any, key string) float64 {
+	switch v := m[key].(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// maxTNetStringLength bounds the length of a tnetstring, so that corrupt
+// input does not trigger a huge allocation.
+const maxTNetStringLength = 1 << 31
+
+// readTNetString reads a value in the tnetstring serialization of
+// mitmproxy: "length:payload" followed by a type tag, "," for bytes, ";"
+// for text, "#" for integers, "^" for floats, "!" for booleans, "~" for
+// null, "]" for lists and "}" for dictionaries.
+func readTNetString(r *bufio.Reader) (any, error) {
+	var n int64
+	for digits := 0; ; digits++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if c == ':' && digits > 0 {
+			break
+		}
+		if c < '0' || c > '9' || n > maxTNetStringLength {
+			return nil, fmt.Errorf("invalid tnetstring length at %q", c)
+		}
+		n = n*10 + int64(c-'0')
+	}
+	payload := make([]byte, n+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	tag := payload[n]
+	payload = payload[:n]
+	switch tag {
+	case ',':
+		return payload, nil
+	case ';':
+		return string(payload), nil
+	case '#':
+		return strconv.ParseInt(string(payload), 10, 64)
+	case '^':
+		return strconv.ParseFloat(string(payload), 64)
+	case '!':
+		return string(payload) == "true", nil
+	case '~':
+		return nil, nil
+	case ']':
+		list := []any{}
+		inner := bufio.NewReader(bytes.NewReader(payload))
+		for {
+			if _, err := inner.Peek(1); err == io.EOF {
+				return list, nil
+			}
+			v, err := readTNetString(inner)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	case '}':
+		dict := make(map[string]any)
+		inner := bufio.NewReader(bytes.NewReader(payload))
+		for {
+			if _, err := inner.Peek(1); err == io.EOF {
+				return dict, nil
+			}
+			k, err := readTNetString(inner)
+			if err != nil {
+				return nil, err
+			}
+			v, err := readTNetString(inner)
+			if err != nil {
+				return nil, err
+			}
+			dict[mitmValueString(k)] = v
+		}
+	}
+	return nil, fmt.Errorf("invalid tnetstring type %q", tag)
+}
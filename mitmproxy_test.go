@@ -0,0 +1,104 @@
+package harkit
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromMitmproxy(t *testing.T) {
+	f, err := os.Open("testdata/mitmproxy.flow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, warnings, err := FromMitmproxy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "mitmproxy.har", append(data, '\n'))
+	if err := h.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	if len(warnings) != 1 || warnings[0].Path != "flows" || warnings[0].Message != "2 non-HTTP flows skipped (dns 1, tcp 1)" {
+		t.Errorf("warnings = %+v", warnings)
+	}
+	entries := h.Log.Entries
+	if len(entries) != 4 {
+		t.Fatalf("%d entries, want 4", len(entries))
+	}
+
+	first := entries[0]
+	if want := time.Date(2024, 5, 1, 12, 0, 0, 61e6, time.UTC); !first.StartedDateTime.Equal(want) {
+		t.Errorf("StartedDateTime = %v, want %v", first.StartedDateTime, want)
+	}
+	if tm := first.Timings; tm.Connect != 50 || tm.Ssl != 35 || tm.Send != 1 || tm.Wait != 50 || tm.Receive != 8 {
+		t.Errorf("first timings = %+v", tm)
+	}
+	if first.ServerIPAddress != "203.0.113.10" || first.Connection != "52344" {
+		t.Errorf("server %s, connection %s", first.ServerIPAddress, first.Connection)
+	}
+	if text := first.Response.Content.Text; text != `{"items":[1,2,3],"page":2}` {
+		t.Errorf("gzip body decoded to %q", text)
+	}
+	sd := first.SecurityDetails
+	if sd == nil || sd.Protocol != "TLS 1.3" || sd.Cipher != "TLS_AES_128_GCM_SHA256" || sd.ServerName != "api.example.com" ||
+		sd.SubjectName != "api.example.com" || strings.Join(sd.SanList, " ") != "api.example.com *.example.com 203.0.113.10" {
+		t.Errorf("securityDetails = %+v", sd)
+	}
+
+	// The second flow reuses the connection of the first.
+	if tm := entries[1].Timings; tm.Connect != -1 || tm.Ssl != -1 || tm.Send != 4 || tm.Wait != 26 || tm.Receive != 1 {
+		t.Errorf("reused connection timings = %+v", tm)
+	}
+	if pd := entries[1].Request.PostData; pd == nil || pd.Text != `{"name":"four"}` {
+		t.Errorf("postData = %+v", pd)
+	}
+	if r := entries[2].Response; r.Status != 0 || r.Error != "Connection refused" {
+		t.Errorf("failed flow response = %d %q", r.Status, r.Error)
+	}
+	if e := entries[3]; e.Request.URL != "http://example.com:8080/stream" || e.Response.BodySize != -1 || e.Timings.Ssl != -1 {
+		t.Errorf("streamed flow: %s, bodySize %d, timings %+v", e.Request.URL, e.Response.BodySize, e.Timings)
+	}
+}
+
+func TestFromMitmproxyErrors(t *testing.T) {
+	valid, err := os.ReadFile("testdata/mitmproxy.flow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"har", `{"log":{"version":"1.2"}}`, ErrNotMitmproxy.Error()},
+		{"list", "2:0#]", ErrNotMitmproxy.Error()},
+		{"truncated flow", string(valid[:len(valid)-10]), "harkit: decode mitmproxy flow 5: "},
+		{"second value not a flow", string(valid) + "1:0#", "harkit: decode mitmproxy flow 6: not a dictionary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := FromMitmproxy(strings.NewReader(tt.input))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("FromMitmproxy = %v, want %s", err, tt.want)
+			}
+			if strings.HasPrefix(tt.want, "harkit: not") != errors.Is(err, ErrNotMitmproxy) {
+				t.Errorf("errors.Is(%v, ErrNotMitmproxy) = %v", err, errors.Is(err, ErrNotMitmproxy))
+			}
+		})
+	}
+
+	h, warnings, err := FromMitmproxy(strings.NewReader(""))
+	if err != nil || len(h.Log.Entries) != 0 || len(warnings) != 0 {
+		t.Errorf("empty dump = %v, %v, %v", h, warnings, err)
+	}
+}
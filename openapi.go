@@ -0,0 +1,441 @@
+package harkit
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// OpenAPIOptions configures [ToOpenAPI].
+type OpenAPIOptions struct {
+	Title   string // info.title. Empty means "Captured API".
+	Version string // info.version. Empty means "0.0.0".
+	// GroupBy maps request paths to OpenAPI paths, e.g. PathTemplater,
+	// whose "{name}" segments become path parameters. Nil templates the
+	// numeric and UUID segments.
+	GroupBy GroupBy
+}
+
+type openAPIDoc struct {
+	OpenAPI string                           `json:"openapi"`
+	Info    openAPIInfo                      `json:"info"`
+	Servers []*openAPIServer                 `json:"servers,omitempty"`
+	Paths   map[string]map[string]*openAPIOp `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOp struct {
+	Parameters  []*openAPIParam             `json:"parameters,omitempty"`
+	RequestBody *openAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Servers     []*openAPIServer            `json:"servers,omitempty"`
+	Samples     int                         `json:"x-harkit-samples"`
+
+	params  map[string]*openAPIParam
+	servers map[string]bool
+}
+
+type openAPIParam struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *jsonSchema `json:"schema"`
+	Samples  int         `json:"x-harkit-samples"`
+}
+
+type openAPIBody struct {
+	Content map[string]*openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                   `json:"description"`
+	Content     map[string]*openAPIMedia `json:"content,omitempty"`
+	Samples     int                      `json:"x-harkit-samples"`
+}
+
+type openAPIMedia struct {
+	Schema  *jsonSchema `json:"schema,omitempty"`
+	Samples int         `json:"x-harkit-samples"`
+}
+
+// jsonSchema is the subset of JSON Schema inferred from sample values. A
+// schema without a type accepts anything.
+type jsonSchema struct {
+	Type       string                 `json:"type,omitempty"`
+	Format     string                 `json:"format,omitempty"`
+	Nullable   bool                   `json:"nullable,omitempty"`
+	Properties map[string]*jsonSchema `json:"properties,omitempty"`
+	Items      *jsonSchema            `json:"items,omitempty"`
+
+	unknown bool // Nothing but null, or an empty array, was observed.
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ignoredParamHeaders are not described as header parameters: OpenAPI
+// ignores Accept, Content-Type and Authorization, and the rest are set by
+// clients rather than by the API.
+var ignoredParamHeaders = []string{
+	"accept", "accept-encoding", "accept-language", "authorization", "cache-control",
+	"connection", "content-length", "content-type", "cookie", "host", "origin",
+	"pragma", "priority", "referer", "te", "upgrade-insecure-requests", "user-agent",
+}
+
+// ToOpenAPI infers an OpenAPI 3.0 skeleton, as JSON, from the traffic in
+// log. Entries are grouped by templated path, numeric and UUID segments
+// becoming path parameters unless opts.GroupBy says otherwise, with one
+// operation per method. Parameters,
+// content types and body schemas are merged across the observed samples,
+// whose counts are recorded in x-harkit-samples. Bodies that are not JSON
+// only contribute their media type.
+//
+// Paths are shared by every host; operations observed on a subset of the
+// servers list them.
+func ToOpenAPI(log *harfile.Log, opts OpenAPIOptions) ([]byte, error) {
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: cmp.Or(opts.Title, "Captured API"), Version: cmp.Or(opts.Version, "0.0.0")},
+		Paths:   make(map[string]map[string]*openAPIOp),
+	}
+	servers := make(map[string]bool)
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		server := u.Scheme + "://" + u.Host
+		if !servers[server] {
+			servers[server] = true
+			doc.Servers = append(doc.Servers, &openAPIServer{URL: server})
+		}
+
+		var path string
+		var pathParams map[string]string
+		if opts.GroupBy != nil {
+			path, pathParams = groupedPath(u.EscapedPath(), opts.GroupBy)
+		} else {
+			path, pathParams = templatePath(u.EscapedPath())
+		}
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(map[string]*openAPIOp)
+			doc.Paths[path] = item
+		}
+		method := strings.ToLower(cmp.Or(e.Request.Method, http.MethodGet))
+		op := item[method]
+		if op == nil {
+			op = &openAPIOp{
+				Responses: make(map[string]*openAPIResponse),
+				params:    make(map[string]*openAPIParam),
+				servers:   make(map[string]bool),
+			}
+			item[method] = op
+		}
+		op.Samples++
+		if !op.servers[server] {
+			op.servers[server] = true
+			op.Servers = append(op.Servers, &openAPIServer{URL: server})
+		}
+		op.observe(e, pathParams)
+	}
+
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			for _, p := range op.Parameters {
+				p.Required = p.In == "path" || p.Samples == op.Samples
+			}
+			if len(op.servers) == len(servers) {
+				op.Servers = nil
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// templatePath replaces the numeric and UUID segments of path with
+// parameters named after their position, and returns the replaced values
+// by parameter name.
+func templatePath(path string) (string, map[string]string) {
+	if path == "" {
+		return "/", nil
+	}
+	segments := strings.Split(path, "/")
+	params := make(map[string]string)
+	for i, s := range segments {
+		if s == "" || !isNumeric(s) && !uuidRe.MatchString(s) {
+			continue
+		}
+		name := "param"
+		if len(params) > 0 {
+			name += strconv.Itoa(len(params) + 1)
+		}
+		params[name] = s
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// groupedPath maps path with group and returns the parameters of the
+// result: segments written "{name}" take the value of the segment of path
+// at the same position. Repeated names are numbered, as in "{id}" and
+// "{id2}", since OpenAPI requires them to be unique.
+func groupedPath(path string, group GroupBy) (string, map[string]string) {
+	actual := strings.Split(cmp.Or(path, "/"), "/")
+	segments := strings.Split(group(cmp.Or(path, "/")), "/")
+	params := make(map[string]string)
+	for i, s := range segments {
+		if len(s) < 3 || s[0] != '{' || s[len(s)-1] != '}' {
+			continue
+		}
+		base := s[1 : len(s)-1]
+		name := base
+		for n := 2; ; n++ {
+			if _, taken := params[name]; !taken {
+				break
+			}
+			name = base + strconv.Itoa(n)
+		}
+		var value string
+		if i < len(actual) {
+			value = actual[i]
+		}
+		params[name] = value
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func (op *openAPIOp) observe(e *harfile.Entry, pathParams map[string]string) {
+	for _, name := range slices.Sorted(maps.Keys(pathParams)) {
+		op.param(name, "path", pathParams[name])
+	}
+	for _, q := range e.Request.QueryString {
+		if q != nil {
+			op.param(q.Name, "query", q.Value)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, h := range e.Request.Headers {
+		if h == nil || strings.HasPrefix(h.Name, ":") || strings.HasPrefix(strings.ToLower(h.Name), "sec-") {
+			continue
+		}
+		name := http.CanonicalHeaderKey(h.Name)
+		if seen[name] || containsFold(ignoredParamHeaders, name) {
+			continue
+		}
+		seen[name] = true
+		op.param(name, "header", h.Value)
+	}
+
+	if pd := e.Request.PostData; pd != nil && (pd.Text != "" || len(pd.Params) > 0) {
+		if op.RequestBody == nil {
+			op.RequestBody = &openAPIBody{Content: make(map[string]*openAPIMedia)}
+		}
+		body, _ := pd.DecodedText()
+		observeMedia(op.RequestBody.Content, pd.MimeType, body)
+	}
+
+	resp := e.Response
+	if resp == nil || resp.Status == 0 {
+		return
+	}
+	code := strconv.FormatInt(resp.Status, 10)
+	r := op.Responses[code]
+	if r == nil {
+		r = &openAPIResponse{Description: cmp.Or(http.StatusText(int(resp.Status)), resp.StatusText, code)}
+		op.Responses[code] = r
+	}
+	r.Samples++
+	if resp.Content == nil || resp.Content.Size == 0 && resp.Content.Text == "" {
+		return
+	}
+	body, err := resp.Content.DecodeBody(headerValue(resp.Headers, "Content-Encoding"))
+	if err != nil {
+		body = nil
+	}
+	if r.Content == nil {
+		r.Content = make(map[string]*openAPIMedia)
+	}
+	observeMedia(r.Content, cmp.Or(resp.Content.MimeType, headerValue(resp.Headers, "Content-Type")), body)
+}
+
+func (op *openAPIOp) param(name, in, value string) {
+	key := in + "\x00" + name
+	if in == "header" {
+		key = in + "\x00" + strings.ToLower(name)
+	}
+	p := op.params[key]
+	if p == nil {
+		p = &openAPIParam{Name: name, In: in}
+		op.params[key] = p
+		op.Parameters = append(op.Parameters, p)
+	}
+	p.Samples++
+	p.Schema = mergeSchema(p.Schema, scalarSchema(value))
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// observeMedia records a body of the given media type in content, inferring
+// its schema when it is JSON.
+func observeMedia(content map[string]*openAPIMedia, contentType string, body []byte) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	m := content[mediaType]
+	if m == nil {
+		m = &openAPIMedia{}
+		content[mediaType] = m
+	}
+	m.Samples++
+	if !isJSONMime(mediaType) {
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) == nil {
+		m.Schema = mergeSchema(m.Schema, inferSchema(v))
+	}
+}
+
+// scalarSchema infers the schema of a parameter value.
+func scalarSchema(value string) *jsonSchema {
+	switch {
+	case isNumeric(strings.TrimPrefix(value, "-")):
+		return &jsonSchema{Type: "integer"}
+	case value == "true" || value == "false":
+		return &jsonSchema{Type: "boolean"}
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return &jsonSchema{Type: "number"}
+	}
+	return stringSchema(value)
+}
+
+func stringSchema(s string) *jsonSchema {
+	schema := &jsonSchema{Type: "string"}
+	if uuidRe.MatchString(s) {
+		schema.Format = "uuid"
+	} else if _, err := time.Parse(time.RFC3339, s); err == nil {
+		schema.Format = "date-time"
+	}
+	return schema
+}
+
+// inferSchema infers the schema of a JSON value decoded with UseNumber.
+func inferSchema(v any) *jsonSchema {
+	switch v := v.(type) {
+	case nil:
+		return &jsonSchema{Nullable: true, unknown: true}
+	case bool:
+		return &jsonSchema{Type: "boolean"}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &jsonSchema{Type: "integer"}
+		}
+		return &jsonSchema{Type: "number"}
+	case string:
+		return stringSchema(v)
+	case []any:
+		s := &jsonSchema{Type: "array"}
+		for _, item := range v {
+			s.Items = mergeSchema(s.Items, inferSchema(item))
+		}
+		if s.Items == nil {
+			s.Items = &jsonSchema{unknown: true}
+		}
+		return s
+	case map[string]any:
+		s := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema, len(v))}
+		for k, item := range v {
+			s.Properties[k] = inferSchema(item)
+		}
+		return s
+	}
+	return &jsonSchema{}
+}
+
+// mergeSchema widens a to also describe the values described by b.
+// Integers widen to numbers and conflicting types to any type.
+func mergeSchema(a, b *jsonSchema) *jsonSchema {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.unknown:
+		b.Nullable = b.Nullable || a.Nullable
+		return b
+	case b.unknown:
+		a.Nullable = a.Nullable || b.Nullable
+		return a
+	}
+	a.Nullable = a.Nullable || b.Nullable
+	if a.Type != b.Type {
+		if (a.Type == "integer" || a.Type == "number") && (b.Type == "integer" || b.Type == "number") {
+			a.Type = "number"
+		} else {
+			*a = jsonSchema{Nullable: a.Nullable}
+		}
+		a.Format = ""
+		return a
+	}
+	if a.Format != b.Format {
+		a.Format = ""
+	}
+	for k, s := range b.Properties {
+		a.Properties[k] = mergeSchema(a.Properties[k], s)
+	}
+	if a.Items != nil || b.Items != nil {
+		a.Items = mergeSchema(a.Items, b.Items)
+	}
+	return a
+}
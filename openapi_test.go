@@ -0,0 +1,49 @@
+package harkit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func openAPILog() *harfile.Log {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &harfile.Log{Entries: []*harfile.Entry{
+		respond(exportEntry("GET", "https://api.example.com/users/42?fields=name&debug", start, "", nil, 200, "Accept", "application/json", "X-Api-Version", "2"),
+			"application/json", []byte(`{"id":42,"name":"ada","tags":[],"manager":null,"joined":"2024-01-01T00:00:00Z"}`)),
+		respond(exportEntry("GET", "https://api.example.com/users/7?fields=name", start, "", nil, 200),
+			"application/json; charset=utf-8", []byte(`{"id":7,"name":"bob","tags":["admin"],"manager":{"id":1},"score":1.5}`)),
+		respond(exportEntry("POST", "https://api.example.com/users", start, "application/json", []byte(`{"name":"cy","age":30,"active":true}`), 201),
+			"application/json", []byte(`{"id":8}`)),
+		respond(exportEntry("GET", "https://api.example.com/users/42", start, "", nil, 404),
+			"text/plain", []byte("not found")),
+		exportEntry("DELETE", "https://api.example.com/users/42", start, "", nil, 204),
+		respond(exportEntry("GET", "https://cdn.example.com/assets/3fa85f64-5717-4562-b357-2c963f66afa6", start, "", nil, 200),
+			"image/png", []byte{0x89, 'P', 'N', 'G'}),
+		{Request: &harfile.Request{Method: "GET", URL: "/relative"}},
+		nil,
+	}}
+}
+
+func TestToOpenAPI(t *testing.T) {
+	data, err := ToOpenAPI(openAPILog(), OpenAPIOptions{Title: "Users", Version: "1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "openapi.json", data)
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = ToOpenAPI(&harfile.Log{}, OpenAPIOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"openapi\": \"3.0.3\",\n  \"info\": {\n    \"title\": \"Captured API\",\n    \"version\": \"0.0.0\"\n  },\n  \"paths\": {}\n}\n"
+	if string(data) != want {
+		t.Errorf("empty log =\n%s", data)
+	}
+}
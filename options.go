@@ -0,0 +1,270 @@
+package harkit
+
+import (
+	"math/rand/v2"
+	"net"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Option configures the recorders: [Transport] and [Middleware].
+type Option func(*options)
+
+type options struct {
+	maxRequestBodySize  int64
+	maxResponseBodySize int64
+	skipBodies          []string
+	includeHosts        []string
+	excludeHosts        []string
+	includePaths        []*regexp.Regexp
+	excludePaths        []string
+	excludePathsRegexp  []*regexp.Regexp
+	entryFilter         func(*harfile.Entry) bool
+	sampleRate          float64
+	security            bool
+	compressedBodies    bool
+	cacheObserver       CacheObserver
+	hooks               []Hook
+	transformers        []BodyTransformer
+	maxEvents           int
+	policy              *policyApplier
+	logOptions          []LogOption
+	window              time.Duration
+	clock               Clock
+	precision           time.Duration
+	recorder            *Recorder
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{sampleRate: 1, maxEvents: DefaultMaxEvents, clock: RealClock}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.recorder == nil {
+		o.recorder = NewRecorder(RecorderOptions{LogOptions: o.logOptions, Clock: o.clock, RollingWindow: o.window})
+	}
+	return o
+}
+
+// WithMaxBodySize caps the bytes kept from each request and response body.
+// Longer bodies are truncated: their comment says so, they are flagged as
+// _truncated, and BodySize and Content.Size still report the full length.
+// The caller always gets the complete body. By default bodies are kept
+// entirely.
+func WithMaxBodySize(n int64) Option {
+	return func(o *options) {
+		o.maxRequestBodySize = n
+		o.maxResponseBodySize = n
+	}
+}
+
+// WithMaxRequestBodySize caps the bytes kept from each request body, see
+// [WithMaxBodySize].
+func WithMaxRequestBodySize(n int64) Option {
+	return func(o *options) {
+		o.maxRequestBodySize = n
+	}
+}
+
+// WithMaxResponseBodySize caps the bytes kept from each response body, see
+// [WithMaxBodySize].
+func WithMaxResponseBodySize(n int64) Option {
+	return func(o *options) {
+		o.maxResponseBodySize = n
+	}
+}
+
+// WithSkipBodies records only the size of the bodies whose Content-Type
+// starts with one of the prefixes, compared case insensitively, e.g.
+// "video/", "audio/" or "application/octet-stream". Such bodies are
+// reported as truncated to nothing.
+func WithSkipBodies(mimePrefixes ...string) Option {
+	return func(o *options) {
+		o.skipBodies = append(o.skipBodies, mimePrefixes...)
+	}
+}
+
+// bodyBuffer returns the buffer capturing a body of the given Content-Type,
+// keeping at most limit bytes.
+func (o *options) bodyBuffer(contentType string, limit int64) limitedBuffer {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, prefix := range o.skipBodies {
+		if strings.HasPrefix(contentType, strings.ToLower(prefix)) {
+			return limitedBuffer{discard: true}
+		}
+	}
+	return limitedBuffer{limit: limit}
+}
+
+// WithIncludeHosts records only requests whose host, without port, matches
+// one of the [path.Match] patterns, e.g. "api.example.com" or
+// "*.example.com". Exclusions apply on top.
+func WithIncludeHosts(patterns ...string) Option {
+	return func(o *options) {
+		o.includeHosts = append(o.includeHosts, patterns...)
+	}
+}
+
+// WithExcludeHosts skips requests whose host, without port, matches one of
+// the [path.Match] patterns, e.g. "*.internal".
+func WithExcludeHosts(patterns ...string) Option {
+	return func(o *options) {
+		o.excludeHosts = append(o.excludeHosts, patterns...)
+	}
+}
+
+// WithExcludePaths skips requests whose URL path matches one of the
+// [path.Match] patterns, e.g. "/healthz" or "/static/*".
+func WithExcludePaths(patterns ...string) Option {
+	return func(o *options) {
+		o.excludePaths = append(o.excludePaths, patterns...)
+	}
+}
+
+// WithIncludePathsRegexp records only requests whose URL path matches one
+// of the patterns. Exclusions apply on top.
+func WithIncludePathsRegexp(patterns ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.includePaths = append(o.includePaths, patterns...)
+	}
+}
+
+// WithExcludePathsRegexp skips requests whose URL path matches one of the
+// patterns, e.g. regexp.MustCompile(`^/(metrics|collect)\b`).
+func WithExcludePathsRegexp(patterns ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.excludePathsRegexp = append(o.excludePathsRegexp, patterns...)
+	}
+}
+
+// WithEntryFilter drops the entries for which keep returns false, e.g. by
+// status or MIME type. It is called once the entry is complete, before it
+// is recorded, and must be safe for concurrent use. Unlike the host and
+// path filters, it cannot spare the cost of capturing the exchange.
+func WithEntryFilter(keep func(*harfile.Entry) bool) Option {
+	return func(o *options) {
+		o.entryFilter = keep
+	}
+}
+
+// WithSampleRate records only a random fraction of the requests, between 0
+// and 1. The default is 1, recording everything.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithRecorder records into rec, e.g. to share a log between several
+// recorders or to bound its size. By default each recorder has its own
+// unbounded [Recorder].
+func WithRecorder(rec *Recorder) Option {
+	return func(o *options) {
+		o.recorder = rec
+	}
+}
+
+// WithLogOptions applies opts to the log of the recorder, e.g. to set its
+// creator or browser, see [NewLog]. They are ignored with [WithRecorder],
+// whose [RecorderOptions].LogOptions apply instead.
+func WithLogOptions(opts ...LogOption) Option {
+	return func(o *options) {
+		o.logOptions = append(o.logOptions, opts...)
+	}
+}
+
+// WithRollingWindow keeps only the entries of the last d in the log of the
+// recorder, e.g. the last 15 minutes of an always-on capture: entries
+// started earlier are evicted as new ones are appended, along with the
+// pages left without entries. It is ignored with [WithRecorder], whose
+// [RecorderOptions].RollingWindow applies instead; there, OnEvict receives
+// the evicted entries, e.g. to spool them to disk.
+func WithRollingWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// CaptureCompressedBodies records response bodies as received, still
+// compressed, rather than decoded: Text holds them base64 encoded,
+// Content.ContentEncoding names the coding so that
+// [harfile.Content.DecodedBody] can decode them, and BodySize, Content.Size
+// and Compression give the transferred and decoded lengths. The transparent
+// gzip decompression of [http.Transport] is done by the recorder instead, so
+// callers still read decoded bodies. Bodies changed by a [BodyTransformer]
+// are stored decoded. Only the [Transport] records them.
+func CaptureCompressedBodies() Option {
+	return func(o *options) {
+		o.compressedBodies = true
+	}
+}
+
+// WithSecurityDetails records the TLS connection details of each HTTPS
+// request in Entry.SecurityDetails. Only the [Transport] records them.
+func WithSecurityDetails() Option {
+	return func(o *options) {
+		o.security = true
+	}
+}
+
+// WithHook calls h with a copy of each entry recorded, once it is complete.
+// Unlike [RecorderOptions].Hooks, it only sees the entries of this
+// recorder when a [Recorder] is shared.
+func WithHook(h Hook) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, h)
+	}
+}
+
+// WithCacheObserver has the [Transport] ask obs for the cache state of each
+// entry, e.g. [HeaderCacheObserver]. Without it, Entry.Cache is left empty.
+func WithCacheObserver(obs CacheObserver) Option {
+	return func(o *options) {
+		o.cacheObserver = obs
+	}
+}
+
+// keeps reports whether a complete entry should be recorded.
+func (o *options) keeps(e *harfile.Entry) bool {
+	return o.entryFilter == nil || o.entryFilter(e)
+}
+
+// records reports whether a request to host and urlPath should be recorded.
+func (o *options) records(host, urlPath string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if len(o.includeHosts) > 0 && !slices.ContainsFunc(o.includeHosts, func(p string) bool {
+		ok, _ := path.Match(strings.ToLower(p), host)
+		return ok
+	}) {
+		return false
+	}
+	if len(o.includePaths) > 0 && !slices.ContainsFunc(o.includePaths, func(re *regexp.Regexp) bool {
+		return re.MatchString(urlPath)
+	}) {
+		return false
+	}
+	for _, p := range o.excludeHosts {
+		if ok, _ := path.Match(strings.ToLower(p), host); ok {
+			return false
+		}
+	}
+	for _, p := range o.excludePaths {
+		if ok, _ := path.Match(p, urlPath); ok {
+			return false
+		}
+	}
+	for _, re := range o.excludePathsRegexp {
+		if re.MatchString(urlPath) {
+			return false
+		}
+	}
+	return o.sampleRate >= 1 || rand.Float64() < o.sampleRate
+}
@@ -0,0 +1,81 @@
+package harkit
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// PaginateOptions configures [AutoPaginate].
+type PaginateOptions struct {
+	IdleGap time.Duration // Time without activity after which the next entry starts a new page. Zero disables this rule.
+}
+
+// AutoPaginate groups the entries of log without a page into detected
+// pages, and returns the pages it added. A page starts at each top-level
+// navigation: a successful GET answered with HTML and sent without a
+// Referer, or with a Referer that is not part of the current page (which
+// rules out frames). With opts.IdleGap, an entry started that long after
+// the previous one ended also starts a page.
+//
+// Entries before the first detected page stay without a page. The onLoad
+// timing of each page is set to the end of its last entry.
+func AutoPaginate(log *harfile.Log, opts PaginateOptions) []*harfile.Page {
+	entries := slices.DeleteFunc(slices.Clone(log.Entries), func(e *harfile.Entry) bool {
+		return e == nil || e.Request == nil || e.Pageref != ""
+	})
+	slices.SortStableFunc(entries, func(a, b *harfile.Entry) int {
+		return a.StartedDateTime.Compare(b.StartedDateTime)
+	})
+
+	var (
+		pages   []*harfile.Page
+		page    *harfile.Page
+		urls    map[string]bool // Request URLs of the current page.
+		lastEnd time.Time
+	)
+	for _, e := range entries {
+		idle := opts.IdleGap > 0 && !lastEnd.IsZero() && e.StartedDateTime.Sub(lastEnd) >= opts.IdleGap
+		if isNavigation(e, urls) || idle {
+			page = log.AddPage("", e.Request.URL, e.StartedDateTime)
+			pages = append(pages, page)
+			urls = make(map[string]bool)
+		}
+		end := e.StartedDateTime.Add(time.Duration(max(e.Time, 0) * float64(time.Millisecond)))
+		if end.After(lastEnd) {
+			lastEnd = end
+		}
+		if page == nil {
+			continue
+		}
+		e.AttachTo(page)
+		urls[e.Request.URL] = true
+		if onLoad := float64(end.Sub(page.StartedDateTime)) / float64(time.Millisecond); onLoad > page.PageTimings.OnLoad {
+			page.PageTimings.OnLoad = onLoad
+		}
+	}
+	return pages
+}
+
+// isNavigation reports whether e loads a top-level document, given the
+// URLs requested by the current page.
+func isNavigation(e *harfile.Entry, pageURLs map[string]bool) bool {
+	if e.Request.Method != http.MethodGet || e.Response == nil || e.Response.Content == nil {
+		return false
+	}
+	if e.Response.Status < 200 || e.Response.Status > 299 {
+		return false
+	}
+	if mediaType, _, _ := mime.ParseMediaType(e.Response.Content.MimeType); mediaType != "text/html" {
+		return false
+	}
+	for _, h := range e.Request.Headers {
+		if h != nil && http.CanonicalHeaderKey(h.Name) == "Referer" && pageURLs[h.Value] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package harkit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// pageEntry returns a GET of url started ms milliseconds in and lasting
+// 10 ms, answered with mimeType and sent with referer when not empty.
+func pageEntry(ms int, url, mimeType, referer string) *harfile.Entry {
+	e := &harfile.Entry{
+		StartedDateTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(ms) * time.Millisecond),
+		Time:            10,
+		Request:         &harfile.Request{Method: "GET", URL: url},
+		Response:        &harfile.Response{Status: 200, Content: &harfile.Content{MimeType: mimeType}},
+	}
+	if referer != "" {
+		e.Request.Headers = []*harfile.NameValuePair{{Name: "referer", Value: referer}}
+	}
+	return e
+}
+
+// pagerefs renders the pageref of each entry, "-" for none.
+func pagerefs(l *harfile.Log) string {
+	var refs []string
+	for _, e := range l.Entries {
+		ref := e.Pageref
+		if ref == "" {
+			ref = "-"
+		}
+		refs = append(refs, ref)
+	}
+	return strings.Join(refs, " ")
+}
+
+func TestAutoPaginate(t *testing.T) {
+	l := &harfile.Log{Entries: []*harfile.Entry{
+		pageEntry(0, "https://example.com/api/ping", "application/json", ""),
+		pageEntry(10, "https://example.com/", "text/html; charset=utf-8", ""),
+		pageEntry(20, "https://example.com/app.js", "text/javascript", "https://example.com/"),
+		pageEntry(30, "https://example.com/frame", "text/html", "https://example.com/"),
+		pageEntry(40, "https://example.com/about", "text/html", "https://elsewhere.example/"),
+		pageEntry(60, "https://example.com/logo.png", "image/png", "https://example.com/about"),
+	}}
+	pages := AutoPaginate(l, PaginateOptions{})
+	if len(pages) != 2 || len(l.Pages) != 2 {
+		t.Fatalf("%d pages added, %d in log, want 2", len(pages), len(l.Pages))
+	}
+	if got := pagerefs(l); got != "- page_1 page_1 page_1 page_2 page_2" {
+		t.Errorf("pagerefs = %s", got)
+	}
+	if p := pages[0]; p.Title != "https://example.com/" || !p.StartedDateTime.Equal(l.Entries[1].StartedDateTime) || p.PageTimings.OnLoad != 30 {
+		t.Errorf("first page = %+v, onLoad %v", p, p.PageTimings.OnLoad)
+	}
+	if p := pages[1]; p.PageTimings.OnLoad != 30 {
+		t.Errorf("second page onLoad %v, want 30", p.PageTimings.OnLoad)
+	}
+
+	// Entries already in a page are left alone.
+	if again := AutoPaginate(l, PaginateOptions{}); len(again) != 0 {
+		t.Errorf("second run added %d pages", len(again))
+	}
+}
+
+func TestAutoPaginateNotNavigation(t *testing.T) {
+	post := pageEntry(0, "https://example.com/form", "text/html", "")
+	post.Request.Method = "POST"
+	redirect := pageEntry(10, "https://example.com/old", "text/html", "")
+	redirect.Response.Status = 302
+	l := &harfile.Log{Entries: []*harfile.Entry{post, redirect, {}, nil}}
+	if pages := AutoPaginate(l, PaginateOptions{}); len(pages) != 0 {
+		t.Errorf("%d pages for entries that are no navigation", len(pages))
+	}
+}
+
+func TestAutoPaginateIdleGap(t *testing.T) {
+	var entries []*harfile.Entry
+	for i, ms := range []int{0, 20, 40, 2000, 2020, 5000} {
+		entries = append(entries, pageEntry(ms, fmt.Sprintf("https://example.com/api/%d", i), "application/json", ""))
+	}
+	// Recorded out of order. The first burst has no gap before it and
+	// stays outside any page.
+	entries[0], entries[3] = entries[3], entries[0]
+	l := &harfile.Log{Entries: entries}
+	pages := AutoPaginate(l, PaginateOptions{IdleGap: time.Second})
+	if len(pages) != 2 {
+		t.Fatalf("%d pages, want 2", len(pages))
+	}
+	if got := pagerefs(l); got != "page_1 - - - page_1 page_2" {
+		t.Errorf("pagerefs = %s", got)
+	}
+}
@@ -0,0 +1,214 @@
+package harkit
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Placeholders substituted by [TemplatePath].
+const (
+	PlaceholderID   = "{id}"   // Numeric segments.
+	PlaceholderUUID = "{uuid}" // UUIDs.
+	PlaceholderHash = "{hash}" // Hexadecimal hashes and object IDs.
+)
+
+// minHashLen is the shortest hexadecimal segment taken for a hash: 16
+// digits, a 64-bit value.
+const minHashLen = 16
+
+// GroupBy maps the path of a request URL to the key its entries are
+// grouped under, such as a path template returned by [PathTemplater].
+type GroupBy func(path string) string
+
+// PathRule replaces the path segments matched in full by Pattern with
+// Placeholder.
+type PathRule struct {
+	Pattern     *regexp.Regexp
+	Placeholder string // Empty means PlaceholderID.
+}
+
+// PathTemplateOptions configures [TemplatePath].
+type PathTemplateOptions struct {
+	// Routes are explicit route patterns, in the syntax of chi and
+	// gorilla/mux, tried in order before any heuristic: "{name}" matches
+	// one segment, "{name:regexp}" a segment matching the regexp, and a
+	// final "*" the rest of the path. The first matching route is the
+	// template, regexps removed. Invalid routes never match.
+	Routes []string
+	// Rules are custom segment rules, tried in order before the built-in
+	// ones.
+	Rules []PathRule
+	// TrimTrailingSlash groups "/users/" with "/users".
+	TrimTrailingSlash bool
+}
+
+// TemplatePath returns the path of u, a URL or a bare path, with the
+// segments that look like identifiers collapsed into placeholders, so that
+// "/users/123/orders/456" becomes "/users/{id}/orders/{id}". Unless a route
+// of opts matches, segments are tested against the rules of opts, then are
+// replaced when numeric, by [PlaceholderID], when UUIDs, by
+// [PlaceholderUUID], and when hexadecimal strings of at least 16 digits
+// holding a decimal digit, by [PlaceholderHash]. Other segments, such as
+// the version in "/v2/", are kept. The query and fragment are dropped.
+//
+// Routes are compiled on each call; [PathTemplater] compiles them once.
+func TemplatePath(u string, opts PathTemplateOptions) string {
+	return PathTemplater(opts)(u)
+}
+
+// PathTemplater returns a [GroupBy] applying [TemplatePath] with opts.
+func PathTemplater(opts PathTemplateOptions) GroupBy {
+	var routes []*pathRoute
+	for _, r := range opts.Routes {
+		if route, ok := compileRoute(r); ok {
+			routes = append(routes, route)
+		}
+	}
+	rules := opts.Rules
+	return func(u string) string {
+		path := urlPath(u)
+		if opts.TrimTrailingSlash && len(path) > 1 {
+			path = strings.TrimRight(path, "/")
+			if path == "" {
+				path = "/"
+			}
+		}
+		for _, r := range routes {
+			if r.re.MatchString(path) {
+				return r.template
+			}
+		}
+		segments := strings.Split(path, "/")
+		for i, s := range segments {
+			if s != "" {
+				segments[i] = templateSegment(s, rules)
+			}
+		}
+		return strings.Join(segments, "/")
+	}
+}
+
+// urlPath returns the escaped path of u, a URL or a bare path.
+func urlPath(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		path, _, _ := strings.Cut(u, "#")
+		path, _, _ = strings.Cut(path, "?")
+		return path
+	}
+	if path := parsed.EscapedPath(); path != "" || parsed.Host == "" {
+		return path
+	}
+	return "/"
+}
+
+func templateSegment(s string, rules []PathRule) string {
+	for _, r := range rules {
+		if r.Pattern == nil {
+			continue
+		}
+		if loc := r.Pattern.FindStringIndex(s); loc != nil && loc[0] == 0 && loc[1] == len(s) {
+			if r.Placeholder == "" {
+				return PlaceholderID
+			}
+			return r.Placeholder
+		}
+	}
+	switch {
+	case isNumeric(s):
+		return PlaceholderID
+	case uuidRe.MatchString(s):
+		return PlaceholderUUID
+	case isHexHash(s):
+		return PlaceholderHash
+	}
+	return s
+}
+
+// isHexHash reports whether s is a long hexadecimal string with at least
+// one decimal digit, so that long words made of the letters a to f are not
+// taken for hashes.
+func isHexHash(s string) bool {
+	if len(s) < minHashLen {
+		return false
+	}
+	digit := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case r >= 'a' && r <= 'f', r >= 'A' && r <= 'F':
+		default:
+			return false
+		}
+	}
+	return digit
+}
+
+// pathRoute is a compiled route pattern.
+type pathRoute struct {
+	re       *regexp.Regexp
+	template string // The route with the regexps of its variables removed.
+}
+
+// compileRoute compiles a chi or gorilla/mux route pattern, reporting false
+// when it is invalid.
+func compileRoute(route string) (*pathRoute, bool) {
+	var expr, template strings.Builder
+	expr.WriteByte('^')
+	rest := route
+	if strings.HasSuffix(rest, "/*") {
+		rest = strings.TrimSuffix(rest, "*")
+	}
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			expr.WriteString(regexp.QuoteMeta(rest))
+			template.WriteString(rest)
+			break
+		}
+		expr.WriteString(regexp.QuoteMeta(rest[:open]))
+		template.WriteString(rest[:open])
+
+		// Variable regexps may hold braces of their own, as in {n:[0-9]{3}}.
+		depth, end := 0, -1
+		for i := open; i < len(rest) && end < 0; i++ {
+			switch rest[i] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return nil, false
+		}
+		name, pattern, hasPattern := strings.Cut(rest[open+1:end], ":")
+		if name = strings.TrimSpace(name); name == "" {
+			return nil, false
+		}
+		if hasPattern {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, false
+			}
+			expr.WriteString("(?:" + pattern + ")")
+		} else {
+			expr.WriteString("[^/]+")
+		}
+		template.WriteString("{" + name + "}")
+		rest = rest[end+1:]
+	}
+	if strings.HasSuffix(route, "/*") {
+		expr.WriteString(".*")
+		template.WriteByte('*')
+	}
+	expr.WriteByte('$')
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, false
+	}
+	return &pathRoute{re: re, template: template.String()}, true
+}
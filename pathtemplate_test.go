@@ -0,0 +1,244 @@
+package harkit
+
+import (
+	"encoding/json"
+	"maps"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/hardiff"
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestTemplatePath(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts PathTemplateOptions
+		want string
+	}{
+		{"ids", "/users/123/orders/456", PathTemplateOptions{}, "/users/{id}/orders/{id}"},
+		{"full url", "https://api.example.com/users/1?page=2#top", PathTemplateOptions{}, "/users/{id}"},
+		{"host only", "https://example.com", PathTemplateOptions{}, "/"},
+		{"root", "/", PathTemplateOptions{}, "/"},
+		{"empty", "", PathTemplateOptions{}, ""},
+		{"version kept", "/v2/users/7", PathTemplateOptions{}, "/v2/users/{id}"},
+		{"version-like words kept", "/api/v1.2/x2/2fa", PathTemplateOptions{}, "/api/v1.2/x2/2fa"},
+		{"uuid", "/orders/3F2504E0-4F89-11D3-9A0C-0305E82C3301/items", PathTemplateOptions{}, "/orders/{uuid}/items"},
+		{"hash", "/blobs/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", PathTemplateOptions{}, "/blobs/{hash}"},
+		{"object id", "/docs/507f1f77bcf86cd799439011", PathTemplateOptions{}, "/docs/{hash}"},
+		{"short hex kept", "/colors/ff0000", PathTemplateOptions{}, "/colors/ff0000"},
+		{"hex word kept", "/words/deadbeefcafefacade", PathTemplateOptions{}, "/words/deadbeefcafefacade"},
+		{"negative and decimal kept", "/temps/-3/1.5", PathTemplateOptions{}, "/temps/-3/1.5"},
+		{"trailing slash kept", "/users/42/", PathTemplateOptions{}, "/users/{id}/"},
+		{"trailing slash trimmed", "/users/42/", PathTemplateOptions{TrimTrailingSlash: true}, "/users/{id}"},
+		{"repeated slashes trimmed", "/users///", PathTemplateOptions{TrimTrailingSlash: true}, "/users"},
+		{"root kept when trimming", "/", PathTemplateOptions{TrimTrailingSlash: true}, "/"},
+		{"empty segments", "/a//1", PathTemplateOptions{}, "/a//{id}"},
+		{"escaped segment", "/files/a%2Fb/3", PathTemplateOptions{}, "/files/a%2Fb/{id}"},
+		{
+			"custom rule",
+			"/skus/AB-1234/reviews/12",
+			PathTemplateOptions{Rules: []PathRule{{Pattern: regexp.MustCompile(`[A-Z]{2}-\d+`), Placeholder: "{sku}"}}},
+			"/skus/{sku}/reviews/{id}",
+		},
+		{
+			"rule matches whole segments only",
+			"/skus/xAB-1234",
+			PathTemplateOptions{Rules: []PathRule{{Pattern: regexp.MustCompile(`[A-Z]{2}-\d+`)}}},
+			"/skus/xAB-1234",
+		},
+		{
+			"rule default placeholder",
+			"/users/alice",
+			PathTemplateOptions{Rules: []PathRule{{Pattern: regexp.MustCompile(`[a-z]+`)}, {}}},
+			"/{id}/{id}",
+		},
+		{
+			"route",
+			"/users/alice/orders/456",
+			PathTemplateOptions{Routes: []string{"/users/{user}/orders/{order}"}},
+			"/users/{user}/orders/{order}",
+		},
+		{
+			"route before heuristics",
+			"/v2/items/123",
+			PathTemplateOptions{Routes: []string{"/{version:v[0-9]+}/items/{item:[0-9]+}"}},
+			"/{version}/items/{item}",
+		},
+		{
+			"route regexp with braces",
+			"/codes/123",
+			PathTemplateOptions{Routes: []string{"/codes/{code:[0-9]{4}}", "/codes/{code:[0-9]{3}}"}},
+			"/codes/{code}",
+		},
+		{
+			"first matching route",
+			"/a/b",
+			PathTemplateOptions{Routes: []string{"/a/{x}", "/{y}/b"}},
+			"/a/{x}",
+		},
+		{
+			"wildcard route",
+			"/static/js/app.123.js",
+			PathTemplateOptions{Routes: []string{"/static/*"}},
+			"/static/*",
+		},
+		{
+			"route not matching a trailing slash",
+			"/users/1/",
+			PathTemplateOptions{Routes: []string{"/users/{user}"}},
+			"/users/{id}/",
+		},
+		{
+			"route after trimming",
+			"/users/1/",
+			PathTemplateOptions{Routes: []string{"/users/{user}"}, TrimTrailingSlash: true},
+			"/users/{user}",
+		},
+		{
+			"invalid routes ignored",
+			"/users/1",
+			PathTemplateOptions{Routes: []string{"/users/{user", "/users/{}", "/users/{u:[}"}},
+			"/users/{id}",
+		},
+		{
+			"route quoting",
+			"/a.b/1",
+			PathTemplateOptions{Routes: []string{"/a.c/{n}", "/a.b/{n}"}},
+			"/a.b/{n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TemplatePath(tt.in, tt.opts); got != tt.want {
+				t.Errorf("TemplatePath(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// groupLog has entries for two users and two orders on the same routes.
+func groupLog() *harfile.Log {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var entries []*harfile.Entry
+	for i, u := range []string{
+		"https://api.example.com/v2/users/1",
+		"https://api.example.com/v2/users/2",
+		"https://api.example.com/v2/users/1/orders/10",
+		"https://api.example.com/v2/users/2/orders/20?expand=items",
+	} {
+		entries = append(entries, harfile.NewEntry().Get(u).StartedAt(started.Add(time.Duration(i)*time.Second)).
+			RespondStatus(200).Timing(harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Wait: 10 * float64(i+1)}).Build())
+	}
+	return harfile.NewLog().Entries(entries...).Build()
+}
+
+func TestGroupByHistogram(t *testing.T) {
+	group := PathTemplater(PathTemplateOptions{})
+	h := HistogramBy(groupLog(), []float64{25}, ByPathGroup(group))
+	want := map[string][]int{
+		"/v2/users/{id}":             {2, 0},
+		"/v2/users/{id}/orders/{id}": {0, 2},
+	}
+	if !maps.EqualFunc(h.Counts, want, slices.Equal) {
+		t.Errorf("Counts = %v, want %v", h.Counts, want)
+	}
+	if h := HistogramBy(groupLog(), nil, ByPathGroup(nil)); len(h.Counts) != 4 {
+		t.Errorf("ungrouped Counts = %v", h.Counts)
+	}
+}
+
+func TestGroupByDiff(t *testing.T) {
+	a := groupLog()
+	b := groupLog()
+	for i, e := range b.Entries {
+		e.Request.URL = []string{
+			"https://api.example.com/v2/users/3",
+			"https://api.example.com/v2/users/4",
+			"https://api.example.com/v2/users/3/orders/30",
+			"https://api.example.com/v2/users/4/orders/40?expand=items",
+		}[i]
+	}
+	ha, hb := &harfile.HAR{Log: a}, &harfile.HAR{Log: b}
+	if r := Diff(ha, hb, DiffOptions{}); len(r.OnlyInA) != 4 || len(r.OnlyInB) != 4 {
+		t.Errorf("without grouping: %d only in a, %d only in b", len(r.OnlyInA), len(r.OnlyInB))
+	}
+	r := Diff(ha, hb, DiffOptions{GroupBy: PathTemplater(PathTemplateOptions{})})
+	if len(r.OnlyInA) != 0 || len(r.OnlyInB) != 0 || len(r.Unchanged) != 4 {
+		t.Fatalf("grouped: %d only in a, %d only in b, %d unchanged", len(r.OnlyInA), len(r.OnlyInB), len(r.Unchanged))
+	}
+	for _, d := range r.Unchanged {
+		if d.A.Request.URL == d.B.Request.URL {
+			t.Errorf("pair %s holds the grouped URL", d.A.Request.URL)
+		}
+	}
+	if a.Entries[0].Request.URL != "https://api.example.com/v2/users/1" {
+		t.Errorf("grouping changed the entry URL to %s", a.Entries[0].Request.URL)
+	}
+}
+
+func TestGroupByCoverage(t *testing.T) {
+	h := &harfile.HAR{Log: groupLog()}
+	var paths []string
+	for ep, stats := range hardiff.Coverage(h, hardiff.WithGroupBy(PathTemplater(PathTemplateOptions{}))) {
+		paths = append(paths, ep.Path)
+		if stats.Count != 2 {
+			t.Errorf("%s: %d entries, want 2", ep, stats.Count)
+		}
+	}
+	slices.Sort(paths)
+	if want := []string{"/v2/users/{id}", "/v2/users/{id}/orders/{id}"}; !slices.Equal(paths, want) {
+		t.Errorf("endpoints %v, want %v", paths, want)
+	}
+}
+
+func TestGroupByOpenAPI(t *testing.T) {
+	data, err := ToOpenAPI(groupLog(), OpenAPIOptions{GroupBy: PathTemplater(PathTemplateOptions{
+		Routes: []string{"/v2/users/{user}/orders/{order}"},
+	})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Parameters []struct {
+				Name, In string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	params := func(path string) []string {
+		var names []string
+		for _, p := range doc.Paths[path]["get"].Parameters {
+			if p.In == "path" {
+				names = append(names, p.Name)
+			}
+		}
+		slices.Sort(names)
+		return names
+	}
+	if got := slices.Sorted(maps.Keys(doc.Paths)); !slices.Equal(got, []string{"/v2/users/{id}", "/v2/users/{user}/orders/{order}"}) {
+		t.Fatalf("paths = %v", got)
+	}
+	if got := params("/v2/users/{user}/orders/{order}"); !slices.Equal(got, []string{"order", "user"}) {
+		t.Errorf("route parameters = %v", got)
+	}
+	if got := params("/v2/users/{id}"); !slices.Equal(got, []string{"id"}) {
+		t.Errorf("parameters = %v", got)
+	}
+}
+
+func TestGroupedPathRepeatedNames(t *testing.T) {
+	path, params := groupedPath("/users/1/orders/2", PathTemplater(PathTemplateOptions{}))
+	if want := "/users/{id}/orders/{id2}"; path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	if want := map[string]string{"id": "1", "id2": "2"}; !maps.Equal(params, want) {
+		t.Errorf("params = %v, want %v", params, want)
+	}
+}
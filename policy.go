@@ -0,0 +1,530 @@
+package harkit
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// FieldRules lists what a [Policy] does with named values. The name "*"
+// stands for every name listed nowhere else; values matched by no rule are
+// kept.
+type FieldRules struct {
+	Keep   []string `json:"keep,omitempty"`   // Left as is.
+	Redact []string `json:"redact,omitempty"` // Replaced by the placeholder.
+	Hash   []string `json:"hash,omitempty"`   // Replaced by "sha256:" and the hex SHA-256 digest, so that equal values stay equal.
+	Drop   []string `json:"drop,omitempty"`   // Removed.
+}
+
+// Policy bundles the rules scrubbing captures in one declarative value, to
+// be shared by services as JSON: [Policy.Apply] enforces it on a HAR, and
+// [WithPolicy] on the entries recorded by [Transport] and [Middleware].
+// YAML documents can be converted by libraries going through JSON, such as
+// sigs.k8s.io/yaml.
+//
+// Cookie rules apply to the cookie lists and to the Cookie and Set-Cookie
+// headers, before header rules. Body rules name posted parameters, in
+// PostData.Params and in URL encoded bodies, and paths of JSON request and
+// response bodies in the syntax of [RedactOptions].JSONPaths, "[*]" being
+// accepted for "[]"; they cannot use "*".
+type Policy struct {
+	Headers     FieldRules `json:"headers"`               // Header names, compared case insensitively.
+	Cookies     FieldRules `json:"cookies"`               // Cookie names.
+	QueryParams FieldRules `json:"queryParams"`           // Query parameter names, in QueryString and in the URL.
+	Body        FieldRules `json:"body"`                  // Posted parameter names and JSON paths.
+	Placeholder string     `json:"placeholder,omitempty"` // Replacement of redacted values. Empty means DefaultPlaceholder.
+}
+
+// DefaultPrivacyPolicy redacts the usual credentials: authorization and API
+// key headers, every cookie value, and token, secret and password query
+// parameters and body fields.
+var DefaultPrivacyPolicy = Policy{
+	Headers: FieldRules{Redact: []string{
+		"Authorization", "Proxy-Authorization", "X-Api-Key", "X-Auth-Token",
+		"X-Csrf-Token", "X-Xsrf-Token", "X-Amz-Security-Token",
+	}},
+	Cookies: FieldRules{Redact: []string{"*"}},
+	QueryParams: FieldRules{Redact: []string{
+		"access_token", "refresh_token", "id_token", "token", "api_key",
+		"apikey", "key", "client_secret", "password", "signature", "sig",
+	}},
+	Body: FieldRules{Redact: []string{
+		"password", "passwd", "secret", "client_secret", "access_token",
+		"refresh_token", "id_token", "token", "api_key", "apiKey",
+	}},
+}
+
+// ParsePolicy decodes a JSON policy, rejecting unknown members, and
+// validates it.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("harkit: policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate reports the conflicting rules of p, such as a header both kept
+// and redacted, as well as empty names and "*" in body rules.
+func (p *Policy) Validate() error {
+	var errs []error
+	check := func(kind string, rules FieldRules, fold bool) {
+		first := make(map[string]string)
+		for _, r := range rules.all() {
+			if r.name == "" {
+				errs = append(errs, fmt.Errorf("harkit: policy: empty %s name", kind))
+				continue
+			}
+			if kind == "body field" && r.name == "*" {
+				errs = append(errs, errors.New(`harkit: policy: body rules cannot use "*"`))
+				continue
+			}
+			key := r.name
+			if fold {
+				key = strings.ToLower(key)
+			}
+			if prev, ok := first[key]; ok && prev != r.action {
+				errs = append(errs, fmt.Errorf("harkit: policy: %s %q is both %s and %s", kind, r.name, participle[prev], participle[r.action]))
+				continue
+			}
+			first[key] = r.action
+		}
+	}
+	check("header", p.Headers, true)
+	check("cookie", p.Cookies, false)
+	check("query parameter", p.QueryParams, false)
+	check("body field", p.Body, false)
+	return errors.Join(errs...)
+}
+
+// Apply enforces p on har in place. Sizes are fixed as by [Redact], and
+// changed entries are tagged with [harfile.TagRedacted] and get a note in
+// their comment. Apply changes nothing when p is invalid and returns the
+// error of [Policy.Validate].
+func (p *Policy) Apply(har *harfile.HAR) error {
+	a, err := p.compile()
+	if err != nil {
+		return err
+	}
+	if har == nil || har.Log == nil {
+		return nil
+	}
+	for _, e := range har.Log.Entries {
+		if e != nil {
+			a.entry(e)
+		}
+	}
+	return nil
+}
+
+// WithPolicy enforces p on the recorded entries, before the hooks see them.
+// It panics when p is invalid, see [Policy.Validate].
+func WithPolicy(p Policy) Option {
+	a, err := p.compile()
+	if err != nil {
+		panic(err)
+	}
+	return func(o *options) {
+		o.policy = a
+	}
+}
+
+// Policy actions.
+const (
+	actKeep   = "keep"
+	actRedact = "redact"
+	actHash   = "hash"
+	actDrop   = "drop"
+)
+
+var participle = map[string]string{actKeep: "kept", actRedact: "redacted", actHash: "hashed", actDrop: "dropped"}
+
+type fieldRule struct {
+	name, action string
+}
+
+func (r FieldRules) all() []fieldRule {
+	var rules []fieldRule
+	for _, list := range []struct {
+		action string
+		names  []string
+	}{{actKeep, r.Keep}, {actRedact, r.Redact}, {actHash, r.Hash}, {actDrop, r.Drop}} {
+		for _, name := range list.names {
+			rules = append(rules, fieldRule{name, list.action})
+		}
+	}
+	return rules
+}
+
+// ruleSet maps names to actions.
+type ruleSet struct {
+	exact    map[string]string
+	wildcard string
+	fold     bool
+}
+
+func newRuleSet(rules FieldRules, fold bool) ruleSet {
+	rs := ruleSet{exact: make(map[string]string), fold: fold}
+	for _, r := range rules.all() {
+		if r.name == "*" {
+			rs.wildcard = r.action
+			continue
+		}
+		if fold {
+			r.name = strings.ToLower(r.name)
+		}
+		rs.exact[r.name] = r.action
+	}
+	return rs
+}
+
+// action returns what to do with the value named name, "" to keep it.
+func (rs ruleSet) action(name string) string {
+	if rs.fold {
+		name = strings.ToLower(name)
+	}
+	if a, ok := rs.exact[name]; ok {
+		return a
+	}
+	return rs.wildcard
+}
+
+// policyApplier enforces a validated policy.
+type policyApplier struct {
+	placeholder string
+	headers     ruleSet
+	cookies     ruleSet
+	query       ruleSet
+	params      ruleSet
+	paths       []jsonRule
+	count       int
+}
+
+type jsonRule struct {
+	path   []string
+	action string
+}
+
+func (p *Policy) compile() (*policyApplier, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	a := &policyApplier{
+		placeholder: cmp.Or(p.Placeholder, DefaultPlaceholder),
+		headers:     newRuleSet(p.Headers, true),
+		cookies:     newRuleSet(p.Cookies, false),
+		query:       newRuleSet(p.QueryParams, false),
+		params:      newRuleSet(p.Body, false),
+	}
+	for _, r := range p.Body.all() {
+		if r.action != actKeep {
+			path := splitJSONPath(strings.ReplaceAll(r.name, "[*]", "[]"))
+			a.paths = append(a.paths, jsonRule{path, r.action})
+		}
+	}
+	return a, nil
+}
+
+// entry enforces the policy on e. It is safe for concurrent use, counting
+// the changes of each call on a copy of a.
+func (a *policyApplier) entry(e *harfile.Entry) {
+	run := *a
+	run.count = 0
+	if req := e.Request; req != nil {
+		run.request(req)
+	}
+	if resp := e.Response; resp != nil {
+		run.response(resp)
+	}
+	if run.count > 0 {
+		e.AddTag(harfile.TagRedacted)
+		e.AddNote(fmt.Sprintf("values scrubbed by policy: %d", run.count))
+	}
+}
+
+// value returns what action leaves of v, and false when v is dropped.
+func (a *policyApplier) value(action, v string) (string, bool) {
+	switch action {
+	case actRedact:
+		if v != a.placeholder {
+			a.count++
+		}
+		return a.placeholder, true
+	case actHash:
+		if !strings.HasPrefix(v, "sha256:") {
+			a.count++
+			sum := sha256.Sum256([]byte(v))
+			v = "sha256:" + hex.EncodeToString(sum[:])
+		}
+		return v, true
+	case actDrop:
+		a.count++
+		return "", false
+	}
+	return v, true
+}
+
+func (a *policyApplier) request(req *harfile.Request) {
+	req.Cookies = a.cookieList(req.Cookies)
+	if a.headerList(&req.Headers) {
+		req.HeadersSize = -1
+	}
+
+	req.QueryString = a.pairs(req.QueryString, a.query)
+	if u, err := url.Parse(req.URL); err == nil && u.RawQuery != "" {
+		// Values listed in QueryString were counted already.
+		count := a.count
+		if raw := a.rawParams(u.RawQuery, a.query); raw != u.RawQuery {
+			u.RawQuery = raw
+			req.URL = u.String()
+			req.HeadersSize = -1
+			if len(req.QueryString) > 0 {
+				a.count = count
+			}
+		}
+	}
+
+	pd := req.PostData
+	if pd == nil {
+		return
+	}
+	pd.Params = slices.DeleteFunc(pd.Params, func(p *harfile.Param) bool {
+		if p == nil {
+			return false
+		}
+		v, keep := a.value(a.params.action(p.Name), p.Value)
+		p.Value = v
+		return !keep
+	})
+	text := pd.Text
+	switch {
+	case isJSONMime(pd.MimeType):
+		text = a.json(text)
+	case strings.HasPrefix(strings.ToLower(pd.MimeType), "application/x-www-form-urlencoded"):
+		count := a.count
+		text = a.rawParams(text, a.params)
+		if len(pd.Params) > 0 {
+			a.count = count
+		}
+	}
+	if text != pd.Text {
+		pd.Text = text
+		req.BodySize = int64(len(text))
+	}
+}
+
+func (a *policyApplier) response(resp *harfile.Response) {
+	resp.Cookies = a.cookieList(resp.Cookies)
+	if a.headerList(&resp.Headers) {
+		resp.HeadersSize = -1
+	}
+	rewriteContent(resp, func(mimeType, text string) string {
+		if !isJSONMime(mimeType) {
+			return text
+		}
+		return a.json(text)
+	})
+}
+
+func (a *policyApplier) cookieList(cookies []*harfile.Cookie) []*harfile.Cookie {
+	return slices.DeleteFunc(cookies, func(c *harfile.Cookie) bool {
+		if c == nil {
+			return false
+		}
+		v, keep := a.value(a.cookies.action(c.Name), c.Value)
+		c.Value = v
+		return !keep
+	})
+}
+
+// headerList applies the cookie rules to the Cookie and Set-Cookie headers
+// of *headers, then the header rules, and reports whether it changed.
+func (a *policyApplier) headerList(headers *[]*harfile.NameValuePair) bool {
+	changed := false
+	*headers = slices.DeleteFunc(*headers, func(h *harfile.NameValuePair) bool {
+		if h == nil {
+			return false
+		}
+		before := h.Value
+		keep := true
+		switch {
+		case strings.EqualFold(h.Name, "Cookie"):
+			h.Value = a.cookieHeader(h.Value)
+			keep = h.Value != "" || before == ""
+		case strings.EqualFold(h.Name, "Set-Cookie"):
+			pair, rest, _ := strings.Cut(h.Value, ";")
+			if name, value, ok := strings.Cut(pair, "="); ok {
+				var v string
+				if v, keep = a.value(a.cookies.action(strings.TrimSpace(name)), value); keep && v != value {
+					h.Value = name + "=" + v
+					if rest != "" {
+						h.Value += ";" + rest
+					}
+				}
+			}
+		}
+		if keep {
+			h.Value, keep = a.value(a.headers.action(h.Name), h.Value)
+		}
+		changed = changed || !keep || h.Value != before
+		return !keep
+	})
+	return changed
+}
+
+// cookieHeader applies the cookie rules to the value of a Cookie header.
+func (a *policyApplier) cookieHeader(value string) string {
+	var parts []string
+	for _, part := range strings.Split(value, ";") {
+		name, v, ok := strings.Cut(part, "=")
+		if ok {
+			var keep bool
+			if v, keep = a.value(a.cookies.action(strings.TrimSpace(name)), v); !keep {
+				continue
+			}
+			part = name + "=" + v
+		}
+		parts = append(parts, part)
+	}
+	return strings.TrimSpace(strings.Join(parts, ";"))
+}
+
+func (a *policyApplier) pairs(pairs []*harfile.NameValuePair, rules ruleSet) []*harfile.NameValuePair {
+	return slices.DeleteFunc(pairs, func(p *harfile.NameValuePair) bool {
+		if p == nil {
+			return false
+		}
+		v, keep := a.value(rules.action(p.Name), p.Value)
+		p.Value = v
+		return !keep
+	})
+}
+
+// rawParams applies rules to a URL encoded parameter list, keeping the
+// order and the encoding of the other parameters.
+func (a *policyApplier) rawParams(raw string, rules ruleSet) string {
+	if raw == "" {
+		return raw
+	}
+	var parts []string
+	for _, part := range strings.Split(raw, "&") {
+		key, value, _ := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if action := rules.action(name); action != "" && action != actKeep {
+			decoded, err := url.QueryUnescape(value)
+			if err != nil {
+				decoded = value
+			}
+			v, keep := a.value(action, decoded)
+			if !keep {
+				continue
+			}
+			if v != decoded {
+				part = key + "=" + url.QueryEscape(v)
+			}
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "&")
+}
+
+// json applies the body rules to a JSON document. The document is
+// re-serialized when changed, so object keys end up sorted.
+func (a *policyApplier) json(text string) string {
+	if len(a.paths) == 0 || text == "" {
+		return text
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return text
+	}
+	count := a.count
+	for _, r := range a.paths {
+		doc = a.jsonPath(doc, r.path, r.action)
+	}
+	if a.count == count {
+		return text
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		a.count = count
+		return text
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonPath applies action to the values of v at path, returning v.
+func (a *policyApplier) jsonPath(v any, path []string, action string) any {
+	if len(path) == 0 {
+		return v
+	}
+	switch node := v.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return v
+		}
+		if len(path) > 1 {
+			node[path[0]] = a.jsonPath(child, path[1:], action)
+			return v
+		}
+		if leaf, keep := a.jsonValue(action, child); keep {
+			node[path[0]] = leaf
+		} else {
+			delete(node, path[0])
+		}
+	case []any:
+		if path[0] != "[]" {
+			return v
+		}
+		kept := node[:0]
+		for _, elem := range node {
+			if len(path) > 1 {
+				kept = append(kept, a.jsonPath(elem, path[1:], action))
+			} else if leaf, keep := a.jsonValue(action, elem); keep {
+				kept = append(kept, leaf)
+			}
+		}
+		return kept
+	}
+	return v
+}
+
+// jsonValue applies action to a JSON value, hashing the text of strings and
+// the encoding of other values.
+func (a *policyApplier) jsonValue(action string, v any) (any, bool) {
+	if v == nil && action != actDrop {
+		return v, true
+	}
+	s, ok := v.(string)
+	if !ok {
+		b, _ := json.Marshal(v)
+		s = string(b)
+	}
+	out, keep := a.value(action, s)
+	if !ok && out == s {
+		return v, keep
+	}
+	return out, keep
+}
@@ -0,0 +1,264 @@
+package harkit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		err  string // Substrings of the error, separated by "|"; empty when valid.
+	}{
+		{"valid", `{"headers":{"keep":["Accept"],"redact":["Authorization"]},"cookies":{"drop":["*"]},"body":{"hash":["$.email","items[*].card"]},"placeholder":"***"}`, ""},
+		{"empty", `{}`, ""},
+		{"same action twice", `{"queryParams":{"redact":["token","token"]}}`, ""},
+		{"cookie names are case sensitive", `{"cookies":{"keep":["sid"],"drop":["SID"]}}`, ""},
+		{"unknown member", `{"header":{"keep":["Accept"]}}`, `harkit: policy: json: unknown field "header"`},
+		{"not json", `headers: {}`, "harkit: policy: "},
+		{"header kept and redacted", `{"headers":{"keep":["authorization"],"redact":["Authorization"]}}`,
+			`harkit: policy: header "Authorization" is both kept and redacted`},
+		{"cookie hashed and dropped", `{"cookies":{"hash":["sid"],"drop":["sid"]}}`, `harkit: policy: cookie "sid" is both hashed and dropped`},
+		{"wildcard conflict", `{"queryParams":{"keep":["*"],"redact":["*"]}}`, `query parameter "*" is both kept and redacted`},
+		{"empty name", `{"queryParams":{"redact":[""]}}`, "harkit: policy: empty query parameter name"},
+		{"body wildcard", `{"body":{"redact":["*"]}}`, `harkit: policy: body rules cannot use "*"`},
+		{"every error", `{"headers":{"keep":["A"],"drop":["a",""]},"body":{"keep":["x"],"hash":["x"]}}`,
+			`header "a" is both kept and dropped|empty header name|body field "x" is both kept and hashed`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePolicy([]byte(tt.json))
+			if tt.err == "" {
+				if err != nil || p == nil {
+					t.Fatalf("ParsePolicy = %v, %v", p, err)
+				}
+				return
+			}
+			if err == nil || p != nil {
+				t.Fatalf("ParsePolicy = %+v, want error %q", p, tt.err)
+			}
+			for _, want := range strings.Split(tt.err, "|") {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePolicyRoundTrip(t *testing.T) {
+	data, err := json.Marshal(DefaultPrivacyPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParsePolicy(data)
+	if err != nil || !reflect.DeepEqual(*p, DefaultPrivacyPolicy) {
+		t.Errorf("ParsePolicy = %+v, %v, want the default policy", p, err)
+	}
+}
+
+// TestParsePolicyYAML decodes a policy as sigs.k8s.io/yaml hands it over:
+// the YAML document below is decoded to generic values, then encoded to
+// JSON.
+//
+//	headers:
+//	  redact: [Authorization]
+//	cookies:
+//	  keep: [theme]
+//	  hash: ["*"]
+//	body:
+//	  drop: [$.card.number]
+//	placeholder: "-"
+func TestParsePolicyYAML(t *testing.T) {
+	doc := map[string]any{
+		"headers":     map[string]any{"redact": []any{"Authorization"}},
+		"cookies":     map[string]any{"keep": []any{"theme"}, "hash": []any{"*"}},
+		"body":        map[string]any{"drop": []any{"$.card.number"}},
+		"placeholder": "-",
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParsePolicy(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Policy{
+		Headers:     FieldRules{Redact: []string{"Authorization"}},
+		Cookies:     FieldRules{Keep: []string{"theme"}, Hash: []string{"*"}},
+		Body:        FieldRules{Drop: []string{"$.card.number"}},
+		Placeholder: "-",
+	}
+	if !reflect.DeepEqual(*p, want) {
+		t.Errorf("ParsePolicy = %+v, want %+v", *p, want)
+	}
+}
+
+// policyEntry returns an entry carrying credentials in every place a
+// policy looks at.
+func policyEntry() *harfile.Entry {
+	body := `{"user":"ada","password":"hunter2","cards":[{"number":"4111","exp":"01/30"}]}`
+	return &harfile.Entry{
+		Request: &harfile.Request{
+			Method: "POST",
+			URL:    "https://example.com/login?token=abc&page=2&email=ada%40example.com",
+			Headers: []*harfile.NameValuePair{
+				{Name: "authorization", Value: "Bearer abc"},
+				{Name: "Accept", Value: "*/*"},
+				{Name: "X-Trace", Value: "1"},
+				{Name: "Cookie", Value: "sid=1; theme=dark"},
+				nil,
+			},
+			Cookies: []*harfile.Cookie{{Name: "sid", Value: "1"}, {Name: "theme", Value: "dark"}},
+			QueryString: []*harfile.NameValuePair{
+				{Name: "token", Value: "abc"},
+				{Name: "page", Value: "2"},
+				{Name: "email", Value: "ada@example.com"},
+			},
+			PostData:    &harfile.PostData{MimeType: "application/json", Text: body},
+			HeadersSize: 300,
+			BodySize:    int64(len(body)),
+		},
+		Response: &harfile.Response{
+			Status: 200,
+			Headers: []*harfile.NameValuePair{
+				{Name: "Set-Cookie", Value: "sid=2; Path=/; HttpOnly"},
+				{Name: "Content-Type", Value: "application/json"},
+			},
+			Cookies:     []*harfile.Cookie{{Name: "sid", Value: "2"}},
+			Content:     &harfile.Content{MimeType: "application/json", Text: `{"password":"x","id":7}`, Size: 23},
+			HeadersSize: 200,
+			BodySize:    23,
+		},
+	}
+}
+
+var testPolicy = Policy{
+	Headers:     FieldRules{Redact: []string{"Authorization"}, Drop: []string{"x-trace"}},
+	Cookies:     FieldRules{Keep: []string{"theme"}, Redact: []string{"*"}},
+	QueryParams: FieldRules{Redact: []string{"token"}, Hash: []string{"email"}},
+	Body:        FieldRules{Redact: []string{"password"}, Drop: []string{"cards[*].number"}},
+}
+
+func TestPolicyApply(t *testing.T) {
+	e := policyEntry()
+	h := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e, nil}}}
+	if err := testPolicy.Apply(h); err != nil {
+		t.Fatal(err)
+	}
+
+	req := e.Request
+	var headers []string
+	for _, h := range req.Headers {
+		if h != nil {
+			headers = append(headers, h.Name+": "+h.Value)
+		}
+	}
+	if got := strings.Join(headers, ", "); got != "authorization: [REDACTED], Accept: */*, Cookie: sid=[REDACTED]; theme=dark" {
+		t.Errorf("request headers = %q", got)
+	}
+	if req.Cookies[0].Value != DefaultPlaceholder || req.Cookies[1].Value != "dark" {
+		t.Errorf("request cookies = %+v, %+v", req.Cookies[0], req.Cookies[1])
+	}
+	const emailHash = "sha256:b5fc85e55755f9e0d030a10ab4429b6b2944855f9a0d60077fe832becbc41d72"
+	if q := req.QueryString; q[0].Value != DefaultPlaceholder || q[1].Value != "2" || q[2].Value != emailHash {
+		t.Errorf("query string = %v", q)
+	}
+	if !strings.Contains(req.URL, "token=%5BREDACTED%5D&page=2&email=sha256%3A"+emailHash[len("sha256:"):]) {
+		t.Errorf("URL = %s", req.URL)
+	}
+	want := `{"cards":[{"exp":"01/30"}],"password":"[REDACTED]","user":"ada"}`
+	if req.PostData.Text != want || req.BodySize != int64(len(want)) || req.HeadersSize != -1 {
+		t.Errorf("request body %s, bodySize %d, headersSize %d", req.PostData.Text, req.BodySize, req.HeadersSize)
+	}
+
+	resp := e.Response
+	if v := resp.Headers[0].Value; v != "sid=[REDACTED]; Path=/; HttpOnly" || resp.Cookies[0].Value != DefaultPlaceholder || resp.HeadersSize != -1 {
+		t.Errorf("Set-Cookie %q, cookies %+v", v, resp.Cookies[0])
+	}
+	if c := resp.Content; c.Text != `{"id":7,"password":"[REDACTED]"}` || c.Size != int64(len(c.Text)) || resp.BodySize != c.Size {
+		t.Errorf("content = %+v, bodySize %d", c, resp.BodySize)
+	}
+	if !e.HasTag(harfile.TagRedacted) || !strings.HasPrefix(e.Comment, "values scrubbed by policy: ") {
+		t.Errorf("tags %v, comment %q", e.Tags, e.Comment)
+	}
+
+	// Applying the policy again changes nothing more.
+	before := e.Clone()
+	testPolicy.Apply(h)
+	if before.Request.URL != e.Request.URL || before.Comment != e.Comment {
+		t.Errorf("second pass changed the entry: %s, %q", e.Request.URL, e.Comment)
+	}
+}
+
+func TestPolicyApplyForm(t *testing.T) {
+	e := &harfile.Entry{Request: &harfile.Request{
+		Method:   "POST",
+		URL:      "https://example.com/",
+		PostData: &harfile.PostData{MimeType: "application/x-www-form-urlencoded", Text: "user=ada&password=hunter%32&cards%5B%5D=1"},
+	}}
+	p := Policy{Body: FieldRules{Hash: []string{"user"}, Drop: []string{"password"}}, Placeholder: "-"}
+	if err := p.Apply(&harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Request.PostData.Text; !strings.HasPrefix(got, "user=sha256%3A") || !strings.HasSuffix(got, "&cards%5B%5D=1") || strings.Contains(got, "password") {
+		t.Errorf("form body = %q", got)
+	}
+}
+
+func TestPolicyApplyInvalid(t *testing.T) {
+	e := policyEntry()
+	h := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e}}}
+	p := Policy{Headers: FieldRules{Keep: []string{"Accept"}, Hash: []string{"accept"}}}
+	if err := p.Apply(h); err == nil || !strings.Contains(err.Error(), "both kept and hashed") {
+		t.Errorf("Apply = %v", err)
+	}
+	if !reflect.DeepEqual(e, policyEntry()) {
+		t.Error("invalid policy changed the entry")
+	}
+	if err := p.Apply(nil); err == nil {
+		t.Error("invalid policy applied to nil without error")
+	}
+	if err := testPolicy.Apply(&harfile.HAR{}); err != nil {
+		t.Errorf("HAR without log: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("WithPolicy accepted an invalid policy")
+		}
+	}()
+	WithPolicy(p)
+}
+
+func TestTransportPolicy(t *testing.T) {
+	tr := NewTransport(stubTransport{}, WithPolicy(DefaultPrivacyPolicy))
+	req, _ := http.NewRequest("GET", "https://example.com/?api_key=abc&q=go", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("Cookie", "sid=1")
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if req.Header.Get("Authorization") != "Bearer abc" || req.URL.RawQuery != "api_key=abc&q=go" {
+		t.Error("the policy changed the request sent")
+	}
+
+	e := tr.HAR().Log.Entries[0]
+	if e.Request.URL != "https://example.com/?api_key=%5BREDACTED%5D&q=go" || !e.HasTag(harfile.TagRedacted) {
+		t.Errorf("recorded URL %s, tags %v", e.Request.URL, e.Tags)
+	}
+	for _, h := range e.Request.Headers {
+		if (h.Name == "Authorization" || h.Name == "Cookie") && strings.Contains(h.Value, "abc") || h.Value == "sid=1" {
+			t.Errorf("header %s: %s recorded", h.Name, h.Value)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+package harkit
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// PostmanSchema is the schema URL of the Postman Collection Format v2.1.
+const PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// PostmanGrouping selects how [ToPostman] arranges items into folders.
+type PostmanGrouping int
+
+const (
+	PostmanFlat   PostmanGrouping = iota // Every item at the top level.
+	PostmanByHost                        // One folder per request host.
+	PostmanByPage                        // One folder per page, items without a page at the top level.
+)
+
+// PostmanOptions configures [ToPostman].
+type PostmanOptions struct {
+	Name             string          // Collection name. Empty means the log creator name.
+	GroupBy          PostmanGrouping // Folder layout.
+	IncludeResponses bool            // Attach each response as a saved example.
+	Redact           bool            // Replace the values of DefaultRedactedHeaders, cookies included, with DefaultPlaceholder.
+}
+
+type postmanCollection struct {
+	Info     postmanInfo        `json:"info"`
+	Item     []*postmanItem     `json:"item"`
+	Variable []*postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// postmanItem is either a folder, with Item set, or a request.
+type postmanItem struct {
+	Name     string             `json:"name"`
+	Item     []*postmanItem     `json:"item,omitempty"`
+	Request  *postmanRequest    `json:"request,omitempty"`
+	Response []*postmanResponse `json:"response,omitempty"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header postmanHeaderList `json:"header"`
+	Body   *postmanBody      `json:"body,omitempty"`
+	URL    *postmanURL       `json:"url"`
+}
+
+// UnmarshalJSON also accepts the plain URL form of a GET request.
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*r = postmanRequest{Method: http.MethodGet, URL: &postmanURL{Raw: raw}}
+		return nil
+	}
+	type plain postmanRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+type postmanKeyValue struct {
+	Key         string        `json:"key"`
+	Value       postmanString `json:"value"`
+	Type        string        `json:"type,omitempty"`
+	Src         postmanString `json:"src,omitempty"`
+	ContentType string        `json:"contentType,omitempty"`
+	Disabled    bool          `json:"disabled,omitempty"`
+}
+
+// postmanString is a string decoded from any JSON scalar, as Postman
+// writes numbers and booleans for variable values, or from the first
+// element of an array, as it writes formdata sources.
+type postmanString string
+
+func (s *postmanString) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if a, ok := v.([]any); ok {
+		if len(a) == 0 {
+			return nil
+		}
+		v = a[0]
+	}
+	switch v := v.(type) {
+	case nil:
+	case string:
+		*s = postmanString(v)
+	default:
+		*s = postmanString(fmt.Sprint(v))
+	}
+	return nil
+}
+
+// postmanHeaderList also decodes the "Name: value" lines form of headers.
+type postmanHeaderList []*postmanKeyValue
+
+func (l *postmanHeaderList) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) != nil {
+		return json.Unmarshal(data, (*[]*postmanKeyValue)(l))
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			*l = append(*l, &postmanKeyValue{Key: strings.TrimSpace(name), Value: postmanString(strings.TrimSpace(value))})
+		}
+	}
+	return nil
+}
+
+type postmanBody struct {
+	Mode       string             `json:"mode"`
+	Raw        string             `json:"raw,omitempty"`
+	URLEncoded []*postmanKeyValue `json:"urlencoded,omitempty"`
+	FormData   []*postmanKeyValue `json:"formdata,omitempty"`
+	Options    *struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string             `json:"raw"`
+	Protocol string             `json:"protocol,omitempty"`
+	Host     []string           `json:"host,omitempty"`
+	Port     string             `json:"port,omitempty"`
+	Path     []string           `json:"path,omitempty"`
+	Query    []*postmanKeyValue `json:"query,omitempty"`
+}
+
+// UnmarshalJSON also accepts the plain string form of a URL.
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if json.Unmarshal(data, &u.Raw) == nil {
+		return nil
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+type postmanResponse struct {
+	Name            string            `json:"name"`
+	OriginalRequest *postmanRequest   `json:"originalRequest,omitempty"`
+	Status          string            `json:"status"`
+	Code            int64             `json:"code"`
+	Header          postmanHeaderList `json:"header"`
+	Body            string            `json:"body"`
+}
+
+// ToPostman converts log into a Postman Collection Format v2.1 document,
+// one request item per entry in log order. Binary request bodies, which
+// Postman cannot hold, are left out.
+func ToPostman(log *harfile.Log, opts PostmanOptions) ([]byte, error) {
+	c := &postmanCollection{
+		Info: postmanInfo{Name: opts.Name, Schema: PostmanSchema},
+		Item: []*postmanItem{},
+	}
+	if c.Info.Name == "" && log.Creator != nil {
+		c.Info.Name = log.Creator.Name
+	}
+	if c.Info.Name == "" {
+		c.Info.Name = "HAR export"
+	}
+
+	folders := make(map[string]*postmanItem)
+	pageTitles := make(map[string]string)
+	for _, p := range log.Pages {
+		if p != nil {
+			pageTitles[p.ID] = cmp.Or(p.Title, p.ID)
+		}
+	}
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		item := postmanEntry(e, opts)
+
+		var folder string
+		switch opts.GroupBy {
+		case PostmanByHost:
+			if u, err := url.Parse(e.Request.URL); err == nil {
+				folder = u.Host
+			}
+		case PostmanByPage:
+			folder = pageTitles[e.Pageref]
+		}
+		if folder == "" {
+			c.Item = append(c.Item, item)
+			continue
+		}
+		f := folders[folder]
+		if f == nil {
+			f = &postmanItem{Name: folder}
+			folders[folder] = f
+			c.Item = append(c.Item, f)
+		}
+		f.Item = append(f.Item, item)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func postmanEntry(e *harfile.Entry, opts PostmanOptions) *postmanItem {
+	req := postmanRequestFrom(e.Request, opts.Redact)
+	item := &postmanItem{Name: e.Request.Method + " " + req.URL.Raw, Request: req}
+	if u, err := url.Parse(e.Request.URL); err == nil {
+		item.Name = e.Request.Method + " " + cmp.Or(u.Path, "/")
+	}
+	if opts.IncludeResponses && e.Response != nil && e.Response.Status != 0 {
+		resp := &postmanResponse{
+			Name:            item.Name,
+			OriginalRequest: req,
+			Status:          cmp.Or(e.Response.StatusText, http.StatusText(int(e.Response.Status))),
+			Code:            e.Response.Status,
+			Header:          postmanHeaders(e.Response.Headers, opts.Redact),
+		}
+		if e.Response.Content != nil {
+			var contentEncoding string
+			for _, h := range e.Response.Headers {
+				if h != nil && strings.EqualFold(h.Name, "Content-Encoding") {
+					contentEncoding = h.Value
+				}
+			}
+			if body, err := e.Response.Content.DecodeBody(contentEncoding); err == nil && utf8.Valid(body) {
+				resp.Body = string(body)
+			}
+		}
+		item.Response = append(item.Response, resp)
+	}
+	return item
+}
+
+func postmanRequestFrom(r *harfile.Request, redact bool) *postmanRequest {
+	req := &postmanRequest{
+		Method: r.Method,
+		Header: postmanHeaders(r.Headers, redact),
+		URL:    &postmanURL{Raw: r.URL},
+	}
+	if u, err := url.Parse(r.URL); err == nil {
+		req.URL.Protocol = u.Scheme
+		if host := u.Hostname(); host != "" {
+			req.URL.Host = strings.Split(host, ".")
+		}
+		req.URL.Port = u.Port()
+		if p := strings.TrimPrefix(u.EscapedPath(), "/"); p != "" {
+			req.URL.Path = strings.Split(p, "/")
+		}
+	}
+	for _, q := range r.QueryString {
+		if q != nil {
+			req.URL.Query = append(req.URL.Query, &postmanKeyValue{Key: q.Name, Value: postmanString(q.Value)})
+		}
+	}
+	if r.PostData != nil {
+		req.Body = postmanBodyFrom(r.PostData)
+	}
+	return req
+}
+
+func postmanHeaders(headers []*harfile.NameValuePair, redact bool) postmanHeaderList {
+	out := postmanHeaderList{}
+	for _, h := range headers {
+		if h == nil || strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		kv := &postmanKeyValue{Key: h.Name, Value: postmanString(h.Value)}
+		if redact && slices.ContainsFunc(DefaultRedactedHeaders, func(name string) bool {
+			return strings.EqualFold(name, h.Name)
+		}) {
+			kv.Value = DefaultPlaceholder
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func postmanBodyFrom(pd *harfile.PostData) *postmanBody {
+	mediaType, _, _ := strings.Cut(pd.MimeType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	switch {
+	case len(pd.Params) > 0 && mediaType == "multipart/form-data":
+		b := &postmanBody{Mode: "formdata"}
+		for _, p := range pd.Params {
+			if p == nil {
+				continue
+			}
+			kv := &postmanKeyValue{Key: p.Name, Type: "text", Value: postmanString(p.Value)}
+			if p.FileName != "" {
+				kv.Type, kv.Value, kv.Src, kv.ContentType = "file", "", postmanString(p.FileName), p.ContentType
+			}
+			b.FormData = append(b.FormData, kv)
+		}
+		return b
+	case len(pd.Params) > 0:
+		b := &postmanBody{Mode: "urlencoded"}
+		for _, p := range pd.Params {
+			if p != nil {
+				b.URLEncoded = append(b.URLEncoded, &postmanKeyValue{Key: p.Name, Value: postmanString(p.Value)})
+			}
+		}
+		return b
+	}
+	data, err := pd.DecodedText()
+	if err != nil || len(data) == 0 || !utf8.Valid(data) {
+		return nil
+	}
+	return &postmanBody{Mode: "raw", Raw: string(data)}
+}
+
+// ErrNotPostman is returned by [FromPostman] for documents that are not
+// Postman collections.
+var ErrNotPostman = errors.New("harkit: not a Postman collection")
+
+var postmanVariable = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+
+// FromPostman converts a Postman Collection Format v2.1 document into a HAR,
+// one entry per request item in document order, walking folders
+// recursively. Folders become pages.
+//
+// {{variables}} are replaced by vars, then by the collection variables.
+// Unresolved ones are left verbatim and reported as warnings, with paths
+// into the collection such as "item[0].item[2].request.url". Bodies in a
+// mode other than raw, urlencoded and formdata, such as graphql or file,
+// are dropped with a warning.
+//
+// The first saved example response of an item becomes the entry response.
+// Items without one get a stub response with status 0, so the HAR still
+// validates. Timings are zero and entries are dated at conversion time.
+func FromPostman(data []byte, vars map[string]string) (*harfile.HAR, []harfile.Warning, error) {
+	var c postmanCollection
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, nil, fmt.Errorf("harkit: decode Postman collection: %w", err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "collection") {
+		return nil, nil, ErrNotPostman
+	}
+
+	imp := &postmanImporter{
+		vars:  make(map[string]string),
+		start: time.Now(),
+		log:   NewLog(),
+	}
+	for _, v := range c.Variable {
+		if v != nil && !v.Disabled {
+			imp.vars[v.Key] = string(v.Value)
+		}
+	}
+	for k, v := range vars {
+		imp.vars[k] = v
+	}
+	imp.items("item", c.Item, "")
+	return &harfile.HAR{Log: imp.log}, imp.warnings, nil
+}
+
+type postmanImporter struct {
+	vars     map[string]string
+	start    time.Time
+	log      *harfile.Log
+	warnings []harfile.Warning
+}
+
+func (imp *postmanImporter) items(path string, items []*postmanItem, pageref string) {
+	for i, it := range items {
+		if it == nil {
+			continue
+		}
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if it.Request == nil {
+			page := imp.log.AddPage("", it.Name, imp.start)
+			imp.items(itemPath+".item", it.Item, page.ID)
+			continue
+		}
+		e := imp.entry(itemPath, it)
+		e.Pageref = pageref
+		imp.log.Entries = append(imp.log.Entries, e)
+	}
+}
+
+// expand substitutes variables in s, warning at path about unresolved ones.
+func (imp *postmanImporter) expand(path, s string) string {
+	return postmanVariable.ReplaceAllStringFunc(s, func(m string) string {
+		name := postmanVariable.FindStringSubmatch(m)[1]
+		if v, ok := imp.vars[name]; ok {
+			return v
+		}
+		imp.warnings = append(imp.warnings, harfile.Warning{Path: path, Message: "unresolved variable " + m + " left verbatim"})
+		return m
+	})
+}
+
+func (imp *postmanImporter) entry(path string, it *postmanItem) *harfile.Entry {
+	req := imp.request(path+".request", it.Request)
+	resp := &harfile.Response{
+		Cookies:     []*harfile.Cookie{},
+		Headers:     []*harfile.NameValuePair{},
+		Content:     &harfile.Content{MimeType: "x-unknown"},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if len(it.Response) > 0 && it.Response[0] != nil {
+		resp = imp.response(fmt.Sprintf("%s.response[0]", path), it.Response[0])
+	}
+	return &harfile.Entry{
+		StartedDateTime: imp.start,
+		Request:         req,
+		Response:        resp,
+		Cache:           &harfile.Cache{},
+		Timings:         &harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1},
+	}
+}
+
+func (imp *postmanImporter) request(path string, pr *postmanRequest) *harfile.Request {
+	r := &harfile.Request{
+		Method:      strings.ToUpper(cmp.Or(pr.Method, http.MethodGet)),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []*harfile.Cookie{},
+		Headers:     imp.headers(path+".header", pr.Header),
+		QueryString: []*harfile.NameValuePair{},
+	}
+	if pr.URL != nil {
+		r.URL = imp.url(path+".url", pr.URL)
+	}
+	if err := r.ParseQueryString(); err != nil {
+		imp.warnings = append(imp.warnings, harfile.Warning{Path: path + ".url", Message: err.Error()})
+	}
+	for _, h := range r.Headers {
+		if strings.EqualFold(h.Name, "Cookie") {
+			r.Cookies = append(r.Cookies, harfile.CookiesFromRequest(http.Header{"Cookie": {h.Value}})...)
+		}
+	}
+	if pr.Body != nil {
+		r.PostData = imp.postData(path+".body", pr.Body, headerValue(r.Headers, "Content-Type"))
+	}
+	r.ComputeSizes()
+	return r
+}
+
+func (imp *postmanImporter) url(path string, u *postmanURL) string {
+	raw := u.Raw
+	if raw == "" {
+		// Rebuild the URL from its parts, as collections written by hand
+		// may leave raw out.
+		raw = strings.Join(u.Host, ".")
+		if u.Protocol != "" {
+			raw = u.Protocol + "://" + raw
+		}
+		if u.Port != "" {
+			raw += ":" + u.Port
+		}
+		if len(u.Path) > 0 {
+			raw += "/" + strings.Join(u.Path, "/")
+		}
+		var query []string
+		for _, q := range u.Query {
+			if q != nil && !q.Disabled {
+				query = append(query, q.Key+"="+string(q.Value))
+			}
+		}
+		if len(query) > 0 {
+			raw += "?" + strings.Join(query, "&")
+		}
+	}
+	return imp.expand(path, raw)
+}
+
+func (imp *postmanImporter) headers(path string, list postmanHeaderList) []*harfile.NameValuePair {
+	headers := []*harfile.NameValuePair{}
+	for _, h := range list {
+		if h != nil && !h.Disabled && h.Key != "" {
+			headers = append(headers, &harfile.NameValuePair{
+				Name:  imp.expand(path, h.Key),
+				Value: imp.expand(path, string(h.Value)),
+			})
+		}
+	}
+	return headers
+}
+
+func (imp *postmanImporter) postData(path string, b *postmanBody, contentType string) *harfile.PostData {
+	switch b.Mode {
+	case "raw":
+		if b.Raw == "" {
+			return nil
+		}
+		if contentType == "" {
+			contentType = "text/plain"
+			if b.Options != nil && b.Options.Raw.Language == "json" {
+				contentType = "application/json"
+			}
+		}
+		return &harfile.PostData{MimeType: contentType, Params: []*harfile.Param{}, Text: imp.expand(path+".raw", b.Raw)}
+	case "urlencoded":
+		var pairs []string
+		for _, p := range b.URLEncoded {
+			if p != nil && !p.Disabled {
+				pairs = append(pairs, url.QueryEscape(imp.expand(path+".urlencoded", p.Key))+"="+url.QueryEscape(imp.expand(path+".urlencoded", string(p.Value))))
+			}
+		}
+		pd, _ := harfile.PostDataFromBody(cmp.Or(contentType, "application/x-www-form-urlencoded"), []byte(strings.Join(pairs, "&")))
+		return pd
+	case "formdata":
+		pd := &harfile.PostData{MimeType: cmp.Or(contentType, "multipart/form-data"), Params: []*harfile.Param{}}
+		for _, p := range b.FormData {
+			if p == nil || p.Disabled {
+				continue
+			}
+			param := &harfile.Param{Name: imp.expand(path+".formdata", p.Key)}
+			if p.Type == "file" {
+				param.FileName, param.ContentType = string(p.Src), p.ContentType
+			} else {
+				param.Value = imp.expand(path+".formdata", string(p.Value))
+			}
+			pd.Params = append(pd.Params, param)
+		}
+		return pd
+	}
+	if b.Mode != "" {
+		imp.warnings = append(imp.warnings, harfile.Warning{Path: path, Message: fmt.Sprintf("unsupported body mode %q dropped", b.Mode)})
+	}
+	return nil
+}
+
+func (imp *postmanImporter) response(path string, pr *postmanResponse) *harfile.Response {
+	resp := &harfile.Response{
+		Status:      pr.Code,
+		StatusText:  cmp.Or(pr.Status, http.StatusText(int(pr.Code))),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []*harfile.Cookie{},
+		Headers:     imp.headers(path+".header", pr.Header),
+		Content:     &harfile.Content{},
+	}
+	for _, h := range resp.Headers {
+		if strings.EqualFold(h.Name, "Set-Cookie") {
+			resp.Cookies = append(resp.Cookies, harfile.CookiesFromResponse(http.Header{"Set-Cookie": {h.Value}})...)
+		}
+	}
+	// Postman saves decoded bodies, so the encoding headers no longer apply.
+	resp.Headers = slices.DeleteFunc(resp.Headers, func(h *harfile.NameValuePair) bool {
+		return strings.EqualFold(h.Name, "Content-Encoding") || strings.EqualFold(h.Name, "Content-Length")
+	})
+	resp.Content.SetBody([]byte(pr.Body), headerValue(resp.Headers, "Content-Type"))
+	resp.ComputeSizes()
+	return resp
+}
+
+func headerValue(headers []*harfile.NameValuePair, name string) string {
+	for _, h := range headers {
+		if h != nil && strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
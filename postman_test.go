@@ -0,0 +1,353 @@
+package harkit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// exportEntry returns a request sent with the body of type contentType
+// and the headers given as name, value pairs, answered with status. A
+// Content-Type header is added for the body unless one is given, and the
+// query string is parsed from url.
+func exportEntry(method, url string, start time.Time, contentType string, body []byte, status int64, headers ...string) *harfile.Entry {
+	req := &harfile.Request{Method: method, URL: url, HTTPVersion: "HTTP/1.1"}
+	hasType := false
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Headers = append(req.Headers, &harfile.NameValuePair{Name: headers[i], Value: headers[i+1]})
+		hasType = hasType || strings.EqualFold(headers[i], "Content-Type")
+	}
+	if contentType != "" {
+		pd, err := harfile.PostDataFromBody(contentType, body)
+		if err != nil {
+			panic(err)
+		}
+		req.PostData = pd
+		if !hasType {
+			req.Headers = append(req.Headers, &harfile.NameValuePair{Name: "Content-Type", Value: contentType})
+		}
+	}
+	if err := req.ParseQueryString(); err != nil {
+		panic(err)
+	}
+	return &harfile.Entry{
+		StartedDateTime: start,
+		Request:         req,
+		Response:        &harfile.Response{Status: status, HTTPVersion: "HTTP/1.1", Content: &harfile.Content{}},
+	}
+}
+
+// respond sets the response body of e to data of type mimeType, with its
+// Content-Type header after the headers given as name, value pairs.
+func respond(e *harfile.Entry, mimeType string, data []byte, headers ...string) *harfile.Entry {
+	for i := 0; i+1 < len(headers); i += 2 {
+		e.Response.Headers = append(e.Response.Headers, &harfile.NameValuePair{Name: headers[i], Value: headers[i+1]})
+	}
+	e.Response.Content.SetBody(data, mimeType)
+	e.Response.Headers = append(e.Response.Headers, &harfile.NameValuePair{Name: "Content-Type", Value: mimeType})
+	return e
+}
+
+func postmanLog() *harfile.Log {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	multipart := "--XyZ\r\nContent-Disposition: form-data; name=\"title\"\r\n\r\nhello\r\n" +
+		"--XyZ\r\nContent-Disposition: form-data; name=\"file\"; filename=\"a.txt\"\r\nContent-Type: text/plain\r\n\r\nabc\r\n--XyZ--\r\n"
+	l := &harfile.Log{Version: "1.2", Creator: &harfile.Creator{Name: "recorder", Version: "1"}}
+	page := l.AddPage("page_1", "Login", start)
+	login := respond(exportEntry("POST", "https://api.example.com:8443/v1/login?next=%2Fhome&lang=fr", start, "application/json", []byte(`{"user":"ada"}`), 200,
+		"Authorization", "Bearer secret", "Cookie", "session=abc"),
+		"application/json", []byte(`{"ok":true}`), "Set-Cookie", "session=def; Path=/")
+	login.AttachTo(page)
+	search := exportEntry("POST", "https://example.com/search", start, "application/x-www-form-urlencoded", []byte("q=har+files&page=2"), 302)
+	search.AttachTo(page)
+	home := exportEntry("GET", "https://example.com/", start, "", nil, 200, ":authority", "example.com")
+	home.Request.HTTPVersion, home.Response.HTTPVersion = "HTTP/2", "HTTP/2"
+	l.Entries = []*harfile.Entry{
+		login,
+		search,
+		respond(exportEntry("POST", "https://example.com/upload", start, "multipart/form-data; boundary=XyZ", []byte(multipart), 201),
+			"image/png", []byte{0x89, 'P', 'N', 'G', 0xff}),
+		home,
+		nil,
+	}
+	return l
+}
+
+func TestToPostman(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		opts   PostmanOptions
+	}{
+		{"flat", "postman_flat.json", PostmanOptions{IncludeResponses: true}},
+		{"by host", "postman_host.json", PostmanOptions{Name: "API", GroupBy: PostmanByHost, Redact: true}},
+		{"by page", "postman_page.json", PostmanOptions{GroupBy: PostmanByPage}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ToPostman(postmanLog(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, data)
+			var doc any
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+			if err := checkPostmanSchema(doc); err != nil {
+				t.Error(err)
+			}
+			if tt.opts.Redact && (strings.Contains(string(data), "secret") || strings.Contains(string(data), "session=abc")) {
+				t.Error("credentials left in a redacted collection")
+			}
+		})
+	}
+}
+
+// TestToPostmanRoundTrip imports the exported collection back and checks
+// the requests survive.
+func TestToPostmanRoundTrip(t *testing.T) {
+	log := postmanLog()
+	data, err := ToPostman(log, PostmanOptions{GroupBy: PostmanByPage, IncludeResponses: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, warnings, err := FromPostman(data, nil)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("FromPostman: %v, %v", err, warnings)
+	}
+	var got, want []string
+	for _, e := range h.Log.Entries {
+		got = append(got, e.Request.Method+" "+e.Request.URL+fmt.Sprintf(" %d", e.Response.Status))
+	}
+	for _, e := range log.Entries {
+		if e != nil {
+			want = append(want, e.Request.Method+" "+e.Request.URL+fmt.Sprintf(" %d", e.Response.Status))
+		}
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("round trip =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFromPostman(t *testing.T) {
+	collection := `{
+		"info": {"name": "api", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+		"variable": [
+			{"key": "base", "value": "https://example.com"},
+			{"key": "token", "value": "from-collection"},
+			{"key": "off", "value": "x", "disabled": true},
+			{"key": "port", "value": 8443}
+		],
+		"item": [
+			{"name": "users", "item": [
+				{"name": "list", "request": {
+					"method": "get",
+					"header": "Authorization: Bearer {{token}}\nX-Trace: {{ trace }}",
+					"url": "{{base}}/users?limit=10"
+				}},
+				{"name": "create", "request": {
+					"method": "POST",
+					"header": [{"key": "X-Off", "value": "1", "disabled": true}],
+					"body": {"mode": "raw", "raw": "{\"name\":\"{{name}}\"}", "options": {"raw": {"language": "json"}}},
+					"url": {"protocol": "https", "host": ["api", "example", "com"], "port": "{{port}}", "path": ["users"]}
+				}, "response": [{"code": 201, "header": [{"key": "Content-Type", "value": "application/json"}, {"key": "Content-Encoding", "value": "gzip"}], "body": "{\"id\":7}"}]}
+			]},
+			{"name": "query", "request": {"method": "POST", "url": "{{base}}/graphql", "body": {"mode": "graphql", "graphql": {"query": "{ me }"}}}},
+			{"name": "upload", "request": {"method": "PUT", "url": "{{base}}/{{off}}", "body": {"mode": "file", "file": {"src": "a.bin"}}}}
+		]
+	}`
+	h, warnings, err := FromPostman([]byte(collection), map[string]string{"token": "abc", "name": "ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.Path+": "+w.Message)
+	}
+	want := []string{
+		`item[0].item[0].request.header: unresolved variable {{ trace }} left verbatim`,
+		`item[1].request.body: unsupported body mode "graphql" dropped`,
+		`item[2].request.url: unresolved variable {{off}} left verbatim`,
+		`item[2].request.body: unsupported body mode "file" dropped`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	l := h.Log
+	if len(l.Entries) != 4 || len(l.Pages) != 1 || l.Pages[0].Title != "users" {
+		t.Fatalf("%d entries, pages %v", len(l.Entries), l.Pages)
+	}
+	list, create, query, upload := l.Entries[0], l.Entries[1], l.Entries[2], l.Entries[3]
+	if list.Pageref != l.Pages[0].ID || create.Pageref != l.Pages[0].ID || query.Pageref != "" {
+		t.Errorf("pagerefs %q %q %q", list.Pageref, create.Pageref, query.Pageref)
+	}
+	if r := list.Request; r.Method != "GET" || r.URL != "https://example.com/users?limit=10" ||
+		len(r.QueryString) != 1 || r.QueryString[0].Value != "10" ||
+		len(r.Headers) != 2 || r.Headers[0].Value != "Bearer abc" || r.Headers[1].Value != "{{ trace }}" {
+		t.Errorf("list request = %s %s, query %v, headers %v", r.Method, r.URL, r.QueryString, r.Headers)
+	}
+	if list.Response.Status != 0 || list.Response.Content.MimeType != "x-unknown" {
+		t.Errorf("stub response = %+v", list.Response)
+	}
+	r := create.Request
+	if r.URL != "https://api.example.com:8443/users" || len(r.Headers) != 0 {
+		t.Errorf("create request %s, headers %v", r.URL, r.Headers)
+	}
+	if r.PostData == nil || r.PostData.MimeType != "application/json" || r.PostData.Text != `{"name":"ada"}` {
+		t.Errorf("create body = %+v", r.PostData)
+	}
+	if resp := create.Response; resp.Status != 201 || resp.StatusText != "Created" || len(resp.Headers) != 1 || resp.Content.Text != `{"id":7}` {
+		t.Errorf("create response = %d %s, headers %v, body %q", resp.Status, resp.StatusText, resp.Headers, resp.Content.Text)
+	}
+	if query.Request.PostData != nil || upload.Request.URL != "https://example.com/{{off}}" {
+		t.Errorf("graphql body %+v, upload url %s", query.Request.PostData, upload.Request.URL)
+	}
+}
+
+func TestFromPostmanErrors(t *testing.T) {
+	if _, _, err := FromPostman([]byte(`{"info": {"schema": "https://example.com/openapi.json"}}`), nil); !errors.Is(err, ErrNotPostman) {
+		t.Errorf("foreign schema: %v", err)
+	}
+	if _, _, err := FromPostman([]byte(`[`), nil); err == nil || errors.Is(err, ErrNotPostman) {
+		t.Errorf("bad JSON: %v", err)
+	}
+}
+
+// checkPostmanSchema checks doc against the parts of the v2.1 collection
+// schema that Postman enforces on import: required members and their types,
+// the item/folder structure, and the allowed body modes.
+func checkPostmanSchema(doc any) error {
+	c, ok := doc.(map[string]any)
+	if !ok {
+		return fmt.Errorf("collection is not an object")
+	}
+	info, _ := c["info"].(map[string]any)
+	if name, _ := info["name"].(string); name == "" {
+		return fmt.Errorf("info.name missing")
+	}
+	if info["schema"] != PostmanSchema {
+		return fmt.Errorf("info.schema = %v", info["schema"])
+	}
+	return checkPostmanItems("item", c["item"])
+}
+
+func checkPostmanItems(path string, v any) error {
+	items, ok := v.([]any)
+	if !ok {
+		return fmt.Errorf("%s is not an array", path)
+	}
+	for i, v := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		item, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s is not an object", itemPath)
+		}
+		if _, ok := item["name"].(string); !ok {
+			return fmt.Errorf("%s.name missing", itemPath)
+		}
+		if sub, ok := item["item"]; ok {
+			if _, ok := item["request"]; ok {
+				return fmt.Errorf("%s is both a folder and a request", itemPath)
+			}
+			if err := checkPostmanItems(itemPath+".item", sub); err != nil {
+				return err
+			}
+			continue
+		}
+		req, ok := item["request"].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s.request missing", itemPath)
+		}
+		if err := checkPostmanRequest(itemPath+".request", req); err != nil {
+			return err
+		}
+		responses, _ := item["response"].([]any)
+		for j, v := range responses {
+			respPath := fmt.Sprintf("%s.response[%d]", itemPath, j)
+			resp, ok := v.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s is not an object", respPath)
+			}
+			if code, ok := resp["code"].(float64); !ok || code != float64(int(code)) {
+				return fmt.Errorf("%s.code is not an integer", respPath)
+			}
+			if err := checkPostmanHeaders(respPath+".header", resp["header"]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkPostmanRequest(path string, req map[string]any) error {
+	if m, _ := req["method"].(string); m == "" || strings.ToUpper(m) != m {
+		return fmt.Errorf("%s.method = %v", path, req["method"])
+	}
+	u, ok := req["url"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s.url is not an object", path)
+	}
+	if raw, _ := u["raw"].(string); raw == "" {
+		return fmt.Errorf("%s.url.raw missing", path)
+	}
+	for _, member := range []string{"host", "path"} {
+		if v, ok := u[member]; ok {
+			parts, ok := v.([]any)
+			if !ok {
+				return fmt.Errorf("%s.url.%s is not an array", path, member)
+			}
+			for _, p := range parts {
+				if _, ok := p.(string); !ok {
+					return fmt.Errorf("%s.url.%s holds %v", path, member, p)
+				}
+			}
+		}
+	}
+	if err := checkPostmanHeaders(path+".header", req["header"]); err != nil {
+		return err
+	}
+	if v, ok := req["body"]; ok {
+		body, _ := v.(map[string]any)
+		switch body["mode"] {
+		case "raw":
+			if _, ok := body["raw"].(string); !ok {
+				return fmt.Errorf("%s.body.raw missing", path)
+			}
+		case "urlencoded", "formdata":
+			mode := body["mode"].(string)
+			if _, ok := body[mode].([]any); !ok {
+				return fmt.Errorf("%s.body.%s missing", path, mode)
+			}
+		default:
+			return fmt.Errorf("%s.body.mode = %v", path, body["mode"])
+		}
+	}
+	return nil
+}
+
+func checkPostmanHeaders(path string, v any) error {
+	headers, ok := v.([]any)
+	if !ok {
+		return fmt.Errorf("%s is not an array", path)
+	}
+	for i, h := range headers {
+		kv, _ := h.(map[string]any)
+		if _, ok := kv["key"].(string); !ok {
+			return fmt.Errorf("%s[%d].key missing", path, i)
+		}
+		if _, ok := kv["value"].(string); !ok {
+			return fmt.Errorf("%s[%d].value missing", path, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package harkit
+
+// Stages reported by this package to a [harfile.Progress].
+const (
+	StageReplay  = "replay"  // Requests completed by Replayer.Replay.
+	StageExtract = "extract" // Entries examined by ExtractBodies.
+	StageExport  = "export"  // Entries written by ExportHTML and ExportJSONL.
+)
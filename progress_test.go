@@ -0,0 +1,86 @@
+package harkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// stageSteps records the steps of a stage it receives, in order.
+type stageSteps struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (p *stageSteps) Step(stage string, done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, fmt.Sprintf("%s %d/%d", stage, done, total))
+}
+
+func (p *stageSteps) String() string {
+	return strings.Join(p.steps, ", ")
+}
+
+func progressLog() *harfile.Log {
+	return &harfile.Log{Entries: []*harfile.Entry{
+		stubEntry("GET", "https://example.com/a.txt", 200, "text/plain", []byte("a")),
+		nil,
+		stubEntry("GET", "https://example.com/b.txt", 200, "text/plain", []byte("b")),
+		stubEntry("POST", "https://example.com/c", 204, "", nil),
+	}}
+}
+
+func TestProgress(t *testing.T) {
+	const steps = "%[1]s 1/4, %[1]s 2/4, %[1]s 3/4, %[1]s 4/4"
+	tests := []struct {
+		stage string
+		run   func(p harfile.Progress) error
+		want  string
+	}{
+		{StageExtract, func(p harfile.Progress) error {
+			_, err := ExtractBodies(progressLog(), t.TempDir(), ExtractOptions{Progress: p})
+			return err
+		}, fmt.Sprintf(steps, StageExtract)},
+		{StageExport, func(p harfile.Progress) error {
+			return ExportJSONL(io.Discard, progressLog(), JSONLOptions{Progress: p})
+		}, fmt.Sprintf(steps, StageExport)},
+		{StageExport + " html", func(p harfile.Progress) error {
+			return ExportHTML(io.Discard, &harfile.HAR{Log: progressLog()}, HTMLOptions{Progress: p})
+		}, "export 1/3, export 2/3, export 3/3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stage, func(t *testing.T) {
+			var p stageSteps
+			if err := tt.run(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.String() != tt.want {
+				t.Errorf("steps = %q, want %q", p.String(), tt.want)
+			}
+			// Without a Progress, nothing breaks.
+			if err := tt.run(nil); err != nil {
+				t.Errorf("without progress: %v", err)
+			}
+		})
+	}
+}
+
+func TestReplayProgress(t *testing.T) {
+	var p stageSteps
+	opts := ReplayOptions{Concurrency: 3, Progress: &p}
+	if _, err := (&Replayer{Transport: stubTransport{}}).Replay(context.Background(), progressLog(), opts); err != nil {
+		t.Fatal(err)
+	}
+	// Requests complete in any order, each adding one to the count.
+	slices.Sort(p.steps)
+	if got := p.String(); got != "replay 1/3, replay 2/3, replay 3/3" {
+		t.Errorf("steps = %q", got)
+	}
+}
@@ -0,0 +1,23 @@
+package harkit
+
+import "net/http/httputil"
+
+// DefaultProxyMaxBodySize is the body capture limit of [WrapReverseProxy]
+// unless [WithMaxBodySize] says otherwise.
+const DefaultProxyMaxBodySize = 1 << 20
+
+// WrapReverseProxy records the upstream round trips of p: its Transport is
+// replaced by a [Transport] wrapping it, which is returned to read the log.
+// Entries describe the requests as rewritten by the proxy, with the
+// upstream ServerIPAddress and timings around the upstream round trip.
+//
+// Responses are passed to the client as they stream, and only the first
+// DefaultProxyMaxBodySize bytes of each body are kept by default, so
+// server-sent events and long downloads are not buffered; truncated bodies
+// are marked in their comment. An entry is added once the proxy is done
+// with the response, at the end of the body or when the client goes away.
+func WrapReverseProxy(p *httputil.ReverseProxy, opts ...Option) *Transport {
+	t := NewTransport(p.Transport, append([]Option{WithMaxBodySize(DefaultProxyMaxBodySize)}, opts...)...)
+	p.Transport = t
+	return t
+}
@@ -0,0 +1,158 @@
+package harkit
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWrapReverseProxyStreaming checks that a download larger than the
+// capture limit streams to the client unbuffered and in full, while the
+// entry keeps a truncated copy.
+func TestWrapReverseProxyStreaming(t *testing.T) {
+	release := make(chan struct{})
+	chunk := bytes.Repeat([]byte("x"), 1000)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(chunk)
+		w.(http.Flusher).Flush()
+		<-release // The client must see the first chunk before the rest is written.
+		for range 9 {
+			w.Write(chunk)
+		}
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	rec := WrapReverseProxy(proxy, WithMaxBodySize(2500))
+	front := httptest.NewServer(proxy)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	first := make([]byte, len(chunk))
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadFull(resp.Body, first)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("first chunk held back by the proxy")
+	}
+	if n := len(rec.HAR().Log.Entries); n != 0 {
+		t.Errorf("%d entries before the end of the body", n)
+	}
+	close(release)
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(first) + len(rest); got != 10*len(chunk) {
+		t.Errorf("client got %d bytes, want %d", got, 10*len(chunk))
+	}
+	resp.Body.Close()
+
+	var entries int
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if entries = len(rec.HAR().Log.Entries); entries > 0 {
+			break
+		}
+	}
+	if entries != 1 {
+		t.Fatalf("%d entries, want 1", entries)
+	}
+	c := rec.HAR().Log.Entries[0].Response.Content
+	if len(c.Text) != 2500 || c.Size != 10000 || !c.Truncated {
+		t.Errorf("content: %d bytes of %d, truncated %v", len(c.Text), c.Size, c.Truncated)
+	}
+	if c.Comment != "body truncated to 2500 of 10000 bytes" {
+		t.Errorf("comment = %q", c.Comment)
+	}
+}
+
+func TestWrapReverseProxyDefaultLimit(t *testing.T) {
+	body := strings.Repeat("y", DefaultProxyMaxBodySize+10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	rec := WrapReverseProxy(proxy)
+	front := httptest.NewServer(proxy)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(got) != len(body) {
+		t.Errorf("client got %d bytes, want %d", len(got), len(body))
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(rec.HAR().Log.Entries) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c := rec.HAR().Log.Entries[0].Response.Content; len(c.Text) != DefaultProxyMaxBodySize || !c.Truncated || !strings.HasPrefix(c.Comment, "body truncated") {
+		t.Errorf("content: %d bytes, comment %q", len(c.Text), c.Comment)
+	}
+}
+
+// TestWrapReverseProxyEventStream checks that events are recorded while the
+// stream is still open.
+func TestWrapReverseProxyEventStream(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "data: two\n\n")
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	target, _ := url.Parse(upstream.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	rec := WrapReverseProxy(proxy)
+	front := httptest.NewServer(proxy)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line := make([]byte, len("data: one\n\n"))
+	if _, err := io.ReadFull(resp.Body, line); err != nil {
+		t.Fatal(err)
+	}
+	var messages int
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if entries := rec.HAR().Log.Entries; len(entries) == 1 {
+			if messages = len(entries[0].EventStreamMessages); messages > 0 {
+				break
+			}
+		}
+	}
+	if messages != 1 {
+		t.Fatalf("%d events recorded while the stream is open, want 1", messages)
+	}
+}
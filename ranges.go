@@ -0,0 +1,230 @@
+package harkit
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// ByteRange is an inclusive range of byte positions, as in a Content-Range
+// header.
+type ByteRange struct {
+	First, Last int64
+}
+
+// Len returns the number of bytes of r.
+func (r ByteRange) Len() int64 {
+	return r.Last - r.First + 1
+}
+
+func (r ByteRange) String() string {
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// RangeReport describes how the 206 Partial Content responses of a URL
+// cover the resource, see [Ranges].
+type RangeReport struct {
+	URL       string
+	Entries   []int       // Indexes of the 206 entries in Log.Entries, in order.
+	Size      int64       // Complete length of the resource, -1 when no Content-Range gives it.
+	Covered   []ByteRange // Ranges fetched at least once, merged and sorted.
+	Gaps      []ByteRange // Ranges never fetched, up to Size, or up to the last covered byte when Size is unknown.
+	Overlaps  []ByteRange // Ranges fetched more than once.
+	Fetched   int64       // Bytes of every range fetched.
+	Distinct  int64       // Bytes fetched at least once.
+	Refetched int64       // Fetched - Distinct: bytes fetched again.
+	Problems  []string    // Ranges that could not be read, such as malformed Content-Range headers.
+}
+
+// Complete reports whether every byte of the resource was fetched.
+func (r *RangeReport) Complete() bool {
+	return r.Size >= 0 && len(r.Gaps) == 0 && r.Distinct == r.Size
+}
+
+// Ranges groups the 206 Partial Content responses of log by request URL,
+// each group being a logical resource fetched in pieces, e.g. by a video
+// player or a download manager, and reports which bytes were fetched,
+// missing or fetched again. Ranges come from the Content-Range header or,
+// for multipart/byteranges responses, from the headers of their parts,
+// which need the recorded body. Unreadable ranges are listed in Problems.
+// Reports are in the order of their first entry.
+func Ranges(log *harfile.Log) []RangeReport {
+	var reports []*RangeReport
+	byURL := make(map[string]*RangeReport)
+	ranges := make(map[*RangeReport][]ByteRange)
+	for i, e := range log.Entries {
+		if e == nil || e.Request == nil || e.Response == nil || e.Response.Status != 206 {
+			continue
+		}
+		rep, ok := byURL[e.Request.URL]
+		if !ok {
+			rep = &RangeReport{URL: e.Request.URL, Size: -1}
+			byURL[e.Request.URL] = rep
+			reports = append(reports, rep)
+		}
+		rep.Entries = append(rep.Entries, i)
+		parts, err := responseRanges(e.Response)
+		if err != nil {
+			rep.Problems = append(rep.Problems, fmt.Sprintf("entries[%d]: %v", i, err))
+		}
+		for _, p := range parts {
+			if p.size >= 0 {
+				if rep.Size >= 0 && rep.Size != p.size {
+					rep.Problems = append(rep.Problems, fmt.Sprintf("entries[%d]: complete length %d, but %d elsewhere", i, p.size, rep.Size))
+				}
+				rep.Size = max(rep.Size, p.size)
+			}
+			ranges[rep] = append(ranges[rep], p.ByteRange)
+		}
+	}
+
+	out := make([]RangeReport, len(reports))
+	for i, rep := range reports {
+		rep.cover(ranges[rep])
+		out[i] = *rep
+	}
+	return out
+}
+
+// cover computes the coverage of rep from the ranges fetched.
+func (rep *RangeReport) cover(ranges []ByteRange) {
+	// Sweep over range boundaries, counting the ranges holding each byte.
+	type boundary struct {
+		at    int64
+		delta int
+	}
+	var bounds []boundary
+	for _, r := range ranges {
+		rep.Fetched += r.Len()
+		bounds = append(bounds, boundary{r.First, 1}, boundary{r.Last + 1, -1})
+	}
+	slices.SortFunc(bounds, func(a, b boundary) int {
+		return cmp.Or(cmp.Compare(a.at, b.at), cmp.Compare(a.delta, b.delta))
+	})
+	add := func(list []ByteRange, first, last int64) []ByteRange {
+		if n := len(list); n > 0 && list[n-1].Last+1 == first {
+			list[n-1].Last = last
+			return list
+		}
+		return append(list, ByteRange{first, last})
+	}
+	depth := 0
+	for k, b := range bounds {
+		depth += b.delta
+		if k+1 == len(bounds) || bounds[k+1].at == b.at {
+			continue
+		}
+		first, last := b.at, bounds[k+1].at-1
+		if depth >= 1 {
+			rep.Covered = add(rep.Covered, first, last)
+			rep.Distinct += last - first + 1
+		}
+		if depth >= 2 {
+			rep.Overlaps = add(rep.Overlaps, first, last)
+		}
+	}
+	rep.Refetched = rep.Fetched - rep.Distinct
+
+	end := rep.Size - 1
+	if rep.Size < 0 && len(rep.Covered) > 0 {
+		end = rep.Covered[len(rep.Covered)-1].Last
+	}
+	next := int64(0)
+	for _, c := range rep.Covered {
+		if c.First > next && next <= end {
+			rep.Gaps = append(rep.Gaps, ByteRange{next, min(c.First-1, end)})
+		}
+		next = c.Last + 1
+	}
+	if next <= end {
+		rep.Gaps = append(rep.Gaps, ByteRange{next, end})
+	}
+}
+
+// contentRange is a range of a partial response and the complete length
+// of the resource, -1 when unknown.
+type contentRange struct {
+	ByteRange
+	size int64
+}
+
+// responseRanges returns the ranges carried by a 206 response.
+func responseRanges(resp *harfile.Response) ([]contentRange, error) {
+	if v := headerValue(resp.Headers, "Content-Range"); v != "" {
+		r, err := parseContentRange(v)
+		if err != nil {
+			return nil, err
+		}
+		return []contentRange{r}, nil
+	}
+	var contentType string
+	if resp.Content != nil {
+		contentType = resp.Content.MimeType
+	}
+	contentType = cmp.Or(headerValue(resp.Headers, "Content-Type"), contentType)
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType != "multipart/byteranges" {
+		return nil, errors.New("no Content-Range header")
+	}
+	if resp.Content == nil || (resp.Content.Text == "" && !resp.Content.Stored()) || resp.Content.Truncated {
+		return nil, errors.New("multipart/byteranges body not recorded")
+	}
+	body, err := resp.Content.DecodedBody()
+	if err != nil {
+		return nil, err
+	}
+	var ranges []contentRange
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return ranges, nil
+		}
+		if err != nil {
+			return ranges, fmt.Errorf("multipart/byteranges body: %w", err)
+		}
+		r, err := parseContentRange(part.Header.Get("Content-Range"))
+		if err != nil {
+			return ranges, err
+		}
+		ranges = append(ranges, r)
+	}
+}
+
+// parseContentRange parses a "bytes first-last/length" Content-Range
+// value, length being "*" when unknown.
+func parseContentRange(v string) (contentRange, error) {
+	bad := fmt.Errorf("malformed Content-Range %q", v)
+	unit, spec, ok := strings.Cut(strings.TrimSpace(v), " ")
+	if !ok || !strings.EqualFold(unit, "bytes") {
+		return contentRange{}, bad
+	}
+	rng, length, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	firstText, lastText, ok2 := strings.Cut(rng, "-")
+	if !ok || !ok2 {
+		return contentRange{}, bad
+	}
+	first, err1 := strconv.ParseInt(firstText, 10, 64)
+	last, err2 := strconv.ParseInt(lastText, 10, 64)
+	if err1 != nil || err2 != nil || first < 0 || last < first {
+		return contentRange{}, bad
+	}
+	r := contentRange{ByteRange{first, last}, -1}
+	if length != "*" {
+		size, err := strconv.ParseInt(length, 10, 64)
+		if err != nil || size <= last {
+			return contentRange{}, bad
+		}
+		r.size = size
+	}
+	return r, nil
+}
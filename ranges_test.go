@@ -0,0 +1,187 @@
+package harkit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// rangeEntry returns a 206 response to url carrying body, the bytes of
+// contentRange, with that Content-Range header unless it is empty.
+func rangeEntry(url, contentRange, body string) *harfile.Entry {
+	e := bodyEntry(url, "video/mp4", body)
+	e.Response.Status = 206
+	e.Response.BodySize = int64(len(body))
+	if contentRange != "" {
+		e.Response.Headers = []*harfile.NameValuePair{{Name: "Content-Range", Value: contentRange}}
+	}
+	return e
+}
+
+func TestRanges(t *testing.T) {
+	const video, audio = "https://example.com/video.mp4", "https://example.com/audio.mp3"
+	multipart := "--B\r\nContent-Type: video/mp4\r\nContent-Range: bytes 0-1/10\r\n\r\nab\r\n" +
+		"--B\r\nContent-Type: video/mp4\r\nContent-Range: bytes 8-9/10\r\n\r\nij\r\n--B--\r\n"
+	parts := bodyEntry("https://example.com/parts", "multipart/byteranges; boundary=B", multipart)
+	parts.Response.Status = 206
+
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		rangeEntry(video, "bytes 0-99/1000", strings.Repeat("v", 100)), // 0
+		rangeEntry(audio, "bytes 0-9/*", strings.Repeat("a", 10)),      // 1
+		nil, // 2
+		rangeEntry(video, "bytes 50-199/1000", strings.Repeat("v", 150)),  // 3
+		bodyEntry(video, "video/mp4", "whole"),                            // 4, not partial
+		rangeEntry(video, "bytes 500-999/1000", strings.Repeat("v", 500)), // 5
+		rangeEntry(audio, "bytes 20-29/*", strings.Repeat("a", 10)),       // 6
+		parts, // 7
+	}}
+	want := []RangeReport{
+		{
+			URL:       video,
+			Entries:   []int{0, 3, 5},
+			Size:      1000,
+			Covered:   []ByteRange{{0, 199}, {500, 999}},
+			Gaps:      []ByteRange{{200, 499}},
+			Overlaps:  []ByteRange{{50, 99}},
+			Fetched:   750,
+			Distinct:  700,
+			Refetched: 50,
+		},
+		{
+			URL:      audio,
+			Entries:  []int{1, 6},
+			Size:     -1,
+			Covered:  []ByteRange{{0, 9}, {20, 29}},
+			Gaps:     []ByteRange{{10, 19}},
+			Fetched:  20,
+			Distinct: 20,
+		},
+		{
+			URL:      "https://example.com/parts",
+			Entries:  []int{7},
+			Size:     10,
+			Covered:  []ByteRange{{0, 1}, {8, 9}},
+			Gaps:     []ByteRange{{2, 7}},
+			Fetched:  4,
+			Distinct: 4,
+		},
+	}
+	if got := Ranges(log); !reflect.DeepEqual(got, want) {
+		t.Errorf("Ranges =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := Ranges(&harfile.Log{}); got == nil || len(got) != 0 {
+		t.Errorf("empty log = %#v", got)
+	}
+}
+
+func TestRangesComplete(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		rangeEntry("https://example.com/a", "bytes 5-9/10", "fghij"),
+		rangeEntry("https://example.com/a", "bytes 0-4/10", "abcde"),
+	}}
+	reps := Ranges(log)
+	if len(reps) != 1 || !reps[0].Complete() || reps[0].Gaps != nil || reps[0].Refetched != 0 {
+		t.Errorf("Ranges = %+v", reps)
+	}
+
+	// Without a complete length, the resource may go on.
+	log.Entries[0].Response.Headers[0].Value = "bytes 5-9/*"
+	log.Entries[1].Response.Headers[0].Value = "bytes 0-4/*"
+	if reps := Ranges(log); reps[0].Size != -1 || reps[0].Complete() {
+		t.Errorf("Ranges = %+v", reps)
+	}
+}
+
+func TestRangesProblems(t *testing.T) {
+	truncated := bodyEntry("https://example.com/a", "multipart/byteranges; boundary=B", "--B\r\n")
+	truncated.Response.Status = 206
+	truncated.Response.Content.Truncated = true
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		rangeEntry("https://example.com/a", "bytes 0-9/100", strings.Repeat("x", 10)),
+		rangeEntry("https://example.com/a", "bytes 10-19/200", strings.Repeat("x", 10)),
+		rangeEntry("https://example.com/a", "bytes 9-5/100", "x"),
+		rangeEntry("https://example.com/a", "", "x"),
+		truncated,
+	}}
+	reps := Ranges(log)
+	if len(reps) != 1 {
+		t.Fatalf("Ranges = %+v", reps)
+	}
+	want := []string{
+		"entries[1]: complete length 200, but 100 elsewhere",
+		`entries[2]: malformed Content-Range "bytes 9-5/100"`,
+		"entries[3]: no Content-Range header",
+		"entries[4]: multipart/byteranges body not recorded",
+	}
+	if !reflect.DeepEqual(reps[0].Problems, want) {
+		t.Errorf("problems = %q, want %q", reps[0].Problems, want)
+	}
+	if reps[0].Size != 200 || reps[0].Distinct != 20 {
+		t.Errorf("size %d, distinct %d", reps[0].Size, reps[0].Distinct)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in   string
+		want contentRange
+		ok   bool
+	}{
+		{"bytes 0-499/1234", contentRange{ByteRange{0, 499}, 1234}, true},
+		{"Bytes  10-10/*", contentRange{ByteRange{10, 10}, -1}, true},
+		{"bytes 0-0/1", contentRange{ByteRange{0, 0}, 1}, true},
+		{"bytes */1234", contentRange{}, false},
+		{"items 0-9/10", contentRange{}, false},
+		{"bytes 0-10/10", contentRange{}, false},
+		{"bytes 5-4/10", contentRange{}, false},
+		{"bytes -1-4/10", contentRange{}, false},
+		{"bytes 0-4", contentRange{}, false},
+		{"bytes 0-x/10", contentRange{}, false},
+		{"", contentRange{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseContentRange(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseContentRange(%q) = %+v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestPartialDuplicates(t *testing.T) {
+	body := strings.Repeat("x", 10)
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		rangeEntry("https://example.com/a", "bytes 0-9/30", body),
+		rangeEntry("https://example.com/a", "bytes 10-19/30", body),
+		rangeEntry("https://example.com/a", "bytes 0-9/30", body),
+		bodyEntry("https://example.com/b", "video/mp4", body),
+	}}
+	groups := FindDuplicateBodies(log)
+	if len(groups) != 1 || !reflect.DeepEqual(groups[0].Entries, []int{0, 2}) {
+		t.Errorf("groups = %+v", groups)
+	}
+}
+
+func TestRenderReportRanges(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		rangeEntry("https://example.com/a", "bytes 0-1023/4096", strings.Repeat("x", 1024)),
+		rangeEntry("https://example.com/a", "bytes 512-2047/4096", strings.Repeat("x", 1536)),
+		rangeEntry("https://example.com/b", "bytes 0-9/*", strings.Repeat("x", 10)),
+	}}
+	var b strings.Builder
+	if err := RenderReport(&b, log, ReportOptions{Format: ReportText}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2 resources, 2.0 KiB distinct, 512 B refetched\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("report does not contain %q:\n%s", want, b.String())
+	}
+
+	b.Reset()
+	if err := RenderReport(&b, reportLog(), ReportOptions{Format: ReportText}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "Ranges:") {
+		t.Errorf("ranges line without partial responses:\n%s", b.String())
+	}
+}
@@ -0,0 +1,238 @@
+package harkit
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// RecorderOptions sets the limits of a [Recorder]. Zero values mean no
+// limit.
+type RecorderOptions struct {
+	MaxEntries   int                  // Entries kept; appending more evicts the oldest.
+	MaxBodyBytes int64                // Request and response body text kept in total; appending more evicts the oldest entries.
+	OnEvict      func(*harfile.Entry) // Called with each evicted entry, e.g. to write it out with a [harfile.StreamWriter].
+	Hooks        []Hook               // Called with a copy of each entry and page appended.
+	LogOptions   []LogOption          // Applied to the log created on first use and after Reset, see NewLog.
+	Clock        Clock                // Clock timing autosaves and the rolling window. Nil means RealClock.
+
+	// RollingWindow keeps only the entries started within this duration
+	// of now, see [WithRollingWindow]. Zero keeps entries of any age.
+	RollingWindow time.Duration
+
+	// AutosavePath is a file the log is saved to periodically, see
+	// [Recorder.FlushNow], so that a long capture survives the process.
+	AutosavePath string
+	// AutosaveInterval is the period of autosaves. Zero means
+	// DefaultAutosaveInterval.
+	AutosaveInterval time.Duration
+	// OnAutosaveError is called with the errors of periodic autosaves.
+	OnAutosaveError func(error)
+}
+
+// Recorder is a HAR log safe for concurrent use, shared by the recorders:
+// see [WithRecorder]. The zero value has no limits and is ready to use.
+type Recorder struct {
+	opts RecorderOptions
+
+	mu        sync.Mutex
+	log       *harfile.Log
+	bodyBytes int64
+	pageRefs  map[string]int          // Entries kept per Pageref.
+	open      map[*harfile.Entry]bool // Entries still updated, by WebSocket frames or events, copied by Freeze.
+	gen       uint64                  // Incremented by every change, to skip autosaves of an unchanged log.
+
+	autosave *autosaver
+}
+
+// NewRecorder returns an empty Recorder with the given limits, autosaving
+// when opts.AutosavePath is set.
+func NewRecorder(opts RecorderOptions) *Recorder {
+	r := &Recorder{opts: opts}
+	if opts.AutosavePath != "" {
+		r.autosave = newAutosaver(r, cmp.Or(opts.AutosaveInterval, DefaultAutosaveInterval))
+	}
+	return r
+}
+
+// init creates the log on first use. r.mu must be held.
+func (r *Recorder) init() {
+	if r.log == nil {
+		r.log = NewLog(r.opts.LogOptions...)
+		r.pageRefs = make(map[string]int)
+		r.open = make(map[*harfile.Entry]bool)
+	}
+}
+
+// Append adds e to the log, then evicts the oldest entries beyond the
+// limits and, with a RollingWindow, the entries at the front of the log
+// started before it. Hooks and OnEvict are called after the lock is
+// released, so they may use the Recorder. The entry just appended is never
+// evicted.
+func (r *Recorder) Append(e *harfile.Entry) {
+	r.add(e, nil, false)
+}
+
+// add appends e and calls the hooks of the Recorder, then the extra hooks
+// of the recorder that captured e. With open, e is still to be changed
+// through update until released, and Freeze copies it.
+func (r *Recorder) add(e *harfile.Entry, extra []Hook, open bool) {
+	var evicted []*harfile.Entry
+	r.mu.Lock()
+	r.init()
+	copies := make([]*harfile.Entry, len(r.opts.Hooks)+len(extra))
+	for i := range copies {
+		copies[i] = e.Clone()
+	}
+	r.log.Entries = append(r.log.Entries, e)
+	r.bodyBytes += bodyBytes(e)
+	r.pageRefs[e.Pageref]++
+	if open {
+		r.open[e] = true
+	}
+	r.gen++
+	for len(r.log.Entries) > 1 &&
+		(r.opts.MaxEntries > 0 && len(r.log.Entries) > r.opts.MaxEntries ||
+			r.opts.MaxBodyBytes > 0 && r.bodyBytes > r.opts.MaxBodyBytes) {
+		evicted = append(evicted, r.evictFirst(false))
+	}
+	if r.opts.RollingWindow > 0 {
+		// Entries are appended roughly in start order: stop at the first
+		// one within the window, so that pruning costs only what it
+		// removes. Late entries started earlier go once they reach the
+		// front.
+		cutoff := cmp.Or(r.opts.Clock, RealClock).Now().Add(-r.opts.RollingWindow)
+		for len(r.log.Entries) > 1 && r.log.Entries[0].StartedDateTime.Before(cutoff) {
+			evicted = append(evicted, r.evictFirst(true))
+		}
+	}
+	r.mu.Unlock()
+
+	for i, h := range append(slices.Clip(r.opts.Hooks), extra...) {
+		h.OnEntry(copies[i])
+	}
+	if r.opts.OnEvict != nil {
+		for _, old := range evicted {
+			r.opts.OnEvict(old)
+		}
+	}
+}
+
+// evictFirst removes the first entry of the log and returns it. With
+// dropPage, the page it referred to is removed too when no entry kept
+// refers to it anymore. r.mu must be held.
+func (r *Recorder) evictFirst(dropPage bool) *harfile.Entry {
+	old := r.log.Entries[0]
+	r.log.Entries[0] = nil
+	r.log.Entries = r.log.Entries[1:]
+	r.bodyBytes -= bodyBytes(old)
+	delete(r.open, old)
+	if r.pageRefs[old.Pageref]--; r.pageRefs[old.Pageref] <= 0 {
+		delete(r.pageRefs, old.Pageref)
+		if dropPage && old.Pageref != "" {
+			r.log.Pages = slices.DeleteFunc(r.log.Pages, func(p *harfile.Page) bool {
+				return p != nil && p.ID == old.Pageref
+			})
+		}
+	}
+	return old
+}
+
+// AppendPage adds p to the log, then calls the hooks with a copy of it.
+func (r *Recorder) AppendPage(p *harfile.Page) {
+	r.mu.Lock()
+	r.init()
+	r.log.Pages = append(r.log.Pages, p)
+	r.gen++
+	copies := make([]*harfile.Page, len(r.opts.Hooks))
+	for i := range copies {
+		copies[i] = p.Clone()
+	}
+	r.mu.Unlock()
+
+	for i, h := range r.opts.Hooks {
+		h.OnPage(copies[i])
+	}
+}
+
+// Snapshot returns a deep copy of the log.
+func (r *Recorder) Snapshot() *harfile.HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+	return (&harfile.HAR{Log: r.log}).Clone()
+}
+
+// Freeze returns a read-only view of the log as it is now, safe to read
+// while recording goes on, without copying the finished entries: the
+// entries and pages of the Recorder are never changed once recorded, and
+// the view keeps its own lists of them. Entries still being recorded,
+// WebSocket upgrades until their connection is closed and event streams
+// until they end, are copied, so later frames and events do not show in
+// the view.
+func (r *Recorder) Freeze() *harfile.LogView {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+	l := *r.log
+	l.Entries = slices.Clone(r.log.Entries)
+	l.Pages = slices.Clone(r.log.Pages)
+	for i, e := range l.Entries {
+		if r.open[e] {
+			l.Entries[i] = e.Clone()
+		}
+	}
+	return harfile.NewLogView(&l)
+}
+
+// Reset removes every entry and page, without calling OnEvict.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = nil
+	r.bodyBytes = 0
+	r.pageRefs = nil
+	r.open = nil
+	r.gen++
+}
+
+// Len returns the number of entries.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.log == nil {
+		return 0
+	}
+	return len(r.log.Entries)
+}
+
+// update runs f with the lock held, for changes to recorded entries. Only
+// open entries, see add, may be changed.
+func (r *Recorder) update(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f()
+	r.gen++
+}
+
+// release marks e as no longer changed, once its recording is over.
+func (r *Recorder) release(e *harfile.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.open, e)
+}
+
+// bodyBytes returns the length of the body texts kept in e.
+func bodyBytes(e *harfile.Entry) int64 {
+	var n int64
+	if e.Request != nil && e.Request.PostData != nil {
+		n += int64(len(e.Request.PostData.Text))
+	}
+	if e.Response != nil && e.Response.Content != nil {
+		n += int64(len(e.Response.Content.Text))
+	}
+	return n
+}
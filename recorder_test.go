@@ -0,0 +1,458 @@
+package harkit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// recordedEntry returns a GET of url answered with a text body of size
+// bytes.
+func recordedEntry(url string, size int) *harfile.Entry {
+	text := strings.Repeat("x", size)
+	return &harfile.Entry{
+		Request:  &harfile.Request{Method: "GET", URL: url, HTTPVersion: "HTTP/1.1"},
+		Response: &harfile.Response{Status: 200, Content: &harfile.Content{Size: int64(size), MimeType: "text/plain", Text: text}, BodySize: int64(size)},
+	}
+}
+
+func TestRecorderLimits(t *testing.T) {
+	urls := func(h *harfile.HAR) string {
+		var paths []string
+		for _, e := range h.Log.Entries {
+			paths = append(paths, strings.TrimPrefix(e.Request.URL, "https://example.com"))
+		}
+		return strings.Join(paths, " ")
+	}
+	tests := []struct {
+		name    string
+		opts    RecorderOptions
+		bodies  []int
+		kept    string
+		evicted string
+	}{
+		{"no limit", RecorderOptions{}, []int{10, 10, 10}, "/0 /1 /2", ""},
+		{"max entries", RecorderOptions{MaxEntries: 2}, []int{1, 1, 1, 1}, "/2 /3", "/0 /1"},
+		{"max body bytes", RecorderOptions{MaxBodyBytes: 25}, []int{10, 10, 10, 3, 20}, "/3 /4", "/0 /1 /2"},
+		{"both", RecorderOptions{MaxEntries: 3, MaxBodyBytes: 100}, []int{90, 5, 5, 5}, "/1 /2 /3", "/0"},
+		// The entry just appended is kept even beyond the limit.
+		{"oversized entry", RecorderOptions{MaxBodyBytes: 5}, []int{1, 50}, "/1", "/0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var evicted []string
+			opts := tt.opts
+			opts.OnEvict = func(e *harfile.Entry) {
+				evicted = append(evicted, strings.TrimPrefix(e.Request.URL, "https://example.com"))
+			}
+			r := NewRecorder(opts)
+			for i, n := range tt.bodies {
+				r.Append(recordedEntry(fmt.Sprintf("https://example.com/%d", i), n))
+			}
+			if got := urls(r.Snapshot()); got != tt.kept {
+				t.Errorf("kept %q, want %q", got, tt.kept)
+			}
+			if got := strings.Join(evicted, " "); got != tt.evicted {
+				t.Errorf("evicted %q, want %q", got, tt.evicted)
+			}
+			if r.Len() != len(strings.Fields(tt.kept)) {
+				t.Errorf("Len = %d", r.Len())
+			}
+		})
+	}
+}
+
+// TestRecorderEvictToStream writes evicted entries out with a StreamWriter,
+// so that the file and the recorder hold the whole capture between them.
+func TestRecorderEvictToStream(t *testing.T) {
+	var buf bytes.Buffer
+	sw := harfile.NewStreamWriter(&buf, &harfile.Creator{Name: "test"})
+	r := NewRecorder(RecorderOptions{MaxEntries: 3, OnEvict: func(e *harfile.Entry) {
+		if err := sw.WriteEntry(e); err != nil {
+			t.Error(err)
+		}
+	}})
+	for i := range 10 {
+		r.Append(recordedEntry(fmt.Sprintf("https://example.com/%d", i), 0))
+	}
+	for _, e := range r.Snapshot().Log.Entries {
+		sw.WriteEntry(e)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	h, err := harfile.Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range h.Log.Entries {
+		if want := fmt.Sprintf("https://example.com/%d", i); e.Request.URL != want {
+			t.Errorf("entry %d = %s, want %s", i, e.Request.URL, want)
+		}
+	}
+	if len(h.Log.Entries) != 10 {
+		t.Errorf("%d entries written, want 10", len(h.Log.Entries))
+	}
+}
+
+func TestRecorderSnapshotIsCopy(t *testing.T) {
+	r := &Recorder{}
+	r.AppendPage(&harfile.Page{ID: "p", Title: "p", PageTimings: &harfile.PageTimings{}})
+	e := recordedEntry("https://example.com/", 0)
+	e.Request.Headers = []*harfile.NameValuePair{{Name: "A", Value: "1"}}
+	r.Append(e)
+	h := r.Snapshot()
+	h.Log.Entries[0].Request.Headers[0].Value = "changed"
+	h.Log.Pages[0].Title = "changed"
+	again := r.Snapshot()
+	if again.Log.Entries[0].Request.Headers[0].Value != "1" || again.Log.Pages[0].Title != "p" {
+		t.Error("snapshot shares memory with the recorder")
+	}
+	r.Reset()
+	if r.Len() != 0 || len(r.Snapshot().Log.Entries) != 0 || len(h.Log.Entries) != 1 {
+		t.Error("Reset did not empty the recorder, or emptied a snapshot")
+	}
+}
+
+// TestRecorderShared runs a Transport and a Middleware recording into the
+// same Recorder while it is read and reset.
+func TestRecorderShared(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	rec := NewRecorder(RecorderOptions{MaxEntries: 50})
+	tr := NewTransport(nil, WithRecorder(rec))
+	client := &http.Client{Transport: tr}
+	mw := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "served")
+	}), WithRecorder(rec))
+	if tr.Recorder() != rec {
+		t.Fatal("Transport records elsewhere")
+	}
+
+	const workers, perWorker = 4, 25
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				resp, err := client.Get(fmt.Sprintf("%s/%d/%d", srv.URL, w, i))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/served/%d/%d", w, i), nil))
+			}
+		}()
+	}
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if n := rec.Len(); n > 50 {
+				t.Errorf("Len = %d beyond MaxEntries", n)
+			}
+			rec.Snapshot()
+			rec.AppendPage(&harfile.Page{ID: "p", PageTimings: &harfile.PageTimings{}})
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+	if n := rec.Len(); n != 50 {
+		t.Errorf("Len = %d, want 50", n)
+	}
+	rec.Reset()
+	if rec.Len() != 0 {
+		t.Error("Reset kept entries")
+	}
+}
+
+func TestRecorderRollingWindow(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(base)
+	var evicted []string
+	r := NewRecorder(RecorderOptions{
+		RollingWindow: 15 * time.Minute,
+		Clock:         clock,
+		OnEvict: func(e *harfile.Entry) {
+			evicted = append(evicted, strings.TrimPrefix(e.Request.URL, "https://example.com/"))
+		},
+	})
+	r.AppendPage(&harfile.Page{ID: "p0", StartedDateTime: base})
+	r.AppendPage(&harfile.Page{ID: "p1", StartedDateTime: base.Add(10 * time.Minute)})
+
+	describe := func() string {
+		h := r.Snapshot()
+		var parts []string
+		for _, e := range h.Log.Entries {
+			parts = append(parts, strings.TrimPrefix(e.Request.URL, "https://example.com/"))
+		}
+		parts = append(parts, "|")
+		for _, p := range h.Log.Pages {
+			parts = append(parts, p.ID)
+		}
+		return strings.Join(parts, " ")
+	}
+	steps := []struct {
+		now, started time.Duration // Since base.
+		path, page   string
+		kept         string
+		evicted      string
+	}{
+		{0, 0, "a", "p0", "a | p0 p1", ""},
+		{5 * time.Minute, 5 * time.Minute, "b", "p0", "a b | p0 p1", ""},
+		{10 * time.Minute, 10 * time.Minute, "c", "p1", "a b c | p0 p1", ""},
+		// A retry started at 2m finishes late: it stays until it reaches
+		// the front of the log.
+		{18 * time.Minute, 2 * time.Minute, "retry", "p1", "b c retry | p0 p1", "a"},
+		{22 * time.Minute, 22 * time.Minute, "d", "p1", "c retry d | p1", "a b"},
+		{26 * time.Minute, 26 * time.Minute, "e", "p1", "d e | p1", "a b c retry"},
+		// The entry just appended is kept, however old.
+		{time.Hour, 0, "old", "", "old |", "a b c retry d e"},
+	}
+	for _, s := range steps {
+		clock.Advance(base.Add(s.now).Sub(clock.Now()))
+		e := harfile.NewEntry().Get("https://example.com/" + s.path).StartedAt(base.Add(s.started)).Build()
+		e.Pageref = s.page
+		r.Append(e)
+		if got := describe(); got != s.kept {
+			t.Errorf("after %s: kept %q, want %q", s.path, got, s.kept)
+		}
+		if got := strings.Join(evicted, " "); got != s.evicted {
+			t.Errorf("after %s: evicted %q, want %q", s.path, got, s.evicted)
+		}
+	}
+}
+
+// TestRecorderRollingWindowConcurrent prunes while snapshots are taken, and
+// checks that every snapshot holds a window of the entries in order.
+func TestRecorderRollingWindowConcurrent(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var readers sync.WaitGroup
+	var mu sync.Mutex
+	evicted := 0
+	r := NewRecorder(RecorderOptions{RollingWindow: 10 * time.Millisecond, Clock: clock, OnEvict: func(*harfile.Entry) {
+		mu.Lock()
+		evicted++
+		mu.Unlock()
+	}})
+	const appends = 500
+
+	done := make(chan struct{})
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				entries := r.Snapshot().Log.Entries
+				if len(entries) > 11 {
+					t.Errorf("snapshot of %d entries for a window of 10", len(entries))
+					return
+				}
+				for i := 1; i < len(entries); i++ {
+					if !entries[i-1].StartedDateTime.Before(entries[i].StartedDateTime) {
+						t.Errorf("snapshot out of order at %d", i)
+						return
+					}
+				}
+			}
+		}()
+	}
+	for i := range appends {
+		r.Append(harfile.NewEntry().Get(fmt.Sprintf("https://example.com/%d", i)).StartedAt(clock.Now()).Build())
+		clock.Advance(time.Millisecond)
+	}
+	close(done)
+	readers.Wait()
+
+	if kept := r.Len(); kept+evicted != appends {
+		t.Errorf("%d kept and %d evicted of %d entries", kept, evicted, appends)
+	}
+}
+
+func TestWithRollingWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	h := Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), WithClock(clock), WithRollingWindow(time.Minute))
+	for i := range 5 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://example.com/%d", i), nil))
+		clock.Advance(30 * time.Second)
+	}
+	var urls []string
+	for _, e := range h.Snapshot().Log.Entries {
+		urls = append(urls, e.Request.URL)
+	}
+	// The last entry started at 2m, so the window starts at 1m.
+	if got := strings.Join(urls, " "); got != "http://example.com/2 http://example.com/3 http://example.com/4" {
+		t.Errorf("kept %s", got)
+	}
+}
+
+// The tests below are meant to be run with -race.
+
+func TestFreezeConcurrentAppend(t *testing.T) {
+	r := NewRecorder(RecorderOptions{})
+	const writers, perWriter = 4, 200
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				r.Append(harfile.NewEntry().
+					Get(fmt.Sprintf("https://example.com/%d/%d", w, i)).
+					RespondStatus(200 + i%2*300).
+					Build())
+			}
+		}()
+	}
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			last := 0
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				v := r.Freeze()
+				if v.EntryCount() < last {
+					t.Errorf("view shrank from %d to %d entries", last, v.EntryCount())
+				}
+				last = v.EntryCount()
+				n := 0
+				for _, e := range v.All() {
+					_ = e.Request.URL
+					n++
+				}
+				if n != last {
+					t.Errorf("iterated %d of %d entries", n, last)
+				}
+				v.Filter(harfile.ByStatusRange(500, 599))
+				HistogramBy(v.Log(), nil, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	if v := r.Freeze(); v.EntryCount() != writers*perWriter {
+		t.Errorf("EntryCount = %d, want %d", v.EntryCount(), writers*perWriter)
+	}
+}
+
+func TestFreezeIsStable(t *testing.T) {
+	r := NewRecorder(RecorderOptions{MaxEntries: 2})
+	r.Append(harfile.NewEntry().Get("https://example.com/a").Build())
+	r.Append(harfile.NewEntry().Get("https://example.com/b").Build())
+	v := r.Freeze()
+	r.Append(harfile.NewEntry().Get("https://example.com/c").Build())
+	r.Reset()
+	if v.EntryCount() != 2 || v.EntryAt(0).Request.URL != "https://example.com/a" || v.EntryAt(2) != nil {
+		t.Errorf("view changed after append, eviction and reset")
+	}
+}
+
+func TestFreezeWebSocket(t *testing.T) {
+	srv := upgradeServer(t)
+	rec := NewRecorder(RecorderOptions{})
+	tr := NewTransport(nil, WithRecorder(rec))
+	resp := dialUpgrade(t, tr, srv.URL)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			tr.RecordWSMessage(resp, harfile.WebSocketReceive, harfile.OpcodeText, []byte("tick"))
+		}
+	}()
+	for range 50 {
+		v := rec.Freeze()
+		for _, e := range v.All() {
+			for _, m := range e.WebSocketMessages {
+				_ = m.Data
+			}
+		}
+	}
+	wg.Wait()
+
+	// Until the connection is closed, the view holds a copy of the entry.
+	v := rec.Freeze()
+	tr.RecordWSMessage(resp, harfile.WebSocketSend, harfile.OpcodeText, []byte("bye"))
+	if got := len(v.EntryAt(0).WebSocketMessages); got != 100 {
+		t.Errorf("view has %d messages, want the 100 recorded before Freeze", got)
+	}
+
+	resp.Body.Close()
+	rec.mu.Lock()
+	open, entry := len(rec.open), rec.log.Entries[0]
+	rec.mu.Unlock()
+	if open != 0 {
+		t.Errorf("%d entries still open after the connection closed", open)
+	}
+	if rec.Freeze().EntryAt(0) != entry {
+		t.Error("Freeze copied an entry whose connection is closed")
+	}
+}
+
+func TestFreezeEventStreamRelease(t *testing.T) {
+	rec := NewRecorder(RecorderOptions{})
+	rec.add(harfile.NewEntry().Get("https://example.com/events").Build(), nil, true)
+	e := rec.log.Entries[0]
+	if rec.Freeze().EntryAt(0) == e {
+		t.Error("Freeze shared an open entry")
+	}
+	rec.release(e)
+	if rec.Freeze().EntryAt(0) != e {
+		t.Error("Freeze copied a released entry")
+	}
+}
+
+func BenchmarkFreeze(b *testing.B) {
+	r := NewRecorder(RecorderOptions{})
+	e := harfile.NewEntry().Get("https://example.com/").StartedAt(time.Now()).RespondStatus(200).Build()
+	for range 10_000 {
+		r.Append(e.Clone())
+	}
+	b.ReportAllocs()
+	for range b.N {
+		r.Freeze()
+	}
+}
@@ -0,0 +1,394 @@
+package harkit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Mathious6/harkit/haraudit"
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// DefaultPlaceholder replaces redacted values unless RedactOptions says
+// otherwise.
+const DefaultPlaceholder = "[REDACTED]"
+
+// DefaultRedactedHeaders are the headers redacted when RedactOptions.Headers
+// is nil.
+var DefaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// RedactOptions selects what [Redact] scrubs. Names are matched case
+// insensitively for headers and exactly for everything else.
+type RedactOptions struct {
+	Headers       []string         // Header names to redact. Nil means DefaultRedactedHeaders; use an empty slice to keep every header.
+	Cookies       []string         // Cookie names to redact. Nil means every cookie; use an empty slice to keep every cookie.
+	QueryParams   []string         // Query parameter names to redact, in QueryString and in the URL.
+	PostParams    []string         // Posted parameter names to redact, in PostData.Params and in URL encoded PostData.Text.
+	JSONPaths     []string         // Dotted paths of JSON body fields to redact, "[]" standing for every array element (e.g. "users[].email").
+	BodyPatterns  []*regexp.Regexp // Patterns replaced in textual request and response bodies.
+	ClearServerIP bool             // Remove Entry.ServerIPAddress.
+	Placeholder   string           // Replacement value. Empty means DefaultPlaceholder.
+}
+
+// AddRules extends o with the rules produced by [haraudit.Inventory.ToRules].
+func (o *RedactOptions) AddRules(rules []haraudit.Rule) {
+	for _, r := range rules {
+		switch r.Field.Location {
+		case haraudit.RequestHeader, haraudit.ResponseHeader:
+			if o.Headers == nil {
+				o.Headers = slices.Clone(DefaultRedactedHeaders)
+			}
+			o.Headers = appendUnique(o.Headers, r.Field.Name)
+		case haraudit.RequestCookie, haraudit.ResponseCookie:
+			if o.Cookies != nil {
+				o.Cookies = appendUnique(o.Cookies, r.Field.Name)
+			}
+		case haraudit.QueryParam:
+			o.QueryParams = appendUnique(o.QueryParams, r.Field.Name)
+		case haraudit.RequestBody, haraudit.ResponseBody:
+			if r.Field.Location == haraudit.RequestBody && !strings.ContainsAny(r.Field.Name, ".[") {
+				o.PostParams = appendUnique(o.PostParams, r.Field.Name)
+			}
+			o.JSONPaths = appendUnique(o.JSONPaths, r.Field.Name)
+		}
+	}
+}
+
+func appendUnique(s []string, v string) []string {
+	if slices.Contains(s, v) {
+		return s
+	}
+	return append(s, v)
+}
+
+// RedactReport counts the values replaced by [Redact].
+type RedactReport struct {
+	Headers     int
+	Cookies     int
+	QueryParams int
+	PostParams  int
+	BodyFields  int // JSON fields and pattern matches replaced in bodies.
+	ServerIPs   int
+}
+
+// Total returns the number of redacted values.
+func (r *RedactReport) Total() int {
+	return r.Headers + r.Cookies + r.QueryParams + r.PostParams + r.BodyFields + r.ServerIPs
+}
+
+// Redact scrubs secrets from har in place and reports what was replaced.
+// Sizes affected by a replacement are recomputed, or set to -1 when they can
+// no longer be known, so the file stays consistent. Entries with replaced
+// values are tagged with [harfile.TagRedacted] and get a note in their
+// comment.
+func Redact(har *harfile.HAR, opts RedactOptions) *RedactReport {
+	r := &redactor{opts: opts, report: &RedactReport{}}
+	if r.opts.Placeholder == "" {
+		r.opts.Placeholder = DefaultPlaceholder
+	}
+	if r.opts.Headers == nil {
+		r.opts.Headers = DefaultRedactedHeaders
+	}
+	if har == nil || har.Log == nil {
+		return r.report
+	}
+	for _, e := range har.Log.Entries {
+		if e == nil {
+			continue
+		}
+		before := r.report.Total()
+		if opts.ClearServerIP && e.ServerIPAddress != "" {
+			e.ServerIPAddress = ""
+			r.report.ServerIPs++
+		}
+		if e.Request != nil {
+			r.request(e.Request)
+		}
+		if e.Response != nil {
+			r.response(e.Response)
+		}
+		if n := r.report.Total() - before; n > 0 {
+			e.AddTag(harfile.TagRedacted)
+			e.AddNote(fmt.Sprintf("values redacted: %d", n))
+		}
+	}
+	return r.report
+}
+
+type redactor struct {
+	opts   RedactOptions
+	report *RedactReport
+}
+
+func (r *redactor) request(req *harfile.Request) {
+	if r.headers(req.Headers) {
+		req.HeadersSize = -1
+	}
+	r.cookies(req.Cookies)
+
+	queryChanged := false
+	for _, q := range req.QueryString {
+		if q != nil && slices.Contains(r.opts.QueryParams, q.Name) && q.Value != r.opts.Placeholder {
+			q.Value = r.opts.Placeholder
+			r.report.QueryParams++
+			queryChanged = true
+		}
+	}
+	if u, err := url.Parse(req.URL); err == nil && len(r.opts.QueryParams) > 0 {
+		if raw, n := r.replaceQuery(u.RawQuery); n > 0 {
+			u.RawQuery = raw
+			req.URL = u.String()
+			if !queryChanged {
+				r.report.QueryParams += n
+			}
+			req.HeadersSize = -1
+		}
+	}
+
+	pd := req.PostData
+	if pd == nil {
+		return
+	}
+	for _, p := range pd.Params {
+		if p != nil && slices.Contains(r.opts.PostParams, p.Name) && p.Value != r.opts.Placeholder {
+			p.Value = r.opts.Placeholder
+			r.report.PostParams++
+		}
+	}
+	text := pd.Text
+	if strings.HasPrefix(strings.ToLower(pd.MimeType), "application/x-www-form-urlencoded") && len(r.opts.PostParams) > 0 {
+		if raw, n := r.replaceForm(text); n > 0 {
+			text = raw
+			if len(pd.Params) == 0 {
+				r.report.PostParams += n
+			}
+		}
+	}
+	text = r.body(pd.MimeType, text)
+	if text != pd.Text {
+		pd.Text = text
+		req.BodySize = int64(len(text))
+	}
+}
+
+func (r *redactor) response(resp *harfile.Response) {
+	if r.headers(resp.Headers) {
+		resp.HeadersSize = -1
+	}
+	r.cookies(resp.Cookies)
+
+	rewriteContent(resp, r.body)
+}
+
+// rewriteContent replaces the textual response body with rewrite(mimeType,
+// text), decoding and re-encoding base64 contents and fixing the sizes when
+// the body changes. Binary bodies are left alone.
+func rewriteContent(resp *harfile.Response, rewrite func(mimeType, text string) string) {
+	c := resp.Content
+	if c == nil || c.Text == "" {
+		return
+	}
+	text := c.Text
+	if c.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(c.Text)
+		if err != nil || !utf8.Valid(decoded) {
+			return
+		}
+		text = string(decoded)
+	}
+	rewritten := rewrite(c.MimeType, text)
+	if rewritten == text {
+		return
+	}
+	if c.Encoding == "base64" {
+		c.Text = base64.StdEncoding.EncodeToString([]byte(rewritten))
+	} else {
+		c.Text = rewritten
+	}
+	c.Size = int64(len(rewritten))
+	if c.Compression != 0 {
+		c.Compression = 0
+		resp.BodySize = -1
+	} else if resp.BodySize >= 0 {
+		resp.BodySize = c.Size
+	}
+}
+
+func (r *redactor) headers(headers []*harfile.NameValuePair) bool {
+	changed := false
+	for _, h := range headers {
+		if h == nil || h.Value == r.opts.Placeholder {
+			continue
+		}
+		if slices.ContainsFunc(r.opts.Headers, func(name string) bool { return strings.EqualFold(name, h.Name) }) {
+			h.Value = r.opts.Placeholder
+			r.report.Headers++
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (r *redactor) cookies(cookies []*harfile.Cookie) {
+	for _, c := range cookies {
+		if c == nil || c.Value == r.opts.Placeholder {
+			continue
+		}
+		if r.opts.Cookies == nil || slices.Contains(r.opts.Cookies, c.Name) {
+			c.Value = r.opts.Placeholder
+			r.report.Cookies++
+		}
+	}
+}
+
+// replaceQuery rewrites the values of redacted parameters in a raw query,
+// keeping parameter order and the encoding of the other parameters.
+func (r *redactor) replaceQuery(raw string) (string, int) {
+	return replaceParams(raw, r.opts.QueryParams, r.opts.Placeholder)
+}
+
+func (r *redactor) replaceForm(raw string) (string, int) {
+	return replaceParams(raw, r.opts.PostParams, r.opts.Placeholder)
+}
+
+func replaceParams(raw string, names []string, placeholder string) (string, int) {
+	if raw == "" {
+		return raw, 0
+	}
+	parts := strings.Split(raw, "&")
+	n := 0
+	escaped := url.QueryEscape(placeholder)
+	for i, part := range parts {
+		key, value, _ := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if slices.Contains(names, name) && value != escaped {
+			parts[i] = key + "=" + escaped
+			n++
+		}
+	}
+	return strings.Join(parts, "&"), n
+}
+
+// body applies JSON path and pattern redaction to a textual body.
+func (r *redactor) body(mimeType, text string) string {
+	if text == "" {
+		return text
+	}
+	if len(r.opts.JSONPaths) > 0 && isJSONMime(mimeType) {
+		if redacted, n := redactJSON(text, r.opts.JSONPaths, r.opts.Placeholder); n > 0 {
+			text = redacted
+			r.report.BodyFields += n
+		}
+	}
+	for _, re := range r.opts.BodyPatterns {
+		n := 0
+		text = re.ReplaceAllStringFunc(text, func(m string) string {
+			if m == r.opts.Placeholder {
+				return m
+			}
+			n++
+			return r.opts.Placeholder
+		})
+		r.report.BodyFields += n
+	}
+	return text
+}
+
+// redactJSON replaces the values found at paths. The document is
+// re-serialized, so object keys end up sorted.
+func redactJSON(text string, paths []string, placeholder string) (string, int) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return text, 0
+	}
+	n := 0
+	for _, path := range paths {
+		n += redactPath(doc, splitJSONPath(path), placeholder)
+	}
+	if n == 0 {
+		return text, 0
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return text, 0
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), n
+}
+
+// splitJSONPath splits "a.b[].c" into ["a", "b", "[]", "c"]. A leading "$"
+// is ignored.
+func splitJSONPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		arrays := 0
+		for strings.HasSuffix(part, "[]") {
+			part = strings.TrimSuffix(part, "[]")
+			arrays++
+		}
+		if part != "" {
+			segments = append(segments, part)
+		}
+		for range arrays {
+			segments = append(segments, "[]")
+		}
+	}
+	return segments
+}
+
+func redactPath(v any, path []string, placeholder string) int {
+	if len(path) == 0 {
+		return 0
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		child, ok := v[path[0]]
+		if !ok {
+			return 0
+		}
+		if len(path) == 1 {
+			if child == nil || child == placeholder {
+				return 0
+			}
+			v[path[0]] = placeholder
+			return 1
+		}
+		return redactPath(child, path[1:], placeholder)
+	case []any:
+		if path[0] != "[]" {
+			return 0
+		}
+		n := 0
+		for i, elem := range v {
+			if len(path) == 1 {
+				if elem != nil && elem != placeholder {
+					v[i] = placeholder
+					n++
+				}
+				continue
+			}
+			n += redactPath(elem, path[1:], placeholder)
+		}
+		return n
+	}
+	return 0
+}
+
+func isJSONMime(mimeType string) bool {
+	mt, _, _ := strings.Cut(strings.ToLower(mimeType), ";")
+	mt = strings.TrimSpace(mt)
+	return mt == "application/json" || mt == "text/json" || strings.HasSuffix(mt, "+json")
+}
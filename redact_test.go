@@ -0,0 +1,189 @@
+package harkit
+
+import (
+	"encoding/base64"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/Mathious6/harkit/haraudit"
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// redactHAR returns a capture with a secret in every place Redact looks.
+func redactHAR() *harfile.HAR {
+	e := &harfile.Entry{
+		ServerIPAddress: "203.0.113.7",
+		Request: &harfile.Request{
+			Method: "POST",
+			URL:    "https://example.com/login?token=abc&page=2",
+			Headers: []*harfile.NameValuePair{
+				{Name: "authorization", Value: "Bearer abc"},
+				{Name: "Accept", Value: "*/*"},
+			},
+			Cookies:     []*harfile.Cookie{{Name: "sid", Value: "s1"}, {Name: "theme", Value: "dark"}},
+			QueryString: []*harfile.NameValuePair{{Name: "token", Value: "abc"}, {Name: "page", Value: "2"}},
+			PostData: &harfile.PostData{
+				MimeType: "application/x-www-form-urlencoded",
+				Params:   []*harfile.Param{{Name: "user", Value: "ada"}, {Name: "password", Value: "hunter2"}},
+				Text:     "user=ada&password=hunter2",
+			},
+			HeadersSize: 120,
+			BodySize:    25,
+		},
+		Response: &harfile.Response{
+			Status:  200,
+			Headers: []*harfile.NameValuePair{{Name: "Set-Cookie", Value: "sid=s2"}},
+			Cookies: []*harfile.Cookie{{Name: "sid", Value: "s2"}},
+			Content: &harfile.Content{
+				Size:     64,
+				MimeType: "application/json; charset=utf-8",
+				Text:     `{"user":{"email":"ada@example.com","id":1},"keys":["k1","k2"],"note":"call 555-0100"}`,
+			},
+			HeadersSize: 80,
+			BodySize:    64,
+		},
+	}
+	return &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e, nil}}}
+}
+
+func TestRedact(t *testing.T) {
+	h := redactHAR()
+	report := Redact(h, RedactOptions{
+		QueryParams:   []string{"token"},
+		PostParams:    []string{"password"},
+		JSONPaths:     []string{"$.user.email", "keys[]"},
+		BodyPatterns:  []*regexp.Regexp{regexp.MustCompile(`\d{3}-\d{4}`)},
+		ClearServerIP: true,
+	})
+	want := RedactReport{Headers: 2, Cookies: 3, QueryParams: 1, PostParams: 1, BodyFields: 4, ServerIPs: 1}
+	if *report != want {
+		t.Errorf("report = %+v, want %+v", *report, want)
+	}
+	if report.Total() != 12 {
+		t.Errorf("Total = %d, want 12", report.Total())
+	}
+
+	e := h.Log.Entries[0]
+	req, resp := e.Request, e.Response
+	if req.Headers[0].Value != DefaultPlaceholder || req.Headers[1].Value != "*/*" || req.HeadersSize != -1 {
+		t.Errorf("request headers = %v %v, size %d", req.Headers[0], req.Headers[1], req.HeadersSize)
+	}
+	if req.Cookies[0].Value != DefaultPlaceholder || req.Cookies[1].Value != DefaultPlaceholder {
+		t.Error("nil Cookies left a cookie")
+	}
+	if req.URL != "https://example.com/login?token=%5BREDACTED%5D&page=2" || req.QueryString[0].Value != DefaultPlaceholder || req.QueryString[1].Value != "2" {
+		t.Errorf("url %s, query %v", req.URL, req.QueryString)
+	}
+	if pd := req.PostData; pd.Params[1].Value != DefaultPlaceholder || pd.Text != "user=ada&password=%5BREDACTED%5D" || req.BodySize != int64(len(pd.Text)) {
+		t.Errorf("postData = %+v, bodySize %d", pd, req.BodySize)
+	}
+	wantBody := `{"keys":["[REDACTED]","[REDACTED]"],"note":"call [REDACTED]","user":{"email":"[REDACTED]","id":1}}`
+	if c := resp.Content; c.Text != wantBody || c.Size != int64(len(wantBody)) || resp.BodySize != c.Size {
+		t.Errorf("content = %q, size %d, bodySize %d", c.Text, c.Size, resp.BodySize)
+	}
+	if e.ServerIPAddress != "" {
+		t.Error("server IP kept")
+	}
+	if !e.HasTag(harfile.TagRedacted) || e.Comment != "values redacted: 12" {
+		t.Errorf("tags %q, comment %q", e.Tags, e.Comment)
+	}
+
+	// Redacting again finds nothing left.
+	if again := Redact(h, RedactOptions{QueryParams: []string{"token"}, PostParams: []string{"password"}, JSONPaths: []string{"user.email"}}); again.Total() != 0 {
+		t.Errorf("second pass = %+v", *again)
+	}
+	if e.Comment != "values redacted: 12" {
+		t.Errorf("second pass noted %q", e.Comment)
+	}
+}
+
+func TestRedactOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  RedactOptions
+		check func(t *testing.T, e *harfile.Entry)
+	}{
+		{"keep every header", RedactOptions{Headers: []string{}}, func(t *testing.T, e *harfile.Entry) {
+			if e.Request.Headers[0].Value != "Bearer abc" || e.Request.HeadersSize != 120 {
+				t.Errorf("headers = %v", e.Request.Headers[0])
+			}
+		}},
+		{"named cookies", RedactOptions{Cookies: []string{"theme"}}, func(t *testing.T, e *harfile.Entry) {
+			if e.Request.Cookies[0].Value != "s1" || e.Request.Cookies[1].Value != DefaultPlaceholder {
+				t.Errorf("cookies = %v %v", e.Request.Cookies[0], e.Request.Cookies[1])
+			}
+		}},
+		{"placeholder", RedactOptions{Placeholder: "***"}, func(t *testing.T, e *harfile.Entry) {
+			if e.Request.Headers[0].Value != "***" {
+				t.Errorf("header = %v", e.Request.Headers[0])
+			}
+		}},
+		{"compressed body", RedactOptions{JSONPaths: []string{"user.id"}}, func(t *testing.T, e *harfile.Entry) {
+			if e.Response.BodySize != -1 || e.Response.Content.Compression != 0 {
+				t.Errorf("bodySize %d, compression %d", e.Response.BodySize, e.Response.Content.Compression)
+			}
+		}},
+		{"unknown path", RedactOptions{JSONPaths: []string{"user.phone", "user[]"}}, func(t *testing.T, e *harfile.Entry) {
+			if e.Response.Content.Text != redactHAR().Log.Entries[0].Response.Content.Text {
+				t.Error("body rewritten without a match")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := redactHAR()
+			h.Log.Entries[0].Response.Content.Compression = 20
+			Redact(h, tt.opts)
+			tt.check(t, h.Log.Entries[0])
+		})
+	}
+}
+
+func TestRedactBase64Body(t *testing.T) {
+	h := redactHAR()
+	c := h.Log.Entries[0].Response.Content
+	c.Text = base64.StdEncoding.EncodeToString([]byte(`{"token":"abc"}`))
+	c.Encoding = "base64"
+	Redact(h, RedactOptions{JSONPaths: []string{"token"}})
+	decoded, err := base64.StdEncoding.DecodeString(c.Text)
+	if err != nil || string(decoded) != `{"token":"[REDACTED]"}` || c.Size != int64(len(decoded)) {
+		t.Errorf("base64 body = %q, %v, size %d", decoded, err, c.Size)
+	}
+
+	// Binary bodies are left alone.
+	binary := base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe, 0x00})
+	c.Text = binary
+	Redact(h, RedactOptions{BodyPatterns: []*regexp.Regexp{regexp.MustCompile(".")}})
+	if c.Text != binary {
+		t.Errorf("binary body rewritten to %q", c.Text)
+	}
+}
+
+func TestRedactAddRules(t *testing.T) {
+	h := redactHAR()
+	var opts RedactOptions
+	opts.AddRules(haraudit.DataInventory(h).ToRules())
+	if !slices.Contains(opts.Headers, "Authorization") || !slices.Contains(opts.Headers, "authorization") {
+		t.Errorf("Headers = %q", opts.Headers)
+	}
+	if !slices.Contains(opts.QueryParams, "token") || !slices.Contains(opts.PostParams, "password") || !slices.Contains(opts.JSONPaths, "user.email") {
+		t.Errorf("opts = %+v", opts)
+	}
+	if opts.Cookies != nil {
+		t.Errorf("Cookies = %q, want every cookie", opts.Cookies)
+	}
+	Redact(h, opts)
+	if r := haraudit.DataInventory(h).Categories[haraudit.CategoryEmail]; r != nil {
+		t.Errorf("emails left: %v", r.Paths())
+	}
+}
+
+func TestRedactNil(t *testing.T) {
+	if r := Redact(nil, RedactOptions{}); r.Total() != 0 {
+		t.Errorf("nil HAR = %+v", *r)
+	}
+	if r := Redact(&harfile.HAR{}, RedactOptions{}); r.Total() != 0 {
+		t.Errorf("HAR without log = %+v", *r)
+	}
+}
@@ -0,0 +1,84 @@
+package harkit
+
+import (
+	"net/url"
+	"slices"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// RedirectChain is a sequence of entries linked by redirects.
+type RedirectChain struct {
+	Entries  []*harfile.Entry // Entries in order, all but possibly the last being redirects.
+	Dangling bool             // The last entry is a redirect whose target was not recorded.
+	Loop     bool             // The last entry requests a URL already visited by the chain.
+}
+
+// Final returns the last entry of the chain.
+func (c *RedirectChain) Final() *harfile.Entry {
+	return c.Entries[len(c.Entries)-1]
+}
+
+// RedirectChains follows every redirect of log to the entry that satisfied
+// it, see [harfile.Entry.FollowedBy], and returns the resulting chains in
+// order of start. Each entry belongs to at most one chain, and a chain that
+// comes back to a URL it already visited stops there with Loop set.
+func RedirectChains(log *harfile.Log) []*RedirectChain {
+	entries := slices.Clone(log.Entries)
+	entries = slices.DeleteFunc(entries, func(e *harfile.Entry) bool { return e == nil || e.Request == nil })
+	slices.SortStableFunc(entries, func(a, b *harfile.Entry) int {
+		return a.StartedDateTime.Compare(b.StartedDateTime)
+	})
+
+	// Link each redirect to the first unclaimed later entry requesting its
+	// target.
+	next := make(map[*harfile.Entry]*harfile.Entry)
+	claimed := make(map[*harfile.Entry]bool)
+	for i, e := range entries {
+		target, ok := e.RedirectTarget()
+		if !ok {
+			continue
+		}
+		for _, c := range entries[i+1:] {
+			if !claimed[c] && sameURL(c.Request.URL, target) {
+				next[e] = c
+				claimed[c] = true
+				break
+			}
+		}
+	}
+
+	var chains []*RedirectChain
+	for _, e := range entries {
+		if _, ok := e.RedirectTarget(); !ok || claimed[e] {
+			continue
+		}
+		chain := &RedirectChain{Entries: []*harfile.Entry{e}}
+		visited := map[string]bool{e.Request.URL: true}
+		for cur := e; ; {
+			n, ok := next[cur]
+			if !ok {
+				_, chain.Dangling = cur.RedirectTarget()
+				break
+			}
+			chain.Entries = append(chain.Entries, n)
+			if visited[n.Request.URL] {
+				chain.Loop = true
+				break
+			}
+			visited[n.Request.URL] = true
+			cur = n
+		}
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+func sameURL(raw, target string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String() == target
+}
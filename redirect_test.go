@@ -0,0 +1,110 @@
+package harkit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// redirectEntry returns an entry for url started sec seconds in,
+// redirecting to location when it is not empty.
+func redirectEntry(sec int, url, location string) *harfile.Entry {
+	e := &harfile.Entry{
+		StartedDateTime: time.Date(2024, 1, 1, 0, 0, sec, 0, time.UTC),
+		Request:         &harfile.Request{Method: "GET", URL: url},
+		Response:        &harfile.Response{Status: 200},
+	}
+	if location != "" {
+		e.Response.Status = 301
+		e.Response.RedirectURL = location
+	}
+	return e
+}
+
+// chainURLs renders a chain as its URLs joined by arrows.
+func chainURLs(c *RedirectChain) string {
+	var urls []string
+	for _, e := range c.Entries {
+		urls = append(urls, e.Request.URL)
+	}
+	return strings.Join(urls, " -> ")
+}
+
+func TestRedirectChains(t *testing.T) {
+	l := &harfile.Log{Entries: []*harfile.Entry{
+		redirectEntry(3, "https://example.com/c", ""),
+		redirectEntry(0, "http://example.com/a", "https://example.com/b"),
+		redirectEntry(2, "https://example.com/b", "/c"),
+		redirectEntry(4, "https://example.com/old", "/moved"),
+		redirectEntry(5, "https://example.com/plain", ""),
+		nil,
+	}}
+	chains := RedirectChains(l)
+	if len(chains) != 2 {
+		t.Fatalf("%d chains, want 2", len(chains))
+	}
+	if got := chainURLs(chains[0]); got != "http://example.com/a -> https://example.com/b -> https://example.com/c" {
+		t.Errorf("chain = %s", got)
+	}
+	if chains[0].Dangling || chains[0].Loop || chains[0].Final() != l.Entries[0] {
+		t.Errorf("complete chain: dangling %v, loop %v", chains[0].Dangling, chains[0].Loop)
+	}
+	if got := chainURLs(chains[1]); got != "https://example.com/old" || !chains[1].Dangling {
+		t.Errorf("dangling chain = %s, dangling %v", got, chains[1].Dangling)
+	}
+}
+
+func TestRedirectChainsLoop(t *testing.T) {
+	// A -> B -> A -> B ... as a misconfigured server would record it.
+	var entries []*harfile.Entry
+	for i := range 6 {
+		if i%2 == 0 {
+			entries = append(entries, redirectEntry(i, "https://example.com/a", "/b"))
+		} else {
+			entries = append(entries, redirectEntry(i, "https://example.com/b", "/a"))
+		}
+	}
+	done := make(chan []*RedirectChain)
+	go func() { done <- RedirectChains(&harfile.Log{Entries: entries}) }()
+	var chains []*RedirectChain
+	select {
+	case chains = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RedirectChains did not terminate on a loop")
+	}
+	if len(chains) != 1 {
+		t.Fatalf("%d chains, want 1", len(chains))
+	}
+	c := chains[0]
+	if got := chainURLs(c); got != "https://example.com/a -> https://example.com/b -> https://example.com/a" || !c.Loop || c.Dangling {
+		t.Errorf("chain = %s, loop %v, dangling %v", got, c.Loop, c.Dangling)
+	}
+
+	// A page redirecting to itself loops at once.
+	self := redirectEntry(0, "https://example.com/self", "/self")
+	again := redirectEntry(1, "https://example.com/self", "/self")
+	chains = RedirectChains(&harfile.Log{Entries: []*harfile.Entry{self, again}})
+	if len(chains) != 1 || len(chains[0].Entries) != 2 || !chains[0].Loop {
+		t.Errorf("self redirect: %d chains", len(chains))
+	}
+}
+
+func TestRedirectChainsFollowedBy(t *testing.T) {
+	// The chains agree with single-step lookups.
+	l := &harfile.Log{Entries: []*harfile.Entry{
+		redirectEntry(0, "https://example.com/1", "/2"),
+		redirectEntry(1, "https://example.com/2", "/3"),
+		redirectEntry(2, "https://example.com/3", ""),
+	}}
+	chain := RedirectChains(l)[0].Entries
+	for i, e := range chain[:len(chain)-1] {
+		if got := e.FollowedBy(l); got != chain[i+1] {
+			t.Errorf("%s followed by %v, want %s", e.Request.URL, got, chain[i+1].Request.URL)
+		}
+	}
+	if chain[len(chain)-1].FollowedBy(l) != nil {
+		t.Error("final entry followed by another")
+	}
+}
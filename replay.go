@@ -0,0 +1,223 @@
+package harkit
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// MatchOptions selects how [NewReplayHandler] pairs incoming requests with
+// recorded entries. Method and URL path always have to match.
+type MatchOptions struct {
+	IgnoreHost        bool     // Ignore the scheme and host, e.g. to replay through httptest.NewServer.
+	IgnoreQueryParams []string // Query parameters left out of the comparison, e.g. cache busters.
+	MatchBody         bool     // Also require identical request bodies.
+	Once              bool     // Replay each entry at most once, so that extra calls get a 404.
+	PaceEvents        bool     // Send the recorded events of event streams as spaced when recorded, rather than at once.
+}
+
+// hopByHopHeaders are not replayed, being specific to the recorded
+// connection.
+var hopByHopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// replayHandler serves the responses of recorded entries.
+type replayHandler struct {
+	matcher Matcher
+	once    bool
+	pace    bool
+	index   *harfile.EntryIndex
+	keys    []string
+
+	mu       sync.Mutex
+	consumed map[*harfile.Entry]bool
+}
+
+// NewReplayHandler returns a handler answering each request with the
+// recorded response of the first matching entry of har, in recorded order.
+// Headers are replayed except hop-by-hop ones, and the body is decoded, so
+// Content-Encoding and Content-Length describe the replayed body rather than
+// the recorded one.
+//
+// Requests without a match get a 404 whose body lists the closest recorded
+// requests.
+func NewReplayHandler(har *harfile.HAR, opts MatchOptions) http.Handler {
+	h := &replayHandler{
+		matcher: Matcher{
+			Method:            true,
+			Scheme:            !opts.IgnoreHost,
+			Host:              !opts.IgnoreHost,
+			Path:              true,
+			Query:             true,
+			IgnoreQueryParams: opts.IgnoreQueryParams,
+			Body:              opts.MatchBody,
+		},
+		once:     opts.Once,
+		pace:     opts.PaceEvents,
+		consumed: make(map[*harfile.Entry]bool),
+	}
+	log := &harfile.Log{}
+	if har != nil && har.Log != nil {
+		log = har.Log
+	}
+	h.index = log.Index(h.matcher)
+	seen := make(map[string]bool)
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		if fp := h.matcher.Fingerprint(e.Request); !seen[fp] {
+			seen[fp] = true
+			h.keys = append(h.keys, fp)
+		}
+	}
+	return h
+}
+
+func (h *replayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, err := h.matcher.RequestFingerprint(r)
+	if err != nil {
+		http.Error(w, "harkit: read request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry, reason := h.match(key)
+	if entry == nil {
+		h.notFound(w, key, reason)
+		return
+	}
+	if len(entry.EventStreamMessages) > 0 {
+		writeEventStream(w, r, entry, h.pace)
+		return
+	}
+	writeRecorded(w, entry.Response)
+}
+
+// match returns the first entry matching key, marking it consumed in Once
+// mode, or explains why there is none.
+func (h *replayHandler) match(key string) (*harfile.Entry, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	candidates := h.index.LookupFingerprint(key)
+	if len(candidates) == 0 {
+		return nil, "no recorded request matches"
+	}
+	for _, e := range candidates {
+		if e.Response == nil || h.once && h.consumed[e] {
+			continue
+		}
+		h.consumed[e] = true
+		return e, ""
+	}
+	return nil, fmt.Sprintf("the %d matching recorded request(s) were already replayed", len(candidates))
+}
+
+// notFound answers with a 404 listing the recorded requests closest to key.
+func (h *replayHandler) notFound(w http.ResponseWriter, key, reason string) {
+	type candidate struct {
+		key      string
+		distance int
+	}
+	var closest []candidate
+	for _, k := range h.keys {
+		closest = append(closest, candidate{k, editDistance(key, k)})
+	}
+	slices.SortFunc(closest, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.key, b.key)
+	})
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "harkit: %s: %s\n", reason, key)
+	if len(closest) > 0 {
+		b.WriteString("closest recorded requests:\n")
+		for _, c := range closest[:min(len(closest), 3)] {
+			fmt.Fprintf(&b, "  %s\n", c.key)
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(b.Bytes())
+}
+
+// writeRecorded writes resp to w.
+func writeRecorded(w http.ResponseWriter, resp *harfile.Response) {
+	status := int(resp.Status)
+	if status == http.StatusSwitchingProtocols {
+		http.Error(w, "harkit: recorded protocol upgrades, such as WebSockets, cannot be replayed", http.StatusNotImplemented)
+		return
+	}
+	if status < 100 || status > 999 {
+		http.Error(w, "harkit: recorded response has no status", http.StatusBadGateway)
+		return
+	}
+
+	header, contentEncoding := recordedHeader(resp)
+	var body []byte
+	if resp.Content != nil {
+		var err error
+		if body, err = resp.Content.DecodeBody(contentEncoding); err != nil {
+			http.Error(w, "harkit: decode recorded body: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	maps.Copy(w.Header(), header)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// recordedHeader returns the headers of resp to replay, without
+// Content-Encoding, Content-Length, hop-by-hop and pseudo-headers, and the
+// recorded Content-Encoding.
+func recordedHeader(resp *harfile.Response) (http.Header, string) {
+	var contentEncoding string
+	header := make(http.Header, len(resp.Headers))
+	for _, hdr := range resp.Headers {
+		if hdr == nil || strings.HasPrefix(hdr.Name, ":") {
+			continue
+		}
+		name := http.CanonicalHeaderKey(hdr.Name)
+		switch {
+		case name == "Content-Encoding":
+			contentEncoding = hdr.Value
+		case name == "Content-Length", slices.Contains(hopByHopHeaders, name):
+		default:
+			header.Add(name, hdr.Value)
+		}
+	}
+	return header, contentEncoding
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
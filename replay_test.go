@@ -0,0 +1,153 @@
+package harkit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// replayEntry returns an entry answering method and url with status and text.
+func replayEntry(method, url string, status int64, text string, headers ...string) *harfile.Entry {
+	resp := &harfile.Response{Status: status, HTTPVersion: "HTTP/1.1", Content: &harfile.Content{Size: int64(len(text)), MimeType: "text/plain", Text: text}}
+	for i := 0; i+1 < len(headers); i += 2 {
+		resp.Headers = append(resp.Headers, &harfile.NameValuePair{Name: headers[i], Value: headers[i+1]})
+	}
+	return &harfile.Entry{Request: &harfile.Request{Method: method, URL: url}, Response: resp}
+}
+
+func replay(t *testing.T, h http.Handler, method, target, body string) (*http.Response, string) {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req, err := http.NewRequest(method, srv.URL+target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestReplayHandler(t *testing.T) {
+	har := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{
+		replayEntry("GET", "https://api.example.com/users?id=1&_=123", 200, "first"),
+		replayEntry("GET", "https://api.example.com/users?id=1&_=456", 200, "second"),
+		replayEntry("GET", "https://api.example.com/users?id=2", 200, "other"),
+		nil,
+	}}}
+	h := NewReplayHandler(har, MatchOptions{IgnoreHost: true, IgnoreQueryParams: []string{"_"}})
+	for range 2 {
+		if _, body := replay(t, h, "GET", "/users?_=9&id=1", ""); body != "first" {
+			t.Errorf("body = %q, want the first match every time", body)
+		}
+	}
+	if _, body := replay(t, h, "GET", "/users?id=2", ""); body != "other" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestReplayHandlerOnce(t *testing.T) {
+	har := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{
+		replayEntry("POST", "http://x/jobs", 201, "job 1"),
+		replayEntry("POST", "http://x/jobs", 201, "job 2"),
+	}}}
+	h := NewReplayHandler(har, MatchOptions{IgnoreHost: true, Once: true})
+	for _, want := range []string{"job 1", "job 2"} {
+		if resp, body := replay(t, h, "POST", "/jobs", ""); resp.StatusCode != 201 || body != want {
+			t.Errorf("got %d %q, want %q", resp.StatusCode, body, want)
+		}
+	}
+	resp, body := replay(t, h, "POST", "/jobs", "")
+	if resp.StatusCode != 404 || !strings.Contains(body, "the 2 matching recorded request(s) were already replayed") {
+		t.Errorf("third call = %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestReplayHandlerMatchBody(t *testing.T) {
+	e := replayEntry("POST", "http://x/q", 200, "a")
+	e.Request.PostData = &harfile.PostData{MimeType: "application/json", Text: `{"q":"a"}`}
+	h := NewReplayHandler(&harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e}}}, MatchOptions{IgnoreHost: true, MatchBody: true})
+	if _, body := replay(t, h, "POST", "/q", `{"q":"a"}`); body != "a" {
+		t.Errorf("body = %q", body)
+	}
+	resp, body := replay(t, h, "POST", "/q", `{"q":"b"}`)
+	if resp.StatusCode != 404 || !strings.Contains(body, "harkit: no recorded request matches: POST /q\nbody: ") {
+		t.Errorf("other body = %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestReplayHandlerNotFound(t *testing.T) {
+	har := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{
+		replayEntry("GET", "http://x/users/1", 200, ""),
+		replayEntry("GET", "http://x/users/2", 200, ""),
+		replayEntry("GET", "http://x/orders", 200, ""),
+		replayEntry("DELETE", "http://x/users/1", 204, ""),
+	}}}
+	resp, body := replay(t, NewReplayHandler(har, MatchOptions{IgnoreHost: true}), "GET", "/users/3", "")
+	if resp.StatusCode != 404 || resp.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("response = %d %v", resp.StatusCode, resp.Header)
+	}
+	want := "harkit: no recorded request matches: GET /users/3\n" +
+		"closest recorded requests:\n" +
+		"  GET /users/1\n" +
+		"  GET /users/2\n" +
+		"  DELETE /users/1\n"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	if resp, _ := replay(t, NewReplayHandler(nil, MatchOptions{}), "GET", "/", ""); resp.StatusCode != 404 {
+		t.Errorf("empty HAR answered %d", resp.StatusCode)
+	}
+}
+
+func TestReplayHandlerHeaders(t *testing.T) {
+	text := strings.Repeat("compressed ", 10)
+	e := replayEntry("GET", "http://x/", 200, text,
+		"Content-Type", "text/plain",
+		"Content-Encoding", "gzip",
+		"Content-Length", "3",
+		"Connection", "keep-alive",
+		"Keep-Alive", "timeout=5",
+		"Transfer-Encoding", "chunked",
+		"Upgrade", "h2c",
+		":status", "200",
+		"set-cookie", "a=1",
+		"Set-Cookie", "b=2",
+	)
+	resp, body := replay(t, NewReplayHandler(&harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e}}}, MatchOptions{IgnoreHost: true}), "GET", "/", "")
+	if body != text {
+		t.Errorf("body = %q", body)
+	}
+	for _, name := range []string{"Content-Encoding", "Keep-Alive", "Upgrade", ":status"} {
+		if v := resp.Header.Get(name); v != "" {
+			t.Errorf("%s replayed as %q", name, v)
+		}
+	}
+	if resp.ContentLength != int64(len(text)) || len(resp.TransferEncoding) != 0 {
+		t.Errorf("ContentLength %d, TransferEncoding %v", resp.ContentLength, resp.TransferEncoding)
+	}
+	if got := resp.Header.Values("Set-Cookie"); len(got) != 2 || got[0] != "a=1" || got[1] != "b=2" {
+		t.Errorf("Set-Cookie = %q", got)
+	}
+}
+
+func TestReplayHandlerBadRecording(t *testing.T) {
+	noStatus := replayEntry("GET", "http://x/a", 0, "")
+	badBody := replayEntry("GET", "http://x/b", 200, "not base64!")
+	badBody.Response.Content.Encoding = "base64"
+	h := NewReplayHandler(&harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{noStatus, badBody}}}, MatchOptions{IgnoreHost: true})
+	for _, target := range []string{"/a", "/b"} {
+		if resp, _ := replay(t, h, "GET", target, ""); resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("%s answered %d, want 502", target, resp.StatusCode)
+		}
+	}
+}
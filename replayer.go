@@ -0,0 +1,166 @@
+package harkit
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// ReplayOptions configures [Replayer.Replay].
+type ReplayOptions struct {
+	Scheme      string      // Scheme replacing the recorded one, e.g. "http" for a local target.
+	Host        string      // Host, with optional port, replacing the recorded one.
+	Concurrency int         // Maximum number of requests in flight. Zero means 1.
+	Pacing      bool        // Honor the recorded gaps between request starts instead of sending as fast as possible.
+	Headers     http.Header // Headers set on every request, replacing the recorded values.
+
+	// Rewriters are applied to each request after the overrides above and
+	// before it is sent, e.g. harfile.BearerToken to replace a stale or
+	// redacted Authorization header, or a signer hashing the final payload.
+	Rewriters []harfile.RequestRewriter
+
+	Progress harfile.Progress // Receives a StageReplay step as each request completes. Nil means harfile.NoProgress.
+}
+
+// Replayer sends the requests of a log again. The zero value uses
+// [http.DefaultTransport].
+type Replayer struct {
+	Transport http.RoundTripper // Transport sending the requests, wrapped by a recording Transport.
+}
+
+// Replay sends the requests of log, in recorded order, and returns the
+// recording of the replay, ready to be compared with the original through
+// [Diff]. Redirects are not followed, since the log holds them as separate
+// entries.
+//
+// Failed requests do not stop the replay; their errors are joined in the
+// returned error. When ctx is canceled, no new request is sent, the ones in
+// flight are aborted, and the entries recorded so far are returned with the
+// context error.
+func (rp *Replayer) Replay(ctx context.Context, log *harfile.Log, opts ReplayOptions) (*harfile.HAR, error) {
+	recorder := NewTransport(rp.Transport)
+	client := &http.Client{
+		Transport: recorder,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, max(opts.Concurrency, 1))
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	progress := cmp.Or(opts.Progress, harfile.NoProgress)
+	var total int64
+	for _, e := range log.Entries {
+		if e != nil && e.Request != nil {
+			total++
+		}
+	}
+	var done atomic.Int64
+	step := func() {
+		progress.Step(StageReplay, done.Add(1), total)
+	}
+
+	start := time.Now()
+	var first time.Time
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		if first.IsZero() {
+			first = e.StartedDateTime
+		}
+		if opts.Pacing {
+			if err := sleepUntil(ctx, start.Add(e.StartedDateTime.Sub(first))); err != nil {
+				break
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		req, err := replayRequest(ctx, e.Request, opts)
+		if err != nil {
+			<-sem
+			fail(err)
+			step()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer step()
+			resp, err := client.Do(req)
+			if err != nil {
+				fail(err)
+				return
+			}
+			// The recording is complete once the body is drained and closed.
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	har := recorder.HAR()
+	har.Log.SortEntries()
+	if err := ctx.Err(); err != nil {
+		return har, err
+	}
+	return har, errors.Join(errs...)
+}
+
+// replayRequest converts a recorded request, applying the overrides of opts.
+func replayRequest(ctx context.Context, r *harfile.Request, opts ReplayOptions) (*http.Request, error) {
+	req, err := r.ToHTTP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Scheme != "" {
+		req.URL.Scheme = opts.Scheme
+	}
+	if opts.Host != "" {
+		req.URL.Host = opts.Host
+		req.Host = opts.Host
+	}
+	for name, values := range opts.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if err := harfile.RewriteRequest(ctx, req, opts.Rewriters...); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// sleepUntil waits until t or until ctx is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,254 @@
+package harkit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// replayLog returns n GET requests to https://recorded.example/<i>, started
+// gap apart.
+func replayLog(n int, gap time.Duration) *harfile.Log {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := &harfile.Log{}
+	for i := range n {
+		l.Entries = append(l.Entries, &harfile.Entry{
+			StartedDateTime: start.Add(time.Duration(i) * gap),
+			Request: &harfile.Request{
+				Method:  "GET",
+				URL:     fmt.Sprintf("https://recorded.example/%d", i),
+				Headers: []*harfile.NameValuePair{{Name: "Authorization", Value: "Bearer old"}},
+			},
+		})
+	}
+	return l
+}
+
+// replayTarget returns a server, its host and scheme overrides.
+func replayTarget(t *testing.T, h http.HandlerFunc) ReplayOptions {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return ReplayOptions{Scheme: u.Scheme, Host: u.Host}
+}
+
+func TestReplayer(t *testing.T) {
+	var auth atomic.Value
+	opts := replayTarget(t, func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+		if r.URL.Path == "/1" {
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	})
+	opts.Headers = http.Header{"authorization": {"Bearer new"}}
+	log := replayLog(3, time.Millisecond)
+	log.Entries = append(log.Entries, nil, &harfile.Entry{})
+
+	har, err := (&Replayer{}).Replay(context.Background(), log, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := har.Log.Entries
+	if len(entries) != 3 {
+		t.Fatalf("%d entries, want 3", len(entries))
+	}
+	for i, e := range entries {
+		if e.Request.URL != fmt.Sprintf("%s://%s/%d", opts.Scheme, opts.Host, i) {
+			t.Errorf("entry %d: URL %s", i, e.Request.URL)
+		}
+	}
+	if entries[1].Response.Status != http.StatusFound {
+		t.Errorf("redirect followed: status %d", entries[1].Response.Status)
+	}
+	if got := auth.Load(); got != "Bearer new" {
+		t.Errorf("Authorization = %v", got)
+	}
+}
+
+func TestReplayerConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	opts := replayTarget(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+	for _, concurrency := range []int{0, 3} {
+		peak.Store(0)
+		opts.Concurrency = concurrency
+		har, err := (&Replayer{}).Replay(context.Background(), replayLog(9, 0), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(har.Log.Entries) != 9 {
+			t.Errorf("concurrency %d: %d entries", concurrency, len(har.Log.Entries))
+		}
+		if p := peak.Load(); p > int32(max(concurrency, 1)) || (concurrency > 1 && p < 2) {
+			t.Errorf("concurrency %d: %d requests in flight at once", concurrency, p)
+		}
+	}
+}
+
+func TestReplayerPacing(t *testing.T) {
+	opts := replayTarget(t, func(w http.ResponseWriter, r *http.Request) {})
+	log := replayLog(3, 50*time.Millisecond)
+
+	start := time.Now()
+	if _, err := (&Replayer{}).Replay(context.Background(), log, opts); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= 100*time.Millisecond {
+		t.Errorf("unpaced replay took %v", d)
+	}
+
+	opts.Pacing = true
+	start = time.Now()
+	har, err := (&Replayer{}).Replay(context.Background(), log, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("paced replay took %v, want at least 100ms", d)
+	}
+	e := har.Log.Entries
+	if gap := e[2].StartedDateTime.Sub(e[0].StartedDateTime); gap < 100*time.Millisecond {
+		t.Errorf("replayed requests started %v apart", gap)
+	}
+}
+
+func TestReplayerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var served atomic.Int32
+	opts := replayTarget(t, func(w http.ResponseWriter, r *http.Request) {
+		if served.Add(1) == 2 {
+			// Canceled in flight: the request is aborted.
+			cancel()
+			<-r.Context().Done()
+		}
+	})
+	opts.Pacing = true
+	start := time.Now()
+	har, err := (&Replayer{}).Replay(ctx, replayLog(5, 20*time.Millisecond), opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	// The aborted request is recorded as failed.
+	if n := len(har.Log.Entries); n != 2 || served.Load() != 2 {
+		t.Fatalf("%d entries recorded of %d requests, want 2", n, served.Load())
+	}
+	if resp := har.Log.Entries[1].Response; resp.Status != 0 || resp.Error == "" {
+		t.Errorf("aborted request recorded with status %d, error %q", resp.Status, resp.Error)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("canceled replay took %v", d)
+	}
+}
+
+func TestReplayerErrors(t *testing.T) {
+	opts := replayTarget(t, func(w http.ResponseWriter, r *http.Request) {})
+	log := replayLog(2, 0)
+	log.Entries[0].Request.URL = "://bad"
+	har, err := (&Replayer{}).Replay(context.Background(), log, opts)
+	if err == nil {
+		t.Fatal("no error for an invalid URL")
+	}
+	if len(har.Log.Entries) != 1 {
+		t.Errorf("%d entries, want the valid request replayed", len(har.Log.Entries))
+	}
+}
+
+// TestReplayRedactedRewrite replays a redacted recording: without
+// rewriters the placeholder is sent as is, with them the server sees a
+// fresh token and a signature of the body actually sent.
+func TestReplayRedactedRewrite(t *testing.T) {
+	type seen struct{ auth, signature, body string }
+	var mu sync.Mutex
+	var got []seen
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(body)) {
+			t.Errorf("ContentLength %d for a body of %d bytes", r.ContentLength, len(body))
+		}
+		mu.Lock()
+		got = append(got, seen{r.Header.Get("Authorization"), r.Header.Get("X-Signature"), string(body)})
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	h := harfile.NewLog().Entries(harfile.NewEntry().
+		Post("https://api.example.com/orders").
+		Header("Authorization", "Bearer stale").
+		Header("X-Signature", "stale").
+		Body("application/json", []byte(`{"nonce":"1"}`)).
+		Build()).HAR()
+	Redact(h, RedactOptions{Headers: []string{"Authorization", "X-Signature"}})
+	if a := h.Log.Entries[0].Request.HeaderValues("Authorization"); len(a) != 1 || a[0] != DefaultPlaceholder {
+		t.Fatalf("redacted Authorization = %q", a)
+	}
+
+	sign := harfile.RequestRewriterFunc(func(_ context.Context, req *http.Request) error {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		data = []byte(strings.Replace(string(data), `"nonce":"1"`, `"nonce":"22"`, 1))
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(data)
+		req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+		req.Body = io.NopCloser(strings.NewReader(string(data)))
+		return nil
+	})
+	rp := &Replayer{}
+	base := ReplayOptions{Scheme: target.Scheme, Host: target.Host}
+	if _, err := rp.Replay(context.Background(), h.Log, base); err != nil {
+		t.Fatal(err)
+	}
+	opts := base
+	opts.Rewriters = []harfile.RequestRewriter{harfile.BearerToken("fresh"), sign}
+	replayed, err := rp.Replay(context.Background(), h.Log, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`{"nonce":"22"}`))
+	want := []seen{
+		{DefaultPlaceholder, DefaultPlaceholder, `{"nonce":"1"}`},
+		{"Bearer fresh", hex.EncodeToString(mac.Sum(nil)), `{"nonce":"22"}`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("server saw %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if pd := replayed.Log.Entries[0].Request.PostData; pd == nil || pd.Text != `{"nonce":"22"}` {
+		t.Errorf("replay recorded %+v", pd)
+	}
+}
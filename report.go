@@ -0,0 +1,433 @@
+package harkit
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+
+	"github.com/Mathious6/harkit/harfile"
+	"github.com/Mathious6/harkit/internal/markdown"
+)
+
+// ReportFormat selects the output of [RenderReport].
+type ReportFormat int
+
+const (
+	ReportMarkdown ReportFormat = iota // GitHub flavored Markdown.
+	ReportText                         // Plain text with aligned columns.
+)
+
+// ReportColumn is a column of the [RenderReport] entries table to sort by.
+type ReportColumn string
+
+const (
+	SortByOrder  ReportColumn = ""       // Log order.
+	SortByMethod ReportColumn = "method" // Ascending.
+	SortByURL    ReportColumn = "url"    // Ascending.
+	SortByStatus ReportColumn = "status" // Ascending.
+	SortBySize   ReportColumn = "size"   // Largest first.
+	SortByTime   ReportColumn = "time"   // Slowest first.
+)
+
+// ReportOptions configures [RenderReport].
+type ReportOptions struct {
+	Format     ReportFormat
+	SortBy     ReportColumn // Order of the entries table.
+	Limit      int          // Rows of the entries table. Zero means every entry.
+	URLWidth   int          // Characters of URL shown, longer ones being truncated. Zero means 60.
+	ShowQuery  bool         // Keep query strings in URLs.
+	Slowest    int          // Size of a section listing the slowest entries. Zero leaves it out.
+	Errors     bool         // Add a section listing the failed requests and error responses.
+	Duplicates int          // Size of a section listing the responses with identical bodies, see [FindDuplicateBodies]. Zero leaves it out.
+	SniffMime  bool         // Count content types as sniffed from the bodies, see [harfile.Response.SniffedMimeType], rather than as declared.
+}
+
+// RenderReport writes a human readable summary of log: its creator,
+// browser, page count, protocols and content types, the bytes fetched by
+// 206 Partial Content responses, see [Ranges], a table of the entries with their
+// method, URL, status, response size and time, and the sections selected by
+// opts.
+//
+// In plain text, columns are aligned by display width, so wide characters
+// such as CJK ideographs count as two columns.
+func RenderReport(w io.Writer, log *harfile.Log, opts ReportOptions) error {
+	if opts.URLWidth <= 0 {
+		opts.URLWidth = 60
+	}
+	r := &reporter{opts: opts}
+	entries := slices.DeleteFunc(slices.Clone(log.Entries), func(e *harfile.Entry) bool { return e == nil })
+
+	r.heading("HAR report")
+	if log.Creator != nil {
+		r.field("Creator", strings.TrimSpace(log.Creator.Name+" "+log.Creator.Version))
+	}
+	if log.Browser != nil {
+		r.field("Browser", strings.TrimSpace(log.Browser.Name+" "+log.Browser.Version))
+	}
+	r.field("Pages", strconv.Itoa(len(log.Pages)))
+	r.field("Entries", strconv.Itoa(len(entries)))
+	if protocols := reportProtocols(entries); protocols != "" {
+		r.field("Protocols", protocols)
+	}
+	if types := reportContentTypes(entries, opts.SniffMime); types != "" {
+		r.field("Content types", types)
+	}
+	if ranges := reportRanges(log); ranges != "" {
+		r.field("Ranges", ranges)
+	}
+
+	sorted := slices.Clone(entries)
+	sortReportEntries(sorted, opts.SortBy)
+	r.section("Entries")
+	r.table(sorted, opts.Limit)
+
+	if opts.Slowest > 0 && len(entries) > 0 {
+		slowest := slices.Clone(entries)
+		sortReportEntries(slowest, SortByTime)
+		r.section("Slowest requests")
+		r.table(slowest[:min(opts.Slowest, len(slowest))], 0)
+	}
+	if opts.Errors {
+		failed := slices.DeleteFunc(slices.Clone(entries), func(e *harfile.Entry) bool {
+			return e.Response != nil && e.Response.Status >= 100 && e.Response.Status < 400
+		})
+		r.section("Errors")
+		if len(failed) == 0 {
+			r.b.WriteString("None.\n")
+		} else {
+			r.table(failed, 0)
+		}
+	}
+
+	if opts.Duplicates > 0 {
+		groups := FindDuplicateBodies(log)
+		r.section("Duplicate bodies")
+		if len(groups) == 0 {
+			r.b.WriteString("None.\n")
+		} else {
+			r.duplicates(log, groups[:min(opts.Duplicates, len(groups))])
+		}
+	}
+
+	_, err := io.WriteString(w, r.b.String())
+	return err
+}
+
+// reportContentTypes counts the entries of each response media type,
+// declared or sniffed, e.g. "application/json (4), text/html (1)".
+func reportContentTypes(entries []*harfile.Entry, sniff bool) string {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		if e.Response == nil || e.Response.Content == nil {
+			continue
+		}
+		mimeType := e.Response.Content.MimeType
+		if sniff {
+			mimeType = e.Response.SniffedMimeType()
+		}
+		if mediaType, _, _ := mime.ParseMediaType(mimeType); mediaType != "" {
+			counts[mediaType]++
+		}
+	}
+	var parts []string
+	for _, t := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, t+" ("+strconv.Itoa(counts[t])+")")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reportRanges sums up the partial responses of log, counting the bytes
+// fetched again once, e.g. "2 resources, 1.5 MiB distinct, 256.0 KiB
+// refetched".
+func reportRanges(log *harfile.Log) string {
+	reports := Ranges(log)
+	if len(reports) == 0 {
+		return ""
+	}
+	var distinct, refetched int64
+	for _, rep := range reports {
+		distinct += rep.Distinct
+		refetched += rep.Refetched
+	}
+	noun := "resources"
+	if len(reports) == 1 {
+		noun = "resource"
+	}
+	return fmt.Sprintf("%d %s, %s distinct, %s refetched", len(reports), noun, formatBytes(distinct), formatBytes(refetched))
+}
+
+// reportProtocols counts the entries of each response protocol, in their
+// canonical spelling, e.g. "HTTP/1.1 (3), HTTP/2.0 (12)".
+func reportProtocols(entries []*harfile.Entry) string {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		if e.Response == nil {
+			continue
+		}
+		version := e.Response.Protocol().String()
+		if version == "" {
+			version = cmp.Or(strings.TrimSpace(e.Response.HTTPVersion), "unknown")
+		}
+		counts[version]++
+	}
+	var parts []string
+	for _, v := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, v+" ("+strconv.Itoa(counts[v])+")")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortReportEntries(entries []*harfile.Entry, by ReportColumn) {
+	var key func(a, b *harfile.Entry) int
+	switch by {
+	case SortByMethod:
+		key = func(a, b *harfile.Entry) int { return strings.Compare(reportMethod(a), reportMethod(b)) }
+	case SortByURL:
+		key = func(a, b *harfile.Entry) int { return strings.Compare(reportURL(a, true), reportURL(b, true)) }
+	case SortByStatus:
+		key = func(a, b *harfile.Entry) int { return cmp.Compare(status(a), status(b)) }
+	case SortBySize:
+		key = func(a, b *harfile.Entry) int { return cmp.Compare(reportSize(b), reportSize(a)) }
+	case SortByTime:
+		key = func(a, b *harfile.Entry) int { return cmp.Compare(b.Time, a.Time) }
+	default:
+		return
+	}
+	slices.SortStableFunc(entries, key)
+}
+
+type reporter struct {
+	opts ReportOptions
+	b    strings.Builder
+}
+
+func (r *reporter) heading(title string) {
+	if r.opts.Format == ReportText {
+		fmt.Fprintf(&r.b, "%s\n%s\n\n", title, strings.Repeat("=", len(title)))
+		return
+	}
+	fmt.Fprintf(&r.b, "# %s\n\n", title)
+}
+
+func (r *reporter) field(name, value string) {
+	if r.opts.Format == ReportText {
+		fmt.Fprintf(&r.b, "%-14s %s\n", name+":", value)
+		return
+	}
+	fmt.Fprintf(&r.b, "- **%s:** %s\n", name, markdown.Escape(value))
+}
+
+func (r *reporter) section(title string) {
+	if r.opts.Format == ReportText {
+		fmt.Fprintf(&r.b, "\n%s\n%s\n", title, strings.Repeat("-", len(title)))
+		return
+	}
+	fmt.Fprintf(&r.b, "\n## %s\n\n", title)
+}
+
+// table writes a row per entry, up to limit rows when positive, noting how
+// many were left out.
+func (r *reporter) table(entries []*harfile.Entry, limit int) {
+	omitted := 0
+	if limit > 0 && len(entries) > limit {
+		omitted = len(entries) - limit
+		entries = entries[:limit]
+	}
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		size := "-"
+		if n := reportSize(e); n >= 0 {
+			size = formatBytes(n)
+		}
+		statusText := "-"
+		if s := status(e); s != 0 {
+			statusText = strconv.FormatInt(s, 10)
+		}
+		rows[i] = []string{
+			reportMethod(e),
+			truncateWidth(reportURL(e, r.opts.ShowQuery), r.opts.URLWidth),
+			statusText,
+			size,
+			strconv.FormatFloat(e.Time, 'f', 0, 64) + " ms",
+		}
+	}
+
+	r.grid([]string{"Method", "URL", "Status", "Size", "Time"}, rows, 1, 2)
+	if omitted > 0 {
+		fmt.Fprintf(&r.b, "\n… and %d more.\n", omitted)
+	}
+}
+
+// duplicates writes a row per group of identical bodies, with the URL of
+// its first entry.
+func (r *reporter) duplicates(log *harfile.Log, groups []DuplicateGroup) {
+	rows := make([][]string, len(groups))
+	for i, g := range groups {
+		rows[i] = []string{
+			truncateWidth(reportURL(log.Entries[g.Entries[0]], r.opts.ShowQuery), r.opts.URLWidth),
+			g.Hash[:12],
+			strconv.Itoa(len(g.Entries)),
+			formatBytes(g.Size),
+			formatBytes(g.Wasted),
+		}
+	}
+	r.grid([]string{"URL", "SHA-256", "Count", "Size", "Wasted"}, rows, 0, 2)
+}
+
+// grid writes a table whose columns from index right on are right aligned
+// numbers. In Markdown, the column at index code is set in code.
+func (r *reporter) grid(header []string, rows [][]string, code, right int) {
+	if r.opts.Format == ReportText {
+		widths := make([]int, len(header))
+		for _, row := range append([][]string{header}, rows...) {
+			for i, cell := range row {
+				widths[i] = max(widths[i], displayWidth(cell))
+			}
+		}
+		for _, row := range append([][]string{header}, rows...) {
+			for i, cell := range row {
+				pad := strings.Repeat(" ", widths[i]-displayWidth(cell))
+				if i > 0 {
+					r.b.WriteString("  ")
+				}
+				if i >= right {
+					r.b.WriteString(pad + cell)
+				} else if i < len(row)-1 {
+					r.b.WriteString(cell + pad)
+				} else {
+					r.b.WriteString(cell)
+				}
+			}
+			r.b.WriteString("\n")
+		}
+		return
+	}
+	r.b.WriteString("|")
+	for _, h := range header {
+		r.b.WriteString(" " + h + " |")
+	}
+	r.b.WriteString("\n|")
+	for i := range header {
+		if i >= right {
+			r.b.WriteString("---:|")
+		} else {
+			r.b.WriteString("---|")
+		}
+	}
+	r.b.WriteString("\n")
+	for _, row := range rows {
+		r.b.WriteString("|")
+		for i, cell := range row {
+			switch {
+			case i >= right:
+			case i == code:
+				cell = markdown.Code(cell)
+			default:
+				cell = markdown.Escape(cell)
+			}
+			r.b.WriteString(" " + cell + " |")
+		}
+		r.b.WriteString("\n")
+	}
+}
+
+func reportMethod(e *harfile.Entry) string {
+	if e.Request == nil {
+		return ""
+	}
+	return e.Request.Method
+}
+
+// reportURL returns the URL of e, without query string unless query.
+func reportURL(e *harfile.Entry, query bool) string {
+	if e.Request == nil {
+		return ""
+	}
+	u := e.Request.URL
+	if !query {
+		if i := strings.IndexAny(u, "?#"); i >= 0 && u[i] == '?' {
+			rest := u[i:]
+			u = u[:i]
+			if j := strings.IndexByte(rest, '#'); j >= 0 {
+				u += rest[j:]
+			}
+		}
+	}
+	return u
+}
+
+// reportSize returns the response body size of e, or -1 when unknown.
+func reportSize(e *harfile.Entry) int64 {
+	if size, ok := e.Response.KnownBodySize(); ok {
+		return size
+	}
+	if e.Response == nil {
+		return harfile.SizeUnknown
+	}
+	if size, ok := e.Response.Content.KnownSize(); ok {
+		return size
+	}
+	return harfile.SizeUnknown
+}
+
+// displayWidth returns the number of terminal columns s occupies: wide and
+// fullwidth characters take two and combining marks none.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == '\u200b':
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// truncateWidth shortens s to at most w columns, ending with an ellipsis
+// when truncated.
+func truncateWidth(s string, w int) string {
+	if displayWidth(s) <= w {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		rw := runeWidth(r)
+		if n+rw > w-1 {
+			break
+		}
+		b.WriteRune(r)
+		n += rw
+	}
+	return b.String() + "…"
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,124 @@
+package harkit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func reportLog() *harfile.Log {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(method, url string, status, size int64, ms float64) *harfile.Entry {
+		e := exportEntry(method, url, start, "", nil, status)
+		e.Response.BodySize, e.Time = size, ms
+		return e
+	}
+	unknown := entry("GET", "https://example.com/stream", 200, -1, 12)
+	unknown.Response.Content.Size = -1
+	return &harfile.Log{
+		Creator: &harfile.Creator{Name: "harkit", Version: "1.0"},
+		Browser: &harfile.Browser{Name: "Firefox", Version: "125.0"},
+		Pages:   []*harfile.Page{{ID: "page_1"}},
+		Entries: []*harfile.Entry{
+			entry("GET", "https://example.com/?q=1", 200, 512, 40),
+			entry("GET", "https://例え.jp/商品/一覧?page=2#top", 200, 2048, 130),
+			entry("POST", "https://example.com/café/re\u0301sume|draft", 500, 0, 85),
+			entry("GET", "https://ｅｘａｍｐｌｅ.com/ｆｕｌｌｗｉｄｔｈ/ｐａｔｈ/that/is/long", 404, 3<<20, 7),
+			unknown,
+			{Request: &harfile.Request{Method: "GET", URL: "https://example.com/failed"}, Time: 3},
+			nil,
+		},
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	tests := []struct {
+		golden string
+		opts   ReportOptions
+	}{
+		{"report.md", ReportOptions{Slowest: 2, Errors: true}},
+		{"report.txt", ReportOptions{Format: ReportText, URLWidth: 24, ShowQuery: true, Slowest: 2, Errors: true}},
+		{"report_sorted.txt", ReportOptions{Format: ReportText, SortBy: SortBySize, Limit: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var b strings.Builder
+			if err := RenderReport(&b, reportLog(), tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, []byte(b.String()))
+		})
+	}
+}
+
+// TestRenderReportAlignment checks that every line of a plain text table
+// takes the same number of columns, whatever the characters of its URL.
+func TestRenderReportAlignment(t *testing.T) {
+	var b strings.Builder
+	if err := RenderReport(&b, reportLog(), ReportOptions{Format: ReportText, URLWidth: 20}); err != nil {
+		t.Fatal(err)
+	}
+	_, table, _ := strings.Cut(b.String(), "-------\n")
+	lines := strings.Split(strings.TrimSuffix(table, "\n"), "\n")
+	for _, line := range lines {
+		if w := displayWidth(line); w != displayWidth(lines[0]) {
+			t.Errorf("%q is %d columns wide, want %d", line, w, displayWidth(lines[0]))
+		}
+	}
+}
+
+// TestRenderReportContentTypes checks the content types line, as declared
+// and as sniffed from the bodies.
+func TestRenderReportContentTypes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		respond(exportEntry("GET", "https://example.com/a", start, "", nil, 200), "text/html; charset=utf-8", []byte(`{"a":1}`)),
+		respond(exportEntry("GET", "https://example.com/b", start, "", nil, 200), "application/json", []byte(`{"b":2}`)),
+		exportEntry("GET", "https://example.com/c", start, "", nil, 204),
+	}}
+	tests := []struct {
+		opts ReportOptions
+		want string
+	}{
+		{ReportOptions{Format: ReportText}, "Content types: application/json (1), text/html (1)\n"},
+		{ReportOptions{Format: ReportText, SniffMime: true}, "Content types: application/json (2)\n"},
+		{ReportOptions{}, "- **Content types:** application/json (1), text/html (1)\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := RenderReport(&b, log, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(b.String(), tt.want) {
+			t.Errorf("%+v: report does not contain %q:\n%s", tt.opts, tt.want, b.String())
+		}
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		w    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"truncated", 5, "trun…"},
+		{"商品一覧", 8, "商品一覧"},
+		{"商品一覧", 6, "商品…"},
+		{"商品一覧", 5, "商品…"},
+		{"caf\u00e9s", 4, "caf…"},
+		{"cafe\u0301s", 5, "cafe\u0301s"},
+		{"e\u0301tude", 3, "e\u0301t…"},
+	}
+	for _, tt := range tests {
+		if got := truncateWidth(tt.s, tt.w); got != tt.want {
+			t.Errorf("truncateWidth(%q, %d) = %q, want %q", tt.s, tt.w, got, tt.want)
+		}
+		if got := truncateWidth(tt.s, tt.w); displayWidth(got) > tt.w {
+			t.Errorf("truncateWidth(%q, %d) is %d columns wide", tt.s, tt.w, displayWidth(got))
+		}
+	}
+}
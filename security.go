@@ -0,0 +1,187 @@
+package harkit
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Mathious6/harkit/haraudit"
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// SecurityCategory names a check of [AuditSecurity] and the findings it
+// reports.
+type SecurityCategory string
+
+// Checks of [AuditSecurity].
+const (
+	SecurityMixedContent SecurityCategory = "mixed-content"         // A plain HTTP request from a page served over HTTPS.
+	SecurityCookie       SecurityCategory = "insecure-cookie"       // A cookie set over HTTPS without the Secure flag, or flagged Secure over plain HTTP.
+	SecurityCredentials  SecurityCategory = "cleartext-credentials" // An Authorization or API key header sent over plain HTTP.
+	SecurityHSTS         SecurityCategory = "missing-hsts"          // A first-party HTTPS host sending no Strict-Transport-Security header.
+	SecurityDowngrade    SecurityCategory = "https-downgrade"       // A redirect from HTTPS to plain HTTP.
+)
+
+// SecurityCategories lists every check of [AuditSecurity], in the order
+// their findings are listed for an entry.
+var SecurityCategories = []SecurityCategory{
+	SecurityMixedContent, SecurityCookie, SecurityCredentials, SecurityHSTS, SecurityDowngrade,
+}
+
+// SecurityFinding is a weakness of a capture reported by [AuditSecurity].
+type SecurityFinding struct {
+	Category SecurityCategory `json:"category"`
+	Severity Severity         `json:"severity"`
+	Index    int              `json:"index"` // Index of the entry in Log.Entries.
+	URL      string           `json:"url"`   // Request URL.
+	Message  string           `json:"message"`
+}
+
+func (f SecurityFinding) String() string {
+	return fmt.Sprintf("entries[%d] %s: %s: %s: %s", f.Index, f.URL, f.Severity, f.Category, f.Message)
+}
+
+// AuditSecurity reviews the transport security of the entries of log and
+// returns its findings in entry order, e.g. to be encoded as JSON to gate
+// a CI job. Only the checks named by categories run; none runs them all:
+//   - SecurityMixedContent reports plain HTTP and WS requests of a page
+//     whose document, the first "document" entry of the page or else its
+//     first entry, was loaded over HTTPS: as warnings for images and media,
+//     which browsers upgrade or block, and as errors otherwise. Entries
+//     without a page are skipped;
+//   - SecurityCookie reports, as warnings, cookies set by HTTPS responses
+//     without the Secure flag and cookies set with it by plain HTTP
+//     responses, which browsers reject, and, as errors, cookies marked
+//     Secure sent by plain HTTP requests. Response cookies are taken from
+//     the cookie list, or else from the Set-Cookie headers;
+//   - SecurityCredentials reports, as errors, credential headers sent over
+//     plain HTTP, such as Authorization or X-Api-Key, see
+//     haraudit.IsSecretKey;
+//   - SecurityHSTS reports, as warnings, first-party HTTPS hosts none of
+//     whose responses carries a Strict-Transport-Security header, once per
+//     host on its first response. A host is first party when it shares the
+//     last two labels of the host of a page document, or of the first
+//     entry when no entry has a page;
+//   - SecurityDowngrade reports, as errors, redirects of HTTPS requests to
+//     plain HTTP URLs.
+func AuditSecurity(log *harfile.Log, categories ...SecurityCategory) []SecurityFinding {
+	enabled := func(c SecurityCategory) bool {
+		return len(categories) == 0 || slices.Contains(categories, c)
+	}
+	documents := pageDocuments(log)
+	firstParty := make(map[string]bool)
+	for page, doc := range documents {
+		// Entries without a page only make a document when no entry has one.
+		if page != "" || len(documents) == 1 {
+			firstParty[lastLabels(strings.ToLower(hostname(requestHost(doc))))] = true
+		}
+	}
+	hsts := make(map[string]bool) // Whether each HTTPS host sent the header.
+	for _, e := range log.Entries {
+		if e == nil || e.Request == nil || e.Response == nil || e.Response.Status == 0 {
+			continue
+		}
+		if u, err := url.Parse(e.Request.URL); err == nil && u.Scheme == "https" {
+			host := strings.ToLower(u.Hostname())
+			hsts[host] = hsts[host] || headerValue(e.Response.Headers, "Strict-Transport-Security") != ""
+		}
+	}
+
+	var findings []SecurityFinding
+	for i, e := range log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			continue
+		}
+		report := func(category SecurityCategory, severity Severity, format string, args ...any) {
+			findings = append(findings, SecurityFinding{
+				Category: category,
+				Severity: severity,
+				Index:    i,
+				URL:      e.Request.URL,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+		secure := u.Scheme == "https" || u.Scheme == "wss"
+		plain := u.Scheme == "http" || u.Scheme == "ws"
+
+		if enabled(SecurityMixedContent) && plain && e.Pageref != "" {
+			if page, err := url.Parse(documents[e.Pageref]); err == nil && page.Scheme == "https" {
+				severity := SeverityError
+				if e.ResourceType == "image" || e.ResourceType == "media" {
+					severity = SeverityWarning
+				}
+				report(SecurityMixedContent, severity, "%s loaded over %s by HTTPS page %s",
+					cmp.Or(e.ResourceType, "resource"), u.Scheme, documents[e.Pageref])
+			}
+		}
+
+		if enabled(SecurityCookie) {
+			if plain {
+				for _, c := range e.Request.Cookies {
+					if c != nil && c.Secure {
+						report(SecurityCookie, SeverityError, "cookie %q marked Secure sent over plain HTTP", c.Name)
+					}
+				}
+			}
+			for _, c := range responseCookies(e.Response) {
+				switch {
+				case secure && !c.Secure:
+					report(SecurityCookie, SeverityWarning, "cookie %q set over HTTPS without the Secure flag", c.Name)
+				case plain && c.Secure:
+					report(SecurityCookie, SeverityWarning, "cookie %q set with the Secure flag over plain HTTP", c.Name)
+				}
+			}
+		}
+
+		if enabled(SecurityCredentials) && plain {
+			for _, h := range e.Request.Headers {
+				if h != nil && h.Value != "" && haraudit.IsSecretKey(h.Name) {
+					report(SecurityCredentials, SeverityError, "%s header sent over plain HTTP", h.Name)
+				}
+			}
+		}
+
+		if enabled(SecurityHSTS) && u.Scheme == "https" && e.Response != nil && e.Response.Status != 0 {
+			host := strings.ToLower(u.Hostname())
+			if sent, ok := hsts[host]; ok && !sent && firstParty[lastLabels(host)] {
+				report(SecurityHSTS, SeverityWarning, "no Strict-Transport-Security header from %s", host)
+				delete(hsts, host) // Once per host.
+			}
+		}
+
+		if enabled(SecurityDowngrade) && u.Scheme == "https" {
+			if target, ok := e.RedirectTarget(); ok && strings.HasPrefix(target, "http:") {
+				report(SecurityDowngrade, SeverityError, "redirects to %s", target)
+			}
+		}
+	}
+	return findings
+}
+
+// responseCookies returns the cookies set by resp: its cookie list, or else
+// those of its Set-Cookie headers.
+func responseCookies(resp *harfile.Response) []*harfile.Cookie {
+	if resp == nil {
+		return nil
+	}
+	var cookies []*harfile.Cookie
+	if len(resp.Cookies) > 0 {
+		cookies = resp.Cookies
+	} else {
+		header := make(http.Header)
+		for _, h := range resp.Headers {
+			if h != nil && strings.EqualFold(h.Name, "Set-Cookie") {
+				header.Add("Set-Cookie", h.Value)
+			}
+		}
+		cookies = harfile.CookiesFromResponse(header)
+	}
+	return slices.DeleteFunc(slices.Clone(cookies), func(c *harfile.Cookie) bool { return c == nil })
+}
@@ -0,0 +1,138 @@
+package harkit
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// securityLog returns a page loaded over HTTPS with a weakness of each
+// kind, and a few entries the audit must leave alone.
+func securityLog() *harfile.Log {
+	doc := stubEntry("GET", "https://example.com/", 200, "text/html", nil, "Set-Cookie", "sid=1; HttpOnly")
+	doc.ResourceType = "document"
+
+	img := stubEntry("GET", "http://example.com/img.png", 200, "image/png", nil)
+	img.ResourceType = "image"
+
+	xhr := stubEntry("GET", "http://api.example.com/data", 200, "application/json", nil)
+	xhr.ResourceType = "xhr"
+	xhr.Request.Headers = []*harfile.NameValuePair{{Name: "Authorization", Value: "Bearer x"}, {Name: "X-Api-Key", Value: ""}}
+	xhr.Request.Cookies = []*harfile.Cookie{{Name: "token", Value: "t", Secure: true}, {Name: "theme", Value: "dark"}}
+
+	downgrade := stubEntry("GET", "https://example.com/old", 301, "", nil, "Location", "http://example.com/new")
+
+	script := stubEntry("GET", "https://cdn.other.net/x.js", 200, "text/javascript", nil)
+	script.ResourceType = "script"
+	script.Response.Cookies = []*harfile.Cookie{{Name: "cdn", Value: "1", Secure: true}}
+
+	hsts := stubEntry("GET", "https://secure.example.com/", 200, "text/plain", nil, "Strict-Transport-Security", "max-age=63072000")
+	noHSTS := stubEntry("GET", "https://secure.example.com/again", 200, "text/plain", nil)
+
+	set := stubEntry("GET", "http://example.com/set", 204, "", nil)
+	set.Response.Cookies = []*harfile.Cookie{{Name: "pref", Value: "1", Secure: true}}
+
+	aborted := stubEntry("GET", "https://shop.example.com/", 0, "", nil)
+
+	for _, e := range []*harfile.Entry{doc, img, xhr, downgrade, script, hsts, noHSTS, aborted} {
+		e.Pageref = "p1"
+	}
+	return &harfile.Log{
+		Pages:   []*harfile.Page{{ID: "p1"}},
+		Entries: []*harfile.Entry{doc, img, xhr, downgrade, script, hsts, noHSTS, nil, set, aborted},
+	}
+}
+
+func TestAuditSecurity(t *testing.T) {
+	finding := func(i int, url string, c SecurityCategory, s Severity, msg string) SecurityFinding {
+		return SecurityFinding{Category: c, Severity: s, Index: i, URL: url, Message: msg}
+	}
+	want := []SecurityFinding{
+		finding(0, "https://example.com/", SecurityCookie, SeverityWarning, `cookie "sid" set over HTTPS without the Secure flag`),
+		finding(0, "https://example.com/", SecurityHSTS, SeverityWarning, "no Strict-Transport-Security header from example.com"),
+		finding(1, "http://example.com/img.png", SecurityMixedContent, SeverityWarning, "image loaded over http by HTTPS page https://example.com/"),
+		finding(2, "http://api.example.com/data", SecurityMixedContent, SeverityError, "xhr loaded over http by HTTPS page https://example.com/"),
+		finding(2, "http://api.example.com/data", SecurityCookie, SeverityError, `cookie "token" marked Secure sent over plain HTTP`),
+		finding(2, "http://api.example.com/data", SecurityCredentials, SeverityError, "Authorization header sent over plain HTTP"),
+		finding(3, "https://example.com/old", SecurityDowngrade, SeverityError, "redirects to http://example.com/new"),
+		finding(8, "http://example.com/set", SecurityCookie, SeverityWarning, `cookie "pref" set with the Secure flag over plain HTTP`),
+	}
+	got := AuditSecurity(securityLog())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AuditSecurity =\n%v\nwant\n%v", got, want)
+	}
+	if !reflect.DeepEqual(AuditSecurity(securityLog(), SecurityCategories...), want) {
+		t.Error("every category differs from none")
+	}
+	if s, want := got[2].String(), "entries[1] http://example.com/img.png: warning: mixed-content: image loaded over http by HTTPS page https://example.com/"; s != want {
+		t.Errorf("String = %q, want %q", s, want)
+	}
+	if got := AuditSecurity(&harfile.Log{}); got != nil {
+		t.Errorf("empty log = %v", got)
+	}
+}
+
+func TestAuditSecurityCategories(t *testing.T) {
+	got := AuditSecurity(securityLog(), SecurityDowngrade, SecurityCredentials)
+	var kinds []SecurityCategory
+	for _, f := range got {
+		kinds = append(kinds, f.Category)
+	}
+	if want := []SecurityCategory{SecurityCredentials, SecurityDowngrade}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("categories %q, want %q", kinds, want)
+	}
+	if got := AuditSecurity(securityLog(), "unknown"); got != nil {
+		t.Errorf("unknown category = %v", got)
+	}
+}
+
+// TestAuditSecurityHSTS checks that, without pages, the first entry makes
+// the first party, and that each host is reported once.
+func TestAuditSecurityHSTS(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		stubEntry("GET", "https://www.example.com/", 200, "text/html", nil),
+		stubEntry("GET", "https://static.Example.com/a.css", 200, "text/css", nil),
+		stubEntry("GET", "https://static.example.com/b.css", 200, "text/css", nil),
+		stubEntry("GET", "https://tracker.net/p.gif", 200, "image/gif", nil),
+	}}
+	var hosts []string
+	for _, f := range AuditSecurity(log, SecurityHSTS) {
+		hosts = append(hosts, f.Message)
+	}
+	want := []string{
+		"no Strict-Transport-Security header from www.example.com",
+		"no Strict-Transport-Security header from static.example.com",
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("findings %q, want %q", hosts, want)
+	}
+}
+
+func TestSecurityFindingJSON(t *testing.T) {
+	f := SecurityFinding{Category: SecurityDowngrade, Severity: SeverityError, Index: 3, URL: "https://example.com/", Message: "redirects to http://example.com/"}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"category":"https-downgrade","severity":"error","index":3,"url":"https://example.com/","message":"redirects to http://example.com/"}`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+	var back SecurityFinding
+	if err := json.Unmarshal(data, &back); err != nil || back != f {
+		t.Errorf("round trip = %+v, %v", back, err)
+	}
+
+	if _, err := json.Marshal(Severity(7)); err == nil {
+		t.Error("invalid severity encoded")
+	}
+	var s Severity
+	if err := json.Unmarshal([]byte(`"fatal"`), &s); err == nil {
+		t.Error("unknown severity decoded")
+	}
+	if err := json.Unmarshal([]byte(`"info"`), &s); err != nil || s != SeverityInfo {
+		t.Errorf("info = %v, %v", s, err)
+	}
+}
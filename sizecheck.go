@@ -0,0 +1,246 @@
+package harkit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Kinds of [SizeIssue].
+const (
+	SizeInvalid        = "invalid"         // A size is below -1.
+	SizeNoBody         = "no-body"         // A 1xx, 204 or 304 response, or the response to a HEAD request, has a body size.
+	SizeHeaderMismatch = "header-mismatch" // Content-Length differs from bodySize.
+	SizeEncoding       = "encoding"        // bodySize differs from content.size without Content-Encoding, or exceeds it with one.
+	SizeBodyMismatch   = "body-mismatch"   // The recorded body length differs from content.size.
+	SizeRange          = "range"           // A 206 response has a malformed Content-Range, or a bodySize other than the length of its range.
+)
+
+// SizeIssue is a discrepancy between the sizes of a response, see
+// [CheckSizes]. Unknown sizes are -1.
+type SizeIssue struct {
+	Index         int    // Index of the entry in Log.Entries.
+	URL           string // Request URL.
+	Kind          string // SizeInvalid, SizeNoBody, SizeHeaderMismatch, SizeEncoding, SizeBodyMismatch or SizeRange.
+	ContentLength int64  // Value of the Content-Length header.
+	BodySize      int64  // response.bodySize, the bytes received.
+	ContentSize   int64  // response.content.size, the decoded length.
+	BodyLength    int64  // Length of the recorded body, once decoded; -1 when not recorded entirely.
+	Message       string
+}
+
+func (i SizeIssue) String() string {
+	return fmt.Sprintf("entries[%d] %s: %s (Content-Length %d, bodySize %d, content.size %d)", i.Index, i.URL, i.Message, i.ContentLength, i.BodySize, i.ContentSize)
+}
+
+// responseSizes are the sizes a response declares and the length of its
+// recorded body.
+type responseSizes struct {
+	contentLength, bodySize, contentSize, bodyLength int64
+	encoded, noBody                                  bool
+}
+
+func sizesOf(e *harfile.Entry) responseSizes {
+	resp := e.Response
+	s := responseSizes{contentLength: -1, bodySize: resp.BodySize, contentSize: -1, bodyLength: -1}
+	if v := headerValue(resp.Headers, "Content-Length"); v != "" {
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil && n >= 0 {
+			s.contentLength = n
+		}
+	}
+	encoding := headerValue(resp.Headers, "Content-Encoding")
+	s.encoded = encoding != "" && !strings.EqualFold(strings.TrimSpace(encoding), "identity")
+	s.noBody = resp.Status >= 100 && resp.Status < 200 || resp.Status == 204 || resp.Status == 304 ||
+		e.Request != nil && strings.EqualFold(e.Request.Method, "HEAD")
+	if c := resp.Content; c != nil {
+		s.contentSize = c.Size
+		if (c.Text != "" || c.Stored()) && !c.Truncated {
+			if body, err := c.DecodeBody(encoding); err == nil {
+				s.bodyLength = int64(len(body))
+			}
+		}
+	}
+	return s
+}
+
+// CheckSizes cross-checks the Content-Length header, bodySize and
+// content.size of each response, as well as the length of the recorded
+// body, and reports each discrepancy:
+//   - statuses without a body, 1xx, 204 and 304, and HEAD requests must
+//     have a bodySize of 0, their Content-Length describing another
+//     response;
+//   - Content-Length and bodySize both count the bytes received, so they
+//     must agree;
+//   - without Content-Encoding, bodySize must equal content.size, and with
+//     one, which compresses, not exceed it;
+//   - content.size must match the recorded body when kept entirely;
+//   - a 206 response with a Content-Range header must have a bodySize of
+//     the length of its range, and the header must be well-formed; see
+//     [Ranges] for the coverage of the whole resource.
+//
+// Sizes of -1 are unknown and never compared. Entries without a response
+// are skipped.
+func CheckSizes(log *harfile.Log) []SizeIssue {
+	var issues []SizeIssue
+	for i, e := range log.Entries {
+		if e == nil || e.Response == nil {
+			continue
+		}
+		s := sizesOf(e)
+		report := func(kind, format string, args ...any) {
+			issue := SizeIssue{
+				Index:         i,
+				Kind:          kind,
+				ContentLength: s.contentLength,
+				BodySize:      s.bodySize,
+				ContentSize:   s.contentSize,
+				BodyLength:    s.bodyLength,
+				Message:       fmt.Sprintf(format, args...),
+			}
+			if e.Request != nil {
+				issue.URL = e.Request.URL
+			}
+			issues = append(issues, issue)
+		}
+
+		if s.bodySize < -1 || s.contentSize < -1 {
+			report(SizeInvalid, "size below -1")
+			continue
+		}
+		if s.noBody {
+			if s.bodySize > 0 {
+				report(SizeNoBody, "bodySize %d for a response without a body", s.bodySize)
+			}
+			continue
+		}
+		if s.contentLength >= 0 && s.bodySize >= 0 && s.contentLength != s.bodySize {
+			report(SizeHeaderMismatch, "Content-Length %d but bodySize %d", s.contentLength, s.bodySize)
+		}
+		if s.bodySize >= 0 && s.contentSize >= 0 {
+			switch {
+			case !s.encoded && s.bodySize != s.contentSize:
+				report(SizeEncoding, "bodySize %d differs from content.size %d without Content-Encoding", s.bodySize, s.contentSize)
+			case s.encoded && s.bodySize > s.contentSize:
+				report(SizeEncoding, "bodySize %d exceeds content.size %d despite Content-Encoding", s.bodySize, s.contentSize)
+			}
+		}
+		if s.bodyLength >= 0 && s.contentSize >= 0 && s.bodyLength != s.contentSize {
+			report(SizeBodyMismatch, "recorded body of %d bytes but content.size %d", s.bodyLength, s.contentSize)
+		}
+		if v := headerValue(e.Response.Headers, "Content-Range"); e.Response.Status == 206 && v != "" {
+			if r, err := parseContentRange(v); err != nil {
+				report(SizeRange, "%v", err)
+			} else if s.bodySize >= 0 && s.bodySize != r.Len() {
+				report(SizeRange, "bodySize %d but Content-Range %s spans %d bytes", s.bodySize, r.ByteRange, r.Len())
+			}
+		}
+	}
+	return issues
+}
+
+// Source is the size [FixSizes] trusts.
+type Source int
+
+const (
+	SourceHeader   Source = iota // The Content-Length header.
+	SourceBody                   // The length of the recorded body.
+	SourceDeclared               // content.size.
+)
+
+// FixSizes rewrites the sizes of each response from the prefer authority,
+// so that [CheckSizes] finds no discrepancy but those it cannot settle, and
+// returns the number of entries changed:
+//   - sizes below -1 become -1 and responses without a body get a bodySize
+//     of 0;
+//   - SourceHeader sets bodySize from Content-Length, and content.size too
+//     without Content-Encoding;
+//   - SourceBody sets content.size from the recorded body, when kept
+//     entirely, and SourceDeclared keeps it;
+//   - then, without Content-Encoding, bodySize and a Content-Length header
+//     follow content.size; with one, a bodySize above content.size becomes
+//     -1, and content.compression is their difference.
+//
+// Entries lacking the preferred size are left alone. A rewritten header
+// sets headersSize to -1.
+func FixSizes(log *harfile.Log, prefer Source) int {
+	changed := 0
+	for _, e := range log.Entries {
+		if e == nil || e.Response == nil {
+			continue
+		}
+		resp := e.Response
+		bodySize, headersSize := resp.BodySize, resp.HeadersSize
+		var size, compression int64
+		if c := resp.Content; c != nil {
+			size, compression = c.Size, c.Compression
+		}
+		fixSizes(e, prefer)
+		if resp.BodySize != bodySize || resp.HeadersSize != headersSize ||
+			resp.Content != nil && (resp.Content.Size != size || resp.Content.Compression != compression) {
+			changed++
+		}
+	}
+	return changed
+}
+
+// fixSizes implements FixSizes for e.
+func fixSizes(e *harfile.Entry, prefer Source) {
+	resp := e.Response
+	s := sizesOf(e)
+	c := resp.Content
+	if resp.BodySize < -1 {
+		resp.BodySize = -1
+	}
+	if c != nil && c.Size < -1 {
+		c.Size = -1
+	}
+	if s.noBody {
+		resp.BodySize = 0
+		return
+	}
+
+	size := s.contentSize
+	switch prefer {
+	case SourceHeader:
+		if s.contentLength < 0 {
+			return
+		}
+		resp.BodySize = s.contentLength
+		if !s.encoded {
+			size = s.contentLength
+		}
+	case SourceBody:
+		if s.bodyLength < 0 {
+			return
+		}
+		size = s.bodyLength
+	case SourceDeclared:
+	}
+	if c == nil || size < 0 {
+		return
+	}
+	c.Size = size
+
+	if s.encoded {
+		if resp.BodySize > size {
+			resp.BodySize = -1
+		}
+		c.Compression = 0
+		if resp.BodySize >= 0 {
+			c.Compression = size - resp.BodySize
+		}
+		return
+	}
+	resp.BodySize, c.Compression = size, 0
+	if s.contentLength < 0 || s.contentLength == size {
+		return
+	}
+	for _, h := range resp.Headers {
+		if h != nil && strings.EqualFold(h.Name, "Content-Length") {
+			h.Value = strconv.FormatInt(size, 10)
+		}
+	}
+	resp.HeadersSize = -1
+}
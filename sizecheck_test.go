@@ -0,0 +1,141 @@
+package harkit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// sizeEntry returns a GET entry answered with status, whose response has
+// the given sizes and body, and a header for each "Name: value" string.
+func sizeEntry(status, bodySize, contentSize int64, text string, headers ...string) *harfile.Entry {
+	resp := &harfile.Response{
+		Status:      status,
+		BodySize:    bodySize,
+		HeadersSize: 100,
+		Content:     &harfile.Content{Size: contentSize, MimeType: "text/plain", Text: text},
+	}
+	for _, h := range headers {
+		name, value, _ := strings.Cut(h, ": ")
+		resp.Headers = append(resp.Headers, &harfile.NameValuePair{Name: name, Value: value})
+	}
+	return &harfile.Entry{Request: &harfile.Request{Method: "GET", URL: "https://example.com/"}, Response: resp}
+}
+
+func TestCheckSizes(t *testing.T) {
+	head := sizeEntry(200, 5, 5, "")
+	head.Request.Method = "HEAD"
+	truncated := sizeEntry(200, 100, 100, "hello")
+	truncated.Response.Content.Truncated = true
+
+	tests := []struct {
+		name  string
+		entry *harfile.Entry
+		kinds string // Kinds reported, separated by spaces.
+	}{
+		{"consistent", sizeEntry(200, 5, 5, "hello", "Content-Length: 5"), ""},
+		{"unknown sizes", sizeEntry(200, -1, -1, "", "Content-Length: 5"), ""},
+		{"invalid body size", sizeEntry(200, -2, 5, "hello"), SizeInvalid},
+		{"invalid content size", sizeEntry(200, 5, -3, ""), SizeInvalid},
+		{"204 with a body", sizeEntry(204, 5, 0, ""), SizeNoBody},
+		{"304 keeps the Content-Length of the cached response", sizeEntry(304, 0, 0, "", "Content-Length: 5000"), ""},
+		{"HEAD with a body size", head, SizeNoBody},
+		{"header mismatch", sizeEntry(200, 5, 5, "hello", "Content-Length: 6"), SizeHeaderMismatch},
+		{"invalid Content-Length is ignored", sizeEntry(200, 5, 5, "hello", "Content-Length: five"), ""},
+		{"size differs without encoding", sizeEntry(200, 4, 5, "hello"), SizeEncoding},
+		{"compressed", sizeEntry(200, 3, 5, "hello", "Content-Encoding: gzip"), ""},
+		{"identity encoding", sizeEntry(200, 3, 5, "hello", "Content-Encoding: identity"), SizeEncoding},
+		{"bigger than decoded", sizeEntry(200, 6, 5, "hello", "Content-Encoding: br"), SizeEncoding},
+		{"body mismatch", sizeEntry(200, 4, 4, "hello", "Content-Length: 4"), SizeBodyMismatch},
+		{"truncated body", truncated, ""},
+		{"range", sizeEntry(206, 5, 5, "hello", "Content-Range: bytes 10-14/100"), ""},
+		{"range length mismatch", sizeEntry(206, 5, 5, "hello", "Content-Range: bytes 10-19/100"), SizeRange},
+		{"malformed range", sizeEntry(206, 5, 5, "hello", "Content-Range: bytes 10-14"), SizeRange},
+		{"range of a 200 is ignored", sizeEntry(200, 5, 5, "hello", "Content-Range: bytes 10-14"), ""},
+		{"every discrepancy", sizeEntry(200, 3, 4, "hello", "Content-Length: 2"), SizeHeaderMismatch + " " + SizeEncoding + " " + SizeBodyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds []string
+			for _, issue := range CheckSizes(&harfile.Log{Entries: []*harfile.Entry{tt.entry}}) {
+				kinds = append(kinds, issue.Kind)
+			}
+			if got := strings.Join(kinds, " "); got != tt.kinds {
+				t.Errorf("kinds = %q, want %q", got, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestCheckSizesIssue(t *testing.T) {
+	gz := gzipString(t, strings.Repeat("hello, ", 50))
+	e := sizeEntry(200, int64(len(gz)), 350, gz, "Content-Encoding: gzip", "Content-Length: 7")
+	log := &harfile.Log{Entries: []*harfile.Entry{nil, {Request: &harfile.Request{}}, e}}
+	issues := CheckSizes(log)
+	if len(issues) != 1 {
+		t.Fatalf("issues = %v", issues)
+	}
+	want := SizeIssue{Index: 2, URL: "https://example.com/", Kind: SizeHeaderMismatch, ContentLength: 7, BodySize: int64(len(gz)), ContentSize: 350, BodyLength: 350}
+	wantString := fmt.Sprintf("entries[2] https://example.com/: Content-Length 7 but bodySize %d (Content-Length 7, bodySize %d, content.size 350)", len(gz), len(gz))
+	if got := issues[0].String(); got != wantString {
+		t.Errorf("String() = %q, want %q", got, wantString)
+	}
+	issues[0].Message = ""
+	if issues[0] != want {
+		t.Errorf("issue = %+v, want %+v", issues[0], want)
+	}
+}
+
+func TestFixSizes(t *testing.T) {
+	tests := []struct {
+		name        string
+		entry       *harfile.Entry
+		prefer      Source
+		changed     bool
+		bodySize    int64
+		size        int64
+		compression int64
+		length      string // Content-Length header afterwards, if any.
+		rewritten   bool   // Whether the Content-Length header was rewritten.
+	}{
+		{"consistent", sizeEntry(200, 5, 5, "hello", "Content-Length: 5"), SourceBody, false, 5, 5, 0, "5", false},
+		{"header", sizeEntry(200, 4, 4, "", "Content-Length: 5"), SourceHeader, true, 5, 5, 0, "5", false},
+		{"header with encoding", sizeEntry(200, 9, 5, "hello", "Content-Length: 3", "Content-Encoding: gzip"), SourceHeader, true, 3, 5, 2, "3", false},
+		{"header missing", sizeEntry(200, 4, 3, ""), SourceHeader, false, 4, 3, 0, "", false},
+		{"body", sizeEntry(200, 4, 4, "hello", "Content-Length: 4"), SourceBody, true, 5, 5, 0, "5", true},
+		{"body with encoding", sizeEntry(200, 3, 9, "hello", "Content-Encoding: gzip"), SourceBody, true, 3, 5, 2, "", false},
+		{"body with encoding too big", sizeEntry(200, 6, 9, "hello", "Content-Encoding: gzip"), SourceBody, true, -1, 5, 0, "", false},
+		{"body missing", sizeEntry(200, 4, 3, ""), SourceBody, false, 4, 3, 0, "", false},
+		{"declared", sizeEntry(200, 4, 5, "", "Content-Length: 4"), SourceDeclared, true, 5, 5, 0, "5", true},
+		{"declared unknown", sizeEntry(200, 4, -1, ""), SourceDeclared, false, 4, -1, 0, "", false},
+		{"invalid", sizeEntry(200, -5, -2, ""), SourceDeclared, true, -1, -1, 0, "", false},
+		{"no body", sizeEntry(204, 5, 0, "", "Content-Length: 5"), SourceHeader, true, 0, 0, 0, "5", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &harfile.Log{Entries: []*harfile.Entry{tt.entry, nil}}
+			if n := FixSizes(log, tt.prefer); n != map[bool]int{false: 0, true: 1}[tt.changed] {
+				t.Errorf("FixSizes = %d, want changed %v", n, tt.changed)
+			}
+			resp := tt.entry.Response
+			if resp.BodySize != tt.bodySize || resp.Content.Size != tt.size || resp.Content.Compression != tt.compression {
+				t.Errorf("bodySize %d, size %d, compression %d, want %d, %d, %d",
+					resp.BodySize, resp.Content.Size, resp.Content.Compression, tt.bodySize, tt.size, tt.compression)
+			}
+			if got := headerValue(resp.Headers, "Content-Length"); got != tt.length {
+				t.Errorf("Content-Length = %q, want %q", got, tt.length)
+			}
+			if rewritten := resp.HeadersSize == -1; rewritten != tt.rewritten {
+				t.Errorf("headersSize = %d", resp.HeadersSize)
+			}
+			if issues := CheckSizes(log); tt.changed && len(issues) != 0 {
+				t.Errorf("issues left: %v", issues)
+			}
+			if n := FixSizes(log, tt.prefer); n != 0 {
+				t.Errorf("second pass changed %d entries", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package harkit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// OrphanKey is the [Split] bucket of entries without a key, such as
+// entries without a page when splitting [ByPage].
+const OrphanKey = "orphan"
+
+// SplitKey returns the bucket of an entry for [Split], or "" for
+// [OrphanKey].
+type SplitKey func(*harfile.Entry) string
+
+var (
+	// ByPage splits by page ID.
+	ByPage SplitKey = func(e *harfile.Entry) string { return e.Pageref }
+
+	// ByHost splits by request host, port included.
+	ByHost SplitKey = func(e *harfile.Entry) string {
+		if e.Request == nil {
+			return ""
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return ""
+		}
+		return u.Host
+	}
+)
+
+// ByPathGroup splits by request path as mapped by group, e.g. by path
+// template with [PathTemplater]. A nil group splits by path.
+func ByPathGroup(group GroupBy) SplitKey {
+	return func(e *harfile.Entry) string {
+		if e.Request == nil {
+			return ""
+		}
+		path := urlPath(e.Request.URL)
+		if group != nil {
+			path = group(path)
+		}
+		return path
+	}
+}
+
+// ByTimeBucket splits by start time, in buckets of d aligned on the zero
+// time. Keys are the UTC start of the bucket, e.g. "20240102T150400Z".
+func ByTimeBucket(d time.Duration) SplitKey {
+	return func(e *harfile.Entry) string {
+		if e.StartedDateTime.IsZero() {
+			return ""
+		}
+		return e.StartedDateTime.UTC().Truncate(d).Format("20060102T150405Z")
+	}
+}
+
+// Split distributes the entries of har into one HAR per key. Each HAR gets
+// a copy of the log metadata, the pages its entries refer to and copies of
+// its entries, sorted by start time. Entries without a key go to
+// [OrphanKey].
+func Split(har *harfile.HAR, by SplitKey) map[string]*harfile.HAR {
+	out := make(map[string]*harfile.HAR)
+	if har == nil || har.Log == nil {
+		return out
+	}
+	meta := *har.Log
+	meta.Pages, meta.Entries = nil, nil
+	pages := make(map[string]*harfile.Page)
+	for _, p := range har.Log.Pages {
+		if p != nil {
+			pages[p.ID] = p
+		}
+	}
+
+	refs := make(map[string]map[string]bool)
+	for _, e := range har.Log.Entries {
+		if e == nil {
+			continue
+		}
+		key := by(e)
+		if key == "" {
+			key = OrphanKey
+		}
+		h, ok := out[key]
+		if !ok {
+			h = &harfile.HAR{Log: meta.Clone()}
+			out[key] = h
+			refs[key] = make(map[string]bool)
+		}
+		h.Log.Entries = append(h.Log.Entries, e.Clone())
+		if p := pages[e.Pageref]; p != nil && !refs[key][p.ID] {
+			refs[key][p.ID] = true
+			h.Log.Pages = append(h.Log.Pages, p.Clone())
+		}
+	}
+	for _, h := range out {
+		h.Log.SortEntries()
+	}
+	return out
+}
+
+// SplitToDir splits har as by [Split] and writes each part to
+// "<key>.har" in dir, characters other than letters, digits, '.', '-' and
+// '_' being replaced by '_' in the file name. Keys whose names collide,
+// such as "a/b" and "a_b", or that differ only in case, get a "-2", "-3"...
+// suffix, keys already valid as names keeping theirs. Files are written to
+// a temporary name first, so readers never see a partial file.
+func SplitToDir(har *harfile.HAR, dir string, by SplitKey) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	parts := Split(har, by)
+	names := fileKeys(slices.Collect(maps.Keys(parts)))
+	for key, h := range parts {
+		if err := writeFileAtomic(filepath.Join(dir, names[key]+".har"), h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileKeys returns a distinct file name for each key, see [SplitToDir].
+// Names are compared case-insensitively, as some file systems do.
+func fileKeys(keys []string) map[string]string {
+	// Keys already valid as names are served first, so that they keep
+	// them whatever the other keys.
+	var clean, other []string
+	for _, key := range keys {
+		if fileKey(key) == key {
+			clean = append(clean, key)
+		} else {
+			other = append(other, key)
+		}
+	}
+	slices.Sort(clean)
+	slices.Sort(other)
+	names := make(map[string]string, len(keys))
+	used := make(map[string]bool, len(keys))
+	for _, key := range append(clean, other...) {
+		base := fileKey(key)
+		name := base
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = base + "-" + strconv.Itoa(i)
+		}
+		used[strings.ToLower(name)] = true
+		names[key] = name
+	}
+	return names
+}
+
+func fileKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// writeFileAtomic writes h to path through a temporary file renamed over
+// it, so that readers never see a partial file. The file is written as by
+// [harfile.HAR.WriteFile], gzip compressed when path ends in ".gz", and
+// synced before the rename so that a crash cannot leave an empty file in
+// place of the previous one. It keeps the permissions of the file it
+// replaces, 0644 for a new one.
+func writeFileAtomic(path string, h *harfile.HAR) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".harkit-*"+filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeHAR(tmp, h, strings.EqualFold(filepath.Ext(path), ".gz")); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeHAR writes h to w, gzip compressed if gz is set.
+func writeHAR(w io.Writer, h *harfile.HAR, gz bool) error {
+	bw := bufio.NewWriter(w)
+	if !gz {
+		if err := h.Write(bw); err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+	zw := gzip.NewWriter(bw)
+	if err := h.Write(zw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
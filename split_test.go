@@ -0,0 +1,223 @@
+package harkit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+	"github.com/Mathious6/harkit/hartransform"
+)
+
+// splitEntry returns a valid GET of url answered with 200, in the page
+// pageref.
+func splitEntry(url, pageref string) *harfile.Entry {
+	return &harfile.Entry{
+		Pageref:         pageref,
+		StartedDateTime: time.Now(),
+		Request: &harfile.Request{
+			Method: "GET", URL: url, HTTPVersion: "HTTP/1.1",
+			Cookies: []*harfile.Cookie{}, Headers: []*harfile.NameValuePair{}, QueryString: []*harfile.NameValuePair{},
+			HeadersSize: -1, BodySize: 0,
+		},
+		Response: &harfile.Response{
+			Status: 200, StatusText: "OK", HTTPVersion: "HTTP/1.1",
+			Cookies: []*harfile.Cookie{}, Headers: []*harfile.NameValuePair{}, Content: &harfile.Content{MimeType: "text/plain"},
+			HeadersSize: -1, BodySize: 0,
+		},
+		Cache:   &harfile.Cache{},
+		Timings: &harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1},
+	}
+}
+
+func TestSplitByPageAfterRepair(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(pageref string, offset time.Duration) *harfile.Entry {
+		e := splitEntry("https://example.com/", pageref)
+		e.StartedDateTime = start.Add(offset)
+		return e
+	}
+	log := &harfile.Log{Version: "1.2", Creator: harfile.NewCreator(), Entries: []*harfile.Entry{
+		entry("home", time.Second),
+		entry("home", time.Minute+time.Second),
+		entry("missing", 2*time.Minute),
+		entry("", 3*time.Minute),
+	}}
+	log.AddPage("home", "first visit", start)
+	log.Pages = append(log.Pages, &harfile.Page{StartedDateTime: start.Add(time.Minute), ID: "home", Title: "second visit", PageTimings: &harfile.PageTimings{}})
+	h := &harfile.HAR{Log: log}
+
+	hartransform.FixPageGraph(h)
+	parts := Split(h, ByPage)
+	if len(parts) != 4 {
+		t.Fatalf("got %d parts, want home, home-2, missing and orphan", len(parts))
+	}
+	for key, part := range parts {
+		if key == OrphanKey {
+			if len(part.Log.Pages) != 0 || len(part.Log.Entries) != 1 {
+				t.Errorf("orphan part = %d pages, %d entries", len(part.Log.Pages), len(part.Log.Entries))
+			}
+			continue
+		}
+		if len(part.Log.Pages) != 1 || part.Log.Pages[0].ID != key || len(part.Log.Entries) != 1 {
+			t.Errorf("part %s = %d pages, %d entries", key, len(part.Log.Pages), len(part.Log.Entries))
+		}
+		if err := part.Validate(); err != nil {
+			t.Errorf("part %s: %v", key, err)
+		}
+	}
+	if parts["home-2"].Log.Pages[0].Title != "second visit" {
+		t.Error("entry of the second visit not moved to its page")
+	}
+}
+
+func TestFileKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want map[string]string
+	}{
+		{"distinct", []string{"home", "a/b"}, map[string]string{"home": "home", "a/b": "a_b"}},
+		{"sanitized alike", []string{"a/b", "a?b", "a b"}, map[string]string{"a b": "a_b", "a/b": "a_b-2", "a?b": "a_b-3"}},
+		{"valid name kept", []string{"a/b", "a_b"}, map[string]string{"a_b": "a_b", "a/b": "a_b-2"}},
+		{"suffix taken", []string{"a/b", "a_b", "a_b-2"}, map[string]string{"a_b": "a_b", "a_b-2": "a_b-2", "a/b": "a_b-3"}},
+		{"case", []string{"Home", "home", "HOME/"}, map[string]string{"HOME/": "HOME_", "Home": "Home", "home": "home-2"}},
+		{"unicode", []string{"café", "caf€"}, map[string]string{"café": "caf_", "caf€": "caf_-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := slices.Clone(tt.keys)
+			slices.Reverse(keys)
+			for _, keys := range [][]string{tt.keys, keys} {
+				got := fileKeys(slices.Clone(keys))
+				for key, want := range tt.want {
+					if got[key] != want {
+						t.Errorf("name of %q = %q, want %q (all %v)", key, got[key], want, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestSplitToDirCollisions writes parts whose keys sanitize to the same
+// name, and checks that none overwrites another.
+func TestSplitToDirCollisions(t *testing.T) {
+	entry := func(pageref string) *harfile.Entry {
+		return splitEntry("https://example.com/"+pageref, pageref)
+	}
+	h := &harfile.HAR{Log: &harfile.Log{Version: "1.2", Creator: harfile.NewCreator(), Entries: []*harfile.Entry{
+		entry("a/b"), entry("a_b"), entry("a?b"), entry("A_B"),
+	}}}
+	dir := t.TempDir()
+	if err := SplitToDir(h, dir, ByPage); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"A_B": "A_B", "a_b-2": "a_b", "a_b-3": "a/b", "a_b-4": "a?b"}
+	files, _ := os.ReadDir(dir)
+	if len(files) != len(want) {
+		t.Errorf("%d files written, want %d: %v", len(files), len(want), files)
+	}
+	for name, key := range want {
+		part, err := harfile.LoadFile(filepath.Join(dir, name+".har"))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if len(part.Log.Entries) != 1 || part.Log.Entries[0].Pageref != key {
+			t.Errorf("%s.har holds %d entries, want the one of %q", name, len(part.Log.Entries), key)
+		}
+	}
+}
+
+// TestWriteFileAtomic checks that parts are written as by HAR.WriteFile,
+// gzip compressed when the name ends in ".gz", and that no temporary file
+// is left behind.
+func TestWriteFileAtomic(t *testing.T) {
+	h := &harfile.HAR{Log: &harfile.Log{Version: "1.2", Creator: harfile.NewCreator(), Entries: []*harfile.Entry{
+		splitEntry("https://example.com/", ""),
+	}}}
+	for _, name := range []string{"part.har", "part.har.gz"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte("previous"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeFileAtomic(path, h); err != nil {
+				t.Fatal(err)
+			}
+			want := filepath.Join(t.TempDir(), name)
+			if err := h.WriteFile(want); err != nil {
+				t.Fatal(err)
+			}
+			got, _ := os.ReadFile(path)
+			wantData, _ := os.ReadFile(want)
+			if !bytes.Equal(got, wantData) {
+				t.Errorf("written file differs from HAR.WriteFile:\n%q\n%q", got, wantData)
+			}
+			if gz := bytes.HasPrefix(got, []byte{0x1f, 0x8b}); gz != strings.HasSuffix(name, ".gz") {
+				t.Errorf("gzip = %v", gz)
+			}
+
+			back, err := harfile.LoadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var before, after bytes.Buffer
+			h.Write(&before)
+			back.Write(&after)
+			if !bytes.Equal(before.Bytes(), after.Bytes()) {
+				t.Error("round trip changed the document")
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 1 {
+				t.Errorf("files left: %v", files)
+			}
+		})
+	}
+}
+
+// TestWriteFileAtomicMode checks that a new file is readable by others, as
+// by os.Create, and that a replaced file keeps its permissions.
+func TestWriteFileAtomicMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permissions")
+	}
+	h := &harfile.HAR{Log: &harfile.Log{Version: "1.2", Creator: harfile.NewCreator(), Entries: []*harfile.Entry{}}}
+	for _, tt := range []struct {
+		name     string
+		existing os.FileMode // Zero for a new file.
+		want     os.FileMode
+	}{
+		{"new", 0, 0o644},
+		{"private", 0o600, 0o600},
+		{"group readable", 0o640, 0o640},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "part.har")
+			if tt.existing != 0 {
+				if err := os.WriteFile(path, nil, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writeFileAtomic(path, h); err != nil {
+				t.Fatal(err)
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fi.Mode().Perm(); got != tt.want {
+				t.Errorf("mode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
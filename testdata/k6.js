@@ -0,0 +1,33 @@
+// Generated by harkit from a HAR capture.
+import http from 'k6/http';
+import encoding from 'k6/encoding';
+import { group, sleep } from 'k6';
+
+export default function () {
+  group("Login 'page'", function () {
+    http.request("GET", "https://example.com/login", null, {
+      headers: {
+        "Cookie": "session=abc",
+        "Accept": "text/html",
+      },
+    });
+    http.request("GET", "https://example.com/logo.png", null, {
+      headers: {
+      },
+    });
+    sleep(1.500);
+    http.request("POST", "https://example.com/login", "user=ada&pass=l0ve%26", {
+      headers: {
+        "Content-Type": "application/x-www-form-urlencoded",
+      },
+    });
+  });
+  sleep(1.000);
+  http.request("POST", "https://api.example.com/v1/upload?kind=raw", encoding.b64decode("AAEC/w=="), {
+    headers: {
+      "Content-Type": "application/octet-stream",
+      "X-Trace": "a",
+      "x-trace": "b",
+    },
+  });
+}
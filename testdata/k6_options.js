@@ -0,0 +1,28 @@
+// Generated by harkit from a HAR capture.
+import http from 'k6/http';
+import encoding from 'k6/encoding';
+import { group, sleep } from 'k6';
+
+export default function () {
+  group("Login 'page'", function () {
+    http.request("GET", "http://localhost:8080/login", null, {
+      headers: {
+        "Accept": "text/html",
+      },
+    });
+    sleep(1.500);
+    http.request("POST", "http://localhost:8080/login", "user=ada&pass=l0ve%26", {
+      headers: {
+        "Content-Type": "application/x-www-form-urlencoded",
+      },
+    });
+  });
+  sleep(1.000);
+  http.request("POST", "http://localhost:8080/v1/upload?kind=raw", encoding.b64decode("AAEC/w=="), {
+    headers: {
+      "Content-Type": "application/octet-stream",
+      "X-Trace": "a",
+      "x-trace": "b",
+    },
+  });
+}
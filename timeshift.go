@@ -0,0 +1,109 @@
+package harkit
+
+import (
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// ShiftOptions configures [ShiftTimes] and [NormalizeStart].
+type ShiftOptions struct {
+	Cookies bool // Also shift the expiration dates of request and response cookies, so sessions do not appear expired.
+	Cache   bool // Also shift the expires and lastAccess dates of cache entries.
+}
+
+// ShiftTimes moves every timestamp of har by delta: the start of pages and
+// entries and the time of WebSocket messages, plus cookie and cache dates
+// when opts asks for it. Relative offsets, durations and timings are left
+// untouched, and sub-millisecond precision is kept. Dates that cannot be
+// parsed, and session cookies, are left as they are.
+func ShiftTimes(har *harfile.HAR, delta time.Duration, opts ShiftOptions) {
+	if har == nil || har.Log == nil || delta == 0 {
+		return
+	}
+	for _, p := range har.Log.Pages {
+		if p != nil && !p.StartedDateTime.IsZero() {
+			p.StartedDateTime = p.StartedDateTime.Add(delta)
+		}
+	}
+	for _, e := range har.Log.Entries {
+		if e == nil {
+			continue
+		}
+		if !e.StartedDateTime.IsZero() {
+			e.StartedDateTime = e.StartedDateTime.Add(delta)
+		}
+		for _, m := range e.WebSocketMessages {
+			if m != nil {
+				m.Time += delta.Seconds()
+			}
+		}
+		if opts.Cookies {
+			if e.Request != nil {
+				shiftCookies(e.Request.Cookies, delta)
+			}
+			if e.Response != nil {
+				shiftCookies(e.Response.Cookies, delta)
+			}
+		}
+		if opts.Cache && e.Cache != nil {
+			for _, d := range []*harfile.CacheData{e.Cache.BeforeRequest, e.Cache.AfterRequest} {
+				if d != nil {
+					d.Expires = shiftDate(d.Expires, delta)
+					d.LastAccess = shiftDate(d.LastAccess, delta)
+				}
+			}
+		}
+	}
+}
+
+// NormalizeStart shifts the timestamps of har, as [ShiftTimes] does, so that
+// the earliest page or entry starts at anchor. Normalizing two runs to
+// time.Unix(0, 0) makes them comparable from t=0. It does nothing when har
+// has no timestamp.
+func NormalizeStart(har *harfile.HAR, anchor time.Time, opts ShiftOptions) {
+	if har == nil || har.Log == nil {
+		return
+	}
+	var earliest time.Time
+	first := func(t time.Time) {
+		if !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+			earliest = t
+		}
+	}
+	for _, p := range har.Log.Pages {
+		if p != nil {
+			first(p.StartedDateTime)
+		}
+	}
+	for _, e := range har.Log.Entries {
+		if e != nil {
+			first(e.StartedDateTime)
+		}
+	}
+	if earliest.IsZero() {
+		return
+	}
+	ShiftTimes(har, anchor.Sub(earliest), opts)
+}
+
+func shiftCookies(cookies []*harfile.Cookie, delta time.Duration) {
+	for _, c := range cookies {
+		if c == nil {
+			continue
+		}
+		if t, ok := c.ExpiresTime(); ok {
+			c.SetExpires(t.Add(delta))
+		}
+	}
+}
+
+// shiftDate moves the date s by delta, keeping its precision. Dates that
+// cannot be parsed are returned unchanged.
+func shiftDate(s string, delta time.Duration) string {
+	t, ok := harfile.ParseCookieTime(s)
+	if !ok {
+		return s
+	}
+	return t.Add(delta).Format(time.RFC3339Nano)
+}
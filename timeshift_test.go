@@ -0,0 +1,126 @@
+package harkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// shiftHAR returns a capture with a timestamp in every place ShiftTimes
+// looks.
+func shiftHAR() *harfile.HAR {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 500_000, time.UTC)
+	return &harfile.HAR{Log: &harfile.Log{
+		Pages: []*harfile.Page{{ID: "page_1", StartedDateTime: start.Add(time.Second)}, nil, {ID: "page_2"}},
+		Entries: []*harfile.Entry{
+			{
+				StartedDateTime: start.Add(2 * time.Second),
+				Time:            42,
+				Request: &harfile.Request{Cookies: []*harfile.Cookie{
+					{Name: "sid", Expires: "2024-01-02T12:00:00.000Z"},
+					{Name: "session"},
+					nil,
+				}},
+				Response: &harfile.Response{Cookies: []*harfile.Cookie{{Name: "id", Expires: "Tue, 02 Jan 2024 12:00:00 GMT"}}},
+				Cache: &harfile.Cache{BeforeRequest: &harfile.CacheData{
+					Expires:    "2024-01-02T12:00:00Z",
+					LastAccess: "2024-01-01T11:00:00.25Z",
+				}, AfterRequest: &harfile.CacheData{Expires: "whenever"}},
+				Timings:           &harfile.Timings{Wait: 42},
+				WebSocketMessages: []*harfile.WebSocketMessage{{Time: 1704110402.5}, nil},
+			},
+			nil,
+			{StartedDateTime: start, Pageref: "page_1"},
+			{},
+		},
+	}}
+}
+
+func TestShiftTimes(t *testing.T) {
+	const delta = 36*time.Hour + 250*time.Microsecond
+	tests := []struct {
+		name                string
+		opts                ShiftOptions
+		cookie, setCookie   string
+		expires, lastAccess string
+	}{
+		{"timestamps only", ShiftOptions{}, "2024-01-02T12:00:00.000Z", "Tue, 02 Jan 2024 12:00:00 GMT", "2024-01-02T12:00:00Z", "2024-01-01T11:00:00.25Z"},
+		{"cookies", ShiftOptions{Cookies: true}, "2024-01-04T00:00:00.000Z", "2024-01-04T00:00:00.000Z", "2024-01-02T12:00:00Z", "2024-01-01T11:00:00.25Z"},
+		{"cache", ShiftOptions{Cache: true}, "2024-01-02T12:00:00.000Z", "Tue, 02 Jan 2024 12:00:00 GMT", "2024-01-04T00:00:00.00025Z", "2024-01-02T23:00:00.25025Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, orig := shiftHAR(), shiftHAR()
+			ShiftTimes(h, delta, tt.opts)
+
+			l, ol := h.Log, orig.Log
+			if !l.Pages[0].StartedDateTime.Equal(ol.Pages[0].StartedDateTime.Add(delta)) || !l.Pages[2].StartedDateTime.IsZero() {
+				t.Errorf("pages start at %v and %v", l.Pages[0].StartedDateTime, l.Pages[2].StartedDateTime)
+			}
+			e, oe := l.Entries[0], ol.Entries[0]
+			if !e.StartedDateTime.Equal(oe.StartedDateTime.Add(delta)) || !l.Entries[2].StartedDateTime.Equal(ol.Entries[2].StartedDateTime.Add(delta)) {
+				t.Errorf("entries start at %v and %v", e.StartedDateTime, l.Entries[2].StartedDateTime)
+			}
+			if !l.Entries[3].StartedDateTime.IsZero() {
+				t.Errorf("zero start shifted to %v", l.Entries[3].StartedDateTime)
+			}
+			if e.Time != 42 || e.Timings.Wait != 42 {
+				t.Errorf("time %v, wait %v", e.Time, e.Timings.Wait)
+			}
+			if got, want := e.WebSocketMessages[0].Time, 1704110402.5+delta.Seconds(); got != want {
+				t.Errorf("message time %v, want %v", got, want)
+			}
+			if got := e.Request.Cookies[0].Expires; got != tt.cookie {
+				t.Errorf("request cookie expires %q, want %q", got, tt.cookie)
+			}
+			if e.Request.Cookies[1].Expires != "" {
+				t.Errorf("session cookie expires %q", e.Request.Cookies[1].Expires)
+			}
+			if got := e.Response.Cookies[0].Expires; got != tt.setCookie {
+				t.Errorf("response cookie expires %q, want %q", got, tt.setCookie)
+			}
+			if d := e.Cache.BeforeRequest; d.Expires != tt.expires || d.LastAccess != tt.lastAccess {
+				t.Errorf("cache expires %q, lastAccess %q, want %q, %q", d.Expires, d.LastAccess, tt.expires, tt.lastAccess)
+			}
+			if e.Cache.AfterRequest.Expires != "whenever" {
+				t.Errorf("unparsable date became %q", e.Cache.AfterRequest.Expires)
+			}
+		})
+	}
+
+	ShiftTimes(nil, time.Hour, ShiftOptions{})
+	ShiftTimes(&harfile.HAR{}, time.Hour, ShiftOptions{})
+}
+
+func TestNormalizeStart(t *testing.T) {
+	h := shiftHAR()
+	NormalizeStart(h, time.Unix(0, 0), ShiftOptions{})
+	l := h.Log
+	// The earliest timestamp is the start of the third entry, before any page.
+	if got := l.Entries[2].StartedDateTime; !got.Equal(time.Unix(0, 0)) {
+		t.Errorf("earliest entry starts at %v", got)
+	}
+	if got := l.Pages[0].StartedDateTime.Sub(l.Entries[2].StartedDateTime); got != time.Second {
+		t.Errorf("page starts %v after the earliest entry, want 1s", got)
+	}
+	if got := l.Entries[0].StartedDateTime.Sub(time.Unix(0, 0)); got != 2*time.Second {
+		t.Errorf("first entry starts at +%v, want +2s", got)
+	}
+
+	// A page can be the earliest timestamp too.
+	h = shiftHAR()
+	h.Log.Pages[0].StartedDateTime = h.Log.Entries[2].StartedDateTime.Add(-time.Minute)
+	anchor := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	NormalizeStart(h, anchor, ShiftOptions{})
+	if !h.Log.Pages[0].StartedDateTime.Equal(anchor) {
+		t.Errorf("page starts at %v, want %v", h.Log.Pages[0].StartedDateTime, anchor)
+	}
+
+	empty := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{{}}}}
+	NormalizeStart(empty, anchor, ShiftOptions{})
+	if !empty.Log.Entries[0].StartedDateTime.IsZero() {
+		t.Error("log without timestamps shifted")
+	}
+	NormalizeStart(nil, anchor, ShiftOptions{})
+}
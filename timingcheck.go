@@ -0,0 +1,233 @@
+package harkit
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Severity ranks a [TimingIssue] or a [SecurityFinding]. It is encoded as
+// text, e.g. "warning", in JSON.
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // Unusual but possibly genuine, such as entries listed out of order.
+	SeverityWarning                 // Likely wrong, or ambiguous enough to mislead a waterfall.
+	SeverityError                   // Invalid under the HAR specification.
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	if s < SeverityInfo || s > SeverityError {
+		return nil, fmt.Errorf("harkit: invalid severity %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for _, v := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if string(text) == v.String() {
+			*s = v
+			return nil
+		}
+	}
+	return fmt.Errorf("harkit: unknown severity %q", text)
+}
+
+// Kinds of [TimingIssue].
+const (
+	TimingNegative = "negative" // A phase is negative, other than -1 for an optional phase.
+	TimingTotal    = "total"    // Entry.Time differs from the total of the timings.
+	TimingOverlap  = "overlap"  // An HTTP/1.x exchange starts on a connection before the previous one ended.
+	TimingOrder    = "order"    // An entry starts before the entry listed before it.
+	TimingSSL      = "ssl"      // ssl exceeds connect, which includes it.
+)
+
+// TimingIssue is a problem with the timings of an entry, see
+// [CheckTimings].
+type TimingIssue struct {
+	Index    int    // Index of the entry in Log.Entries.
+	Other    int    // Index of the other entry involved in an overlap or order issue, -1 otherwise.
+	URL      string // Request URL.
+	Kind     string // TimingNegative, TimingTotal, TimingOverlap, TimingOrder or TimingSSL.
+	Severity Severity
+	Message  string
+}
+
+func (i TimingIssue) String() string {
+	return fmt.Sprintf("entries[%d] %s: %s: %s", i.Index, i.URL, i.Severity, i.Message)
+}
+
+// timingSlack is the overlap tolerated between exchanges on a connection,
+// in milliseconds, since StartedDateTime is only recorded to the
+// millisecond.
+const timingSlack = 1
+
+// CheckTimings reports the timings of log a waterfall cannot draw
+// faithfully:
+//   - negative phases, as errors, but -1 for the optional blocked, dns,
+//     connect and ssl phases;
+//   - ssl exceeding connect, as errors;
+//   - Entry.Time differing from the total of the timings by more than
+//     harfile.TimeTolerance, as warnings;
+//   - entries sharing an HTTP/1.x Connection whose exchanges overlap, the
+//     later one sending its request before the earlier one was received,
+//     as warnings; HTTP/2 and HTTP/3 entries multiplex and are skipped;
+//   - entries starting before the entry listed before them, as info.
+//
+// Entries without timings are only checked for order.
+func CheckTimings(log *harfile.Log) []TimingIssue {
+	var issues, overlaps []TimingIssue
+	issue := func(i, other int, kind string, severity Severity, format string, args ...any) TimingIssue {
+		issue := TimingIssue{Index: i, Other: other, Kind: kind, Severity: severity, Message: fmt.Sprintf(format, args...)}
+		if e := log.Entries[i]; e.Request != nil {
+			issue.URL = e.Request.URL
+		}
+		return issue
+	}
+	report := func(i, other int, kind string, severity Severity, format string, args ...any) {
+		issues = append(issues, issue(i, other, kind, severity, format, args...))
+	}
+
+	connections := make(map[string][]int)
+	prev := -1
+	for i, e := range log.Entries {
+		if e == nil {
+			continue
+		}
+		if prev >= 0 && e.StartedDateTime.Before(log.Entries[prev].StartedDateTime) {
+			report(i, prev, TimingOrder, SeverityInfo, "starts %s before entries[%d]",
+				log.Entries[prev].StartedDateTime.Sub(e.StartedDateTime), prev)
+		}
+		prev = i
+
+		t := e.Timings
+		if t == nil {
+			continue
+		}
+		for _, p := range timingPhases(t) {
+			if *p.value < p.min {
+				report(i, -1, TimingNegative, SeverityError, "%s is %g", p.name, *p.value)
+			}
+		}
+		if t.Connect >= 0 && t.Ssl > t.Connect {
+			report(i, -1, TimingSSL, SeverityError, "ssl %g exceeds connect %g", t.Ssl, t.Connect)
+		}
+		if total := t.Total(); math.Abs(e.Time-total) > harfile.TimeTolerance {
+			report(i, -1, TimingTotal, SeverityWarning, "time %g but timings total %g", e.Time, total)
+		}
+		if e.Connection != "" && !multiplexed(e) {
+			connections[e.Connection] = append(connections[e.Connection], i)
+		}
+	}
+
+	for _, indexes := range connections {
+		slices.SortStableFunc(indexes, func(a, b int) int {
+			return sendStart(log.Entries[a]).Compare(sendStart(log.Entries[b]))
+		})
+		for k := 1; k < len(indexes); k++ {
+			before, e := log.Entries[indexes[k-1]], log.Entries[indexes[k]]
+			end := before.StartedDateTime.Add(millis(before.Time))
+			if gap := end.Sub(sendStart(e)); gap > millis(timingSlack) {
+				overlaps = append(overlaps, issue(indexes[k], indexes[k-1], TimingOverlap, SeverityWarning,
+					"sends %s before entries[%d] ends on connection %s", gap, indexes[k-1], e.Connection))
+			}
+		}
+	}
+	// Map iteration is random: list overlaps by entry.
+	slices.SortFunc(overlaps, func(a, b TimingIssue) int { return cmp.Compare(a.Index, b.Index) })
+	return append(issues, overlaps...)
+}
+
+// timingPhase is a phase of [harfile.Timings] and its least valid value.
+type timingPhase struct {
+	name  string
+	value *float64
+	min   float64
+}
+
+func timingPhases(t *harfile.Timings) []timingPhase {
+	return []timingPhase{
+		{"blocked", &t.Blocked, -1},
+		{"dns", &t.DNS, -1},
+		{"connect", &t.Connect, -1},
+		{"ssl", &t.Ssl, -1},
+		{"send", &t.Send, 0},
+		{"wait", &t.Wait, 0},
+		{"receive", &t.Receive, 0},
+	}
+}
+
+// multiplexed reports whether e was sent over HTTP/2 or HTTP/3, whose
+// connections carry concurrent exchanges.
+func multiplexed(e *harfile.Entry) bool {
+	version := ""
+	if e.Request != nil {
+		version = strings.ToLower(e.Request.HTTPVersion)
+	}
+	return strings.HasPrefix(version, "http/2") || strings.HasPrefix(version, "http/3") ||
+		version == "h2" || version == "h3"
+}
+
+// sendStart returns when e started sending its request: after its
+// blocked, dns and connect phases.
+func sendStart(e *harfile.Entry) time.Time {
+	start := e.StartedDateTime
+	t := e.Timings
+	if t == nil {
+		return start
+	}
+	for _, ms := range []float64{t.Blocked, t.DNS, t.Connect} {
+		start = start.Add(millis(max(ms, 0)))
+	}
+	if t.Connect < 0 && t.Ssl > 0 {
+		start = start.Add(millis(t.Ssl))
+	}
+	return start
+}
+
+// ClampTimings fixes the mechanical timing issues of log, those
+// [CheckTimings] reports as TimingNegative or TimingTotal, and returns the
+// number of entries changed: negative optional phases become -1, negative
+// send, wait and receive phases 0, and Time is set to the total of the
+// timings. Overlaps, order and ssl issues are left alone, since they
+// cannot be settled without knowing which value is wrong.
+func ClampTimings(log *harfile.Log) int {
+	changed := 0
+	for _, e := range log.Entries {
+		if e == nil || e.Timings == nil {
+			continue
+		}
+		fixed := false
+		for _, p := range timingPhases(e.Timings) {
+			if *p.value < p.min {
+				*p.value = p.min
+				fixed = true
+			}
+		}
+		if total := e.Timings.Total(); math.Abs(e.Time-total) > harfile.TimeTolerance {
+			e.Time = total
+			fixed = true
+		}
+		if fixed {
+			changed++
+		}
+	}
+	return changed
+}
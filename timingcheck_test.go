@@ -0,0 +1,158 @@
+package harkit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// plainTimings returns timings without optional phases, totalling ms
+// milliseconds.
+func plainTimings(ms float64) harfile.Timings {
+	return harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: ms - 2, Receive: 1}
+}
+
+// connEntry returns an entry sent over connection with version, started at
+// offset and taking ms milliseconds.
+func connEntry(connection, version string, offset time.Duration, ms float64) *harfile.Entry {
+	e := timedEntry("https://example.com/", offset, plainTimings(ms))
+	e.Connection = connection
+	e.Request.HTTPVersion = version
+	return e
+}
+
+func TestCheckTimings(t *testing.T) {
+	wrongTime := timedEntry("https://example.com/", 0, plainTimings(10))
+	wrongTime.Time = 12
+	rounded := timedEntry("https://example.com/", 0, plainTimings(10))
+	rounded.Time += harfile.TimeTolerance / 2
+
+	tests := []struct {
+		name    string
+		entries []*harfile.Entry
+		kinds   string // Kinds reported, separated by spaces.
+	}{
+		{"consistent", []*harfile.Entry{
+			timedEntry("https://example.com/", 0, harfile.Timings{Blocked: 2, DNS: 3, Connect: 10, Ssl: 6, Send: 1, Wait: 20, Receive: 4}),
+		}, ""},
+		{"optional phases at -1", []*harfile.Entry{timedEntry("https://example.com/", 0, plainTimings(10))}, ""},
+		{"negative optional phase", []*harfile.Entry{
+			timedEntry("https://example.com/", 0, harfile.Timings{Blocked: -2, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 5, Receive: 1}),
+		}, TimingNegative},
+		{"send at -1", []*harfile.Entry{
+			timedEntry("https://example.com/", 0, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: -1, Wait: 5, Receive: -1}),
+		}, TimingNegative + " " + TimingNegative},
+		{"ssl exceeds connect", []*harfile.Entry{
+			timedEntry("https://example.com/", 0, harfile.Timings{Blocked: -1, DNS: -1, Connect: 5, Ssl: 8, Send: 1, Wait: 5, Receive: 1}),
+		}, TimingSSL},
+		{"ssl without connect", []*harfile.Entry{
+			timedEntry("https://example.com/", 0, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: 8, Send: 1, Wait: 5, Receive: 1}),
+		}, ""},
+		{"wrong time", []*harfile.Entry{wrongTime}, TimingTotal},
+		{"time within tolerance", []*harfile.Entry{rounded}, ""},
+		{"out of order", []*harfile.Entry{
+			connEntry("", "HTTP/1.1", 50*time.Millisecond, 10),
+			nil,
+			connEntry("", "HTTP/1.1", 0, 10),
+		}, TimingOrder},
+		{"without timings", []*harfile.Entry{
+			{StartedDateTime: testStart.Add(time.Second), Request: &harfile.Request{}, Time: 5},
+			{StartedDateTime: testStart, Request: &harfile.Request{}, Time: 7},
+		}, TimingOrder},
+		{"HTTP/1.1 overlap", []*harfile.Entry{
+			connEntry("1", "HTTP/1.1", 0, 100),
+			connEntry("1", "HTTP/1.1", 50*time.Millisecond, 100),
+		}, TimingOverlap},
+		{"HTTP/1.1 in turn", []*harfile.Entry{
+			connEntry("1", "HTTP/1.1", 0, 100),
+			connEntry("1", "HTTP/1.1", 100*time.Millisecond, 100),
+			connEntry("1", "HTTP/1.1", 200*time.Millisecond+timingSlack*time.Millisecond, 100),
+		}, ""},
+		{"HTTP/1.1 on other connections", []*harfile.Entry{
+			connEntry("1", "HTTP/1.1", 0, 100),
+			connEntry("2", "HTTP/1.1", 50*time.Millisecond, 100),
+			connEntry("", "HTTP/1.1", 50*time.Millisecond, 100),
+		}, ""},
+		{"HTTP/2 multiplexed", []*harfile.Entry{
+			connEntry("1", "HTTP/2.0", 0, 100),
+			connEntry("1", "h2", 50*time.Millisecond, 100),
+			connEntry("1", "HTTP/3", 60*time.Millisecond, 100),
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds []string
+			for _, issue := range CheckTimings(&harfile.Log{Entries: tt.entries}) {
+				kinds = append(kinds, issue.Kind)
+			}
+			if got := strings.Join(kinds, " "); got != tt.kinds {
+				t.Errorf("kinds = %q, want %q", got, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestCheckTimingsIssue(t *testing.T) {
+	// The connection is opened by the first entry; the second waits in
+	// blocked, then sends 10ms before the first one ends.
+	first := connEntry("7", "HTTP/1.1", 0, 100)
+	first.Request.URL = "https://example.com/first"
+	second := timedEntry("https://example.com/second", 40*time.Millisecond,
+		harfile.Timings{Blocked: 50, DNS: -1, Connect: -1, Ssl: -1, Send: -3, Wait: 20, Receive: 1})
+	second.Connection = "7"
+	log := &harfile.Log{Entries: []*harfile.Entry{first, second}}
+
+	issues := CheckTimings(log)
+	want := []TimingIssue{
+		{Index: 1, Other: -1, URL: "https://example.com/second", Kind: TimingNegative, Severity: SeverityError, Message: "send is -3"},
+		{Index: 1, Other: 0, URL: "https://example.com/second", Kind: TimingOverlap, Severity: SeverityWarning, Message: "sends 10ms before entries[0] ends on connection 7"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %v", issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issues[%d] = %+v, want %+v", i, issues[i], want[i])
+		}
+	}
+	if got := issues[1].String(); got != "entries[1] https://example.com/second: warning: sends 10ms before entries[0] ends on connection 7" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := Severity(7).String(); got != "Severity(7)" {
+		t.Errorf("unknown severity = %q", got)
+	}
+}
+
+func TestClampTimings(t *testing.T) {
+	consistent := timedEntry("https://example.com/", 0, plainTimings(10))
+	wrongTime := timedEntry("https://example.com/", 0, plainTimings(10))
+	wrongTime.Time = 50
+	negative := timedEntry("https://example.com/", 0,
+		harfile.Timings{Blocked: -5, DNS: -1, Connect: 4, Ssl: 9, Send: -1, Wait: 10, Receive: -2})
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		consistent, nil, {StartedDateTime: testStart, Request: &harfile.Request{}, Time: 3}, wrongTime, negative,
+	}}
+
+	if n := ClampTimings(log); n != 2 {
+		t.Errorf("ClampTimings = %d, want 2", n)
+	}
+	if consistent.Time != 10 || wrongTime.Time != 10 || log.Entries[2].Time != 3 {
+		t.Errorf("times %g, %g and %g, want 10, 10 and 3", consistent.Time, wrongTime.Time, log.Entries[2].Time)
+	}
+	want := &harfile.Timings{Blocked: -1, DNS: -1, Connect: 4, Ssl: 9, Send: 0, Wait: 10, Receive: 0}
+	if !reflect.DeepEqual(negative.Timings, want) || negative.Time != 14 {
+		t.Errorf("timings %+v and time %g, want %+v and 14", *negative.Timings, negative.Time, *want)
+	}
+
+	// Only the ssl issue is left, which ClampTimings cannot settle.
+	issues := CheckTimings(log)
+	if len(issues) != 1 || issues[0].Kind != TimingSSL {
+		t.Errorf("issues left: %v", issues)
+	}
+	if n := ClampTimings(log); n != 0 {
+		t.Errorf("second pass changed %d entries", n)
+	}
+}
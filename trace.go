@@ -0,0 +1,181 @@
+package harkit
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// TraceCollector measures the phases of a request through the hooks of an
+// [httptrace.ClientTrace] and converts them into HAR timings. A collector
+// measures a single request; its methods are safe for concurrent use.
+type TraceCollector struct {
+	mu    sync.Mutex
+	clock Clock
+
+	start        time.Time
+	getConn      time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	done         time.Time
+	reused       bool
+	remoteAddr   net.Addr
+	localAddr    net.Addr
+}
+
+// NewTraceCollector returns a collector whose measurements start now.
+func NewTraceCollector() *TraceCollector {
+	return newTraceCollector(RealClock)
+}
+
+// newTraceCollector returns a collector telling the time with clock.
+func newTraceCollector(clock Clock) *TraceCollector {
+	return &TraceCollector{clock: clock, start: clock.Now()}
+}
+
+// ClientTrace returns the hooks feeding the collector. Attach them to a
+// request with [httptrace.WithClientTrace].
+func (tc *TraceCollector) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) { tc.mark(&tc.getConn) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			tc.mu.Lock()
+			defer tc.mu.Unlock()
+			tc.gotConn = tc.clock.Now()
+			tc.reused = info.Reused
+			if info.Conn != nil {
+				tc.remoteAddr = info.Conn.RemoteAddr()
+				tc.localAddr = info.Conn.LocalAddr()
+			}
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { tc.mark(&tc.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { tc.mark(&tc.dnsDone) },
+		ConnectStart:         func(string, string) { tc.mark(&tc.connectStart) },
+		ConnectDone:          func(string, string, error) { tc.markLast(&tc.connectDone) },
+		TLSHandshakeStart:    func() { tc.mark(&tc.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tc.markLast(&tc.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { tc.markLast(&tc.wroteRequest) },
+		GotFirstResponseByte: func() { tc.mark(&tc.firstByte) },
+	}
+}
+
+// Done records the end of the response, typically when its body has been
+// read entirely or closed.
+func (tc *TraceCollector) Done() {
+	tc.mark(&tc.done)
+}
+
+// Reused reports whether the request was sent on a pooled connection.
+func (tc *TraceCollector) Reused() bool {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.reused
+}
+
+// ServerIPAddress returns the IP address of the server the request was sent
+// to, or "" when no connection was obtained.
+func (tc *TraceCollector) ServerIPAddress() string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.remoteAddr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(tc.remoteAddr.String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// Connection returns the local port of the connection the request was sent
+// on, which identifies it among the connections to a server, or "" when no
+// connection was obtained.
+func (tc *TraceCollector) Connection() string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.localAddr == nil {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(tc.localAddr.String())
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// mark records the first occurrence of an event.
+func (tc *TraceCollector) mark(t *time.Time) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if t.IsZero() {
+		*t = tc.clock.Now()
+	}
+}
+
+// markLast records the last occurrence of an event, e.g. the last of the
+// parallel dials started by Happy Eyeballs.
+func (tc *TraceCollector) markLast(t *time.Time) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	*t = tc.clock.Now()
+}
+
+// Timings converts the measurements into HAR timings. Phases that did not
+// happen, such as dns, connect and ssl on a reused connection, are -1, and
+// ssl is included in connect as required by the specification. When the
+// request failed, the phases that were not reached are 0 for send, wait and
+// receive and -1 for the others.
+func (tc *TraceCollector) Timings() *harfile.Timings {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	t := &harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}
+	getConn := tc.getConn
+	if getConn.IsZero() {
+		getConn = tc.start
+	}
+
+	if !tc.reused {
+		t.DNS = span(tc.dnsStart, tc.dnsDone)
+		connectEnd := tc.connectDone
+		if !tc.tlsDone.IsZero() {
+			connectEnd = tc.tlsDone
+			t.Ssl = span(tc.tlsStart, tc.tlsDone)
+		}
+		t.Connect = span(tc.connectStart, connectEnd)
+	}
+
+	// Blocked covers the time spent before the first network activity.
+	firstActivity := tc.gotConn
+	for _, ts := range []time.Time{tc.dnsStart, tc.connectStart, tc.tlsStart} {
+		if !tc.reused && !ts.IsZero() && (firstActivity.IsZero() || ts.Before(firstActivity)) {
+			firstActivity = ts
+		}
+	}
+	t.Blocked = span(getConn, firstActivity)
+
+	t.Send = max(span(tc.gotConn, tc.wroteRequest), 0)
+	t.Wait = max(span(tc.wroteRequest, tc.firstByte), 0)
+	t.Receive = max(span(tc.firstByte, tc.done), 0)
+	return t
+}
+
+// span returns the milliseconds elapsed between from and to, or -1 when
+// either is unknown.
+func span(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() {
+		return -1
+	}
+	return max(float64(to.Sub(from))/float64(time.Millisecond), 0)
+}
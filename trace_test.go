@@ -0,0 +1,81 @@
+package harkit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+)
+
+// traced sends a GET of url through client with a fresh collector and reads
+// the whole response.
+func traced(t *testing.T, client *http.Client, url string) *TraceCollector {
+	t.Helper()
+	tc := NewTraceCollector()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tc.ClientTrace()))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	tc.Done()
+	return tc
+}
+
+func TestTraceCollectorReusedConnection(t *testing.T) {
+	for _, tls := range []bool{false, true} {
+		name := "http"
+		if tls {
+			name = "https"
+		}
+		t.Run(name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+			var srv *httptest.Server
+			if tls {
+				srv = httptest.NewTLSServer(handler)
+			} else {
+				srv = httptest.NewServer(handler)
+			}
+			defer srv.Close()
+			client := srv.Client()
+
+			first := traced(t, client, srv.URL)
+			if first.Reused() {
+				t.Fatal("first request on a reused connection")
+			}
+			ft := first.Timings()
+			if ft.Connect < 0 || ft.Send < 0 || ft.Wait < 0 || ft.Receive < 0 {
+				t.Errorf("first timings = %+v", ft)
+			}
+			if tls != (ft.Ssl >= 0) || tls && ft.Ssl > ft.Connect {
+				t.Errorf("ssl %v of connect %v, TLS %v", ft.Ssl, ft.Connect, tls)
+			}
+
+			second := traced(t, client, srv.URL)
+			if !second.Reused() {
+				t.Fatal("second request did not reuse the connection")
+			}
+			st := second.Timings()
+			if st.DNS != -1 || st.Connect != -1 || st.Ssl != -1 {
+				t.Errorf("reused connection timings = %+v, want dns, connect and ssl of -1", st)
+			}
+			if st.Blocked < 0 || st.Send < 0 || st.Wait < 0 || st.Receive < 0 {
+				t.Errorf("reused connection timings = %+v, want measured send, wait and receive", st)
+			}
+		})
+	}
+}
+
+func TestTraceCollectorFailed(t *testing.T) {
+	// No event fired: the request failed before reaching the network.
+	tm := NewTraceCollector().Timings()
+	if tm.Blocked != -1 || tm.DNS != -1 || tm.Connect != -1 || tm.Ssl != -1 || tm.Send != 0 || tm.Wait != 0 || tm.Receive != 0 {
+		t.Errorf("timings = %+v", tm)
+	}
+}
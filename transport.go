@@ -0,0 +1,538 @@
+package harkit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Transport is an [http.RoundTripper] recording every round trip as a HAR
+// entry. An entry is added once the response body has been read to the end
+// or closed, so that the receive time and the body are complete; responses
+// whose body is never closed are not recorded. WebSocket upgrades, whose
+// body is the connection itself, are recorded as soon as the response
+// arrives; see [Transport.RecordWSMessage]. So are text/event-stream
+// responses, which may never end: their events are added to
+// Entry.EventStreamMessages as they are read, instead of the body, and the
+// receive time lasts until the first event; see [WithMaxEvents]. Round
+// trips failing with an error are recorded at once, as browsers do, with
+// status 0, the error in the _error member of the response and the timings
+// reached before the failure.
+//
+// Entries carry the IP address of the peer in ServerIPAddress and the local
+// port of the connection in Connection, so entries sent on the same
+// connection share it. When the base [http.Transport] uses a proxy, the
+// peer is the proxy, and the entry comment names the proxy and the target.
+//
+// A Transport is safe for concurrent use.
+type Transport struct {
+	base http.RoundTripper
+	opts *options
+
+	mu       sync.Mutex
+	upgrades map[*http.Response]*harfile.Entry
+}
+
+// ErrNotWebSocket is returned by [Transport.RecordWSMessage] for a response
+// that is not a WebSocket upgrade recorded by the transport.
+var ErrNotWebSocket = errors.New("harkit: not a recorded WebSocket upgrade")
+
+// NewTransport returns a Transport sending requests through base, or
+// [http.DefaultTransport] when base is nil.
+func NewTransport(base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		base:     base,
+		opts:     newOptions(opts),
+		upgrades: make(map[*http.Response]*harfile.Entry),
+	}
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.opts.records(req.URL.Host, req.URL.Path) {
+		return t.base.RoundTrip(req)
+	}
+	tc := newTraceCollector(t.opts.clock)
+	started := t.opts.clock.Now()
+
+	reqBody := &requestCapture{buf: t.opts.bodyBuffer(req.Header.Get("Content-Type"), t.opts.maxRequestBodySize)}
+	out := req.WithContext(httptrace.WithClientTrace(req.Context(), tc.ClientTrace()))
+	if req.Body != nil && req.Body != http.NoBody {
+		out.Body = reqBody.body(req.Body)
+		if req.GetBody != nil {
+			out.GetBody = func() (io.ReadCloser, error) {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				return reqBody.body(body), nil
+			}
+		}
+	}
+	askedGzip := t.opts.compressedBodies && transparentGzip(req)
+	if askedGzip {
+		// Asking for gzip ourselves keeps the base transport from
+		// decompressing, so the compressed body can be recorded.
+		out.Header = req.Header.Clone()
+		out.Header.Set("Accept-Encoding", "gzip")
+	}
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		tc.Done()
+		t.recordFailure(req, reqBody, err, started, tc)
+		return nil, err
+	}
+	// wire is the response as received, recorded even when resp is
+	// decompressed for the caller.
+	wire := resp
+	gunzip := askedGzip && resp.StatusCode != http.StatusSwitchingProtocols &&
+		strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip")
+	if gunzip {
+		received := *resp
+		received.Header = resp.Header.Clone()
+		wire = &received
+	}
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// The body is the upgraded connection and must stay writable.
+		tc.Done()
+		if entry := t.record(req, reqBody, resp, &limitedBuffer{}, started, tc, true); entry != nil {
+			t.mu.Lock()
+			t.upgrades[resp] = entry
+			t.mu.Unlock()
+			resp.Body = newUpgradeBody(resp.Body, func() { t.closeUpgrade(resp) })
+		}
+		return resp, nil
+	}
+	if isEventStream(resp.Header.Get("Content-Type")) {
+		// The stream may never end: record it now, then its events as they
+		// are read.
+		if gunzip {
+			decompress(resp)
+		}
+		if entry := t.record(req, reqBody, wire, &limitedBuffer{}, started, tc, true); entry != nil {
+			resp.Body = &eventStreamBody{
+				ReadCloser: resp.Body,
+				opts:       t.opts,
+				entry:      entry,
+				tc:         tc,
+				encoded:    resp.Uncompressed || resp.Header.Get("Content-Encoding") != "",
+			}
+		}
+		return resp, nil
+	}
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		buf:        t.opts.bodyBuffer(resp.Header.Get("Content-Type"), t.opts.maxResponseBodySize),
+		finish: func(body *limitedBuffer) {
+			tc.Done()
+			t.record(req, reqBody, wire, body, started, tc, false)
+		},
+	}
+	if gunzip {
+		decompress(resp)
+	}
+	return resp, nil
+}
+
+// transparentGzip reports whether an [http.Transport] would ask for gzip
+// on behalf of the caller, and decompress the response, for req.
+func transparentGzip(req *http.Request) bool {
+	return req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != http.MethodHead
+}
+
+// decompress has the caller read the decoded body of a gzip response, as
+// [http.Transport] does when it asked for gzip itself.
+func decompress(resp *http.Response) {
+	resp.Body = &gzipBody{body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// gzipBody decompresses a response body, reading its gzip header on the
+// first call to Read.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil && b.err == nil {
+		b.zr, b.err = gzip.NewReader(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
+
+// record records a round trip. With open, the entry is still to be
+// updated, by WebSocket frames or events.
+func (t *Transport) record(req *http.Request, reqBody *requestCapture, resp *http.Response, respBody *limitedBuffer, started time.Time, tc *TraceCollector, open bool) *harfile.Entry {
+	hreq, err := reqBody.request(req)
+	if err != nil {
+		return nil
+	}
+	hresp, err := responseFromHTTP(resp, respBody, t.opts.compressedBodies)
+	if err != nil {
+		return nil
+	}
+	hreq.HTTPVersion = httpVersion(resp.ProtoMajor, resp.ProtoMinor, resp.Proto, resp.TLS)
+	hresp.HTTPVersion = hreq.HTTPVersion
+	t.opts.transformBodies(hreq, hresp)
+	timings := tc.Timings()
+	entry := &harfile.Entry{
+		StartedDateTime: started,
+		Time:            timings.Total(),
+		Request:         hreq,
+		Response:        hresp,
+		Cache:           t.cache(req, resp),
+		Timings:         timings,
+		ServerIPAddress: tc.ServerIPAddress(),
+		Connection:      tc.Connection(),
+	}
+	if t.opts.security {
+		entry.SecurityDetails = harfile.NewSecurityDetails(resp.TLS)
+	}
+	return t.add(req, entry, open)
+}
+
+// recordFailure records a round trip that failed with err, such as a DNS
+// failure, a refused connection or a canceled request, the way browsers do:
+// the response has status 0 and the error in _error, and the timings cover
+// the phases reached before the failure.
+func (t *Transport) recordFailure(req *http.Request, reqBody *requestCapture, err error, started time.Time, tc *TraceCollector) *harfile.Entry {
+	hreq, reqErr := reqBody.request(req)
+	if reqErr != nil {
+		return nil
+	}
+	hreq.HTTPVersion = httpVersion(req.ProtoMajor, req.ProtoMinor, req.Proto, nil)
+	t.opts.transformBodies(hreq, nil)
+	timings := tc.Timings()
+	entry := &harfile.Entry{
+		StartedDateTime: started,
+		Time:            timings.Total(),
+		Request:         hreq,
+		Response: &harfile.Response{
+			Cookies:     []*harfile.Cookie{},
+			Headers:     []*harfile.NameValuePair{},
+			Content:     &harfile.Content{MimeType: "x-unknown"},
+			HeadersSize: -1,
+			BodySize:    -1,
+			Error:       err.Error(),
+		},
+		Cache:           &harfile.Cache{},
+		Timings:         timings,
+		ServerIPAddress: tc.ServerIPAddress(),
+		Connection:      tc.Connection(),
+	}
+	return t.add(req, entry, false)
+}
+
+// add completes entry with what is known of the route of req, and records
+// it unless filtered out, see [Recorder.add] for open.
+func (t *Transport) add(req *http.Request, entry *harfile.Entry, open bool) *harfile.Entry {
+	if proxy := t.proxyURL(req); proxy != nil && entry.ServerIPAddress != "" {
+		via := "sent through"
+		if req.URL.Scheme == "https" {
+			via = "tunneled through"
+		}
+		entry.Comment = fmt.Sprintf("%s proxy %s to %s, serverIPAddress being the proxy's", via, proxy.Host, req.URL.Host)
+	}
+	tagTruncated(entry)
+	t.opts.roundTimings(entry)
+	if !t.opts.keeps(entry) {
+		return nil
+	}
+	if t.opts.policy != nil {
+		t.opts.policy.entry(entry)
+	}
+
+	t.opts.recorder.add(entry, t.opts.hooks, open)
+	return entry
+}
+
+// proxyURL returns the proxy the base transport sends req through, if any.
+// Only [http.Transport] proxies are known.
+func (t *Transport) proxyURL(req *http.Request) *url.URL {
+	base, ok := t.base.(*http.Transport)
+	if !ok || base.Proxy == nil {
+		return nil
+	}
+	u, err := base.Proxy(req)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// RecordWSMessage appends a frame to the entry of a WebSocket upgrade, resp
+// being the 101 response returned by the transport. direction is
+// [harfile.WebSocketSend] or [harfile.WebSocketReceive], and payload is
+// base64 encoded for binary frames. Frames are timestamped on arrival, so
+// call it as they are sent or received. Once the connection, resp.Body, is
+// closed, it returns [ErrNotWebSocket].
+func (t *Transport) RecordWSMessage(resp *http.Response, direction string, opcode int, payload []byte) error {
+	msg := harfile.NewWebSocketMessage(direction, opcode, payload, t.opts.clock.Now())
+	// t.mu is held until the frame is added, so that closeUpgrade cannot
+	// release the entry in between.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.upgrades[resp]
+	if !ok {
+		return ErrNotWebSocket
+	}
+	t.opts.recorder.update(func() {
+		entry.WebSocketMessages = append(entry.WebSocketMessages, msg)
+	})
+	return nil
+}
+
+// closeUpgrade ends the recording of the frames of the WebSocket upgrade
+// resp, once its connection is closed, and releases its entry.
+func (t *Transport) closeUpgrade(resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if entry, ok := t.upgrades[resp]; ok {
+		delete(t.upgrades, resp)
+		t.opts.recorder.release(entry)
+	}
+}
+
+// Recorder returns the [Recorder] holding the recorded entries.
+func (t *Transport) Recorder() *Recorder {
+	return t.opts.recorder
+}
+
+// HAR returns a copy of the recorded log.
+func (t *Transport) HAR() *harfile.HAR {
+	return t.opts.recorder.Snapshot()
+}
+
+// recordingBody keeps a copy of a body while it is read, and calls finish
+// once, at EOF or on Close, with everything read.
+type recordingBody struct {
+	io.ReadCloser
+	buf    limitedBuffer
+	once   sync.Once
+	finish func(*limitedBuffer)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		b.once.Do(func() { b.finish(&b.buf) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.finish(&b.buf) })
+	return err
+}
+
+// requestCapture keeps a copy of a request body while the base transport
+// sends it, possibly from another goroutine, so that the caps and skipped
+// types of the options apply without buffering the whole body first.
+type requestCapture struct {
+	mu  sync.Mutex
+	buf limitedBuffer
+}
+
+func (c *requestCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+// body returns body copying what is read into c. The capture starts over,
+// as a body is only read again when the base transport retries the request.
+func (c *requestCapture) body(body io.ReadCloser) io.ReadCloser {
+	c.mu.Lock()
+	c.buf = limitedBuffer{limit: c.buf.limit, discard: c.buf.discard}
+	c.mu.Unlock()
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(body, c), body}
+}
+
+// request converts req with the body captured so far.
+func (c *requestCapture) request(req *http.Request) (*harfile.Request, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return requestFromHTTP(req, &c.buf)
+}
+
+// upgradeBody is the connection of a recorded protocol upgrade, calling
+// closed once on Close.
+type upgradeBody struct {
+	io.ReadCloser
+	once   sync.Once
+	closed func()
+}
+
+// writableUpgradeBody is an upgradeBody over a writable connection, as
+// [http.Transport] returns, so that callers asserting io.ReadWriteCloser
+// still can.
+type writableUpgradeBody struct {
+	*upgradeBody
+}
+
+// newUpgradeBody wraps the body of a 101 response, keeping it writable when
+// it is.
+func newUpgradeBody(body io.ReadCloser, closed func()) io.ReadCloser {
+	b := &upgradeBody{ReadCloser: body, closed: closed}
+	if _, ok := body.(io.Writer); ok {
+		return writableUpgradeBody{b}
+	}
+	return b
+}
+
+func (b *upgradeBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.closed)
+	return err
+}
+
+func (b writableUpgradeBody) Write(p []byte) (int, error) {
+	return b.ReadCloser.(io.Writer).Write(p)
+}
+
+// limitedBuffer keeps the first limit bytes written to it, or all of them
+// when limit is not positive, or none when discard is set, and counts the
+// total.
+type limitedBuffer struct {
+	limit   int64
+	discard bool
+	buf     bytes.Buffer
+	total   int64
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	keep := p
+	switch {
+	case b.discard:
+		keep = nil
+	case b.limit > 0:
+		keep = p[:min(int64(len(p)), max(b.limit-int64(b.buf.Len()), 0))]
+	}
+	b.buf.Write(keep)
+	return len(p), nil
+}
+
+func (b *limitedBuffer) truncated() bool {
+	return b.total > int64(b.buf.Len())
+}
+
+func truncatedComment(b *limitedBuffer) string {
+	if b.buf.Len() == 0 {
+		return fmt.Sprintf("body of %d bytes not recorded", b.total)
+	}
+	return fmt.Sprintf("body truncated to %d of %d bytes", b.buf.Len(), b.total)
+}
+
+// requestFromHTTP converts req and its captured body, noting truncation.
+func requestFromHTTP(req *http.Request, body *limitedBuffer) (*harfile.Request, error) {
+	r, err := harfile.FromHTTPRequest(req, body.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if body.truncated() {
+		r.BodySize = body.total
+		if r.PostData == nil {
+			r.PostData = &harfile.PostData{MimeType: req.Header.Get("Content-Type"), Params: []*harfile.Param{}}
+		}
+		r.PostData.Comment = truncatedComment(body)
+		r.PostData.Truncated = true
+	}
+	return r, nil
+}
+
+// tagTruncated tags e with [harfile.TagTruncated] when a body limit cut its
+// request or response body.
+func tagTruncated(e *harfile.Entry) {
+	if e.Request.PostData != nil && e.Request.PostData.Truncated || e.Response.Content != nil && e.Response.Content.Truncated {
+		e.AddTag(harfile.TagTruncated)
+	}
+}
+
+// cache returns the cache state reported by the cache observer, if any.
+func (t *Transport) cache(req *http.Request, resp *http.Response) *harfile.Cache {
+	if t.opts.cacheObserver != nil {
+		if c := t.opts.cacheObserver.ObserveCache(req, resp); c != nil {
+			return c
+		}
+	}
+	return &harfile.Cache{}
+}
+
+// httpVersion returns the canonical httpVersion of an exchange: the protocol
+// negotiated by ALPN when the connection used TLS, otherwise the one given
+// by major and minor. proto is kept when neither names a known version.
+func httpVersion(major, minor int, proto string, state *tls.ConnectionState) string {
+	if state != nil {
+		if p := harfile.ProtocolOf(state.NegotiatedProtocol); p != harfile.ProtocolUnknown {
+			return p.String()
+		}
+	}
+	if p := harfile.ProtocolOf(fmt.Sprintf("HTTP/%d.%d", major, minor)); p != harfile.ProtocolUnknown {
+		return p.String()
+	}
+	return proto
+}
+
+// responseFromHTTP converts resp and its captured body, noting truncation. A
+// truncated body usually cannot be decompressed, in which case
+// [harfile.FromHTTPResponse] keeps it as received, with Content-Encoding left
+// in place among the headers. Its sizes are then the transferred ones, the
+// decoded size being unknown. With raw, a compressed body is kept as
+// received too, see [CaptureCompressedBodies]. A body the base transport
+// decompressed has an unknown transferred size.
+func responseFromHTTP(resp *http.Response, body *limitedBuffer, raw bool) (*harfile.Response, error) {
+	r, err := harfile.FromHTTPResponse(resp, body.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if body.truncated() {
+		r.BodySize = body.total
+		r.Content.Size = body.total
+		r.Content.Compression = 0
+		r.Content.Comment = truncatedComment(body)
+		r.Content.Truncated = true
+	} else if enc := resp.Header.Get("Content-Encoding"); raw && enc != "" {
+		r.Content.Text = base64.StdEncoding.EncodeToString(body.buf.Bytes())
+		r.Content.Encoding = "base64"
+		r.Content.ContentEncoding = enc
+	}
+	if resp.Uncompressed {
+		r.BodySize = harfile.SizeUnknown
+		r.Content.Compression = 0
+	}
+	return r, nil
+}
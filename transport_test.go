@@ -0,0 +1,726 @@
+package harkit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Mathious6/harkit/harfile"
+	"github.com/andybalholm/brotli"
+)
+
+// upgradeServer answers every request with a 101 response, then echoes
+// what it reads on the connection.
+func upgradeServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		io.Copy(conn, rw)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dialUpgrade(t *testing.T, tr *Transport, url string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	return resp
+}
+
+func TestTransportWebSocket(t *testing.T) {
+	srv := upgradeServer(t)
+	tr := NewTransport(nil)
+	resp := dialUpgrade(t, tr, srv.URL)
+
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatal("upgraded body is not writable")
+	}
+	if _, err := io.WriteString(conn, "ping\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.RecordWSMessage(resp, harfile.WebSocketSend, harfile.OpcodeText, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Fatalf("echo = %q, %v", line, err)
+	}
+	if err := tr.RecordWSMessage(resp, harfile.WebSocketReceive, harfile.OpcodeBinary, []byte{0xff}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := tr.HAR().Log.Entries
+	if len(entries) != 1 {
+		t.Fatalf("recorded %d entries, want 1", len(entries))
+	}
+	msgs := entries[0].WebSocketMessages
+	if len(msgs) != 2 || msgs[0].Data != "ping" || msgs[1].Type != harfile.WebSocketReceive || msgs[1].Data != "/w==" {
+		t.Errorf("messages = %+v", msgs)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tr.mu.Lock()
+	left := len(tr.upgrades)
+	tr.mu.Unlock()
+	if left != 0 {
+		t.Errorf("%d upgrades still tracked after Close", left)
+	}
+	if err := tr.RecordWSMessage(resp, harfile.WebSocketSend, harfile.OpcodeText, []byte("late")); !errors.Is(err, ErrNotWebSocket) {
+		t.Errorf("RecordWSMessage after Close = %v, want ErrNotWebSocket", err)
+	}
+	conn.Close()
+}
+
+func TestTransportWebSocketManyConnections(t *testing.T) {
+	srv := upgradeServer(t)
+	tr := NewTransport(nil)
+	for range 20 {
+		resp := dialUpgrade(t, tr, srv.URL)
+		tr.RecordWSMessage(resp, harfile.WebSocketSend, harfile.OpcodeText, []byte("x"))
+		resp.Body.Close()
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.upgrades) != 0 {
+		t.Errorf("%d closed upgrades still tracked", len(tr.upgrades))
+	}
+}
+
+func TestRecordWSMessageUnknownResponse(t *testing.T) {
+	tr := NewTransport(nil)
+	if err := tr.RecordWSMessage(&http.Response{}, harfile.WebSocketSend, harfile.OpcodeText, nil); !errors.Is(err, ErrNotWebSocket) {
+		t.Errorf("err = %v, want ErrNotWebSocket", err)
+	}
+}
+
+// get sends a GET to rawURL through client and reads the body, so that the
+// entry is recorded.
+func get(t *testing.T, client *http.Client, rawURL string) {
+	t.Helper()
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func TestTransportServerAddress(t *testing.T) {
+	tests := []struct {
+		network, addr, ip string
+	}{
+		{"tcp4", "127.0.0.1:0", "127.0.0.1"},
+		{"tcp6", "[::1]:0", "::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			ln, err := net.Listen(tt.network, tt.addr)
+			if err != nil {
+				t.Skipf("no %s listener: %v", tt.network, err)
+			}
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "ok")
+			}))
+			srv.Listener.Close()
+			srv.Listener = ln
+			srv.Start()
+			defer srv.Close()
+
+			tr := NewTransport(&http.Transport{})
+			client := &http.Client{Transport: tr}
+			get(t, client, srv.URL+"/a")
+			get(t, client, srv.URL+"/b") // Reuses the connection.
+			other := &http.Client{Transport: NewTransport(&http.Transport{}, WithRecorder(tr.Recorder()))}
+			get(t, other, srv.URL+"/c") // A connection of its own.
+
+			entries := tr.HAR().Log.Entries
+			if len(entries) != 3 {
+				t.Fatalf("%d entries, want 3", len(entries))
+			}
+			for _, e := range entries {
+				if e.ServerIPAddress != tt.ip {
+					t.Errorf("%s: ServerIPAddress = %q, want %q", e.Request.URL, e.ServerIPAddress, tt.ip)
+				}
+				if e.Connection == "" || e.Comment != "" {
+					t.Errorf("%s: connection %q, comment %q", e.Request.URL, e.Connection, e.Comment)
+				}
+			}
+			if entries[0].Connection != entries[1].Connection {
+				t.Errorf("reused connection recorded as %s and %s", entries[0].Connection, entries[1].Connection)
+			}
+			if entries[2].Connection == entries[0].Connection {
+				t.Errorf("separate connections both recorded as %s", entries[0].Connection)
+			}
+		})
+	}
+}
+
+// forwardProxy is a proxy forwarding plain requests and tunneling CONNECT
+// ones, counting both.
+type forwardProxy struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (p *forwardProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.requests = append(p.requests, r.Method+" "+r.Host)
+	p.mu.Unlock()
+	if r.Method != http.MethodConnect {
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	rw.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+	rw.Flush()
+	go io.Copy(upstream, rw)
+	io.Copy(conn, upstream)
+}
+
+func TestTransportProxy(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+	fp := &forwardProxy{}
+	proxy := httptest.NewServer(fp)
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	base := secure.Client().Transport.(*http.Transport).Clone()
+	base.Proxy = http.ProxyURL(proxyURL)
+	tr := NewTransport(base)
+	client := &http.Client{Transport: tr}
+	get(t, client, plain.URL+"/plain")
+	get(t, client, secure.URL+"/secure")
+
+	entries := tr.HAR().Log.Entries
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(entries))
+	}
+	target := func(raw string) string { u, _ := url.Parse(raw); return u.Host }
+	want := []string{
+		fmt.Sprintf("sent through proxy %s to %s, serverIPAddress being the proxy's", proxyURL.Host, target(plain.URL)),
+		fmt.Sprintf("tunneled through proxy %s to %s, serverIPAddress being the proxy's", proxyURL.Host, target(secure.URL)),
+	}
+	for i, e := range entries {
+		if e.Comment != want[i] {
+			t.Errorf("comment = %q, want %q", e.Comment, want[i])
+		}
+		if e.ServerIPAddress != "127.0.0.1" {
+			t.Errorf("ServerIPAddress = %q", e.ServerIPAddress)
+		}
+	}
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if got := strings.Join(fp.requests, ", "); got != "GET "+target(plain.URL)+", CONNECT "+target(secure.URL) {
+		t.Errorf("proxy saw %s", got)
+	}
+}
+
+func TestResponseFromHTTPTruncatedCompressed(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(bytes.Repeat([]byte("0123456789"), 100))
+	zw.Close()
+	resp := &http.Response{
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		Header: http.Header{
+			harfile.HeaderOrderKey: {"x-z", "content-encoding", "content-type"},
+			"X-Z":                  {"1"},
+			"Content-Encoding":     {"gzip"},
+			"Content-Type":         {"text/plain"},
+		},
+	}
+	body := limitedBuffer{limit: 8}
+	body.Write(gz.Bytes())
+
+	// The prefix cannot be decompressed, so it is kept as received with the
+	// headers in their recorded order.
+	r, err := responseFromHTTP(resp, &body, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, h := range r.Headers {
+		names = append(names, h.Name)
+	}
+	if got := strings.Join(names, " "); got != "X-Z Content-Encoding Content-Type" {
+		t.Errorf("headers = %q", got)
+	}
+	if r.Content.Size != int64(gz.Len()) || r.BodySize != int64(gz.Len()) || !r.Content.Truncated || r.Content.Comment == "" {
+		t.Errorf("size %d, bodySize %d, truncated %v, comment %q", r.Content.Size, r.BodySize, r.Content.Truncated, r.Content.Comment)
+	}
+}
+
+func TestTransportSecurityDetails(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+
+	tr := NewTransport(srv.Client().Transport, WithSecurityDetails())
+	client := &http.Client{Transport: tr}
+	for _, url := range []string{srv.URL, plain.URL} {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	entries := tr.HAR().Log.Entries
+	d := entries[0].SecurityDetails
+	if d == nil {
+		t.Fatal("no security details for HTTPS")
+	}
+	if !strings.HasPrefix(d.Protocol, "TLS 1.") || d.Cipher == "" || !d.Verified {
+		t.Errorf("protocol %q, cipher %q, verified %v", d.Protocol, d.Cipher, d.Verified)
+	}
+	if d.SubjectName != "O=Acme Co" || d.Issuer != "O=Acme Co" || !slices.Contains(d.SanList, "example.com") || !slices.Contains(d.SanList, "127.0.0.1") {
+		t.Errorf("subject %q, issuer %q, sans %q", d.SubjectName, d.Issuer, d.SanList)
+	}
+	if d.ValidFrom >= d.ValidTo {
+		t.Errorf("valid from %d to %d", d.ValidFrom, d.ValidTo)
+	}
+	if entries[1].SecurityDetails != nil {
+		t.Errorf("plain HTTP details = %+v", entries[1].SecurityDetails)
+	}
+
+	// Without the option nothing is recorded.
+	tr = NewTransport(srv.Client().Transport)
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if d := tr.HAR().Log.Entries[0].SecurityDetails; d != nil {
+		t.Errorf("details recorded without the option: %+v", d)
+	}
+}
+
+func TestTransportBodyLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+	sent := strings.Repeat("0123456789", 10)
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		typ        string
+		req, resp  int  // Bytes kept from each body.
+		truncated  bool // Whether the request body is flagged.
+		respTrunc  bool
+		reqComment string
+	}{
+		{"no limit", nil, "text/plain", 100, 100, false, false, ""},
+		{"both", []Option{WithMaxBodySize(8)}, "text/plain", 8, 8, true, true, "body truncated to 8 of 100 bytes"},
+		{"request only", []Option{WithMaxRequestBodySize(8)}, "text/plain", 8, 100, true, false, "body truncated to 8 of 100 bytes"},
+		{"response only", []Option{WithMaxResponseBodySize(8)}, "text/plain", 100, 8, false, true, ""},
+		{"larger than body", []Option{WithMaxBodySize(1000)}, "text/plain", 100, 100, false, false, ""},
+		{"skipped", []Option{WithSkipBodies("Application/Octet-Stream")}, "application/octet-stream", 0, 0, true, true, "body of 100 bytes not recorded"},
+		{"other type kept", []Option{WithSkipBodies("video/")}, "text/plain", 100, 100, false, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTransport(nil, tt.opts...)
+			req, _ := http.NewRequest("POST", srv.URL+"/?type="+tt.typ, strings.NewReader(sent))
+			req.Header.Set("Content-Type", tt.typ)
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(got) != sent {
+				t.Errorf("client got %d bytes, want the full body", len(got))
+			}
+
+			e := tr.HAR().Log.Entries[0]
+			pd, c := e.Request.PostData, e.Response.Content
+			if len(pd.Text) != tt.req || pd.Truncated != tt.truncated || pd.Comment != tt.reqComment || e.Request.BodySize != 100 {
+				t.Errorf("postData = %d bytes, truncated %v, comment %q, bodySize %d", len(pd.Text), pd.Truncated, pd.Comment, e.Request.BodySize)
+			}
+			if e.HasTag(harfile.TagTruncated) != (tt.truncated || tt.respTrunc) {
+				t.Errorf("tags = %q", e.Tags)
+			}
+			if len(c.Text) != tt.resp || c.Truncated != tt.respTrunc || c.Size != 100 || e.Response.BodySize != 100 {
+				t.Errorf("content = %d bytes, truncated %v, size %d, bodySize %d", len(c.Text), c.Truncated, c.Size, e.Response.BodySize)
+			}
+		})
+	}
+}
+
+// retryTransport reads the request body, then sends the request again with
+// the body from GetBody, as a transport retrying on a closed connection
+// does.
+type retryTransport struct{ base http.RoundTripper }
+
+func (rt retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	io.ReadAll(req.Body)
+	req.Body.Close()
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	return rt.base.RoundTrip(retry)
+}
+
+func TestTransportRequestBodyRetried(t *testing.T) {
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+	}))
+	defer srv.Close()
+
+	tr := NewTransport(retryTransport{http.DefaultTransport}, WithMaxRequestBodySize(5))
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("hello world"))
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if received != "hello world" {
+		t.Errorf("server got %q", received)
+	}
+	if r := tr.HAR().Log.Entries[0].Request; r.PostData.Text != "hello" || r.BodySize != 11 {
+		t.Errorf("postData %q, bodySize %d", r.PostData.Text, r.BodySize)
+	}
+}
+
+// TestTransportFailure checks that a refused connection is recorded the way
+// browsers record failed requests.
+func TestTransportFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	tr := NewTransport(http.DefaultTransport)
+	req, _ := http.NewRequest("GET", "http://"+addr+"/x?a=1", nil)
+	if _, err := tr.RoundTrip(req); err == nil {
+		t.Fatal("no error from a closed port")
+	}
+	h := tr.HAR()
+	if len(h.Log.Entries) != 1 {
+		t.Fatalf("%d entries recorded, want 1", len(h.Log.Entries))
+	}
+	e := h.Log.Entries[0]
+	resp := e.Response
+	if resp.Status != 0 || !strings.Contains(resp.Error, "refused") || len(resp.Headers) != 0 || resp.HeadersSize != -1 || resp.BodySize != -1 {
+		t.Errorf("response = %+v", resp)
+	}
+	if e.Request.URL != req.URL.String() || e.Request.QueryString[0].Value != "1" || e.Timings == nil || e.Timings.Connect < 0 {
+		t.Errorf("request %s, query %v, timings %+v", e.Request.URL, e.Request.QueryString, e.Timings)
+	}
+	if !harfile.Failed()(e) {
+		t.Error("entry not selected by Failed")
+	}
+	if err := h.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	// Failed entries go through the entry filter like the others.
+	tr = NewTransport(http.DefaultTransport, WithEntryFilter(harfile.Not(harfile.Failed())))
+	tr.RoundTrip(req)
+	if n := len(tr.HAR().Log.Entries); n != 0 {
+		t.Errorf("%d failed entries recorded despite the filter", n)
+	}
+}
+
+// stubTransport answers every request with a short text body, without
+// any network, to measure the recording alone.
+type stubTransport struct{}
+
+func (stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       io.NopCloser(strings.NewReader("ok")),
+		Request:    req,
+	}, nil
+}
+
+func TestTransportFilters(t *testing.T) {
+	tr := NewTransport(stubTransport{},
+		WithIncludeHosts("*.example.com"),
+		WithExcludePathsRegexp(regexp.MustCompile(`^/collect\b`)),
+		WithEntryFilter(func(e *harfile.Entry) bool { return e.Request.Method != "OPTIONS" }),
+	)
+	for _, tt := range []struct {
+		method, url string
+		recorded    bool
+	}{
+		{"GET", "https://api.example.com/v1", true},
+		{"GET", "https://example.org/v1", false},
+		{"GET", "https://api.example.com/collect?e=1", false},
+		{"OPTIONS", "https://api.example.com/v1", false},
+		{"POST", "https://cdn.example.com/v2", true},
+	} {
+		req, _ := http.NewRequest(tt.method, tt.url, nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, wrapped := resp.Body.(*recordingBody); wrapped == (tt.method == "GET" && !tt.recorded) {
+			t.Errorf("%s %s: body wrapped %v", tt.method, tt.url, wrapped)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	var got []string
+	for _, e := range tr.HAR().Log.Entries {
+		got = append(got, e.Request.Method+" "+e.Request.URL)
+	}
+	if want := "GET https://api.example.com/v1, POST https://cdn.example.com/v2"; strings.Join(got, ", ") != want {
+		t.Errorf("recorded %q, want %q", strings.Join(got, ", "), want)
+	}
+}
+
+// BenchmarkTransport compares a recorded round trip, an excluded one, which
+// should cost about as much as the bare transport, and the bare transport.
+func BenchmarkTransport(b *testing.B) {
+	bench := func(b *testing.B, rt http.RoundTripper) {
+		req, _ := http.NewRequest("GET", "https://api.example.com/v1/users", nil)
+		b.ReportAllocs()
+		for b.Loop() {
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	b.Run("recording", func(b *testing.B) {
+		// Evicting keeps the memory of the recorder flat across iterations.
+		bench(b, NewTransport(stubTransport{}, WithRecorder(NewRecorder(RecorderOptions{MaxEntries: 100}))))
+	})
+	b.Run("excluded", func(b *testing.B) {
+		bench(b, NewTransport(stubTransport{}, WithExcludePathsRegexp(regexp.MustCompile(`^/v1/`))))
+	})
+	b.Run("bare", func(b *testing.B) {
+		bench(b, stubTransport{})
+	})
+}
+
+func TestHTTPVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		major, minor int
+		proto        string
+		state        *tls.ConnectionState
+		want         string
+	}{
+		{"http/1.1", 1, 1, "HTTP/1.1", nil, "HTTP/1.1"},
+		{"http/1.0", 1, 0, "HTTP/1.0", nil, "HTTP/1.0"},
+		{"h2 over tls", 2, 0, "HTTP/2.0", &tls.ConnectionState{NegotiatedProtocol: "h2"}, "HTTP/2.0"},
+		{"alpn wins", 1, 1, "HTTP/1.1", &tls.ConnectionState{NegotiatedProtocol: "h3"}, "HTTP/3.0"},
+		{"tls without alpn", 1, 1, "HTTP/1.1", &tls.ConnectionState{}, "HTTP/1.1"},
+		{"unknown kept", 0, 9, "HTTP/0.9", nil, "HTTP/0.9"},
+	}
+	for _, tt := range tests {
+		if got := httpVersion(tt.major, tt.minor, tt.proto, tt.state); got != tt.want {
+			t.Errorf("%s: httpVersion = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	tr := NewTransport(srv.Client().Transport)
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if e := tr.HAR().Log.Entries[0]; e.Request.HTTPVersion != "HTTP/2.0" || e.Response.HTTPVersion != "HTTP/2.0" {
+		t.Errorf("versions %q and %q, want HTTP/2.0", e.Request.HTTPVersion, e.Response.HTTPVersion)
+	}
+}
+
+// compressingServer serves body compressed with coding, "gzip" or "br",
+// to the requests accepting it, and as is to the others.
+func compressingServer(t *testing.T, coding string, body []byte) *httptest.Server {
+	t.Helper()
+	var encoded bytes.Buffer
+	var zw io.WriteCloser
+	if coding == "gzip" {
+		zw = gzip.NewWriter(&encoded)
+	} else {
+		zw = brotli.NewWriter(&encoded)
+	}
+	zw.Write(body)
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), coding) {
+			w.Write(body)
+			return
+		}
+		w.Header().Set("Content-Encoding", coding)
+		w.Header().Set("Content-Length", fmt.Sprint(encoded.Len()))
+		w.Write(encoded.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTransportCompression(t *testing.T) {
+	body := []byte(strings.Repeat("compressible text ", 500))
+	tests := []struct {
+		name       string
+		coding     string // Coding of the server.
+		accept     string // Accept-Encoding set by the caller.
+		capture    bool   // CaptureCompressedBodies.
+		compressed bool   // The caller reads the body compressed.
+		raw        bool   // The body is recorded compressed.
+		wireSize   bool   // BodySize is the transferred length.
+	}{
+		// http.Transport decompresses on its own: the transferred length is
+		// lost.
+		{name: "gzip", coding: "gzip"},
+		{name: "gzip asked by the caller", coding: "gzip", accept: "gzip", compressed: true, wireSize: true},
+		{name: "br asked by the caller", coding: "br", accept: "br", compressed: true, wireSize: true},
+		{name: "gzip captured", coding: "gzip", capture: true, raw: true, wireSize: true},
+		{name: "gzip asked and captured", coding: "gzip", accept: "gzip", capture: true, compressed: true, raw: true, wireSize: true},
+		{name: "br asked and captured", coding: "br", accept: "br", capture: true, compressed: true, raw: true, wireSize: true},
+		// Nothing to keep compressed when the server does not speak gzip.
+		{name: "br server captured", coding: "br", capture: true, wireSize: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := compressingServer(t, tt.coding, body)
+			opts := []Option{}
+			if tt.capture {
+				opts = append(opts, CaptureCompressedBodies())
+			}
+			tr := NewTransport(http.DefaultTransport.(*http.Transport).Clone(), opts...)
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			resp, err := (&http.Client{Transport: tr}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read := !bytes.Equal(got, body); read != tt.compressed {
+				t.Errorf("caller read %d compressed bytes: %v, want %v", len(got), read, tt.compressed)
+			}
+			if tt.compressed == (resp.Header.Get("Content-Encoding") == "") {
+				t.Errorf("caller sees Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+			}
+
+			e := tr.HAR().Log.Entries[0]
+			c := e.Response.Content
+			if c.Size != int64(len(body)) {
+				t.Errorf("Content.Size = %d, want %d", c.Size, len(body))
+			}
+			if decoded, err := c.DecodedBody(); err != nil || !bytes.Equal(decoded, body) {
+				t.Errorf("DecodedBody = %d bytes, %v", len(decoded), err)
+			}
+			if raw := c.Encoding == "base64" && c.ContentEncoding == tt.coding; raw != tt.raw {
+				t.Errorf("recorded compressed %v, want %v (encoding %q, contentEncoding %q)", raw, tt.raw, c.Encoding, c.ContentEncoding)
+			}
+			switch {
+			case !tt.wireSize:
+				if e.Response.BodySize != harfile.SizeUnknown || c.Compression != 0 {
+					t.Errorf("bodySize %d, compression %d, want unknown", e.Response.BodySize, c.Compression)
+				}
+			case tt.coding == "br" && !tt.compressed && !tt.raw:
+				if e.Response.BodySize != int64(len(body)) || c.Compression != 0 {
+					t.Errorf("bodySize %d, compression %d of an uncompressed body", e.Response.BodySize, c.Compression)
+				}
+			default:
+				if e.Response.BodySize >= int64(len(body)) || c.Compression != c.Size-e.Response.BodySize {
+					t.Errorf("bodySize %d, compression %d, size %d", e.Response.BodySize, c.Compression, c.Size)
+				}
+			}
+
+			hr, err := e.Response.ToHTTP()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if replayed, _ := io.ReadAll(hr.Body); !bytes.Equal(replayed, body) {
+				t.Errorf("ToHTTP body of %d bytes, want the decoded %d", len(replayed), len(body))
+			}
+		})
+	}
+}
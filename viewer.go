@@ -0,0 +1,220 @@
+package harkit
+
+import (
+	"cmp"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+//go:embed templates/viewer.html
+var templates embed.FS
+
+var viewerTemplate = template.Must(template.ParseFS(templates, "templates/viewer.html"))
+
+// HTMLOptions configures [ExportHTML].
+type HTMLOptions struct {
+	Title       string // Page title. Empty means "HAR viewer".
+	MaxBodySize int    // Bytes of text body shown; longer bodies are cut. Zero means 64 KiB.
+	HexPreview  int    // Bytes of binary body shown as a hex dump. Zero means 256.
+
+	Progress harfile.Progress // Receives a StageExport step per entry rendered. Nil means harfile.NoProgress.
+}
+
+type htmlPage struct {
+	Title, Creator, Browser string
+	Pages                   int
+	Total                   string
+	Entries                 []*htmlEntry
+}
+
+type htmlEntry struct {
+	Index                           int
+	Method, URL                     string
+	Status                          int64
+	StatusText, MimeType            string
+	Failed                          bool
+	Size                            int64
+	SizeText                        string
+	Time                            float64
+	TimeText, TimingsText           string
+	Offset                          float64
+	Segments                        []htmlSegment
+	RequestHeaders, ResponseHeaders []*harfile.NameValuePair
+	RequestBody, ResponseBody       htmlBody
+}
+
+type htmlSegment struct {
+	Phase       string
+	Left, Width float64 // Percentages of the timeline.
+}
+
+type htmlBody struct {
+	Text string
+	Note string
+}
+
+// ExportHTML writes har as a self-contained HTML page, without external
+// assets, that can be opened in any browser: a table of the entries,
+// sortable by clicking a column, with a waterfall bar drawn from the
+// timings of each entry and expandable panes showing the headers and bodies.
+// Text bodies longer than opts.MaxBodySize are cut, and binary bodies are
+// shown as a hex dump of their first bytes. Every recorded string is HTML
+// escaped.
+func ExportHTML(w io.Writer, har *harfile.HAR, opts HTMLOptions) error {
+	opts.Title = cmp.Or(opts.Title, "HAR viewer")
+	opts.MaxBodySize = cmp.Or(opts.MaxBodySize, 64<<10)
+	opts.HexPreview = cmp.Or(opts.HexPreview, 256)
+
+	page := &htmlPage{Title: opts.Title}
+	if har == nil || har.Log == nil {
+		return viewerTemplate.Execute(w, page)
+	}
+	log := har.Log
+	if log.Creator != nil {
+		page.Creator = strings.TrimSpace(log.Creator.Name + " " + log.Creator.Version)
+	}
+	if log.Browser != nil {
+		page.Browser = strings.TrimSpace(log.Browser.Name + " " + log.Browser.Version)
+	}
+	page.Pages = len(log.Pages)
+
+	timeline := Waterfall(log)
+	page.Total = formatMillis(float64(timeline.Total.Microseconds()) / 1000)
+	total := float64(timeline.Total)
+	percent := func(d float64) float64 {
+		if total <= 0 {
+			return 0
+		}
+		return float64(int(d/total*10000)) / 100
+	}
+	index := make(map[*harfile.Entry]int, len(log.Entries))
+	for i, e := range log.Entries {
+		index[e] = i
+	}
+
+	progress := cmp.Or(opts.Progress, harfile.NoProgress)
+	for i, row := range timeline.Rows {
+		progress.Step(StageExport, int64(i+1), int64(len(timeline.Rows)))
+		e := row.Entry
+		he := &htmlEntry{
+			Index:    index[e],
+			Time:     e.Time,
+			TimeText: formatMillis(e.Time),
+			Offset:   percent(float64(row.Start.Sub(timeline.Origin))),
+			Size:     -1,
+			SizeText: "—",
+		}
+		for _, s := range row.Segments {
+			if !s.End.After(s.Start) {
+				continue
+			}
+			he.Segments = append(he.Segments, htmlSegment{
+				Phase: s.Phase,
+				Left:  percent(float64(s.Start.Sub(timeline.Origin))),
+				Width: percent(float64(s.End.Sub(s.Start))),
+			})
+		}
+		if t := e.Timings; t != nil {
+			he.TimingsText = fmt.Sprintf("blocked %s, dns %s, connect %s, ssl %s, send %s, wait %s, receive %s",
+				formatMillis(t.Blocked), formatMillis(t.DNS), formatMillis(t.Connect), formatMillis(t.Ssl),
+				formatMillis(t.Send), formatMillis(t.Wait), formatMillis(t.Receive))
+		}
+		if req := e.Request; req != nil {
+			he.Method, he.URL = req.Method, req.URL
+			he.RequestHeaders = htmlPairs(req.Headers)
+			if req.PostData != nil {
+				he.RequestBody = requestBody(req.PostData, opts)
+			}
+		}
+		if resp := e.Response; resp != nil {
+			he.Status, he.StatusText = resp.Status, resp.StatusText
+			he.Failed = resp.Status == 0 || resp.Status >= 400
+			he.ResponseHeaders = htmlPairs(resp.Headers)
+			if n := reportSize(e); n >= 0 {
+				he.Size, he.SizeText = n, formatBytes(n)
+			}
+			if c := resp.Content; c != nil {
+				he.MimeType = c.MimeType
+				he.ResponseBody = responseBody(resp, opts)
+			}
+			if resp.Error != "" {
+				he.ResponseBody.Note = strings.TrimSpace(resp.Error + " " + he.ResponseBody.Note)
+			}
+		}
+		page.Entries = append(page.Entries, he)
+	}
+	return viewerTemplate.Execute(w, page)
+}
+
+// htmlPairs returns the pairs of list shown in the viewer, leaving out the
+// nil elements the template cannot render.
+func htmlPairs(list []*harfile.NameValuePair) []*harfile.NameValuePair {
+	return slices.DeleteFunc(slices.Clone(list), func(p *harfile.NameValuePair) bool { return p == nil })
+}
+
+func requestBody(pd *harfile.PostData, opts HTMLOptions) htmlBody {
+	r, _, err := pd.BodyReader()
+	if err != nil {
+		return htmlBody{Note: "Body cannot be decoded: " + err.Error()}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return htmlBody{Note: "Body cannot be decoded: " + err.Error()}
+	}
+	return previewBody(data, opts)
+}
+
+func responseBody(resp *harfile.Response, opts HTMLOptions) htmlBody {
+	data, err := resp.Content.DecodeBody(headerValue(resp.Headers, "Content-Encoding"))
+	if err != nil {
+		return htmlBody{Note: "Body cannot be decoded: " + err.Error()}
+	}
+	b := previewBody(data, opts)
+	if resp.Content.Truncated {
+		b.Note = strings.TrimSpace(b.Note + " Recorded body truncated, " + formatBytes(resp.Content.Size) + " in full.")
+	}
+	return b
+}
+
+// previewBody returns the part of data shown in the viewer.
+func previewBody(data []byte, opts HTMLOptions) htmlBody {
+	switch {
+	case len(data) == 0:
+		return htmlBody{}
+	case !utf8.Valid(data):
+		n := min(len(data), opts.HexPreview)
+		b := htmlBody{Text: hex.Dump(data[:n])}
+		b.Note = "Binary body of " + formatBytes(int64(len(data))) + "."
+		if n < len(data) {
+			b.Note += " First " + strconv.Itoa(n) + " bytes shown."
+		}
+		return b
+	case len(data) > opts.MaxBodySize:
+		cut := opts.MaxBodySize
+		for cut > 0 && !utf8.RuneStart(data[cut]) {
+			cut--
+		}
+		return htmlBody{Text: string(data[:cut]), Note: "Body of " + formatBytes(int64(len(data))) + ", first " + formatBytes(int64(cut)) + " shown."}
+	}
+	return htmlBody{Text: string(data)}
+}
+
+// formatMillis formats a duration in milliseconds, or "-" when unknown.
+func formatMillis(ms float64) string {
+	switch {
+	case ms < 0:
+		return "-"
+	case ms < 1000:
+		return strconv.FormatFloat(ms, 'f', 0, 64) + " ms"
+	}
+	return strconv.FormatFloat(ms/1000, 'f', 2, 64) + " s"
+}
@@ -0,0 +1,85 @@
+package harkit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+func TestExportHTMLEscapes(t *testing.T) {
+	const payload = `<script>alert("x")</script>`
+	e := respond(exportEntry("GET", "https://example.com/?q="+payload, time.Time{}, "", nil, 200, "X-Evil", payload),
+		"text/html", []byte(payload), "X-Evil", payload)
+	har := &harfile.HAR{Log: &harfile.Log{Creator: &harfile.Creator{Name: payload, Version: "1"}, Entries: []*harfile.Entry{e}}}
+
+	var b bytes.Buffer
+	if err := ExportHTML(&b, har, HTMLOptions{Title: payload}); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if strings.Contains(out, payload) {
+		t.Error("recorded <script> tag rendered unescaped")
+	}
+	if n := strings.Count(out, "<script>"); n != 1 {
+		t.Errorf("page has %d <script> tags, want only the viewer's own", n)
+	}
+	if !strings.Contains(out, "&lt;script&gt;") {
+		t.Error("escaped payload missing from the page")
+	}
+}
+
+func TestExportHTMLNilElements(t *testing.T) {
+	e := exportEntry("GET", "https://example.com/", time.Time{}, "", nil, 200, "Accept", "*/*")
+	e.Request.Headers = append(e.Request.Headers, nil)
+	e.Request.Cookies = append(e.Request.Cookies, nil)
+	e.Request.QueryString = append(e.Request.QueryString, nil)
+	e.Response.Headers = append([]*harfile.NameValuePair{nil}, e.Response.Headers...)
+	e.Response.Cookies = append(e.Response.Cookies, nil)
+	har := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e, nil}}}
+
+	var b bytes.Buffer
+	if err := ExportHTML(&b, har, HTMLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "Accept") {
+		t.Error("headers around the nil element are missing")
+	}
+}
+
+func TestExportHTMLZeroDurationEntries(t *testing.T) {
+	zero := harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}
+	har := &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{
+		timedEntry("https://example.com/a.css", 0, zero),
+		timedEntry("https://example.com/b.css", 0, zero),
+	}}}
+
+	done := make(chan error)
+	go func() { done <- ExportHTML(&bytes.Buffer{}, har, HTMLOptions{}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ExportHTML did not return")
+	}
+}
+
+func TestExportHTMLBodies(t *testing.T) {
+	binary := []byte{0xff, 0xfe, 0x00, 0x01, 0x02}
+	e := respond(exportEntry("POST", "https://example.com/upload", time.Time{}, "text/plain", []byte(strings.Repeat("a", 100)), 200),
+		"application/octet-stream", binary)
+	var b bytes.Buffer
+	if err := ExportHTML(&b, &harfile.HAR{Log: &harfile.Log{Entries: []*harfile.Entry{e}}}, HTMLOptions{MaxBodySize: 10, HexPreview: 4}); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{"first 10 B shown", "Binary body of 5 B", "First 4 bytes shown", "ff fe 00 01"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
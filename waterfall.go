@@ -0,0 +1,195 @@
+package harkit
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+// Phases of a [Segment], named after the timings fields.
+const (
+	PhaseBlocked = "blocked"
+	PhaseDNS     = "dns"
+	PhaseConnect = "connect"
+	PhaseSSL     = "ssl"
+	PhaseSend    = "send"
+	PhaseWait    = "wait"
+	PhaseReceive = "receive"
+)
+
+// Segment is a phase of a request placed on the timeline.
+type Segment struct {
+	Phase      string
+	Start, End time.Time
+}
+
+// Row holds the segments of an entry. Segments follow each other except
+// for ssl, which overlaps the end of connect as in the timings.
+type Row struct {
+	Entry      *harfile.Entry
+	Start, End time.Time
+	Segments   []Segment // Empty when the entry has no timings.
+}
+
+// Timeline places the entries of a log on a common time axis.
+type Timeline struct {
+	Origin       time.Time     // Start of the first page, or of the first entry when the log has no pages.
+	Total        time.Duration // Wall time from Origin to the end of the last entry.
+	Rows         []*Row        // Rows in order of start.
+	CriticalPath []*Row        // Rows, in order, each starting after the previous one ended, that lead to the last end.
+}
+
+// Waterfall converts the entries of log into a timeline. Phases that are
+// not available (-1) are skipped, and an entry without timings spans its
+// total time with no segment.
+func Waterfall(log *harfile.Log) *Timeline {
+	t := &Timeline{}
+	for _, p := range log.Pages {
+		if p != nil && (t.Origin.IsZero() || p.StartedDateTime.Before(t.Origin)) {
+			t.Origin = p.StartedDateTime
+		}
+	}
+	for _, e := range log.Entries {
+		if e == nil {
+			continue
+		}
+		row := newRow(e)
+		t.Rows = append(t.Rows, row)
+		if len(log.Pages) == 0 && (t.Origin.IsZero() || row.Start.Before(t.Origin)) {
+			t.Origin = row.Start
+		}
+	}
+	slices.SortStableFunc(t.Rows, func(a, b *Row) int { return a.Start.Compare(b.Start) })
+
+	var last *Row
+	for _, r := range t.Rows {
+		if last == nil || r.End.After(last.End) {
+			last = r
+		}
+	}
+	if last == nil {
+		return t
+	}
+	t.Total = last.End.Sub(t.Origin)
+	for i := slices.Index(t.Rows, last); i >= 0; i = t.predecessor(i) {
+		t.CriticalPath = append(t.CriticalPath, t.Rows[i])
+	}
+	slices.Reverse(t.CriticalPath)
+	return t
+}
+
+func newRow(e *harfile.Entry) *Row {
+	row := &Row{Entry: e, Start: e.StartedDateTime}
+	row.End = row.Start.Add(millis(e.Time))
+	if e.Timings == nil {
+		return row
+	}
+	at := row.Start
+	add := func(phase string, ms float64) {
+		if ms < 0 {
+			return
+		}
+		end := at.Add(millis(ms))
+		row.Segments = append(row.Segments, Segment{Phase: phase, Start: at, End: end})
+		at = end
+	}
+	tm := e.Timings
+	add(PhaseBlocked, tm.Blocked)
+	add(PhaseDNS, tm.DNS)
+	add(PhaseConnect, tm.Connect)
+	if tm.Ssl >= 0 {
+		ssl := Segment{Phase: PhaseSSL, Start: at.Add(-millis(tm.Ssl)), End: at}
+		if tm.Connect < 0 {
+			// Exporters that leave ssl out of connect.
+			ssl.Start, ssl.End = at, at.Add(millis(tm.Ssl))
+			at = ssl.End
+		}
+		row.Segments = append(row.Segments, ssl)
+	}
+	add(PhaseSend, tm.Send)
+	add(PhaseWait, tm.Wait)
+	add(PhaseReceive, tm.Receive)
+	if at.After(row.End) {
+		row.End = at
+	}
+	return row
+}
+
+// predecessor returns the index of the row that ended last before row i
+// started, which it most likely waited for, or -1. A row ending exactly
+// when row i starts must come before it in Rows, so that rows of zero
+// duration starting together, such as cached responses, cannot be each
+// other's predecessor.
+func (t *Timeline) predecessor(i int) int {
+	r, pred := t.Rows[i], -1
+	for j, c := range t.Rows {
+		if j == i || c.End.After(r.Start) || c.End.Equal(r.Start) && j > i {
+			continue
+		}
+		if pred < 0 || c.End.After(t.Rows[pred].End) {
+			pred = j
+		}
+	}
+	return pred
+}
+
+// phaseChars draws each phase in RenderText.
+var phaseChars = map[string]byte{
+	PhaseBlocked: '-',
+	PhaseDNS:     'd',
+	PhaseConnect: 'c',
+	PhaseSSL:     's',
+	PhaseSend:    '>',
+	PhaseWait:    '.',
+	PhaseReceive: '#',
+}
+
+// RenderText draws the timeline as text, one line per row with its method,
+// URL and a bar of width characters scaled to the total time. Phases are
+// drawn as '-' blocked, 'd' dns, 'c' connect, 's' ssl, '>' send, '.' wait
+// and '#' receive; entries without timings as '='.
+func (t *Timeline) RenderText(w io.Writer, width int) error {
+	width = max(width, 10)
+	scale := func(at time.Time) int {
+		if t.Total <= 0 {
+			return 0
+		}
+		return min(int(float64(at.Sub(t.Origin))/float64(t.Total)*float64(width)), width-1)
+	}
+	for _, r := range t.Rows {
+		bar := []byte(strings.Repeat(" ", width))
+		draw := func(from, to time.Time, c byte) {
+			for i := scale(from); i <= scale(to) && i < width; i++ {
+				bar[max(i, 0)] = c
+			}
+		}
+		if len(r.Segments) == 0 {
+			draw(r.Start, r.End, '=')
+		}
+		for _, s := range r.Segments {
+			if s.End.After(s.Start) {
+				draw(s.Start, s.End, phaseChars[s.Phase])
+			}
+		}
+		label := ""
+		if r.Entry.Request != nil {
+			label = r.Entry.Request.Method + " " + r.Entry.Request.URL
+		}
+		if runes := []rune(label); len(runes) > 40 {
+			label = string(runes[:39]) + "…"
+		}
+		if _, err := fmt.Fprintf(w, "%-40s |%s| %s\n", label, bar, r.End.Sub(r.Start).Round(time.Millisecond)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "total %s\n", t.Total.Round(time.Millisecond))
+	return err
+}
+
+func millis(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,82 @@
+package harkit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/harkit/harfile"
+)
+
+var testStart = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func timedEntry(url string, offset time.Duration, t harfile.Timings) *harfile.Entry {
+	e := &harfile.Entry{
+		StartedDateTime: testStart.Add(offset),
+		Request:         &harfile.Request{Method: "GET", URL: url},
+		Response:        &harfile.Response{Status: 200, StatusText: "OK", Content: &harfile.Content{}},
+		Timings:         &t,
+	}
+	e.ComputeTime()
+	return e
+}
+
+func TestWaterfallCriticalPath(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		timedEntry("https://example.com/", 0, harfile.Timings{Blocked: -1, DNS: 10, Connect: 20, Ssl: 5, Send: 1, Wait: 50, Receive: 19}),
+		timedEntry("https://example.com/app.js", 100*time.Millisecond, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 30, Receive: 9}),
+		timedEntry("https://example.com/logo.png", 110*time.Millisecond, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 5, Receive: 4}),
+		timedEntry("https://example.com/api", 150*time.Millisecond, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 1, Wait: 40, Receive: 9}),
+	}}
+
+	tl := Waterfall(log)
+	if got, want := tl.Total, 200*time.Millisecond; got != want {
+		t.Errorf("Total = %s, want %s", got, want)
+	}
+	var path []string
+	for _, r := range tl.CriticalPath {
+		path = append(path, r.Entry.Request.URL)
+	}
+	want := []string{"https://example.com/", "https://example.com/app.js", "https://example.com/api"}
+	if strings.Join(path, " ") != strings.Join(want, " ") {
+		t.Errorf("CriticalPath = %v, want %v", path, want)
+	}
+	if n := len(tl.Rows[0].Segments); n != 6 {
+		t.Errorf("first row has %d segments, want 6 without blocked", n)
+	}
+}
+
+func TestWaterfallZeroDurationRows(t *testing.T) {
+	// Cached responses often take no time and start together: neither row
+	// may be the predecessor of the other.
+	zero := harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		timedEntry("https://example.com/a.css", 0, zero),
+		timedEntry("https://example.com/b.css", 0, zero),
+		timedEntry("https://example.com/c.css", 0, zero),
+	}}
+
+	done := make(chan *Timeline)
+	go func() { done <- Waterfall(log) }()
+	select {
+	case tl := <-done:
+		if n := len(tl.CriticalPath); n == 0 || n > len(tl.Rows) {
+			t.Errorf("CriticalPath has %d rows, want 1 to %d", n, len(tl.Rows))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Waterfall did not return")
+	}
+}
+
+func TestTimelineRenderText(t *testing.T) {
+	log := &harfile.Log{Entries: []*harfile.Entry{
+		timedEntry("https://example.com/", 0, harfile.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 0, Wait: 50, Receive: 50}),
+	}}
+	var b strings.Builder
+	if err := Waterfall(log).RenderText(&b, 20); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "GET https://example.com/") || !strings.HasSuffix(b.String(), "total 100ms\n") {
+		t.Errorf("RenderText =\n%s", b.String())
+	}
+}